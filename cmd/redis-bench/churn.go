@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// churnScenario opens a fresh connection for every request, sends one PING,
+// and closes it. It stresses the accept path and fd lifecycle rather than
+// command execution. Connect failures (refused, reset, timed out during
+// accept) are reported separately from command errors.
+func churnScenario() scenario {
+	return scenario{
+		name:        "connection_churn",
+		description: "connect + PING + close per request",
+		run:         runChurn,
+	}
+}
+
+func runChurn(addr string, sc scenario, requests, concurrency int) (scenarioResult, error) {
+	jobs := make(chan struct{}, requests)
+	for i := 0; i < requests; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	type workerOut struct {
+		latencies     []float64
+		errors        int
+		connectErrors int
+	}
+	outs := make(chan workerOut, concurrency)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out := workerOut{latencies: make([]float64, 0, requests/concurrency+8)}
+			for range jobs {
+				t0 := time.Now()
+				connectErr, cmdErr := churnOnce(addr)
+				out.latencies = append(out.latencies, time.Since(t0).Seconds()*1000.0)
+				switch {
+				case connectErr != nil:
+					out.connectErrors++
+				case cmdErr != nil:
+					out.errors++
+				}
+			}
+			outs <- out
+		}()
+	}
+	wg.Wait()
+	close(outs)
+
+	dur := time.Since(start)
+	allLat := make([]float64, 0, requests)
+	res := scenarioResult{
+		Scenario:    sc.name,
+		Description: sc.description,
+		Requests:    requests,
+		Concurrency: concurrency,
+		DurationMs:  dur.Seconds() * 1000.0,
+		Throughput:  float64(requests) / dur.Seconds(),
+	}
+	for out := range outs {
+		allLat = append(allLat, out.latencies...)
+		res.Errors += out.errors
+		res.ConnectErrors += out.connectErrors
+	}
+	sort.Float64s(allLat)
+	res.P50Ms = percentile(allLat, 50)
+	res.P95Ms = percentile(allLat, 95)
+	res.P99Ms = percentile(allLat, 99)
+	return res, nil
+}
+
+// churnOnce performs one connect/PING/close cycle. The connect error is
+// returned separately so accept-path failures are not hidden among
+// ordinary command failures.
+func churnOnce(addr string) (connectErr, cmdErr error) {
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return err, nil
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	wire, err := redisproto.Encode(buildCommand([]string{"PING"}))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write(wire); err != nil {
+		return nil, err
+	}
+	_, err = readOneRESP(conn)
+	return nil, err
+}
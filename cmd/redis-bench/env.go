@@ -0,0 +1,244 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redismvp"
+)
+
+// benchEnvironment records where a report was produced, so results from
+// different commits or machines are not compared as if they were alike.
+// Fields that cannot be determined are left empty.
+type benchEnvironment struct {
+	GitCommit     string       `json:"git_commit"`
+	GitDirty      bool         `json:"git_dirty"`
+	GoVersion     string       `json:"go_version"`
+	OS            string       `json:"os"`
+	Kernel        string       `json:"kernel"`
+	CPUModel      string       `json:"cpu_model"`
+	NumCPU        int          `json:"num_cpu"`
+	GOMAXPROCS    int          `json:"gomaxprocs"`
+	LibxevVersion string       `json:"libxev_version"`
+	Servers       []serverInfo `json:"servers"`
+}
+
+// serverInfo is the version and configuration of one benchmarked server.
+type serverInfo struct {
+	Target  string            `json:"target"`
+	Version string            `json:"version,omitempty"`
+	Config  map[string]string `json:"config"`
+}
+
+// referenceRedisArgs are the settings startReferenceRedis passes after the
+// port, which turn off persistence.
+var referenceRedisArgs = []string{"--save", "", "--appendonly", "no"}
+
+// captureEnvironment describes the running machine and build, and the
+// servers the report compares.
+func captureEnvironment(servers ...serverInfo) benchEnvironment {
+	env := benchEnvironment{
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS + "/" + runtime.GOARCH,
+		Kernel:        kernelVersion(),
+		CPUModel:      cpuModel(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		LibxevVersion: libxevVersion(),
+		Servers:       servers,
+	}
+	env.GitCommit, env.GitDirty = gitCommit()
+	return env
+}
+
+// mvpServerInfo describes an MVP server started with cfg, using Redis
+// setting names. File paths are left out, since they point at temporary
+// directories.
+func mvpServerInfo(target string, cfg redismvp.Config) serverInfo {
+	maxClients := cfg.MaxClients
+	if maxClients == 0 {
+		maxClients = redismvp.DefaultMaxClients
+	}
+	config := map[string]string{
+		"appendonly":       yesNo(cfg.AppendOnly),
+		"maxclients":       strconv.Itoa(maxClients),
+		"maxmemory":        strconv.FormatInt(cfg.MaxMemory, 10),
+		"maxmemory-policy": cfg.MaxMemoryPolicy.String(),
+		"tcp-keepalive":    strconv.Itoa(int(cfg.TCPKeepAlive / time.Second)),
+		"tcp-nodelay":      yesNo(cfg.TCPNoDelay),
+		"timeout":          strconv.Itoa(int(cfg.Timeout / time.Second)),
+	}
+	if cfg.AppendOnly {
+		config["appendfsync"] = cfg.AppendFsync.String()
+	}
+	return serverInfo{Target: target, Config: config}
+}
+
+// referenceServerInfo describes the redis-server started by
+// startReferenceRedis.
+func referenceServerInfo() serverInfo {
+	info := serverInfo{Target: "redis-server", Config: make(map[string]string)}
+	for i := 0; i+1 < len(referenceRedisArgs); i += 2 {
+		info.Config[strings.TrimPrefix(referenceRedisArgs[i], "--")] = referenceRedisArgs[i+1]
+	}
+	bin := os.Getenv("REDIS_SERVER_BIN")
+	if bin == "" {
+		bin = "redis-server"
+	}
+	if out, err := exec.Command(bin, "--version").Output(); err == nil {
+		info.Version = parseRedisVersion(string(out))
+	}
+	return info
+}
+
+// parseRedisVersion extracts the version from the output of
+// "redis-server --version", such as "Redis server v=7.2.4 sha=...".
+func parseRedisVersion(out string) string {
+	for _, field := range strings.Fields(out) {
+		if v, ok := strings.CutPrefix(field, "v="); ok {
+			return v
+		}
+	}
+	return strings.TrimSpace(out)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// gitCommit returns the commit the binary was built from, as stamped by
+// the go command, or else the commit checked out in the working directory,
+// which is what "go run" benchmarks.
+func gitCommit() (string, bool) {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		var rev string
+		var dirty bool
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				rev = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if rev != "" {
+			return rev, dirty
+		}
+	}
+	rev := commandOutput("git", "rev-parse", "HEAD")
+	if rev == "" {
+		return "", false
+	}
+	return rev, commandOutput("git", "status", "--porcelain") != ""
+}
+
+// libxevVersion returns the libxev commit checked out in deps/libxev, or
+// the commit the superproject pins when the submodule is not checked out.
+func libxevVersion() string {
+	if rev := commandOutput("git", "-C", "deps/libxev", "rev-parse", "HEAD"); rev != "" {
+		return rev
+	}
+	return commandOutput("git", "rev-parse", "HEAD:deps/libxev")
+}
+
+func kernelVersion() string {
+	typ, err1 := os.ReadFile("/proc/sys/kernel/ostype")
+	rel, err2 := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err1 == nil && err2 == nil {
+		return strings.TrimSpace(string(typ)) + " " + strings.TrimSpace(string(rel))
+	}
+	return commandOutput("uname", "-sr")
+}
+
+func cpuModel() string {
+	if f, err := os.Open("/proc/cpuinfo"); err == nil {
+		defer f.Close()
+		if model := parseCPUInfo(f); model != "" {
+			return model
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		return commandOutput("sysctl", "-n", "machdep.cpu.brand_string")
+	}
+	return ""
+}
+
+// parseCPUInfo returns the model name of the first processor listed in a
+// /proc/cpuinfo file.
+func parseCPUInfo(r io.Reader) string {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// commandOutput runs a command and returns its trimmed output, or "" if it
+// fails.
+func commandOutput(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// renderEnvironment writes the environment section of a markdown report.
+// Reports written before the environment was recorded have none.
+func renderEnvironment(b *strings.Builder, env benchEnvironment) {
+	if env.GoVersion == "" {
+		return
+	}
+	commit := env.GitCommit
+	if commit == "" {
+		commit = "unknown"
+	} else if env.GitDirty {
+		commit += " (modified)"
+	}
+	b.WriteString("## Environment\n\n")
+	_, _ = fmt.Fprintf(b, "- commit: %s\n", commit)
+	_, _ = fmt.Fprintf(b, "- go: %s, %s\n", env.GoVersion, env.OS)
+	_, _ = fmt.Fprintf(b, "- kernel: %s\n", orUnknown(env.Kernel))
+	_, _ = fmt.Fprintf(b, "- cpu: %s, %d CPUs, GOMAXPROCS %d\n", orUnknown(env.CPUModel), env.NumCPU, env.GOMAXPROCS)
+	_, _ = fmt.Fprintf(b, "- libxev: %s\n", orUnknown(env.LibxevVersion))
+	for _, s := range env.Servers {
+		var settings []string
+		for _, k := range slices.Sorted(maps.Keys(s.Config)) {
+			settings = append(settings, fmt.Sprintf("%s=%q", k, s.Config[k]))
+		}
+		version := ""
+		if s.Version != "" {
+			version = " " + s.Version
+		}
+		_, _ = fmt.Fprintf(b, "- %s%s: %s\n", s.Target, version, strings.Join(settings, " "))
+	}
+	b.WriteByte('\n')
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
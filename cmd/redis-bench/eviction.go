@@ -0,0 +1,306 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redismvp"
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// An eviction run measures how well each maxmemory policy keeps a cache
+// warm. The MVP server is restarted per policy with maxmemory sized for a
+// fraction of the keyspace, and driven as a cache-aside store: every
+// request GETs a key and SETs it on a miss. Keys follow a zipfian
+// distribution; the zipf_scan workload also sends every fifth request to a
+// sequential scan of keys that are never read again, the traffic that
+// flushes an LRU cache and that LFU is meant to resist. The gate requires
+// allkeys-lfu to hit at least as often as allkeys-lru on every workload.
+
+const (
+	evictionValueSize = 64
+	// evictionKeyOverhead approximates what the server charges per key on
+	// top of its name and value.
+	evictionKeyOverhead = 64
+	// zipfS is the skew of the key distribution; larger is more skewed.
+	zipfS = 1.1
+)
+
+var evictionPolicies = []redismvp.MaxMemoryPolicy{
+	redismvp.MaxMemoryAllKeysRandom,
+	redismvp.MaxMemoryAllKeysLRU,
+	redismvp.MaxMemoryAllKeysLFU,
+}
+
+type evictionWorkload struct {
+	name        string
+	description string
+	// scanEvery sends every scanEvery-th request to the scan; zero never.
+	scanEvery int
+}
+
+var evictionWorkloads = []evictionWorkload{
+	{name: "zipf", description: "zipfian GET, SET on miss"},
+	{name: "zipf_scan", description: "zipfian GET, SET on miss, 20% one-off scan keys", scanEvery: 5},
+}
+
+type evictionResult struct {
+	Workload   string  `json:"workload"`
+	Policy     string  `json:"policy"`
+	Requests   int     `json:"requests"`
+	Gets       int     `json:"zipf_gets"`
+	Hits       int     `json:"zipf_hits"`
+	HitRate    float64 `json:"hit_rate"`
+	DurationMs float64 `json:"duration_ms"`
+	Errors     int     `json:"errors"`
+}
+
+type evictionReport struct {
+	GeneratedAt  time.Time        `json:"generated_at"`
+	Keys         int              `json:"keys"`
+	CachedKeys   int              `json:"cached_keys"`
+	MaxMemory    int64            `json:"maxmemory"`
+	Requests     int              `json:"requests"`
+	Concurrency  int              `json:"concurrency"`
+	Results      []evictionResult `json:"results"`
+	LFUBeatsLRU  bool             `json:"lfu_beats_lru"`
+	Command      string           `json:"command"`
+	Descriptions []scenarioInfo   `json:"workloads"`
+	Environment  benchEnvironment `json:"environment"`
+}
+
+func runEviction(keys int, cacheRatio float64, requests, concurrency int) error {
+	if keys <= 1 || cacheRatio <= 0 || cacheRatio >= 1 {
+		return errors.New("eviction needs more than one key and a cache ratio in (0, 1)")
+	}
+	cached := max(int(float64(keys)*cacheRatio), 1)
+	keySize := len(zipfKey(0)) + evictionValueSize + evictionKeyOverhead
+	report := evictionReport{
+		GeneratedAt: time.Now().UTC(),
+		Keys:        keys,
+		CachedKeys:  cached,
+		MaxMemory:   int64(cached * keySize),
+		Requests:    requests,
+		Concurrency: concurrency,
+		Command:     strings.Join(os.Args, " "),
+	}
+	var servers []serverInfo
+	for _, p := range evictionPolicies {
+		servers = append(servers, mvpServerInfo("libxev-go-mvp "+p.String(), evictionConfig(p, report.MaxMemory, "")))
+	}
+	report.Environment = captureEnvironment(servers...)
+	for _, w := range evictionWorkloads {
+		report.Descriptions = append(report.Descriptions, scenarioInfo{Name: w.name, Description: w.description})
+		for _, p := range evictionPolicies {
+			res, err := benchmarkPolicy(p, w, report.MaxMemory, keys, requests, concurrency)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", w.name, p, err)
+			}
+			_, _ = fmt.Printf("%-10s %-15s hit rate %.3f (%d/%d) in %.0f ms, %d errors\n",
+				res.Workload, res.Policy, res.HitRate, res.Hits, res.Gets, res.DurationMs, res.Errors)
+			report.Results = append(report.Results, res)
+		}
+	}
+	report.LFUBeatsLRU = lfuBeatsLRU(report.Results)
+
+	if err := writeEvictionReport(report); err != nil {
+		return err
+	}
+	if !report.LFUBeatsLRU {
+		return errors.New("eviction gate failed: allkeys-lfu hit rate below allkeys-lru")
+	}
+	_, _ = fmt.Println("eviction gate passed")
+	return nil
+}
+
+// evictionConfig returns the configuration of a server evicting with
+// policy, keeping its files in dir.
+func evictionConfig(policy redismvp.MaxMemoryPolicy, maxMemory int64, dir string) redismvp.Config {
+	return redismvp.Config{
+		Addr:            "127.0.0.1:0",
+		LogLevel:        redismvp.LevelWarning,
+		DBFilename:      filepath.Join(dir, "dump.rdb"),
+		MaxMemory:       maxMemory,
+		MaxMemoryPolicy: policy,
+	}
+}
+
+func benchmarkPolicy(policy redismvp.MaxMemoryPolicy, wl evictionWorkload, maxMemory int64, keys, requests, concurrency int) (evictionResult, error) {
+	dir, err := os.MkdirTemp("", "redis-bench-eviction-")
+	if err != nil {
+		return evictionResult{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	server, err := redismvp.StartConfig(evictionConfig(policy, maxMemory, dir))
+	if err != nil {
+		return evictionResult{}, fmt.Errorf("start mvp redis server failed: %w", err)
+	}
+	defer func() { _ = server.Close() }()
+	addr := server.Addr()
+	if err = waitUntilReady(addr, 3*time.Second); err != nil {
+		return evictionResult{}, fmt.Errorf("mvp server not ready: %w", err)
+	}
+
+	jobs := make(chan struct{}, requests)
+	for range requests {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	type workerOut struct {
+		gets, hits, errors int
+		err                error
+	}
+	outs := make(chan workerOut, concurrency)
+	value := strings.Repeat("v", evictionValueSize)
+	var scan atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			var out workerOut
+			defer func() { outs <- out }()
+
+			conn, err := dialRESP(addr)
+			if err != nil {
+				out.err = err
+				return
+			}
+			defer conn.Close()
+
+			gen := newCacheTraffic(int64(workerID+1), keys, wl.scanEvery, &scan)
+			for range jobs {
+				key, cold := gen.next()
+				reply, err := conn.do("GET", key)
+				if err != nil {
+					out.err = err
+					return
+				}
+				switch {
+				case reply.Kind == redisproto.KindError:
+					out.errors++
+					continue
+				case reply.Kind != redisproto.KindNull:
+					if !cold {
+						out.hits++
+					}
+				default:
+					if reply, err = conn.do("SET", key, value); err != nil {
+						out.err = err
+						return
+					}
+					if reply.Kind == redisproto.KindError {
+						out.errors++
+					}
+				}
+				if !cold {
+					out.gets++
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(outs)
+
+	res := evictionResult{
+		Workload:   wl.name,
+		Policy:     policy.String(),
+		Requests:   requests,
+		DurationMs: time.Since(start).Seconds() * 1000.0,
+	}
+	for out := range outs {
+		if out.err != nil {
+			return evictionResult{}, out.err
+		}
+		res.Gets += out.gets
+		res.Hits += out.hits
+		res.Errors += out.errors
+	}
+	if res.Gets > 0 {
+		res.HitRate = float64(res.Hits) / float64(res.Gets)
+	}
+	return res, nil
+}
+
+// cacheTraffic generates the keys of one worker: zipfian keys, and every
+// scanEvery-th request the next key of a scan shared by all workers.
+type cacheTraffic struct {
+	zipf      *rand.Zipf
+	scanEvery int
+	scan      *atomic.Int64
+	n         int
+}
+
+func newCacheTraffic(seed int64, keys, scanEvery int, scan *atomic.Int64) *cacheTraffic {
+	rng := rand.New(rand.NewSource(seed))
+	return &cacheTraffic{
+		zipf:      rand.NewZipf(rng, zipfS, 1, uint64(keys-1)),
+		scanEvery: scanEvery,
+		scan:      scan,
+	}
+}
+
+// next returns the next key, and whether it belongs to the scan.
+func (t *cacheTraffic) next() (string, bool) {
+	t.n++
+	if t.scanEvery > 0 && t.n%t.scanEvery == 0 {
+		return fmt.Sprintf("bench:scan:%09d", t.scan.Add(1)), true
+	}
+	return zipfKey(t.zipf.Uint64()), false
+}
+
+func zipfKey(rank uint64) string {
+	return fmt.Sprintf("bench:zipf:%09d", rank)
+}
+
+// lfuBeatsLRU reports whether allkeys-lfu hit at least as often as
+// allkeys-lru on every workload.
+func lfuBeatsLRU(results []evictionResult) bool {
+	rates := make(map[string]map[string]float64)
+	for _, r := range results {
+		if rates[r.Workload] == nil {
+			rates[r.Workload] = make(map[string]float64)
+		}
+		rates[r.Workload][r.Policy] = r.HitRate
+	}
+	lru, lfu := redismvp.MaxMemoryAllKeysLRU.String(), redismvp.MaxMemoryAllKeysLFU.String()
+	for _, byPolicy := range rates {
+		if byPolicy[lfu] < byPolicy[lru] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeEvictionReport(report evictionReport) error {
+	if err := os.MkdirAll(reportDir, 0o755); err != nil {
+		return fmt.Errorf("create reports dir failed: %w", err)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal eviction report failed: %w", err)
+	}
+	ts := report.GeneratedAt.Format("20060102-150405")
+	path := filepath.Join(reportDir, fmt.Sprintf("eviction-%s.json", ts))
+	if err = os.WriteFile(path, blob, 0o644); err != nil {
+		return fmt.Errorf("write eviction report failed: %w", err)
+	}
+	_, _ = fmt.Printf("wrote eviction report: %s\n", path)
+	return nil
+}
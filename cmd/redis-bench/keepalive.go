@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keepalive scenarios come in pairs that run the same mix twice: once over
+// one persistent connection per worker and once dialing a new connection
+// for every request. Their throughput ratio isolates what connection setup
+// costs each target, which is where the accept path of the MVP server
+// differs most from redis-server.
+const (
+	keepaliveSuffix = "_keepalive"
+	reconnectSuffix = "_reconnect"
+)
+
+// keepaliveMixes are the mixes run as keepalive/reconnect pairs.
+var keepaliveMixes = []scenario{
+	{name: "ping_only", description: "100% PING", mix: []operation{{name: "PING", weight: 100}}},
+	{name: "read_heavy", description: "70% GET + 30% SET", mix: []operation{{name: "GET", weight: 70}, {name: "SET", weight: 30}}},
+}
+
+// connectionReuse compares the two scenarios of a keepalive pair on one
+// target.
+type connectionReuse struct {
+	Target       string  `json:"target"`
+	Mix          string  `json:"mix"`
+	KeepaliveRPS float64 `json:"keepalive_rps"`
+	ReconnectRPS float64 `json:"reconnect_rps"`
+	// Speedup is KeepaliveRPS / ReconnectRPS, or zero if the reconnect
+	// scenario completed nothing.
+	Speedup float64 `json:"speedup"`
+}
+
+// keepaliveScenarios returns the keepalive/reconnect pair of every mix in
+// keepaliveMixes.
+func keepaliveScenarios() []scenario {
+	out := make([]scenario, 0, 2*len(keepaliveMixes))
+	for _, m := range keepaliveMixes {
+		out = append(out,
+			scenario{
+				name:        m.name + keepaliveSuffix,
+				description: m.description + ", one persistent connection per worker",
+				mix:         m.mix,
+				run: func(addr string, sc scenario, requests, concurrency int) (scenarioResult, error) {
+					return runPipelined(addr, sc, requests, concurrency, 1)
+				},
+			},
+			scenario{
+				name:        m.name + reconnectSuffix,
+				description: m.description + ", new connection per request",
+				mix:         m.mix,
+				run:         runScenario,
+			},
+		)
+	}
+	return out
+}
+
+// buildConnectionReuse pairs up the keepalive and reconnect results of
+// every target. Mixes missing either half are skipped.
+func buildConnectionReuse(targets []targetReport) []connectionReuse {
+	var out []connectionReuse
+	for _, target := range targets {
+		byName := make(map[string]scenarioResult, len(target.Scenarios))
+		for _, s := range target.Scenarios {
+			byName[s.Scenario] = s
+		}
+		for _, s := range target.Scenarios {
+			mix, ok := strings.CutSuffix(s.Scenario, keepaliveSuffix)
+			if !ok {
+				continue
+			}
+			r, ok := byName[mix+reconnectSuffix]
+			if !ok {
+				continue
+			}
+			speedup := 0.0
+			if r.Throughput > 0 {
+				speedup = s.Throughput / r.Throughput
+			}
+			out = append(out, connectionReuse{
+				Target:       target.Target,
+				Mix:          mix,
+				KeepaliveRPS: s.Throughput,
+				ReconnectRPS: r.Throughput,
+				Speedup:      speedup,
+			})
+		}
+	}
+	return out
+}
+
+// renderConnectionReuse writes the keepalive/reconnect table, if there is
+// anything to put in it.
+func renderConnectionReuse(b *strings.Builder, pairs []connectionReuse) {
+	if len(pairs) == 0 {
+		return
+	}
+	b.WriteString("## Keepalive vs Reconnect\n\n")
+	b.WriteString("target | mix | keepalive rps | reconnect rps | speedup\n")
+	b.WriteString("---|---|---:|---:|---:\n")
+	for _, p := range pairs {
+		_, _ = fmt.Fprintf(b, "%s | %s | %.1f | %.1f | %.2f\n",
+			p.Target, p.Mix, p.KeepaliveRPS, p.ReconnectRPS, p.Speedup)
+	}
+	b.WriteByte('\n')
+}
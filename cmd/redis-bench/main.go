@@ -44,6 +44,9 @@ type scenario struct {
 	name        string
 	description string
 	mix         []operation
+	// run overrides the default request/response runner for scenarios
+	// that need a different traffic shape than a weighted command mix.
+	run func(addr string, sc scenario, requests, concurrency int) (scenarioResult, error)
 }
 
 type scenarioResult struct {
@@ -57,6 +60,27 @@ type scenarioResult struct {
 	P95Ms       float64 `json:"p95_ms"`
 	P99Ms       float64 `json:"p99_ms"`
 	Errors      int     `json:"errors"`
+
+	// Pub/Sub scenarios only: messages each subscriber should have received
+	// in total versus what actually arrived. Latency percentiles measure
+	// publish-to-delivery time for these scenarios.
+	ExpectedDeliveries int `json:"expected_deliveries,omitempty"`
+	Delivered          int `json:"delivered,omitempty"`
+
+	// ConnectErrors counts failed dials, kept apart from Errors so accept
+	// path problems are visible on their own.
+	ConnectErrors int `json:"connect_errors,omitempty"`
+
+	// Violations counts replies that arrived but were wrong, kept apart
+	// from Errors, which are network failures. Only the verify_responses
+	// scenario checks replies.
+	Violations int `json:"violations,omitempty"`
+}
+
+// deliveryComplete reports whether every expected Pub/Sub message arrived.
+// Non Pub/Sub scenarios are always complete.
+func (r scenarioResult) deliveryComplete() bool {
+	return r.Delivered == r.ExpectedDeliveries
 }
 
 type targetReport struct {
@@ -83,16 +107,30 @@ type comparison struct {
 	ReferenceP99Ms      float64 `json:"reference_p99_ms"`
 	MVPErrorCount       int     `json:"mvp_error_count"`
 	ReferenceErrorCount int     `json:"reference_error_count"`
+	MVPConnectErrors    int     `json:"mvp_connect_errors"`
+	RefConnectErrors    int     `json:"reference_connect_errors"`
+	DeliveryComplete    bool    `json:"delivery_complete"`
+	MVPViolations       int     `json:"mvp_violations"`
+	RefViolations       int     `json:"reference_violations"`
 }
 
 type benchmarkReport struct {
-	GeneratedAt time.Time      `json:"generated_at"`
-	Requests    int            `json:"requests"`
-	Concurrency int            `json:"concurrency"`
-	Gates       gateConfig     `json:"gates"`
-	Targets     []targetReport `json:"targets"`
-	Comparisons []comparison   `json:"comparisons"`
-	Command     string         `json:"command"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Requests    int              `json:"requests"`
+	Concurrency int              `json:"concurrency"`
+	Gates       gateConfig       `json:"gates"`
+	Targets     []targetReport   `json:"targets"`
+	Comparisons []comparison     `json:"comparisons"`
+	Command     string           `json:"command"`
+	Scenarios   []scenarioInfo   `json:"scenario_info,omitempty"`
+	Environment benchEnvironment `json:"environment"`
+	// ConnectionReuse compares the keepalive/reconnect scenario pairs.
+	ConnectionReuse []connectionReuse `json:"connection_reuse,omitempty"`
+}
+
+type scenarioInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 func main() {
@@ -120,7 +158,11 @@ func main() {
 
 func usage() {
 	_, _ = fmt.Fprintln(os.Stderr, "usage:")
-	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --requests 2000 --concurrency 30")
+	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --requests 2000 --concurrency 30 [--pubsub --publishers 4 --subscribers 16] [--verify]")
+	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --matrix [--matrix-procs 1,4 --matrix-pipeline 1,16 --matrix-aof off,everysec]")
+	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --eviction --requests 200000 [--eviction-keys 20000 --eviction-cache-ratio 0.05]")
+	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --open-loop [--open-rates 5000,10000,20000 --open-step 5s --slo-p99 1ms,5ms]")
+	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench compare --soak 30m [--soak-interval 30s --soak-max-growth 0.10]")
 	_, _ = fmt.Fprintln(os.Stderr, "  redis-bench report")
 }
 
@@ -128,18 +170,85 @@ func runCompare(args []string) error {
 	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
 	requests := fs.Int("requests", 2000, "total requests per scenario")
 	concurrency := fs.Int("concurrency", 30, "number of concurrent workers")
+	churn := fs.Bool("churn", true, "include the connection churn scenario")
+	keepalive := fs.Bool("keepalive", true, "include the keepalive vs reconnect scenario pairs")
+	pubsub := fs.Bool("pubsub", false, "include the Pub/Sub fanout scenario (requires PUBLISH/SUBSCRIBE on both targets)")
+	publishers := fs.Int("publishers", 4, "pubsub scenario: number of publishing connections")
+	subscribers := fs.Int("subscribers", 16, "pubsub scenario: number of subscribing connections")
+	verify := fs.Bool("verify", false, "include the verify_responses scenario, which checks every reply and reports wrong ones as violations")
+	soak := fs.Duration("soak", 0, "run a soak test of the MVP server for this long instead of comparing (e.g. 30m)")
+	soakInterval := fs.Duration("soak-interval", 30*time.Second, "soak: time between RSS and callback samples")
+	soakMaxGrowth := fs.Float64("soak-max-growth", 0.10, "soak: growth over the run, as a fraction, above which monotonic growth fails the gate")
+	matrix := fs.Bool("matrix", false, "benchmark MVP server variants against each other instead of comparing with redis-server")
+	matrixProcs := fs.String("matrix-procs", "1,4", "matrix: comma-separated GOMAXPROCS values")
+	matrixPipeline := fs.String("matrix-pipeline", "1,16", "matrix: comma-separated client pipeline depths")
+	matrixAOF := fs.String("matrix-aof", "off,everysec", "matrix: comma-separated appendfsync policies, or off")
+	eviction := fs.Bool("eviction", false, "compare the hit rates of the MVP server's maxmemory policies instead of comparing with redis-server")
+	evictionKeys := fs.Int("eviction-keys", 20000, "eviction: number of distinct zipfian keys")
+	evictionCacheRatio := fs.Float64("eviction-cache-ratio", 0.05, "eviction: fraction of the keys maxmemory is sized for")
+	openLoop := fs.Bool("open-loop", false, "offer fixed request rates and report the max throughput each target sustains under p99 objectives")
+	openRates := fs.String("open-rates", "5000,10000,20000,40000,80000", "open-loop: comma-separated request rates to offer, in requests per second")
+	openStep := fs.Duration("open-step", 5*time.Second, "open-loop: time each rate is offered for")
+	sloP99 := fs.String("slo-p99", "1ms,5ms", "open-loop: comma-separated p99 latency objectives to summarize")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	if *requests <= 0 || *concurrency <= 0 {
 		return errors.New("requests and concurrency must be > 0")
 	}
+	if *soak > 0 {
+		return runSoak(*soak, *soakInterval, *soakMaxGrowth, *requests, *concurrency)
+	}
+	if *openLoop {
+		rates, err := parseIntList(*openRates)
+		if err != nil {
+			return fmt.Errorf("--open-rates: %w", err)
+		}
+		slos, err := parseDurationList(*sloP99)
+		if err != nil {
+			return fmt.Errorf("--slo-p99: %w", err)
+		}
+		return runOpenLoop(rates, *openStep, slos, *requests, *concurrency)
+	}
+	if *eviction {
+		return runEviction(*evictionKeys, *evictionCacheRatio, *requests, *concurrency)
+	}
+	if *matrix {
+		procs, err := parseIntList(*matrixProcs)
+		if err != nil {
+			return fmt.Errorf("--matrix-procs: %w", err)
+		}
+		pipelines, err := parseIntList(*matrixPipeline)
+		if err != nil {
+			return fmt.Errorf("--matrix-pipeline: %w", err)
+		}
+		fsyncs, err := parseFsyncList(*matrixAOF)
+		if err != nil {
+			return fmt.Errorf("--matrix-aof: %w", err)
+		}
+		return runMatrix(matrixVariants(procs, pipelines, fsyncs), *requests, *concurrency)
+	}
+	if *publishers <= 0 || *subscribers <= 0 {
+		return errors.New("publishers and subscribers must be > 0")
+	}
 
 	scenarios := []scenario{
 		{name: "ping_only", description: "100% PING", mix: []operation{{name: "PING", weight: 100}}},
 		{name: "read_heavy", description: "70% GET + 30% SET", mix: []operation{{name: "GET", weight: 70}, {name: "SET", weight: 30}}},
 		{name: "write_heavy", description: "80% SET + 20% GET", mix: []operation{{name: "SET", weight: 80}, {name: "GET", weight: 20}}},
 	}
+	if *churn {
+		scenarios = append(scenarios, churnScenario())
+	}
+	if *keepalive {
+		scenarios = append(scenarios, keepaliveScenarios()...)
+	}
+	if *pubsub {
+		scenarios = append(scenarios, pubsubScenario(*publishers, *subscribers))
+	}
+	if *verify {
+		scenarios = append(scenarios, verifyScenario())
+	}
 
 	mvpServer, err := redismvp.Start(fmt.Sprintf("127.0.0.1:%d", defaultMVPort))
 	if err != nil {
@@ -184,9 +293,14 @@ func runCompare(args []string) error {
 			{Target: "libxev-go-mvp", Addr: mvpAddr, Scenarios: mvpResults},
 			{Target: "redis-server", Addr: refAddr, Scenarios: refResults},
 		},
-		Command: strings.Join(os.Args, " "),
+		Command:     strings.Join(os.Args, " "),
+		Environment: captureEnvironment(mvpServerInfo("libxev-go-mvp", redismvp.Config{}), referenceServerInfo()),
+	}
+	for _, sc := range scenarios {
+		report.Scenarios = append(report.Scenarios, scenarioInfo{Name: sc.name, Description: sc.description})
 	}
 	report.Comparisons = buildComparisons(report.Gates, mvpResults, refResults)
+	report.ConnectionReuse = buildConnectionReuse(report.Targets)
 
 	if err := writeReport(report); err != nil {
 		return err
@@ -228,7 +342,11 @@ func benchmarkTarget(addr, target string, scenarios []scenario, requests, concur
 
 	results := make([]scenarioResult, 0, len(scenarios))
 	for _, sc := range scenarios {
-		res, err := runScenario(addr, sc, requests, concurrency)
+		run := runScenario
+		if sc.run != nil {
+			run = sc.run
+		}
+		res, err := run(addr, sc, requests, concurrency)
 		if err != nil {
 			return nil, err
 		}
@@ -346,11 +464,7 @@ func execOnce(addr string, args []string) (redisproto.Value, error) {
 	defer conn.Close()
 	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
 
-	cmd := make([]redisproto.Value, 0, len(args))
-	for _, arg := range args {
-		cmd = append(cmd, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(arg)})
-	}
-	wire, err := redisproto.Encode(redisproto.Value{Kind: redisproto.KindArray, Array: cmd})
+	wire, err := redisproto.Encode(buildCommand(args))
 	if err != nil {
 		return redisproto.Value{}, err
 	}
@@ -403,11 +517,7 @@ func startReferenceRedis(port int) (*exec.Cmd, error) {
 		return nil, fmt.Errorf("redis-server binary not found; install redis-server or set REDIS_SERVER_BIN: %w", err)
 	}
 
-	cmd := exec.Command(bin,
-		"--port", fmt.Sprintf("%d", port),
-		"--save", "",
-		"--appendonly", "no",
-	)
+	cmd := exec.Command(bin, append([]string{"--port", fmt.Sprintf("%d", port)}, referenceRedisArgs...)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Start(); err != nil {
@@ -489,19 +599,26 @@ func buildComparisons(gates gateConfig, mvp, ref []scenarioResult) []comparison
 		}
 		thrPass := thrRatio >= gates.MinThroughputRatio
 		p99Pass := p99Ratio <= gates.MaxP99Ratio
+		complete := m.deliveryComplete() && r.deliveryComplete()
+		correct := m.Violations == 0 && r.Violations == 0
 		out = append(out, comparison{
 			Scenario:            m.Scenario,
 			ThroughputRatio:     thrRatio,
 			P99Ratio:            p99Ratio,
 			ThroughputPass:      thrPass,
 			P99Pass:             p99Pass,
-			OverallPass:         thrPass && p99Pass,
+			OverallPass:         thrPass && p99Pass && complete && correct,
 			MVPThroughputRPS:    m.Throughput,
 			RefThroughputRPS:    r.Throughput,
 			MVPP99Ms:            m.P99Ms,
 			ReferenceP99Ms:      r.P99Ms,
 			MVPErrorCount:       m.Errors,
 			ReferenceErrorCount: r.Errors,
+			MVPConnectErrors:    m.ConnectErrors,
+			RefConnectErrors:    r.ConnectErrors,
+			DeliveryComplete:    complete,
+			MVPViolations:       m.Violations,
+			RefViolations:       r.Violations,
 		})
 	}
 	return out
@@ -522,6 +639,11 @@ func printComparison(report benchmarkReport) {
 			c.OverallPass,
 		)
 	}
+	if len(report.ConnectionReuse) > 0 {
+		var b strings.Builder
+		renderConnectionReuse(&b, report.ConnectionReuse)
+		_, _ = fmt.Print("\n", b.String())
+	}
 }
 
 func renderMarkdown(report benchmarkReport) string {
@@ -532,9 +654,12 @@ func renderMarkdown(report benchmarkReport) string {
 	_, _ = fmt.Fprintf(&b, "Concurrency: %d\\n\\n", report.Concurrency)
 
 	b.WriteString("## Scenarios\n\n")
-	b.WriteString("- ping_only: 100% PING\n")
-	b.WriteString("- read_heavy: 70% GET + 30% SET\n")
-	b.WriteString("- write_heavy: 80% SET + 20% GET\n\n")
+	for _, sc := range reportScenarios(report) {
+		_, _ = fmt.Fprintf(&b, "- %s: %s\n", sc.Name, sc.Description)
+	}
+	b.WriteByte('\n')
+
+	renderEnvironment(&b, report.Environment)
 
 	b.WriteString("## Gates\n\n")
 	_, _ = fmt.Fprintf(&b, "- throughput ratio >= %.2f\\n", report.Gates.MinThroughputRatio)
@@ -559,23 +684,58 @@ func renderMarkdown(report benchmarkReport) string {
 	b.WriteString("\n## Target Details\n\n")
 	for _, target := range report.Targets {
 		_, _ = fmt.Fprintf(&b, "### %s (%s)\\n\\n", target.Target, target.Addr)
-		b.WriteString("scenario | throughput rps | p50 ms | p95 ms | p99 ms | errors\n")
-		b.WriteString("---|---:|---:|---:|---:|---:\n")
+		b.WriteString("scenario | throughput rps | p50 ms | p95 ms | p99 ms | errors | connect errors | violations\n")
+		b.WriteString("---|---:|---:|---:|---:|---:|---:|---:\n")
 		for _, s := range target.Scenarios {
-			_, _ = fmt.Fprintf(&b, "%s | %.1f | %.3f | %.3f | %.3f | %d\\n",
+			_, _ = fmt.Fprintf(&b, "%s | %.1f | %.3f | %.3f | %.3f | %d | %d | %d\\n",
 				s.Scenario,
 				s.Throughput,
 				s.P50Ms,
 				s.P95Ms,
 				s.P99Ms,
 				s.Errors,
+				s.ConnectErrors,
+				s.Violations,
 			)
 		}
 		b.WriteByte('\n')
 	}
+
+	renderConnectionReuse(&b, report.ConnectionReuse)
+
+	var deliveries strings.Builder
+	for _, target := range report.Targets {
+		for _, s := range target.Scenarios {
+			if s.ExpectedDeliveries == 0 {
+				continue
+			}
+			_, _ = fmt.Fprintf(&deliveries, "%s | %s | %d | %d | %t\n",
+				target.Target, s.Scenario, s.ExpectedDeliveries, s.Delivered, s.deliveryComplete())
+		}
+	}
+	if deliveries.Len() > 0 {
+		b.WriteString("## Pub/Sub Delivery\n\n")
+		b.WriteString("target | scenario | expected | delivered | complete\n")
+		b.WriteString("---|---|---:|---:|---\n")
+		b.WriteString(deliveries.String())
+		b.WriteByte('\n')
+	}
 	return b.String()
 }
 
+// reportScenarios returns the scenario list recorded in report, falling back
+// to the built-in mixes for reports written before scenarios were recorded.
+func reportScenarios(report benchmarkReport) []scenarioInfo {
+	if len(report.Scenarios) > 0 {
+		return report.Scenarios
+	}
+	return []scenarioInfo{
+		{Name: "ping_only", Description: "100% PING"},
+		{Name: "read_heavy", Description: "70% GET + 30% SET"},
+		{Name: "write_heavy", Description: "80% SET + 20% GET"},
+	}
+}
+
 func percentile(sorted []float64, p float64) float64 {
 	if len(sorted) == 0 {
 		return 0
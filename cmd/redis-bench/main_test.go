@@ -6,8 +6,15 @@
 package main
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redismvp"
+	"github.com/crrow/libxev-go/pkg/redisproto"
 )
 
 func TestPickOperationWeighted(t *testing.T) {
@@ -58,12 +65,39 @@ func TestBuildComparisons(t *testing.T) {
 		ReferenceP99Ms:      1.0,
 		MVPErrorCount:       0,
 		ReferenceErrorCount: 0,
+		DeliveryComplete:    true,
 	}
 	if !reflect.DeepEqual(out[0], want) {
 		t.Fatalf("comparison mismatch: got=%+v want=%+v", out[0], want)
 	}
 }
 
+func TestBuildComparisonsIncompleteDelivery(t *testing.T) {
+	g := gateConfig{MinThroughputRatio: 0.7, MaxP99Ratio: 1.5}
+	mvp := []scenarioResult{{Scenario: "pubsub_fanout", Throughput: 1000, P99Ms: 1.0, ExpectedDeliveries: 100, Delivered: 99}}
+	ref := []scenarioResult{{Scenario: "pubsub_fanout", Throughput: 1000, P99Ms: 1.0, ExpectedDeliveries: 100, Delivered: 100}}
+
+	out := buildComparisons(g, mvp, ref)
+	if len(out) != 1 {
+		t.Fatalf("unexpected comparison size: %d", len(out))
+	}
+	if out[0].DeliveryComplete || out[0].OverallPass {
+		t.Fatalf("missing deliveries must fail the gate: %+v", out[0])
+	}
+}
+
+func TestParsePubSubMessage(t *testing.T) {
+	msg := buildCommand([]string{"message", "bench:pubsub", "12345"})
+	sent, ok := parsePubSubMessage(msg)
+	if !ok || sent != 12345 {
+		t.Fatalf("parsePubSubMessage = %d, %t", sent, ok)
+	}
+
+	if _, ok = parsePubSubMessage(buildCommand([]string{"subscribe", "bench:pubsub", "1"})); ok {
+		t.Fatal("subscribe confirmation must not count as a delivery")
+	}
+}
+
 func deterministicPick(ops []operation, seed int) string {
 	// deterministic proxy without depending on random internals.
 	total := 0
@@ -80,3 +114,304 @@ func deterministicPick(ops []operation, seed int) string {
 	}
 	return ops[len(ops)-1].name
 }
+
+func TestBuildComparisonsCarriesConnectErrors(t *testing.T) {
+	g := gateConfig{MinThroughputRatio: 0.7, MaxP99Ratio: 1.5}
+	mvp := []scenarioResult{{Scenario: "connection_churn", Throughput: 1000, P99Ms: 1.0, ConnectErrors: 3}}
+	ref := []scenarioResult{{Scenario: "connection_churn", Throughput: 1000, P99Ms: 1.0}}
+
+	out := buildComparisons(g, mvp, ref)
+	if len(out) != 1 {
+		t.Fatalf("unexpected comparison size: %d", len(out))
+	}
+	if out[0].MVPConnectErrors != 3 || out[0].RefConnectErrors != 0 {
+		t.Fatalf("connect errors not carried: %+v", out[0])
+	}
+}
+
+func TestVerifierCheck(t *testing.T) {
+	v := newVerifier(0)
+	bulk := func(s string) redisproto.Value {
+		return redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(s)}
+	}
+	integer := func(n int64) redisproto.Value {
+		return redisproto.Value{Kind: redisproto.KindInteger, Int: n}
+	}
+	ok := redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"}
+	null := redisproto.Value{Kind: redisproto.KindNull}
+
+	steps := []struct {
+		cmd   []string
+		reply redisproto.Value
+		want  bool
+	}{
+		{v.command("GET", 1), null, true},
+		{v.command("GET", 1), bulk("stale"), false},
+		{v.command("SET", 1), ok, true},
+		{v.command("GET", 1), bulk("value:1"), true},
+		{v.command("GET", 1), bulk("value:2"), false},
+		{v.command("INCR", 1), integer(1), true},
+		{v.command("INCR", 1), integer(2), true},
+		{v.command("INCR", 1), integer(2), false},
+		{v.command("INCR", 1), integer(3), true},
+		{v.command("INCR", 1), redisproto.Value{Kind: redisproto.KindError, Str: "ERR"}, false},
+	}
+	for i, step := range steps {
+		if got := v.check(step.cmd, step.reply); got != step.want {
+			t.Fatalf("step %d: check(%q) = %t, want %t", i, step.cmd, got, step.want)
+		}
+	}
+
+	// After a network failure the key's state is unknown, so the next
+	// reply is accepted and becomes the new baseline.
+	v.forget(v.command("INCR", 1))
+	if !v.check(v.command("INCR", 1), integer(10)) {
+		t.Fatal("reply after a failed INCR should be accepted")
+	}
+	if v.check(v.command("INCR", 1), integer(12)) {
+		t.Fatal("skipped count after resync should be a violation")
+	}
+}
+
+func TestBuildComparisonsFailsOnViolations(t *testing.T) {
+	g := gateConfig{MinThroughputRatio: 0.7, MaxP99Ratio: 1.5}
+	mvp := []scenarioResult{{Scenario: "verify_responses", Throughput: 1000, P99Ms: 1.0, Violations: 2}}
+	ref := []scenarioResult{{Scenario: "verify_responses", Throughput: 1000, P99Ms: 1.0}}
+
+	out := buildComparisons(g, mvp, ref)
+	if len(out) != 1 {
+		t.Fatalf("unexpected comparison size: %d", len(out))
+	}
+	if out[0].OverallPass || out[0].MVPViolations != 2 || out[0].RefViolations != 0 {
+		t.Fatalf("violations not gated: %+v", out[0])
+	}
+}
+
+func TestBuildConnectionReuse(t *testing.T) {
+	targets := []targetReport{
+		{Target: "libxev-go-mvp", Scenarios: []scenarioResult{
+			{Scenario: "ping_only", Throughput: 500},
+			{Scenario: "ping_only_keepalive", Throughput: 4000},
+			{Scenario: "ping_only_reconnect", Throughput: 1000},
+			{Scenario: "read_heavy_keepalive", Throughput: 3000},
+		}},
+		{Target: "redis-server", Scenarios: []scenarioResult{
+			{Scenario: "ping_only_keepalive", Throughput: 5000},
+			{Scenario: "ping_only_reconnect", Throughput: 0},
+		}},
+	}
+	got := buildConnectionReuse(targets)
+	want := []connectionReuse{
+		{Target: "libxev-go-mvp", Mix: "ping_only", KeepaliveRPS: 4000, ReconnectRPS: 1000, Speedup: 4},
+		{Target: "redis-server", Mix: "ping_only", KeepaliveRPS: 5000, ReconnectRPS: 0, Speedup: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("connection reuse mismatch: got=%+v want=%+v", got, want)
+	}
+
+	md := renderMarkdown(benchmarkReport{ConnectionReuse: got})
+	if !strings.Contains(md, "libxev-go-mvp | ping_only | 4000.0 | 1000.0 | 4.00\n") {
+		t.Fatalf("markdown lacks the keepalive row:\n%s", md)
+	}
+}
+
+func TestKeepaliveScenariosArePaired(t *testing.T) {
+	names := make(map[string]bool)
+	for _, sc := range keepaliveScenarios() {
+		if sc.run == nil {
+			t.Fatalf("scenario %s has no runner", sc.name)
+		}
+		names[sc.name] = true
+	}
+	for _, m := range keepaliveMixes {
+		if !names[m.name+keepaliveSuffix] || !names[m.name+reconnectSuffix] {
+			t.Fatalf("mix %s is not paired: %v", m.name, names)
+		}
+	}
+}
+
+func TestGrowsMonotonically(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float64
+		want    bool
+	}{
+		{name: "too few samples", samples: []float64{1, 100}, want: false},
+		{name: "steady growth", samples: []float64{100, 110, 120, 130}, want: true},
+		{name: "plateau", samples: []float64{100, 120, 120, 119}, want: false},
+		{name: "small drift", samples: []float64{100, 101, 102, 105}, want: false},
+		{name: "from zero", samples: []float64{0, 1, 2, 3}, want: true},
+		{name: "flat zero", samples: []float64{0, 0, 0}, want: false},
+	}
+	for _, tt := range tests {
+		if got := growsMonotonically(tt.samples, 0.10); got != tt.want {
+			t.Fatalf("%s: growsMonotonically(%v) = %t, want %t", tt.name, tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestParseVmRSS(t *testing.T) {
+	status := []byte("Name:\tredis-bench\nVmPeak:\t  20000 kB\nVmRSS:\t   1234 kB\nThreads:\t8\n")
+	if got := parseVmRSS(status); got != 1234*1024 {
+		t.Fatalf("parseVmRSS = %d", got)
+	}
+	if got := parseVmRSS([]byte("Name:\tx\n")); got != 0 {
+		t.Fatalf("parseVmRSS without VmRSS = %d", got)
+	}
+}
+
+func TestMatrixVariants(t *testing.T) {
+	procs, err := parseIntList("1, 4")
+	if err != nil {
+		t.Fatalf("parseIntList failed: %v", err)
+	}
+	fsyncs, err := parseFsyncList("off,EverySec")
+	if err != nil {
+		t.Fatalf("parseFsyncList failed: %v", err)
+	}
+	got := matrixVariants(procs, []int{16}, fsyncs)
+	want := []serverVariant{
+		{Procs: 1, Pipeline: 16, AppendFsync: "off"},
+		{Procs: 1, Pipeline: 16, AppendFsync: "everysec"},
+		{Procs: 4, Pipeline: 16, AppendFsync: "off"},
+		{Procs: 4, Pipeline: 16, AppendFsync: "everysec"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("matrixVariants = %v, want %v", got, want)
+	}
+
+	if _, err := parseIntList("1,0"); err == nil {
+		t.Fatal("parseIntList accepted 0")
+	}
+	if _, err := parseFsyncList("sometimes"); err == nil {
+		t.Fatal("parseFsyncList accepted an unknown policy")
+	}
+}
+
+func TestCacheTraffic(t *testing.T) {
+	var scan atomic.Int64
+	gen := newCacheTraffic(1, 100, 5, &scan)
+	scanned := 0
+	for i := 1; i <= 50; i++ {
+		key, cold := gen.next()
+		if cold != (i%5 == 0) {
+			t.Fatalf("request %d: key %q cold = %v", i, key, cold)
+		}
+		if cold {
+			scanned++
+			if want := fmt.Sprintf("bench:scan:%09d", scanned); key != want {
+				t.Fatalf("scan key %q, want %q", key, want)
+			}
+		} else if !strings.HasPrefix(key, "bench:zipf:") || key > zipfKey(99) {
+			t.Fatalf("zipfian key %q out of range", key)
+		}
+	}
+}
+
+func TestLFUBeatsLRU(t *testing.T) {
+	results := []evictionResult{
+		{Workload: "zipf", Policy: "allkeys-lru", HitRate: 0.60},
+		{Workload: "zipf", Policy: "allkeys-lfu", HitRate: 0.62},
+		{Workload: "zipf_scan", Policy: "allkeys-lru", HitRate: 0.40},
+		{Workload: "zipf_scan", Policy: "allkeys-lfu", HitRate: 0.55},
+	}
+	if !lfuBeatsLRU(results) {
+		t.Fatal("gate failed with LFU ahead on every workload")
+	}
+	results[1].HitRate = 0.59
+	if lfuBeatsLRU(results) {
+		t.Fatal("gate passed with LFU behind on zipf")
+	}
+}
+
+func TestMaxSustainedRate(t *testing.T) {
+	steps := []openLoopStep{
+		{RateRPS: 1000, Completed: 5000, P99Ms: 0.4},
+		{RateRPS: 2000, Completed: 10000, P99Ms: 0.9},
+		{RateRPS: 4000, Completed: 20000, P99Ms: 3},
+		// Noise can let a higher rate pass after a failure; the failure
+		// still caps the result.
+		{RateRPS: 8000, Completed: 40000, P99Ms: 0.8},
+	}
+	for _, tc := range []struct {
+		slo  time.Duration
+		want int
+	}{
+		{100 * time.Microsecond, 0},
+		{time.Millisecond, 2000},
+		{5 * time.Millisecond, 8000},
+	} {
+		if got := maxSustainedRate(steps, tc.slo); got != tc.want {
+			t.Errorf("slo %s: max rate %d, want %d", tc.slo, got, tc.want)
+		}
+	}
+
+	// Falling behind the offered rate or failing requests is not sustained,
+	// whatever the latency.
+	steps[1].Overloaded = true
+	if got := maxSustainedRate(steps, 5*time.Millisecond); got != 1000 {
+		t.Errorf("overloaded step: max rate %d, want 1000", got)
+	}
+	steps[0].Errors = 1
+	if got := maxSustainedRate(steps, 5*time.Millisecond); got != 0 {
+		t.Errorf("failing step: max rate %d, want 0", got)
+	}
+}
+
+func TestParseDurationList(t *testing.T) {
+	got, err := parseDurationList("1ms, 500us,2s")
+	if want := []time.Duration{time.Millisecond, 500 * time.Microsecond, 2 * time.Second}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseDurationList = %v, %v; want %v", got, err, want)
+	}
+	for _, bad := range []string{"", "1ms,", "0s", "-1ms", "fast"} {
+		if _, err := parseDurationList(bad); err == nil {
+			t.Errorf("parseDurationList(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseEnvironmentProbes(t *testing.T) {
+	cpuinfo := "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Intel(R) Xeon(R) CPU @ 2.20GHz\n\nprocessor\t: 1\nmodel name\t: other\n"
+	if got := parseCPUInfo(strings.NewReader(cpuinfo)); got != "Intel(R) Xeon(R) CPU @ 2.20GHz" {
+		t.Errorf("parseCPUInfo = %q", got)
+	}
+	if got := parseCPUInfo(strings.NewReader("processor\t: 0\nHardware\t: BCM2835\n")); got != "" {
+		t.Errorf("parseCPUInfo without a model name = %q", got)
+	}
+	if got := parseRedisVersion("Redis server v=7.2.4 sha=00000000:0 malloc=jemalloc-5.3.0 bits=64 build=1\n"); got != "7.2.4" {
+		t.Errorf("parseRedisVersion = %q", got)
+	}
+}
+
+func TestRenderEnvironment(t *testing.T) {
+	report := benchmarkReport{}
+	if md := renderMarkdown(report); strings.Contains(md, "## Environment") {
+		t.Fatalf("report without an environment rendered one:\n%s", md)
+	}
+
+	report.Environment = benchEnvironment{
+		GitCommit:  "abc123",
+		GitDirty:   true,
+		GoVersion:  "go1.25.0",
+		OS:         "linux/amd64",
+		NumCPU:     8,
+		GOMAXPROCS: 8,
+		Servers: []serverInfo{
+			mvpServerInfo("libxev-go-mvp", redismvp.Config{}),
+			{Target: "redis-server", Version: "7.2.4", Config: map[string]string{"save": "", "appendonly": "no"}},
+		},
+	}
+	md := renderMarkdown(report)
+	for _, want := range []string{
+		"- commit: abc123 (modified)\n",
+		"- go: go1.25.0, linux/amd64\n",
+		"- kernel: unknown\n",
+		"- libxev: unknown\n",
+		`- libxev-go-mvp: appendonly="no" maxclients="10000" maxmemory="0" maxmemory-policy="noeviction" tcp-keepalive="0" tcp-nodelay="no" timeout="0"` + "\n",
+		`- redis-server 7.2.4: appendonly="no" save=""` + "\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md)
+		}
+	}
+}
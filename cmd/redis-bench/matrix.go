@@ -0,0 +1,364 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redismvp"
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// A matrix run benchmarks the MVP server once per combination of the
+// values given for each axis, restarting it with a fresh data directory for
+// every variant, and reports each variant against the first one. The axes
+// are the settings that change how the server spends its time: the number
+// of OS threads running Go code, the client pipeline depth, and the append
+// only file fsync policy ("off" disables the AOF). The server runs a single
+// loop, so there is no loop-count axis.
+
+type serverVariant struct {
+	Procs       int    `json:"procs"`
+	Pipeline    int    `json:"pipeline"`
+	AppendFsync string `json:"appendfsync"`
+}
+
+func (v serverVariant) String() string {
+	return fmt.Sprintf("procs=%d pipeline=%d aof=%s", v.Procs, v.Pipeline, v.AppendFsync)
+}
+
+type variantResult struct {
+	Variant   serverVariant    `json:"variant"`
+	Scenarios []scenarioResult `json:"scenarios"`
+}
+
+type matrixReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Requests    int              `json:"requests"`
+	Concurrency int              `json:"concurrency"`
+	Variants    []variantResult  `json:"variants"`
+	Command     string           `json:"command"`
+	Environment benchEnvironment `json:"environment"`
+}
+
+// matrixVariants returns every combination of the axis values, varying the
+// last axis fastest.
+func matrixVariants(procs, pipelines []int, fsyncs []string) []serverVariant {
+	out := make([]serverVariant, 0, len(procs)*len(pipelines)*len(fsyncs))
+	for _, p := range procs {
+		for _, depth := range pipelines {
+			for _, fsync := range fsyncs {
+				out = append(out, serverVariant{Procs: p, Pipeline: depth, AppendFsync: fsync})
+			}
+		}
+	}
+	return out
+}
+
+// parseIntList parses a comma-separated list of positive integers.
+func parseIntList(s string) ([]int, error) {
+	var out []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid value %q: must be a positive integer", field)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// parseFsyncList parses a comma-separated list of appendfsync policies and
+// "off".
+func parseFsyncList(s string) ([]string, error) {
+	var out []string
+	for _, field := range strings.Split(s, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field != "off" {
+			if _, err := redismvp.ParseAppendFsync(field); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, field)
+	}
+	return out, nil
+}
+
+func runMatrix(variants []serverVariant, requests, concurrency int) error {
+	scenarios := []scenario{
+		{name: "ping_only", description: "100% PING", mix: []operation{{name: "PING", weight: 100}}},
+		{name: "read_heavy", description: "70% GET + 30% SET", mix: []operation{{name: "GET", weight: 70}, {name: "SET", weight: 30}}},
+		{name: "write_heavy", description: "80% SET + 20% GET", mix: []operation{{name: "SET", weight: 80}, {name: "GET", weight: 20}}},
+	}
+
+	report := matrixReport{
+		GeneratedAt: time.Now().UTC(),
+		Requests:    requests,
+		Concurrency: concurrency,
+		Command:     strings.Join(os.Args, " "),
+	}
+	var servers []serverInfo
+	for _, v := range variants {
+		cfg, err := variantConfig(v, "")
+		if err != nil {
+			return err
+		}
+		servers = append(servers, mvpServerInfo(v.String(), cfg))
+	}
+	report.Environment = captureEnvironment(servers...)
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, v := range variants {
+		_, _ = fmt.Printf("benchmarking variant %s\n", v)
+		results, err := benchmarkVariant(v, scenarios, requests, concurrency)
+		if err != nil {
+			return fmt.Errorf("variant %s: %w", v, err)
+		}
+		report.Variants = append(report.Variants, variantResult{Variant: v, Scenarios: results})
+	}
+
+	if err := writeMatrixReport(report); err != nil {
+		return err
+	}
+	_, _ = fmt.Print(renderMatrix(report))
+	return nil
+}
+
+func benchmarkVariant(v serverVariant, scenarios []scenario, requests, concurrency int) ([]scenarioResult, error) {
+	dir, err := os.MkdirTemp("", "redis-bench-matrix-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := variantConfig(v, dir)
+	if err != nil {
+		return nil, err
+	}
+	runtime.GOMAXPROCS(v.Procs)
+	server, err := redismvp.StartConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("start mvp redis server failed: %w", err)
+	}
+	defer func() { _ = server.Close() }()
+	addr := server.Addr()
+	if err = waitUntilReady(addr, 3*time.Second); err != nil {
+		return nil, fmt.Errorf("mvp server not ready: %w", err)
+	}
+	if err = prewarm(addr, 1000); err != nil {
+		return nil, fmt.Errorf("prewarm failed: %w", err)
+	}
+
+	results := make([]scenarioResult, 0, len(scenarios))
+	for _, sc := range scenarios {
+		res, err := runPipelined(addr, sc, requests, concurrency, v.Pipeline)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// variantConfig returns the configuration of a server for v, keeping its
+// files in dir.
+func variantConfig(v serverVariant, dir string) (redismvp.Config, error) {
+	cfg := redismvp.Config{
+		Addr:       "127.0.0.1:0",
+		LogLevel:   redismvp.LevelWarning,
+		DBFilename: filepath.Join(dir, "dump.rdb"),
+	}
+	if v.AppendFsync != "off" {
+		var err error
+		cfg.AppendOnly = true
+		cfg.AppendFilename = filepath.Join(dir, redismvp.DefaultAppendFilename)
+		if cfg.AppendFsync, err = redismvp.ParseAppendFsync(v.AppendFsync); err != nil {
+			return redismvp.Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// runPipelined runs sc over one persistent connection per worker, sending
+// depth commands before reading their replies. Each command's latency is
+// the round trip of its batch.
+func runPipelined(addr string, sc scenario, requests, concurrency, depth int) (scenarioResult, error) {
+	batches := make(chan int, requests/depth+1)
+	for sent := 0; sent < requests; sent += depth {
+		batches <- min(depth, requests-sent)
+	}
+	close(batches)
+
+	type workerOut struct {
+		latencies []float64
+		errors    int
+		err       error
+	}
+	outs := make(chan workerOut, concurrency)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			var out workerOut
+			defer func() { outs <- out }()
+
+			conn, err := dialRESP(addr)
+			if err != nil {
+				out.err = err
+				return
+			}
+			defer conn.Close()
+
+			rng := rand.New(rand.NewSource(int64(workerID + 99)))
+			cmds := make([][]string, 0, depth)
+			for n := range batches {
+				cmds = cmds[:0]
+				for i := 0; i < n; i++ {
+					op := pickOperation(rng, sc.mix)
+					key := fmt.Sprintf("bench:key:%d", rng.Intn(1000))
+					switch op {
+					case "PING":
+						cmds = append(cmds, []string{"PING"})
+					case "SET":
+						cmds = append(cmds, []string{"SET", key, fmt.Sprintf("value:%d", i)})
+					default:
+						cmds = append(cmds, []string{op, key})
+					}
+				}
+				t0 := time.Now()
+				replies, err := conn.pipeline(cmds)
+				elapsed := time.Since(t0).Seconds() * 1000.0
+				if err != nil {
+					out.err = err
+					return
+				}
+				for _, reply := range replies {
+					out.latencies = append(out.latencies, elapsed)
+					if reply.Kind == redisproto.KindError {
+						out.errors++
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(outs)
+
+	dur := time.Since(start)
+	allLat := make([]float64, 0, requests)
+	res := scenarioResult{
+		Scenario:    sc.name,
+		Description: sc.description,
+		Requests:    requests,
+		Concurrency: concurrency,
+		DurationMs:  dur.Seconds() * 1000.0,
+		Throughput:  float64(requests) / dur.Seconds(),
+	}
+	for out := range outs {
+		if out.err != nil {
+			return scenarioResult{}, out.err
+		}
+		allLat = append(allLat, out.latencies...)
+		res.Errors += out.errors
+	}
+	sort.Float64s(allLat)
+	res.P50Ms = percentile(allLat, 50)
+	res.P95Ms = percentile(allLat, 95)
+	res.P99Ms = percentile(allLat, 99)
+	return res, nil
+}
+
+// pipeline sends cmds in one write and returns their replies.
+func (c *respConn) pipeline(cmds [][]string) ([]redisproto.Value, error) {
+	var wire []byte
+	for _, args := range cmds {
+		var err error
+		if wire, err = redisproto.AppendEncode(wire, buildCommand(args)); err != nil {
+			return nil, err
+		}
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write(wire); err != nil {
+		return nil, err
+	}
+	replies := make([]redisproto.Value, 0, len(cmds))
+	for range cmds {
+		reply, err := c.next()
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+func writeMatrixReport(report matrixReport) error {
+	if err := os.MkdirAll(reportDir, 0o755); err != nil {
+		return fmt.Errorf("create reports dir failed: %w", err)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal matrix report failed: %w", err)
+	}
+	ts := report.GeneratedAt.Format("20060102-150405")
+	jsonPath := filepath.Join(reportDir, fmt.Sprintf("matrix-%s.json", ts))
+	if err = os.WriteFile(jsonPath, blob, 0o644); err != nil {
+		return fmt.Errorf("write matrix report failed: %w", err)
+	}
+	mdPath := filepath.Join(reportDir, fmt.Sprintf("matrix-%s.md", ts))
+	if err = os.WriteFile(mdPath, []byte(renderMatrix(report)), 0o644); err != nil {
+		return fmt.Errorf("write matrix markdown failed: %w", err)
+	}
+	_, _ = fmt.Printf("wrote matrix report: %s\n", jsonPath)
+	return nil
+}
+
+// renderMatrix renders one row per variant and scenario, with throughput
+// and p99 relative to the first variant.
+func renderMatrix(report matrixReport) string {
+	var b strings.Builder
+	b.WriteString("# Redis MVP Variant Matrix\n\n")
+	_, _ = fmt.Fprintf(&b, "Generated at: %s UTC, %d requests per scenario, concurrency %d\n\n",
+		report.GeneratedAt.Format(time.RFC3339), report.Requests, report.Concurrency)
+	renderEnvironment(&b, report.Environment)
+	b.WriteString("variant | scenario | rps | p50 ms | p99 ms | errors | rps vs baseline | p99 vs baseline\n")
+	b.WriteString("---|---|---:|---:|---:|---:|---:|---:\n")
+	if len(report.Variants) == 0 {
+		return b.String()
+	}
+	baseline := make(map[string]scenarioResult)
+	for _, s := range report.Variants[0].Scenarios {
+		baseline[s.Scenario] = s
+	}
+	for _, v := range report.Variants {
+		for _, s := range v.Scenarios {
+			base := baseline[s.Scenario]
+			_, _ = fmt.Fprintf(&b, "%s | %s | %.1f | %.3f | %.3f | %d | %.3f | %.3f\n",
+				v.Variant, s.Scenario, s.Throughput, s.P50Ms, s.P99Ms, s.Errors,
+				ratio(s.Throughput, base.Throughput), ratio(s.P99Ms, base.P99Ms))
+		}
+	}
+	return b.String()
+}
+
+func ratio(v, base float64) float64 {
+	if base <= 0 {
+		return 0
+	}
+	return v / base
+}
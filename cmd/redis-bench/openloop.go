@@ -0,0 +1,374 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redismvp"
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// A closed-loop benchmark sends the next request only once the previous
+// reply arrived, so a server that slows down is simply offered less load,
+// and its latency percentiles hide the requests that would have queued up
+// meanwhile. An open-loop run instead offers each target a fixed request
+// rate for a while, stepping through increasing rates, and measures every
+// request from the moment it was due to be sent. The summary answers the
+// question that matters for capacity planning: the highest rate each
+// target sustains with its p99 under a latency objective. A step is not
+// sustained if the target fell behind the offered rate or returned errors.
+
+// openLoopMix is the command mix offered in open-loop steps.
+var openLoopMix = scenario{
+	name:        "read_heavy",
+	description: "70% GET + 30% SET",
+	mix:         []operation{{name: "GET", weight: 70}, {name: "SET", weight: 30}},
+}
+
+// openLoopMinRatio is the fraction of the offered rate a target must
+// complete for a step to count as keeping up.
+const openLoopMinRatio = 0.95
+
+type openLoopStep struct {
+	RateRPS    int     `json:"rate_rps"`
+	Sent       int     `json:"sent"`
+	Completed  int     `json:"completed"`
+	Throughput float64 `json:"throughput_rps"`
+	P50Ms      float64 `json:"p50_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	P999Ms     float64 `json:"p999_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	Errors     int     `json:"errors"`
+	// Overloaded is set when the target completed less than the offered
+	// rate or its backlog of unanswered requests grew past the limit.
+	Overloaded bool `json:"overloaded"`
+}
+
+type openLoopTarget struct {
+	Target string         `json:"target"`
+	Addr   string         `json:"addr"`
+	Closed scenarioResult `json:"closed_loop"`
+	Steps  []openLoopStep `json:"open_loop"`
+}
+
+// sloSummary is the highest offered rate a target sustained with its p99
+// under the objective; zero if none.
+type sloSummary struct {
+	Target     string  `json:"target"`
+	P99SLOMs   float64 `json:"p99_slo_ms"`
+	MaxRateRPS int     `json:"max_rate_rps"`
+}
+
+type openLoopReport struct {
+	GeneratedAt   time.Time        `json:"generated_at"`
+	StepDurationS float64          `json:"step_duration_s"`
+	Connections   int              `json:"connections"`
+	Mix           string           `json:"mix"`
+	Targets       []openLoopTarget `json:"targets"`
+	Summary       []sloSummary     `json:"summary"`
+	Command       string           `json:"command"`
+	Environment   benchEnvironment `json:"environment"`
+}
+
+func runOpenLoop(rates []int, step time.Duration, slos []time.Duration, requests, concurrency int) error {
+	if step <= 0 {
+		return errors.New("open-loop step duration must be > 0")
+	}
+	sort.Ints(rates)
+
+	mvpServer, err := redismvp.Start(fmt.Sprintf("127.0.0.1:%d", defaultMVPort))
+	if err != nil {
+		return fmt.Errorf("start mvp redis server failed: %w", err)
+	}
+	defer func() { _ = mvpServer.Close() }()
+	redisServerCmd, err := startReferenceRedis(defaultRedisServerPort)
+	if err != nil {
+		return err
+	}
+	defer stopCommand(redisServerCmd)
+
+	report := openLoopReport{
+		GeneratedAt:   time.Now().UTC(),
+		StepDurationS: step.Seconds(),
+		Connections:   concurrency,
+		Mix:           openLoopMix.description,
+		Command:       strings.Join(os.Args, " "),
+		Environment:   captureEnvironment(mvpServerInfo("libxev-go-mvp", redismvp.Config{}), referenceServerInfo()),
+	}
+	targets := []openLoopTarget{
+		{Target: "libxev-go-mvp", Addr: mvpServer.Addr()},
+		{Target: "redis-server", Addr: fmt.Sprintf("127.0.0.1:%d", defaultRedisServerPort)},
+	}
+	for _, t := range targets {
+		if err = waitUntilReady(t.Addr, 3*time.Second); err != nil {
+			return fmt.Errorf("%s not ready: %w", t.Target, err)
+		}
+		if err = prewarm(t.Addr, 1000); err != nil {
+			return fmt.Errorf("prewarm %s failed: %w", t.Target, err)
+		}
+		if t.Closed, err = runPipelined(t.Addr, openLoopMix, requests, concurrency, 1); err != nil {
+			return fmt.Errorf("%s closed loop: %w", t.Target, err)
+		}
+		_, _ = fmt.Printf("%-14s closed loop: %.1f rps, p99 %.3f ms\n", t.Target, t.Closed.Throughput, t.Closed.P99Ms)
+		for _, rate := range rates {
+			res, err := runOpenLoopStep(t.Addr, openLoopMix.mix, rate, step, concurrency)
+			if err != nil {
+				return fmt.Errorf("%s at %d rps: %w", t.Target, rate, err)
+			}
+			_, _ = fmt.Printf("%-14s %8d rps offered: %.1f rps, p99 %.3f ms, %d errors, overloaded %t\n",
+				t.Target, rate, res.Throughput, res.P99Ms, res.Errors, res.Overloaded)
+			t.Steps = append(t.Steps, res)
+			// Higher rates would only overload it further.
+			if res.Overloaded {
+				break
+			}
+		}
+		report.Targets = append(report.Targets, t)
+		for _, slo := range slos {
+			report.Summary = append(report.Summary, sloSummary{
+				Target:     t.Target,
+				P99SLOMs:   slo.Seconds() * 1000.0,
+				MaxRateRPS: maxSustainedRate(t.Steps, slo),
+			})
+		}
+	}
+
+	if err := writeOpenLoopReport(report); err != nil {
+		return err
+	}
+	_, _ = fmt.Print(renderOpenLoop(report))
+	return nil
+}
+
+// runOpenLoopStep offers rate requests per second, spread evenly over
+// conns connections, for d. Each connection sends on a fixed schedule
+// whether or not replies have arrived, and a request's latency runs from
+// its scheduled send time to its reply, so time spent queued behind a slow
+// reply counts even when the sender itself fell behind.
+func runOpenLoopStep(addr string, mix []operation, rate int, d time.Duration, conns int) (openLoopStep, error) {
+	interval := time.Duration(float64(time.Second) * float64(conns) / float64(rate))
+	perConn := max(int(d/interval), 1)
+	// A connection whose backlog reaches a second of its traffic, or whose
+	// sender itself runs a second late, is not keeping up; stop offering it
+	// more.
+	const maxLag = time.Second
+	backlog := max(int(maxLag/interval), 16)
+
+	type connOut struct {
+		latencies  []float64
+		sent       int
+		errors     int
+		overloaded bool
+		err        error
+	}
+	outs := make(chan connOut, conns)
+	var wg sync.WaitGroup
+	start := time.Now().Add(10 * time.Millisecond)
+	for c := range conns {
+		wg.Go(func() {
+			var out connOut
+			defer func() { outs <- out }()
+			conn, err := dialRESP(addr)
+			if err != nil {
+				out.err = err
+				return
+			}
+			defer conn.Close()
+
+			// Stagger the connections so their sends interleave.
+			first := start.Add(interval * time.Duration(c) / time.Duration(conns))
+			due := make(chan time.Time, backlog)
+			sendErr := make(chan error, 1)
+			go func() {
+				defer close(due)
+				rng := rand.New(rand.NewSource(int64(c + 99)))
+				var wire []byte
+				var err error
+				for i := range perConn {
+					at := first.Add(interval * time.Duration(i))
+					wait := time.Until(at)
+					if wait > 0 {
+						time.Sleep(wait)
+					}
+					if len(due) == cap(due) || wait < -maxLag {
+						out.overloaded = true
+						return
+					}
+					wire, err = redisproto.AppendEncode(wire[:0], buildCommand(mixCommand(rng, mix, i)))
+					if err == nil {
+						_ = conn.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+						_, err = conn.conn.Write(wire)
+					}
+					if err != nil {
+						sendErr <- err
+						return
+					}
+					due <- at
+					out.sent++
+				}
+			}()
+			for at := range due {
+				_ = conn.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				reply, err := conn.next()
+				if err != nil {
+					out.err = err
+					// Closing the connection fails the sender's next write;
+					// wait for it to stop.
+					_ = conn.Close()
+					for range due {
+					}
+					return
+				}
+				out.latencies = append(out.latencies, time.Since(at).Seconds()*1000.0)
+				if reply.Kind == redisproto.KindError {
+					out.errors++
+				}
+			}
+			select {
+			case out.err = <-sendErr:
+			default:
+			}
+		})
+	}
+	wg.Wait()
+	close(outs)
+	elapsed := time.Since(start)
+
+	res := openLoopStep{RateRPS: rate}
+	var all []float64
+	for out := range outs {
+		if out.err != nil {
+			return openLoopStep{}, out.err
+		}
+		all = append(all, out.latencies...)
+		res.Sent += out.sent
+		res.Errors += out.errors
+		res.Overloaded = res.Overloaded || out.overloaded
+	}
+	sort.Float64s(all)
+	res.Completed = len(all)
+	res.Throughput = float64(res.Completed) / elapsed.Seconds()
+	res.P50Ms = percentile(all, 50)
+	res.P99Ms = percentile(all, 99)
+	res.P999Ms = percentile(all, 99.9)
+	res.MaxMs = percentile(all, 100)
+	if res.Throughput < openLoopMinRatio*float64(rate) {
+		res.Overloaded = true
+	}
+	return res, nil
+}
+
+// mixCommand returns the i-th command of a connection's share of mix.
+func mixCommand(rng *rand.Rand, mix []operation, i int) []string {
+	key := fmt.Sprintf("bench:key:%d", rng.Intn(1000))
+	switch op := pickOperation(rng, mix); op {
+	case "PING":
+		return []string{"PING"}
+	case "SET":
+		return []string{"SET", key, fmt.Sprintf("value:%d", i)}
+	default:
+		return []string{op, key}
+	}
+}
+
+// sustained reports whether a step kept up with its rate, without errors,
+// and with its p99 under slo.
+func (s openLoopStep) sustained(slo time.Duration) bool {
+	return !s.Overloaded && s.Errors == 0 && s.Completed > 0 && s.P99Ms < slo.Seconds()*1000.0
+}
+
+// maxSustainedRate returns the highest rate of steps, ordered by rate,
+// below which every step was sustained under slo; zero if the first was
+// not. A failing step caps the result even if a higher rate happened to
+// pass.
+func maxSustainedRate(steps []openLoopStep, slo time.Duration) int {
+	best := 0
+	for _, s := range steps {
+		if !s.sustained(slo) {
+			break
+		}
+		best = s.RateRPS
+	}
+	return best
+}
+
+// parseDurationList parses a comma-separated list of positive durations.
+func parseDurationList(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid value %q: must be a positive duration", field)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func writeOpenLoopReport(report openLoopReport) error {
+	if err := os.MkdirAll(reportDir, 0o755); err != nil {
+		return fmt.Errorf("create reports dir failed: %w", err)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal open-loop report failed: %w", err)
+	}
+	ts := report.GeneratedAt.Format("20060102-150405")
+	jsonPath := filepath.Join(reportDir, fmt.Sprintf("openloop-%s.json", ts))
+	if err = os.WriteFile(jsonPath, blob, 0o644); err != nil {
+		return fmt.Errorf("write open-loop report failed: %w", err)
+	}
+	mdPath := filepath.Join(reportDir, fmt.Sprintf("openloop-%s.md", ts))
+	if err = os.WriteFile(mdPath, []byte(renderOpenLoop(report)), 0o644); err != nil {
+		return fmt.Errorf("write open-loop markdown failed: %w", err)
+	}
+	_, _ = fmt.Printf("wrote open-loop report: %s\n", jsonPath)
+	return nil
+}
+
+// renderOpenLoop renders the SLO summary, then each target's closed-loop
+// result and open-loop steps.
+func renderOpenLoop(report openLoopReport) string {
+	var b strings.Builder
+	b.WriteString("# Redis MVP Latency Under Load\n\n")
+	_, _ = fmt.Fprintf(&b, "Generated at: %s UTC, %s, %d connections, %.0f s per rate\n\n",
+		report.GeneratedAt.Format(time.RFC3339), report.Mix, report.Connections, report.StepDurationS)
+	renderEnvironment(&b, report.Environment)
+
+	b.WriteString("## Max sustainable throughput\n\n")
+	b.WriteString("target | p99 SLO ms | max rate rps\n")
+	b.WriteString("---|---:|---:\n")
+	for _, s := range report.Summary {
+		rate := "none"
+		if s.MaxRateRPS > 0 {
+			rate = fmt.Sprint(s.MaxRateRPS)
+		}
+		_, _ = fmt.Fprintf(&b, "%s | %.3f | %s\n", s.Target, s.P99SLOMs, rate)
+	}
+
+	for _, t := range report.Targets {
+		_, _ = fmt.Fprintf(&b, "\n## %s (%s)\n\n", t.Target, t.Addr)
+		_, _ = fmt.Fprintf(&b, "Closed loop: %.1f rps, p50 %.3f ms, p99 %.3f ms\n\n",
+			t.Closed.Throughput, t.Closed.P50Ms, t.Closed.P99Ms)
+		b.WriteString("offered rps | achieved rps | p50 ms | p99 ms | p99.9 ms | max ms | errors | overloaded\n")
+		b.WriteString("---:|---:|---:|---:|---:|---:|---:|---\n")
+		for _, s := range t.Steps {
+			_, _ = fmt.Fprintf(&b, "%d | %.1f | %.3f | %.3f | %.3f | %.3f | %d | %t\n",
+				s.RateRPS, s.Throughput, s.P50Ms, s.P99Ms, s.P999Ms, s.MaxMs, s.Errors, s.Overloaded)
+		}
+	}
+	return b.String()
+}
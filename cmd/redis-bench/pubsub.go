@@ -0,0 +1,256 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+const (
+	pubsubChannel = "bench:pubsub"
+	// pubsubDrainTimeout bounds how long subscribers wait for in-flight
+	// messages after the last publish returns.
+	pubsubDrainTimeout = 3 * time.Second
+)
+
+// pubsubScenario measures publish throughput and publish-to-delivery latency
+// with the given number of publishing and subscribing connections. The
+// requests budget is the total number of PUBLISH commands across publishers.
+func pubsubScenario(publishers, subscribers int) scenario {
+	return scenario{
+		name:        "pubsub_fanout",
+		description: fmt.Sprintf("PUBLISH fanout, %d publishers x %d subscribers", publishers, subscribers),
+		run: func(addr string, sc scenario, requests, _ int) (scenarioResult, error) {
+			return runPubSub(addr, sc, requests, publishers, subscribers)
+		},
+	}
+}
+
+func runPubSub(addr string, sc scenario, requests, publishers, subscribers int) (scenarioResult, error) {
+	subs := make([]*respConn, 0, subscribers)
+	defer func() {
+		for _, c := range subs {
+			_ = c.Close()
+		}
+	}()
+	for i := 0; i < subscribers; i++ {
+		c, err := dialRESP(addr)
+		if err != nil {
+			return scenarioResult{}, fmt.Errorf("subscriber %d dial: %w", i, err)
+		}
+		subs = append(subs, c)
+		reply, err := c.do("SUBSCRIBE", pubsubChannel)
+		if err != nil {
+			return scenarioResult{}, fmt.Errorf("subscriber %d subscribe: %w", i, err)
+		}
+		if reply.Kind == redisproto.KindError {
+			return scenarioResult{}, fmt.Errorf("subscriber %d subscribe: %s", i, reply.Str)
+		}
+		// Subscribers block until the publishers finish; the drain deadline
+		// is set once publishing is done.
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+
+	type subOut struct {
+		latencies []float64
+		received  int
+	}
+	subOuts := make(chan subOut, subscribers)
+	var subWG sync.WaitGroup
+	for _, c := range subs {
+		subWG.Add(1)
+		go func(c *respConn) {
+			defer subWG.Done()
+			out := subOut{latencies: make([]float64, 0, requests)}
+			for out.received < requests {
+				v, err := c.next()
+				if err != nil {
+					break
+				}
+				sent, ok := parsePubSubMessage(v)
+				if !ok {
+					continue
+				}
+				out.received++
+				out.latencies = append(out.latencies, float64(time.Now().UnixNano()-sent)/1e6)
+			}
+			subOuts <- out
+		}(c)
+	}
+
+	jobs := make(chan struct{}, requests)
+	for i := 0; i < requests; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	var (
+		pubWG     sync.WaitGroup
+		errMu     sync.Mutex
+		pubErrors int
+		published int
+	)
+	start := time.Now()
+	for p := 0; p < publishers; p++ {
+		pubWG.Add(1)
+		go func() {
+			defer pubWG.Done()
+			c, err := dialRESP(addr)
+			if err != nil {
+				// Remaining jobs are drained by the other publishers.
+				errMu.Lock()
+				pubErrors++
+				errMu.Unlock()
+				return
+			}
+			defer c.Close()
+
+			okCount, errCount := 0, 0
+			for range jobs {
+				payload := strconv.FormatInt(time.Now().UnixNano(), 10)
+				reply, err := c.do("PUBLISH", pubsubChannel, payload)
+				if err != nil || reply.Kind == redisproto.KindError {
+					errCount++
+					continue
+				}
+				okCount++
+			}
+			errMu.Lock()
+			pubErrors += errCount
+			published += okCount
+			errMu.Unlock()
+		}()
+	}
+	pubWG.Wait()
+	dur := time.Since(start)
+
+	// Unblock subscribers still waiting for messages that never arrive.
+	deadline := time.Now().Add(pubsubDrainTimeout)
+	for _, c := range subs {
+		_ = c.conn.SetReadDeadline(deadline)
+	}
+	subWG.Wait()
+	close(subOuts)
+
+	allLat := make([]float64, 0, requests*subscribers)
+	delivered := 0
+	for out := range subOuts {
+		allLat = append(allLat, out.latencies...)
+		delivered += out.received
+	}
+	sort.Float64s(allLat)
+
+	return scenarioResult{
+		Scenario:           sc.name,
+		Description:        sc.description,
+		Requests:           requests,
+		Concurrency:        publishers,
+		DurationMs:         dur.Seconds() * 1000.0,
+		Throughput:         float64(published) / dur.Seconds(),
+		P50Ms:              percentile(allLat, 50),
+		P95Ms:              percentile(allLat, 95),
+		P99Ms:              percentile(allLat, 99),
+		Errors:             pubErrors,
+		ExpectedDeliveries: published * subscribers,
+		Delivered:          delivered,
+	}, nil
+}
+
+// parsePubSubMessage extracts the publish timestamp from a
+// ["message", channel, payload] push frame.
+func parsePubSubMessage(v redisproto.Value) (int64, bool) {
+	if v.Kind != redisproto.KindArray || len(v.Array) != 3 {
+		return 0, false
+	}
+	if string(v.Array[0].Bulk) != "message" {
+		return 0, false
+	}
+	sent, err := strconv.ParseInt(string(v.Array[2].Bulk), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return sent, true
+}
+
+// respConn is a persistent RESP connection that keeps parser state across
+// replies, unlike execOnce which dials per command.
+type respConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	parser  *redisproto.Parser
+	pending []redisproto.Value
+	buf     []byte
+}
+
+func dialRESP(addr string) (*respConn, error) {
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &respConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		parser: redisproto.NewParser(),
+		buf:    make([]byte, 4096),
+	}, nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends one command and returns its reply.
+func (c *respConn) do(args ...string) (redisproto.Value, error) {
+	wire, err := redisproto.Encode(buildCommand(args))
+	if err != nil {
+		return redisproto.Value{}, err
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err = c.conn.Write(wire); err != nil {
+		return redisproto.Value{}, err
+	}
+	return c.next()
+}
+
+// next returns the next frame received on the connection.
+func (c *respConn) next() (redisproto.Value, error) {
+	for len(c.pending) == 0 {
+		n, err := c.reader.Read(c.buf)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return redisproto.Value{}, errors.New("connection closed")
+			}
+			return redisproto.Value{}, err
+		}
+		frames, err := c.parser.Feed(c.buf[:n])
+		if err != nil {
+			return redisproto.Value{}, err
+		}
+		c.pending = append(c.pending, frames...)
+	}
+	v := c.pending[0]
+	c.pending = c.pending[1:]
+	return v, nil
+}
+
+func buildCommand(args []string) redisproto.Value {
+	cmd := make([]redisproto.Value, 0, len(args))
+	for _, arg := range args {
+		cmd = append(cmd, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(arg)})
+	}
+	return redisproto.Value{Kind: redisproto.KindArray, Array: cmd}
+}
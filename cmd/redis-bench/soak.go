@@ -0,0 +1,213 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+	"github.com/crrow/libxev-go/pkg/redismvp"
+)
+
+// A soak run drives the MVP server with rounds of mixed and churning
+// traffic for a long time, sampling the process RSS and the number of live
+// cxev callback registrations between rounds, when the server is idle. A
+// leak shows up as a metric that only ever grows; usage that plateaus or
+// dips under load is healthy. The first sample is taken after one round so
+// pools and caches have warmed up.
+
+// minSoakSamples is the number of samples needed before growth is judged.
+const minSoakSamples = 3
+
+type soakSample struct {
+	ElapsedS  float64 `json:"elapsed_s"`
+	RSSBytes  uint64  `json:"rss_bytes"`
+	HeapBytes uint64  `json:"heap_bytes"`
+	Callbacks int     `json:"callbacks"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+}
+
+type soakReport struct {
+	GeneratedAt  time.Time        `json:"generated_at"`
+	DurationS    float64          `json:"duration_s"`
+	IntervalS    float64          `json:"interval_s"`
+	Concurrency  int              `json:"concurrency"`
+	MaxGrowth    float64          `json:"max_growth"`
+	Samples      []soakSample     `json:"samples"`
+	RSSPass      bool             `json:"rss_pass"`
+	CallbackPass bool             `json:"callback_pass"`
+	Command      string           `json:"command"`
+	Environment  benchEnvironment `json:"environment"`
+}
+
+func runSoak(duration, interval time.Duration, maxGrowth float64, requests, concurrency int) error {
+	if interval <= 0 || interval > duration {
+		return fmt.Errorf("soak interval must be in (0, %s]", duration)
+	}
+
+	server, err := redismvp.Start(fmt.Sprintf("127.0.0.1:%d", defaultMVPort))
+	if err != nil {
+		return fmt.Errorf("start mvp redis server failed: %w", err)
+	}
+	defer func() { _ = server.Close() }()
+	addr := server.Addr()
+	if err = waitUntilReady(addr, 3*time.Second); err != nil {
+		return fmt.Errorf("mvp server not ready: %w", err)
+	}
+	if err = prewarm(addr, 1000); err != nil {
+		return fmt.Errorf("prewarm failed: %w", err)
+	}
+
+	scenarios := []scenario{
+		{name: "read_heavy", description: "70% GET + 30% SET", mix: []operation{{name: "GET", weight: 70}, {name: "SET", weight: 30}}},
+		churnScenario(),
+	}
+
+	report := soakReport{
+		GeneratedAt: time.Now().UTC(),
+		DurationS:   duration.Seconds(),
+		IntervalS:   interval.Seconds(),
+		Concurrency: concurrency,
+		MaxGrowth:   maxGrowth,
+		Command:     strings.Join(os.Args, " "),
+		Environment: captureEnvironment(mvpServerInfo("libxev-go-mvp", redismvp.Config{})),
+	}
+	start := time.Now()
+	var pending soakSample
+	nextSample := start
+	for time.Since(start) < duration {
+		for _, sc := range scenarios {
+			run := runScenario
+			if sc.run != nil {
+				run = sc.run
+			}
+			res, err := run(addr, sc, requests, concurrency)
+			if err != nil {
+				return err
+			}
+			pending.Requests += res.Requests
+			pending.Errors += res.Errors + res.ConnectErrors
+		}
+		if time.Now().Before(nextSample) {
+			continue
+		}
+		sample := takeSoakSample(pending, time.Since(start))
+		report.Samples = append(report.Samples, sample)
+		_, _ = fmt.Printf("soak %6.0fs | rss %8.1f MiB | heap %8.1f MiB | callbacks %5d | requests %d | errors %d\n",
+			sample.ElapsedS, mib(sample.RSSBytes), mib(sample.HeapBytes), sample.Callbacks, sample.Requests, sample.Errors)
+		pending = soakSample{}
+		nextSample = time.Now().Add(interval)
+	}
+
+	rss := make([]float64, len(report.Samples))
+	callbacks := make([]float64, len(report.Samples))
+	for i, s := range report.Samples {
+		rss[i] = float64(s.RSSBytes)
+		callbacks[i] = float64(s.Callbacks)
+	}
+	report.RSSPass = !growsMonotonically(rss, maxGrowth)
+	report.CallbackPass = !growsMonotonically(callbacks, maxGrowth)
+
+	if err := writeSoakReport(report); err != nil {
+		return err
+	}
+	if !report.RSSPass || !report.CallbackPass {
+		return fmt.Errorf("soak gate failed: rss_pass=%t callback_pass=%t", report.RSSPass, report.CallbackPass)
+	}
+	_, _ = fmt.Println("soak gate passed")
+	return nil
+}
+
+func takeSoakSample(s soakSample, elapsed time.Duration) soakSample {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.ElapsedS = elapsed.Seconds()
+	s.RSSBytes = readRSS()
+	s.HeapBytes = ms.HeapInuse
+	for _, n := range cxev.ActiveCallbacks() {
+		s.Callbacks += n
+	}
+	return s
+}
+
+// growsMonotonically reports whether samples never decrease and end more
+// than maxGrowth above where they started. A zero start is treated as one
+// so that counters starting at zero are judged too.
+func growsMonotonically(samples []float64, maxGrowth float64) bool {
+	if len(samples) < minSoakSamples {
+		return false
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i] < samples[i-1] {
+			return false
+		}
+	}
+	first := max(samples[0], 1)
+	return samples[len(samples)-1] > first*(1+maxGrowth)
+}
+
+// readRSS returns the resident set size of this process, which also hosts
+// the MVP server, or 0 where /proc is not available.
+func readRSS() uint64 {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	return parseVmRSS(data)
+}
+
+// parseVmRSS extracts VmRSS in bytes from the contents of a
+// /proc/<pid>/status file.
+func parseVmRSS(status []byte) uint64 {
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		rest, ok := bytes.CutPrefix(scanner.Bytes(), []byte("VmRSS:"))
+		if !ok {
+			continue
+		}
+		fields := bytes.Fields(rest)
+		if len(fields) != 2 || string(fields[1]) != "kB" {
+			return 0
+		}
+		kb, err := strconv.ParseUint(string(fields[0]), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
+
+func mib(b uint64) float64 {
+	return float64(b) / (1 << 20)
+}
+
+func writeSoakReport(report soakReport) error {
+	if err := os.MkdirAll(reportDir, 0o755); err != nil {
+		return fmt.Errorf("create reports dir failed: %w", err)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal soak report failed: %w", err)
+	}
+	ts := report.GeneratedAt.Format("20060102-150405")
+	path := filepath.Join(reportDir, fmt.Sprintf("soak-%s.json", ts))
+	if err = os.WriteFile(path, blob, 0o644); err != nil {
+		return fmt.Errorf("write soak report failed: %w", err)
+	}
+	_, _ = fmt.Printf("wrote soak report: %s\n", path)
+	return nil
+}
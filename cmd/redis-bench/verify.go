@@ -0,0 +1,199 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// verifyKeysPerWorker is the number of string keys and of counters each
+// verify worker cycles through.
+const verifyKeysPerWorker = 16
+
+// verifyScenario runs a SET/GET/INCR mix in which every reply is checked
+// against what the worker itself wrote: GET returns the last value SET and
+// INCR counts up by one. Each worker owns its keys, so the expected reply
+// is exact. Wrong replies are reported as violations, apart from network
+// errors, which makes the bench a correctness harness under load.
+func verifyScenario() scenario {
+	return scenario{
+		name:        "verify_responses",
+		description: "40% SET + 40% GET + 20% INCR on per-worker keys, replies checked",
+		mix:         []operation{{name: "SET", weight: 40}, {name: "GET", weight: 40}, {name: "INCR", weight: 20}},
+		run:         runVerify,
+	}
+}
+
+func runVerify(addr string, sc scenario, requests, concurrency int) (scenarioResult, error) {
+	jobs := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	type workerOut struct {
+		latencies  []float64
+		errors     int
+		violations int
+	}
+	outs := make(chan workerOut, concurrency)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(workerID + 99)))
+			out := workerOut{latencies: make([]float64, 0, requests/concurrency+8)}
+			v := newVerifier(workerID)
+			out.errors += v.reset(addr)
+			for idx := range jobs {
+				cmd := v.command(pickOperation(rng, sc.mix), idx)
+				t0 := time.Now()
+				reply, err := execOnce(addr, cmd)
+				out.latencies = append(out.latencies, time.Since(t0).Seconds()*1000.0)
+				if err != nil {
+					out.errors++
+					v.forget(cmd)
+					continue
+				}
+				if !v.check(cmd, reply) {
+					out.violations++
+				}
+			}
+			outs <- out
+		}(w)
+	}
+	wg.Wait()
+	close(outs)
+
+	dur := time.Since(start)
+	allLat := make([]float64, 0, requests)
+	res := scenarioResult{
+		Scenario:    sc.name,
+		Description: sc.description,
+		Requests:    requests,
+		Concurrency: concurrency,
+		DurationMs:  dur.Seconds() * 1000.0,
+		Throughput:  float64(requests) / dur.Seconds(),
+	}
+	for out := range outs {
+		allLat = append(allLat, out.latencies...)
+		res.Errors += out.errors
+		res.Violations += out.violations
+	}
+	sort.Float64s(allLat)
+	res.P50Ms = percentile(allLat, 50)
+	res.P95Ms = percentile(allLat, 95)
+	res.P99Ms = percentile(allLat, 99)
+	return res, nil
+}
+
+// verifier tracks what one worker has written to its keys, so it can tell
+// what every reply should be.
+type verifier struct {
+	prefix string
+	// values holds the last value SET per string key, or nil after DEL.
+	values map[string]*string
+	// counters holds the last INCR reply per counter key.
+	counters map[string]int64
+	// unknown holds keys whose last write failed on the network and may
+	// or may not have been applied; their next reply is taken as is.
+	unknown map[string]bool
+}
+
+func newVerifier(workerID int) *verifier {
+	return &verifier{
+		prefix:   fmt.Sprintf("bench:verify:%d:", workerID),
+		values:   make(map[string]*string),
+		counters: make(map[string]int64),
+		unknown:  make(map[string]bool),
+	}
+}
+
+// reset deletes the worker's keys, left over from an earlier run, and
+// returns the number of failed requests. Keys that could not be deleted
+// are marked unknown.
+func (v *verifier) reset(addr string) int {
+	failed := 0
+	for i := 0; i < verifyKeysPerWorker; i++ {
+		for _, key := range []string{v.stringKey(i), v.counterKey(i)} {
+			if _, err := execOnce(addr, []string{"DEL", key}); err != nil {
+				failed++
+				v.unknown[key] = true
+			}
+		}
+	}
+	return failed
+}
+
+func (v *verifier) stringKey(i int) string {
+	return v.prefix + "str:" + strconv.Itoa(i%verifyKeysPerWorker)
+}
+
+func (v *verifier) counterKey(i int) string {
+	return v.prefix + "ctr:" + strconv.Itoa(i%verifyKeysPerWorker)
+}
+
+// command builds request idx for op.
+func (v *verifier) command(op string, idx int) []string {
+	switch op {
+	case "SET":
+		return []string{"SET", v.stringKey(idx), "value:" + strconv.Itoa(idx)}
+	case "INCR":
+		return []string{"INCR", v.counterKey(idx)}
+	default:
+		return []string{"GET", v.stringKey(idx)}
+	}
+}
+
+// forget marks the key of a request that failed on the network as unknown.
+func (v *verifier) forget(cmd []string) {
+	v.unknown[cmd[1]] = true
+}
+
+// check reports whether reply is the correct reply to cmd, and records
+// the state the reply leaves the key in.
+func (v *verifier) check(cmd []string, reply redisproto.Value) bool {
+	key := cmd[1]
+	unknown := v.unknown[key]
+	delete(v.unknown, key)
+
+	switch cmd[0] {
+	case "SET":
+		value := cmd[2]
+		v.values[key] = &value
+		return reply.Kind == redisproto.KindSimpleString && reply.Str == "OK"
+	case "INCR":
+		if reply.Kind != redisproto.KindInteger {
+			return false
+		}
+		want := v.counters[key] + 1
+		v.counters[key] = reply.Int
+		return unknown || reply.Int == want
+	default:
+		want := v.values[key]
+		switch reply.Kind {
+		case redisproto.KindNull:
+			v.values[key] = nil
+			return unknown || want == nil
+		case redisproto.KindBulkString:
+			got := string(reply.Bulk)
+			v.values[key] = &got
+			return unknown || (want != nil && *want == got)
+		default:
+			return false
+		}
+	}
+}
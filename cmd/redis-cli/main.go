@@ -16,6 +16,15 @@ import (
 func main() {
 	addr := flag.String("addr", "127.0.0.1:6379", "redis server address")
 	auth := flag.String("auth", "", "auth token placeholder (not used yet)")
+	resp3 := flag.Bool("3", false, "start the session in RESP3 mode")
+	eval := flag.String("eval", "", "evaluate a Lua script file; args are KEYS, then \",\", then ARGV")
+	clients := flag.Int("n", 0, "run the command from this many concurrent clients and summarize the replies")
+	repeat := flag.Int("r", 1, "with -n, times each client runs the command")
+	pool := flag.Int("pool", 0, "with -n, connections the clients share (default one per client)")
+	copyTo := flag.String("copy-to", "", "copy every key to this server address with DUMP and RESTORE, keeping TTLs")
+	copyMatch := flag.String("copy-match", "", "with --copy-to, only copy keys matching this pattern")
+	copyReplace := flag.Bool("copy-replace", false, "with --copy-to, overwrite keys that exist on the destination")
+	rdb := flag.String("rdb", "", "download a snapshot of the dataset, as a replica would, into this file (\"-\" for stdout)")
 	flag.Parse()
 
 	if *auth != "" {
@@ -23,6 +32,23 @@ func main() {
 	}
 
 	client := rediscli.NewClient(*addr)
+	if *resp3 {
+		client.Protocol = 3
+	}
+	if *eval != "" {
+		os.Exit(client.RunEval(*eval, flag.Args(), os.Stdout, os.Stderr))
+	}
+	if *rdb != "" {
+		os.Exit(client.RunRDB(*rdb, os.Stdout, os.Stderr))
+	}
+	if *copyTo != "" {
+		opts := rediscli.CopyOptions{Match: *copyMatch, Replace: *copyReplace}
+		os.Exit(client.RunCopy(*copyTo, opts, os.Stdout, os.Stderr))
+	}
+	if *clients > 0 {
+		opts := rediscli.ParallelOptions{Clients: *clients, Repeat: *repeat, PoolSize: *pool}
+		os.Exit(client.RunParallel(opts, flag.Args(), os.Stdout, os.Stderr))
+	}
 	exitCode := client.Run(flag.Args(), os.Stdin, os.Stdout, os.Stderr)
 	os.Exit(exitCode)
 }
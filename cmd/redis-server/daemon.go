@@ -0,0 +1,118 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// A Go process cannot fork safely, so --daemonize re-executes the binary in
+// a new session with its standard streams on /dev/null. The parent waits on
+// a pipe until the child reports that the server is listening, or why it
+// could not start, and exits with a matching status. Scripts can therefore
+// rely on the server accepting connections once the command returns.
+
+// daemonReadyEnv names the environment variable that carries the number of
+// the fd a daemonized child reports its startup status on.
+const daemonReadyEnv = "REDIS_SERVER_READY_FD"
+
+// defaultDaemonPidfile is the pidfile of a daemonized server when none is
+// given, as in Redis.
+const defaultDaemonPidfile = "/var/run/redis.pid"
+
+// daemonReadyOK is written by the child once the server is running.
+const daemonReadyOK = "ok"
+
+// isDaemonChild reports whether this process is the detached child started
+// by spawnDaemon.
+func isDaemonChild() bool {
+	return os.Getenv(daemonReadyEnv) != ""
+}
+
+// spawnDaemon starts the detached child and waits for its startup status.
+func spawnDaemon() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	// ExtraFiles[0] becomes fd 3 in the child.
+	cmd.Env = append(os.Environ(), daemonReadyEnv+"=3")
+	cmd.ExtraFiles = []*os.File{w}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return fmt.Errorf("start daemon: %w", err)
+	}
+	return readDaemonStatus(r)
+}
+
+// readDaemonStatus reads the status a child reports on r. A child that
+// exits without reporting leaves r empty.
+func readDaemonStatus(r io.Reader) error {
+	status, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch msg := strings.TrimSpace(string(status)); msg {
+	case daemonReadyOK:
+		return nil
+	case "":
+		return errors.New("daemon exited during startup")
+	default:
+		return errors.New(msg)
+	}
+}
+
+// notifyDaemonParent reports the startup status of a daemonized child to
+// the waiting parent. It does nothing in a process that is not one.
+func notifyDaemonParent(startErr error) {
+	fd, err := strconv.Atoi(os.Getenv(daemonReadyEnv))
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "daemon-ready")
+	if f == nil {
+		return
+	}
+	defer f.Close()
+	msg := daemonReadyOK
+	if startErr != nil {
+		msg = startErr.Error()
+	}
+	_, _ = f.WriteString(msg)
+}
+
+// writePidfile writes the process id to path.
+func writePidfile(path string) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+// openLogfile opens path for appending, or returns stderr if path is empty.
+func openLogfile(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return os.Stderr, func() {}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,72 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestReadDaemonStatus(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr string
+	}{
+		{status: daemonReadyOK},
+		{status: "", wantErr: "daemon exited during startup"},
+		{status: "listen tcp 127.0.0.1:6379: bind: address already in use", wantErr: "address already in use"},
+	}
+	for _, tt := range tests {
+		err := readDaemonStatus(strings.NewReader(tt.status))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("status %q: unexpected error %v", tt.status, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("status %q: got %v, want error containing %q", tt.status, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNotifyDaemonParent(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe failed: %v", err)
+	}
+	defer r.Close()
+	// notifyDaemonParent closes the fd it is given, so hand it a copy.
+	fd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatalf("dup failed: %v", err)
+	}
+	t.Setenv(daemonReadyEnv, strconv.Itoa(fd))
+
+	notifyDaemonParent(nil)
+	if err := readDaemonStatus(r); err != nil {
+		t.Fatalf("parent saw %v after a successful start", err)
+	}
+}
+
+func TestWritePidfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.pid")
+	if err := writePidfile(path); err != nil {
+		t.Fatalf("writePidfile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read pidfile failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("pidfile holds %q, want %d", got, os.Getpid())
+	}
+}
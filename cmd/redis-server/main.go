@@ -8,31 +8,184 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/crrow/libxev-go/pkg/redismvp"
+	"github.com/crrow/libxev-go/pkg/xev"
 )
 
 func main() {
 	addr := flag.String("addr", "127.0.0.1:6379", "listen address")
+	loglevel := flag.String("loglevel", "notice", "log level: debug, verbose, notice, warning, nothing")
+	timeout := flag.Duration("timeout", 0, "close client connections idle for this long (0 disables)")
+	tcpKeepalive := flag.Duration("tcp-keepalive", 300*time.Second, "send TCP keepalive probes to clients idle this long (0 disables)")
+	tcpNoDelay := flag.Bool("tcp-nodelay", true, "disable Nagle's algorithm on client connections")
+	slowlog := flag.Duration("slowlog", redismvp.DefaultSlowLogThreshold, "log commands slower than this (negative disables)")
+	replicaof := flag.String("replicaof", "", "replicate the master at this host:port")
+	backlog := flag.Int("repl-backlog-size", redismvp.DefaultReplBacklogSize, "replication backlog size in bytes")
+	readOnly := flag.Bool("replica-read-only", true, "reject client writes while a replica")
+	dir := flag.String("dir", "", "directory of the RDB and append only files (default the working directory)")
+	dbFilename := flag.String("dbfilename", redismvp.DefaultDBFilename, "RDB file written by SAVE and BGSAVE")
+	appendOnly := flag.Bool("appendonly", false, "log writes to an append only file replayed at startup")
+	appendFilename := flag.String("appendfilename", redismvp.DefaultAppendFilename, "append only file path")
+	appendFsync := flag.String("appendfsync", "everysec", "append only file fsync policy: always, everysec, no")
+	rdbPreamble := flag.Bool("aof-use-rdb-preamble", true, "start rewritten append only files with an RDB snapshot")
+	maxclients := flag.Int("maxclients", redismvp.DefaultMaxClients, "refuse connections beyond this many clients")
+	databases := flag.Int("databases", 1, "number of databases SELECT accepts (only 1 is supported)")
+	protectedMode := flag.Bool("protected-mode", true, "refuse connections from non-loopback addresses")
+	maxmemory := flag.String("maxmemory", "0", "memory limit of the dataset, e.g. 100mb (0 means no limit)")
+	maxmemoryPolicy := flag.String("maxmemory-policy", "noeviction", "eviction policy: noeviction, allkeys-lru, allkeys-lfu, allkeys-random")
+	maxmemorySamples := flag.Int("maxmemory-samples", redismvp.DefaultMaxMemorySamples, "keys sampled to pick each key to evict")
+	lfuLogFactor := flag.Int("lfu-log-factor", redismvp.DefaultLFULogFactor, "hits it takes to grow the LFU counters, logarithmically")
+	lfuDecayTime := flag.Duration("lfu-decay-time", redismvp.DefaultLFUDecayTime, "idle time that decrements an LFU counter (0 disables decay)")
+	extensionCommands := flag.Bool("extension-commands", false, "serve the x.* extension commands, such as X.SETIFEQ")
+	debugKeyspace := flag.Bool("debug-keyspace", false, "serve DEBUG KEYSPACE, which lists every key name")
+	daemonize := flag.Bool("daemonize", false, "detach and run in the background once the server is listening")
+	pidfile := flag.String("pidfile", "", "write the process id to this file (default "+defaultDaemonPidfile+" when daemonized)")
+	logfile := flag.String("logfile", "", "append the log to this file instead of stderr")
+	renames := renameFlag{}
+	flag.Var(renames, "rename-command", "serve a command under a new name, as NAME=NEWNAME, or disable it with NAME= (repeatable)")
 	flag.Parse()
 
-	srv, err := redismvp.Start(*addr)
+	level, err := redismvp.ParseLogLevel(*loglevel)
 	if err != nil {
-		log.Fatalf("start redis server failed: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fsync, err := redismvp.ParseAppendFsync(*appendFsync)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	maxmem, err := redismvp.ParseMemorySize(*maxmemory)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	policy, err := redismvp.ParseMaxMemoryPolicy(*maxmemoryPolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *daemonize && !isDaemonChild() {
+		if err := spawnDaemon(); err != nil {
+			fmt.Fprintln(os.Stderr, "redis-server:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logOut, closeLog, err := openLogfile(*logfile)
+	if err != nil {
+		notifyDaemonParent(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer closeLog()
+	logger := slog.New(slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: level}))
+	// Conditions inside the event loop library go to the server log too.
+	xev.SetLogger(logger)
+
+	srv, err := redismvp.StartConfig(redismvp.Config{
+		Addr:              *addr,
+		Logger:            logger,
+		Timeout:           *timeout,
+		TCPKeepAlive:      *tcpKeepalive,
+		TCPNoDelay:        *tcpNoDelay,
+		SlowLogThreshold:  nonZero(*slowlog),
+		ReplicaOf:         *replicaof,
+		ReplBacklogSize:   *backlog,
+		ReplicaWritable:   !*readOnly,
+		Dir:               *dir,
+		DBFilename:        *dbFilename,
+		AppendOnly:        *appendOnly,
+		AppendFilename:    *appendFilename,
+		AppendFsync:       fsync,
+		AOFNoRDBPreamble:  !*rdbPreamble,
+		MaxClients:        *maxclients,
+		Databases:         *databases,
+		ProtectedMode:     *protectedMode,
+		MaxMemory:         maxmem,
+		MaxMemoryPolicy:   policy,
+		MaxMemorySamples:  *maxmemorySamples,
+		LFULogFactor:      *lfuLogFactor,
+		LFUDecayTime:      orDisabled(*lfuDecayTime),
+		ExtensionCommands: *extensionCommands,
+		DebugKeyspace:     *debugKeyspace,
+		RenameCommands:    renames,
+	})
+	if err != nil {
+		notifyDaemonParent(err)
+		logger.Error("start redis server failed", "err", err)
+		os.Exit(1)
 	}
 	defer func() { _ = srv.Close() }()
 
+	if *pidfile == "" && *daemonize {
+		*pidfile = defaultDaemonPidfile
+	}
+	if *pidfile != "" {
+		// Like Redis, a pidfile that cannot be written is not fatal.
+		if err := writePidfile(*pidfile); err != nil {
+			logger.Warn("failed to write pid file", "path", *pidfile, "err", err)
+		} else {
+			defer os.Remove(*pidfile)
+		}
+	}
+	notifyDaemonParent(nil)
+
 	fmt.Printf("redis-server listening on %s\n", srv.Addr())
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	sig := <-sigCh
+	logger.Info("received signal, shutting down", "signal", sig.String())
 
 	if err = srv.Close(); err != nil {
-		log.Printf("shutdown error: %v", err)
+		logger.Error("shutdown error", "err", err)
+	}
+}
+
+// nonZero maps an explicit zero threshold to "log every command", since a
+// zero Config.SlowLogThreshold means "use the default".
+func nonZero(d time.Duration) time.Duration {
+	if d == 0 {
+		return time.Nanosecond
+	}
+	return d
+}
+
+// orDisabled maps a zero duration flag, which disables the setting as in
+// Redis, to the negative value Config uses for that.
+func orDisabled(d time.Duration) time.Duration {
+	if d == 0 {
+		return -1
+	}
+	return d
+}
+
+// renameFlag collects -rename-command flags, each NAME=NEWNAME, into the
+// map of Config.RenameCommands.
+type renameFlag map[string]string
+
+func (f renameFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, newName := range f {
+		pairs = append(pairs, name+"="+newName)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f renameFlag) Set(v string) error {
+	name, newName, ok := strings.Cut(v, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid rename-command %q, want NAME=NEWNAME", v)
 	}
+	f[name] = newName
+	return nil
 }
@@ -19,16 +19,6 @@ import (
 // The copyTask is registered when created and removed when both files are closed.
 var activeCopyTasks sync.Map
 
-// chunkBufferPool reuses read buffers across copy tasks to reduce allocations.
-// With maxConcurrent=16, we only need ~16 buffers in circulation rather than
-// allocating a new buffer for each of the 50+ files being copied.
-var chunkBufferPool = sync.Pool{
-	New: func() any {
-		buf := make([]byte, chunkSize)
-		return &buf
-	},
-}
-
 const chunkSize = 64 * 1024 // 64KB per read/write
 const maxConcurrent = 16    // Maximum concurrent file operations
 
@@ -60,14 +50,11 @@ func (c *XevCopier) Close() {
 
 // copyTask represents a single file copy operation.
 type copyTask struct {
-	copier   *XevCopier
-	src      *xev.File
-	dst      *xev.File
-	srcPath  string
-	dstPath  string
-	fileSize int64
-	offset   uint64
-	buf      []byte
+	copier  *XevCopier
+	src     *xev.File
+	dst     *xev.File
+	srcPath string
+	dstPath string
 }
 
 // CopyFiles copies all src files to dst paths concurrently.
@@ -106,12 +93,6 @@ func (c *XevCopier) startMoreCopies() {
 }
 
 func (c *XevCopier) startCopy(srcPath, dstPath string) error {
-	// Get file size
-	info, err := os.Stat(srcPath)
-	if err != nil {
-		return err
-	}
-
 	src, err := xev.OpenFile(srcPath, os.O_RDONLY, 0)
 	if err != nil {
 		return fmt.Errorf("open src: %w", err)
@@ -123,67 +104,26 @@ func (c *XevCopier) startCopy(srcPath, dstPath string) error {
 		return fmt.Errorf("open dst: %w", err)
 	}
 
-	// Get buffer from pool for this task
-	bufPtr := chunkBufferPool.Get().(*[]byte)
-
 	task := &copyTask{
-		copier:   c,
-		src:      src,
-		dst:      dst,
-		srcPath:  srcPath,
-		dstPath:  dstPath,
-		fileSize: info.Size(),
-		offset:   0,
-		buf:      *bufPtr,
-	}
-
-	activeCopyTasks.Store(task, struct{}{})
-	return task.readNext()
-}
-
-func (t *copyTask) readNext() error {
-	if t.offset >= uint64(t.fileSize) {
-		// Done - close files
-		return t.finish(nil)
-	}
-
-	return t.src.PReadFunc(t.copier.loop, t.buf, t.offset, t.onRead)
-}
-
-func (t *copyTask) onRead(f *xev.File, data []byte, err error) xev.Action {
-	if err != nil {
-		t.finish(fmt.Errorf("read at %d: %w", t.offset, err))
-		return xev.Stop
-	}
-
-	if len(data) == 0 {
-		t.finish(nil)
-		return xev.Stop
+		copier:  c,
+		src:     src,
+		dst:     dst,
+		srcPath: srcPath,
+		dstPath: dstPath,
 	}
 
-	writeData := make([]byte, len(data))
-	copy(writeData, data)
-
-	writeOffset := t.offset
-	t.offset += uint64(len(data))
-
-	dstFd := t.dst.Fd()
-	if err := t.dst.PWriteFunc(t.copier.loop, writeData, writeOffset, func(f *xev.File, bytesWritten int, err error) xev.Action {
-		if err != nil {
-			t.finish(fmt.Errorf("write at offset %d, original_fd=%d, callback_fd=%d: %w", writeOffset, dstFd, f.Fd(), err))
-			return xev.Stop
-		}
-
-		if err := t.readNext(); err != nil {
-			t.finish(err)
-		}
-		return xev.Stop
+	// Copy in chunks on the loop even where the kernel could copy the files
+	// itself, so the benchmark compares async I/O with blocking I/O.
+	opts := xev.CopyOptions{ChunkSize: chunkSize, NoKernelCopy: true}
+	if err := xev.CopyFile(c.loop, src, dst, opts, func(_ int64, err error) {
+		task.finish(err)
 	}); err != nil {
-		t.finish(fmt.Errorf("start write at %d: %w", writeOffset, err))
-		return xev.Stop
+		src.Cleanup()
+		dst.Cleanup()
+		return err
 	}
-
-	return xev.Stop
+	activeCopyTasks.Store(task, struct{}{})
+	return nil
 }
 
 func (t *copyTask) finish(err error) error {
@@ -196,9 +136,6 @@ func (t *copyTask) finish(err error) error {
 	onClose := func(f *xev.File, closeErr error) {
 		f.Cleanup()
 		if closeCount.Add(1) == 2 {
-			// Return buffer to pool when both files are closed
-			chunkBufferPool.Put(&t.buf)
-
 			activeCopyTasks.Delete(t)
 			t.copier.completed.Add(1)
 			t.copier.pending.Add(-1)
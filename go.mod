@@ -4,8 +4,22 @@
 
 module github.com/crrow/libxev-go
 
-go 1.25
+go 1.25.0
 
-require github.com/jupiterrider/ffi v0.5.1
+require (
+	github.com/ebitengine/purego v0.9.1
+	github.com/jupiterrider/ffi v0.5.1
+	go.opentelemetry.io/otel v1.46.0
+	go.opentelemetry.io/otel/sdk v1.46.0
+	go.opentelemetry.io/otel/trace v1.46.0
+	golang.org/x/sys v0.47.0
+)
 
-require github.com/ebitengine/purego v0.9.1 // indirect
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	go.opentelemetry.io/otel/metric v1.46.0 // indirect
+)
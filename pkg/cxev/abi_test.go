@@ -0,0 +1,240 @@
+//go:build (darwin && arm64) || (linux && (amd64 || arm64))
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// Sentinels passed by the test shim in zig/abi_api.zig.
+const (
+	abiResult    int32 = -0x12345678
+	abiCount     int32 = 0x7f3c5a21
+	abiReadCount int32 = 3
+	abiErr       int32 = -0x0f1e2d3c
+)
+
+// abiArgs records what a callback received from the shim.
+type abiArgs struct {
+	loop, c unsafe.Pointer
+	addr    *Sockaddr
+	buf     []byte
+	a, b    int32
+	id      uintptr
+	calls   int
+}
+
+// abiCase is one callback signature, called through the shim that invokes
+// it. register registers a callback recording into got and returns its ID.
+type abiCase struct {
+	name     string
+	shim     string
+	ptr      func() uintptr
+	register func(got *abiArgs, buf []byte) uintptr
+	// a and b are the integer arguments the callback must see.
+	a, b int32
+}
+
+var abiCases = []abiCase{
+	{
+		name: "timer", shim: "xev_abi_call_result", ptr: GetTimerCallbackPtr, a: abiResult,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterCallback(func(loop *Loop, c *Completion, result int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, result, 0, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "tcp", shim: "xev_abi_call_result", ptr: GetTCPCallbackPtr, a: abiResult,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterTCPCallback(func(loop *Loop, c *TCPCompletion, result int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, result, 0, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "tcp_accept", shim: "xev_abi_call_accept", ptr: GetTCPAcceptCallbackPtr, a: abiCount, b: abiErr,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterTCPAcceptCallback(func(loop *Loop, c *TCPCompletion, fd, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, fd, errCode, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "tcp_read", shim: "xev_abi_call_read", ptr: GetTCPReadCallbackPtr, a: abiReadCount, b: abiErr,
+		register: func(got *abiArgs, buf []byte) uintptr {
+			return RegisterTCPReadCallback(func(loop *Loop, c *TCPCompletion, data []byte, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, data, n, errCode, id)
+				return Rearm
+			}, buf)
+		},
+	},
+	{
+		name: "tcp_write", shim: "xev_abi_call_write", ptr: GetTCPWriteCallbackPtr, a: abiCount, b: abiErr,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterTCPWriteCallback(func(loop *Loop, c *TCPCompletion, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, n, errCode, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "udp", shim: "xev_abi_call_result", ptr: GetUDPCallbackPtr, a: abiResult,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterUDPCallback(func(loop *Loop, c *UDPCompletion, result int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, result, 0, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "udp_read", shim: "xev_abi_call_udp_read", ptr: GetUDPReadCallbackPtr, a: abiReadCount, b: abiErr,
+		register: func(got *abiArgs, buf []byte) uintptr {
+			return RegisterUDPReadCallback(func(loop *Loop, c *UDPCompletion, addr *Sockaddr, data []byte, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), addr, data, n, errCode, id)
+				return Rearm
+			}, buf)
+		},
+	},
+	{
+		name: "udp_write", shim: "xev_abi_call_write", ptr: GetUDPWriteCallbackPtr, a: abiCount, b: abiErr,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterUDPWriteCallback(func(loop *Loop, c *UDPCompletion, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, n, errCode, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "file", shim: "xev_abi_call_result", ptr: GetFileCallbackPtr, a: abiResult,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterFileCallback(func(loop *Loop, c *FileCompletion, result int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, result, 0, id)
+				return Rearm
+			})
+		},
+	},
+	{
+		name: "file_read", shim: "xev_abi_call_read", ptr: GetFileReadCallbackPtr, a: abiReadCount, b: abiErr,
+		register: func(got *abiArgs, buf []byte) uintptr {
+			return RegisterFileReadCallback(func(loop *Loop, c *FileCompletion, data []byte, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, data, n, errCode, id)
+				return Rearm
+			}, buf)
+		},
+	},
+	{
+		name: "file_write", shim: "xev_abi_call_write", ptr: GetFileWriteCallbackPtr, a: abiCount, b: abiErr,
+		register: func(got *abiArgs, buf []byte) uintptr {
+			return RegisterFileWriteCallback(func(loop *Loop, c *FileCompletion, n, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, n, errCode, id)
+				return Rearm
+			}, buf)
+		},
+	},
+	{
+		name: "process_wait", shim: "xev_abi_call_write", ptr: GetProcessWaitCallbackPtr, a: abiCount, b: abiErr,
+		register: func(got *abiArgs, _ []byte) uintptr {
+			return RegisterProcessWaitCallback(func(loop *Loop, c *TCPCompletion, status, errCode int32, id uintptr) CbAction {
+				got.record(unsafe.Pointer(loop), unsafe.Pointer(c), nil, nil, status, errCode, id)
+				return Rearm
+			})
+		},
+	},
+}
+
+func (g *abiArgs) record(loop, c unsafe.Pointer, addr *Sockaddr, buf []byte, a, b int32, id uintptr) {
+	*g = abiArgs{loop: loop, c: c, addr: addr, buf: buf, a: a, b: b, id: id, calls: g.calls + 1}
+}
+
+// abiShims prepares the shim functions, which share the leading callback,
+// loop and completion arguments and the trailing userdata.
+func abiShims(t *testing.T) map[string]*ffi.Fun {
+	t.Helper()
+	p := &ffi.TypePointer
+	shims := map[string][]*ffi.Type{
+		"xev_abi_call_result":   {p, p, p, p},
+		"xev_abi_call_accept":   {p, p, p, p},
+		"xev_abi_call_write":    {p, p, p, p},
+		"xev_abi_call_read":     {p, p, p, p, p},
+		"xev_abi_call_udp_read": {p, p, p, p, p, p},
+	}
+	funs := make(map[string]*ffi.Fun, len(shims))
+	for name, args := range shims {
+		fn, err := libExt.Prep(name, &ffi.TypeSint32, args...)
+		if err != nil {
+			t.Skipf("extended library has no ABI test shim: %v", err)
+		}
+		funs[name] = &fn
+	}
+	return funs
+}
+
+// TestTrampolineABI calls every trampoline the way native code does and
+// checks each argument arrives in its position and at its full width.
+func TestTrampolineABI(t *testing.T) {
+	if !ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	shims := abiShims(t)
+	if len(abiCases) != int(numCallbackKinds) {
+		t.Fatalf("%d cases for %d callback kinds", len(abiCases), numCallbackKinds)
+	}
+
+	for _, tc := range abiCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				loop Loop
+				c    [512]byte
+				addr Sockaddr
+				got  abiArgs
+			)
+			buf := []byte("abi-buffer")
+			id := tc.register(&got, buf)
+			defer UnregisterCallback(id)
+
+			f := getFrame().word(uint64(tc.ptr())).ptr(unsafe.Pointer(&loop)).ptr(unsafe.Pointer(&c))
+			switch tc.shim {
+			case "xev_abi_call_read":
+				f.ptr(unsafe.Pointer(&buf[0]))
+			case "xev_abi_call_udp_read":
+				f.ptr(unsafe.Pointer(&addr)).ptr(unsafe.Pointer(&buf[0]))
+			}
+			ret := int32(f.word(uint64(id)).call(shims[tc.shim]))
+
+			if got.calls != 1 {
+				t.Fatalf("callback called %d times", got.calls)
+			}
+			if ret != int32(Rearm) {
+				t.Errorf("shim returned action %d, want %d", ret, Rearm)
+			}
+			if got.loop != unsafe.Pointer(&loop) || got.c != unsafe.Pointer(&c) {
+				t.Errorf("loop %p, completion %p; want %p, %p", got.loop, got.c, &loop, &c)
+			}
+			if got.a != tc.a || got.b != tc.b {
+				t.Errorf("integer arguments %#x, %#x; want %#x, %#x", got.a, got.b, tc.a, tc.b)
+			}
+			if got.id != id {
+				t.Errorf("userdata %d, want %d", got.id, id)
+			}
+			if tc.shim == "xev_abi_call_udp_read" && got.addr != &addr {
+				t.Errorf("remote address %p, want %p", got.addr, &addr)
+			}
+			if tc.a == abiReadCount && string(got.buf) != "abi" {
+				t.Errorf("read data %q, want %q", got.buf, "abi")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestArenaGetPut(t *testing.T) {
+	a := NewArena[TCPCompletion](4)
+	defer a.Close()
+
+	items := make([]*TCPCompletion, 0, 6)
+	for i := 0; i < 6; i++ {
+		c := a.Get()
+		c[0] = byte(i + 1)
+		items = append(items, c)
+	}
+	if a.InUse() != 6 {
+		t.Fatalf("InUse = %d, want 6", a.InUse())
+	}
+	if a.Cap() != 8 {
+		t.Fatalf("Cap = %d, want 8 (two chunks)", a.Cap())
+	}
+
+	seen := make(map[*TCPCompletion]bool, len(items))
+	for _, c := range items {
+		if seen[c] {
+			t.Fatal("arena handed out the same item twice")
+		}
+		seen[c] = true
+	}
+
+	a.Put(items[0])
+	reused := a.Get()
+	if reused != items[0] {
+		t.Fatal("expected most recently returned item to be reused")
+	}
+	if reused[0] != 0 {
+		t.Fatal("reused item must be zeroed")
+	}
+	if a.Cap() != 8 {
+		t.Fatalf("reuse should not grow the arena, Cap = %d", a.Cap())
+	}
+}
+
+func BenchmarkArenaCompletion(b *testing.B) {
+	a := NewArena[TCPCompletion](DefaultArenaChunkSize)
+	defer a.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := a.Get()
+		a.Put(c)
+	}
+}
+
+func BenchmarkPinnedCompletion(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := new(TCPCompletion)
+		var p runtime.Pinner
+		p.Pin(c)
+		p.Unpin()
+	}
+}
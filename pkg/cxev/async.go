@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// FFI function descriptors for async operations.
+var (
+	fnAsyncInit   ffi.Fun
+	fnAsyncDeinit ffi.Fun
+	fnAsyncNotify ffi.Fun
+	fnAsyncWait   ffi.Fun
+)
+
+func registerAsyncFunctions() error {
+	var err error
+
+	// int xev_async_init(xev_async* async)
+	fnAsyncInit, err = lib.Prep("xev_async_init", &ffi.TypeSint32, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// void xev_async_deinit(xev_async* async)
+	fnAsyncDeinit, err = lib.Prep("xev_async_deinit", &ffi.TypeVoid, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// int xev_async_notify(xev_async* async)
+	fnAsyncNotify, err = lib.Prep("xev_async_notify", &ffi.TypeSint32, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// void xev_async_wait(xev_async*, xev_loop*, xev_completion*, void* userdata, callback_fn)
+	fnAsyncWait, err = lib.Prep("xev_async_wait",
+		&ffi.TypeVoid,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AsyncInit initializes an async watcher, which another thread can notify
+// to wake the loop waiting on it.
+func AsyncInit(w *Watcher) error {
+	if loadErr != nil {
+		return loadErr
+	}
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(w)
+	fnAsyncInit.Call(&ret, &ptr)
+	if int32(ret) != 0 {
+		return errors.New("xev_async_init failed")
+	}
+	return nil
+}
+
+// AsyncDeinit releases the resources of an async watcher. No wait may be
+// in flight on it.
+func AsyncDeinit(w *Watcher) {
+	ptr := unsafe.Pointer(w)
+	fnAsyncDeinit.Call(nil, &ptr)
+}
+
+// AsyncNotify wakes the loop waiting on w. It is safe to call from any
+// thread; notifications sent before the wait callback runs are coalesced
+// into one.
+func AsyncNotify(w *Watcher) error {
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(w)
+	fnAsyncNotify.Call(&ret, &ptr)
+	if int32(ret) != 0 {
+		return errors.New("xev_async_notify failed")
+	}
+	return nil
+}
+
+// AsyncWait waits on w for a notification. The callback has the timer
+// signature; returning Rearm keeps waiting.
+func AsyncWait(w *Watcher, loop *Loop, c *Completion, userdata, cb uintptr) {
+	wPtr := unsafe.Pointer(w)
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	fnAsyncWait.Call(nil, &wPtr, &loopPtr, &cPtr, &userdata, &cb)
+}
+
+// AsyncWaitWithCallback registers cb and waits on w, like
+// TimerRunWithCallback. Returns the callback ID (needed for
+// UnregisterCallback).
+func AsyncWaitWithCallback(w *Watcher, loop *Loop, c *Completion, cb TimerCallback) uintptr {
+	initTimerClosure()
+	id := RegisterCallback(cb)
+	AsyncWait(w, loop, c, id, timerCallbackPtr)
+	return id
+}
@@ -35,8 +35,8 @@
 //	                                            │ lookup userdata
 //	                                            ▼
 //	                                    ┌───────────────────┐
-//	                                    │ callbackRegistry  │
-//	                                    │ (sync.Map)        │
+//	                                    │ callback registry │
+//	                                    │ (registry.go)     │
 //	                                    └───────┬───────────┘
 //	                                            │
 //	                                            ▼
@@ -49,13 +49,13 @@
 //  1. ffi.Closure: Allocated via ffi.ClosureAlloc, holds the generated thunk
 //  2. timerClosureCode: The actual executable address passed to libxev
 //  3. timerCif: CIF describing the callback's C signature
-//  4. callbackRegistry: Maps userdata IDs to Go callback functions
+//  4. callbacks: Maps userdata IDs to Go callback functions (see registry.go)
 //
 // # Thread Safety
 //
 // The callback registry uses sync.Map for concurrent access. Callbacks may be
 // invoked from any thread (though libxev typically uses a single thread).
-// The closure itself is allocated once and never freed (lives for program lifetime).
+// The closure itself is allocated once and lives until [Teardown].
 //
 // # Why userdata for dispatch?
 //
@@ -67,7 +67,6 @@ package cxev
 
 import (
 	"sync"
-	"sync/atomic"
 	"unsafe"
 
 	"github.com/jupiterrider/ffi"
@@ -85,14 +84,7 @@ import (
 //   - Rearm: Repeat with the same interval
 type TimerCallback func(loop *Loop, c *Completion, result int32, userdata uintptr) CbAction
 
-// Callback registry state.
-// We use a monotonic counter for IDs to avoid ABA problems.
-var (
-	callbackRegistry sync.Map // map[uintptr]TimerCallback
-	callbackCounter  uint64   // atomic counter for generating unique IDs
-)
-
-// Closure state - initialized once, lives forever.
+// Closure state - initialized on first use, freed by Teardown.
 // We use a single closure for all timer callbacks, dispatching via userdata.
 var (
 	timerCallbackPtr uintptr        // C-callable address to pass to libxev
@@ -107,9 +99,9 @@ var (
 //
 // # Closure Creation Steps
 //
-//  1. ClosureAlloc: Allocate memory for closure + get executable code pointer
+//  1. allocClosure: Allocate memory for closure + get executable code pointer
 //  2. PrepCif: Define the C function signature (return type + arg types)
-//  3. NewCallback: Wrap our Go trampoline as a libffi callback
+//  3. trampolineCallback: Wrap our Go trampoline as a libffi callback
 //  4. PrepClosureLoc: Wire everything together
 //
 // After this, timerClosureCode can be passed to any C function expecting a
@@ -118,7 +110,7 @@ func initTimerClosure() {
 	closureInit.Do(func() {
 		// Step 1: Allocate closure memory.
 		// timerClosureCode receives a pointer to executable memory.
-		timerClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &timerClosureCode)
+		timerClosure = allocClosure(&timerClosureCode)
 
 		// Step 2: Prepare CIF describing the callback signature.
 		// C signature: int32_t callback(void* loop, void* completion, int32_t result, void* userdata)
@@ -133,8 +125,8 @@ func initTimerClosure() {
 		}
 
 		// Step 3: Create Go callback wrapper.
-		// ffi.NewCallback returns a C function pointer that calls our Go function.
-		goCallback := ffi.NewCallback(timerTrampolineClosure)
+		// trampolineCallback returns a C function pointer that calls our Go function.
+		goCallback := trampolineCallback(timerTrampolineClosure)
 
 		// Step 4: Prepare the closure.
 		// This wires: timerClosureCode -> timerCif + goCallback
@@ -173,8 +165,8 @@ func timerTrampolineClosure(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Point
 	action := int32(Disarm)
 
 	// Look up and invoke the registered Go callback
-	if cb, ok := callbackRegistry.Load(userdata); ok {
-		action = int32(cb.(TimerCallback)(
+	if cb, ok := timerSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*Completion)(completion),
 			result,
@@ -191,15 +183,7 @@ func timerTrampolineClosure(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Point
 // Pass this ID as userdata when calling TimerRun.
 // The callback will be invoked when the timer fires.
 func RegisterCallback(cb TimerCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&callbackCounter, 1))
-	callbackRegistry.Store(id, cb)
-	return id
-}
-
-// UnregisterCallback removes a callback from the registry.
-// Call this after the timer is done to avoid memory leaks.
-func UnregisterCallback(id uintptr) {
-	callbackRegistry.Delete(id)
+	return timerSlot.register(cb)
 }
 
 // GetTimerCallbackPtr returns the C function pointer for timer callbacks.
@@ -218,3 +202,15 @@ func TimerRunWithCallback(w *Watcher, loop *Loop, c *Completion, delayMs uint64,
 	TimerRun(w, loop, c, delayMs, id, timerCallbackPtr)
 	return id
 }
+
+// TimerCancelWithCallback is a convenience function that registers the
+// callback and cancels the timer armed on c, using cCancel for the
+// cancellation. The timer's own callback is invoked with a cancellation
+// result, and cb once the cancellation is done.
+// Returns the callback ID (needed for UnregisterCallback).
+func TimerCancelWithCallback(w *Watcher, loop *Loop, c, cCancel *Completion, cb TimerCallback) uintptr {
+	initTimerClosure()
+	id := RegisterCallback(cb)
+	TimerCancel(w, loop, c, cCancel, id, timerCallbackPtr)
+	return id
+}
@@ -6,28 +6,31 @@
 
 package cxev
 
-import "sync"
-
-func mapCount(m *sync.Map) int {
+func activeCount(kinds ...CallbackKind) int {
 	count := 0
-	m.Range(func(_, _ any) bool {
-		count++
-		return true
-	})
+	for _, k := range kinds {
+		count += int(callbacks.active[k].Load())
+	}
 	return count
 }
 
 // DebugTCPCallbackCount returns the number of active TCP callback registrations.
 func DebugTCPCallbackCount() int {
-	return mapCount(&tcpCallbackRegistry) +
-		mapCount(&tcpAcceptCallbackRegistry) +
-		mapCount(&tcpReadCallbackRegistry) +
-		mapCount(&tcpWriteCallbackRegistry)
+	return activeCount(KindTCP, KindTCPAccept, KindTCPRead, KindTCPWrite)
 }
 
 // DebugUDPCallbackCount returns the number of active UDP callback registrations.
 func DebugUDPCallbackCount() int {
-	return mapCount(&udpReadCallbackRegistry) +
-		mapCount(&udpWriteCallbackRegistry) +
-		mapCount(&udpCallbackRegistry)
+	return activeCount(KindUDP, KindUDPRead, KindUDPWrite)
+}
+
+// DebugFileCallbackCount returns the number of active File callback registrations.
+func DebugFileCallbackCount() int {
+	return activeCount(KindFile, KindFileRead, KindFileWrite)
+}
+
+// DebugProcessCallbackCount returns the number of active process wait
+// callback registrations.
+func DebugProcessCallbackCount() int {
+	return activeCount(KindProcessWait)
 }
@@ -0,0 +1,173 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements pooled registrations for short-lived callbacks.
+//
+// # Why
+//
+// Connect, close and shutdown register a fresh callback for every call and
+// unregister it when the completion fires, which costs a registry insert
+// and its allocations each time. A pool keeps a set of registrations alive
+// instead: each one dispatches to a replaceable function, and is handed out
+// again once released.
+//
+// Reads are pooled the same way. A read registration pairs the callback
+// with the buffer the trampoline slices the data from, so a pooled one
+// forwards to the pair its holder registered.
+//
+// Pooled IDs go through the usual unregister path: [UnregisterCallback] on
+// a pooled ID returns it to its pool rather than deleting it, so callers
+// cannot tell pooled IDs from ordinary ones. Active counts only include
+// pooled registrations that are handed out, so leak checks keep working.
+
+package cxev
+
+import "sync"
+
+// callbackReleaser is implemented by pools that own registry entries.
+type callbackReleaser interface {
+	release(id uintptr) bool
+}
+
+// pooledCallback is one pooled registration.
+type pooledCallback[T any] struct {
+	id   uintptr
+	fn   T
+	busy bool
+}
+
+// callbackPool recycles registrations of one callback kind. wrap builds the
+// function stored in the registry, which forwards to the current fn.
+type callbackPool[T any] struct {
+	kind CallbackKind
+	wrap func(p *callbackPool[T], pc *pooledCallback[T]) T
+
+	mu   sync.Mutex
+	free []*pooledCallback[T]
+	byID map[uintptr]*pooledCallback[T]
+}
+
+func (p *callbackPool[T]) get(fn T) uintptr {
+	p.mu.Lock()
+	var pc *pooledCallback[T]
+	if n := len(p.free); n > 0 {
+		pc = p.free[n-1]
+		p.free = p.free[:n-1]
+	} else {
+		pc = &pooledCallback[T]{}
+		pc.id = callbacks.registerPooled(p.kind, p.wrap(p, pc), p)
+		if p.byID == nil {
+			p.byID = make(map[uintptr]*pooledCallback[T])
+		}
+		p.byID[pc.id] = pc
+	}
+	pc.fn = fn
+	pc.busy = true
+	p.mu.Unlock()
+
+	callbacks.active[p.kind].Add(1)
+	callbacks.total[p.kind].Add(1)
+	return pc.id
+}
+
+// current returns the function pc dispatches to, or false once released.
+func (p *callbackPool[T]) current(pc *pooledCallback[T]) (T, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return pc.fn, pc.busy
+}
+
+func (p *callbackPool[T]) release(id uintptr) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc := p.byID[id]
+	if pc == nil || !pc.busy {
+		return false
+	}
+	var zero T
+	pc.fn = zero
+	pc.busy = false
+	p.free = append(p.free, pc)
+	callbacks.active[p.kind].Add(-1)
+	return true
+}
+
+// tcpCallbackPool serves the connect, close and shutdown callbacks.
+var tcpCallbackPool = callbackPool[TCPCallback]{
+	kind: KindTCP,
+	wrap: func(p *callbackPool[TCPCallback], pc *pooledCallback[TCPCallback]) TCPCallback {
+		return func(loop *Loop, c *TCPCompletion, result int32, userdata uintptr) CbAction {
+			fn, ok := p.current(pc)
+			if !ok {
+				return Disarm
+			}
+			return fn(loop, c, result, userdata)
+		}
+	},
+}
+
+// RegisterPooledTCPCallback registers cb like [RegisterTCPCallback], but
+// reuses an ID released earlier when one is available. Unregistering the
+// ID releases it.
+func RegisterPooledTCPCallback(cb TCPCallback) uintptr {
+	return tcpCallbackPool.get(cb)
+}
+
+// readContext is the registration of a read: its callback and the buffer
+// the trampoline slices the data from. A pooled registration sets current
+// instead, which returns the context of its holder.
+type readContext[F any] struct {
+	cb      F
+	buf     []byte
+	current func() (readContext[F], bool)
+}
+
+// loadRead returns the read context registered under id, resolving a
+// pooled registration to its holder's.
+func loadRead[F any](s slot[readContext[F]], id uintptr) (readContext[F], bool) {
+	r, ok := s.lookup(id)
+	if !ok || r.current == nil {
+		return r, ok
+	}
+	return r.current()
+}
+
+// data returns the bytes a read of n bytes left in the buffer.
+func (r readContext[F]) data(n int32) []byte {
+	if n <= 0 {
+		return nil
+	}
+	return r.buf[:n]
+}
+
+func wrapPooledRead[F any](p *callbackPool[readContext[F]], pc *pooledCallback[readContext[F]]) readContext[F] {
+	return readContext[F]{current: func() (readContext[F], bool) { return p.current(pc) }}
+}
+
+// Read pools, one per transport.
+var (
+	tcpReadPool  = callbackPool[tcpReadContext]{kind: KindTCPRead, wrap: wrapPooledRead[TCPReadCallback]}
+	udpReadPool  = callbackPool[udpReadContext]{kind: KindUDPRead, wrap: wrapPooledRead[UDPReadCallback]}
+	fileReadPool = callbackPool[fileReadContext]{kind: KindFileRead, wrap: wrapPooledRead[FileReadCallback]}
+)
+
+// RegisterPooledTCPReadCallback registers cb and buf like
+// [RegisterTCPReadCallback], reusing a released ID when one is available.
+func RegisterPooledTCPReadCallback(cb TCPReadCallback, buf []byte) uintptr {
+	return tcpReadPool.get(tcpReadContext{cb: cb, buf: buf})
+}
+
+// RegisterPooledUDPReadCallback is the UDP counterpart of
+// [RegisterPooledTCPReadCallback].
+func RegisterPooledUDPReadCallback(cb UDPReadCallback, buf []byte) uintptr {
+	return udpReadPool.get(udpReadContext{cb: cb, buf: buf})
+}
+
+// RegisterPooledFileReadCallback is the file counterpart of
+// [RegisterPooledTCPReadCallback].
+func RegisterPooledFileReadCallback(cb FileReadCallback, buf []byte) uintptr {
+	return fileReadPool.get(fileReadContext{cb: cb, buf: buf})
+}
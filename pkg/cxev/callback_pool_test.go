@@ -0,0 +1,160 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestPooledTCPCallbackReuse(t *testing.T) {
+	before := DebugTCPCallbackCount()
+	calls := 0
+	cb := func(loop *Loop, c *TCPCompletion, result int32, userdata uintptr) CbAction {
+		calls++
+		return Disarm
+	}
+
+	id := RegisterPooledTCPCallback(cb)
+	if got := DebugTCPCallbackCount(); got != before+1 {
+		t.Fatalf("active tcp callbacks = %d, want %d", got, before+1)
+	}
+	fn, ok := tcpSlot.load(id)
+	if !ok {
+		t.Fatal("pooled callback not visible to the tcp slot")
+	}
+	fn(nil, nil, 0, id)
+	if calls != 1 {
+		t.Fatalf("pooled callback ran %d times", calls)
+	}
+
+	if !UnregisterCallback(id) {
+		t.Fatal("first unregister should release the callback")
+	}
+	if UnregisterCallback(id) {
+		t.Fatal("second unregister should be a no-op")
+	}
+	if got := DebugTCPCallbackCount(); got != before {
+		t.Fatalf("active tcp callbacks = %d, want %d", got, before)
+	}
+	// A released registration no longer reaches the old function.
+	if fn(nil, nil, 0, id) != Disarm || calls != 1 {
+		t.Fatal("released callback still dispatched")
+	}
+
+	if again := RegisterPooledTCPCallback(cb); again != id {
+		t.Fatalf("expected released id %d to be reused, got %d", id, again)
+	}
+	UnregisterTCPCallback(id)
+	if err := CheckCallbackLeaks(); err != nil {
+		t.Fatalf("unexpected leak: %v", err)
+	}
+}
+
+// readArgs fabricates the arguments libxev passes to the TCP read
+// trampoline for a read of n bytes under id.
+type readArgs struct {
+	loop, comp, buf unsafe.Pointer
+	n, errCode      int32
+	id              uintptr
+	ret             int32
+	args            [6]unsafe.Pointer
+}
+
+func newReadArgs() *readArgs {
+	a := &readArgs{}
+	a.args = [6]unsafe.Pointer{
+		unsafe.Pointer(&a.loop), unsafe.Pointer(&a.comp), unsafe.Pointer(&a.buf),
+		unsafe.Pointer(&a.n), unsafe.Pointer(&a.errCode), unsafe.Pointer(&a.id),
+	}
+	return a
+}
+
+func (a *readArgs) dispatch(id uintptr, n int32) CbAction {
+	a.id, a.n = id, n
+	tcpReadTrampoline(nil, unsafe.Pointer(&a.ret), &a.args[0], nil)
+	return CbAction(a.ret)
+}
+
+func TestPooledTCPReadCallback(t *testing.T) {
+	var got []byte
+	cb := func(loop *Loop, c *TCPCompletion, data []byte, n, errCode int32, id uintptr) CbAction {
+		got = data
+		return Rearm
+	}
+	a := newReadArgs()
+
+	first := []byte("first buffer")
+	id := RegisterPooledTCPReadCallback(cb, first)
+	if a.dispatch(id, 5) != Rearm || string(got) != "first" {
+		t.Fatalf("dispatched %q", got)
+	}
+	UnregisterTCPCallback(id)
+	if got = nil; a.dispatch(id, 5) != Disarm || got != nil {
+		t.Fatal("released read registration still dispatched")
+	}
+
+	// The reused registration slices the buffer of its new holder.
+	second := []byte("second buffer")
+	if again := RegisterPooledTCPReadCallback(cb, second); again != id {
+		t.Fatalf("expected released id %d to be reused, got %d", id, again)
+	}
+	if a.dispatch(id, 6); string(got) != "second" {
+		t.Fatalf("dispatched %q after reuse", got)
+	}
+	UnregisterTCPCallback(id)
+	if err := CheckCallbackLeaks(); err != nil {
+		t.Fatalf("unexpected leak: %v", err)
+	}
+}
+
+// TestPooledReadCycleDoesNotAllocate registers, dispatches and releases a
+// read the way a connection that stops and restarts reading does.
+func TestPooledReadCycleDoesNotAllocate(t *testing.T) {
+	buf := make([]byte, 64)
+	cb := func(loop *Loop, c *TCPCompletion, data []byte, n, errCode int32, id uintptr) CbAction {
+		return Disarm
+	}
+	a := newReadArgs()
+	cycle := func() {
+		id := RegisterPooledTCPReadCallback(cb, buf)
+		a.dispatch(id, 32)
+		UnregisterTCPCallback(id)
+	}
+	cycle() // warm the pool
+	if allocs := testing.AllocsPerRun(1000, cycle); allocs != 0 {
+		t.Fatalf("read cycle allocates %.1f times", allocs)
+	}
+	steady := func() { a.dispatch(a.id, 32) }
+	a.id = RegisterPooledTCPReadCallback(cb, buf)
+	defer UnregisterTCPCallback(a.id)
+	if allocs := testing.AllocsPerRun(1000, steady); allocs != 0 {
+		t.Fatalf("re-armed read dispatch allocates %.1f times", allocs)
+	}
+}
+
+func benchmarkTCPCallback(b *testing.B, register func(TCPCallback) uintptr) {
+	cb := func(loop *Loop, c *TCPCompletion, result int32, userdata uintptr) CbAction {
+		return Disarm
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		id := register(cb)
+		if fn, ok := tcpSlot.load(id); ok {
+			fn(nil, nil, 0, id)
+		}
+		UnregisterTCPCallback(id)
+	}
+}
+
+func BenchmarkTCPCallbackRegister(b *testing.B) {
+	benchmarkTCPCallback(b, RegisterTCPCallback)
+}
+
+func BenchmarkTCPCallbackPooled(b *testing.B) {
+	benchmarkTCPCallback(b, RegisterPooledTCPCallback)
+}
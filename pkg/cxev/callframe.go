@@ -0,0 +1,90 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements argument frames for calls into libxev that sit on
+// hot paths.
+//
+// # Why
+//
+// ffi.Fun.Call takes each argument as a pointer to its value boxed in an
+// interface, so every argument escapes to the heap and submitting a read,
+// or running the loop once, costs an allocation per argument. A callFrame
+// holds the argument values and the pointers to them in one pooled object.
+// ffi.Call would still allocate the arguments of its own call into libffi,
+// so a frame calls ffi_call itself when it can find it.
+//
+// Pointer arguments are kept in a pointer-typed array, so the objects they
+// refer to stay reachable for the duration of the call.
+
+package cxev
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+	"github.com/jupiterrider/ffi"
+)
+
+// maxFrameArgs is the largest argument count of a framed call.
+const maxFrameArgs = 9
+
+type callFrame struct {
+	args  [maxFrameArgs]unsafe.Pointer
+	ptrs  [maxFrameArgs]unsafe.Pointer
+	words [maxFrameArgs]uint64
+	n     int
+	ret   ffi.Arg
+	// sys holds the arguments of ffi_call.
+	sys [4]uintptr
+}
+
+var framePool = sync.Pool{New: func() any { return new(callFrame) }}
+
+// ffiCall returns the address of libffi's ffi_call, or zero if it cannot
+// be found and frames go through ffi.Call.
+var ffiCall = sync.OnceValue(lookupFFICall)
+
+func getFrame() *callFrame {
+	return framePool.Get().(*callFrame)
+}
+
+// ptr appends a pointer argument.
+func (f *callFrame) ptr(p unsafe.Pointer) *callFrame {
+	f.ptrs[f.n] = p
+	f.args[f.n] = unsafe.Pointer(&f.ptrs[f.n])
+	f.n++
+	return f
+}
+
+// word appends an integer argument of up to 64 bits. Narrower arguments
+// are read from its low bytes, as on the little-endian targets supported.
+func (f *callFrame) word(w uint64) *callFrame {
+	f.words[f.n] = w
+	f.args[f.n] = unsafe.Pointer(&f.words[f.n])
+	f.n++
+	return f
+}
+
+// call calls fn with the arguments appended so far, returns the frame to
+// the pool and returns the result of fn, which is zero if it returns void.
+func (f *callFrame) call(fn *ffi.Fun) ffi.Arg {
+	f.ret = 0
+	if addr := ffiCall(); addr != 0 {
+		f.sys = [4]uintptr{
+			uintptr(unsafe.Pointer(fn.Cif)), fn.Addr,
+			uintptr(unsafe.Pointer(&f.ret)), uintptr(unsafe.Pointer(&f.args[0])),
+		}
+		purego.SyscallN(addr, f.sys[:]...)
+	} else {
+		ffi.Call(fn.Cif, fn.Addr, unsafe.Pointer(&f.ret), f.args[:f.n]...)
+	}
+	ret := f.ret
+	f.ptrs = [maxFrameArgs]unsafe.Pointer{}
+	f.n = 0
+	framePool.Put(f)
+	return ret
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || freebsd || linux)
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+func lookupFFICall() uintptr {
+	return 0
+}
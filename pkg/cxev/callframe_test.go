@@ -0,0 +1,58 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// libcFuns prepares memset and abs, which take the argument kinds a frame
+// passes: pointers, 64-bit words and a 32-bit integer.
+func libcFuns(t *testing.T) (memset, abs ffi.Fun) {
+	t.Helper()
+	name := "libc.so.6"
+	if runtime.GOOS == "darwin" {
+		name = "libSystem.B.dylib"
+	}
+	libc, err := ffi.Load(name)
+	if err != nil {
+		t.Skipf("libc not loadable: %v", err)
+	}
+	if memset, err = libc.Prep("memset", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeUint64); err != nil {
+		t.Fatal(err)
+	}
+	if abs, err = libc.Prep("abs", &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		t.Fatal(err)
+	}
+	return memset, abs
+}
+
+func TestCallFrame(t *testing.T) {
+	memset, abs := libcFuns(t)
+
+	buf := make([]byte, 8)
+	ret := getFrame().ptr(unsafe.Pointer(&buf[0])).word('x').word(5).call(&memset)
+	if string(buf) != "xxxxx\x00\x00\x00" {
+		t.Fatalf("memset wrote %q", buf)
+	}
+	if uintptr(ret) != uintptr(unsafe.Pointer(&buf[0])) {
+		t.Fatal("memset result not returned")
+	}
+	if got := int32(getFrame().word(uint64(uint32(-42 & 0xffffffff))).call(&abs)); got != 42 {
+		t.Fatalf("abs(-42) = %d", got)
+	}
+
+	call := func() { getFrame().ptr(unsafe.Pointer(&buf[0])).word('y').word(8).call(&memset) }
+	call()
+	if allocs := testing.AllocsPerRun(1000, call); allocs != 0 {
+		t.Fatalf("framed call allocates %.1f times", allocs)
+	}
+}
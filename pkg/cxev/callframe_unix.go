@@ -0,0 +1,29 @@
+//go:build darwin || freebsd || linux
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import "github.com/ebitengine/purego"
+
+func lookupFFICall() uintptr {
+	if addr, err := purego.Dlsym(purego.RTLD_DEFAULT, "ffi_call"); err == nil {
+		return addr
+	}
+	// Libraries are loaded locally on Linux and FreeBSD, so ffi_call is
+	// only found through a handle to libffi. Opening it again returns the
+	// one the ffi package loaded.
+	h, err := purego.Dlopen("libffi.so.8", purego.RTLD_LAZY)
+	if err != nil {
+		return 0
+	}
+	addr, err := purego.Dlsym(h, "ffi_call")
+	if err != nil {
+		return 0
+	}
+	return addr
+}
@@ -114,6 +114,18 @@ type LoopOptions struct {
 	_ uint32
 }
 
+// LoopTuning is the backend tuning passed to LoopInitTuned, matching
+// xev_loop_tuning in the extended library. The zero value changes nothing.
+type LoopTuning struct {
+	// SQPollIdleMs is how long, in milliseconds, the io_uring SQPOLL thread
+	// polls the submission queue before it sleeps, after which submitting
+	// enters the kernel to wake it. Zero leaves SQPOLL off.
+	SQPollIdleMs uint32
+
+	// Reserved for further knobs; must be zero.
+	_ uint32
+}
+
 // Completion represents a pending I/O operation or timer.
 // Each operation (timer fire, async notification, etc.) requires its own
 // Completion instance. The completion tracks the operation state and stores
@@ -184,7 +196,8 @@ const (
 )
 
 // Package-level state for library loading.
-// The library is loaded once on package init and the result is cached.
+// The library is loaded on package init and the result is cached until
+// Teardown.
 var (
 	lib     ffi.Lib // Handle to the loaded libxev shared library
 	libExt  ffi.Lib // Handle to the extended API library (TCP, etc.)
@@ -201,6 +214,13 @@ var (
 // After loading, all FFI function descriptors are prepared. Any error
 // is stored in loadErr and can be retrieved via LoadError().
 func init() {
+	Load()
+}
+
+// Load loads the libraries if they are not loaded and returns the result,
+// like [LoadError]. Loading happens on import, so Load is only needed to
+// load them again after [Teardown].
+func Load() error {
 	once.Do(func() {
 		lib, loadErr = ffi.Load(libPath())
 		if loadErr != nil {
@@ -230,9 +250,14 @@ func init() {
 			if loadErr != nil {
 				return
 			}
+			loadErr = registerProcessFunctions()
+			if loadErr != nil {
+				return
+			}
 			loadErr = registerExtendedFunctions()
 		}
 	})
+	return loadErr
 }
 
 // libPath determines the path to the libxev shared library.
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// ErrExtLibNotLoaded is reported by the functions of the extended API (TCP,
+// UDP, File and the extended loop functions) when the extended library is
+// not loaded. Set the LIBXEV_EXT_PATH environment variable to the path of
+// libxev_extended.dylib/.so/.dll.
+//
+// Functions that return an error return it. The others, which start
+// operations or only read state, panic with it: calling them is a bug,
+// since the objects they take cannot be set up without the library, and
+// the panic replaces a crash inside the library call.
+var ErrExtLibNotLoaded = errors.New("extended library (TCP support) not loaded; set LIBXEV_EXT_PATH")
+
+// extLoaded returns the error an extended API function reports when it
+// cannot run: ErrExtLibNotLoaded, wrapping the error that stopped the
+// libraries from loading if there is one, or that error alone if it came
+// after the extended library loaded.
+func extLoaded() error {
+	if !ExtLibLoaded() {
+		if loadErr != nil {
+			return fmt.Errorf("%w: %w", ErrExtLibNotLoaded, loadErr)
+		}
+		return ErrExtLibNotLoaded
+	}
+	return loadErr
+}
+
+// mustExtLoaded panics if the extended API function name cannot run.
+func mustExtLoaded(name string) {
+	if err := extLoaded(); err != nil {
+		panic(fmt.Errorf("cxev.%s: %w", name, err))
+	}
+}
+
+// Error codes delivered to TCP/UDP/File callbacks by the extended library are
+// Zig error values, not errno. These helpers translate them into something a
+// Go caller can act on.
+
+var (
+	fnErrorErrno ffi.Fun
+	fnErrorName  ffi.Fun
+)
+
+func registerErrorFunctions() error {
+	var err error
+
+	// int xev_error_errno(int code)
+	fnErrorErrno, err = libExt.Prep("xev_error_errno", &ffi.TypeSint32, &ffi.TypeSint32)
+	if err != nil {
+		return err
+	}
+
+	// const char* xev_error_name(int code)
+	fnErrorName, err = libExt.Prep("xev_error_name", &ffi.TypePointer, &ffi.TypeSint32)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ErrnoFromCode maps an extended API error code to the matching errno.
+// It returns 0 if the code has no errno equivalent or the extended library
+// is not loaded.
+func ErrnoFromCode(code int32) syscall.Errno {
+	if code == 0 || !ExtLibLoaded() {
+		return 0
+	}
+	var ret ffi.Arg
+	fnErrorErrno.Call(&ret, &code)
+	return syscall.Errno(int32(ret))
+}
+
+// ErrorName returns the library's name for an extended API error code, such
+// as "ProcessFdQuotaExceeded". If the name is unavailable it falls back to
+// the decimal code.
+func ErrorName(code int32) string {
+	if code == 0 || !ExtLibLoaded() {
+		return strconv.Itoa(int(code))
+	}
+	var ret unsafe.Pointer
+	fnErrorName.Call(&ret, &code)
+	if ret == nil {
+		return strconv.Itoa(int(code))
+	}
+	return cString(ret)
+}
+
+// cString copies a NUL-terminated C string.
+func cString(p unsafe.Pointer) string {
+	n := 0
+	for *(*byte)(unsafe.Add(p, n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(p), n))
+}
@@ -0,0 +1,59 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExtendedAPIWithoutLibrary(t *testing.T) {
+	if ExtLibLoaded() {
+		t.Skip("extended library loaded")
+	}
+
+	var tcp TCP
+	var udp UDP
+	var addr Sockaddr
+	for name, err := range map[string]error{
+		"TCPInit":             TCPInit(&tcp, 2),
+		"TCPBind":             TCPBind(&tcp, &addr),
+		"TCPListen":           TCPListen(&tcp, 1),
+		"UDPInit":             UDPInit(&udp, 2),
+		"UDPBind":             UDPBind(&udp, &addr),
+		"UDPReadMsg":          UDPReadMsg(&udp, nil, nil, nil, nil, nil, 0, 0),
+		"LoopInitWithOptions": LoopInitWithOptions(nil, nil),
+		"LoopSubmit":          LoopSubmit(nil),
+		"TCPPollWritable":     TCPPollWritable(nil, nil, nil, 0, 0),
+	} {
+		if !errors.Is(err, ErrExtLibNotLoaded) {
+			t.Errorf("%s: %v, want ErrExtLibNotLoaded", name, err)
+		}
+	}
+
+	// Functions without an error result panic with it instead.
+	for name, call := range map[string]func(){
+		"TCPInitFd":          func() { TCPInitFd(&tcp, 3) },
+		"TCPRead":            func() { TCPRead(&tcp, nil, nil, make([]byte, 1), 0, 0) },
+		"UDPClose":           func() { UDPClose(&udp, nil, nil, 0, 0) },
+		"FileRead":           func() { FileRead(nil, nil, nil, make([]byte, 1), 0, 0) },
+		"LoopAlive":          func() { LoopAlive(nil) },
+		"LoopFd":             func() { LoopFd(nil) },
+		"LoopSetStopped":     func() { LoopSetStopped(nil, true) },
+		"ThreadPoolGetStats": func() { ThreadPoolGetStats(nil) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrExtLibNotLoaded) {
+					t.Errorf("%s: panicked with %v, want ErrExtLibNotLoaded", name, err)
+				}
+			}()
+			call()
+		}()
+	}
+}
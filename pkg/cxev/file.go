@@ -8,7 +8,6 @@ package cxev
 
 import (
 	"sync"
-	"sync/atomic"
 	"unsafe"
 
 	"github.com/jupiterrider/ffi"
@@ -93,12 +92,14 @@ func registerFileFunctions() error {
 
 // FileInitFd initializes a File from an existing file descriptor.
 func FileInitFd(file *File, fd int32) {
+	mustExtLoaded("FileInitFd")
 	ptr := unsafe.Pointer(file)
 	fnFileInitFd.Call(nil, &ptr, &fd)
 }
 
 // FileFd returns the file descriptor of a File.
 func FileFd(file *File) int32 {
+	mustExtLoaded("FileFd")
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(file)
 	fnFileFd.Call(&ret, &ptr)
@@ -127,17 +128,9 @@ type FileWriteCallback func(loop *Loop, c *FileCompletion, bytesWritten int32, e
 // result is 0 on success, or an error code on failure.
 type FileCallback func(loop *Loop, c *FileCompletion, result int32, userdata uintptr) CbAction
 
-// File callback registry - separate from TCP to allow independent cleanup.
-var (
-	fileCallbackRegistry      sync.Map
-	fileReadCallbackRegistry  sync.Map
-	fileWriteCallbackRegistry sync.Map
-	fileCallbackCounter       uint64
-)
-
 // File callback closure state.
 // The callback signatures match TCP's, so we can reuse the same CIF structures,
-// but we create separate closures to keep the registries independent.
+// but we create separate closures so each trampoline only dispatches its own kind.
 var (
 	fileClosureInit sync.Once
 
@@ -160,42 +153,42 @@ var (
 func initFileClosures() {
 	fileClosureInit.Do(func() {
 		// File simple callback: (loop*, completion*, result int32, userdata*) -> int32
-		fileClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &fileClosureCode)
+		fileClosure = allocClosure(&fileClosureCode)
 		if status := ffi.PrepCif(&fileCif, ffi.DefaultAbi, 4,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare File callback CIF")
 		}
-		goCallback := ffi.NewCallback(fileTrampoline)
+		goCallback := trampolineCallback(fileTrampoline)
 		if status := ffi.PrepClosureLoc(fileClosure, &fileCif, goCallback, nil, fileClosureCode); status != ffi.OK {
 			panic("failed to prepare File closure")
 		}
 		fileCallbackPtr = uintptr(fileClosureCode)
 
 		// File read callback: (loop*, completion*, buf*, bytes_read int32, err int32, userdata*) -> int32
-		fileReadClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &fileReadCode)
+		fileReadClosure = allocClosure(&fileReadCode)
 		if status := ffi.PrepCif(&fileReadCif, ffi.DefaultAbi, 6,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare File read callback CIF")
 		}
-		goReadCallback := ffi.NewCallback(fileReadTrampoline)
+		goReadCallback := trampolineCallback(fileReadTrampoline)
 		if status := ffi.PrepClosureLoc(fileReadClosure, &fileReadCif, goReadCallback, nil, fileReadCode); status != ffi.OK {
 			panic("failed to prepare File read closure")
 		}
 		fileReadCallbackPtr = uintptr(fileReadCode)
 
 		// File write callback: (loop*, completion*, bytes_written int32, err int32, userdata*) -> int32
-		fileWriteClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &fileWriteCode)
+		fileWriteClosure = allocClosure(&fileWriteCode)
 		if status := ffi.PrepCif(&fileWriteCif, ffi.DefaultAbi, 5,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare File write callback CIF")
 		}
-		goWriteCallback := ffi.NewCallback(fileWriteTrampoline)
+		goWriteCallback := trampolineCallback(fileWriteTrampoline)
 		if status := ffi.PrepClosureLoc(fileWriteClosure, &fileWriteCif, goWriteCallback, nil, fileWriteCode); status != ffi.OK {
 			panic("failed to prepare File write closure")
 		}
@@ -211,8 +204,8 @@ func fileTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, user
 	userdata := *(*uintptr)(arguments[3])
 
 	action := int32(Disarm)
-	if cb, ok := fileCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(FileCallback)(
+	if cb, ok := fileSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*FileCompletion)(completion),
 			result,
@@ -223,11 +216,8 @@ func fileTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, user
 	return 0
 }
 
-// fileReadContext holds the buffer pointer and length for read callbacks.
-type fileReadContext struct {
-	cb  FileReadCallback
-	buf []byte
-}
+// fileReadContext is the registration of a file read.
+type fileReadContext = readContext[FileReadCallback]
 
 func fileReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
 	arguments := unsafe.Slice(args, 6)
@@ -239,16 +229,11 @@ func fileReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	userdata := *(*uintptr)(arguments[5])
 
 	action := int32(Disarm)
-	if ctx, ok := fileReadCallbackRegistry.Load(userdata); ok {
-		readCtx := ctx.(fileReadContext)
-		var buf []byte
-		if bytesRead > 0 {
-			buf = readCtx.buf[:bytesRead]
-		}
+	if readCtx, ok := loadRead(fileReadSlot, userdata); ok {
 		action = int32(readCtx.cb(
 			(*Loop)(loop),
 			(*FileCompletion)(completion),
-			buf,
+			readCtx.data(bytesRead),
 			bytesRead,
 			errCode,
 			userdata,
@@ -273,8 +258,7 @@ func fileWriteTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	userdata := *(*uintptr)(arguments[4])
 
 	action := int32(Disarm)
-	if ctx, ok := fileWriteCallbackRegistry.Load(userdata); ok {
-		writeCtx := ctx.(fileWriteContext)
+	if writeCtx, ok := fileWriteSlot.lookup(userdata); ok {
 		action = int32(writeCtx.cb(
 			(*Loop)(loop),
 			(*FileCompletion)(completion),
@@ -289,30 +273,23 @@ func fileWriteTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 
 // RegisterFileCallback registers a File callback and returns its unique ID.
 func RegisterFileCallback(cb FileCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&fileCallbackCounter, 1))
-	fileCallbackRegistry.Store(id, cb)
-	return id
+	return fileSlot.register(cb)
 }
 
 // RegisterFileReadCallback registers a File read callback with its buffer.
 func RegisterFileReadCallback(cb FileReadCallback, buf []byte) uintptr {
-	id := uintptr(atomic.AddUint64(&fileCallbackCounter, 1))
-	fileReadCallbackRegistry.Store(id, fileReadContext{cb: cb, buf: buf})
-	return id
+	return fileReadSlot.register(fileReadContext{cb: cb, buf: buf})
 }
 
 // RegisterFileWriteCallback registers a File write callback with its buffer.
 func RegisterFileWriteCallback(cb FileWriteCallback, buf []byte) uintptr {
-	id := uintptr(atomic.AddUint64(&fileCallbackCounter, 1))
-	fileWriteCallbackRegistry.Store(id, fileWriteContext{cb: cb, buf: buf})
-	return id
+	return fileWriteSlot.register(fileWriteContext{cb: cb, buf: buf})
 }
 
 // UnregisterFileCallback removes a File callback from the registry.
+// It is equivalent to [UnregisterCallback].
 func UnregisterFileCallback(id uintptr) {
-	fileCallbackRegistry.Delete(id)
-	fileReadCallbackRegistry.Delete(id)
-	fileWriteCallbackRegistry.Delete(id)
+	callbacks.unregister(id)
 }
 
 // GetFileCallbackPtr returns the C function pointer for File callbacks.
@@ -334,25 +311,28 @@ func GetFileWriteCallbackPtr() uintptr {
 }
 
 // FileRead starts reading from a file at the current position.
+// It does not allocate.
 func FileRead(file *File, loop *Loop, c *FileCompletion, buf []byte, userdata, cb uintptr) {
-	filePtr := unsafe.Pointer(file)
-	loopPtr := unsafe.Pointer(loop)
-	cPtr := unsafe.Pointer(c)
-	bufPtr := bufferPointer(buf)
-	bufLen := uint64(len(buf))
-	fnFileRead.Call(nil, &filePtr, &loopPtr, &cPtr, &bufPtr, &bufLen, &cb, &userdata)
+	mustExtLoaded("FileRead")
+	getFrame().
+		ptr(unsafe.Pointer(file)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).
+		word(uint64(cb)).word(uint64(userdata)).
+		call(&fnFileRead)
 }
 
 // FileReadWithCallback is a convenience function that registers the callback and starts reading.
+// Like [TCPReadWithCallback], it pools the registration.
 func FileReadWithCallback(file *File, loop *Loop, c *FileCompletion, buf []byte, cb FileReadCallback) uintptr {
 	initFileClosures()
-	id := RegisterFileReadCallback(cb, buf)
+	id := RegisterPooledFileReadCallback(cb, buf)
 	FileRead(file, loop, c, buf, id, fileReadCallbackPtr)
 	return id
 }
 
 // FileWrite starts writing to a file at the current position.
 func FileWrite(file *File, loop *Loop, c *FileCompletion, buf []byte, userdata, cb uintptr) {
+	mustExtLoaded("FileWrite")
 	filePtr := unsafe.Pointer(file)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -370,25 +350,28 @@ func FileWriteWithCallback(file *File, loop *Loop, c *FileCompletion, buf []byte
 }
 
 // FilePRead starts reading from a file at a specific offset.
+// It does not allocate.
 func FilePRead(file *File, loop *Loop, c *FileCompletion, buf []byte, offset uint64, userdata, cb uintptr) {
-	filePtr := unsafe.Pointer(file)
-	loopPtr := unsafe.Pointer(loop)
-	cPtr := unsafe.Pointer(c)
-	bufPtr := bufferPointer(buf)
-	bufLen := uint64(len(buf))
-	fnFilePRead.Call(nil, &filePtr, &loopPtr, &cPtr, &bufPtr, &bufLen, &offset, &cb, &userdata)
+	mustExtLoaded("FilePRead")
+	getFrame().
+		ptr(unsafe.Pointer(file)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).word(offset).
+		word(uint64(cb)).word(uint64(userdata)).
+		call(&fnFilePRead)
 }
 
 // FilePReadWithCallback is a convenience function for positional read.
+// Like [TCPReadWithCallback], it pools the registration.
 func FilePReadWithCallback(file *File, loop *Loop, c *FileCompletion, buf []byte, offset uint64, cb FileReadCallback) uintptr {
 	initFileClosures()
-	id := RegisterFileReadCallback(cb, buf)
+	id := RegisterPooledFileReadCallback(cb, buf)
 	FilePRead(file, loop, c, buf, offset, id, fileReadCallbackPtr)
 	return id
 }
 
 // FilePWrite starts writing to a file at a specific offset.
 func FilePWrite(file *File, loop *Loop, c *FileCompletion, buf []byte, offset uint64, userdata, cb uintptr) {
+	mustExtLoaded("FilePWrite")
 	filePtr := unsafe.Pointer(file)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -407,6 +390,7 @@ func FilePWriteWithCallback(file *File, loop *Loop, c *FileCompletion, buf []byt
 
 // FileClose starts closing a file.
 func FileClose(file *File, loop *Loop, c *FileCompletion, userdata, cb uintptr) {
+	mustExtLoaded("FileClose")
 	filePtr := unsafe.Pointer(file)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
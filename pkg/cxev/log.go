@@ -0,0 +1,54 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// Logger receives reports of internal conditions that have no caller to
+// return an error to, such as a completion for a callback that is no
+// longer registered or a descriptor that fails to close. [*slog.Logger]
+// implements it.
+type Logger interface {
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type loggerBox struct{ Logger }
+
+var logger atomic.Pointer[loggerBox]
+
+// SetLogger sets the logger for internal conditions of this package and
+// of the packages built on it. A nil l restores the default, which is
+// [slog.Default] at the time of each report.
+func SetLogger(l Logger) {
+	if l == nil {
+		logger.Store(nil)
+		return
+	}
+	logger.Store(&loggerBox{l})
+}
+
+// GetLogger returns the logger set by SetLogger, or the default one.
+func GetLogger() Logger {
+	if b := logger.Load(); b != nil {
+		return b.Logger
+	}
+	return slog.Default()
+}
+
+// lookup is load for a trampoline: a completion whose registration is gone
+// is disarmed by the caller, and reported here.
+func (s slot[T]) lookup(id uintptr) (T, bool) {
+	v, ok := s.load(id)
+	if !ok {
+		GetLogger().Warn("cxev: completion for an unregistered callback", "kind", s.kind, "id", id)
+	}
+	return v, ok
+}
@@ -0,0 +1,40 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"log/slog"
+	"testing"
+)
+
+type recordingLogger struct{ msgs []string }
+
+func (l *recordingLogger) Warn(msg string, _ ...any)  { l.msgs = append(l.msgs, "warn: "+msg) }
+func (l *recordingLogger) Error(msg string, _ ...any) { l.msgs = append(l.msgs, "error: "+msg) }
+
+func TestLoggerReportsUnregisteredCompletion(t *testing.T) {
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+
+	id := RegisterTCPWriteCallback(nil)
+	if _, ok := tcpWriteSlot.lookup(id); !ok {
+		t.Fatal("registered callback not found")
+	}
+	UnregisterTCPCallback(id)
+	if _, ok := tcpWriteSlot.lookup(id); ok {
+		t.Fatal("unregistered callback found")
+	}
+	if len(rec.msgs) != 1 || rec.msgs[0] != "warn: cxev: completion for an unregistered callback" {
+		t.Fatalf("logged %q", rec.msgs)
+	}
+
+	SetLogger(nil)
+	if GetLogger() != slog.Default() {
+		t.Fatal("SetLogger(nil) did not restore the default logger")
+	}
+}
@@ -8,6 +8,8 @@ package cxev
 
 import (
 	"errors"
+	"fmt"
+	"runtime"
 	"unsafe"
 
 	"github.com/jupiterrider/ffi"
@@ -23,10 +25,22 @@ import (
 var (
 	fnLoopInit            ffi.Fun
 	fnLoopInitWithOptions ffi.Fun
+	fnLoopInitTuned       ffi.Fun
+	fnLoopSubmitThreshold ffi.Fun
 	fnLoopDeinit          ffi.Fun
 	fnLoopRun             ffi.Fun
 	fnLoopNow             ffi.Fun
 	fnLoopUpdateNow       ffi.Fun
+	fnLoopAlive           ffi.Fun
+	fnLoopActive          ffi.Fun
+	fnLoopFd              ffi.Fun
+	fnLoopBackend         ffi.Fun
+	fnLoopFeatures        ffi.Fun
+	fnLoopSubmit          ffi.Fun
+	fnLoopSetStopped      ffi.Fun
+	fnLoopRegisterBuffers ffi.Fun
+	fnLoopRegisterFiles   ffi.Fun
+	fnLoopUpdateFile      ffi.Fun
 )
 
 // registerFunctions prepares all FFI function descriptors.
@@ -101,6 +115,68 @@ func registerExtendedFunctions() error {
 		if err != nil {
 			return err
 		}
+		// int xev_loop_init_tuned(xev_loop* loop, xev_options* options, xev_loop_tuning* tuning)
+		fnLoopInitTuned, err = libExt.Prep("xev_loop_init_tuned", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_submit_threshold(xev_loop* loop, uint32_t threshold)
+		fnLoopSubmitThreshold, err = libExt.Prep("xev_loop_submit_threshold", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint32)
+		if err != nil {
+			return err
+		}
+		fnLoopAlive, err = libExt.Prep("xev_loop_alive", &ffi.TypeSint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_active(xev_loop* loop)
+		fnLoopActive, err = libExt.Prep("xev_loop_active", &ffi.TypeSint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_fd(xev_loop* loop)
+		fnLoopFd, err = libExt.Prep("xev_loop_fd", &ffi.TypeSint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_backend(xev_loop* loop)
+		fnLoopBackend, err = libExt.Prep("xev_loop_backend", &ffi.TypeSint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// unsigned xev_loop_features(xev_loop* loop)
+		fnLoopFeatures, err = libExt.Prep("xev_loop_features", &ffi.TypeUint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_register_buffers(xev_loop* loop, const struct iovec* iovecs, uint32_t n)
+		fnLoopRegisterBuffers, err = libExt.Prep("xev_loop_register_buffers", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint32)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_register_files(xev_loop* loop, uint32_t n)
+		fnLoopRegisterFiles, err = libExt.Prep("xev_loop_register_files", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint32)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_update_file(xev_loop* loop, uint32_t index, int fd)
+		fnLoopUpdateFile, err = libExt.Prep("xev_loop_update_file", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint32, &ffi.TypeSint32)
+		if err != nil {
+			return err
+		}
+		// int xev_loop_submit(xev_loop* loop)
+		fnLoopSubmit, err = libExt.Prep("xev_loop_submit", &ffi.TypeSint32, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+		// void xev_loop_set_stopped(xev_loop* loop, int stopped)
+		fnLoopSetStopped, err = libExt.Prep("xev_loop_set_stopped", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32)
+		if err != nil {
+			return err
+		}
+		if err = registerErrorFunctions(); err != nil {
+			return err
+		}
 	}
 
 	return registerThreadPoolFunctions()
@@ -135,6 +211,7 @@ func LoopInit(loop *Loop) error {
 	if int32(ret) != 0 {
 		return errors.New("xev_loop_init failed")
 	}
+	liveLoops.Add(1)
 	return nil
 }
 
@@ -142,11 +219,8 @@ func LoopInit(loop *Loop) error {
 // This allows setting a thread pool during initialization, which is required
 // for the new libxev API (thread_pool can no longer be set after init).
 func LoopInitWithOptions(loop *Loop, options *LoopOptions) error {
-	if loadErr != nil {
-		return loadErr
-	}
-	if fnLoopInitWithOptions.Addr == 0 {
-		return errors.New("xev_loop_init_with_options not available (extended library not loaded)")
+	if err := extLoaded(); err != nil {
+		return err
 	}
 
 	var ret ffi.Arg
@@ -156,23 +230,61 @@ func LoopInitWithOptions(loop *Loop, options *LoopOptions) error {
 	if int32(ret) != 0 {
 		return errors.New("xev_loop_init_with_options failed")
 	}
+	liveLoops.Add(1)
 	return nil
 }
 
+// ErrSQPollUnavailable is returned by LoopInitTuned when the kernel refuses
+// the process an io_uring ring polled by an SQPOLL thread, as kernels before
+// 5.11 do to unprivileged processes. Other failures, such as running out of
+// locked memory, are reported with their errno.
+var ErrSQPollUnavailable = errors.New("io_uring SQPOLL unavailable")
+
+// LoopInitTuned initializes a loop like LoopInitWithOptions and applies the
+// backend tuning, which backends it does not apply to ignore. It requires
+// the extended library.
+func LoopInitTuned(loop *Loop, options *LoopOptions, tuning *LoopTuning) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+
+	var ret ffi.Arg
+	loopPtr := unsafe.Pointer(loop)
+	optsPtr := unsafe.Pointer(options)
+	tuningPtr := unsafe.Pointer(tuning)
+	fnLoopInitTuned.Call(&ret, &loopPtr, &optsPtr, &tuningPtr)
+	switch code := int32(ret); code {
+	case 0:
+	case -2:
+		return ErrSQPollUnavailable
+	default:
+		return codeError("xev_loop_init_tuned", code)
+	}
+	liveLoops.Add(1)
+	return nil
+}
+
+// codeError reports the failure of the extended API function name with
+// error code code, wrapping the errno when there is one.
+func codeError(name string, code int32) error {
+	if errno := ErrnoFromCode(code); errno != 0 {
+		return fmt.Errorf("%s failed: %w", name, errno)
+	}
+	return fmt.Errorf("%s failed: %s", name, ErrorName(code))
+}
+
 // LoopDeinit releases resources for an event loop.
 // Must be called when done with the loop to avoid resource leaks.
 func LoopDeinit(loop *Loop) {
 	ptr := unsafe.Pointer(loop)
 	fnLoopDeinit.Call(nil, &ptr)
+	liveLoops.Add(-1)
 }
 
 // LoopRun runs the event loop with the specified mode.
 // See RunMode constants for available modes.
 func LoopRun(loop *Loop, mode RunMode) error {
-	var ret ffi.Arg
-	ptr := unsafe.Pointer(loop)
-	m := int32(mode)
-	fnLoopRun.Call(&ret, &ptr, &m)
+	ret := getFrame().ptr(unsafe.Pointer(loop)).word(uint64(uint32(mode))).call(&fnLoopRun)
 	if int32(ret) != 0 {
 		return errors.New("xev_loop_run failed")
 	}
@@ -195,3 +307,199 @@ func LoopUpdateNow(loop *Loop) {
 	ptr := unsafe.Pointer(loop)
 	fnLoopUpdateNow.Call(nil, &ptr)
 }
+
+// LoopAlive reports whether the loop has completions in flight or queued,
+// which is what RunUntilDone keeps running for. It requires the extended
+// library.
+func LoopAlive(loop *Loop) bool {
+	mustExtLoaded("LoopAlive")
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopAlive.Call(&ret, &ptr)
+	return int32(ret) != 0
+}
+
+// LoopActive returns the number of completions the loop has in flight.
+// Unlike LoopAlive it does not count operations queued since the last run,
+// so call LoopSubmit first for an exact count. It requires the extended
+// library.
+func LoopActive(loop *Loop) int {
+	mustExtLoaded("LoopActive")
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopActive.Call(&ret, &ptr)
+	return int(int32(ret))
+}
+
+// LoopFd returns the descriptor the loop's backend waits on, which is
+// readable when completions are ready, or -1 if the backend has none. It
+// requires the extended library.
+func LoopFd(loop *Loop) int32 {
+	mustExtLoaded("LoopFd")
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopFd.Call(&ret, &ptr)
+	return int32(ret)
+}
+
+// Backends reported by LoopBackend.
+const (
+	BackendUnknown int32 = 0
+	BackendIOUring int32 = 1
+	BackendEpoll   int32 = 2
+	BackendKqueue  int32 = 3
+)
+
+// Feature flags reported by LoopFeatures.
+const (
+	// FeatureNativeFileIO: file I/O is done by the kernel, not a thread pool.
+	FeatureNativeFileIO uint32 = 1 << 0
+	// FeatureThreadPool: the loop was given a thread pool.
+	FeatureThreadPool uint32 = 1 << 1
+	// FeaturePoll: the backend can wait for readiness.
+	FeaturePoll uint32 = 1 << 2
+	// FeatureMsg: the backend can receive and send control messages.
+	FeatureMsg uint32 = 1 << 3
+	// FeatureFd: the backend has a descriptor, returned by LoopFd.
+	FeatureFd uint32 = 1 << 4
+	// FeatureSQPoll: the io_uring ring is polled by an SQPOLL thread.
+	FeatureSQPoll uint32 = 1 << 5
+)
+
+// LoopBackend returns the Backend* constant of the loop's backend. It
+// requires the extended library.
+func LoopBackend(loop *Loop) int32 {
+	mustExtLoaded("LoopBackend")
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopBackend.Call(&ret, &ptr)
+	return int32(ret)
+}
+
+// LoopFeatures returns the Feature* flags of the loop. It requires the
+// extended library.
+func LoopFeatures(loop *Loop) uint32 {
+	mustExtLoaded("LoopFeatures")
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopFeatures.Call(&ret, &ptr)
+	return uint32(ret)
+}
+
+// LoopSetStopped sets or clears the stopped flag of the loop. A stopped
+// loop returns from LoopRun once the current iteration has run its
+// callbacks; clearing the flag lets it run again. It requires the
+// extended library.
+func LoopSetStopped(loop *Loop, stopped bool) {
+	mustExtLoaded("LoopSetStopped")
+	var flag int32
+	if stopped {
+		flag = 1
+	}
+	ptr := unsafe.Pointer(loop)
+	fnLoopSetStopped.Call(nil, &ptr, &flag)
+}
+
+// LoopSubmitThreshold submits the operations queued since the last run,
+// like LoopSubmit, if at least threshold of them wait in the io_uring
+// submission queue; on other backends it does nothing. It does not
+// allocate.
+func LoopSubmitThreshold(loop *Loop, threshold uint32) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	ret := getFrame().ptr(unsafe.Pointer(loop)).word(uint64(threshold)).call(&fnLoopSubmitThreshold)
+	if code := int32(ret); code != 0 {
+		return codeError("xev_loop_submit_threshold", code)
+	}
+	return nil
+}
+
+// LoopSubmit submits the operations queued since the last run without
+// waiting for completions.
+func LoopSubmit(loop *Loop) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(loop)
+	fnLoopSubmit.Call(&ret, &ptr)
+	if code := int32(ret); code != 0 {
+		return fmt.Errorf("xev_loop_submit failed: %s", ErrorName(code))
+	}
+	return nil
+}
+
+// iovec matches struct iovec.
+type iovec struct {
+	base unsafe.Pointer
+	len  uintptr
+}
+
+// LoopRegisterBuffers registers bufs with the loop's io_uring ring,
+// replacing the buffers registered before; no buffers only unregisters
+// them. Reads and writes within buffer i can then be started with
+// TCPReadFixed and TCPWriteFixed with buffer index i. The kernel pins the
+// buffers' memory until they are replaced or the loop is deinitialized, so
+// they must stay allocated, and must not move, until then.
+//
+// Returns errors.ErrUnsupported on backends without a ring. It requires
+// the extended library.
+func LoopRegisterBuffers(loop *Loop, bufs [][]byte) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	iovecs := make([]iovec, len(bufs))
+	for i, b := range bufs {
+		iovecs[i] = iovec{base: bufferPointer(b), len: uintptr(len(b))}
+	}
+	var ret ffi.Arg
+	loopPtr := unsafe.Pointer(loop)
+	iovPtr := unsafe.Pointer(unsafe.SliceData(iovecs))
+	n := uint32(len(iovecs))
+	fnLoopRegisterBuffers.Call(&ret, &loopPtr, &iovPtr, &n)
+	runtime.KeepAlive(iovecs)
+	return registerResult("xev_loop_register_buffers", int32(ret))
+}
+
+// LoopRegisterFiles registers a table of n empty file slots with the
+// loop's io_uring ring, which LoopUpdateFile fills. Operations started
+// with TCPReadFixed and TCPWriteFixed on a slot skip the kernel's
+// descriptor lookup.
+//
+// Returns errors.ErrUnsupported on backends without a ring. Sparse tables
+// need Linux 5.19. It requires the extended library.
+func LoopRegisterFiles(loop *Loop, n uint32) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	loopPtr := unsafe.Pointer(loop)
+	fnLoopRegisterFiles.Call(&ret, &loopPtr, &n)
+	return registerResult("xev_loop_register_files", int32(ret))
+}
+
+// LoopUpdateFile sets slot index of the loop's file table to fd, or empties
+// it if fd is -1. The ring keeps the file open while it is in the table:
+// empty the slot before closing the descriptor. It requires the extended
+// library.
+func LoopUpdateFile(loop *Loop, index uint32, fd int32) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	loopPtr := unsafe.Pointer(loop)
+	fnLoopUpdateFile.Call(&ret, &loopPtr, &index, &fd)
+	return registerResult("xev_loop_update_file", int32(ret))
+}
+
+// registerResult translates the result of a registration function.
+func registerResult(name string, code int32) error {
+	switch code {
+	case 0:
+		return nil
+	case -1:
+		return errors.ErrUnsupported
+	}
+	return codeError(name, code)
+}
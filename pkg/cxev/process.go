@@ -0,0 +1,174 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// SizeofProcess is the size of xev_process, the process watcher storage.
+const SizeofProcess = 16
+
+// Process is a watcher for the exit of a child process.
+type Process [SizeofProcess]byte
+
+// FFI function descriptors for process operations.
+var (
+	fnProcessInit   ffi.Fun
+	fnProcessDeinit ffi.Fun
+	fnProcessWait   ffi.Fun
+)
+
+func registerProcessFunctions() error {
+	var err error
+
+	// int xev_process_init(xev_process* process, pid_t pid)
+	fnProcessInit, err = libExt.Prep("xev_process_init", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeSint32)
+	if err != nil {
+		return err
+	}
+
+	// void xev_process_deinit(xev_process* process)
+	fnProcessDeinit, err = libExt.Prep("xev_process_deinit", &ffi.TypeVoid, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// void xev_process_wait(xev_process*, xev_loop*, xev_completion*, void* userdata, callback)
+	fnProcessWait, err = libExt.Prep("xev_process_wait", &ffi.TypeVoid,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ProcessInit initializes a watcher for the child process pid. On Linux
+// this opens a pidfd, so the process must still exist and not have been
+// reaped.
+func ProcessInit(p *Process, pid int32) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	ptr := unsafe.Pointer(p)
+	fnProcessInit.Call(&ret, &ptr, &pid)
+	if int32(ret) != 0 {
+		return ProcessError(int32(ret))
+	}
+	return nil
+}
+
+// ProcessDeinit releases the resources of a process watcher. No wait may
+// be in flight on it.
+func ProcessDeinit(p *Process) {
+	mustExtLoaded("ProcessDeinit")
+	ptr := unsafe.Pointer(p)
+	fnProcessDeinit.Call(nil, &ptr)
+}
+
+// ProcessError represents an error from process operations.
+type ProcessError int32
+
+func (e ProcessError) Error() string {
+	return "process error: " + ErrorName(int32(e))
+}
+
+// Errno returns the errno equivalent of e, or 0 if there is none.
+func (e ProcessError) Errno() syscall.Errno {
+	return ErrnoFromCode(int32(e))
+}
+
+// ProcessWaitCallback is called when the process exits, with its exit
+// status, or with a nonzero error code if the wait failed.
+type ProcessWaitCallback func(loop *Loop, c *TCPCompletion, status int32, err int32, userdata uintptr) CbAction
+
+// Process callback closure state
+var (
+	processClosureInit sync.Once
+
+	processWaitCallbackPtr uintptr
+	processWaitClosure     *ffi.Closure
+	processWaitCode        unsafe.Pointer
+	processWaitCif         ffi.Cif
+)
+
+func initProcessClosures() {
+	processClosureInit.Do(func() {
+		// Process wait callback: (loop*, completion*, status int32, err int32, userdata*) -> int32
+		processWaitClosure = allocClosure(&processWaitCode)
+		if status := ffi.PrepCif(&processWaitCif, ffi.DefaultAbi, 5,
+			&ffi.TypeSint32,
+			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
+		); status != ffi.OK {
+			panic("failed to prepare process wait callback CIF")
+		}
+		goCallback := trampolineCallback(processWaitTrampoline)
+		if status := ffi.PrepClosureLoc(processWaitClosure, &processWaitCif, goCallback, nil, processWaitCode); status != ffi.OK {
+			panic("failed to prepare process wait closure")
+		}
+		processWaitCallbackPtr = uintptr(processWaitCode)
+	})
+}
+
+func processWaitTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
+	arguments := unsafe.Slice(args, 5)
+	loop := *(*unsafe.Pointer)(arguments[0])
+	completion := *(*unsafe.Pointer)(arguments[1])
+	status := *(*int32)(arguments[2])
+	errCode := *(*int32)(arguments[3])
+	userdata := *(*uintptr)(arguments[4])
+
+	action := int32(Disarm)
+	if cb, ok := processWaitSlot.lookup(userdata); ok {
+		action = int32(cb(
+			(*Loop)(loop),
+			(*TCPCompletion)(completion),
+			status,
+			errCode,
+			userdata,
+		))
+	}
+	*(*int32)(ret) = action
+	return 0
+}
+
+// RegisterProcessWaitCallback registers a process wait callback.
+func RegisterProcessWaitCallback(cb ProcessWaitCallback) uintptr {
+	return processWaitSlot.register(cb)
+}
+
+// GetProcessWaitCallbackPtr returns the C function pointer for process
+// wait callbacks.
+func GetProcessWaitCallbackPtr() uintptr {
+	initProcessClosures()
+	return processWaitCallbackPtr
+}
+
+// ProcessWait waits for the process watched by p to exit and reaps it.
+// The completion is an extended one, like those of TCP operations.
+func ProcessWait(p *Process, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("ProcessWait")
+	pPtr := unsafe.Pointer(p)
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	fnProcessWait.Call(nil, &pPtr, &loopPtr, &cPtr, &userdata, &cb)
+}
+
+// ProcessWaitWithCallback is a convenience function that registers the
+// callback and starts waiting.
+func ProcessWaitWithCallback(p *Process, loop *Loop, c *TCPCompletion, cb ProcessWaitCallback) uintptr {
+	initProcessClosures()
+	id := RegisterProcessWaitCallback(cb)
+	ProcessWait(p, loop, c, id, processWaitCallbackPtr)
+	return id
+}
@@ -0,0 +1,213 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements the callback registry shared by every trampoline.
+//
+// # Design
+//
+// All registered callbacks live in one map keyed by a process-wide
+// monotonic ID. Each entry records the kind of callback it holds, so a
+// trampoline can only dispatch entries registered for its own signature:
+// a read trampoline will never see a write callback, even though both
+// share the same ID space.
+//
+// Typed access goes through slot[T] values (one per callback kind), which
+// give compile-time checked register/load helpers without duplicating the
+// map, the counter, or the unregister logic per kind.
+//
+// Because IDs are unique across kinds, a single [UnregisterCallback] call
+// removes any registration regardless of its kind.
+
+package cxev
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CallbackKind identifies the trampoline signature a registration belongs to.
+type CallbackKind uint8
+
+const (
+	KindTimer CallbackKind = iota
+	KindTCP
+	KindTCPAccept
+	KindTCPRead
+	KindTCPWrite
+	KindUDP
+	KindUDPRead
+	KindUDPWrite
+	KindFile
+	KindFileRead
+	KindFileWrite
+	KindProcessWait
+
+	numCallbackKinds
+)
+
+var callbackKindNames = [numCallbackKinds]string{
+	KindTimer:       "timer",
+	KindTCP:         "tcp",
+	KindTCPAccept:   "tcp_accept",
+	KindTCPRead:     "tcp_read",
+	KindTCPWrite:    "tcp_write",
+	KindUDP:         "udp",
+	KindUDPRead:     "udp_read",
+	KindUDPWrite:    "udp_write",
+	KindFile:        "file",
+	KindFileRead:    "file_read",
+	KindFileWrite:   "file_write",
+	KindProcessWait: "process_wait",
+}
+
+func (k CallbackKind) String() string {
+	if k < numCallbackKinds {
+		return callbackKindNames[k]
+	}
+	return fmt.Sprintf("CallbackKind(%d)", uint8(k))
+}
+
+// registryEntry is the value stored for every registered callback.
+type registryEntry struct {
+	kind  CallbackKind
+	value any
+	// pool owns pooled entries; unregistering one releases it to the pool.
+	pool callbackReleaser
+}
+
+// registry maps userdata IDs to Go callbacks for all callback kinds.
+type registry struct {
+	entries sync.Map // map[uintptr]registryEntry
+	nextID  atomic.Uint64
+	active  [numCallbackKinds]atomic.Int64
+	total   [numCallbackKinds]atomic.Uint64
+}
+
+// callbacks is the process-wide registry used by all trampolines.
+var callbacks registry
+
+func (r *registry) register(kind CallbackKind, value any) uintptr {
+	id := uintptr(r.nextID.Add(1))
+	r.entries.Store(id, registryEntry{kind: kind, value: value})
+	r.active[kind].Add(1)
+	r.total[kind].Add(1)
+	return id
+}
+
+// registerPooled stores an entry owned by pool. It is not counted as
+// active until the pool hands it out.
+func (r *registry) registerPooled(kind CallbackKind, value any, pool callbackReleaser) uintptr {
+	id := uintptr(r.nextID.Add(1))
+	r.entries.Store(id, registryEntry{kind: kind, value: value, pool: pool})
+	return id
+}
+
+func (r *registry) load(kind CallbackKind, id uintptr) (any, bool) {
+	v, ok := r.entries.Load(id)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(registryEntry)
+	if entry.kind != kind {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (r *registry) unregister(id uintptr) bool {
+	if v, ok := r.entries.Load(id); ok {
+		if pool := v.(registryEntry).pool; pool != nil {
+			return pool.release(id)
+		}
+	}
+	v, ok := r.entries.LoadAndDelete(id)
+	if !ok {
+		return false
+	}
+	r.active[v.(registryEntry).kind].Add(-1)
+	return true
+}
+
+// slot is a typed view of the registry for a single callback kind.
+type slot[T any] struct {
+	kind CallbackKind
+}
+
+func (s slot[T]) register(value T) uintptr {
+	return callbacks.register(s.kind, value)
+}
+
+func (s slot[T]) load(id uintptr) (T, bool) {
+	v, ok := callbacks.load(s.kind, id)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return v.(T), true
+}
+
+// Typed registry slots, one per trampoline signature.
+var (
+	timerSlot       = slot[TimerCallback]{kind: KindTimer}
+	tcpSlot         = slot[TCPCallback]{kind: KindTCP}
+	tcpAcceptSlot   = slot[TCPAcceptCallback]{kind: KindTCPAccept}
+	tcpReadSlot     = slot[tcpReadContext]{kind: KindTCPRead}
+	tcpWriteSlot    = slot[TCPWriteCallback]{kind: KindTCPWrite}
+	udpSlot         = slot[UDPCallback]{kind: KindUDP}
+	udpReadSlot     = slot[udpReadContext]{kind: KindUDPRead}
+	udpWriteSlot    = slot[UDPWriteCallback]{kind: KindUDPWrite}
+	fileSlot        = slot[FileCallback]{kind: KindFile}
+	fileReadSlot    = slot[fileReadContext]{kind: KindFileRead}
+	fileWriteSlot   = slot[fileWriteContext]{kind: KindFileWrite}
+	processWaitSlot = slot[ProcessWaitCallback]{kind: KindProcessWait}
+)
+
+// UnregisterCallback removes a registration of any kind from the registry.
+// It returns false if id was not registered (or was already removed), which
+// makes repeated unregistration harmless.
+func UnregisterCallback(id uintptr) bool {
+	return callbacks.unregister(id)
+}
+
+// ActiveCallbacks returns the number of live registrations for each kind.
+// Kinds with no live registrations are omitted.
+func ActiveCallbacks() map[CallbackKind]int {
+	out := make(map[CallbackKind]int)
+	for k := CallbackKind(0); k < numCallbackKinds; k++ {
+		if n := callbacks.active[k].Load(); n != 0 {
+			out[k] = int(n)
+		}
+	}
+	return out
+}
+
+// TotalCallbacks returns the number of registrations ever made for kind.
+func TotalCallbacks(kind CallbackKind) uint64 {
+	if kind >= numCallbackKinds {
+		return 0
+	}
+	return callbacks.total[kind].Load()
+}
+
+// CheckCallbackLeaks returns an error describing every kind that still has
+// live registrations, or nil if the registry is empty. Call it after all
+// loops are drained (typically at the end of a test) to detect callbacks
+// that were never unregistered.
+func CheckCallbackLeaks() error {
+	active := ActiveCallbacks()
+	if len(active) == 0 {
+		return nil
+	}
+	parts := make([]string, 0, len(active))
+	for k, n := range active {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, n))
+	}
+	sort.Strings(parts)
+	return fmt.Errorf("leaked callback registrations: %s", strings.Join(parts, ", "))
+}
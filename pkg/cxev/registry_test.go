@@ -0,0 +1,64 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import "testing"
+
+func TestRegistryKindIsolation(t *testing.T) {
+	id := RegisterTCPWriteCallback(func(loop *Loop, c *TCPCompletion, bytesWritten int32, err int32, userdata uintptr) CbAction {
+		return Disarm
+	})
+	defer UnregisterCallback(id)
+
+	if _, ok := tcpWriteSlot.load(id); !ok {
+		t.Fatal("expected write callback to load from its own slot")
+	}
+	if _, ok := tcpReadSlot.load(id); ok {
+		t.Fatal("write callback must not be visible to the read slot")
+	}
+	if _, ok := udpWriteSlot.load(id); ok {
+		t.Fatal("tcp callback must not be visible to a udp slot")
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	before := DebugFileCallbackCount()
+	totalBefore := TotalCallbacks(KindFile)
+
+	id := RegisterFileCallback(func(loop *Loop, c *FileCompletion, result int32, userdata uintptr) CbAction {
+		return Disarm
+	})
+	if got := DebugFileCallbackCount(); got != before+1 {
+		t.Fatalf("active file callbacks = %d, want %d", got, before+1)
+	}
+	if got := TotalCallbacks(KindFile); got != totalBefore+1 {
+		t.Fatalf("total file callbacks = %d, want %d", got, totalBefore+1)
+	}
+
+	if !UnregisterCallback(id) {
+		t.Fatal("first unregister should report removal")
+	}
+	if UnregisterCallback(id) {
+		t.Fatal("second unregister should be a no-op")
+	}
+	if got := DebugFileCallbackCount(); got != before {
+		t.Fatalf("active file callbacks = %d, want %d", got, before)
+	}
+}
+
+func TestCheckCallbackLeaks(t *testing.T) {
+	id := RegisterUDPCallback(func(loop *Loop, c *UDPCompletion, result int32, userdata uintptr) CbAction {
+		return Disarm
+	})
+	if err := CheckCallbackLeaks(); err == nil {
+		t.Fatal("expected leak to be reported")
+	}
+	UnregisterUDPCallback(id)
+	if err := CheckCallbackLeaks(); err != nil {
+		t.Fatalf("unexpected leak: %v", err)
+	}
+}
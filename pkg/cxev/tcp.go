@@ -8,7 +8,7 @@ package cxev
 
 import (
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"unsafe"
 
 	"github.com/jupiterrider/ffi"
@@ -49,8 +49,12 @@ var (
 	fnTCPConnect     ffi.Fun
 	fnTCPRead        ffi.Fun
 	fnTCPWrite       ffi.Fun
+	fnTCPReadFixed   ffi.Fun
+	fnTCPWriteFixed  ffi.Fun
 	fnTCPClose       ffi.Fun
 	fnTCPShutdown    ffi.Fun
+	fnTCPCancel      ffi.Fun
+	fnTCPPollWrite   ffi.Fun
 	fnSockaddrIPv4   ffi.Fun
 	fnSockaddrIPv6   ffi.Fun
 	fnSockaddrPort   ffi.Fun
@@ -125,6 +129,22 @@ func registerTCPFunctions() error {
 		return err
 	}
 
+	// int xev_tcp_read_fixed(xev_tcp*, xev_loop*, xev_completion*, buf, buf_len, int file_index, int buf_index, void* userdata, callback)
+	fnTCPReadFixed, err = libExt.Prep("xev_tcp_read_fixed", &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64,
+		&ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// int xev_tcp_write_fixed(xev_tcp*, xev_loop*, xev_completion*, buf, buf_len, int file_index, int buf_index, void* userdata, callback)
+	fnTCPWriteFixed, err = libExt.Prep("xev_tcp_write_fixed", &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64,
+		&ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
 	// void xev_tcp_close(xev_tcp*, xev_loop*, xev_completion*, void* userdata, callback)
 	fnTCPClose, err = libExt.Prep("xev_tcp_close", &ffi.TypeVoid,
 		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
@@ -139,6 +159,20 @@ func registerTCPFunctions() error {
 		return err
 	}
 
+	// void xev_tcp_cancel(xev_loop*, xev_completion*, xev_completion* cancel, void* userdata, callback)
+	fnTCPCancel, err = libExt.Prep("xev_tcp_cancel", &ffi.TypeVoid,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// int xev_tcp_poll_writable(xev_tcp*, xev_loop*, xev_completion*, void* userdata, callback)
+	fnTCPPollWrite, err = libExt.Prep("xev_tcp_poll_writable", &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
 	// void xev_sockaddr_ipv4(xev_sockaddr*, u8, u8, u8, u8, u16)
 	fnSockaddrIPv4, err = libExt.Prep("xev_sockaddr_ipv4", &ffi.TypeVoid,
 		&ffi.TypePointer, &ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint16)
@@ -189,8 +223,8 @@ func AF_INET6() int32 { return afInet6 }
 
 // TCPInit initializes a TCP socket with the given address family.
 func TCPInit(tcp *TCP, family int32) error {
-	if loadErr != nil {
-		return loadErr
+	if err := extLoaded(); err != nil {
+		return err
 	}
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(tcp)
@@ -203,12 +237,14 @@ func TCPInit(tcp *TCP, family int32) error {
 
 // TCPInitFd initializes a TCP socket from an existing file descriptor.
 func TCPInitFd(tcp *TCP, fd int32) {
+	mustExtLoaded("TCPInitFd")
 	ptr := unsafe.Pointer(tcp)
 	fnTCPInitFd.Call(nil, &ptr, &fd)
 }
 
 // TCPFd returns the file descriptor of a TCP socket.
 func TCPFd(tcp *TCP) int32 {
+	mustExtLoaded("TCPFd")
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(tcp)
 	fnTCPFd.Call(&ret, &ptr)
@@ -217,6 +253,9 @@ func TCPFd(tcp *TCP) int32 {
 
 // TCPBind binds a TCP socket to an address.
 func TCPBind(tcp *TCP, addr *Sockaddr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
 	var ret ffi.Arg
 	tcpPtr := unsafe.Pointer(tcp)
 	addrPtr := unsafe.Pointer(addr)
@@ -229,6 +268,9 @@ func TCPBind(tcp *TCP, addr *Sockaddr) error {
 
 // TCPListen starts listening on a TCP socket.
 func TCPListen(tcp *TCP, backlog int32) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(tcp)
 	fnTCPListen.Call(&ret, &ptr, &backlog)
@@ -240,6 +282,9 @@ func TCPListen(tcp *TCP, backlog int32) error {
 
 // TCPGetsockname gets the local address of a bound TCP socket.
 func TCPGetsockname(tcp *TCP, addr *Sockaddr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
 	var ret ffi.Arg
 	tcpPtr := unsafe.Pointer(tcp)
 	addrPtr := unsafe.Pointer(addr)
@@ -252,12 +297,14 @@ func TCPGetsockname(tcp *TCP, addr *Sockaddr) error {
 
 // SockaddrIPv4 initializes a sockaddr for IPv4.
 func SockaddrIPv4(addr *Sockaddr, a, b, c, d uint8, port uint16) {
+	mustExtLoaded("SockaddrIPv4")
 	ptr := unsafe.Pointer(addr)
 	fnSockaddrIPv4.Call(nil, &ptr, &a, &b, &c, &d, &port)
 }
 
 // SockaddrIPv6 initializes a sockaddr for IPv6.
 func SockaddrIPv6(addr *Sockaddr, ip *[16]byte, port uint16, flowinfo, scopeID uint32) {
+	mustExtLoaded("SockaddrIPv6")
 	addrPtr := unsafe.Pointer(addr)
 	ipPtr := unsafe.Pointer(ip)
 	fnSockaddrIPv6.Call(nil, &addrPtr, &ipPtr, &port, &flowinfo, &scopeID)
@@ -265,6 +312,7 @@ func SockaddrIPv6(addr *Sockaddr, ip *[16]byte, port uint16, flowinfo, scopeID u
 
 // SockaddrPort returns the port from a sockaddr.
 func SockaddrPort(addr *Sockaddr) uint16 {
+	mustExtLoaded("SockaddrPort")
 	var ret uint16
 	ptr := unsafe.Pointer(addr)
 	fnSockaddrPort.Call(&ret, &ptr)
@@ -275,7 +323,12 @@ func SockaddrPort(addr *Sockaddr) uint16 {
 type TCPError int32
 
 func (e TCPError) Error() string {
-	return "tcp error: " + string(rune(e))
+	return "tcp error: " + ErrorName(int32(e))
+}
+
+// Errno returns the errno equivalent of e, or 0 if there is none.
+func (e TCPError) Errno() syscall.Errno {
+	return ErrnoFromCode(int32(e))
 }
 
 // TCP Callback types
@@ -292,15 +345,6 @@ type TCPReadCallback func(loop *Loop, c *TCPCompletion, buf []byte, bytesRead in
 // TCPWriteCallback is called when data is written.
 type TCPWriteCallback func(loop *Loop, c *TCPCompletion, bytesWritten int32, err int32, userdata uintptr) CbAction
 
-// TCP callback registry
-var (
-	tcpCallbackRegistry       sync.Map
-	tcpAcceptCallbackRegistry sync.Map
-	tcpReadCallbackRegistry   sync.Map
-	tcpWriteCallbackRegistry  sync.Map
-	tcpCallbackCounter        uint64
-)
-
 // TCP callback closure state
 var (
 	tcpClosureInit sync.Once
@@ -328,56 +372,56 @@ var (
 func initTCPClosures() {
 	tcpClosureInit.Do(func() {
 		// TCP simple callback: (loop*, completion*, result int32, userdata*) -> int32
-		tcpClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &tcpClosureCode)
+		tcpClosure = allocClosure(&tcpClosureCode)
 		if status := ffi.PrepCif(&tcpCif, ffi.DefaultAbi, 4,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare TCP callback CIF")
 		}
-		goCallback := ffi.NewCallback(tcpTrampoline)
+		goCallback := trampolineCallback(tcpTrampoline)
 		if status := ffi.PrepClosureLoc(tcpClosure, &tcpCif, goCallback, nil, tcpClosureCode); status != ffi.OK {
 			panic("failed to prepare TCP closure")
 		}
 		tcpCallbackPtr = uintptr(tcpClosureCode)
 
 		// TCP accept callback: (loop*, completion*, fd int32, err int32, userdata*) -> int32
-		tcpAcceptClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &tcpAcceptCode)
+		tcpAcceptClosure = allocClosure(&tcpAcceptCode)
 		if status := ffi.PrepCif(&tcpAcceptCif, ffi.DefaultAbi, 5,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare TCP accept callback CIF")
 		}
-		goAcceptCallback := ffi.NewCallback(tcpAcceptTrampoline)
+		goAcceptCallback := trampolineCallback(tcpAcceptTrampoline)
 		if status := ffi.PrepClosureLoc(tcpAcceptClosure, &tcpAcceptCif, goAcceptCallback, nil, tcpAcceptCode); status != ffi.OK {
 			panic("failed to prepare TCP accept closure")
 		}
 		tcpAcceptCallbackPtr = uintptr(tcpAcceptCode)
 
 		// TCP read callback: (loop*, completion*, buf*, bytes_read int32, err int32, userdata*) -> int32
-		tcpReadClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &tcpReadCode)
+		tcpReadClosure = allocClosure(&tcpReadCode)
 		if status := ffi.PrepCif(&tcpReadCif, ffi.DefaultAbi, 6,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare TCP read callback CIF")
 		}
-		goReadCallback := ffi.NewCallback(tcpReadTrampoline)
+		goReadCallback := trampolineCallback(tcpReadTrampoline)
 		if status := ffi.PrepClosureLoc(tcpReadClosure, &tcpReadCif, goReadCallback, nil, tcpReadCode); status != ffi.OK {
 			panic("failed to prepare TCP read closure")
 		}
 		tcpReadCallbackPtr = uintptr(tcpReadCode)
 
 		// TCP write callback: (loop*, completion*, bytes_written int32, err int32, userdata*) -> int32
-		tcpWriteClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &tcpWriteCode)
+		tcpWriteClosure = allocClosure(&tcpWriteCode)
 		if status := ffi.PrepCif(&tcpWriteCif, ffi.DefaultAbi, 5,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare TCP write callback CIF")
 		}
-		goWriteCallback := ffi.NewCallback(tcpWriteTrampoline)
+		goWriteCallback := trampolineCallback(tcpWriteTrampoline)
 		if status := ffi.PrepClosureLoc(tcpWriteClosure, &tcpWriteCif, goWriteCallback, nil, tcpWriteCode); status != ffi.OK {
 			panic("failed to prepare TCP write closure")
 		}
@@ -393,8 +437,8 @@ func tcpTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userD
 	userdata := *(*uintptr)(arguments[3])
 
 	action := int32(Disarm)
-	if cb, ok := tcpCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(TCPCallback)(
+	if cb, ok := tcpSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*TCPCompletion)(completion),
 			result,
@@ -414,8 +458,8 @@ func tcpAcceptTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	userdata := *(*uintptr)(arguments[4])
 
 	action := int32(Disarm)
-	if cb, ok := tcpAcceptCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(TCPAcceptCallback)(
+	if cb, ok := tcpAcceptSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*TCPCompletion)(completion),
 			fd,
@@ -427,11 +471,8 @@ func tcpAcceptTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	return 0
 }
 
-// tcpReadContext holds the buffer pointer and length for read callbacks.
-type tcpReadContext struct {
-	cb  TCPReadCallback
-	buf []byte
-}
+// tcpReadContext is the registration of a TCP read.
+type tcpReadContext = readContext[TCPReadCallback]
 
 func tcpReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
 	arguments := unsafe.Slice(args, 6)
@@ -443,16 +484,11 @@ func tcpReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, u
 	userdata := *(*uintptr)(arguments[5])
 
 	action := int32(Disarm)
-	if ctx, ok := tcpReadCallbackRegistry.Load(userdata); ok {
-		readCtx := ctx.(tcpReadContext)
-		var buf []byte
-		if bytesRead > 0 {
-			buf = readCtx.buf[:bytesRead]
-		}
+	if readCtx, ok := loadRead(tcpReadSlot, userdata); ok {
 		action = int32(readCtx.cb(
 			(*Loop)(loop),
 			(*TCPCompletion)(completion),
-			buf,
+			readCtx.data(bytesRead),
 			bytesRead,
 			errCode,
 			userdata,
@@ -471,8 +507,8 @@ func tcpWriteTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	userdata := *(*uintptr)(arguments[4])
 
 	action := int32(Disarm)
-	if cb, ok := tcpWriteCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(TCPWriteCallback)(
+	if cb, ok := tcpWriteSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*TCPCompletion)(completion),
 			bytesWritten,
@@ -486,38 +522,28 @@ func tcpWriteTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 
 // RegisterTCPCallback registers a TCP callback and returns its unique ID.
 func RegisterTCPCallback(cb TCPCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&tcpCallbackCounter, 1))
-	tcpCallbackRegistry.Store(id, cb)
-	return id
+	return tcpSlot.register(cb)
 }
 
 // RegisterTCPAcceptCallback registers a TCP accept callback.
 func RegisterTCPAcceptCallback(cb TCPAcceptCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&tcpCallbackCounter, 1))
-	tcpAcceptCallbackRegistry.Store(id, cb)
-	return id
+	return tcpAcceptSlot.register(cb)
 }
 
 // RegisterTCPReadCallback registers a TCP read callback with its buffer.
 func RegisterTCPReadCallback(cb TCPReadCallback, buf []byte) uintptr {
-	id := uintptr(atomic.AddUint64(&tcpCallbackCounter, 1))
-	tcpReadCallbackRegistry.Store(id, tcpReadContext{cb: cb, buf: buf})
-	return id
+	return tcpReadSlot.register(tcpReadContext{cb: cb, buf: buf})
 }
 
 // RegisterTCPWriteCallback registers a TCP write callback.
 func RegisterTCPWriteCallback(cb TCPWriteCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&tcpCallbackCounter, 1))
-	tcpWriteCallbackRegistry.Store(id, cb)
-	return id
+	return tcpWriteSlot.register(cb)
 }
 
 // UnregisterTCPCallback removes a TCP callback from the registry.
+// It is equivalent to [UnregisterCallback].
 func UnregisterTCPCallback(id uintptr) {
-	tcpCallbackRegistry.Delete(id)
-	tcpAcceptCallbackRegistry.Delete(id)
-	tcpReadCallbackRegistry.Delete(id)
-	tcpWriteCallbackRegistry.Delete(id)
+	callbacks.unregister(id)
 }
 
 // GetTCPCallbackPtr returns the C function pointer for TCP callbacks.
@@ -546,6 +572,7 @@ func GetTCPWriteCallbackPtr() uintptr {
 
 // TCPAccept starts accepting a connection on a listening socket.
 func TCPAccept(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("TCPAccept")
 	tcpPtr := unsafe.Pointer(tcp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -562,6 +589,7 @@ func TCPAcceptWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, cb TCPAcceptC
 
 // TCPConnect starts connecting to a remote address.
 func TCPConnect(tcp *TCP, loop *Loop, c *TCPCompletion, addr *Sockaddr, userdata, cb uintptr) {
+	mustExtLoaded("TCPConnect")
 	tcpPtr := unsafe.Pointer(tcp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -572,31 +600,35 @@ func TCPConnect(tcp *TCP, loop *Loop, c *TCPCompletion, addr *Sockaddr, userdata
 // TCPConnectWithCallback is a convenience function that registers the callback and starts connecting.
 func TCPConnectWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, addr *Sockaddr, cb TCPCallback) uintptr {
 	initTCPClosures()
-	id := RegisterTCPCallback(cb)
+	id := RegisterPooledTCPCallback(cb)
 	TCPConnect(tcp, loop, c, addr, id, tcpCallbackPtr)
 	return id
 }
 
 // TCPRead starts reading from a TCP socket.
+// It does not allocate.
 func TCPRead(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, userdata, cb uintptr) {
-	tcpPtr := unsafe.Pointer(tcp)
-	loopPtr := unsafe.Pointer(loop)
-	cPtr := unsafe.Pointer(c)
-	bufPtr := bufferPointer(buf)
-	bufLen := uint64(len(buf))
-	fnTCPRead.Call(nil, &tcpPtr, &loopPtr, &cPtr, &bufPtr, &bufLen, &userdata, &cb)
+	mustExtLoaded("TCPRead")
+	getFrame().
+		ptr(unsafe.Pointer(tcp)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).
+		word(uint64(userdata)).word(uint64(cb)).
+		call(&fnTCPRead)
 }
 
 // TCPReadWithCallback is a convenience function that registers the callback and starts reading.
+// The registration is pooled, so once the pool is warm starting a read does
+// not allocate.
 func TCPReadWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, cb TCPReadCallback) uintptr {
 	initTCPClosures()
-	id := RegisterTCPReadCallback(cb, buf)
+	id := RegisterPooledTCPReadCallback(cb, buf)
 	TCPRead(tcp, loop, c, buf, id, tcpReadCallbackPtr)
 	return id
 }
 
 // TCPWrite starts writing to a TCP socket.
 func TCPWrite(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, userdata, cb uintptr) {
+	mustExtLoaded("TCPWrite")
 	tcpPtr := unsafe.Pointer(tcp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -613,8 +645,61 @@ func TCPWriteWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, cb
 	return id
 }
 
+// TCPReadFixed starts reading from a TCP socket like TCPRead, through slot
+// fileIndex of the loop's file table (see LoopRegisterFiles) and registered
+// buffer bufIndex (see LoopRegisterBuffers), either -1 for none. buf must
+// lie within the registered buffer.
+//
+// It reports whether the read went through them. Backends without a ring
+// ignore the indexes, and a read that waits for room in the ring is
+// submitted the usual way. Only the first submission goes through them:
+// returning Rearm from the callback reads again the usual way. To keep
+// using them, start another TCPReadFixed from the callback and return
+// Disarm. It does not allocate.
+func TCPReadFixed(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, fileIndex, bufIndex int32, userdata, cb uintptr) bool {
+	mustExtLoaded("TCPReadFixed")
+	return int32(getFrame().
+		ptr(unsafe.Pointer(tcp)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).
+		word(uint64(fileIndex)).word(uint64(bufIndex)).
+		word(uint64(userdata)).word(uint64(cb)).
+		call(&fnTCPReadFixed)) == 1
+}
+
+// TCPReadFixedWithCallback registers the callback and starts reading like
+// TCPReadFixed. Like TCPReadWithCallback, it pools the registration.
+func TCPReadFixedWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, fileIndex, bufIndex int32, cb TCPReadCallback) (uintptr, bool) {
+	initTCPClosures()
+	id := RegisterPooledTCPReadCallback(cb, buf)
+	fixed := TCPReadFixed(tcp, loop, c, buf, fileIndex, bufIndex, id, tcpReadCallbackPtr)
+	return id, fixed
+}
+
+// TCPWriteFixed starts writing to a TCP socket like TCPWrite, through the
+// loop's registered file and buffer as TCPReadFixed reads, and reports
+// whether it went through them. It does not allocate.
+func TCPWriteFixed(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, fileIndex, bufIndex int32, userdata, cb uintptr) bool {
+	mustExtLoaded("TCPWriteFixed")
+	return int32(getFrame().
+		ptr(unsafe.Pointer(tcp)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).
+		word(uint64(fileIndex)).word(uint64(bufIndex)).
+		word(uint64(userdata)).word(uint64(cb)).
+		call(&fnTCPWriteFixed)) == 1
+}
+
+// TCPWriteFixedWithCallback registers the callback and starts writing like
+// TCPWriteFixed.
+func TCPWriteFixedWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, buf []byte, fileIndex, bufIndex int32, cb TCPWriteCallback) (uintptr, bool) {
+	initTCPClosures()
+	id := RegisterTCPWriteCallback(cb)
+	fixed := TCPWriteFixed(tcp, loop, c, buf, fileIndex, bufIndex, id, tcpWriteCallbackPtr)
+	return id, fixed
+}
+
 // TCPClose starts closing a TCP socket.
 func TCPClose(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("TCPClose")
 	tcpPtr := unsafe.Pointer(tcp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -624,13 +709,14 @@ func TCPClose(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
 // TCPCloseWithCallback is a convenience function that registers the callback and starts closing.
 func TCPCloseWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, cb TCPCallback) uintptr {
 	initTCPClosures()
-	id := RegisterTCPCallback(cb)
+	id := RegisterPooledTCPCallback(cb)
 	TCPClose(tcp, loop, c, id, tcpCallbackPtr)
 	return id
 }
 
 // TCPShutdown starts shutting down the write side of a TCP socket.
 func TCPShutdown(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("TCPShutdown")
 	tcpPtr := unsafe.Pointer(tcp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -640,11 +726,62 @@ func TCPShutdown(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) {
 // TCPShutdownWithCallback is a convenience function.
 func TCPShutdownWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, cb TCPCallback) uintptr {
 	initTCPClosures()
-	id := RegisterTCPCallback(cb)
+	id := RegisterPooledTCPCallback(cb)
 	TCPShutdown(tcp, loop, c, id, tcpCallbackPtr)
 	return id
 }
 
+// TCPCancel cancels the operation in flight on c, using cCancel for the
+// cancellation. The cancelled operation's callback receives an error code
+// that maps to ECANCELED; cb is called on cCancel when the cancellation is
+// done.
+func TCPCancel(loop *Loop, c, cCancel *TCPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("TCPCancel")
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	cCancelPtr := unsafe.Pointer(cCancel)
+	fnTCPCancel.Call(nil, &loopPtr, &cPtr, &cCancelPtr, &userdata, &cb)
+}
+
+// TCPCancelWithCallback is a convenience function that registers the callback and starts cancelling.
+func TCPCancelWithCallback(loop *Loop, c, cCancel *TCPCompletion, cb TCPCallback) uintptr {
+	initTCPClosures()
+	id := RegisterPooledTCPCallback(cb)
+	TCPCancel(loop, c, cCancel, id, tcpCallbackPtr)
+	return id
+}
+
+// TCPPollWritable waits until tcp is writable, without writing anything.
+// The callback receives 0 once it is, or an error code. Returns an error,
+// and calls nothing, if the backend cannot poll for readiness.
+func TCPPollWritable(tcp *TCP, loop *Loop, c *TCPCompletion, userdata, cb uintptr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	tcpPtr := unsafe.Pointer(tcp)
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	fnTCPPollWrite.Call(&ret, &tcpPtr, &loopPtr, &cPtr, &userdata, &cb)
+	if int32(ret) != 0 {
+		return TCPError(int32(ret))
+	}
+	return nil
+}
+
+// TCPPollWritableWithCallback registers cb and starts waiting for
+// writability. On error nothing stays registered and the returned ID is
+// zero.
+func TCPPollWritableWithCallback(tcp *TCP, loop *Loop, c *TCPCompletion, cb TCPCallback) (uintptr, error) {
+	initTCPClosures()
+	id := RegisterPooledTCPCallback(cb)
+	if err := TCPPollWritable(tcp, loop, c, id, tcpCallbackPtr); err != nil {
+		UnregisterTCPCallback(id)
+		return 0, err
+	}
+	return id, nil
+}
+
 // ExtLibLoaded returns true if the extended library (TCP support) is loaded.
 func ExtLibLoaded() bool {
 	return libExt.Addr != 0
@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements Teardown, which releases the process-wide state that
+// otherwise lives as long as the program.
+//
+// # What is freed
+//
+// The libffi closures handed to libxev, the callback registry and pools,
+// and the library handles. Closures are tracked as they are allocated, so
+// Teardown frees exactly what was initialized.
+//
+// # What is kept
+//
+// The Go side of each trampoline comes from purego.NewCallback, which can
+// never be freed and is capped per process. Those are created once and
+// reused when closures are allocated again after a reload, so load/unload
+// cycles do not use them up.
+
+package cxev
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// ErrTornDown is returned by [LoadError] after [Teardown], until [Load]
+// loads the libraries again.
+var ErrTornDown = errors.New("cxev: libraries unloaded by Teardown")
+
+// liveLoops counts loops initialized and not yet deinitialized.
+var liveLoops atomic.Int64
+
+var (
+	closureMu   sync.Mutex
+	closures    []*ffi.Closure
+	trampolines = make(map[uintptr]uintptr) // Go function -> C callback
+)
+
+// allocClosure allocates a closure that Teardown frees.
+func allocClosure(code *unsafe.Pointer) *ffi.Closure {
+	c := ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), code)
+	closureMu.Lock()
+	closures = append(closures, c)
+	closureMu.Unlock()
+	return c
+}
+
+// trampolineCallback returns the C-callable address of fn, creating it on
+// first use only.
+func trampolineCallback(fn ffi.Callback) uintptr {
+	key := reflect.ValueOf(fn).Pointer()
+	closureMu.Lock()
+	defer closureMu.Unlock()
+	if cb, ok := trampolines[key]; ok {
+		return cb
+	}
+	cb := ffi.NewCallback(fn)
+	trampolines[key] = cb
+	return cb
+}
+
+// LiveLoops returns the number of loops initialized and not yet
+// deinitialized.
+func LiveLoops() int {
+	return int(liveLoops.Load())
+}
+
+// Teardown frees the callback closures, drops every callback registration
+// and unloads the libraries, for embedders that load and unload the
+// package repeatedly, such as plugins and test harnesses.
+//
+// It is only safe once every loop has been deinitialized and no other
+// goroutine uses the package; it returns an error without changing
+// anything while loops are live. Registrations still present are dropped,
+// so their IDs can no longer be unregistered; [CheckCallbackLeaks] beforehand
+// reports them. After Teardown, functions fail with [ErrTornDown] until
+// [Load] is called.
+func Teardown() error {
+	if n := liveLoops.Load(); n != 0 {
+		return fmt.Errorf("cxev: Teardown with %d live loops", n)
+	}
+
+	closureMu.Lock()
+	for _, c := range closures {
+		ffi.ClosureFree(c)
+	}
+	closures = nil
+	closureMu.Unlock()
+	closureInit = sync.Once{}
+	tcpClosureInit = sync.Once{}
+	fileClosureInit = sync.Once{}
+	udpClosureInit = sync.Once{}
+	processClosureInit = sync.Once{}
+
+	callbacks.entries.Clear()
+	for k := range callbacks.active {
+		callbacks.active[k].Store(0)
+		callbacks.total[k].Store(0)
+	}
+	tcpCallbackPool.reset()
+	tcpReadPool.reset()
+	udpReadPool.reset()
+	fileReadPool.reset()
+
+	// Either library may never have been opened, as when the extended
+	// library is missing or Teardown runs twice.
+	var err error
+	for _, l := range []*ffi.Lib{&libExt, &lib} {
+		if l.Addr != 0 {
+			err = errors.Join(err, l.Close())
+		}
+		*l = ffi.Lib{}
+	}
+	loadErr = ErrTornDown
+	once = sync.Once{}
+	return err
+}
+
+// reset forgets every pooled registration.
+func (p *callbackPool[T]) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = nil
+	p.byID = nil
+}
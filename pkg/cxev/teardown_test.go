@@ -0,0 +1,130 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+func TestTeardownRefusesLiveLoops(t *testing.T) {
+	liveLoops.Add(1)
+	defer liveLoops.Add(-1)
+
+	id := registerNopTimer()
+	defer UnregisterCallback(id)
+	if err := Teardown(); err == nil {
+		t.Fatal("Teardown succeeded with a live loop")
+	}
+	if _, ok := timerSlot.load(id); !ok {
+		t.Fatal("refused Teardown dropped a registration")
+	}
+}
+
+func TestTeardownAndReload(t *testing.T) {
+	loadedBefore := LoadError() == nil
+
+	initTCPClosures()
+	initTimerClosure()
+	trampolinesBefore := len(trampolines)
+	registerNopTimer() // left registered on purpose
+	RegisterPooledTCPCallback(func(*Loop, *TCPCompletion, int32, uintptr) CbAction { return Disarm })
+
+	if err := Teardown(); err != nil {
+		t.Fatalf("Teardown: %v", err)
+	}
+	if len(closures) != 0 {
+		t.Fatalf("%d closures left after Teardown", len(closures))
+	}
+	if err := CheckCallbackLeaks(); err != nil {
+		t.Fatalf("registrations left after Teardown: %v", err)
+	}
+	if n := TotalCallbacks(KindTimer); n != 0 {
+		t.Fatalf("TotalCallbacks(KindTimer) = %d after Teardown, want 0", n)
+	}
+	// Nothing is left to unload the second time.
+	if err := Teardown(); err != nil {
+		t.Fatalf("second Teardown: %v", err)
+	}
+	if !errors.Is(LoadError(), ErrTornDown) || ExtLibLoaded() {
+		t.Fatalf("LoadError = %v, ExtLibLoaded = %v after Teardown", LoadError(), ExtLibLoaded())
+	}
+	var loop Loop
+	if err := LoopInit(&loop); !errors.Is(err, ErrTornDown) {
+		t.Fatalf("LoopInit after Teardown: %v", err)
+	}
+
+	if err := Load(); (err == nil) != loadedBefore {
+		t.Fatalf("Load after Teardown: %v", err)
+	}
+	// Closures come back, reusing the trampolines made before.
+	if GetTCPCallbackPtr() == 0 || GetTimerCallbackPtr() == 0 {
+		t.Fatal("closures not allocated again")
+	}
+	if len(trampolines) != trampolinesBefore {
+		t.Fatalf("trampolines = %d after reload, want %d", len(trampolines), trampolinesBefore)
+	}
+	id := RegisterPooledTCPCallback(func(*Loop, *TCPCompletion, int32, uintptr) CbAction { return Disarm })
+	if !UnregisterCallback(id) {
+		t.Fatal("pool unusable after Teardown")
+	}
+}
+
+func TestTeardownAndReloadProcessWait(t *testing.T) {
+	loadedBefore := LoadError() == nil
+
+	initProcessClosures()
+	if err := Teardown(); err != nil {
+		t.Fatalf("Teardown: %v", err)
+	}
+	if err := Load(); (err == nil) != loadedBefore {
+		t.Fatalf("Load after Teardown: %v", err)
+	}
+	// The closure freed by Teardown is allocated again, not reused.
+	if GetProcessWaitCallbackPtr() == 0 || !slices.Contains(closures, processWaitClosure) {
+		t.Fatal("process wait closure not allocated again")
+	}
+
+	if !ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start a child process: %v", err)
+	}
+	var p Process
+	if err := ProcessInit(&p, int32(cmd.Process.Pid)); err != nil {
+		t.Fatalf("ProcessInit: %v", err)
+	}
+	defer ProcessDeinit(&p)
+	var loop Loop
+	if err := LoopInit(&loop); err != nil {
+		t.Fatalf("LoopInit: %v", err)
+	}
+	defer LoopDeinit(&loop)
+
+	var c TCPCompletion
+	exited := false
+	ProcessWaitWithCallback(&p, &loop, &c, func(_ *Loop, _ *TCPCompletion, status, errCode int32, userdata uintptr) CbAction {
+		exited = errCode == 0 && status == 0
+		UnregisterCallback(userdata)
+		return Disarm
+	})
+	if err := LoopRun(&loop, RunUntilDone); err != nil {
+		t.Fatalf("LoopRun: %v", err)
+	}
+	if !exited {
+		t.Fatal("process wait did not complete after reload")
+	}
+}
+
+// registerNopTimer registers a timer callback that does nothing.
+func registerNopTimer() uintptr {
+	return RegisterCallback(func(*Loop, *Completion, int32, uintptr) CbAction { return Disarm })
+}
@@ -17,6 +17,7 @@ var (
 	fnThreadPoolDeinit     ffi.Fun
 	fnThreadPoolShutdown   ffi.Fun
 	fnThreadPoolConfigInit ffi.Fun
+	fnThreadPoolStats      ffi.Fun
 )
 
 func registerThreadPoolFunctions() error {
@@ -42,6 +43,14 @@ func registerThreadPoolFunctions() error {
 		return err
 	}
 
+	// void xev_threadpool_stats(xev_threadpool* pool, uint32_t out[3])
+	if libExt.Addr != 0 {
+		fnThreadPoolStats, err = libExt.Prep("xev_threadpool_stats", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypePointer)
+		if err != nil {
+			return err
+		}
+	}
+
 	// NOTE: xev_loop_set_thread_pool is removed in the new libxev API.
 	// Thread pools must now be passed via LoopOptions during initialization.
 	// Use LoopInitWithOptions instead.
@@ -73,6 +82,28 @@ func ThreadPoolShutdown(pool *ThreadPool) {
 	fnThreadPoolShutdown.Call(nil, &ptr)
 }
 
+// ThreadPoolStats describes the workers of a thread pool.
+type ThreadPoolStats struct {
+	// MaxThreads is the most workers the pool may spawn.
+	MaxThreads uint32
+	// Spawned is the workers it has spawned, and Idle those of them
+	// waiting for a task.
+	Spawned uint32
+	Idle    uint32
+}
+
+// ThreadPoolGetStats returns a snapshot of the workers of pool. The
+// workers update their counts concurrently, so it may already be stale.
+// It requires the extended library.
+func ThreadPoolGetStats(pool *ThreadPool) ThreadPoolStats {
+	mustExtLoaded("ThreadPoolGetStats")
+	var out [3]uint32
+	poolPtr := unsafe.Pointer(pool)
+	outPtr := unsafe.Pointer(&out)
+	fnThreadPoolStats.Call(nil, &poolPtr, &outPtr)
+	return ThreadPoolStats{MaxThreads: out[0], Spawned: out[1], Idle: out[2]}
+}
+
 // NOTE: LoopSetThreadPool is deprecated and removed.
 // libxev no longer supports setting thread_pool after Loop initialization.
 // Use LoopInitWithOptions to pass a thread pool during initialization instead.
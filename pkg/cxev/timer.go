@@ -64,7 +64,7 @@ func registerTimerFunctions() error {
 		return err
 	}
 
-	return nil
+	return registerAsyncFunctions()
 }
 
 // TimerInit initializes a timer watcher.
@@ -8,7 +8,7 @@ package cxev
 
 import (
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"unsafe"
 
 	"github.com/jupiterrider/ffi"
@@ -40,6 +40,8 @@ var (
 	fnUDPGetsockname ffi.Fun
 	fnUDPRead        ffi.Fun
 	fnUDPWrite       ffi.Fun
+	fnUDPReadMsg     ffi.Fun
+	fnUDPWriteMsg    ffi.Fun
 	fnUDPClose       ffi.Fun
 )
 
@@ -92,6 +94,22 @@ func registerUDPFunctions() error {
 		return err
 	}
 
+	// int xev_udp_read_msg(xev_udp*, xev_loop*, xev_completion*, xev_udp_state*, buf, buf_len, control, control_len, void* userdata, callback)
+	fnUDPReadMsg, err = libExt.Prep("xev_udp_read_msg", &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
+	// int xev_udp_write_msg(xev_udp*, xev_loop*, xev_completion*, xev_udp_state*, xev_sockaddr*, buf, buf_len, control, control_len, void* userdata, callback)
+	fnUDPWriteMsg, err = libExt.Prep("xev_udp_write_msg", &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer)
+	if err != nil {
+		return err
+	}
+
 	// void xev_udp_close(xev_udp*, xev_loop*, xev_completion*, void* userdata, callback)
 	fnUDPClose, err = libExt.Prep("xev_udp_close", &ffi.TypeVoid,
 		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
@@ -104,8 +122,8 @@ func registerUDPFunctions() error {
 
 // UDPInit initializes a UDP socket with the given address family.
 func UDPInit(udp *UDP, family int32) error {
-	if loadErr != nil {
-		return loadErr
+	if err := extLoaded(); err != nil {
+		return err
 	}
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(udp)
@@ -118,12 +136,14 @@ func UDPInit(udp *UDP, family int32) error {
 
 // UDPInitFd initializes a UDP socket from an existing file descriptor.
 func UDPInitFd(udp *UDP, fd int32) {
+	mustExtLoaded("UDPInitFd")
 	ptr := unsafe.Pointer(udp)
 	fnUDPInitFd.Call(nil, &ptr, &fd)
 }
 
 // UDPFd returns the file descriptor of a UDP socket.
 func UDPFd(udp *UDP) int32 {
+	mustExtLoaded("UDPFd")
 	var ret ffi.Arg
 	ptr := unsafe.Pointer(udp)
 	fnUDPFd.Call(&ret, &ptr)
@@ -132,6 +152,9 @@ func UDPFd(udp *UDP) int32 {
 
 // UDPBind binds a UDP socket to an address.
 func UDPBind(udp *UDP, addr *Sockaddr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
 	var ret ffi.Arg
 	udpPtr := unsafe.Pointer(udp)
 	addrPtr := unsafe.Pointer(addr)
@@ -144,6 +167,9 @@ func UDPBind(udp *UDP, addr *Sockaddr) error {
 
 // UDPGetsockname gets the local address of a bound UDP socket.
 func UDPGetsockname(udp *UDP, addr *Sockaddr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
 	var ret ffi.Arg
 	udpPtr := unsafe.Pointer(udp)
 	addrPtr := unsafe.Pointer(addr)
@@ -158,7 +184,12 @@ func UDPGetsockname(udp *UDP, addr *Sockaddr) error {
 type UDPError int32
 
 func (e UDPError) Error() string {
-	return "udp error: " + string(rune(e))
+	return "udp error: " + ErrorName(int32(e))
+}
+
+// Errno returns the errno equivalent of e, or 0 if there is none.
+func (e UDPError) Errno() syscall.Errno {
+	return ErrnoFromCode(int32(e))
 }
 
 // UDP Callback types
@@ -173,14 +204,6 @@ type UDPWriteCallback func(loop *Loop, c *UDPCompletion, bytesWritten int32, err
 // UDPCallback is called for simple UDP operations (close).
 type UDPCallback func(loop *Loop, c *UDPCompletion, result int32, userdata uintptr) CbAction
 
-// UDP callback registry
-var (
-	udpReadCallbackRegistry  sync.Map
-	udpWriteCallbackRegistry sync.Map
-	udpCallbackRegistry      sync.Map
-	udpCallbackCounter       uint64
-)
-
 // UDP callback closure state
 var (
 	udpClosureInit sync.Once
@@ -204,7 +227,7 @@ var (
 func initUDPClosures() {
 	udpClosureInit.Do(func() {
 		// UDP read callback: (loop*, completion*, remote_addr*, buf*, bytes_read int32, err int32, userdata*) -> int32
-		udpReadClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &udpReadCode)
+		udpReadClosure = allocClosure(&udpReadCode)
 		if status := ffi.PrepCif(&udpReadCif, ffi.DefaultAbi, 7,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer,
@@ -212,35 +235,35 @@ func initUDPClosures() {
 		); status != ffi.OK {
 			panic("failed to prepare UDP read callback CIF")
 		}
-		goReadCallback := ffi.NewCallback(udpReadTrampoline)
+		goReadCallback := trampolineCallback(udpReadTrampoline)
 		if status := ffi.PrepClosureLoc(udpReadClosure, &udpReadCif, goReadCallback, nil, udpReadCode); status != ffi.OK {
 			panic("failed to prepare UDP read closure")
 		}
 		udpReadCallbackPtr = uintptr(udpReadCode)
 
 		// UDP write callback: (loop*, completion*, bytes_written int32, err int32, userdata*) -> int32
-		udpWriteClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &udpWriteCode)
+		udpWriteClosure = allocClosure(&udpWriteCode)
 		if status := ffi.PrepCif(&udpWriteCif, ffi.DefaultAbi, 5,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare UDP write callback CIF")
 		}
-		goWriteCallback := ffi.NewCallback(udpWriteTrampoline)
+		goWriteCallback := trampolineCallback(udpWriteTrampoline)
 		if status := ffi.PrepClosureLoc(udpWriteClosure, &udpWriteCif, goWriteCallback, nil, udpWriteCode); status != ffi.OK {
 			panic("failed to prepare UDP write closure")
 		}
 		udpWriteCallbackPtr = uintptr(udpWriteCode)
 
 		// UDP simple callback: (loop*, completion*, result int32, userdata*) -> int32
-		udpClosure = ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &udpClosureCode)
+		udpClosure = allocClosure(&udpClosureCode)
 		if status := ffi.PrepCif(&udpCif, ffi.DefaultAbi, 4,
 			&ffi.TypeSint32,
 			&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypePointer,
 		); status != ffi.OK {
 			panic("failed to prepare UDP callback CIF")
 		}
-		goCallback := ffi.NewCallback(udpTrampoline)
+		goCallback := trampolineCallback(udpTrampoline)
 		if status := ffi.PrepClosureLoc(udpClosure, &udpCif, goCallback, nil, udpClosureCode); status != ffi.OK {
 			panic("failed to prepare UDP closure")
 		}
@@ -248,11 +271,8 @@ func initUDPClosures() {
 	})
 }
 
-// udpReadContext holds the buffer pointer and length for read callbacks.
-type udpReadContext struct {
-	cb  UDPReadCallback
-	buf []byte
-}
+// udpReadContext is the registration of a UDP read.
+type udpReadContext = readContext[UDPReadCallback]
 
 func udpReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
 	arguments := unsafe.Slice(args, 7)
@@ -265,17 +285,12 @@ func udpReadTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, u
 	userdata := *(*uintptr)(arguments[6])
 
 	action := int32(Disarm)
-	if ctx, ok := udpReadCallbackRegistry.Load(userdata); ok {
-		readCtx := ctx.(udpReadContext)
-		var buf []byte
-		if bytesRead > 0 {
-			buf = readCtx.buf[:bytesRead]
-		}
+	if readCtx, ok := loadRead(udpReadSlot, userdata); ok {
 		action = int32(readCtx.cb(
 			(*Loop)(loop),
 			(*UDPCompletion)(completion),
 			(*Sockaddr)(remoteAddr),
-			buf,
+			readCtx.data(bytesRead),
 			bytesRead,
 			errCode,
 			userdata,
@@ -294,8 +309,8 @@ func udpWriteTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer,
 	userdata := *(*uintptr)(arguments[4])
 
 	action := int32(Disarm)
-	if cb, ok := udpWriteCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(UDPWriteCallback)(
+	if cb, ok := udpWriteSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*UDPCompletion)(completion),
 			bytesWritten,
@@ -315,8 +330,8 @@ func udpTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userD
 	userdata := *(*uintptr)(arguments[3])
 
 	action := int32(Disarm)
-	if cb, ok := udpCallbackRegistry.Load(userdata); ok {
-		action = int32(cb.(UDPCallback)(
+	if cb, ok := udpSlot.lookup(userdata); ok {
+		action = int32(cb(
 			(*Loop)(loop),
 			(*UDPCompletion)(completion),
 			result,
@@ -329,30 +344,23 @@ func udpTrampoline(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userD
 
 // RegisterUDPReadCallback registers a UDP read callback with its buffer.
 func RegisterUDPReadCallback(cb UDPReadCallback, buf []byte) uintptr {
-	id := uintptr(atomic.AddUint64(&udpCallbackCounter, 1))
-	udpReadCallbackRegistry.Store(id, udpReadContext{cb: cb, buf: buf})
-	return id
+	return udpReadSlot.register(udpReadContext{cb: cb, buf: buf})
 }
 
 // RegisterUDPWriteCallback registers a UDP write callback.
 func RegisterUDPWriteCallback(cb UDPWriteCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&udpCallbackCounter, 1))
-	udpWriteCallbackRegistry.Store(id, cb)
-	return id
+	return udpWriteSlot.register(cb)
 }
 
 // RegisterUDPCallback registers a UDP callback.
 func RegisterUDPCallback(cb UDPCallback) uintptr {
-	id := uintptr(atomic.AddUint64(&udpCallbackCounter, 1))
-	udpCallbackRegistry.Store(id, cb)
-	return id
+	return udpSlot.register(cb)
 }
 
-// UnregisterUDPCallback removes a UDP callback from all registries.
+// UnregisterUDPCallback removes a UDP callback from the registry.
+// It is equivalent to [UnregisterCallback].
 func UnregisterUDPCallback(id uintptr) {
-	udpReadCallbackRegistry.Delete(id)
-	udpWriteCallbackRegistry.Delete(id)
-	udpCallbackRegistry.Delete(id)
+	callbacks.unregister(id)
 }
 
 // GetUDPReadCallbackPtr returns the C function pointer for read callbacks.
@@ -374,26 +382,28 @@ func GetUDPCallbackPtr() uintptr {
 }
 
 // UDPRead starts reading from a UDP socket.
+// It does not allocate.
 func UDPRead(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, buf []byte, userdata, cb uintptr) {
-	udpPtr := unsafe.Pointer(udp)
-	loopPtr := unsafe.Pointer(loop)
-	cPtr := unsafe.Pointer(c)
-	statePtr := unsafe.Pointer(state)
-	bufPtr := bufferPointer(buf)
-	bufLen := uint64(len(buf))
-	fnUDPRead.Call(nil, &udpPtr, &loopPtr, &cPtr, &statePtr, &bufPtr, &bufLen, &userdata, &cb)
+	mustExtLoaded("UDPRead")
+	getFrame().
+		ptr(unsafe.Pointer(udp)).ptr(unsafe.Pointer(loop)).ptr(unsafe.Pointer(c)).ptr(unsafe.Pointer(state)).
+		ptr(bufferPointer(buf)).word(uint64(len(buf))).
+		word(uint64(userdata)).word(uint64(cb)).
+		call(&fnUDPRead)
 }
 
 // UDPReadWithCallback is a convenience function that registers the callback and starts reading.
+// Like [TCPReadWithCallback], it pools the registration.
 func UDPReadWithCallback(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, buf []byte, cb UDPReadCallback) uintptr {
 	initUDPClosures()
-	id := RegisterUDPReadCallback(cb, buf)
+	id := RegisterPooledUDPReadCallback(cb, buf)
 	UDPRead(udp, loop, c, state, buf, id, udpReadCallbackPtr)
 	return id
 }
 
 // UDPWrite starts writing to a UDP socket.
 func UDPWrite(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, addr *Sockaddr, buf []byte, userdata, cb uintptr) {
+	mustExtLoaded("UDPWrite")
 	udpPtr := unsafe.Pointer(udp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -412,8 +422,93 @@ func UDPWriteWithCallback(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPStat
 	return id
 }
 
+// UDPReadMsg starts reading a datagram and its ancillary data from a UDP
+// socket. The callback is the one of [UDPRead]; while it runs, the kernel's
+// control messages are in control, and [UDPStateControlLen] and
+// [UDPStateMsgFlags] describe them. control must stay valid until the read
+// completes for the last time.
+func UDPReadMsg(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, buf, control []byte, userdata, cb uintptr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	udpPtr := unsafe.Pointer(udp)
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	statePtr := unsafe.Pointer(state)
+	bufPtr := bufferPointer(buf)
+	bufLen := uint64(len(buf))
+	controlPtr := bufferPointer(control)
+	controlLen := uint64(len(control))
+	fnUDPReadMsg.Call(&ret, &udpPtr, &loopPtr, &cPtr, &statePtr, &bufPtr, &bufLen, &controlPtr, &controlLen, &userdata, &cb)
+	if int32(ret) != 0 {
+		return UDPError(int32(ret))
+	}
+	return nil
+}
+
+// UDPReadMsgWithCallback registers cb and starts a message read. On error
+// nothing stays registered and the returned ID is zero.
+func UDPReadMsgWithCallback(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, buf, control []byte, cb UDPReadCallback) (uintptr, error) {
+	initUDPClosures()
+	id := RegisterUDPReadCallback(cb, buf)
+	if err := UDPReadMsg(udp, loop, c, state, buf, control, id, udpReadCallbackPtr); err != nil {
+		UnregisterUDPCallback(id)
+		return 0, err
+	}
+	return id, nil
+}
+
+// UDPStateControlLen returns the number of control bytes received by the
+// message read using state.
+func UDPStateControlLen(state *UDPState) int {
+	return int(*(*uint32)(unsafe.Pointer(&state[0])))
+}
+
+// UDPStateMsgFlags returns the flags, such as MSG_TRUNC, of the message
+// received by the read using state.
+func UDPStateMsgFlags(state *UDPState) int32 {
+	return *(*int32)(unsafe.Pointer(&state[4]))
+}
+
+// UDPWriteMsg starts writing a datagram with the control messages in
+// control, which may be empty.
+func UDPWriteMsg(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, addr *Sockaddr, buf, control []byte, userdata, cb uintptr) error {
+	if err := extLoaded(); err != nil {
+		return err
+	}
+	var ret ffi.Arg
+	udpPtr := unsafe.Pointer(udp)
+	loopPtr := unsafe.Pointer(loop)
+	cPtr := unsafe.Pointer(c)
+	statePtr := unsafe.Pointer(state)
+	addrPtr := unsafe.Pointer(addr)
+	bufPtr := bufferPointer(buf)
+	bufLen := uint64(len(buf))
+	controlPtr := bufferPointer(control)
+	controlLen := uint64(len(control))
+	fnUDPWriteMsg.Call(&ret, &udpPtr, &loopPtr, &cPtr, &statePtr, &addrPtr, &bufPtr, &bufLen, &controlPtr, &controlLen, &userdata, &cb)
+	if int32(ret) != 0 {
+		return UDPError(int32(ret))
+	}
+	return nil
+}
+
+// UDPWriteMsgWithCallback registers cb and starts a message write. On
+// error nothing stays registered and the returned ID is zero.
+func UDPWriteMsgWithCallback(udp *UDP, loop *Loop, c *UDPCompletion, state *UDPState, addr *Sockaddr, buf, control []byte, cb UDPWriteCallback) (uintptr, error) {
+	initUDPClosures()
+	id := RegisterUDPWriteCallback(cb)
+	if err := UDPWriteMsg(udp, loop, c, state, addr, buf, control, id, udpWriteCallbackPtr); err != nil {
+		UnregisterUDPCallback(id)
+		return 0, err
+	}
+	return id, nil
+}
+
 // UDPClose starts closing a UDP socket.
 func UDPClose(udp *UDP, loop *Loop, c *UDPCompletion, userdata, cb uintptr) {
+	mustExtLoaded("UDPClose")
 	udpPtr := unsafe.Pointer(udp)
 	loopPtr := unsafe.Pointer(loop)
 	cPtr := unsafe.Pointer(c)
@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements per-operation UDP state.
+//
+// # Why
+//
+// libxev keeps the message header, iovec and destination address of a UDP
+// operation in its xev_udp_state until the operation completes. A socket
+// that shares one state between operations can only have one in flight:
+// a second sendto submitted before the first completes overwrites the
+// header the kernel has yet to read, and the first datagram goes out with
+// the second one's address or payload.
+//
+// A UDPStatePool hands each operation a state of its own and takes it back
+// when the operation's callback disarms. It tracks which states are active,
+// so returning a state twice, or one it never handed out, is reported
+// instead of putting a state that libxev still uses back into circulation.
+
+package cxev
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrUDPStateNotActive is returned by [UDPStatePool.Put] for a state that
+// the pool has not handed out, or that was already returned.
+var ErrUDPStateNotActive = errors.New("cxev: udp state is not active in this pool")
+
+// UDPStatePool hands out pinned [UDPState] values for individual
+// operations. Unlike [Arena], it is safe for concurrent use.
+type UDPStatePool struct {
+	mu     sync.Mutex
+	arena  *Arena[UDPState]
+	active map[*UDPState]struct{}
+}
+
+// NewUDPStatePool creates a pool that grows chunkSize states at a time.
+func NewUDPStatePool(chunkSize int) *UDPStatePool {
+	return &UDPStatePool{
+		arena:  NewArena[UDPState](chunkSize),
+		active: make(map[*UDPState]struct{}),
+	}
+}
+
+// Get returns a zeroed state and marks it active.
+func (p *UDPStatePool) Get() *UDPState {
+	p.mu.Lock()
+	s := p.arena.Get()
+	p.active[s] = struct{}{}
+	p.mu.Unlock()
+	return s
+}
+
+// Put returns s to the pool once libxev no longer references it. It returns
+// [ErrUDPStateNotActive], and leaves the pool unchanged, if s is not active.
+func (p *UDPStatePool) Put(s *UDPState) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.active[s]; !ok {
+		return ErrUDPStateNotActive
+	}
+	delete(p.active, s)
+	p.arena.Put(s)
+	return nil
+}
+
+// Active reports whether s is handed out and not yet returned.
+func (p *UDPStatePool) Active(s *UDPState) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.active[s]
+	return ok
+}
+
+// InUse returns the number of active states.
+func (p *UDPStatePool) InUse() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.active)
+}
+
+// udpStates backs the states of [UDPWriteWithPooledState].
+var udpStates = NewUDPStatePool(0)
+
+// DebugUDPStateCount returns the number of pooled UDP states held by
+// operations in flight.
+func DebugUDPStateCount() int {
+	return udpStates.InUse()
+}
+
+// UDPWriteWithPooledState starts writing buf to addr with a state of its
+// own, so that several writes may be in flight on one socket, each with its
+// own completion. The state goes back to the pool when cb returns [Disarm].
+// Returns the callback ID, like [UDPWriteWithCallback].
+func UDPWriteWithPooledState(udp *UDP, loop *Loop, c *UDPCompletion, addr *Sockaddr, buf []byte, cb UDPWriteCallback) uintptr {
+	initUDPClosures()
+	state := udpStates.Get()
+	id := registerPooledStateWrite(udpStates, state, cb)
+	UDPWrite(udp, loop, c, state, addr, buf, id, udpWriteCallbackPtr)
+	return id
+}
+
+// registerPooledStateWrite registers cb so that state returns to p when the
+// write disarms.
+func registerPooledStateWrite(p *UDPStatePool, state *UDPState, cb UDPWriteCallback) uintptr {
+	return RegisterUDPWriteCallback(func(loop *Loop, c *UDPCompletion, bytesWritten int32, err int32, userdata uintptr) CbAction {
+		action := cb(loop, c, bytesWritten, err, userdata)
+		if action == Disarm {
+			if perr := p.Put(state); perr != nil {
+				panic("cxev: pooled udp write completed twice on one state")
+			}
+		}
+		return action
+	})
+}
@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package cxev
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestUDPStatePoolValidation(t *testing.T) {
+	p := NewUDPStatePool(2)
+
+	s := p.Get()
+	if !p.Active(s) || p.InUse() != 1 {
+		t.Fatalf("fresh state: active=%v in use=%d", p.Active(s), p.InUse())
+	}
+	s[0] = 1
+	if err := p.Put(s); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := p.Put(s); !errors.Is(err, ErrUDPStateNotActive) {
+		t.Fatalf("second Put: %v, want ErrUDPStateNotActive", err)
+	}
+	var foreign UDPState
+	if err := p.Put(&foreign); !errors.Is(err, ErrUDPStateNotActive) {
+		t.Fatalf("Put of foreign state: %v, want ErrUDPStateNotActive", err)
+	}
+	if p.InUse() != 0 {
+		t.Fatalf("InUse = %d after rejected Puts, want 0", p.InUse())
+	}
+
+	again := p.Get()
+	if again != s {
+		t.Fatal("expected the returned state to be reused")
+	}
+	if again[0] != 0 {
+		t.Fatal("reused state must be zeroed")
+	}
+}
+
+// TestUDPStatePoolStress checks that concurrent holders never share a
+// state: each one stamps its state and finds the stamp intact on return.
+func TestUDPStatePoolStress(t *testing.T) {
+	p := NewUDPStatePool(8)
+	const workers, rounds = 16, 2000
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(stamp byte) {
+			defer wg.Done()
+			held := make([]*UDPState, 0, 4)
+			for i := 0; i < rounds; i++ {
+				s := p.Get()
+				for j := range s {
+					s[j] = stamp
+				}
+				held = append(held, s)
+				if len(held) < cap(held) {
+					continue
+				}
+				runtime.Gosched()
+				for _, s := range held {
+					for j := range s {
+						if s[j] != stamp {
+							t.Errorf("state shared between holders: byte %d = %d, want %d", j, s[j], stamp)
+							return
+						}
+					}
+					if err := p.Put(s); err != nil {
+						t.Errorf("Put failed: %v", err)
+						return
+					}
+				}
+				held = held[:0]
+			}
+			for _, s := range held {
+				if err := p.Put(s); err != nil {
+					t.Errorf("Put failed: %v", err)
+				}
+			}
+		}(byte(w + 1))
+	}
+	wg.Wait()
+
+	if p.InUse() != 0 {
+		t.Fatalf("InUse = %d after all holders returned, want 0", p.InUse())
+	}
+}
+
+func TestPooledStateWriteReleasesOnDisarm(t *testing.T) {
+	p := NewUDPStatePool(4)
+
+	// Overlapping writes each hold their own state.
+	const writes = 3
+	ids := make([]uintptr, writes)
+	states := make([]*UDPState, writes)
+	rearms := 1
+	for i := range ids {
+		states[i] = p.Get()
+		ids[i] = registerPooledStateWrite(p, states[i], func(loop *Loop, c *UDPCompletion, n int32, err int32, userdata uintptr) CbAction {
+			if rearms > 0 {
+				rearms--
+				return Rearm
+			}
+			return Disarm
+		})
+		defer UnregisterUDPCallback(ids[i])
+	}
+	if p.InUse() != writes {
+		t.Fatalf("InUse = %d, want %d", p.InUse(), writes)
+	}
+
+	for i, id := range ids {
+		cb, ok := udpWriteSlot.load(id)
+		if !ok {
+			t.Fatal("pooled write not visible to the udp write slot")
+		}
+		if i == 0 {
+			if cb(nil, nil, 1, 0, id) != Rearm {
+				t.Fatal("first completion should rearm")
+			}
+			if !p.Active(states[i]) {
+				t.Fatal("state released while its write is still armed")
+			}
+		}
+		if cb(nil, nil, 1, 0, id) != Disarm {
+			t.Fatal("completion should disarm")
+		}
+		if p.Active(states[i]) {
+			t.Fatalf("state of write %d not released on Disarm", i)
+		}
+	}
+	if p.InUse() != 0 {
+		t.Fatalf("InUse = %d after all writes disarmed, want 0", p.InUse())
+	}
+}
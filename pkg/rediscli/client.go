@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,18 +21,22 @@ import (
 // ErrEmptyCommand indicates no command tokens were provided.
 var ErrEmptyCommand = errors.New("empty command")
 
-// Client executes RESP2 commands against a Redis-compatible endpoint.
+// Client executes commands against a Redis-compatible endpoint.
 type Client struct {
 	Addr    string
 	Timeout time.Duration
 	Dial    func(network, addr string) (net.Conn, error)
+	// Protocol is the RESP version replies are requested in. With 3, each
+	// connection starts with HELLO 3.
+	Protocol int
 }
 
-// NewClient creates a client with default TCP dial behavior.
+// NewClient creates a RESP2 client with default TCP dial behavior.
 func NewClient(addr string) *Client {
 	return &Client{
-		Addr:    addr,
-		Timeout: 2 * time.Second,
+		Addr:     addr,
+		Timeout:  2 * time.Second,
+		Protocol: 2,
 		Dial: func(network, addr string) (net.Conn, error) {
 			d := net.Dialer{Timeout: 2 * time.Second}
 			return d.Dial(network, addr)
@@ -100,36 +105,30 @@ func (c *Client) runInteractive(in io.Reader, out, errOut io.Writer) error {
 	}
 }
 
-// Do sends a single command and waits for one response frame.
+// Do sends a single command on a new connection and waits for one
+// response frame.
 func (c *Client) Do(args []string) (redisproto.Value, error) {
 	if len(args) == 0 {
 		return redisproto.Value{}, ErrEmptyCommand
 	}
-
-	conn, err := c.Dial("tcp", c.Addr)
+	cn, err := c.Connect()
 	if err != nil {
-		return redisproto.Value{}, fmt.Errorf("connect %s failed: %w", c.Addr, err)
-	}
-	defer conn.Close()
-	if c.Timeout > 0 {
-		_ = conn.SetDeadline(time.Now().Add(c.Timeout))
+		return redisproto.Value{}, err
 	}
+	defer cn.Close()
+	return cn.Do(args)
+}
 
-	cmd := BuildCommand(args)
-	wire, err := redisproto.Encode(cmd)
+// roundTrip sends one command on conn and reads its reply.
+func roundTrip(conn net.Conn, br *bufio.Reader, codec *redisproto.Codec, args []string) (redisproto.Value, error) {
+	wire, err := codec.AppendEncode(nil, BuildCommand(args))
 	if err != nil {
 		return redisproto.Value{}, fmt.Errorf("encode command failed: %w", err)
 	}
-
 	if _, err = conn.Write(wire); err != nil {
 		return redisproto.Value{}, fmt.Errorf("write command failed: %w", err)
 	}
-
-	resp, err := ReadResponse(conn)
-	if err != nil {
-		return redisproto.Value{}, err
-	}
-	return resp, nil
+	return readFrame(br, codec)
 }
 
 // BuildCommand constructs a RESP2 array of bulk strings.
@@ -141,10 +140,13 @@ func BuildCommand(args []string) redisproto.Value {
 	return redisproto.Value{Kind: redisproto.KindArray, Array: arr}
 }
 
-// ReadResponse reads one RESP2 frame from reader.
+// ReadResponse reads one RESP frame from reader.
 func ReadResponse(r io.Reader) (redisproto.Value, error) {
-	parser := redisproto.NewParser()
-	br := bufio.NewReader(r)
+	return readFrame(bufio.NewReader(r), redisproto.NewCodec())
+}
+
+// readFrame reads one frame with the connection's codec.
+func readFrame(br *bufio.Reader, codec *redisproto.Codec) (redisproto.Value, error) {
 	buf := make([]byte, 4096)
 
 	for {
@@ -156,7 +158,7 @@ func ReadResponse(r io.Reader) (redisproto.Value, error) {
 			return redisproto.Value{}, fmt.Errorf("read response failed: %w", err)
 		}
 
-		frames, parseErr := parser.Feed(buf[:n])
+		frames, parseErr := codec.Feed(buf[:n])
 		if parseErr != nil {
 			return redisproto.Value{}, fmt.Errorf("protocol error: %w", parseErr)
 		}
@@ -166,32 +168,124 @@ func ReadResponse(r io.Reader) (redisproto.Value, error) {
 	}
 }
 
-// FormatValue renders RESP2 values for CLI output.
+// FormatValue renders RESP values for CLI output the way redis-cli does
+// when its output is a terminal, or with --no-raw: bulk strings are quoted
+// with escapes, array items are numbered "1)", set items "1~" and map
+// entries "1# key => value", and nested aggregates are indented under
+// their parent's index. Attributes are not shown. The result has no
+// trailing newline.
 func FormatValue(v redisproto.Value) string {
+	b := appendFormatted(nil, v, "")
+	return string(b[:len(b)-1])
+}
+
+// appendFormatted appends v and a newline to dst, following redis-cli's
+// cliFormatReplyTTY. prefix is the indentation of the lines after the
+// first one of an aggregate; its first line goes after the index the
+// caller wrote.
+func appendFormatted(dst []byte, v redisproto.Value, prefix string) []byte {
 	switch v.Kind {
 	case redisproto.KindSimpleString:
-		return v.Str
+		dst = append(dst, v.Str...)
 	case redisproto.KindError:
-		return "(error) " + v.Str
+		dst = append(dst, "(error) "...)
+		dst = append(dst, v.Str...)
 	case redisproto.KindInteger:
-		return fmt.Sprintf("(integer) %d", v.Int)
+		dst = append(dst, "(integer) "...)
+		dst = strconv.AppendInt(dst, v.Int, 10)
 	case redisproto.KindBulkString:
-		return string(v.Bulk)
+		dst = appendQuoted(dst, v.Bulk)
 	case redisproto.KindNull:
-		return "(nil)"
+		dst = append(dst, "(nil)"...)
+	case redisproto.KindBigNumber:
+		dst = append(dst, "(big number) "...)
+		dst = append(dst, v.Str...)
+	case redisproto.KindVerbatimString:
+		dst = append(dst, v.Bulk...)
+	case redisproto.KindDouble:
+		dst = append(dst, "(double) "...)
+		dst = append(dst, v.Str...)
+	case redisproto.KindBoolean:
+		if v.Int != 0 {
+			dst = append(dst, "(true)"...)
+		} else {
+			dst = append(dst, "(false)"...)
+		}
 	case redisproto.KindArray:
-		if len(v.Array) == 0 {
-			return "(empty array)"
+		return appendItems(dst, v.Array, ')', "(empty array)", prefix)
+	case redisproto.KindPush:
+		return appendItems(dst, v.Array, ')', "(empty push)", prefix)
+	case redisproto.KindSet:
+		return appendItems(dst, v.Array, '~', "(empty set)", prefix)
+	case redisproto.KindMap:
+		return appendItems(dst, v.Array, '#', "(empty hash)", prefix)
+	default:
+		dst = append(dst, "(unknown)"...)
+	}
+	return append(dst, '\n')
+}
+
+// appendItems appends the items of an aggregate, each on its own line
+// after its index, right-aligned to the width of the largest one. Maps,
+// marked '#', hold keys and values in turn and show them as
+// "key => value".
+func appendItems(dst []byte, items []redisproto.Value, mark byte, empty, prefix string) []byte {
+	if len(items) == 0 {
+		dst = append(dst, empty...)
+		return append(dst, '\n')
+	}
+	n := len(items)
+	if mark == '#' {
+		n /= 2
+	}
+	width := len(strconv.Itoa(n))
+	nested := prefix + strings.Repeat(" ", width+2)
+	for i := 0; i < len(items); i++ {
+		if i > 0 {
+			dst = append(dst, prefix...)
 		}
-		var b strings.Builder
-		for i, item := range v.Array {
-			_, _ = fmt.Fprintf(&b, "%d) %s", i+1, FormatValue(item))
-			if i < len(v.Array)-1 {
-				_ = b.WriteByte('\n')
+		idx := i + 1
+		if mark == '#' {
+			idx = i/2 + 1
+		}
+		dst = fmt.Appendf(dst, "%*d%c ", width, idx, mark)
+		dst = appendFormatted(dst, items[i], nested)
+		if mark == '#' && i+1 < len(items) {
+			i++
+			dst = append(dst[:len(dst)-1], " => "...)
+			dst = appendFormatted(dst, items[i], nested)
+		}
+	}
+	return dst
+}
+
+// appendQuoted appends b quoted the way redis-cli prints bulk strings:
+// quotes and backslashes are escaped, common control characters use their
+// C escapes and other unprintable bytes are shown as \xHH.
+func appendQuoted(dst, b []byte) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for _, c := range b {
+		switch c {
+		case '\\', '"':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		case '\a':
+			dst = append(dst, `\a`...)
+		case '\b':
+			dst = append(dst, `\b`...)
+		default:
+			if c >= 0x20 && c < 0x7f {
+				dst = append(dst, c)
+			} else {
+				dst = append(dst, '\\', 'x', hex[c>>4], hex[c&0xf])
 			}
 		}
-		return b.String()
-	default:
-		return "(unknown)"
 	}
+	return append(dst, '"')
 }
@@ -242,3 +242,89 @@ func TestRedisCLIInteractiveContinuesAfterCommandError(t *testing.T) {
 		t.Fatalf("did not expect network/protocol error in stderr: %q", errOut.String())
 	}
 }
+
+func TestRedisCLIDoRESP3(t *testing.T) {
+	client := NewClient("fake")
+	client.Protocol = 3
+	client.Dial = func(network, addr string) (net.Conn, error) {
+		server, cli := net.Pipe()
+		go func() {
+			defer server.Close()
+			parser := redisproto.NewParser()
+			buf := make([]byte, 256)
+			for i, reply := range []string{"%1\r\n$5\r\nproto\r\n:3\r\n", "%2\r\n$1\r\nf\r\n,1.5\r\n$1\r\ng\r\n#t\r\n"} {
+				n, err := server.Read(buf)
+				if err != nil {
+					return
+				}
+				frames, err := parser.Feed(buf[:n])
+				if err != nil || len(frames) != 1 {
+					return
+				}
+				if i == 0 && string(frames[0].Array[0].Bulk) != "HELLO" {
+					return
+				}
+				_, _ = server.Write([]byte(reply))
+			}
+		}()
+		return cli, nil
+	}
+
+	resp, err := client.Do([]string{"HGETALL", "h"})
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if resp.Kind != redisproto.KindMap {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	if got, want := FormatValue(resp), "1# \"f\" => (double) 1.5\n2# \"g\" => (true)"; got != want {
+		t.Fatalf("unexpected rendering: got=%q want=%q", got, want)
+	}
+}
+
+func TestFormatValueNesting(t *testing.T) {
+	bulk := func(s string) redisproto.Value {
+		return redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(s)}
+	}
+	array := func(items ...redisproto.Value) redisproto.Value {
+		return redisproto.Value{Kind: redisproto.KindArray, Array: items}
+	}
+	var ten []redisproto.Value
+	for i := range 10 {
+		ten = append(ten, redisproto.Value{Kind: redisproto.KindInteger, Int: int64(i)})
+	}
+	ten[9] = array(bulk("x"), bulk("y"))
+
+	// The expected outputs are those of redis-cli --no-raw.
+	tests := []struct {
+		v    redisproto.Value
+		want string
+	}{
+		{bulk("a \"q\" \\ \n\r\t\a\b\x00\xff"), `"a \"q\" \\ \n\r\t\a\b\x00\xff"`},
+		{bulk(""), `""`},
+		{array(), "(empty array)"},
+		{
+			array(array(bulk("a"), bulk("b")), bulk("c"), array()),
+			"1) 1) \"a\"\n   2) \"b\"\n2) \"c\"\n3) (empty array)",
+		},
+		{
+			array(ten...),
+			" 1) (integer) 0\n 2) (integer) 1\n 3) (integer) 2\n 4) (integer) 3\n 5) (integer) 4\n" +
+				" 6) (integer) 5\n 7) (integer) 6\n 8) (integer) 7\n 9) (integer) 8\n10) 1) \"x\"\n    2) \"y\"",
+		},
+		{
+			redisproto.Value{Kind: redisproto.KindMap, Array: []redisproto.Value{
+				bulk("k"), array(bulk("a"), bulk("b")),
+				bulk("s"), {Kind: redisproto.KindSet, Array: []redisproto.Value{bulk("m")}},
+			}},
+			"1# \"k\" => 1) \"a\"\n   2) \"b\"\n2# \"s\" => 1~ \"m\"",
+		},
+		{redisproto.Value{Kind: redisproto.KindPush}, "(empty push)"},
+		{redisproto.Value{Kind: redisproto.KindVerbatimString, Bulk: []byte("a\nb")}, "a\nb"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.v); got != tt.want {
+			t.Errorf("FormatValue(%#v):\ngot  %q\nwant %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// CopyOptions configures [Client.CopyTo].
+type CopyOptions struct {
+	// Match restricts the copy to keys matching a glob pattern; empty
+	// copies every key.
+	Match string
+	// Count is the SCAN COUNT hint; zero leaves the server default.
+	Count int
+	// Replace overwrites keys that exist on the destination. Without it
+	// they are skipped.
+	Replace bool
+}
+
+// CopyResult counts the keys seen by [Client.CopyTo].
+type CopyResult struct {
+	Scanned int
+	Copied  int
+	// Skipped counts keys that vanished from the source before they were
+	// dumped, or that already existed on the destination.
+	Skipped int
+}
+
+// CopyTo copies the keys of c to dst: it SCANs the source, DUMPs every
+// key with its remaining TTL, and RESTOREs it on the destination. Keys
+// written while the copy runs may or may not be copied, as SCAN
+// guarantees. It stops at the first error, returning what was copied.
+func (c *Client) CopyTo(dst *Client, opts CopyOptions) (CopyResult, error) {
+	var res CopyResult
+	src, err := c.Connect()
+	if err != nil {
+		return res, err
+	}
+	defer src.Close()
+	out, err := dst.Connect()
+	if err != nil {
+		return res, err
+	}
+	defer out.Close()
+
+	cursor := "0"
+	for {
+		args := []string{"SCAN", cursor}
+		if opts.Match != "" {
+			args = append(args, "MATCH", opts.Match)
+		}
+		if opts.Count > 0 {
+			args = append(args, "COUNT", strconv.Itoa(opts.Count))
+		}
+		reply, err := do(src, args)
+		if err != nil {
+			return res, err
+		}
+		if reply.Kind != redisproto.KindArray || len(reply.Array) != 2 {
+			return res, fmt.Errorf("unexpected SCAN reply: %s", FormatValue(reply))
+		}
+		cursor = string(replyText(reply.Array[0]))
+		for _, key := range reply.Array[1].Array {
+			res.Scanned++
+			copied, err := copyKey(src, out, string(replyText(key)), opts.Replace)
+			if err != nil {
+				return res, err
+			}
+			if copied {
+				res.Copied++
+			} else {
+				res.Skipped++
+			}
+		}
+		if cursor == "0" {
+			return res, nil
+		}
+	}
+}
+
+// copyKey moves one key from src to dst, reporting false if it is gone
+// from src or, without replace, already on dst.
+func copyKey(src, dst *Conn, key string, replace bool) (bool, error) {
+	payload, err := do(src, []string{"DUMP", key})
+	if err != nil || payload.Kind == redisproto.KindNull {
+		return false, err
+	}
+	ttl, err := do(src, []string{"PTTL", key})
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case ttl.Int == -2:
+		return false, nil
+	case ttl.Int < 0:
+		ttl.Int = 0
+	}
+	args := []string{"RESTORE", key, strconv.FormatInt(ttl.Int, 10), string(replyText(payload))}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+	v, err := dst.Do(args)
+	switch {
+	case err != nil:
+		return false, err
+	case v.Kind == redisproto.KindError && strings.HasPrefix(v.Str, "BUSYKEY"):
+		return false, nil
+	case v.Kind == redisproto.KindError:
+		return false, fmt.Errorf("RESTORE %q failed: %s", key, v.Str)
+	}
+	return true, nil
+}
+
+// do runs a command whose error reply is fatal to the copy.
+func do(cn *Conn, args []string) (redisproto.Value, error) {
+	v, err := cn.Do(args)
+	if err == nil && v.Kind == redisproto.KindError {
+		err = fmt.Errorf("%s failed: %s", args[0], v.Str)
+	}
+	return v, err
+}
+
+// replyText returns the contents of a bulk or simple string reply.
+func replyText(v redisproto.Value) []byte {
+	if v.Bulk != nil {
+		return v.Bulk
+	}
+	return []byte(v.Str)
+}
+
+// RunCopy copies the keys of c to dstAddr as described in [Client.CopyTo]
+// and prints how many were copied.
+func (c *Client) RunCopy(dstAddr string, opts CopyOptions, out, errOut io.Writer) int {
+	dst := *c
+	dst.Addr = dstAddr
+	res, err := c.CopyTo(&dst, opts)
+	_, _ = fmt.Fprintf(out, "copied %d keys to %s (%d scanned, %d skipped)\n", res.Copied, dstAddr, res.Scanned, res.Skipped)
+	if err != nil {
+		_, _ = fmt.Fprintf(errOut, "redis-cli error: %v\n", err)
+		return 1
+	}
+	return 0
+}
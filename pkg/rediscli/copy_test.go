@@ -0,0 +1,175 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"bytes"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// serveStore is a fake server holding string keys with TTLs, answering the
+// commands a copy sends. DUMP payloads are the values themselves, and SCAN
+// returns one key per call.
+type serveStore struct {
+	mu   sync.Mutex
+	vals map[string]string
+	ttls map[string]int64
+}
+
+func newServeStore(vals map[string]string) *serveStore {
+	return &serveStore{vals: vals, ttls: make(map[string]int64)}
+}
+
+func (s *serveStore) dial(network, addr string) (net.Conn, error) {
+	server, cli := net.Pipe()
+	go func() {
+		defer server.Close()
+		parser := redisproto.NewParser()
+		buf := make([]byte, 256)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			frames, err := parser.Feed(buf[:n])
+			if err != nil {
+				return
+			}
+			for _, f := range frames {
+				args := make([]string, len(f.Array))
+				for i, a := range f.Array {
+					args[i] = string(a.Bulk)
+				}
+				wire, _ := redisproto.Encode(s.handle(args))
+				if _, err := server.Write(wire); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return cli, nil
+}
+
+func (s *serveStore) handle(args []string) redisproto.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bulk := func(v string) redisproto.Value {
+		return redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(v)}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "SCAN":
+		keys := slices.Sorted(func(yield func(string) bool) {
+			for k := range s.vals {
+				if len(args) < 4 || matched(args[3], k) {
+					if !yield(k) {
+						return
+					}
+				}
+			}
+		})
+		cursor, _ := strconv.Atoi(args[1])
+		page := redisproto.Value{Kind: redisproto.KindArray}
+		next := "0"
+		if cursor < len(keys) {
+			page.Array = append(page.Array, bulk(keys[cursor]))
+			if cursor+1 < len(keys) {
+				next = strconv.Itoa(cursor + 1)
+			}
+		}
+		return redisproto.Value{Kind: redisproto.KindArray, Array: []redisproto.Value{bulk(next), page}}
+	case "DUMP":
+		v, ok := s.vals[args[1]]
+		if !ok {
+			return redisproto.Value{Kind: redisproto.KindNull}
+		}
+		return bulk(v)
+	case "PTTL":
+		if _, ok := s.vals[args[1]]; !ok {
+			return redisproto.Value{Kind: redisproto.KindInteger, Int: -2}
+		}
+		if ttl, ok := s.ttls[args[1]]; ok {
+			return redisproto.Value{Kind: redisproto.KindInteger, Int: ttl}
+		}
+		return redisproto.Value{Kind: redisproto.KindInteger, Int: -1}
+	case "RESTORE":
+		if _, ok := s.vals[args[1]]; ok && !slices.Contains(args[4:], "REPLACE") {
+			return redisproto.Value{Kind: redisproto.KindError, Str: "BUSYKEY Target key name already exists."}
+		}
+		s.vals[args[1]] = args[3]
+		if ttl, _ := strconv.ParseInt(args[2], 10, 64); ttl > 0 {
+			s.ttls[args[1]] = ttl
+		}
+		return redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"}
+	}
+	return redisproto.Value{Kind: redisproto.KindError, Str: "ERR unknown command"}
+}
+
+func matched(pattern, key string) bool {
+	ok, _ := path.Match(pattern, key)
+	return ok
+}
+
+func TestCopyToPreservesTTL(t *testing.T) {
+	src := newServeStore(map[string]string{"a": "1", "b": "2", "c": "3", "other": "4"})
+	src.ttls["b"] = 5000
+	dst := newServeStore(map[string]string{"c": "old"})
+	from, to := NewClient("src"), NewClient("dst")
+	from.Dial, to.Dial = src.dial, dst.dial
+
+	res, err := from.CopyTo(to, CopyOptions{Match: "?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (CopyResult{Scanned: 3, Copied: 2, Skipped: 1}) {
+		t.Fatalf("result %+v", res)
+	}
+	want := map[string]string{"a": "1", "b": "2", "c": "old"}
+	if len(dst.vals) != len(want) || dst.vals["a"] != "1" || dst.vals["b"] != "2" || dst.vals["c"] != "old" {
+		t.Fatalf("destination holds %v, want %v", dst.vals, want)
+	}
+	if dst.ttls["b"] != 5000 || len(dst.ttls) != 1 {
+		t.Fatalf("destination TTLs %v, want b with 5000 ms", dst.ttls)
+	}
+
+	res, err = from.CopyTo(to, CopyOptions{Replace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Copied != 4 || dst.vals["c"] != "3" || dst.vals["other"] != "4" {
+		t.Fatalf("replace: result %+v, destination %v", res, dst.vals)
+	}
+}
+
+func TestRunCopyReportsFailure(t *testing.T) {
+	src := newServeStore(map[string]string{"a": "1"})
+	from := NewClient("src")
+	from.Dial = src.dial
+	var out, errOut bytes.Buffer
+
+	if code := from.RunCopy("dst", CopyOptions{}, &out, &errOut); code != 0 {
+		t.Fatalf("exit code %d, stderr %q", code, errOut.String())
+	}
+	if got := out.String(); got != "copied 0 keys to dst (1 scanned, 1 skipped)\n" {
+		t.Fatalf("output %q", got)
+	}
+
+	from.Dial = (&serveCounter{}).dial
+	out.Reset()
+	if code := from.RunCopy("dst", CopyOptions{}, &out, &errOut); code != 1 {
+		t.Fatalf("exit code %d, want failure", code)
+	}
+	if !strings.Contains(errOut.String(), "SCAN failed: ERR unknown command") {
+		t.Fatalf("stderr %q", errOut.String())
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// BuildEvalArgs builds EVAL command tokens for script using redis-cli
+// --eval argument syntax: tokens before a standalone "," are KEYS, tokens
+// after it are ARGV. Without a separator every token is a key.
+func BuildEvalArgs(script string, args []string) []string {
+	keys, argv := args, []string(nil)
+	for i, arg := range args {
+		if arg == "," {
+			keys, argv = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	cmd := make([]string, 0, 3+len(keys)+len(argv))
+	cmd = append(cmd, "EVAL", script, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	return append(cmd, argv...)
+}
+
+// RunEval reads the script at path and evaluates it with args split into
+// KEYS and ARGV as described in [BuildEvalArgs].
+func (c *Client) RunEval(path string, args []string, out, errOut io.Writer) int {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(errOut, "redis-cli error: read script: %v\n", err)
+		return 1
+	}
+	return c.Run(BuildEvalArgs(string(script), args), nil, out, errOut)
+}
@@ -0,0 +1,35 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedisCLIBuildEvalArgs(t *testing.T) {
+	const script = "return redis.call('GET', KEYS[1])"
+
+	cases := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no args", nil, []string{"EVAL", script, "0"}},
+		{"keys only", []string{"k1", "k2"}, []string{"EVAL", script, "2", "k1", "k2"}},
+		{"keys and argv", []string{"k1", ",", "a1", "a2"}, []string{"EVAL", script, "1", "k1", "a1", "a2"}},
+		{"argv only", []string{",", "a1"}, []string{"EVAL", script, "0", "a1"}},
+		{"comma inside token", []string{"k1,", "a1"}, []string{"EVAL", script, "2", "k1,", "a1"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := BuildEvalArgs(script, tc.args)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("BuildEvalArgs() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
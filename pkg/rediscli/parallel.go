@@ -0,0 +1,173 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// maxReplyGroups caps how many distinct replies a parallel run lists.
+const maxReplyGroups = 5
+
+// ParallelOptions configures [Client.Parallel].
+type ParallelOptions struct {
+	// Clients is the number of logical clients sending the command
+	// concurrently.
+	Clients int
+	// Repeat is how many times each client sends the command.
+	Repeat int
+	// PoolSize caps the connections the clients share. Zero gives each
+	// client its own.
+	PoolSize int
+}
+
+// ParallelResult aggregates the replies and latencies of a parallel run.
+type ParallelResult struct {
+	Clients  int
+	Requests int
+	// Connections is the number of connections opened.
+	Connections int
+	Duration    time.Duration
+	// Replies counts the requests by rendered reply.
+	Replies map[string]int
+	// ErrorReplies counts error replies; Failures counts requests that got
+	// no reply because of a connection or protocol error.
+	ErrorReplies int
+	Failures     int
+	// FirstFailure is the error of the first failed request.
+	FirstFailure error
+	// Latencies holds the latency of every reply, sorted.
+	Latencies []time.Duration
+}
+
+// Percentile returns the p-th percentile latency, or zero without
+// replies.
+func (r ParallelResult) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	idx := int(float64(len(r.Latencies)-1) * p / 100)
+	return r.Latencies[idx]
+}
+
+// Parallel runs args from opts.Clients concurrent clients over a shared
+// connection pool, each sending it opts.Repeat times in turn.
+func (c *Client) Parallel(opts ParallelOptions, args []string) ParallelResult {
+	clients, repeat := max(opts.Clients, 1), max(opts.Repeat, 1)
+	size := opts.PoolSize
+	if size <= 0 {
+		size = clients
+	}
+	pool := NewPool(c, size)
+	defer pool.Close()
+
+	type clientOut struct {
+		replies   map[string]int
+		errors    int
+		failures  int
+		failure   error
+		latencies []time.Duration
+	}
+	outs := make([]clientOut, clients)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range outs {
+		wg.Add(1)
+		go func(out *clientOut) {
+			defer wg.Done()
+			out.replies = make(map[string]int)
+			for range repeat {
+				t0 := time.Now()
+				v, err := pool.Do(args)
+				if err != nil {
+					out.failures++
+					if out.failure == nil {
+						out.failure = err
+					}
+					continue
+				}
+				out.latencies = append(out.latencies, time.Since(t0))
+				if v.Kind == redisproto.KindError {
+					out.errors++
+				}
+				out.replies[FormatValue(v)]++
+			}
+		}(&outs[i])
+	}
+	wg.Wait()
+
+	res := ParallelResult{
+		Clients:     clients,
+		Requests:    clients * repeat,
+		Connections: pool.Dialed(),
+		Duration:    time.Since(start),
+		Replies:     make(map[string]int),
+	}
+	for _, out := range outs {
+		for reply, n := range out.replies {
+			res.Replies[reply] += n
+		}
+		res.ErrorReplies += out.errors
+		res.Failures += out.failures
+		if res.FirstFailure == nil {
+			res.FirstFailure = out.failure
+		}
+		res.Latencies = append(res.Latencies, out.latencies...)
+	}
+	slices.Sort(res.Latencies)
+	return res
+}
+
+// RunParallel runs args as described in [Client.Parallel] and prints a
+// summary. It fails if any request got no reply.
+func (c *Client) RunParallel(opts ParallelOptions, args []string, out, errOut io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintf(errOut, "redis-cli error: %v\n", ErrEmptyCommand)
+		return 1
+	}
+	res := c.Parallel(opts, args)
+	_, _ = fmt.Fprint(out, FormatParallelResult(res))
+	if res.Failures > 0 {
+		_, _ = fmt.Fprintf(errOut, "redis-cli error: %d requests failed, first: %v\n", res.Failures, res.FirstFailure)
+		return 1
+	}
+	return 0
+}
+
+// FormatParallelResult renders the summary of a parallel run: throughput,
+// latency percentiles, and the most frequent replies.
+func FormatParallelResult(r ParallelResult) string {
+	rate := 0.0
+	if secs := r.Duration.Seconds(); secs > 0 {
+		rate = float64(r.Requests-r.Failures) / secs
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	s := fmt.Sprintf("%d requests from %d clients over %d connections in %.3f s (%.1f requests/s)\n",
+		r.Requests, r.Clients, r.Connections, r.Duration.Seconds(), rate)
+	s += fmt.Sprintf("latency ms: p50 %.3f, p95 %.3f, p99 %.3f, max %.3f\n",
+		ms(r.Percentile(50)), ms(r.Percentile(95)), ms(r.Percentile(99)), ms(r.Percentile(100)))
+	s += fmt.Sprintf("error replies: %d, failed requests: %d\n", r.ErrorReplies, r.Failures)
+
+	replies := slices.SortedFunc(maps.Keys(r.Replies), func(a, b string) int {
+		return cmp.Or(cmp.Compare(r.Replies[b], r.Replies[a]), cmp.Compare(a, b))
+	})
+	for i, reply := range replies {
+		if i == maxReplyGroups {
+			s += fmt.Sprintf("%8s  (%d more distinct replies)\n", "", len(replies)-i)
+			break
+		}
+		s += fmt.Sprintf("%8d  %s\n", r.Replies[reply], reply)
+	}
+	return s
+}
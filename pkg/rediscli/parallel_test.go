@@ -0,0 +1,125 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// serveCounter answers every INCR on its connections with the next value
+// of a shared counter, and any other command with an error.
+type serveCounter struct {
+	n      atomic.Int64
+	dialed atomic.Int64
+}
+
+func (s *serveCounter) dial(network, addr string) (net.Conn, error) {
+	s.dialed.Add(1)
+	server, cli := net.Pipe()
+	go func() {
+		defer server.Close()
+		parser := redisproto.NewParser()
+		buf := make([]byte, 256)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			frames, err := parser.Feed(buf[:n])
+			if err != nil {
+				return
+			}
+			for _, f := range frames {
+				reply := redisproto.Value{Kind: redisproto.KindError, Str: "ERR unknown command"}
+				if strings.EqualFold(string(f.Array[0].Bulk), "INCR") {
+					reply = redisproto.Value{Kind: redisproto.KindInteger, Int: s.n.Add(1)}
+				}
+				wire, _ := redisproto.Encode(reply)
+				if _, err := server.Write(wire); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return cli, nil
+}
+
+func TestParallelSharesPooledConnections(t *testing.T) {
+	srv := &serveCounter{}
+	client := NewClient("fake")
+	client.Dial = srv.dial
+
+	res := client.Parallel(ParallelOptions{Clients: 8, Repeat: 25, PoolSize: 3}, []string{"INCR", "k"})
+	if res.Requests != 200 || res.Failures != 0 || len(res.Latencies) != 200 {
+		t.Fatalf("result: %d requests, %d failures, %d latencies", res.Requests, res.Failures, len(res.Latencies))
+	}
+	if got := srv.n.Load(); got != 200 {
+		t.Fatalf("server saw %d INCRs, want 200", got)
+	}
+	if got := srv.dialed.Load(); got > 3 || int(got) != res.Connections {
+		t.Fatalf("dialed %d connections, result says %d, pool of 3", got, res.Connections)
+	}
+	if len(res.Replies) != 200 {
+		t.Fatalf("%d distinct replies, want every INCR to be distinct", len(res.Replies))
+	}
+	if res.Percentile(50) > res.Percentile(99) || res.Percentile(100) != res.Latencies[199] {
+		t.Fatalf("percentiles out of order: %v", res.Latencies)
+	}
+}
+
+func TestRunParallelSummary(t *testing.T) {
+	srv := &serveCounter{}
+	client := NewClient("fake")
+	client.Dial = srv.dial
+
+	var out, errOut bytes.Buffer
+	code := client.RunParallel(ParallelOptions{Clients: 4, Repeat: 5}, []string{"GET", "k"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code %d, stderr %q", code, errOut.String())
+	}
+	for _, want := range []string{
+		"20 requests from 4 clients over ",
+		"error replies: 20, failed requests: 0",
+		"      20  (error) ERR unknown command",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("summary lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunParallelReportsFailures(t *testing.T) {
+	client := NewClient("fake")
+	client.Dial = func(network, addr string) (net.Conn, error) {
+		return nil, errors.New("dial failed")
+	}
+
+	var out, errOut bytes.Buffer
+	if code := client.RunParallel(ParallelOptions{Clients: 2, Repeat: 3}, []string{"PING"}, &out, &errOut); code != 1 {
+		t.Fatalf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(errOut.String(), "6 requests failed, first: connect fake failed: dial failed") {
+		t.Fatalf("stderr %q", errOut.String())
+	}
+}
+
+func TestFormatParallelResultCapsReplies(t *testing.T) {
+	r := ParallelResult{Clients: 1, Requests: 7, Replies: map[string]int{}}
+	for _, reply := range []string{"a", "b", "c", "d", "e", "f", "f"} {
+		r.Replies[reply]++
+	}
+	got := FormatParallelResult(r)
+	if !strings.Contains(got, "       2  f\n       1  a\n") || !strings.Contains(got, "(1 more distinct replies)") {
+		t.Fatalf("summary:\n%s", got)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// ErrPoolClosed is returned by [Pool.Do] after [Pool.Close].
+var ErrPoolClosed = errors.New("connection pool closed")
+
+// Conn is a persistent connection that sends one command at a time.
+type Conn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	codec   *redisproto.Codec
+	timeout time.Duration
+}
+
+// Connect opens a connection to c.Addr, switching it to RESP3 if
+// c.Protocol is 3.
+func (c *Client) Connect() (*Conn, error) {
+	nc, err := c.Dial("tcp", c.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s failed: %w", c.Addr, err)
+	}
+	cn := &Conn{conn: nc, br: bufio.NewReader(nc), codec: redisproto.NewCodec(), timeout: c.Timeout}
+	if c.Protocol == 3 {
+		hello, err := cn.Do([]string{"HELLO", "3"})
+		if err == nil && hello.Kind == redisproto.KindError {
+			err = fmt.Errorf("HELLO 3 failed: %s", hello.Str)
+		}
+		if err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+		cn.codec.SwitchToRESP3()
+	}
+	return cn, nil
+}
+
+// Do sends a command and waits for its reply.
+func (cn *Conn) Do(args []string) (redisproto.Value, error) {
+	if len(args) == 0 {
+		return redisproto.Value{}, ErrEmptyCommand
+	}
+	if cn.timeout > 0 {
+		_ = cn.conn.SetDeadline(time.Now().Add(cn.timeout))
+	}
+	return roundTrip(cn.conn, cn.br, cn.codec, args)
+}
+
+// Close closes the connection.
+func (cn *Conn) Close() error {
+	return cn.conn.Close()
+}
+
+// Pool shares up to a fixed number of connections between goroutines.
+// Connections are opened on demand; a goroutine that finds them all busy
+// waits for one to be released.
+type Pool struct {
+	client *Client
+	// slots holds one token per connection that may be open.
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*Conn
+	closed bool
+	dialed int
+}
+
+// NewPool returns a pool of at most size connections of c.
+func NewPool(c *Client, size int) *Pool {
+	p := &Pool{client: c, slots: make(chan struct{}, max(size, 1))}
+	for range cap(p.slots) {
+		p.slots <- struct{}{}
+	}
+	return p
+}
+
+// Do runs a command on a pooled connection. A connection that fails is
+// closed rather than returned to the pool.
+func (p *Pool) Do(args []string) (redisproto.Value, error) {
+	cn, err := p.get()
+	if err != nil {
+		return redisproto.Value{}, err
+	}
+	v, err := cn.Do(args)
+	p.put(cn, err == nil)
+	return v, err
+}
+
+// Dialed returns the number of connections the pool has opened.
+func (p *Pool) Dialed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dialed
+}
+
+// Close closes the idle connections and makes later calls fail.
+// Connections in use are closed when they are released.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var errs []error
+	for _, cn := range p.idle {
+		errs = append(errs, cn.Close())
+	}
+	p.idle = nil
+	return errors.Join(errs...)
+}
+
+func (p *Pool) get() (*Conn, error) {
+	<-p.slots
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.slots <- struct{}{}
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		cn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return cn, nil
+	}
+	p.dialed++
+	p.mu.Unlock()
+
+	cn, err := p.client.Connect()
+	if err != nil {
+		p.slots <- struct{}{}
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (p *Pool) put(cn *Conn, healthy bool) {
+	p.mu.Lock()
+	if healthy && !p.closed {
+		p.idle = append(p.idle, cn)
+		cn = nil
+	}
+	p.mu.Unlock()
+	if cn != nil {
+		_ = cn.Close()
+	}
+	p.slots <- struct{}{}
+}
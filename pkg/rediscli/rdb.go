@@ -0,0 +1,182 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadRDB connects to c.Addr as a replica, asks for a full
+// resynchronization with SYNC and writes the snapshot the server sends to
+// w, like redis-cli --rdb. It returns the size of the snapshot.
+//
+// The snapshot is written as received: an RDB file from Redis, the dataset
+// as commands from redismvp. Both the length-prefixed payload and the
+// EOF-marked one of diskless replication are accepted; whatever the server
+// streams after the snapshot is not read.
+func (c *Client) DownloadRDB(w io.Writer) (int64, error) {
+	cn, err := c.Connect()
+	if err != nil {
+		return 0, err
+	}
+	defer cn.Close()
+	// Ask not to be kept as a replica once the snapshot is sent. Servers
+	// that do not know the option reply with an error, which is harmless.
+	if _, err := cn.Do([]string{"REPLCONF", "rdb-only", "1"}); err != nil {
+		return 0, err
+	}
+	wire, err := cn.codec.AppendEncode(nil, BuildCommand([]string{"SYNC"}))
+	if err != nil {
+		return 0, fmt.Errorf("encode command failed: %w", err)
+	}
+	if _, err := cn.conn.Write(wire); err != nil {
+		return 0, fmt.Errorf("write command failed: %w", err)
+	}
+	return readSnapshot(&timeoutReader{cn: cn}, w)
+}
+
+// timeoutReader reads from a connection, giving each read the
+// connection's timeout rather than the whole transfer.
+type timeoutReader struct {
+	cn *Conn
+}
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	if r.cn.timeout > 0 {
+		_ = r.cn.conn.SetDeadline(time.Now().Add(r.cn.timeout))
+	}
+	return r.cn.br.Read(p)
+}
+
+// readSnapshot copies the snapshot that answers SYNC from r to w.
+func readSnapshot(r io.Reader, w io.Writer) (int64, error) {
+	line, err := readSnapshotHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case strings.HasPrefix(line, "-"):
+		return 0, fmt.Errorf("SYNC failed: %s", line[1:])
+	case strings.HasPrefix(line, "$EOF:"):
+		return copyUntilMark(r, w, []byte(line[len("$EOF:"):]))
+	case strings.HasPrefix(line, "$"):
+		size, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("protocol error: bad snapshot header %q", line)
+		}
+		n, err := io.CopyN(w, r, size)
+		if errors.Is(err, io.EOF) {
+			err = errors.New("protocol error: connection closed before the end of the snapshot")
+		}
+		return n, err
+	}
+	return 0, fmt.Errorf("protocol error: unexpected reply to SYNC %q", line)
+}
+
+// readSnapshotHeader reads the line announcing the snapshot, skipping the
+// empty lines Redis sends to keep the connection alive while it prepares
+// it. It reads a byte at a time so as not to consume the payload.
+func readSnapshotHeader(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", errors.New("protocol error: connection closed before the snapshot")
+			}
+			return "", fmt.Errorf("read response failed: %w", err)
+		}
+		if b[0] != '\n' {
+			line = append(line, b[0])
+			continue
+		}
+		if text := strings.TrimSuffix(string(line), "\r"); text != "" {
+			return text, nil
+		}
+		line = line[:0]
+	}
+}
+
+// copyUntilMark copies r to w up to mark, which ends a diskless snapshot.
+// The last len(mark) bytes are held back until more arrive, since they
+// may be the start of the mark.
+func copyUntilMark(r io.Reader, w io.Writer, mark []byte) (int64, error) {
+	var written int64
+	buf := make([]byte, 0, 2*snapshotReadSize)
+	chunk := make([]byte, snapshotReadSize)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if i := bytes.Index(buf, mark); i >= 0 {
+			m, werr := w.Write(buf[:i])
+			return written + int64(m), werr
+		}
+		if keep := len(buf) - len(mark); keep > 0 {
+			m, werr := w.Write(buf[:keep])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			buf = append(buf[:0], buf[keep:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return written, errors.New("protocol error: connection closed before the end of the snapshot")
+		}
+		if err != nil {
+			return written, fmt.Errorf("read response failed: %w", err)
+		}
+	}
+}
+
+const snapshotReadSize = 64 << 10
+
+// RunRDB downloads a snapshot as described in [Client.DownloadRDB] into
+// path, or to out when path is "-". The file is written next to path and
+// renamed into place once complete, so a failed transfer leaves any
+// earlier file as it was.
+func (c *Client) RunRDB(path string, out, errOut io.Writer) int {
+	n, err := c.downloadRDBFile(path, out)
+	if err != nil {
+		_, _ = fmt.Fprintf(errOut, "redis-cli error: %v\n", err)
+		return 1
+	}
+	if path != "-" {
+		_, _ = fmt.Fprintf(out, "Transfer finished with success after %d bytes\n", n)
+	}
+	return 0
+}
+
+func (c *Client) downloadRDBFile(path string, out io.Writer) (int64, error) {
+	if path == "-" {
+		return c.DownloadRDB(out)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "temp-rdb-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.DownloadRDB(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return n, err
+}
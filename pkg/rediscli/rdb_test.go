@@ -0,0 +1,103 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package rediscli
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// syncServer is a fake master that rejects REPLCONF and answers SYNC with
+// reply, then sends a replication stream the download must not read.
+func syncServer(reply string) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		server, cli := net.Pipe()
+		go func() {
+			defer server.Close()
+			parser := redisproto.NewParser()
+			buf := make([]byte, 256)
+			for {
+				n, err := server.Read(buf)
+				if err != nil {
+					return
+				}
+				frames, err := parser.Feed(buf[:n])
+				if err != nil {
+					return
+				}
+				for _, f := range frames {
+					wire := "-ERR Unrecognized REPLCONF option: rdb-only\r\n"
+					if strings.EqualFold(string(f.Array[0].Bulk), "SYNC") {
+						wire = reply + "*1\r\n$4\r\nPING\r\n"
+					}
+					if _, err := server.Write([]byte(wire)); err != nil {
+						return
+					}
+				}
+			}
+		}()
+		return cli, nil
+	}
+}
+
+func TestDownloadRDB(t *testing.T) {
+	payload := "REDIS0011\xfa\x00\r\n$3\r\nend\xff"
+	mark := strings.Repeat("m", 40)
+	cases := map[string]string{
+		"length":   "\n\n$21\r\n" + payload,
+		"eof mark": "\n$EOF:" + mark + "\r\n" + payload + mark,
+	}
+	for name, reply := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewClient("master")
+			c.Dial = syncServer(reply)
+			var got bytes.Buffer
+			n, err := c.DownloadRDB(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != payload || n != int64(len(payload)) {
+				t.Fatalf("downloaded %d bytes %q, want %q", n, got.String(), payload)
+			}
+		})
+	}
+}
+
+func TestRunRDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	c := NewClient("master")
+	c.Dial = syncServer("$5\r\nhello")
+	var out, errOut bytes.Buffer
+	if code := c.RunRDB(path, &out, &errOut); code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut.String())
+	}
+	if got, _ := os.ReadFile(path); string(got) != "hello" {
+		t.Fatalf("file holds %q", got)
+	}
+	if !strings.Contains(out.String(), "after 5 bytes") {
+		t.Fatalf("output %q", out.String())
+	}
+
+	c.Timeout = 100 * time.Millisecond
+	c.Dial = syncServer("$100\r\ntruncated")
+	out.Reset()
+	if code := c.RunRDB(path, &out, &errOut); code != 1 {
+		t.Fatalf("exit code %d for a truncated snapshot", code)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "hello" {
+		t.Fatalf("failed download replaced the file with %q", got)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "temp-*")); len(matches) != 0 {
+		t.Fatalf("temporary files left: %v", matches)
+	}
+}
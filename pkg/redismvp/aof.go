@@ -0,0 +1,428 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// Append only file persistence. Every write is appended to the file as it
+// is propagated to replicas, and the file is replayed at startup.
+// BGREWRITEAOF replaces the file in the background with a compact one: a
+// snapshot of the dataset, followed by the writes made while the snapshot
+// was being written. The snapshot is an RDB file (the preamble), which
+// loads much faster than commands, unless Config.AOFNoRDBPreamble asks for
+// the commands that rebuild the dataset instead.
+//
+// Durability is tracked by replication offset: fsyncedOffset is the offset
+// through which every write is on disk, here or, as a replica reports with
+// REPLCONF ACK FACK, on a replica. WAITAOF blocks a client until enough of
+// them cover its last write.
+
+type aofState struct {
+	path     string
+	fsync    AppendFsync
+	preamble bool
+	file     *os.File
+	// buf holds writes not yet written to file, after a failed write.
+	buf []byte
+	// unsynced is set while file has writes that were not fsynced.
+	unsynced  bool
+	lastFsync time.Time
+	// fsyncedOffset is the replication offset through which writes are
+	// fsynced.
+	fsyncedOffset int64
+	// syncRequested asks the next flush to fsync regardless of the policy,
+	// for a client waiting in WAITAOF.
+	syncRequested bool
+	// stale is set while file does not describe the dataset, after a full
+	// resync with a master replaced it. Writes then only go to the rewrite
+	// that fixes the file.
+	stale   bool
+	rewrite *aofRewrite
+	// rewriteScheduled is set when BGREWRITEAOF waits for a BGSAVE.
+	rewriteScheduled bool
+	// loaded describes the load at startup.
+	loaded aofLoadStats
+}
+
+// aofRewrite is a BGREWRITEAOF in progress. A background snapshot writes
+// the dataset to tmp, and buf collects the writes made meanwhile, which go
+// to the end of the new file.
+type aofRewrite struct {
+	tmp   *os.File
+	start time.Time
+	buf   []byte
+}
+
+// aofLoadStats describes what loading the append only file replayed.
+type aofLoadStats struct {
+	preambleKeys int
+	commands     int
+	// truncated is the size of an incomplete command dropped from the end.
+	truncated int
+}
+
+func init() {
+	registerCommands(
+		&command{name: "bgrewriteaof", arity: 1, group: "server",
+			summary: "Asynchronously rewrites the append-only file to disk.", handler: cmdBGRewriteAOF},
+		&command{name: "waitaof", arity: 4, flags: []string{flagBlocking}, group: "generic",
+			summary: "Blocks until all of the preceding write commands sent by the connection are written to the append-only file of the master and/or replicas.",
+			handler: cmdWaitAOF},
+	)
+}
+
+func cmdBGRewriteAOF(c *clientConn, dst []byte, _ [][]byte) []byte {
+	s := c.server
+	if s.aof == nil {
+		return appendError(dst, "ERR Append only file is disabled")
+	}
+	if s.aof.rewrite != nil {
+		return appendError(dst, "ERR Background append only file rewriting already in progress")
+	}
+	if s.store.snap != nil {
+		s.aof.rewriteScheduled = true
+		return appendSimple(dst, "Background append only file rewriting scheduled")
+	}
+	if err := s.startAOFRewrite(); err != nil {
+		return appendError(dst, "ERR Background append only file rewriting failed: "+err.Error())
+	}
+	return appendSimple(dst, "Background append only file rewriting started")
+}
+
+// cmdWaitAOF blocks until the client's writes are fsynced locally and on
+// numreplicas replicas, or the timeout in milliseconds passes, and replies
+// with how many local and replica AOFs have fsynced them.
+func cmdWaitAOF(c *clientConn, dst []byte, args [][]byte) []byte {
+	s := c.server
+	if s.repl.link != nil {
+		return appendError(dst, "ERR WAITAOF cannot be used with replica instances. "+
+			"Please also note that writes to replicas are just local and are not propagated.")
+	}
+	numLocal, ok := parseInt(args[0])
+	if !ok || numLocal < 0 {
+		return appendError(dst, "ERR value is out of range, must be positive")
+	}
+	numReplicas, ok := parseInt(args[1])
+	if !ok || numReplicas < 0 {
+		return appendError(dst, "ERR value is out of range, must be positive")
+	}
+	timeout, ok := parseInt(args[2])
+	if !ok {
+		return appendError(dst, "ERR timeout is not an integer or out of range")
+	}
+	if timeout < 0 {
+		return appendError(dst, "ERR timeout is negative")
+	}
+	if numLocal > 0 && s.aof == nil {
+		return appendError(dst, "ERR WAITAOF cannot be used when numlocal is set but appendonly is disabled.")
+	}
+
+	woff := c.woff
+	serve := func(dst []byte) ([]byte, bool) {
+		local, replicas := s.aofAcks(woff)
+		if local < numLocal || replicas < numReplicas {
+			return dst, false
+		}
+		return appendWaitAOFReply(dst, local, replicas), true
+	}
+	if out, ok := serve(dst); ok {
+		return out
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	}
+	c.block(nil, deadline, serve, nil)
+	c.blocked.waitAOF = true
+	c.blocked.timeout = func(dst []byte) []byte {
+		local, replicas := s.aofAcks(woff)
+		return appendWaitAOFReply(dst, local, replicas)
+	}
+	if numLocal > 0 {
+		s.aof.syncRequested = true
+	}
+	if numReplicas > 0 {
+		// Ask for acknowledgements right away instead of waiting for
+		// periodic ACKs.
+		s.feedReplication(appendBulkArray(nil, [][]byte{[]byte("REPLCONF"), []byte("GETACK"), []byte("*")}))
+	}
+	return dst
+}
+
+func appendWaitAOFReply(dst []byte, local, replicas int64) []byte {
+	dst = appendArrayLen(dst, 2)
+	dst = appendInteger(dst, local)
+	return appendInteger(dst, replicas)
+}
+
+// aofAcks returns whether our AOF has fsynced the stream through offset,
+// as 0 or 1, and how many replicas report that theirs has.
+func (s *Server) aofAcks(offset int64) (local, replicas int64) {
+	if s.aof != nil && s.aof.fsyncedOffset >= offset {
+		local = 1
+	}
+	for c := range s.repl.replicas {
+		if c.replica.online && c.replica.aofAckOffset >= offset {
+			replicas++
+		}
+	}
+	return local, replicas
+}
+
+// pollWaitAOF replies to WAITAOF clients whose writes are now fsynced
+// where they asked.
+func (s *Server) pollWaitAOF() {
+	for _, c := range sortedConns(s.blockedClients) {
+		if !c.blocked.waitAOF {
+			continue
+		}
+		if reply, ok := c.blocked.serve(nil); ok {
+			c.unblock()
+			c.resume(reply)
+		}
+	}
+}
+
+// openAOF loads the append only file at cfg's path, if it exists, and
+// opens it for appending.
+func (s *Server) openAOF(cfg Config) error {
+	a := &aofState{
+		path:     cfg.appendFilename(),
+		fsync:    cfg.AppendFsync,
+		preamble: !cfg.AOFNoRDBPreamble,
+	}
+	loaded, err := s.loadAOF(a.path)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open append only file: %w", err)
+	}
+	// The file may just have been created.
+	s.syncDir(filepath.Dir(a.path))
+	a.file = f
+	a.lastFsync = time.Now()
+	a.loaded = loaded
+	// Offsets only advance with a backlog, and WAITAOF needs them even
+	// without replicas.
+	if s.repl.link == nil && s.repl.backlog == nil {
+		s.repl.createBacklog()
+	}
+	a.fsyncedOffset = s.repl.offset
+	s.aof = a
+	return nil
+}
+
+// loadAOF replays the append only file at path into the store. A command
+// cut short at the end, as left by a crash during a write, is dropped and
+// truncated from the file; anything else malformed is an error.
+func (s *Server) loadAOF(path string) (aofLoadStats, error) {
+	var stats aofLoadStats
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("read append only file: %w", err)
+	}
+	start := time.Now()
+
+	rest := data
+	if bytes.HasPrefix(data, []byte("REDIS")) {
+		n, err := loadRDB(data, s.store.kv)
+		if err != nil {
+			return stats, fmt.Errorf("load append only file preamble: %w", err)
+		}
+		stats.preambleKeys = len(s.store.kv)
+		rest = data[n:]
+	}
+	parser := redisproto.NewParser()
+	frames, err := parser.Feed(rest)
+	if err != nil {
+		return stats, fmt.Errorf("bad append only file format: %w", err)
+	}
+	c := &clientConn{server: s, log: s.log.With("client", "aof"), replayed: true}
+	for _, frame := range frames {
+		c.replay(frame)
+	}
+	s.readyKeys = s.readyKeys[:0]
+	stats.commands = len(frames)
+
+	if stats.truncated = parser.Buffered(); stats.truncated > 0 {
+		valid := int64(len(data) - stats.truncated)
+		s.log.Warn("append only file ends with an incomplete command, truncating it",
+			"valid_bytes", valid, "dropped_bytes", stats.truncated)
+		if err := os.Truncate(path, valid); err != nil {
+			return stats, fmt.Errorf("truncate append only file: %w", err)
+		}
+	}
+	s.log.Info("DB loaded from append only file", "preamble_keys", stats.preambleKeys,
+		"commands", stats.commands, "keys", len(s.store.kv), "duration", time.Since(start))
+	return stats, nil
+}
+
+// feedAOF appends raw, an encoded write, to the append only file. It is
+// written right away, so a write acknowledged to a client survives a crash
+// of the process; fsync follows the configured policy.
+func (s *Server) feedAOF(raw []byte) {
+	a := s.aof
+	if a == nil {
+		return
+	}
+	if a.rewrite != nil {
+		a.rewrite.buf = append(a.rewrite.buf, raw...)
+	}
+	if a.stale {
+		return
+	}
+	a.buf = append(a.buf, raw...)
+	s.flushAOF(time.Now())
+}
+
+// flushAOF writes buffered writes and fsyncs the file when the policy
+// asks for it. A failed write is kept and retried by the next flush. Once
+// nothing is left to write or fsync, every write through the current
+// offset is on disk.
+func (s *Server) flushAOF(now time.Time) {
+	a := s.aof
+	if len(a.buf) > 0 {
+		n, err := a.file.Write(a.buf)
+		a.buf = a.buf[:copy(a.buf, a.buf[n:])]
+		if n > 0 {
+			a.unsynced = true
+		}
+		if err != nil {
+			s.log.Warn("writing the append only file failed", "err", err, "pending_bytes", len(a.buf))
+			return
+		}
+	}
+	due := a.syncRequested || a.fsync == AppendFsyncAlways ||
+		a.fsync == AppendFsyncEverySec && now.Sub(a.lastFsync) >= time.Second
+	if a.unsynced && due {
+		if err := a.file.Sync(); err != nil {
+			s.log.Warn("fsync of the append only file failed", "err", err)
+			return
+		}
+		a.unsynced = false
+		a.lastFsync = now
+	}
+	a.syncRequested = false
+	if !a.unsynced && !a.stale {
+		a.fsyncedOffset = s.repl.offset
+	}
+}
+
+// pollAOF fsyncs once per second under the everysec policy and starts a
+// rewrite that waited for a BGSAVE.
+func (s *Server) pollAOF(now time.Time) {
+	a := s.aof
+	if a == nil {
+		return
+	}
+	s.flushAOF(now)
+	if a.rewriteScheduled && s.store.snap == nil {
+		s.store.mu.Lock()
+		err := s.startAOFRewrite()
+		s.store.mu.Unlock()
+		if err != nil {
+			s.log.Warn("scheduled append only file rewriting failed", "err", err)
+		}
+	}
+}
+
+// startAOFRewrite starts writing the dataset to a new file in the
+// background. The caller holds the store lock, and no snapshot may be
+// running.
+func (s *Server) startAOFRewrite() error {
+	a := s.aof
+	a.rewriteScheduled = false
+	tmp, err := createTemp(a.path, "temp-rewriteaof-*.aof")
+	if err != nil {
+		return err
+	}
+	a.rewrite = &aofRewrite{tmp: tmp, start: time.Now()}
+	if a.preamble {
+		s.startSnapshot(tmp, snapshotRDB, s.finishAOFRewrite,
+			"aof-base", "1",
+			"ctime", strconv.FormatInt(time.Now().Unix(), 10))
+	} else {
+		s.startSnapshot(tmp, snapshotCommands, s.finishAOFRewrite)
+	}
+	s.log.Info("background append only file rewriting started", "rdb_preamble", a.preamble)
+	return nil
+}
+
+// finishAOFRewrite completes the rewrite after its snapshot was written:
+// the writes made meanwhile are appended, and the new file atomically
+// replaces the old one, which it continues.
+func (s *Server) finishAOFRewrite(err error) {
+	a := s.aof
+	rw := a.rewrite
+	a.rewrite = nil
+	if err == nil {
+		_, err = rw.tmp.Write(rw.buf)
+	}
+	if err == nil {
+		err = rw.tmp.Sync()
+	}
+	if err == nil {
+		if s.diskFaults != nil && !s.injectRename(rw.tmp.Name(), a.path) {
+			return
+		}
+		err = s.replaceFile(rw.tmp.Name(), a.path)
+	}
+	if err != nil {
+		_ = rw.tmp.Close()
+		_ = os.Remove(rw.tmp.Name())
+		s.log.Warn("background append only file rewriting failed", "err", err)
+		return
+	}
+
+	_ = a.file.Close()
+	a.file = rw.tmp
+	a.buf = a.buf[:0]
+	a.unsynced = false
+	a.lastFsync = time.Now()
+	a.stale = false
+	s.log.Info("background append only file rewriting done", "duration", time.Since(rw.start))
+}
+
+// resetAOF is called when a full resync with a master is about to replace
+// the dataset. The file stops being appended to until a rewrite, started
+// once the new dataset is loaded, replaces it.
+func (s *Server) resetAOF() {
+	if a := s.aof; a != nil {
+		a.stale = true
+	}
+}
+
+// closeAOF flushes and closes the file on shutdown. A rewrite in progress
+// must have been completed.
+func (s *Server) closeAOF() {
+	a := s.aof
+	if a == nil {
+		return
+	}
+	if !a.stale {
+		s.flushAOF(time.Now())
+		if err := a.file.Sync(); err != nil {
+			s.log.Warn("fsync of the append only file failed", "err", err)
+		}
+	}
+	_ = a.file.Close()
+}
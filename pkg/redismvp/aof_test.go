@@ -0,0 +1,305 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// aofConfig returns a config persisting to a fresh file, fsyncing every
+// write so a restart sees everything acknowledged.
+func aofConfig(t *testing.T) Config {
+	return Config{
+		AppendOnly:     true,
+		AppendFilename: filepath.Join(t.TempDir(), "appendonly.aof"),
+		AppendFsync:    AppendFsyncAlways,
+	}
+}
+
+// startAOFServer starts a test server on cfg's append only file, loading
+// what earlier servers left in it. Tests abandon servers without closing
+// the file, as a crash would.
+func startAOFServer(t *testing.T, cfg Config) *testClient {
+	t.Helper()
+	tc := newTestClient(t)
+	if err := tc.c.server.openAOF(cfg); err != nil {
+		t.Fatalf("open AOF: %v", err)
+	}
+	t.Cleanup(func() { _ = tc.c.server.aof.file.Close() })
+	return tc
+}
+
+// waitAOFRewrite completes the running rewrite.
+func waitAOFRewrite(t *testing.T, s *Server) {
+	t.Helper()
+	if s.aof.rewrite == nil {
+		t.Fatal("no rewrite in progress")
+	}
+	s.store.mu.Lock()
+	err := s.waitSnapshot()
+	s.store.mu.Unlock()
+	if err != nil || s.aof.rewrite != nil {
+		t.Fatalf("rewrite did not complete: %v", err)
+	}
+}
+
+func wantSameDataset(t *testing.T, got, want *Server) {
+	t.Helper()
+	if g, w := dumpStore(got.store), dumpStore(want.store); !reflect.DeepEqual(g, w) {
+		t.Fatalf("dataset after restart:\n%v\nwant:\n%v", g, w)
+	}
+}
+
+func TestAOFCrashRestart(t *testing.T) {
+	cfg := aofConfig(t)
+	tc := startAOFServer(t, cfg)
+	tc.do("SET", "s", "v")
+	tc.do("INCR", "n")
+	tc.do("INCR", "n")
+	tc.do("RPUSH", "l", "a", "b", "c")
+	tc.do("LPOP", "l")
+	tc.do("SADD", "set", "x", "y", "z")
+	tc.do("SPOP", "set")
+	tc.do("HSET", "h", "f", "1")
+	tc.do("ZADD", "z", "2", "b", "1", "a")
+	tc.do("SET", "gone", "1")
+	tc.do("DEL", "gone")
+	tc.do("GET", "s")
+	tc.do("HSET", "h")
+
+	restarted := startAOFServer(t, cfg)
+	wantSameDataset(t, restarted.c.server, tc.c.server)
+	// Reads and failed commands are not logged.
+	if got := restarted.c.server.aof.loaded.commands; got != 11 {
+		t.Fatalf("replayed %d commands, want 11", got)
+	}
+
+	// The restarted server keeps appending to the same file.
+	restarted.do("SET", "s", "after restart")
+	again := startAOFServer(t, cfg)
+	again.wantBulk("after restart", "GET", "s")
+}
+
+func TestAOFTruncatedTail(t *testing.T) {
+	cfg := aofConfig(t)
+	tc := startAOFServer(t, cfg)
+	tc.do("SET", "k", "v")
+	valid, err := os.Stat(cfg.AppendFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of writing a command.
+	f, err := os.OpenFile(cfg.AppendFilename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\nlo")
+	_ = f.Close()
+
+	restarted := startAOFServer(t, cfg)
+	restarted.wantBulk("v", "GET", "k")
+	if s, _ := os.Stat(cfg.AppendFilename); s.Size() != valid.Size() {
+		t.Fatalf("file is %d bytes after load, want %d", s.Size(), valid.Size())
+	}
+	restarted.do("SET", "k", "w")
+	startAOFServer(t, cfg).wantBulk("w", "GET", "k")
+
+	// Garbage is not mistaken for a truncated command.
+	if err := os.WriteFile(cfg.AppendFilename, []byte("*1\r\n!oops\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestClient(t).c.server.openAOF(cfg); err == nil {
+		t.Fatal("loaded a corrupt append only file")
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	for _, preamble := range []bool{true, false} {
+		t.Run(fmt.Sprintf("preamble=%v", preamble), func(t *testing.T) {
+			cfg := aofConfig(t)
+			cfg.AOFNoRDBPreamble = !preamble
+			tc := startAOFServer(t, cfg)
+			s := tc.c.server
+			for i := range 200 {
+				tc.do("INCR", "counter")
+				tc.do("RPUSH", "list", fmt.Sprint(i))
+				tc.do("ZADD", "z", fmt.Sprint(i), fmt.Sprint("m", i))
+				tc.do("HSET", "h", fmt.Sprint("f", i%10), fmt.Sprint(i))
+			}
+
+			if got := tc.do("BGREWRITEAOF"); got.Str != "Background append only file rewriting started" {
+				t.Fatalf("BGREWRITEAOF: %#v", got)
+			}
+			tc.wantError("ERR Background append only file rewriting already in progress", "BGREWRITEAOF")
+			// Writes made during the rewrite end up in the new file.
+			tc.do("INCR", "counter")
+			tc.do("SADD", "late", "x")
+			waitAOFRewrite(t, s)
+			tc.do("DEL", "list")
+
+			data, err := os.ReadFile(cfg.AppendFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.HasPrefix(data, []byte("REDIS")) != preamble {
+				t.Fatalf("rewritten file starts with %q", data[:min(len(data), 16)])
+			}
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(cfg.AppendFilename), "temp-*")); len(matches) > 0 {
+				t.Fatalf("temporary files left: %v", matches)
+			}
+
+			restarted := startAOFServer(t, cfg)
+			wantSameDataset(t, restarted.c.server, s)
+			loaded := restarted.c.server.aof.loaded
+			if preamble && (loaded.preambleKeys != 4 || loaded.commands != 3) {
+				t.Fatalf("loaded %d keys from the preamble and %d commands", loaded.preambleKeys, loaded.commands)
+			}
+		})
+	}
+}
+
+// TestAOFPreambleLoadTime restarts from the same dataset logged as its
+// write history, as a rewrite without preamble, and as an RDB preamble.
+func TestAOFPreambleLoadTime(t *testing.T) {
+	cfg := aofConfig(t)
+	cfg.AppendFsync = AppendFsyncNo
+	tc := startAOFServer(t, cfg)
+	for i := range 20000 {
+		tc.do("INCR", fmt.Sprint("counter:", i%100))
+		tc.do("HSET", fmt.Sprint("hash:", i%100), fmt.Sprint("f", i%50), fmt.Sprint(i))
+	}
+
+	restart := func(what string) aofLoadStats {
+		start := time.Now()
+		restarted := startAOFServer(t, cfg)
+		t.Logf("load %s: %v", what, time.Since(start))
+		wantSameDataset(t, restarted.c.server, tc.c.server)
+		return restarted.c.server.aof.loaded
+	}
+	if got := restart("history").commands; got != 40000 {
+		t.Fatalf("replayed %d commands, want 40000", got)
+	}
+
+	tc.c.server.aof.preamble = false
+	tc.do("BGREWRITEAOF")
+	waitAOFRewrite(t, tc.c.server)
+	if got := restart("commands").commands; got != 200 {
+		t.Fatalf("replayed %d commands, want one per key", got)
+	}
+
+	tc.c.server.aof.preamble = true
+	tc.do("BGREWRITEAOF")
+	waitAOFRewrite(t, tc.c.server)
+	if got := restart("preamble"); got.preambleKeys != 200 || got.commands != 0 {
+		t.Fatalf("loaded %d keys from the preamble and %d commands", got.preambleKeys, got.commands)
+	}
+}
+
+func TestAOFReplicaFullResync(t *testing.T) {
+	cfg := aofConfig(t)
+	startAOFServer(t, cfg).do("SET", "local", "dropped by the resync")
+
+	p := newReplTestPair(t, 0)
+	if err := p.replica.openAOF(cfg); err != nil {
+		t.Fatalf("open AOF: %v", err)
+	}
+	t.Cleanup(func() { _ = p.replica.aof.file.Close() })
+	p.onMaster().do("SET", "k", "v")
+	p.waitSynced()
+
+	// The full resync replaced the dataset, so the file is rewritten.
+	waitAOFRewrite(t, p.replica)
+	p.onMaster().do("RPUSH", "l", "a")
+	p.waitSynced()
+
+	restarted := startAOFServer(t, cfg)
+	wantSameDataset(t, restarted.c.server, p.master)
+}
+
+func TestAOFDisabled(t *testing.T) {
+	newTestClient(t).wantError("ERR Append only file is disabled", "BGREWRITEAOF")
+}
+
+func waitAOFReply(local, replicas int64) redisproto.Value {
+	return redisproto.Value{Kind: redisproto.KindArray, Array: []redisproto.Value{
+		{Kind: redisproto.KindInteger, Int: local},
+		{Kind: redisproto.KindInteger, Int: replicas},
+	}}
+}
+
+func TestWaitAOF(t *testing.T) {
+	noAOF := newTestClient(t)
+	noAOF.wantError("ERR WAITAOF cannot be used when numlocal is set but appendonly is disabled.", "WAITAOF", "1", "0", "0")
+	noAOF.wantError("ERR value is out of range, must be positive", "WAITAOF", "-1", "0", "0")
+	noAOF.wantError("ERR timeout is negative", "WAITAOF", "0", "0", "-5")
+
+	cfg := aofConfig(t)
+	cfg.AppendFsync = AppendFsyncNo
+	s := startAOFServer(t, cfg).c.server
+	c, peer := newSocketClient(t, s)
+	ok := redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"}
+
+	// A client that wrote nothing has nothing to wait for.
+	feed(t, c, "WAITAOF", "1", "0", "0")
+	expectReplies(t, peer, waitAOFReply(1, 0))
+
+	// The no policy leaves fsync to the kernel, so the waiter asks for one.
+	feed(t, c, "SET", "k", "v")
+	expectReplies(t, peer, ok)
+	feed(t, c, "WAITAOF", "1", "0", "0")
+	feed(t, c, "GET", "k")
+	expectNoReply(t, peer)
+	s.pollAOF(time.Now())
+	s.pollWaitAOF()
+	expectReplies(t, peer, waitAOFReply(1, 0), redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte("v")})
+
+	// Without replicas, waiting for one times out with the counts reached;
+	// nobody asked for the local fsync this time.
+	feed(t, c, "SET", "k", "w")
+	expectReplies(t, peer, ok)
+	feed(t, c, "WAITAOF", "0", "1", "10")
+	s.pollAOF(time.Now())
+	s.pollWaitAOF()
+	expectNoReply(t, peer)
+	s.expireBlocked(time.Now().Add(time.Second))
+	expectReplies(t, peer, waitAOFReply(0, 0))
+}
+
+func TestWaitAOFReplica(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	for _, s := range []*Server{p.master, p.replica} {
+		if err := s.openAOF(aofConfig(t)); err != nil {
+			t.Fatalf("open AOF: %v", err)
+		}
+		t.Cleanup(func() { _ = s.aof.file.Close() })
+	}
+	p.waitSynced()
+	waitAOFRewrite(t, p.replica)
+	p.onReplica().wantError("ERR WAITAOF cannot be used with replica instances. "+
+		"Please also note that writes to replicas are just local and are not propagated.", "WAITAOF", "0", "0", "0")
+
+	c, peer := newSocketClient(t, p.master)
+	feed(t, c, "SET", "k", "v")
+	expectReplies(t, peer, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"})
+	feed(t, c, "WAITAOF", "1", "1", "0")
+	if c.blocked == nil {
+		t.Fatal("WAITAOF returned before the replica fsynced the write")
+	}
+	p.waitFor("the replica to acknowledge its fsync", func() bool { return c.blocked == nil })
+	expectReplies(t, peer, waitAOFReply(1, 1))
+	if got := p.replica.aof.fsyncedOffset; got < c.woff {
+		t.Fatalf("replica fsynced through %d, the write ends at %d", got, c.woff)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Blocking commands (BZPOPMIN and friends) park the client instead of
+// replying when none of their keys can serve them. The client stays
+// registered under each key it waits on, and frames it sends meanwhile are
+// queued. Whenever a command creates one of those keys, the key is marked
+// ready and, once the command finishes, waiters are retried in the order
+// they blocked. The run loop expires waiters whose timeout has passed.
+
+// blockedState describes why a client is blocked.
+type blockedState struct {
+	keys []string
+	// deadline is zero when the client blocks forever.
+	deadline time.Time
+	// serve retries the command with the store lock held. It appends the
+	// reply and reports true once the command could be served.
+	serve func(dst []byte) ([]byte, bool)
+	// timeoutReply is sent when the deadline passes, unless timeout is set
+	// to build the reply then.
+	timeoutReply []byte
+	timeout      func(dst []byte) []byte
+	// args is the blocked command, propagated to replicas once it is
+	// served.
+	args [][]byte
+	// paused is set for a write held back by a failover rather than
+	// waiting on keys.
+	paused bool
+	// waitAOF is set for WAITAOF, which waits for fsync acknowledgements
+	// rather than keys.
+	waitAOF bool
+}
+
+// parseBlockTimeout parses a blocking command's timeout in seconds. Zero
+// means block forever and yields a zero deadline.
+func parseBlockTimeout(arg []byte, now time.Time) (time.Time, string) {
+	secs, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return time.Time{}, "ERR timeout is not a float or out of range"
+	}
+	if secs < 0 {
+		return time.Time{}, "ERR timeout is negative"
+	}
+	if secs == 0 {
+		return time.Time{}, ""
+	}
+	return now.Add(time.Duration(secs * float64(time.Second))), ""
+}
+
+// block parks c until one of keys becomes ready or deadline passes. The
+// caller must not append a reply for the current command.
+func (c *clientConn) block(keys []string, deadline time.Time, serve func([]byte) ([]byte, bool), timeoutReply []byte) {
+	s := c.server
+	if s.blockedOn == nil {
+		s.blockedOn = make(map[string][]*clientConn)
+		s.blockedClients = make(map[*clientConn]struct{})
+	}
+	c.blocked = &blockedState{keys: keys, deadline: deadline, serve: serve, timeoutReply: timeoutReply}
+	for _, key := range keys {
+		if !slices.Contains(s.blockedOn[key], c) {
+			s.blockedOn[key] = append(s.blockedOn[key], c)
+		}
+	}
+	s.blockedClients[c] = struct{}{}
+}
+
+// serveOrBlock appends the reply of serve if the command can be served
+// now, and otherwise blocks on keys with a null array as the timeout reply.
+func (c *clientConn) serveOrBlock(dst []byte, keys []string, deadline time.Time, serve func([]byte) ([]byte, bool)) []byte {
+	if out, ok := serve(dst); ok {
+		return out
+	}
+	c.block(keys, deadline, serve, appendNullArray(nil))
+	return dst
+}
+
+// unblock removes c from every wait queue.
+func (c *clientConn) unblock() {
+	if c.blocked == nil {
+		return
+	}
+	s := c.server
+	for _, key := range c.blocked.keys {
+		queue := slices.DeleteFunc(s.blockedOn[key], func(w *clientConn) bool { return w == c })
+		if len(queue) == 0 {
+			delete(s.blockedOn, key)
+		} else {
+			s.blockedOn[key] = queue
+		}
+	}
+	delete(s.blockedClients, c)
+	c.blocked = nil
+}
+
+// keyCreated is the Store hook that marks keys with waiters as ready.
+func (s *Server) keyCreated(key string) {
+	if _, ok := s.blockedOn[key]; ok {
+		s.readyKeys = append(s.readyKeys, key)
+	}
+}
+
+// serveReadyKeys retries clients blocked on keys marked ready by the last
+// command. Serving a client runs its queued frames, which can make more
+// keys ready; those are handled by the same call.
+func (s *Server) serveReadyKeys() {
+	if s.servingReady {
+		return
+	}
+	s.servingReady = true
+	defer func() { s.servingReady = false }()
+
+	for len(s.readyKeys) > 0 {
+		key := s.readyKeys[0]
+		s.readyKeys = s.readyKeys[1:]
+		for len(s.blockedOn[key]) > 0 {
+			c := s.blockedOn[key][0]
+			s.store.mu.Lock()
+			s.store.preserve(c.blocked.keys)
+			reply, ok := c.blocked.serve(nil)
+			if ok && s.evict != nil {
+				s.evict.account(s.store.kv, c.blocked.keys)
+			}
+			s.store.mu.Unlock()
+			if !ok {
+				break
+			}
+			s.propagate(c.blocked.args)
+			c.woff = s.repl.offset
+			c.unblock()
+			c.resume(reply)
+		}
+	}
+}
+
+// expireBlocked replies to clients whose blocking timeout has passed.
+func (s *Server) expireBlocked(now time.Time) {
+	for c := range s.blockedClients {
+		if d := c.blocked.deadline; !d.IsZero() && !now.Before(d) {
+			reply := c.blocked.timeoutReply
+			if c.blocked.timeout != nil {
+				reply = c.blocked.timeout(nil)
+			}
+			c.unblock()
+			c.resume(reply)
+		}
+	}
+}
+
+// unblockAll fails every blocked command with reason as an error reply.
+// Writes paused by a failover are left to it.
+// It runs inside a command, with the store locked, so the clients are
+// not resumed here: their deadline is moved into the past and the run loop
+// expires them.
+func (s *Server) unblockAll(reason string) {
+	for c := range s.blockedClients {
+		if c.blocked.paused {
+			continue
+		}
+		c.blocked.deadline = time.Unix(0, 1)
+		c.blocked.timeoutReply = appendError(nil, reason)
+		c.blocked.timeout = nil
+	}
+}
+
+// resume sends the reply of the command c was blocked on and runs the
+// frames queued in the meantime.
+func (c *clientConn) resume(reply []byte) {
+	pending := c.pending
+	c.pending = nil
+	c.process(reply, pending)
+}
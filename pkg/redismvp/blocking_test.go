@@ -0,0 +1,196 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// newSocketClient attaches a client to s over a socketpair, so replies
+// written by the server, including ones sent to other clients when they
+// are unblocked, can be read from the returned peer.
+func newSocketClient(t *testing.T, s *Server) (*clientConn, net.Conn) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	f := os.NewFile(uintptr(fds[1]), "peer")
+	peer, err := net.FileConn(f)
+	_ = f.Close()
+	if err != nil {
+		t.Fatalf("file conn: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = peer.Close()
+		_ = syscall.Close(fds[0])
+	})
+	c := &clientConn{
+		server: s,
+		fd:     int32(fds[0]),
+		codec:  redisproto.NewCodec(),
+		log:    s.log,
+	}
+	return c, peer
+}
+
+func newBlockingTestServer() *Server {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &Server{store: NewStore(), log: log, slowLog: -1}
+	s.store.keyCreated = s.keyCreated
+	return s
+}
+
+func feed(t *testing.T, c *clientConn, args ...string) {
+	t.Helper()
+	wire, err := redisproto.Encode(buildTestCommand(args))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	c.onRead(nil, wire, nil)
+}
+
+// expectReplies reads exactly len(want) replies from peer. Replies that
+// the server wrote together may arrive in a single read.
+func expectReplies(t *testing.T, peer net.Conn, want ...redisproto.Value) {
+	t.Helper()
+	_ = peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer peer.SetReadDeadline(time.Time{})
+
+	parser := redisproto.NewParser()
+	var got []redisproto.Value
+	buf := make([]byte, 4096)
+	for len(got) < len(want) {
+		n, err := peer.Read(buf)
+		if err != nil {
+			t.Fatalf("read failed after %d replies: %v", len(got), err)
+		}
+		frames, err := parser.Feed(buf[:n])
+		if err != nil {
+			t.Fatalf("parse reply: %v", err)
+		}
+		got = append(got, frames...)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func expectNoReply(t *testing.T, peer net.Conn) {
+	t.Helper()
+	_ = peer.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	defer peer.SetReadDeadline(time.Time{})
+	buf := make([]byte, 64)
+	n, err := peer.Read(buf)
+	var ne net.Error
+	if err == nil || !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("expected no reply, read %q (err %v)", buf[:n], err)
+	}
+}
+
+func bulkArray(items ...string) redisproto.Value {
+	v := redisproto.Value{Kind: redisproto.KindArray}
+	for _, s := range items {
+		v.Array = append(v.Array, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(s)})
+	}
+	return v
+}
+
+func TestBlockingPopServedByWrite(t *testing.T) {
+	s := newBlockingTestServer()
+	waiter, waiterPeer := newSocketClient(t, s)
+	writer, writerPeer := newSocketClient(t, s)
+
+	feed(t, waiter, "BZPOPMIN", "z", "0")
+	expectNoReply(t, waiterPeer)
+
+	// Commands sent while blocked run after the blocking command is served.
+	feed(t, waiter, "PING")
+	expectNoReply(t, waiterPeer)
+
+	feed(t, writer, "ZADD", "z", "2", "b", "1", "a")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 2})
+	expectReplies(t, waiterPeer,
+		bulkArray("z", "a", "1"),
+		redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
+
+	if waiter.blocked != nil || len(s.blockedOn) != 0 || len(s.blockedClients) != 0 {
+		t.Fatal("waiter still registered after being served")
+	}
+}
+
+func TestBlockingPopFIFO(t *testing.T) {
+	s := newBlockingTestServer()
+	first, firstPeer := newSocketClient(t, s)
+	second, secondPeer := newSocketClient(t, s)
+	writer, writerPeer := newSocketClient(t, s)
+
+	feed(t, first, "BZPOPMAX", "a", "z", "0")
+	feed(t, second, "BZPOPMAX", "z", "0")
+	feed(t, writer, "ZADD", "z", "1", "low", "2", "high")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 2})
+	expectReplies(t, firstPeer, bulkArray("z", "high", "2"))
+	expectReplies(t, secondPeer, bulkArray("z", "low", "1"))
+}
+
+func TestBlockingPopTimeout(t *testing.T) {
+	s := newBlockingTestServer()
+	waiter, waiterPeer := newSocketClient(t, s)
+	forever, foreverPeer := newSocketClient(t, s)
+
+	feed(t, waiter, "BZPOPMIN", "z", "0.05")
+	feed(t, forever, "BZPOPMIN", "z", "0")
+	s.expireBlocked(time.Now())
+	expectNoReply(t, waiterPeer)
+
+	s.expireBlocked(time.Now().Add(time.Second))
+	expectReplies(t, waiterPeer, redisproto.Value{Kind: redisproto.KindNull})
+	expectNoReply(t, foreverPeer)
+	if forever.blocked == nil {
+		t.Fatal("client with zero timeout was unblocked")
+	}
+
+	forever.close("test")
+	if len(s.blockedOn) != 0 || len(s.blockedClients) != 0 {
+		t.Fatal("closed client still registered as blocked")
+	}
+}
+
+func TestBlockingMultiKeyPop(t *testing.T) {
+	s := newBlockingTestServer()
+	lists, listsPeer := newSocketClient(t, s)
+	zsets, zsetsPeer := newSocketClient(t, s)
+	writer, writerPeer := newSocketClient(t, s)
+
+	feed(t, lists, "BLMPOP", "0", "2", "a", "b", "LEFT", "COUNT", "2")
+	feed(t, zsets, "BZMPOP", "0", "1", "z", "MIN")
+	expectNoReply(t, listsPeer)
+	expectNoReply(t, zsetsPeer)
+
+	feed(t, writer, "RPUSH", "b", "1", "2", "3")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 3})
+	expectReplies(t, listsPeer, redisproto.Value{Kind: redisproto.KindArray, Array: []redisproto.Value{
+		{Kind: redisproto.KindBulkString, Bulk: []byte("b")},
+		bulkArray("1", "2"),
+	}})
+
+	feed(t, writer, "ZADD", "z", "1", "m")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 1})
+	expectReplies(t, zsetsPeer, redisproto.Value{Kind: redisproto.KindArray, Array: []redisproto.Value{
+		{Kind: redisproto.KindBulkString, Bulk: []byte("z")},
+		{Kind: redisproto.KindArray, Array: []redisproto.Value{bulkArray("m", "1")}},
+	}})
+}
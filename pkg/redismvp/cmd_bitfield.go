@@ -0,0 +1,283 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"strconv"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "bitfield", arity: -2, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 1, step: 1,
+			group: "bitmap", summary: "Performs arbitrary bitfield integer operations on strings.", handler: cmdBitfield},
+		&command{name: "bitfield_ro", arity: -2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "bitmap", summary: "Performs arbitrary read-only bitfield integer operations on strings.", handler: cmdBitfieldRO},
+	)
+}
+
+// overflowMode is the BITFIELD OVERFLOW behavior for SET and INCRBY.
+type overflowMode uint8
+
+const (
+	overflowWrap overflowMode = iota
+	overflowSat
+	overflowFail
+)
+
+type bitfieldOpKind uint8
+
+const (
+	bitfieldGet bitfieldOpKind = iota
+	bitfieldSet
+	bitfieldIncrBy
+)
+
+// bitfieldOp is one parsed GET, SET or INCRBY subcommand.
+type bitfieldOp struct {
+	kind     bitfieldOpKind
+	signed   bool
+	bits     uint
+	offset   uint64
+	value    int64
+	overflow overflowMode
+}
+
+func cmdBitfield(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.bitfield(dst, args, false)
+}
+
+func cmdBitfieldRO(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.bitfield(dst, args, true)
+}
+
+// bitfield parses every subcommand before touching the key, so a bad
+// argument anywhere fails the whole command. The string is grown once to
+// cover the furthest bit written, even if an op then fails on overflow,
+// as in Redis.
+func (c *clientConn) bitfield(dst []byte, args [][]byte, readonly bool) []byte {
+	key := string(args[0])
+	ops, errReply := parseBitfieldOps(args[1:])
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	var writeEnd uint64
+	for _, op := range ops {
+		if op.kind != bitfieldGet {
+			if readonly {
+				return appendError(dst, "ERR BITFIELD_RO only supports the GET subcommand")
+			}
+			writeEnd = max(writeEnd, (op.offset+uint64(op.bits)+7)/8)
+		}
+	}
+
+	store := c.server.store
+	var buf []byte
+	var err error
+	if writeEnd > 0 {
+		buf, err = store.growString(key, int(writeEnd))
+	} else {
+		buf, _, err = store.lookupString(key)
+	}
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+
+	dst = appendArrayLen(dst, len(ops))
+	for _, op := range ops {
+		old := getBits(buf, op.offset, op.bits)
+		if op.kind == bitfieldGet {
+			dst = appendInteger(dst, bitfieldInt(old, op.signed, op.bits))
+			continue
+		}
+		incr := op.value
+		base := uint64(bitfieldInt(old, op.signed, op.bits))
+		if op.kind == bitfieldSet {
+			base, incr = uint64(op.value), 0
+		}
+		res, overflowed := bitfieldAdd(base, incr, op.signed, op.bits, op.overflow)
+		if overflowed && op.overflow == overflowFail {
+			dst = appendNull(dst)
+			continue
+		}
+		setBits(buf, op.offset, op.bits, res)
+		if op.kind == bitfieldSet {
+			dst = appendInteger(dst, bitfieldInt(old, op.signed, op.bits))
+		} else {
+			dst = appendInteger(dst, bitfieldInt(res, op.signed, op.bits))
+		}
+	}
+	return dst
+}
+
+func parseBitfieldOps(args [][]byte) ([]bitfieldOp, string) {
+	var ops []bitfieldOp
+	overflow := overflowWrap
+	for i := 0; i < len(args); {
+		remaining := len(args) - i - 1
+		var op bitfieldOp
+		switch {
+		case argIs(args[i], "OVERFLOW") && remaining >= 1:
+			switch {
+			case argIs(args[i+1], "WRAP"):
+				overflow = overflowWrap
+			case argIs(args[i+1], "SAT"):
+				overflow = overflowSat
+			case argIs(args[i+1], "FAIL"):
+				overflow = overflowFail
+			default:
+				return nil, "ERR Invalid OVERFLOW type specified"
+			}
+			i += 2
+			continue
+		case argIs(args[i], "GET") && remaining >= 2:
+			op.kind = bitfieldGet
+		case argIs(args[i], "SET") && remaining >= 3:
+			op.kind = bitfieldSet
+		case argIs(args[i], "INCRBY") && remaining >= 3:
+			op.kind = bitfieldIncrBy
+		default:
+			return nil, "ERR syntax error"
+		}
+
+		var ok bool
+		if op.signed, op.bits, ok = parseBitfieldType(args[i+1]); !ok {
+			return nil, "ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is."
+		}
+		if op.offset, ok = parseBitOffset(args[i+2], op.bits); !ok {
+			return nil, "ERR bit offset is not an integer or out of range"
+		}
+		i += 3
+		if op.kind != bitfieldGet {
+			if op.value, ok = parseInt(args[i]); !ok {
+				return nil, "ERR value is not an integer or out of range"
+			}
+			i++
+		}
+		op.overflow = overflow
+		ops = append(ops, op)
+	}
+	return ops, ""
+}
+
+// parseBitfieldType parses i1 through i64 and u1 through u63.
+func parseBitfieldType(arg []byte) (bool, uint, bool) {
+	if len(arg) < 2 || (arg[0] != 'i' && arg[0] != 'u') {
+		return false, 0, false
+	}
+	signed := arg[0] == 'i'
+	bits, err := strconv.ParseUint(string(arg[1:]), 10, 8)
+	if err != nil || bits < 1 || (signed && bits > 64) || (!signed && bits > 63) {
+		return false, 0, false
+	}
+	return signed, uint(bits), true
+}
+
+// parseBitOffset parses a bit offset. A '#' prefix multiplies the offset
+// by the field width.
+func parseBitOffset(arg []byte, bits uint) (uint64, bool) {
+	scale := uint64(1)
+	if len(arg) > 0 && arg[0] == '#' {
+		arg, scale = arg[1:], uint64(bits)
+	}
+	n, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if uint64(n) > maxStringSize*8/scale {
+		return 0, false
+	}
+	offset := uint64(n) * scale
+	return offset, offset/8 < maxStringSize
+}
+
+// getBits reads a bits-wide unsigned field at offset. Bit 0 is the most
+// significant bit of the first byte; bits past the end of buf read as 0.
+func getBits(buf []byte, offset uint64, bits uint) uint64 {
+	var v uint64
+	for i := range uint64(bits) {
+		pos := offset + i
+		v <<= 1
+		if pos/8 < uint64(len(buf)) {
+			v |= uint64(buf[pos/8]>>(7-pos%8)) & 1
+		}
+	}
+	return v
+}
+
+// setBits writes the low bits of v at offset. buf must be long enough.
+func setBits(buf []byte, offset uint64, bits uint, v uint64) {
+	for i := range uint64(bits) {
+		pos := offset + i
+		mask := byte(1) << (7 - pos%8)
+		if v>>(uint64(bits)-1-i)&1 != 0 {
+			buf[pos/8] |= mask
+		} else {
+			buf[pos/8] &^= mask
+		}
+	}
+}
+
+// bitfieldInt interprets the low bits of v as a field value.
+func bitfieldInt(v uint64, signed bool, bits uint) int64 {
+	if bits == 64 {
+		return int64(v)
+	}
+	v &= 1<<bits - 1
+	if signed && v&(1<<(bits-1)) != 0 {
+		v |= ^uint64(0) << bits
+	}
+	return int64(v)
+}
+
+// bitfieldAdd adds incr to v, the field value as a 64-bit integer. For
+// SET, v is the new value itself and may not fit the field. It returns
+// the bits to store and whether the result overflowed, applying mode to
+// out-of-range results.
+func bitfieldAdd(v uint64, incr int64, signed bool, bits uint, mode overflowMode) (uint64, bool) {
+	if signed {
+		res, overflowed := signedAdd(int64(v), incr, bits, mode)
+		return uint64(res), overflowed
+	}
+	return unsignedAdd(v, incr, bits, mode)
+}
+
+func unsignedAdd(v uint64, incr int64, bits uint, mode overflowMode) (uint64, bool) {
+	maxVal := uint64(1)<<bits - 1
+	var limit uint64
+	switch {
+	case v > maxVal || (incr > 0 && uint64(incr) > maxVal-v):
+		limit = maxVal
+	case incr < 0 && uint64(-incr) > v:
+		limit = 0
+	default:
+		return v + uint64(incr), false
+	}
+	if mode == overflowSat {
+		return limit, true
+	}
+	return (v + uint64(incr)) & maxVal, true
+}
+
+func signedAdd(v, incr int64, bits uint, mode overflowMode) (int64, bool) {
+	maxVal := int64(math.MaxInt64 >> (64 - bits))
+	minVal := -maxVal - 1
+	// maxVal-v and minVal-v cannot overflow for fields narrower than 64
+	// bits, and for 64-bit fields only the checks that can fail are done.
+	var limit int64
+	switch {
+	case v > maxVal || (incr > 0 && (v >= 0 || bits < 64) && incr > maxVal-v):
+		limit = maxVal
+	case v < minVal || (incr < 0 && (v < 0 || bits < 64) && incr < minVal-v):
+		limit = minVal
+	default:
+		return v + incr, false
+	}
+	if mode == overflowSat {
+		return limit, true
+	}
+	return bitfieldInt(uint64(v+incr), true, bits), true
+}
@@ -0,0 +1,247 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"encoding/binary"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestSetRangeGetRange(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(11, "SETRANGE", "k", "6", "world")
+	tc.wantBulk("\x00\x00\x00\x00\x00\x00world", "GET", "k")
+	tc.wantInt(11, "SETRANGE", "k", "0", "hello ")
+	tc.wantBulk("hello world", "GET", "k")
+	tc.wantInt(0, "SETRANGE", "missing", "5", "")
+	tc.wantNull("GET", "missing")
+	tc.wantError("ERR offset is out of range", "SETRANGE", "k", "-1", "x")
+	tc.wantError("ERR string exceeds maximum allowed size (proto-max-bulk-len)", "SETRANGE", "k", "536870911", "xy")
+	tc.wantError("ERR string exceeds maximum allowed size (proto-max-bulk-len)", "SETRANGE", "k", "9223372036854775807", "x")
+
+	tc.wantBulk("hello", "GETRANGE", "k", "0", "4")
+	tc.wantBulk("world", "GETRANGE", "k", "-5", "-1")
+	tc.wantBulk("hello world", "GETRANGE", "k", "0", "100")
+	tc.wantBulk("", "GETRANGE", "k", "5", "3")
+	tc.wantBulk("", "GETRANGE", "k", "-1", "-5")
+	tc.wantBulk("", "GETRANGE", "missing", "0", "-1")
+
+	tc.do("SADD", "s", "a")
+	tc.wantError(errWrongType.Error(), "SETRANGE", "s", "0", "x")
+}
+
+func TestBitfield(t *testing.T) {
+	tc := newTestClient(t)
+
+	wantInts := func(want []int64, args ...string) {
+		t.Helper()
+		got := tc.do(args...)
+		if got.Kind != redisproto.KindArray || len(got.Array) != len(want) {
+			t.Fatalf("%v: got %#v", args, got)
+		}
+		for i, v := range got.Array {
+			if v.Kind != redisproto.KindInteger || v.Int != want[i] {
+				t.Fatalf("%v: reply %d is %#v, want %d", args, i, v, want[i])
+			}
+		}
+	}
+
+	wantInts([]int64{0}, "BITFIELD", "bf", "GET", "u8", "0")
+	tc.wantNull("GET", "bf")
+	wantInts([]int64{0, 255}, "BITFIELD", "bf", "SET", "u8", "0", "255", "GET", "u8", "0")
+	wantInts([]int64{-1, 15}, "BITFIELD", "bf", "GET", "i8", "0", "GET", "u4", "4")
+	wantInts([]int64{0, 100}, "BITFIELD", "bf", "SET", "u8", "#1", "100", "GET", "u8", "8")
+	tc.wantBulk("\xffd", "GET", "bf")
+
+	wantInts([]int64{1}, "BITFIELD", "w", "INCRBY", "u2", "0", "5")
+	wantInts([]int64{3}, "BITFIELD", "w", "OVERFLOW", "SAT", "INCRBY", "u2", "0", "5")
+	wantInts([]int64{0, -128}, "BITFIELD", "w", "SET", "i8", "8", "127", "INCRBY", "i8", "8", "1")
+	wantInts([]int64{-128, 127}, "BITFIELD", "w", "OVERFLOW", "SAT", "SET", "i8", "8", "200", "GET", "i8", "8")
+	wantInts([]int64{0, 255}, "BITFIELD", "w", "OVERFLOW", "SAT", "SET", "u8", "16", "-1", "GET", "u8", "16")
+
+	got := tc.do("BITFIELD", "f", "OVERFLOW", "FAIL", "INCRBY", "u8", "0", "256", "GET", "u8", "0")
+	if len(got.Array) != 2 || got.Array[0].Kind != redisproto.KindNull || got.Array[1].Int != 0 {
+		t.Fatalf("OVERFLOW FAIL: got %#v", got)
+	}
+	// The string is grown even though the only write failed.
+	tc.wantBulk("\x00", "GET", "f")
+
+	wantInts([]int64{0}, "BITFIELD_RO", "bf", "GET", "u1", "100")
+	tc.wantError("ERR BITFIELD_RO only supports the GET subcommand", "BITFIELD_RO", "bf", "SET", "u8", "0", "1")
+	tc.wantError("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.",
+		"BITFIELD", "bf", "GET", "u64", "0")
+	tc.wantError("ERR bit offset is not an integer or out of range", "BITFIELD", "bf", "GET", "u8", "-1")
+	tc.wantError("ERR bit offset is not an integer or out of range", "BITFIELD", "bf", "GET", "u8", "4294967296")
+	tc.wantError("ERR Invalid OVERFLOW type specified", "BITFIELD", "bf", "OVERFLOW", "CLAMP")
+	tc.wantError("ERR syntax error", "BITFIELD", "bf", "GET", "u8")
+	tc.wantError("ERR value is not an integer or out of range", "BITFIELD", "bf", "SET", "u8", "0", "x")
+	tc.do("SADD", "s", "a")
+	tc.wantError(errWrongType.Error(), "BITFIELD", "s", "GET", "u8", "0")
+}
+
+// refBitmap is a deliberately naive BITFIELD model: one bool per bit and
+// math/big for all arithmetic.
+type refBitmap struct {
+	bits []bool
+}
+
+func (r *refBitmap) grow(nbits uint64) {
+	nbits = (nbits + 7) / 8 * 8
+	for uint64(len(r.bits)) < nbits {
+		r.bits = append(r.bits, false)
+	}
+}
+
+func (r *refBitmap) get(offset uint64, width uint, signed bool) *big.Int {
+	v := new(big.Int)
+	for i := range uint64(width) {
+		v.Lsh(v, 1)
+		if pos := offset + i; pos < uint64(len(r.bits)) && r.bits[pos] {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	if signed && v.Bit(int(width)-1) == 1 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), width))
+	}
+	return v
+}
+
+func (r *refBitmap) set(offset uint64, width uint, v *big.Int) {
+	m := new(big.Int).Mod(v, new(big.Int).Lsh(big.NewInt(1), width))
+	for i := range uint64(width) {
+		r.bits[offset+i] = m.Bit(int(width)-1-int(i)) == 1
+	}
+}
+
+// apply returns the reply of one op, or nil for a failed write.
+func (r *refBitmap) apply(op bitfieldOp) *big.Int {
+	old := r.get(op.offset, op.bits, op.signed)
+	if op.kind == bitfieldGet {
+		return old
+	}
+	var v *big.Int
+	if op.kind == bitfieldSet {
+		v = big.NewInt(op.value)
+		if !op.signed {
+			v.SetUint64(uint64(op.value))
+		}
+	} else {
+		v = new(big.Int).Add(old, big.NewInt(op.value))
+	}
+	lo, hi := new(big.Int), new(big.Int).Lsh(big.NewInt(1), op.bits)
+	if op.signed {
+		lo.Neg(new(big.Int).Rsh(hi, 1))
+		hi.Rsh(hi, 1)
+	}
+	hi.Sub(hi, big.NewInt(1))
+	if v.Cmp(lo) < 0 || v.Cmp(hi) > 0 {
+		switch op.overflow {
+		case overflowFail:
+			return nil
+		case overflowSat:
+			if v.Cmp(lo) < 0 {
+				v = lo
+			} else {
+				v = hi
+			}
+		}
+	}
+	r.set(op.offset, op.bits, v)
+	if op.kind == bitfieldSet {
+		return old
+	}
+	return r.get(op.offset, op.bits, op.signed)
+}
+
+func (r *refBitmap) bytes() []byte {
+	out := make([]byte, len(r.bits)/8)
+	for i, b := range r.bits {
+		if b {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// decodeBitfieldOp builds an op from 11 fuzz bytes. Offsets stay small so
+// ops overlap and the bitmap stays short.
+func decodeBitfieldOp(b []byte) bitfieldOp {
+	op := bitfieldOp{
+		kind:     bitfieldOpKind(b[0] % 3),
+		signed:   b[1]&0x80 != 0,
+		overflow: overflowMode(b[0] / 3 % 3),
+		offset:   uint64(b[2]),
+		value:    int64(binary.LittleEndian.Uint64(b[3:])),
+	}
+	op.bits = min(uint(b[1]&0x3f)+1, 63)
+	if op.signed && b[1]&0x40 != 0 {
+		op.bits = 64
+	}
+	// Keep most values small enough that increments walk into the bounds.
+	if b[1]&0x40 == 0 {
+		op.value %= 1 << 10
+	}
+	return op
+}
+
+func bitfieldArgs(op bitfieldOp) []string {
+	modes := [...]string{"WRAP", "SAT", "FAIL"}
+	kinds := [...]string{"GET", "SET", "INCRBY"}
+	typ := "u"
+	if op.signed {
+		typ = "i"
+	}
+	args := []string{"OVERFLOW", modes[op.overflow], kinds[op.kind],
+		typ + strconv.Itoa(int(op.bits)), strconv.FormatUint(op.offset, 10)}
+	if op.kind != bitfieldGet {
+		args = append(args, strconv.FormatInt(op.value, 10))
+	}
+	return args
+}
+
+func FuzzBitfield(f *testing.F) {
+	f.Add([]byte("\x00\x07\x00\xff\x00\x00\x00\x00\x00\x00\x00"))
+	f.Add([]byte("\x02\x87\x03\x7f\x00\x00\x00\x00\x00\x00\x00\x05\x87\x03\x01\x00\x00\x00\x00\x00\x00\x00"))
+	f.Add([]byte("\x08\xc0\x05\xff\xff\xff\xff\xff\xff\xff\x7f\x08\xc0\x05\x01\x00\x00\x00\x00\x00\x00\x00"))
+	f.Add([]byte("8\x870010000000"))
+	f.Add([]byte("\x04\x3e\x01\x00\x00\x00\x00\x00\x00\x00\x80\x01\x3e\x01\xff\xff\xff\xff\xff\xff\xff\xff"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tc := newTestClient(t)
+		var ref refBitmap
+		for ; len(data) >= 11; data = data[11:] {
+			op := decodeBitfieldOp(data)
+			args := append([]string{"BITFIELD", "k"}, bitfieldArgs(op)...)
+			if op.kind != bitfieldGet {
+				ref.grow(op.offset + uint64(op.bits))
+			}
+			want := ref.apply(op)
+
+			got := tc.do(args...)
+			if len(got.Array) != 1 {
+				t.Fatalf("%v: got %#v", args, got)
+			}
+			reply := got.Array[0]
+			switch {
+			case want == nil:
+				if reply.Kind != redisproto.KindNull {
+					t.Fatalf("%v: got %#v, want null", args, reply)
+				}
+			case reply.Kind != redisproto.KindInteger || !want.IsInt64() || reply.Int != want.Int64():
+				t.Fatalf("%v: got %#v, want %s", args, reply, want)
+			}
+		}
+		stored, _ := tc.c.server.store.Get("k")
+		if string(stored) != string(ref.bytes()) {
+			t.Fatalf("stored %x, want %x", stored, ref.bytes())
+		}
+	})
+}
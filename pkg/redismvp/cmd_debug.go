@@ -0,0 +1,223 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "debug", arity: -2, group: "server",
+			summary: "A container for debugging commands.", handler: cmdDebug},
+	)
+}
+
+func cmdDebug(c *clientConn, dst []byte, args [][]byte) []byte {
+	sub, rest := args[0], args[1:]
+	switch {
+	case argIs(sub, "RELOAD"):
+		return debugReload(c, dst, rest)
+	case argIs(sub, "KEYSPACE"):
+		return debugKeyspace(c, dst, rest)
+	case argIs(sub, "HELP") && len(rest) == 0:
+		return appendHelp(dst, "DEBUG",
+			"KEYSPACE [MATCH <pattern>] [COUNT <count>]",
+			"    Return a JSON summary of the keys matching the pattern: their number,",
+			"    their number per type, and the type and TTL of the first <count> keys",
+			"    in lexical order (default 100). Needs the debug-keyspace option.",
+			"RELOAD [option ...]",
+			"    Save the RDB on disk and reload it back to memory. Options:",
+			"    * MERGE: Merge the loaded keys into the current dataset instead of",
+			"      replacing it. Loaded keys overwrite existing ones.",
+			"    * NOFLUSH: Do not empty the dataset before loading; a loaded key that",
+			"      already exists is an error unless MERGE is given.",
+			"    * NOSAVE: Do not save before loading, reload the existing dump.")
+	case argIs(sub, "HELP"):
+		return appendWrongArity(dst, "debug|"+strings.ToLower(string(sub)))
+	default:
+		return appendUnknownSubcommand(dst, "DEBUG", sub)
+	}
+}
+
+// debugReload saves the dataset and loads it back in place, so a test can
+// check that every value survives the RDB round trip.
+func debugReload(c *clientConn, dst []byte, args [][]byte) []byte {
+	var merge, noFlush, noSave bool
+	for _, arg := range args {
+		switch {
+		case argIs(arg, "MERGE"):
+			merge = true
+		case argIs(arg, "NOFLUSH"):
+			noFlush = true
+		case argIs(arg, "NOSAVE"):
+			noSave = true
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+	s := c.server
+	if !noSave {
+		// A running snapshot may be a replication or rewrite one; let it
+		// finish rather than failing like SAVE does.
+		_ = s.waitSnapshot()
+		if err := s.startSave(); err != nil {
+			return appendError(dst, "ERR "+err.Error())
+		}
+		if err := s.waitSnapshot(); err != nil {
+			return appendError(dst, "ERR "+err.Error())
+		}
+	}
+	loaded, err := s.readRDBFile(s.dbFilename)
+	if err != nil {
+		s.log.Warn("DEBUG RELOAD failed", "err", err)
+		return appendError(dst, "ERR Error trying to load the RDB dump, check server logs.")
+	}
+	kv := s.store.kv
+	if noFlush && !merge {
+		for key := range loaded {
+			if _, ok := kv[key]; ok {
+				s.log.Warn("DEBUG RELOAD failed", "err", fmt.Errorf("duplicate key %q", key))
+				return appendError(dst, "ERR Error trying to load the RDB dump, check server logs.")
+			}
+		}
+	}
+	if !noFlush {
+		for key, v := range kv {
+			delete(kv, key)
+			s.lazyFree.free(v)
+		}
+	}
+	for key, v := range loaded {
+		if old, ok := kv[key]; ok {
+			s.lazyFree.free(old)
+		}
+		kv[key] = v
+	}
+	if s.evict != nil {
+		s.evict.recount(kv)
+	}
+	s.log.Info("DB reloaded by DEBUG RELOAD", "keys", len(kv))
+	return appendSimple(dst, "OK")
+}
+
+// defaultKeyspaceCount is the number of keys DEBUG KEYSPACE details when
+// COUNT is not given.
+const defaultKeyspaceCount = 100
+
+// keyspaceSummary is the JSON reply of DEBUG KEYSPACE.
+type keyspaceSummary struct {
+	Keys  int            `json:"keys"`
+	Types map[string]int `json:"types"`
+	// Entries holds the first keys in lexical order; Truncated is set if
+	// there are more.
+	Entries   []keyspaceEntry `json:"entries"`
+	Truncated bool            `json:"truncated"`
+}
+
+type keyspaceEntry struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	TTL  int64  `json:"ttl"`
+}
+
+// debugKeyspaceDenied is the error DEBUG KEYSPACE is refused with unless
+// Config.DebugKeyspace is set.
+const debugKeyspaceDenied = "ERR DEBUG KEYSPACE not allowed. Set the debug-keyspace option to enable it."
+
+// debugKeyspace summarizes the keyspace as JSON in a bulk string, so tests
+// and dashboards can check it in one call instead of scanning every key
+// with TYPE and TTL. Keys that are not valid UTF-8 are reported with their
+// invalid bytes replaced, as encoding/json does.
+//
+// It walks every key once to count them, keeping only the first count in
+// lexical order, so it takes memory for count keys rather than for a copy
+// of the keyspace.
+func debugKeyspace(c *clientConn, dst []byte, args [][]byte) []byte {
+	if !c.server.debugKeyspace {
+		return appendError(dst, debugKeyspaceDenied)
+	}
+	var pattern []byte
+	count := defaultKeyspaceCount
+	for i := 0; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "MATCH") && i+1 < len(args):
+			i++
+			pattern = args[i]
+		case argIs(args[i], "COUNT") && i+1 < len(args):
+			i++
+			n, ok := parseInt(args[i])
+			if !ok || n < 0 {
+				return appendError(dst, "ERR value is not an integer or out of range")
+			}
+			count = int(min(n, int64(1<<31-1)))
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+
+	kv := c.server.store.kv
+	summary := keyspaceSummary{Types: make(map[string]int), Entries: []keyspaceEntry{}}
+	var first keyHeap
+	for key, v := range kv {
+		if pattern != nil && !globMatch(pattern, []byte(key)) {
+			continue
+		}
+		summary.Keys++
+		summary.Types[typeName(v)]++
+		switch {
+		case len(first) < count:
+			heap.Push(&first, key)
+		case count > 0 && key < first[0]:
+			first[0] = key
+			heap.Fix(&first, 0)
+		}
+	}
+	summary.Truncated = summary.Keys > len(first)
+	slices.Sort(first)
+	for _, key := range first {
+		summary.Entries = append(summary.Entries, keyspaceEntry{Key: key, Type: typeName(kv[key]), TTL: keyTTL(kv, key)})
+	}
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return appendError(dst, "ERR "+err.Error())
+	}
+	return appendBulk(dst, out)
+}
+
+// keyHeap is a max-heap of keys, whose root is the last of them in lexical
+// order.
+type keyHeap []string
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x any)        { *h = append(*h, x.(string)) }
+
+func (h *keyHeap) Pop() any {
+	old := *h
+	key := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return key
+}
+
+// readRDBFile decodes the snapshot at path into a new map.
+func (s *Server) readRDBFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read RDB file: %w", err)
+	}
+	kv := make(map[string]any)
+	if _, err := loadRDB(data, kv); err != nil {
+		return nil, fmt.Errorf("load RDB file: %w", err)
+	}
+	return kv, nil
+}
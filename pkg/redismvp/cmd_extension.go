@@ -0,0 +1,39 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import "bytes"
+
+// Extension commands are registered like the others, in extensionTable
+// instead of commandTable, and must be enabled with
+// Config.ExtensionCommands. They are propagated to replicas and the AOF as
+// plain Redis commands, so a replica or a replay never needs them enabled.
+
+func init() {
+	registerExtensions(
+		&command{name: "x.setifeq", arity: 4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Sets the string value of a key only if it currently holds the expected value.", handler: cmdXSetIfEq},
+	)
+}
+
+// cmdXSetIfEq implements X.SETIFEQ key expected new, an atomic
+// compare-and-swap: it sets key to new and replies 1 if key holds
+// expected, and otherwise leaves it alone and replies 0. A missing key
+// never matches.
+func cmdXSetIfEq(c *clientConn, dst []byte, args [][]byte) []byte {
+	store := c.server.store
+	cur, ok, err := store.lookupString(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if !ok || !bytes.Equal(cur, args[1]) {
+		c.skipPropagation()
+		return appendInteger(dst, 0)
+	}
+	store.kv[string(args[0])] = args[2]
+	c.propagateAs([]byte("SET"), args[0], args[2])
+	return appendInteger(dst, 1)
+}
@@ -0,0 +1,66 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtensionCommandsDisabled(t *testing.T) {
+	tc := newTestClient(t)
+	tc.do("SET", "k", "v")
+	tc.wantError("ERR unknown command 'x.setifeq'", "X.SETIFEQ", "k", "v", "w")
+	tc.wantInt(int64(len(commandTable)), "COMMAND", "COUNT")
+	if got := tc.do("COMMAND", "INFO", "x.setifeq"); len(got.Array) != 1 || len(got.Array[0].Array) != 0 {
+		t.Fatalf("COMMAND INFO x.setifeq: got %#v", got)
+	}
+}
+
+func TestXSetIfEq(t *testing.T) {
+	tc := newTestClient(t)
+	s := tc.c.server
+	s.extensions = true
+	s.repl.createBacklog()
+	start := s.repl.offset + 1
+
+	tc.wantInt(0, "X.SETIFEQ", "k", "", "v1")
+	tc.wantNull("GET", "k")
+	tc.do("SET", "k", "v1")
+	tc.wantInt(1, "x.setifeq", "k", "v1", "v2")
+	tc.wantBulk("v2", "GET", "k")
+	tc.wantInt(0, "X.SETIFEQ", "k", "v1", "v3")
+	tc.wantBulk("v2", "GET", "k")
+	tc.wantError("ERR wrong number of arguments for 'x.setifeq' command", "X.SETIFEQ", "k", "v2")
+	tc.wantInt(1, "SADD", "set", "m")
+	tc.wantError(errWrongType.Error(), "X.SETIFEQ", "set", "m", "v")
+
+	// Replicas and the AOF see a plain SET, and nothing for a failed swap.
+	stream, _ := s.repl.partialResync(s.repl.id, start)
+	want := string(appendBulkArray(nil, [][]byte{[]byte("SET"), []byte("k"), []byte("v1")})) +
+		string(appendBulkArray(nil, [][]byte{[]byte("SET"), []byte("k"), []byte("v2")})) +
+		string(appendBulkArray(nil, [][]byte{[]byte("SADD"), []byte("set"), []byte("m")}))
+	if string(stream) != want {
+		t.Fatalf("replication stream %q, want %q", stream, want)
+	}
+
+	tc.wantInt(int64(len(commandTable)+len(extensionTable)), "COMMAND", "COUNT")
+	docs := tc.do("COMMAND", "DOCS", "X.SETIFEQ")
+	if len(docs.Array) != 2 || string(docs.Array[0].Bulk) != "x.setifeq" {
+		t.Fatalf("COMMAND DOCS x.setifeq: got %#v", docs)
+	}
+}
+
+func TestExtensionTable(t *testing.T) {
+	for name, cmd := range extensionTable {
+		if cmd.name != name || !strings.HasPrefix(name, "x.") || cmd.handler == nil {
+			t.Errorf("bad extension command %q", name)
+		}
+		if _, ok := commandTable[name]; ok {
+			t.Errorf("%s registered as both a command and an extension", name)
+		}
+	}
+}
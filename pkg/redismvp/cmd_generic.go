@@ -0,0 +1,185 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"maps"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "del", arity: -2, flags: []string{flagWrite}, firstKey: 1, lastKey: -1, step: 1,
+			group: "generic", summary: "Deletes one or more keys.", handler: cmdDel},
+		&command{name: "unlink", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: -1, step: 1,
+			group: "generic", summary: "Asynchronously deletes one or more keys.", handler: cmdUnlink},
+		&command{name: "touch", arity: -2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: -1, step: 1,
+			group: "generic", summary: "Returns the number of existing keys out of those specified after updating the time they were last accessed.", handler: cmdTouch},
+		&command{name: "copy", arity: -3, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 2, step: 1,
+			group: "generic", summary: "Copies the value of a key to a new key.", handler: cmdCopy},
+		&command{name: "type", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "generic", summary: "Determines the type of value stored at a key.", handler: cmdType},
+		&command{name: "scan", arity: -2, flags: []string{flagReadonly}, group: "generic",
+			summary: "Iterates over the key names in the database.", handler: cmdScan},
+		&command{name: "dump", arity: 2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "generic", summary: "Returns a serialized representation of the value stored at a key.", handler: cmdDump},
+		&command{name: "restore", arity: -4, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 1, step: 1,
+			group: "generic", summary: "Creates a key from the serialized representation of a value.", handler: cmdRestore},
+		&command{name: "ttl", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "generic", summary: "Returns the expiration time in seconds of a key.", handler: cmdTTL},
+		&command{name: "pttl", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "generic", summary: "Returns the expiration time in milliseconds of a key.", handler: cmdTTL},
+	)
+}
+
+func cmdDel(c *clientConn, dst []byte, args [][]byte) []byte {
+	return appendInteger(dst, c.server.store.del(keyStrings(args)...))
+}
+
+// cmdUnlink removes keys immediately but releases large values on the
+// lazy-free worker, so unlinking a huge collection does not stall the loop.
+func cmdUnlink(c *clientConn, dst []byte, args [][]byte) []byte {
+	return appendInteger(dst, c.server.store.unlink(keyStrings(args), c.server.lazyFree.free))
+}
+
+// cmdTouch counts the existing keys. Their access time, if tracked, is
+// updated by the dispatcher as for any command naming keys.
+func cmdTouch(c *clientConn, dst []byte, args [][]byte) []byte {
+	n := int64(0)
+	for _, key := range args {
+		if _, ok := c.server.store.kv[string(key)]; ok {
+			n++
+		}
+	}
+	return appendInteger(dst, n)
+}
+
+func cmdCopy(c *clientConn, dst []byte, args [][]byte) []byte {
+	replace := false
+	for i := 2; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "REPLACE"):
+			replace = true
+		case argIs(args[i], "DB") && i+1 < len(args):
+			i++
+			db, ok := parseInt(args[i])
+			if !ok {
+				return appendNotInteger(dst)
+			}
+			if db != 0 {
+				return appendError(dst, "ERR DB index is out of range")
+			}
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+
+	store := c.server.store
+	src, dstKey := string(args[0]), string(args[1])
+	if src == dstKey {
+		return appendError(dst, "ERR source and destination objects are the same")
+	}
+	v, ok := store.kv[src]
+	if !ok {
+		return appendInteger(dst, 0)
+	}
+	if _, exists := store.kv[dstKey]; exists {
+		if !replace {
+			return appendInteger(dst, 0)
+		}
+		store.unlink([]string{dstKey}, c.server.lazyFree.free)
+	}
+	store.kv[dstKey] = copyValue(v)
+	store.created(dstKey)
+	return appendInteger(dst, 1)
+}
+
+func cmdType(c *clientConn, dst []byte, args [][]byte) []byte {
+	return appendSimple(dst, typeName(c.server.store.kv[string(args[0])]))
+}
+
+func cmdDump(c *clientConn, dst []byte, args [][]byte) []byte {
+	v, ok := c.server.store.kv[string(args[0])]
+	if !ok {
+		return appendNull(dst)
+	}
+	return appendBulk(dst, appendDumpPayload(nil, v))
+}
+
+// cmdRestore implements RESTORE key ttl payload [REPLACE] [ABSTTL]. Keys
+// never expire here, so like expire times in RDB files the TTL is
+// validated and dropped.
+func cmdRestore(c *clientConn, dst []byte, args [][]byte) []byte {
+	replace := false
+	for _, opt := range args[3:] {
+		switch {
+		case argIs(opt, "REPLACE"):
+			replace = true
+		case argIs(opt, "ABSTTL"):
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+	ttl, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	if ttl < 0 {
+		return appendError(dst, "ERR Invalid TTL value, must be >= 0")
+	}
+	store := c.server.store
+	key := string(args[0])
+	if _, exists := store.kv[key]; exists && !replace {
+		return appendError(dst, "BUSYKEY Target key name already exists.")
+	}
+	v, err := loadDumpPayload(args[2])
+	if err != nil {
+		return appendError(dst, "ERR "+err.Error())
+	}
+	store.unlink([]string{key}, c.server.lazyFree.free)
+	store.kv[key] = v
+	store.created(key)
+	return appendSimple(dst, "OK")
+}
+
+// cmdTTL serves TTL and PTTL. Keys never expire, so an existing key
+// always reports -1.
+func cmdTTL(c *clientConn, dst []byte, args [][]byte) []byte {
+	return appendInteger(dst, keyTTL(c.server.store.kv, string(args[0])))
+}
+
+// keyTTL returns the TTL of key as TTL and PTTL report it: -2 if the key
+// does not exist, and -1 if it has no expiry, which no key has, as the
+// server does not expire keys.
+func keyTTL(kv map[string]any, key string) int64 {
+	if _, ok := kv[key]; !ok {
+		return -2
+	}
+	return -1
+}
+
+func cmdScan(c *clientConn, dst []byte, args [][]byte) []byte {
+	opts, errReply := parseScanArgs(args, "TYPE")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	kv := c.server.store.kv
+	page, next := c.server.store.scanPage(scanKey{keyspace: true}, maps.Keys(kv), func(key string) bool {
+		_, ok := kv[key]
+		return ok
+	}, opts.cursor, opts.count)
+	keys := page[:0]
+	for _, key := range page {
+		if !opts.match(key) {
+			continue
+		}
+		if opts.typeName != "" && !strings.EqualFold(typeName(kv[key]), opts.typeName) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return appendScanReply(dst, next, keys)
+}
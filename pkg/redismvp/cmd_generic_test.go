@@ -0,0 +1,87 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import "testing"
+
+func TestGetSet(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantNull("GETSET", "k", "a")
+	tc.wantBulk("a", "GETSET", "k", "b")
+	tc.wantBulk("b", "GET", "k")
+	tc.do("SADD", "s", "x")
+	tc.wantError(errWrongType.Error(), "GETSET", "s", "v")
+}
+
+func TestCopy(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(0, "COPY", "missing", "dst")
+	tc.do("SADD", "src", "a", "b")
+	tc.wantInt(1, "COPY", "src", "dst")
+	tc.do("SADD", "dst", "c")
+	tc.wantStrings(false, []string{"a", "b"}, "SMEMBERS", "src")
+	tc.wantStrings(false, []string{"a", "b", "c"}, "SMEMBERS", "dst")
+
+	tc.do("SET", "str", "v")
+	tc.wantInt(0, "COPY", "str", "dst")
+	tc.wantInt(1, "COPY", "str", "dst", "REPLACE")
+	tc.wantBulk("v", "GET", "dst")
+	tc.wantInt(1, "COPY", "src", "dst", "DB", "0", "REPLACE")
+	tc.wantInt(2, "SCARD", "dst")
+
+	tc.wantError("ERR source and destination objects are the same", "COPY", "src", "src")
+	tc.wantError("ERR DB index is out of range", "COPY", "src", "other", "DB", "1")
+	tc.wantError("ERR value is not an integer or out of range", "COPY", "src", "other", "DB", "x")
+	tc.wantError("ERR syntax error", "COPY", "src", "other", "BOGUS")
+}
+
+func TestTouchAndUnlink(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("SET", "a", "1")
+	tc.do("HSET", "h", "f", "v")
+	tc.wantInt(2, "TOUCH", "a", "h", "missing")
+	tc.wantInt(2, "UNLINK", "a", "h", "missing")
+	tc.wantInt(0, "TOUCH", "a", "h")
+}
+
+func TestDumpRestore(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantNull("DUMP", "missing")
+	tc.do("SET", "str", "v")
+	tc.do("RPUSH", "list", "a", "b", "c")
+	tc.do("SADD", "set", "x", "y")
+	tc.do("HSET", "hash", "f", "1", "g", "2")
+	tc.do("ZADD", "zset", "1.5", "m", "2", "n")
+	for _, key := range []string{"str", "list", "set", "hash", "zset"} {
+		payload := string(tc.do("DUMP", key).Bulk)
+		tc.wantError("BUSYKEY Target key name already exists.", "RESTORE", key, "0", payload)
+		tc.do("RESTORE", key+":copy", "0", payload)
+	}
+	tc.wantBulk("v", "GET", "str:copy")
+	tc.wantStrings(true, []string{"a", "b", "c"}, "LRANGE", "list:copy", "0", "-1")
+	tc.wantStrings(false, []string{"x", "y"}, "SMEMBERS", "set:copy")
+	tc.wantStrings(false, []string{"f", "1", "g", "2"}, "HGETALL", "hash:copy")
+	tc.wantStrings(true, []string{"m", "1.5", "n", "2"}, "ZRANGE", "zset:copy", "0", "-1", "WITHSCORES")
+
+	// The TTL is accepted and dropped: keys never expire.
+	payload := string(tc.do("DUMP", "str").Bulk)
+	tc.do("SET", "str", "other")
+	tc.do("RESTORE", "str", "5000", payload, "REPLACE")
+	tc.wantBulk("v", "GET", "str")
+	tc.wantInt(-1, "PTTL", "str")
+	tc.wantInt(-2, "TTL", "missing")
+
+	tc.wantError("ERR Invalid TTL value, must be >= 0", "RESTORE", "k", "-1", payload)
+	tc.wantError("ERR syntax error", "RESTORE", "k", "0", payload, "BOGUS")
+	corrupt := []byte(payload)
+	corrupt[1] ^= 0xff
+	tc.wantError("ERR DUMP payload version or checksum are wrong", "RESTORE", "k", "0", string(corrupt))
+	tc.wantNull("GET", "k")
+}
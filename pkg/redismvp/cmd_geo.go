@@ -0,0 +1,377 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"slices"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "geoadd", arity: -5, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 1, step: 1,
+			group: "geo", summary: "Adds one or more members to a geospatial index. The key is created if it doesn't exist.", handler: cmdGeoAdd},
+		&command{name: "geopos", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "geo", summary: "Returns the longitude and latitude of members from a geospatial index.", handler: cmdGeoPos},
+		&command{name: "geodist", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "geo", summary: "Returns the distance between two members of a geospatial index.", handler: cmdGeoDist},
+		&command{name: "geohash", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "geo", summary: "Returns members from a geospatial index as geohash strings.", handler: cmdGeoHash},
+		&command{name: "geosearch", arity: -7, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "geo", summary: "Queries a geospatial index for members inside an area of a box or a circle.", handler: cmdGeoSearch},
+		&command{name: "geosearchstore", arity: -8, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 2, step: 1,
+			group: "geo", summary: "Queries a geospatial index for members inside an area of a box or a circle, optionally stores the result.", handler: cmdGeoSearchStore},
+	)
+}
+
+// cmdGeoAdd validates the coordinates and hands the resulting geohash
+// scores to ZADD, which is how Redis implements GEOADD too.
+func cmdGeoAdd(c *clientConn, dst []byte, args [][]byte) []byte {
+	zargs := [][]byte{args[0]}
+	i := 1
+	for ; i < len(args); i++ {
+		if !argIs(args[i], "NX") && !argIs(args[i], "XX") && !argIs(args[i], "CH") {
+			break
+		}
+		zargs = append(zargs, args[i])
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 {
+		return appendSyntaxError(dst)
+	}
+	for j := 0; j < len(triples); j += 3 {
+		p, errReply := parseGeoPoint(triples[j], triples[j+1])
+		if errReply != "" {
+			return appendError(dst, errReply)
+		}
+		score := geohashEncode(p, geoStep, geoLatMin, geoLatMax)
+		zargs = append(zargs, formatScore(float64(score)), triples[j+2])
+	}
+	return cmdZAdd(c, dst, zargs)
+}
+
+func parseGeoPoint(lonArg, latArg []byte) (geoPoint, string) {
+	lon, ok1 := parseFloat(lonArg)
+	lat, ok2 := parseFloat(latArg)
+	if !ok1 || !ok2 {
+		return geoPoint{}, "ERR value is not a valid float"
+	}
+	p := geoPoint{lon: lon, lat: lat}
+	if !validGeoPoint(p) {
+		return p, fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lon, lat)
+	}
+	return p, ""
+}
+
+// geoMember returns the position of member, if present.
+func geoMember(zset *zsetValue, member string) (geoPoint, bool) {
+	score, ok := zset.score(member)
+	if !ok {
+		return geoPoint{}, false
+	}
+	return geohashDecode(uint64(score)), true
+}
+
+func cmdGeoPos(c *clientConn, dst []byte, args [][]byte) []byte {
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(args)-1)
+	for _, m := range args[1:] {
+		p, ok := geoMember(zset, string(m))
+		if !ok {
+			dst = appendNullArray(dst)
+			continue
+		}
+		dst = appendGeoPoint(dst, p)
+	}
+	return dst
+}
+
+func cmdGeoDist(c *clientConn, dst []byte, args [][]byte) []byte {
+	unit := 1.0
+	switch len(args) {
+	case 3:
+	case 4:
+		var ok bool
+		if unit, ok = parseGeoUnit(args[3]); !ok {
+			return appendError(dst, "ERR unsupported unit provided. please use M, KM, FT, MI")
+		}
+	default:
+		return appendSyntaxError(dst)
+	}
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	a, ok1 := geoMember(zset, string(args[1]))
+	b, ok2 := geoMember(zset, string(args[2]))
+	if !ok1 || !ok2 {
+		return appendNull(dst)
+	}
+	return appendGeoDistance(dst, geoDistance(a, b), unit)
+}
+
+func cmdGeoHash(c *clientConn, dst []byte, args [][]byte) []byte {
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(args)-1)
+	for _, m := range args[1:] {
+		score, ok := zset.score(string(m))
+		if !ok {
+			dst = appendNull(dst)
+			continue
+		}
+		dst = appendBulkString(dst, geohashString(uint64(score)))
+	}
+	return dst
+}
+
+// geoSearchArgs holds the parsed GEOSEARCH and GEOSEARCHSTORE options.
+type geoSearchArgs struct {
+	shape      geoShape
+	fromMember []byte
+	fromLonLat bool
+	by         bool
+	// sort is 1 for ASC, -1 for DESC and 0 when unsorted.
+	sort                          int
+	count                         int64
+	any                           bool
+	withCoord, withDist, withHash bool
+	storeDist                     bool
+}
+
+// geoResult is a member found by a search.
+type geoResult struct {
+	member string
+	hash   uint64
+	pos    geoPoint
+	dist   float64
+}
+
+func parseGeoSearchArgs(name string, args [][]byte, store bool) (geoSearchArgs, string) {
+	var g geoSearchArgs
+	for i := 0; i < len(args); i++ {
+		more := len(args) - i - 1
+		switch {
+		case argIs(args[i], "FROMMEMBER") && more >= 1:
+			if g.fromMember != nil || g.fromLonLat {
+				return g, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + name
+			}
+			g.fromMember = args[i+1]
+			i++
+		case argIs(args[i], "FROMLONLAT") && more >= 2:
+			if g.fromMember != nil || g.fromLonLat {
+				return g, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + name
+			}
+			p, errReply := parseGeoPoint(args[i+1], args[i+2])
+			if errReply != "" {
+				return g, errReply
+			}
+			g.shape.center, g.fromLonLat = p, true
+			i += 2
+		case argIs(args[i], "BYRADIUS") && more >= 2:
+			if g.by {
+				return g, "ERR exactly one of BYRADIUS and BYBOX can be specified for " + name
+			}
+			r, ok := parseFloat(args[i+1])
+			if !ok {
+				return g, "ERR need numeric radius"
+			}
+			if r < 0 {
+				return g, "ERR radius cannot be negative"
+			}
+			unit, ok := parseGeoUnit(args[i+2])
+			if !ok {
+				return g, "ERR unsupported unit provided. please use M, KM, FT, MI"
+			}
+			g.shape.radius, g.shape.unit, g.by = r*unit, unit, true
+			i += 2
+		case argIs(args[i], "BYBOX") && more >= 3:
+			if g.by {
+				return g, "ERR exactly one of BYRADIUS and BYBOX can be specified for " + name
+			}
+			w, ok1 := parseFloat(args[i+1])
+			h, ok2 := parseFloat(args[i+2])
+			if !ok1 {
+				return g, "ERR need numeric width"
+			}
+			if !ok2 {
+				return g, "ERR need numeric height"
+			}
+			if w < 0 || h < 0 {
+				return g, "ERR height or width cannot be negative"
+			}
+			unit, ok := parseGeoUnit(args[i+3])
+			if !ok {
+				return g, "ERR unsupported unit provided. please use M, KM, FT, MI"
+			}
+			g.shape.width, g.shape.height, g.shape.unit = w*unit, h*unit, unit
+			g.shape.box, g.by = true, true
+			i += 3
+		case argIs(args[i], "ASC"):
+			g.sort = 1
+		case argIs(args[i], "DESC"):
+			g.sort = -1
+		case argIs(args[i], "COUNT") && more >= 1:
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return g, "ERR value is not an integer or out of range"
+			}
+			if n <= 0 {
+				return g, "ERR COUNT must be > 0"
+			}
+			g.count = n
+			i++
+			if i+1 < len(args) && argIs(args[i+1], "ANY") {
+				g.any = true
+				i++
+			}
+		case !store && argIs(args[i], "WITHCOORD"):
+			g.withCoord = true
+		case !store && argIs(args[i], "WITHDIST"):
+			g.withDist = true
+		case !store && argIs(args[i], "WITHHASH"):
+			g.withHash = true
+		case store && argIs(args[i], "STOREDIST"):
+			g.storeDist = true
+		default:
+			return g, "ERR syntax error"
+		}
+	}
+	switch {
+	case g.fromMember == nil && !g.fromLonLat:
+		return g, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + name
+	case !g.by:
+		return g, "ERR exactly one of BYRADIUS and BYBOX can be specified for " + name
+	}
+	if g.count > 0 && !g.any && g.sort == 0 {
+		// Returning the closest matches is the only useful way to limit.
+		g.sort = 1
+	}
+	return g, ""
+}
+
+// geoSearch runs a parsed search against the sorted set at key. A missing
+// key yields no results.
+func (s *Store) geoSearch(key string, g *geoSearchArgs) ([]geoResult, string) {
+	zset, err := s.lookupZSet(key)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if zset == nil {
+		return nil, ""
+	}
+	if g.fromMember != nil {
+		p, ok := geoMember(zset, string(g.fromMember))
+		if !ok {
+			return nil, "ERR could not decode requested zset member"
+		}
+		g.shape.center = p
+	}
+
+	var results []geoResult
+	for _, r := range g.shape.searchRanges() {
+		start, end := zset.scoreIndexes(scoreRange{min: float64(r[0]), max: float64(r[1]), maxEx: true})
+		for _, e := range zset.order[start:end] {
+			hash := uint64(e.score)
+			p := geohashDecode(hash)
+			d, ok := g.shape.contains(p)
+			if !ok {
+				continue
+			}
+			results = append(results, geoResult{member: e.member, hash: hash, pos: p, dist: d})
+			if g.any && int64(len(results)) == g.count {
+				break
+			}
+		}
+		if g.any && int64(len(results)) == g.count {
+			break
+		}
+	}
+	if g.sort != 0 {
+		slices.SortStableFunc(results, func(a, b geoResult) int {
+			if a.dist == b.dist {
+				return 0
+			}
+			if (a.dist < b.dist) == (g.sort > 0) {
+				return -1
+			}
+			return 1
+		})
+	}
+	if g.count > 0 && int64(len(results)) > g.count {
+		results = results[:g.count]
+	}
+	return results, ""
+}
+
+func cmdGeoSearch(c *clientConn, dst []byte, args [][]byte) []byte {
+	g, errReply := parseGeoSearchArgs("geosearch", args[1:], false)
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	results, errReply := c.server.store.geoSearch(string(args[0]), &g)
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	dst = appendArrayLen(dst, len(results))
+	extra := 0
+	for _, with := range []bool{g.withDist, g.withHash, g.withCoord} {
+		if with {
+			extra++
+		}
+	}
+	for _, r := range results {
+		if extra == 0 {
+			dst = appendBulkString(dst, r.member)
+			continue
+		}
+		dst = appendArrayLen(dst, 1+extra)
+		dst = appendBulkString(dst, r.member)
+		if g.withDist {
+			dst = appendGeoDistance(dst, r.dist, g.shape.unit)
+		}
+		if g.withHash {
+			dst = appendInteger(dst, int64(r.hash))
+		}
+		if g.withCoord {
+			dst = appendGeoPoint(dst, r.pos)
+		}
+	}
+	return dst
+}
+
+func cmdGeoSearchStore(c *clientConn, dst []byte, args [][]byte) []byte {
+	g, errReply := parseGeoSearchArgs("geosearchstore", args[2:], true)
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	store := c.server.store
+	results, errReply := store.geoSearch(string(args[1]), &g)
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	dstKey := string(args[0])
+	store.unlink([]string{dstKey}, c.server.lazyFree.free)
+	if len(results) == 0 {
+		return appendInteger(dst, 0)
+	}
+	zset := newZSet()
+	for _, r := range results {
+		score := float64(r.hash)
+		if g.storeDist {
+			score = r.dist / g.shape.unit
+		}
+		zset.add(r.member, score)
+	}
+	store.kv[dstKey] = zset
+	store.created(dstKey)
+	return appendInteger(dst, int64(len(results)))
+}
@@ -0,0 +1,102 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// The expected values below are the replies of upstream Redis for the
+// examples in its GEO documentation.
+
+func newSicily(t *testing.T) *testClient {
+	tc := newTestClient(t)
+	tc.wantInt(2, "GEOADD", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")
+	return tc
+}
+
+func TestGeoAddAndLookup(t *testing.T) {
+	tc := newSicily(t)
+
+	tc.wantBulk("3479099956230698", "ZSCORE", "Sicily", "Palermo")
+	tc.wantBulk("3479447370796909", "ZSCORE", "Sicily", "Catania")
+	tc.wantStrings(true, []string{"sqc8b49rny0", "sqdtr74hyu0"}, "GEOHASH", "Sicily", "Palermo", "Catania")
+
+	got := tc.do("GEOPOS", "Sicily", "Palermo", "missing")
+	if len(got.Array) != 2 || got.Array[1].Kind != redisproto.KindNull {
+		t.Fatalf("GEOPOS: got %#v", got)
+	}
+	if pos := got.Array[0].Array; string(pos[0].Bulk) != "13.36138933897018433" || string(pos[1].Bulk) != "38.11555639549629859" {
+		t.Fatalf("GEOPOS Palermo: got %q %q", pos[0].Bulk, pos[1].Bulk)
+	}
+
+	tc.wantBulk("166274.1516", "GEODIST", "Sicily", "Palermo", "Catania")
+	tc.wantBulk("166.2742", "GEODIST", "Sicily", "Palermo", "Catania", "km")
+	tc.wantBulk("103.3182", "GEODIST", "Sicily", "Palermo", "Catania", "MI")
+	tc.wantNull("GEODIST", "Sicily", "Palermo", "missing")
+	tc.wantError("ERR unsupported unit provided. please use M, KM, FT, MI", "GEODIST", "Sicily", "Palermo", "Catania", "yd")
+
+	tc.wantInt(0, "GEOADD", "Sicily", "XX", "13.361389", "38.115556", "Rome")
+	tc.wantInt(1, "GEOADD", "Sicily", "CH", "13.5", "38.1", "Palermo")
+	tc.wantError("ERR invalid longitude,latitude pair 13.361389,86.000000", "GEOADD", "Sicily", "13.361389", "86", "x")
+	tc.wantError("ERR value is not a valid float", "GEOADD", "Sicily", "east", "0", "x")
+	tc.wantError("ERR syntax error", "GEOADD", "Sicily", "1", "2", "a", "3")
+}
+
+func TestGeoSearch(t *testing.T) {
+	tc := newSicily(t)
+	tc.do("GEOADD", "Sicily", "12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2")
+
+	tc.wantStrings(true, []string{"Catania", "Palermo"}, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "ASC")
+	tc.wantStrings(true, []string{"edge2", "Palermo", "Catania"}, "GEOSEARCH", "Sicily", "FROMMEMBER", "Catania", "BYRADIUS", "240", "km", "DESC")
+	tc.wantStrings(true, []string{"Catania"}, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "COUNT", "1")
+	tc.wantStrings(true, nil, "GEOSEARCH", "missing", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km")
+
+	got := tc.do("GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "WITHCOORD", "WITHDIST", "WITHHASH")
+	if len(got.Array) != 4 {
+		t.Fatalf("GEOSEARCH BYBOX: got %d results", len(got.Array))
+	}
+	first := got.Array[0].Array
+	if string(first[0].Bulk) != "Catania" || string(first[1].Bulk) != "56.4413" || first[2].Int != 3479447370796909 ||
+		string(first[3].Array[0].Bulk) != "15.08726745843887329" || string(first[3].Array[1].Bulk) != "37.50266842333162032" {
+		t.Fatalf("GEOSEARCH BYBOX first result: got %#v", first)
+	}
+	last := got.Array[3].Array
+	if string(last[0].Bulk) != "edge1" || string(last[1].Bulk) != "279.7405" {
+		t.Fatalf("GEOSEARCH BYBOX last result: got %#v", last)
+	}
+
+	tc.wantInt(2, "GEOSEARCHSTORE", "near", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "STOREDIST")
+	if d, ok := parseFloat(tc.do("ZSCORE", "near", "Catania").Bulk); !ok || math.Abs(d-56.44125787) > 1e-6 {
+		t.Fatalf("STOREDIST score for Catania: got %v", d)
+	}
+	tc.wantInt(0, "GEOSEARCHSTORE", "near", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "m")
+	if reply := tc.do("TYPE", "near"); reply.Str != "none" {
+		t.Fatalf("empty GEOSEARCHSTORE result kept the destination: TYPE = %q", reply.Str)
+	}
+
+	tc.wantError("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for geosearch",
+		"GEOSEARCH", "Sicily", "BYRADIUS", "1", "km", "ASC", "DESC")
+	tc.wantError("ERR exactly one of BYRADIUS and BYBOX can be specified for geosearch",
+		"GEOSEARCH", "Sicily", "FROMMEMBER", "Palermo", "ASC", "WITHDIST", "WITHHASH")
+	tc.wantError("ERR syntax error",
+		"GEOSEARCH", "Sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "1", "km", "ANY")
+	tc.wantError("ERR COUNT must be > 0",
+		"GEOSEARCH", "Sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "1", "km", "COUNT", "0")
+	tc.wantError("ERR could not decode requested zset member",
+		"GEOSEARCH", "Sicily", "FROMMEMBER", "Rome", "BYRADIUS", "1", "km")
+	tc.wantError("ERR syntax error",
+		"GEOSEARCHSTORE", "dst", "Sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "1", "km", "WITHDIST")
+}
+
+func TestGeoSearchWrapsAntimeridian(t *testing.T) {
+	tc := newTestClient(t)
+	tc.do("GEOADD", "g", "179.99", "0", "east", "-179.99", "0", "west")
+	tc.wantStrings(false, []string{"east", "west"}, "GEOSEARCH", "g", "FROMLONLAT", "180", "0", "BYRADIUS", "10", "km")
+}
@@ -0,0 +1,361 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"maps"
+	"math"
+	"strconv"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "hset", arity: -4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Creates or modifies the value of a field in a hash.", handler: cmdHSet},
+		&command{name: "hmset", arity: -4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Sets the values of multiple fields.", handler: cmdHMSet},
+		&command{name: "hsetnx", arity: 4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Sets the value of a field in a hash only when the field doesn't exist.", handler: cmdHSetNX},
+		&command{name: "hget", arity: 3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns the value of a field in a hash.", handler: cmdHGet},
+		&command{name: "hmget", arity: -3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns the values of all fields in a hash.", handler: cmdHMGet},
+		&command{name: "hdel", arity: -3, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Deletes one or more fields and their values from a hash.", handler: cmdHDel},
+		&command{name: "hlen", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns the number of fields in a hash.", handler: cmdHLen},
+		&command{name: "hstrlen", arity: 3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns the length of the value of a field.", handler: cmdHStrLen},
+		&command{name: "hexists", arity: 3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Determines whether a field exists in a hash.", handler: cmdHExists},
+		&command{name: "hkeys", arity: 2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns all fields in a hash.", handler: cmdHKeys},
+		&command{name: "hvals", arity: 2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns all values in a hash.", handler: cmdHVals},
+		&command{name: "hgetall", arity: 2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns all fields and values in a hash.", handler: cmdHGetAll},
+		&command{name: "hincrby", arity: 4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Increments the integer value of a field in a hash by a number.", handler: cmdHIncrBy},
+		&command{name: "hincrbyfloat", arity: 4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Increments the floating point value of a field by a number.", handler: cmdHIncrByFloat},
+		&command{name: "hrandfield", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Returns one or more random fields from a hash.", handler: cmdHRandField},
+		&command{name: "hscan", arity: -3, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "hash", summary: "Iterates over fields and values of a hash.", handler: cmdHScan},
+	)
+}
+
+func cmdHSet(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args)%2 == 0 {
+		return appendWrongArity(dst, "hset")
+	}
+	added, err := c.hashSet(args)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, added)
+}
+
+func cmdHMSet(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args)%2 == 0 {
+		return appendWrongArity(dst, "hmset")
+	}
+	if _, err := c.hashSet(args); err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendSimple(dst, "OK")
+}
+
+// hashSet applies "key field value [field value ...]" and returns the
+// number of fields that did not exist before.
+func (c *clientConn) hashSet(args [][]byte) (int64, error) {
+	hash, err := c.server.store.hashForWrite(string(args[0]))
+	if err != nil {
+		return 0, err
+	}
+	added := int64(0)
+	for i := 1; i+1 < len(args); i += 2 {
+		field := string(args[i])
+		if _, ok := hash[field]; !ok {
+			added++
+		}
+		hash[field] = args[i+1]
+	}
+	return added, nil
+}
+
+func cmdHSetNX(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.hashForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	field := string(args[1])
+	if _, ok := hash[field]; ok {
+		return appendInteger(dst, 0)
+	}
+	hash[field] = args[2]
+	return appendInteger(dst, 1)
+}
+
+func cmdHGet(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	v, ok := hash[string(args[1])]
+	if !ok {
+		return appendNull(dst)
+	}
+	return appendBulk(dst, v)
+}
+
+func cmdHMGet(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(args)-1)
+	for _, field := range args[1:] {
+		if v, ok := hash[string(field)]; ok {
+			dst = appendBulk(dst, v)
+		} else {
+			dst = appendNull(dst)
+		}
+	}
+	return dst
+}
+
+func cmdHDel(c *clientConn, dst []byte, args [][]byte) []byte {
+	key := string(args[0])
+	hash, err := c.server.store.lookupHash(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	deleted := int64(0)
+	for _, field := range args[1:] {
+		if _, ok := hash[string(field)]; ok {
+			delete(hash, string(field))
+			deleted++
+		}
+	}
+	c.server.store.dropIfEmpty(key)
+	return appendInteger(dst, deleted)
+}
+
+func cmdHLen(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(len(hash)))
+}
+
+func cmdHStrLen(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(len(hash[string(args[1])])))
+}
+
+func cmdHExists(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	_, ok := hash[string(args[1])]
+	return appendBool(dst, ok)
+}
+
+func cmdHKeys(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(hash))
+	for field := range hash {
+		dst = appendBulkString(dst, field)
+	}
+	return dst
+}
+
+func cmdHVals(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(hash))
+	for _, v := range hash {
+		dst = appendBulk(dst, v)
+	}
+	return dst
+}
+
+func cmdHGetAll(c *clientConn, dst []byte, args [][]byte) []byte {
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, 2*len(hash))
+	for field, v := range hash {
+		dst = appendBulkString(dst, field)
+		dst = appendBulk(dst, v)
+	}
+	return dst
+}
+
+func cmdHIncrBy(c *clientConn, dst []byte, args [][]byte) []byte {
+	incr, ok := parseInt(args[2])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	hash, err := c.server.store.hashForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	field := string(args[1])
+	cur := int64(0)
+	if raw, exists := hash[field]; exists {
+		cur, ok = parseInt(raw)
+		if !ok {
+			c.server.store.dropIfEmpty(string(args[0]))
+			return appendError(dst, "ERR hash value is not an integer")
+		}
+	}
+	if (incr > 0 && cur > math.MaxInt64-incr) || (incr < 0 && cur < math.MinInt64-incr) {
+		c.server.store.dropIfEmpty(string(args[0]))
+		return appendError(dst, "ERR increment or decrement would overflow")
+	}
+	cur += incr
+	hash[field] = strconv.AppendInt(nil, cur, 10)
+	return appendInteger(dst, cur)
+}
+
+func cmdHIncrByFloat(c *clientConn, dst []byte, args [][]byte) []byte {
+	incr, ok := parseFloat(args[2])
+	if !ok {
+		return appendError(dst, "ERR value is not a valid float")
+	}
+	hash, err := c.server.store.hashForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	field := string(args[1])
+	cur := 0.0
+	if raw, exists := hash[field]; exists {
+		cur, ok = parseFloat(raw)
+		if !ok {
+			c.server.store.dropIfEmpty(string(args[0]))
+			return appendError(dst, "ERR hash value is not a float")
+		}
+	}
+	cur += incr
+	if math.IsNaN(cur) || math.IsInf(cur, 0) {
+		c.server.store.dropIfEmpty(string(args[0]))
+		return appendError(dst, "ERR increment would produce NaN or Infinity")
+	}
+	v := formatFloat(cur)
+	hash[field] = v
+	return appendBulk(dst, v)
+}
+
+// cmdHRandField shares SRANDMEMBER's count semantics: a positive count
+// returns distinct fields, a negative count may repeat them.
+func cmdHRandField(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args) > 3 {
+		return appendSyntaxError(dst)
+	}
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+
+	if len(args) == 1 {
+		if len(hash) == 0 {
+			return appendNull(dst)
+		}
+		return appendBulkString(dst, randomKeys(hash, 1, false)[0])
+	}
+
+	count, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	withValues := false
+	if len(args) == 3 {
+		if !argIs(args[2], "WITHVALUES") {
+			return appendSyntaxError(dst)
+		}
+		withValues = true
+	}
+	if count < -maxRandomRepeat || count > math.MaxInt64/2 {
+		return appendError(dst, "ERR value is out of range")
+	}
+	if count == 0 || len(hash) == 0 {
+		return appendArrayLen(dst, 0)
+	}
+
+	var fields []string
+	switch {
+	case count < 0:
+		fields = randomKeys(hash, int(-count), true)
+	case count >= int64(len(hash)):
+		fields = randomKeys(hash, len(hash), false)
+	default:
+		fields = randomKeys(hash, int(count), false)
+	}
+	if !withValues {
+		return appendStringArray(dst, fields)
+	}
+	dst = appendArrayLen(dst, 2*len(fields))
+	for _, field := range fields {
+		dst = appendBulkString(dst, field)
+		dst = appendBulk(dst, hash[field])
+	}
+	return dst
+}
+
+func cmdHScan(c *clientConn, dst []byte, args [][]byte) []byte {
+	opts, errReply := parseScanArgs(args[1:], "NOVALUES")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	hash, err := c.server.store.lookupHash(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	page, next := c.server.store.scanPage(scanKey{key: string(args[0])}, maps.Keys(hash), func(field string) bool {
+		_, ok := hash[field]
+		return ok
+	}, opts.cursor, opts.count)
+	items := make([]string, 0, 2*len(page))
+	for _, field := range page {
+		if !opts.match(field) {
+			continue
+		}
+		items = append(items, field)
+		if !opts.noValues {
+			items = append(items, string(hash[field]))
+		}
+	}
+	return appendScanReply(dst, next, items)
+}
+
+// parseFloat parses a finite float argument the way Redis does, rejecting
+// NaN and empty strings.
+func parseFloat(arg []byte) (float64, bool) {
+	f, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// formatFloat renders f with the shortest representation that round-trips,
+// like Redis's human-friendly float output.
+func formatFloat(f float64) []byte {
+	return strconv.AppendFloat(nil, f, 'f', -1, 64)
+}
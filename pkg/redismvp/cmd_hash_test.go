@@ -0,0 +1,116 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestHashBasics(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(2, "HSET", "h", "a", "1", "b", "2")
+	tc.wantInt(0, "HSET", "h", "a", "10")
+	tc.wantError("ERR wrong number of arguments for 'hset' command", "HSET", "h", "a", "1", "b")
+	tc.wantBulk("10", "HGET", "h", "a")
+	tc.wantNull("HGET", "h", "missing")
+	tc.wantInt(2, "HLEN", "h")
+	tc.wantInt(2, "HSTRLEN", "h", "a")
+	tc.wantInt(1, "HEXISTS", "h", "b")
+	tc.wantStrings(false, []string{"a", "b"}, "HKEYS", "h")
+	tc.wantStrings(false, []string{"10", "2"}, "HVALS", "h")
+	tc.wantStrings(false, []string{"a", "10", "b", "2"}, "HGETALL", "h")
+
+	got := tc.do("HMGET", "h", "a", "missing")
+	if len(got.Array) != 2 || string(got.Array[0].Bulk) != "10" || got.Array[1].Kind != redisproto.KindNull {
+		t.Fatalf("HMGET: got %#v", got)
+	}
+
+	tc.wantInt(0, "HSETNX", "h", "a", "x")
+	tc.wantInt(1, "HSETNX", "h", "c", "3")
+	tc.wantBulk("10", "HGET", "h", "a")
+
+	tc.wantInt(2, "HDEL", "h", "a", "b", "missing")
+	tc.wantInt(1, "HDEL", "h", "c")
+	if reply := tc.do("TYPE", "h"); reply.Str != "none" {
+		t.Fatalf("empty hash was not removed: TYPE = %q", reply.Str)
+	}
+
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "HGET", "str", "a")
+}
+
+func TestHashIncr(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(5, "HINCRBY", "h", "n", "5")
+	tc.wantInt(2, "HINCRBY", "h", "n", "-3")
+	tc.wantError("ERR value is not an integer or out of range", "HINCRBY", "h", "n", "x")
+	tc.do("HSET", "h", "s", "abc", "max", "9223372036854775807")
+	tc.wantError("ERR hash value is not an integer", "HINCRBY", "h", "s", "1")
+	tc.wantError("ERR increment or decrement would overflow", "HINCRBY", "h", "max", "1")
+
+	tc.wantBulk("10.5", "HINCRBYFLOAT", "h", "f", "10.5")
+	tc.wantBulk("5.25", "HINCRBYFLOAT", "h", "f", "-5.25")
+	tc.wantBulk("7.25", "HINCRBYFLOAT", "h", "n", "5.25")
+	tc.wantError("ERR value is not a valid float", "HINCRBYFLOAT", "h", "f", "x")
+	tc.wantError("ERR hash value is not a float", "HINCRBYFLOAT", "h", "s", "1")
+	tc.wantError("ERR increment would produce NaN or Infinity", "HINCRBYFLOAT", "h", "f", "inf")
+
+	// A failed increment must not leave an empty hash behind.
+	tc.wantError("ERR value is not a valid float", "HINCRBYFLOAT", "fresh", "f", "x")
+	if reply := tc.do("TYPE", "fresh"); reply.Str != "none" {
+		t.Fatalf("failed increment created a key: TYPE = %q", reply.Str)
+	}
+}
+
+func TestHashRandField(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantNull("HRANDFIELD", "missing")
+	tc.wantStrings(true, nil, "HRANDFIELD", "missing", "3")
+	tc.do("HSET", "h", "a", "1", "b", "2", "c", "3")
+
+	tc.wantStrings(false, []string{"a", "b", "c"}, "HRANDFIELD", "h", "10")
+	if got := tc.strings("HRANDFIELD", "h", "-7"); len(got) != 7 {
+		t.Fatalf("HRANDFIELD h -7: got %q", got)
+	}
+
+	got := tc.strings("HRANDFIELD", "h", "2", "WITHVALUES")
+	if len(got) != 4 {
+		t.Fatalf("HRANDFIELD WITHVALUES: got %q", got)
+	}
+	want := map[string]string{"a": "1", "b": "2", "c": "3"}
+	for i := 0; i < len(got); i += 2 {
+		if want[got[i]] != got[i+1] {
+			t.Fatalf("HRANDFIELD WITHVALUES: field %q has value %q", got[i], got[i+1])
+		}
+	}
+	tc.wantError("ERR syntax error", "HRANDFIELD", "h", "2", "WITHSCORES")
+
+	// Repeating counts are capped rather than allocated.
+	for _, count := range []string{"-4611686018427387903", "-9223372036854775807", strconv.Itoa(-maxRandomRepeat - 1)} {
+		tc.wantError("ERR value is out of range", "HRANDFIELD", "h", count)
+		tc.wantError("ERR value is out of range", "HRANDFIELD", "h", count, "WITHVALUES")
+	}
+}
+
+func TestHashScan(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("HSET", "h", "a", "1", "b", "2", "other", "3")
+	items := scanAll(tc, "HSCAN", "h", "MATCH", "?")
+	if len(items) != 4 || !items["a"] || !items["1"] || !items["b"] || !items["2"] {
+		t.Fatalf("HSCAN MATCH ?: got %v", items)
+	}
+	items = scanAll(tc, "HSCAN", "h", "NOVALUES")
+	if len(items) != 3 || items["1"] {
+		t.Fatalf("HSCAN NOVALUES: got %v", items)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"time"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "lpush", arity: -3, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.", handler: cmdLPush},
+		&command{name: "rpush", arity: -3, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.", handler: cmdRPush},
+		&command{name: "lpop", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Returns the first elements in a list after removing it.", handler: cmdLPop},
+		&command{name: "rpop", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Returns and removes the last elements of a list.", handler: cmdRPop},
+		&command{name: "llen", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Returns the length of a list.", handler: cmdLLen},
+		&command{name: "lindex", arity: 3, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Returns an element from a list by its index.", handler: cmdLIndex},
+		&command{name: "lrange", arity: 4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "list", summary: "Returns a range of elements from a list.", handler: cmdLRange},
+		&command{name: "lmpop", arity: -4, flags: []string{flagWrite, flagMovableKeys}, numKeysAt: 1,
+			group:   "list",
+			summary: "Returns multiple elements from a list after removing them.", handler: cmdLMPop},
+		&command{name: "blmpop", arity: -5, flags: []string{flagWrite, flagBlocking, flagMovableKeys}, numKeysAt: 2,
+			group:   "list",
+			summary: "Pops the first element from one of multiple lists. Blocks until an element is available otherwise.", handler: cmdBLMPop},
+	)
+}
+
+func cmdLPush(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.listPush(dst, args, false)
+}
+
+func cmdRPush(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.listPush(dst, args, true)
+}
+
+func (c *clientConn) listPush(dst []byte, args [][]byte, back bool) []byte {
+	list, err := c.server.store.listForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	for _, v := range args[1:] {
+		if back {
+			list.pushBack(v)
+		} else {
+			list.pushFront(v)
+		}
+	}
+	return appendInteger(dst, int64(list.len()))
+}
+
+func cmdLPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.listPop(dst, args, false)
+}
+
+func cmdRPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.listPop(dst, args, true)
+}
+
+// listPop serves LPOP and RPOP. Without a count the reply is a single
+// element; with one it is an array, or a null array for a missing key.
+func (c *clientConn) listPop(dst []byte, args [][]byte, fromBack bool) []byte {
+	if len(args) > 2 {
+		if fromBack {
+			return appendWrongArity(dst, "rpop")
+		}
+		return appendWrongArity(dst, "lpop")
+	}
+	withCount := len(args) == 2
+	count := int64(1)
+	if withCount {
+		n, ok := parseInt(args[1])
+		if !ok || n < 0 {
+			return appendError(dst, "ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	key := string(args[0])
+	list, err := c.server.store.lookupList(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if list == nil {
+		if withCount {
+			return appendNullArray(dst)
+		}
+		return appendNull(dst)
+	}
+	popped := list.pop(int(min(count, math.MaxInt32)), fromBack)
+	c.server.store.dropIfEmpty(key)
+	if !withCount {
+		return appendBulk(dst, popped[0])
+	}
+	return appendBulkArray(dst, popped)
+}
+
+func cmdLLen(c *clientConn, dst []byte, args [][]byte) []byte {
+	list, err := c.server.store.lookupList(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(list.len()))
+}
+
+func cmdLIndex(c *clientConn, dst []byte, args [][]byte) []byte {
+	i, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	list, err := c.server.store.lookupList(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	n := int64(list.len())
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return appendNull(dst)
+	}
+	return appendBulk(dst, list.index(int(i)))
+}
+
+func cmdLRange(c *clientConn, dst []byte, args [][]byte) []byte {
+	start, ok1 := parseInt(args[1])
+	stop, ok2 := parseInt(args[2])
+	if !ok1 || !ok2 {
+		return appendNotInteger(dst)
+	}
+	list, err := c.server.store.lookupList(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	start, stop, ok := normalizeRange(start, stop, int64(list.len()))
+	if !ok {
+		return appendArrayLen(dst, 0)
+	}
+	return appendBulkArray(dst, list.elements(int(start), int(stop)))
+}
+
+func cmdLMPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	m, errReply := parseMPopArgs(args, "LEFT", "RIGHT")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	if out, ok := c.server.store.lmpop(dst, m); ok {
+		return out
+	}
+	return appendNullArray(dst)
+}
+
+func cmdBLMPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	deadline, errReply := parseBlockTimeout(args[0], time.Now())
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	m, errReply := parseMPopArgs(args[1:], "LEFT", "RIGHT")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	return c.serveOrBlock(dst, m.keys, deadline, func(dst []byte) ([]byte, bool) {
+		return c.server.store.lmpop(dst, m)
+	})
+}
+
+// lmpop pops up to m.count elements from the first non-empty list among
+// m.keys and appends the [key, [element, ...]] reply.
+func (s *Store) lmpop(dst []byte, m mpopArgs) ([]byte, bool) {
+	for _, key := range m.keys {
+		list, err := s.lookupList(key)
+		if err != nil {
+			return appendStoreError(dst, err), true
+		}
+		if list.len() == 0 {
+			continue
+		}
+		popped := list.pop(m.count, m.fromBack)
+		s.dropIfEmpty(key)
+		dst = appendArrayLen(dst, 2)
+		dst = appendBulkString(dst, key)
+		return appendBulkArray(dst, popped), true
+	}
+	return dst, false
+}
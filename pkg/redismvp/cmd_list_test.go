@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestListPushPop(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(3, "RPUSH", "l", "b", "c", "d")
+	tc.wantInt(5, "LPUSH", "l", "a", "z")
+	tc.wantStrings(true, []string{"z", "a", "b", "c", "d"}, "LRANGE", "l", "0", "-1")
+	tc.wantStrings(true, []string{"c", "d"}, "LRANGE", "l", "-2", "100")
+	tc.wantStrings(true, nil, "LRANGE", "l", "3", "1")
+	tc.wantBulk("a", "LINDEX", "l", "1")
+	tc.wantBulk("d", "LINDEX", "l", "-1")
+	tc.wantNull("LINDEX", "l", "5")
+	tc.wantInt(5, "LLEN", "l")
+
+	tc.wantBulk("z", "LPOP", "l")
+	tc.wantBulk("d", "RPOP", "l")
+	tc.wantStrings(true, []string{"a", "b"}, "LPOP", "l", "2")
+	tc.wantStrings(true, nil, "RPOP", "l", "0")
+	tc.wantStrings(true, []string{"c"}, "RPOP", "l", "5")
+	if reply := tc.do("TYPE", "l"); reply.Str != "none" {
+		t.Fatalf("empty list was not removed: TYPE = %q", reply.Str)
+	}
+	tc.wantNull("LPOP", "l")
+	if got := tc.do("LPOP", "l", "1"); got.Kind != redisproto.KindNull {
+		t.Fatalf("LPOP with count on missing key: got %#v", got)
+	}
+	tc.wantError("ERR value is out of range, must be positive", "LPOP", "l", "-1")
+	tc.wantInt(0, "LLEN", "l")
+
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "LPUSH", "str", "a")
+	tc.wantError(errWrongType.Error(), "LRANGE", "str", "0", "-1")
+}
+
+func TestListDeque(t *testing.T) {
+	l := newList()
+	for i := range 100 {
+		if i%2 == 0 {
+			l.pushFront([]byte{byte(i)})
+		} else {
+			l.pushBack([]byte{byte(i)})
+		}
+		if i%3 == 0 {
+			l.pop(1, i%2 == 1)
+		}
+	}
+	// Every third push is popped again right away. The surviving even
+	// values are at the front in reverse order, the odd ones at the back.
+	var want []byte
+	for i := 98; i >= 0; i -= 2 {
+		if i%3 != 0 {
+			want = append(want, byte(i))
+		}
+	}
+	for i := 1; i < 100; i += 2 {
+		if i%3 != 0 {
+			want = append(want, byte(i))
+		}
+	}
+	got := l.pop(l.len(), false)
+	if len(got) != len(want) {
+		t.Fatalf("got %d elements, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i][0] != want[i] {
+			t.Fatalf("element %d: got %d, want %d", i, got[i][0], want[i])
+		}
+	}
+}
+
+func TestMultiKeyPop(t *testing.T) {
+	tc := newTestClient(t)
+
+	if got := tc.do("LMPOP", "2", "a", "b", "LEFT"); got.Kind != redisproto.KindNull {
+		t.Fatalf("LMPOP on missing keys: got %#v", got)
+	}
+	tc.do("RPUSH", "b", "1", "2", "3")
+	got := tc.do("LMPOP", "2", "a", "b", "RIGHT", "COUNT", "2")
+	if len(got.Array) != 2 || string(got.Array[0].Bulk) != "b" ||
+		len(got.Array[1].Array) != 2 || string(got.Array[1].Array[0].Bulk) != "3" || string(got.Array[1].Array[1].Bulk) != "2" {
+		t.Fatalf("LMPOP RIGHT COUNT 2: got %#v", got)
+	}
+
+	tc.do("ZADD", "z", "1", "a", "2", "b", "3", "c")
+	got = tc.do("ZMPOP", "1", "z", "MAX", "COUNT", "2")
+	if len(got.Array) != 2 || string(got.Array[0].Bulk) != "z" || len(got.Array[1].Array) != 2 {
+		t.Fatalf("ZMPOP MAX COUNT 2: got %#v", got)
+	}
+	if e := got.Array[1].Array[0]; string(e.Array[0].Bulk) != "c" || string(e.Array[1].Bulk) != "3" {
+		t.Fatalf("ZMPOP first entry: got %#v", e)
+	}
+	got = tc.do("ZMPOP", "2", "missing", "z", "MIN")
+	if e := got.Array[1].Array; len(e) != 1 || string(e[0].Array[0].Bulk) != "a" {
+		t.Fatalf("ZMPOP MIN: got %#v", got)
+	}
+
+	tc.wantError("ERR numkeys should be greater than 0", "LMPOP", "0", "a", "LEFT")
+	tc.wantError("ERR numkeys should be greater than 0", "ZMPOP", "x", "a", "MIN")
+	tc.wantError("ERR syntax error", "LMPOP", "3", "a", "LEFT")
+	tc.wantError("ERR syntax error", "LMPOP", "1", "a", "UP")
+	tc.wantError("ERR syntax error", "ZMPOP", "1", "a", "MIN", "COUNT", "1", "COUNT", "2")
+	tc.wantError("ERR count should be greater than 0", "ZMPOP", "1", "a", "MIN", "COUNT", "0")
+	tc.wantError("ERR timeout is negative", "BLMPOP", "-1", "1", "a", "LEFT")
+
+	tc.do("RPUSH", "l", "x")
+	got = tc.do("BLMPOP", "0", "1", "l", "LEFT")
+	if len(got.Array) != 2 || len(got.Array[1].Array) != 1 || string(got.Array[1].Array[0].Bulk) != "x" {
+		t.Fatalf("BLMPOP on non-empty list: got %#v", got)
+	}
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "LMPOP", "1", "str", "LEFT")
+}
@@ -0,0 +1,188 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strconv"
+	"time"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "replicaof", arity: 3, group: "server",
+			summary: "Configures a server as replica of another, or promotes it to a master.", handler: cmdReplicaOf},
+		&command{name: "slaveof", arity: 3, group: "server",
+			summary: "Sets a Redis server as a replica of another, or promotes it to being a master.", handler: cmdReplicaOf},
+		&command{name: "replconf", arity: -1, group: "server",
+			summary: "An internal command for configuring the replication stream.", handler: cmdReplConf},
+		&command{name: "psync", arity: -3, group: "server",
+			summary: "An internal command used in replication.", handler: cmdPSync},
+		&command{name: "sync", arity: 1, group: "server",
+			summary: "An internal command used in replication.", handler: cmdSync},
+		&command{name: "role", arity: 1, flags: []string{flagFast}, group: "server",
+			summary: "Returns the replication role.", handler: cmdRole},
+		&command{name: "readonly", arity: 1, flags: []string{flagFast}, group: "cluster",
+			summary: "Enables read-only queries for a connection to a Redis Cluster replica node.", handler: cmdReadOnly},
+		&command{name: "readwrite", arity: 1, flags: []string{flagFast}, group: "cluster",
+			summary: "Enables read-write queries for a connection to a Redis Cluster replica node.", handler: cmdReadWrite},
+	)
+}
+
+func cmdReplicaOf(c *clientConn, dst []byte, args [][]byte) []byte {
+	s := c.server
+	if s.failover != nil {
+		return appendError(dst, "ERR REPLICAOF not allowed while failing over.")
+	}
+	if argIs(args[0], "NO") && argIs(args[1], "ONE") {
+		if s.repl.link != nil {
+			s.promote()
+		}
+		return appendSimple(dst, "OK")
+	}
+	port, ok := parseInt(args[1])
+	if !ok || port < 0 || port > 65535 {
+		return appendError(dst, "ERR Invalid master port")
+	}
+	host := string(args[0])
+	if l := s.repl.link; l != nil && l.host == host && l.port == int(port) {
+		return appendSimple(dst, "OK Already connected to specified master")
+	}
+	s.replicaOf(host, int(port))
+	return appendSimple(dst, "OK")
+}
+
+// replicaOf makes the server a replica of host:port. The connection is
+// made by the next poll of the link. Our current ID and offset are kept,
+// so a new master that shares our history can continue from them.
+func (s *Server) replicaOf(host string, port int) {
+	if l := s.repl.link; l != nil {
+		l.close()
+	}
+	s.repl.link = newMasterLink(s, host, port)
+	s.unblockAll("UNBLOCKED force unblock from blocking operation, instance state changed (master -> replica?)")
+	s.log.Info("replica of master", "master", s.repl.link.addr())
+}
+
+// promote turns a replica into a master. The master's history is kept as
+// our secondary ID, so replicas of the old master can resync partially.
+func (s *Server) promote() {
+	s.repl.link.close()
+	s.repl.link = nil
+	s.repl.shiftReplID()
+	s.log.Info("promoted to master", "replid", s.repl.id, "offset", s.repl.offset)
+}
+
+// cmdReplConf handles the options a replica sends during the handshake and
+// its offset acknowledgements, which get no reply. A replica with AOF
+// follows ACK with FACK and the offset its AOF has fsynced.
+func cmdReplConf(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args)%2 != 0 {
+		return appendSyntaxError(dst)
+	}
+	for i := 0; i < len(args); i += 2 {
+		opt, val := args[i], args[i+1]
+		switch {
+		case argIs(opt, "LISTENING-PORT"):
+			port, ok := parseInt(val)
+			if !ok {
+				return appendNotInteger(dst)
+			}
+			if c.replica == nil {
+				c.replica = &replicaInfo{aofAckOffset: -1}
+			}
+			c.replica.listeningPort = int(port)
+		case argIs(opt, "ACK"):
+			offset, ok := parseInt(val)
+			if !ok || c.replica == nil || !c.replica.online {
+				return dst
+			}
+			c.replica.ackOffset = max(c.replica.ackOffset, offset)
+			c.replica.ackTime = time.Now()
+			if rest := args[i+2:]; len(rest) == 2 && argIs(rest[0], "FACK") {
+				if fack, ok := parseInt(rest[1]); ok {
+					c.replica.aofAckOffset = max(c.replica.aofAckOffset, fack)
+				}
+			}
+			return dst
+		case argIs(opt, "CAPA"), argIs(opt, "IP-ADDRESS"):
+		default:
+			return appendError(dst, "ERR Unrecognized REPLCONF option: "+string(opt))
+		}
+	}
+	return appendSimple(dst, "OK")
+}
+
+// cmdPSync starts replication to c. With the FAILOVER option, sent by our
+// master during a failover, we first promote ourselves; the master then
+// continues as our replica from the history we share.
+func cmdPSync(c *clientConn, dst []byte, args [][]byte) []byte {
+	failover := len(args) == 3 && argIs(args[2], "FAILOVER")
+	if len(args) != 2 && !failover {
+		return appendSyntaxError(dst)
+	}
+	if c.replica != nil && c.replica.online {
+		return dst
+	}
+	offset, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	s := c.server
+	if failover {
+		if string(args[0]) != s.repl.id {
+			return appendError(dst, "ERR PSYNC FAILOVER replid must match my replid.")
+		}
+		if s.repl.link != nil {
+			s.log.Info("failover request received", "replid", s.repl.id)
+			s.promote()
+		}
+	}
+	return s.syncReplica(c, dst, string(args[0]), offset, true)
+}
+
+func cmdSync(c *clientConn, dst []byte, _ [][]byte) []byte {
+	if c.replica != nil && c.replica.online {
+		return dst
+	}
+	return c.server.syncReplica(c, dst, "", 0, false)
+}
+
+func cmdRole(c *clientConn, dst []byte, _ [][]byte) []byte {
+	r := &c.server.repl
+	if l := r.link; l != nil {
+		dst = appendArrayLen(dst, 5)
+		dst = appendBulkString(dst, "slave")
+		dst = appendBulkString(dst, l.host)
+		dst = appendInteger(dst, int64(l.port))
+		dst = appendBulkString(dst, l.state.String())
+		return appendInteger(dst, r.offset)
+	}
+	dst = appendArrayLen(dst, 3)
+	dst = appendBulkString(dst, "master")
+	dst = appendInteger(dst, r.offset)
+	dst = appendArrayLen(dst, len(r.replicas))
+	for _, rc := range sortedConns(r.replicas) {
+		host, port := rc.replicaAddr()
+		dst = appendArrayLen(dst, 3)
+		dst = appendBulkString(dst, host)
+		dst = appendBulkString(dst, strconv.Itoa(port))
+		dst = appendBulkString(dst, strconv.FormatInt(rc.replica.ackOffset, 10))
+	}
+	return dst
+}
+
+// cmdReadOnly records that the client accepts reads from a replica. Without
+// cluster mode every replica serves reads anyway, so the flag only lets
+// cluster clients set up their connections.
+func cmdReadOnly(c *clientConn, dst []byte, _ [][]byte) []byte {
+	c.readonlyMode = true
+	return appendSimple(dst, "OK")
+}
+
+func cmdReadWrite(c *clientConn, dst []byte, _ [][]byte) []byte {
+	c.readonlyMode = false
+	return appendSimple(dst, "OK")
+}
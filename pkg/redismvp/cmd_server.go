@@ -0,0 +1,245 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "command", arity: -1, group: "server",
+			summary: "Returns detailed information about all commands.", handler: cmdCommand},
+		&command{name: "object", arity: -2, flags: []string{flagReadonly}, group: "generic",
+			summary: "A container for object introspection commands.", handler: cmdObject},
+	)
+}
+
+// aclCategories derives the ACL categories COMMAND reports from the
+// command's group and flags.
+func (cmd *command) aclCategories() []string {
+	var cats []string
+	switch cmd.group {
+	case "generic":
+		cats = append(cats, "@keyspace")
+	case "sorted-set":
+		cats = append(cats, "@sortedset")
+	case "string", "list", "set", "hash", "geo", "bitmap", "connection":
+		cats = append(cats, "@"+cmd.group)
+	}
+	switch {
+	case slices.Contains(cmd.flags, flagWrite):
+		cats = append(cats, "@write")
+	case slices.Contains(cmd.flags, flagReadonly):
+		cats = append(cats, "@read")
+	}
+	if slices.Contains(cmd.flags, flagFast) {
+		cats = append(cats, "@fast")
+	} else {
+		cats = append(cats, "@slow")
+	}
+	if slices.Contains(cmd.flags, flagBlocking) {
+		cats = append(cats, "@blocking")
+	}
+	return cats
+}
+
+// appendCommandInfo appends the COMMAND INFO entry for cmd in the Redis 7
+// layout. Tips, key specs and subcommands are always empty.
+func appendCommandInfo(dst []byte, cmd *command) []byte {
+	dst = appendArrayLen(dst, 10)
+	dst = appendBulkString(dst, cmd.name)
+	dst = appendInteger(dst, int64(cmd.arity))
+	dst = appendArrayLen(dst, len(cmd.flags))
+	for _, f := range cmd.flags {
+		dst = appendSimple(dst, f)
+	}
+	dst = appendInteger(dst, int64(cmd.firstKey))
+	dst = appendInteger(dst, int64(cmd.lastKey))
+	dst = appendInteger(dst, int64(cmd.step))
+	cats := cmd.aclCategories()
+	dst = appendArrayLen(dst, len(cats))
+	for _, c := range cats {
+		dst = appendSimple(dst, c)
+	}
+	dst = appendArrayLen(dst, 0)
+	dst = appendArrayLen(dst, 0)
+	return appendArrayLen(dst, 0)
+}
+
+// appendCommandDocs appends the COMMAND DOCS map of cmd as a flat array.
+func appendCommandDocs(dst []byte, cmd *command) []byte {
+	dst = appendArrayLen(dst, 4)
+	dst = appendBulkString(dst, "summary")
+	dst = appendBulkString(dst, cmd.summary)
+	dst = appendBulkString(dst, "group")
+	return appendBulkString(dst, cmd.group)
+}
+
+// sortedCommands returns the commands s serves ordered by name, so replies
+// listing every command are stable.
+func (s *Server) sortedCommands() []*command {
+	cmds := slices.Collect(maps.Values(commandTable))
+	if s.extensions {
+		cmds = slices.AppendSeq(cmds, maps.Values(extensionTable))
+	}
+	cmds = slices.DeleteFunc(cmds, func(cmd *command) bool {
+		return s.renames.disables(cmd.name)
+	})
+	slices.SortFunc(cmds, func(a, b *command) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return cmds
+}
+
+// appendHelp appends a subcommand help reply: a header line followed by
+// lines, all as simple strings.
+func appendHelp(dst []byte, name string, lines ...string) []byte {
+	lines = append(lines, "HELP", "    Print this help.")
+	dst = appendArrayLen(dst, len(lines)+1)
+	dst = appendSimple(dst, name+" <subcommand> [<arg> [value] [opt] ...]. Subcommands are:")
+	for _, l := range lines {
+		dst = appendSimple(dst, l)
+	}
+	return dst
+}
+
+func appendUnknownSubcommand(dst []byte, name string, sub []byte) []byte {
+	return appendError(dst, "ERR unknown subcommand '"+string(sub)+"'. Try "+name+" HELP.")
+}
+
+func cmdCommand(c *clientConn, dst []byte, args [][]byte) []byte {
+	s := c.server
+	if len(args) == 0 {
+		cmds := s.sortedCommands()
+		dst = appendArrayLen(dst, len(cmds))
+		for _, cmd := range cmds {
+			dst = appendCommandInfo(dst, cmd)
+		}
+		return dst
+	}
+
+	sub, rest := args[0], args[1:]
+	switch {
+	case argIs(sub, "COUNT") && len(rest) == 0:
+		n := len(commandTable) - s.renames.disabled
+		if s.extensions {
+			n += len(extensionTable)
+		}
+		return appendInteger(dst, int64(n))
+	case argIs(sub, "INFO"):
+		if len(rest) == 0 {
+			return cmdCommand(c, dst, nil)
+		}
+		dst = appendArrayLen(dst, len(rest))
+		for _, name := range rest {
+			if cmd := s.lookupCommand(name); cmd != nil {
+				dst = appendCommandInfo(dst, cmd)
+			} else {
+				dst = appendNullArray(dst)
+			}
+		}
+		return dst
+	case argIs(sub, "DOCS"):
+		var cmds []*command
+		if len(rest) == 0 {
+			cmds = s.sortedCommands()
+		}
+		for _, name := range rest {
+			// Unknown names are left out, as in Redis.
+			if cmd := s.lookupCommand(name); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+		dst = appendArrayLen(dst, 2*len(cmds))
+		for _, cmd := range cmds {
+			dst = appendBulkString(dst, cmd.name)
+			dst = appendCommandDocs(dst, cmd)
+		}
+		return dst
+	case argIs(sub, "HELP") && len(rest) == 0:
+		return appendHelp(dst, "COMMAND",
+			"(no subcommand)",
+			"    Return details about all commands.",
+			"COUNT",
+			"    Return the total number of commands in this server.",
+			"INFO [<command-name> ...]",
+			"    Return details about multiple commands.",
+			"    If no command names are given, details for all commands are returned.",
+			"DOCS [<command-name> ...]",
+			"    Return documentation details about multiple commands.",
+			"    If no command names are given, documentation details for all",
+			"    commands are returned.")
+	case argIs(sub, "COUNT"), argIs(sub, "HELP"):
+		return appendWrongArity(dst, "command|"+strings.ToLower(string(sub)))
+	default:
+		return appendUnknownSubcommand(dst, "COMMAND", sub)
+	}
+}
+
+func cmdObject(c *clientConn, dst []byte, args [][]byte) []byte {
+	sub, rest := args[0], args[1:]
+	switch {
+	case argIs(sub, "ENCODING") && len(rest) == 1:
+		v, ok := c.server.store.kv[string(rest[0])]
+		if !ok {
+			return appendNull(dst)
+		}
+		return appendBulkString(dst, objectEncoding(v))
+	case argIs(sub, "FREQ") && len(rest) == 1:
+		ev := c.server.evict
+		if ev == nil || ev.policy != MaxMemoryAllKeysLFU {
+			return appendError(dst, "ERR An LFU maxmemory policy is not selected, access frequency not tracked. "+
+				"Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+		}
+		freq, ok := ev.frequency(string(rest[0]))
+		if !ok {
+			return appendNull(dst)
+		}
+		return appendInteger(dst, int64(freq))
+	case argIs(sub, "HELP") && len(rest) == 0:
+		return appendHelp(dst, "OBJECT",
+			"ENCODING <key>",
+			"    Return the kind of internal representation used in order to store the value",
+			"    associated with a <key>.",
+			"FREQ <key>",
+			"    Return the access frequency index of the <key>. The returned integer is",
+			"    proportional to the logarithm of the recent access frequency of the key.")
+	case argIs(sub, "ENCODING"), argIs(sub, "FREQ"), argIs(sub, "HELP"):
+		return appendWrongArity(dst, "object|"+strings.ToLower(string(sub)))
+	default:
+		return appendUnknownSubcommand(dst, "OBJECT", sub)
+	}
+}
+
+// objectEncoding names the representation of v after the closest Redis
+// encoding. Strings follow Redis's rules; aggregates always report the
+// encoding Redis uses once a value outgrows its compact form.
+func objectEncoding(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		if len(v) <= 20 {
+			if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				return "int"
+			}
+		}
+		if len(v) <= 44 {
+			return "embstr"
+		}
+		return "raw"
+	case *listValue:
+		return "quicklist"
+	case setValue, hashValue:
+		return "hashtable"
+	case *zsetValue:
+		return "skiplist"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestCommandIntrospection(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(int64(len(commandTable)), "COMMAND", "COUNT")
+	all := tc.do("COMMAND")
+	if len(all.Array) != len(commandTable) {
+		t.Fatalf("COMMAND returned %d entries, want %d", len(all.Array), len(commandTable))
+	}
+
+	got := tc.do("COMMAND", "INFO", "GET", "nosuch", "lmpop")
+	if len(got.Array) != 3 || got.Array[1].Kind != redisproto.KindNull {
+		t.Fatalf("COMMAND INFO: got %#v", got)
+	}
+	get := got.Array[0].Array
+	if len(get) != 10 || string(get[0].Bulk) != "get" || get[1].Int != 2 ||
+		get[3].Int != 1 || get[4].Int != 1 || get[5].Int != 1 {
+		t.Fatalf("COMMAND INFO get: got %#v", get)
+	}
+	var flags, cats []string
+	for _, f := range get[2].Array {
+		flags = append(flags, f.Str)
+	}
+	for _, c := range get[6].Array {
+		cats = append(cats, c.Str)
+	}
+	if len(flags) != 2 || flags[0] != "readonly" || flags[1] != "fast" {
+		t.Fatalf("COMMAND INFO get flags: got %q", flags)
+	}
+	if len(cats) != 3 || cats[0] != "@string" || cats[1] != "@read" || cats[2] != "@fast" {
+		t.Fatalf("COMMAND INFO get categories: got %q", cats)
+	}
+	if lmpop := got.Array[2].Array; lmpop[3].Int != 0 || lmpop[2].Array[1].Str != "movablekeys" {
+		t.Fatalf("COMMAND INFO lmpop: got %#v", lmpop)
+	}
+
+	docs := tc.do("COMMAND", "DOCS", "zadd", "nosuch")
+	if len(docs.Array) != 2 || string(docs.Array[0].Bulk) != "zadd" {
+		t.Fatalf("COMMAND DOCS: got %#v", docs)
+	}
+	fields := docs.Array[1].Array
+	if string(fields[0].Bulk) != "summary" || string(fields[1].Bulk) != commandTable["zadd"].summary ||
+		string(fields[2].Bulk) != "group" || string(fields[3].Bulk) != "sorted-set" {
+		t.Fatalf("COMMAND DOCS zadd: got %#v", fields)
+	}
+
+	if help := tc.do("COMMAND", "HELP"); len(help.Array) == 0 || help.Array[0].Kind != redisproto.KindSimpleString {
+		t.Fatalf("COMMAND HELP: got %#v", help)
+	}
+	tc.wantError("ERR unknown subcommand 'bogus'. Try COMMAND HELP.", "COMMAND", "bogus")
+	tc.wantError("ERR wrong number of arguments for 'command|count' command", "COMMAND", "COUNT", "x")
+}
+
+func TestObject(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("SET", "n", "12345")
+	tc.do("SET", "s", "hello")
+	tc.do("SET", "long", "this string is comfortably longer than forty-four bytes")
+	tc.do("RPUSH", "l", "a")
+	tc.do("ZADD", "z", "1", "a")
+	tc.wantBulk("int", "OBJECT", "ENCODING", "n")
+	tc.wantBulk("embstr", "OBJECT", "ENCODING", "s")
+	tc.wantBulk("raw", "OBJECT", "encoding", "long")
+	tc.wantBulk("quicklist", "OBJECT", "ENCODING", "l")
+	tc.wantBulk("skiplist", "OBJECT", "ENCODING", "z")
+	tc.wantNull("OBJECT", "ENCODING", "missing")
+
+	help := tc.do("OBJECT", "HELP")
+	if len(help.Array) < 2 || help.Array[0].Str != "OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:" {
+		t.Fatalf("OBJECT HELP: got %#v", help)
+	}
+	tc.wantError("ERR unknown subcommand 'refcount'. Try OBJECT HELP.", "OBJECT", "refcount", "n")
+	tc.wantError("ERR wrong number of arguments for 'object|encoding' command", "OBJECT", "ENCODING")
+}
+
+func TestDebugKeyspace(t *testing.T) {
+	tc := newTestClient(t)
+	tc.do("SET", "user:1", "a")
+	tc.do("SET", "user:2", "b")
+	tc.do("RPUSH", "user:list", "x")
+	tc.do("SADD", "other", "m")
+
+	tc.wantError(debugKeyspaceDenied, "DEBUG", "KEYSPACE")
+	tc.c.server.debugKeyspace = true
+
+	keyspace := func(args ...string) keyspaceSummary {
+		t.Helper()
+		got := tc.do(append([]string{"DEBUG", "KEYSPACE"}, args...)...)
+		if got.Kind != redisproto.KindBulkString {
+			t.Fatalf("DEBUG KEYSPACE %q: got %#v", args, got)
+		}
+		var summary keyspaceSummary
+		if err := json.Unmarshal(got.Bulk, &summary); err != nil {
+			t.Fatalf("DEBUG KEYSPACE %q: %v in %s", args, err, got.Bulk)
+		}
+		return summary
+	}
+
+	all := keyspace()
+	want := keyspaceSummary{
+		Keys:  4,
+		Types: map[string]int{"string": 2, "list": 1, "set": 1},
+		Entries: []keyspaceEntry{
+			{Key: "other", Type: "set", TTL: -1},
+			{Key: "user:1", Type: "string", TTL: -1},
+			{Key: "user:2", Type: "string", TTL: -1},
+			{Key: "user:list", Type: "list", TTL: -1},
+		},
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Fatalf("DEBUG KEYSPACE = %+v, want %+v", all, want)
+	}
+
+	users := keyspace("MATCH", "user:*", "COUNT", "1")
+	if users.Keys != 3 || !users.Truncated || len(users.Entries) != 1 || users.Entries[0].Key != "user:1" {
+		t.Fatalf("DEBUG KEYSPACE MATCH user:* COUNT 1 = %+v", users)
+	}
+	users = keyspace("MATCH", "user:*", "COUNT", "2")
+	if users.Keys != 3 || !users.Truncated || len(users.Entries) != 2 || users.Entries[0].Key != "user:1" || users.Entries[1].Key != "user:2" {
+		t.Fatalf("DEBUG KEYSPACE MATCH user:* COUNT 2 = %+v", users)
+	}
+	if none := keyspace("COUNT", "0"); none.Keys != 4 || !none.Truncated || len(none.Entries) != 0 {
+		t.Fatalf("DEBUG KEYSPACE COUNT 0 = %+v", none)
+	}
+	if empty := keyspace("MATCH", "none*"); empty.Keys != 0 || len(empty.Entries) != 0 {
+		t.Fatalf("DEBUG KEYSPACE MATCH none* = %+v", empty)
+	}
+
+	tc.wantError("ERR syntax error", "DEBUG", "KEYSPACE", "MATCH")
+	tc.wantError("ERR value is not an integer or out of range", "DEBUG", "KEYSPACE", "COUNT", "-1")
+}
@@ -0,0 +1,434 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"maps"
+	"math"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "sadd", arity: -3, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Adds one or more members to a set.", handler: cmdSAdd},
+		&command{name: "srem", arity: -3, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Removes one or more members from a set.", handler: cmdSRem},
+		&command{name: "scard", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Returns the number of members in a set.", handler: cmdSCard},
+		&command{name: "smembers", arity: 2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Returns all members of a set.", handler: cmdSMembers},
+		&command{name: "sismember", arity: 3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Determines whether a member belongs to a set.", handler: cmdSIsMember},
+		&command{name: "smismember", arity: -3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Determines whether multiple members belong to a set.", handler: cmdSMIsMember},
+		&command{name: "smove", arity: 4, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 2, step: 1,
+			group: "set", summary: "Moves a member from one set to another.", handler: cmdSMove},
+		&command{name: "spop", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Returns one or more random members from a set after removing them.", handler: cmdSPop},
+		&command{name: "srandmember", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Gets one or multiple random members from a set.", handler: cmdSRandMember},
+		&command{name: "sinter", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Returns the intersect of multiple sets.", handler: cmdSInter},
+		&command{name: "sintercard", arity: -3, flags: []string{flagReadonly, flagMovableKeys}, numKeysAt: 1,
+			group:   "set",
+			summary: "Returns the number of members of the intersect of multiple sets.", handler: cmdSInterCard},
+		&command{name: "sinterstore", arity: -3, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Stores the intersect of multiple sets in a key.", handler: cmdSInterStore},
+		&command{name: "sunion", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Returns the union of multiple sets.", handler: cmdSUnion},
+		&command{name: "sunionstore", arity: -3, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Stores the union of multiple sets in a key.", handler: cmdSUnionStore},
+		&command{name: "sdiff", arity: -2, flags: []string{flagReadonly}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Returns the difference of multiple sets.", handler: cmdSDiff},
+		&command{name: "sdiffstore", arity: -3, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: -1, step: 1,
+			group: "set", summary: "Stores the difference of multiple sets in a key.", handler: cmdSDiffStore},
+		&command{name: "sscan", arity: -3, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "set", summary: "Iterates over members of a set.", handler: cmdSScan},
+	)
+}
+
+func cmdSAdd(c *clientConn, dst []byte, args [][]byte) []byte {
+	set, err := c.server.store.setForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	added := int64(0)
+	for _, m := range args[1:] {
+		if _, ok := set[string(m)]; !ok {
+			set[string(m)] = struct{}{}
+			added++
+		}
+	}
+	return appendInteger(dst, added)
+}
+
+func cmdSRem(c *clientConn, dst []byte, args [][]byte) []byte {
+	key := string(args[0])
+	set, err := c.server.store.lookupSet(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	removed := int64(0)
+	for _, m := range args[1:] {
+		if _, ok := set[string(m)]; ok {
+			delete(set, string(m))
+			removed++
+		}
+	}
+	c.server.store.dropIfEmpty(key)
+	return appendInteger(dst, removed)
+}
+
+func cmdSCard(c *clientConn, dst []byte, args [][]byte) []byte {
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(len(set)))
+}
+
+func cmdSMembers(c *clientConn, dst []byte, args [][]byte) []byte {
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendStringArray(dst, set.members())
+}
+
+func cmdSIsMember(c *clientConn, dst []byte, args [][]byte) []byte {
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendBool(dst, set.has(string(args[1])))
+}
+
+func cmdSMIsMember(c *clientConn, dst []byte, args [][]byte) []byte {
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(args)-1)
+	for _, m := range args[1:] {
+		dst = appendBool(dst, set.has(string(m)))
+	}
+	return dst
+}
+
+func cmdSMove(c *clientConn, dst []byte, args [][]byte) []byte {
+	store := c.server.store
+	srcKey, dstKey, member := string(args[0]), string(args[1]), string(args[2])
+	src, err := store.lookupSet(srcKey)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	target, err := store.lookupSet(dstKey)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if !src.has(member) {
+		return appendInteger(dst, 0)
+	}
+	if srcKey == dstKey {
+		return appendInteger(dst, 1)
+	}
+	delete(src, member)
+	store.dropIfEmpty(srcKey)
+	if target == nil {
+		target = make(setValue)
+		store.kv[dstKey] = target
+	}
+	target[member] = struct{}{}
+	return appendInteger(dst, 1)
+}
+
+func cmdSPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args) > 2 {
+		return appendSyntaxError(dst)
+	}
+	store := c.server.store
+	key := string(args[0])
+	set, err := store.lookupSet(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+
+	if len(args) == 1 {
+		if len(set) == 0 {
+			return appendNull(dst)
+		}
+		m := randomKeys(set, 1, false)[0]
+		delete(set, m)
+		store.dropIfEmpty(key)
+		c.propagateAs([]byte("SREM"), args[0], []byte(m))
+		return appendBulkString(dst, m)
+	}
+
+	count, ok := parseInt(args[1])
+	if !ok || count < 0 {
+		return appendError(dst, "ERR value is out of range, must be positive")
+	}
+	var members []string
+	if count >= int64(len(set)) {
+		members = set.members()
+		delete(store.kv, key)
+	} else {
+		members = randomKeys(set, int(count), false)
+		for _, m := range members {
+			delete(set, m)
+		}
+	}
+	// Replicas must remove the same members, not random ones.
+	if len(members) > 0 {
+		srem := [][]byte{[]byte("SREM"), args[0]}
+		for _, m := range members {
+			srem = append(srem, []byte(m))
+		}
+		c.propagateAs(srem...)
+	}
+	return appendStringArray(dst, members)
+}
+
+// cmdSRandMember follows Redis count semantics: a positive count returns
+// up to count distinct members, a negative count returns exactly -count
+// members that may repeat, up to maxRandomRepeat.
+func cmdSRandMember(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args) > 2 {
+		return appendSyntaxError(dst)
+	}
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+
+	if len(args) == 1 {
+		if len(set) == 0 {
+			return appendNull(dst)
+		}
+		return appendBulkString(dst, randomKeys(set, 1, false)[0])
+	}
+
+	count, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	if count < -maxRandomRepeat || count > math.MaxInt64/2 {
+		return appendError(dst, "ERR value is out of range")
+	}
+	if count == 0 || len(set) == 0 {
+		return appendArrayLen(dst, 0)
+	}
+	if count < 0 {
+		return appendStringArray(dst, randomKeys(set, int(-count), true))
+	}
+	if count >= int64(len(set)) {
+		return appendStringArray(dst, set.members())
+	}
+	return appendStringArray(dst, randomKeys(set, int(count), false))
+}
+
+func cmdSInter(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendStringArray(dst, intersectSets(sets, 0).members())
+}
+
+func cmdSInterCard(c *clientConn, dst []byte, args [][]byte) []byte {
+	numKeys, ok := parseInt(args[0])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	if numKeys <= 0 {
+		return appendError(dst, "ERR numkeys should be greater than 0")
+	}
+	if numKeys > int64(len(args)-1) {
+		return appendError(dst, "ERR Number of keys can't be greater than number of args")
+	}
+	keys := args[1 : 1+numKeys]
+	limit := int64(0)
+	for rest := args[1+numKeys:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 || !argIs(rest[0], "LIMIT") {
+			return appendSyntaxError(dst)
+		}
+		n, ok := parseInt(rest[1])
+		if !ok {
+			return appendNotInteger(dst)
+		}
+		if n < 0 {
+			return appendError(dst, "ERR LIMIT can't be negative")
+		}
+		limit = n
+	}
+
+	sets, err := c.server.store.lookupSets(keyStrings(keys))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(len(intersectSets(sets, int(limit)))))
+}
+
+func cmdSInterStore(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args[1:]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return c.storeSetResult(dst, string(args[0]), intersectSets(sets, 0))
+}
+
+func cmdSUnion(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendStringArray(dst, unionSets(sets).members())
+}
+
+func cmdSUnionStore(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args[1:]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return c.storeSetResult(dst, string(args[0]), unionSets(sets))
+}
+
+func cmdSDiff(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendStringArray(dst, diffSets(sets).members())
+}
+
+func cmdSDiffStore(c *clientConn, dst []byte, args [][]byte) []byte {
+	sets, err := c.server.store.lookupSets(keyStrings(args[1:]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return c.storeSetResult(dst, string(args[0]), diffSets(sets))
+}
+
+func cmdSScan(c *clientConn, dst []byte, args [][]byte) []byte {
+	opts, errReply := parseScanArgs(args[1:])
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	set, err := c.server.store.lookupSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	page, next := c.server.store.scanPage(scanKey{key: string(args[0])}, maps.Keys(set), func(m string) bool {
+		_, ok := set[m]
+		return ok
+	}, opts.cursor, opts.count)
+	members := page[:0]
+	for _, m := range page {
+		if opts.match(m) {
+			members = append(members, m)
+		}
+	}
+	return appendScanReply(dst, next, members)
+}
+
+// storeSetResult replaces key with result, whatever type key held before,
+// and replies with the cardinality of result. An empty result deletes key.
+func (c *clientConn) storeSetResult(dst []byte, key string, result setValue) []byte {
+	if len(result) == 0 {
+		delete(c.server.store.kv, key)
+	} else {
+		c.server.store.kv[key] = result
+	}
+	return appendInteger(dst, int64(len(result)))
+}
+
+// lookupSets returns the sets at keys in order. Missing keys yield nil sets,
+// which behave as empty sets.
+func (s *Store) lookupSets(keys []string) ([]setValue, error) {
+	sets := make([]setValue, len(keys))
+	for i, key := range keys {
+		set, err := s.lookupSet(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+func (set setValue) has(member string) bool {
+	_, ok := set[member]
+	return ok
+}
+
+func (set setValue) members() []string {
+	out := make([]string, 0, len(set))
+	for m := range set {
+		out = append(out, m)
+	}
+	return out
+}
+
+// intersectSets returns the members present in every set. A positive limit
+// stops the intersection once it has that many members.
+func intersectSets(sets []setValue, limit int) setValue {
+	result := make(setValue)
+	if len(sets) == 0 {
+		return result
+	}
+	smallest := sets[0]
+	for _, set := range sets[1:] {
+		if len(set) < len(smallest) {
+			smallest = set
+		}
+	}
+	for m := range smallest {
+		inAll := true
+		for _, set := range sets {
+			if !set.has(m) {
+				inAll = false
+				break
+			}
+		}
+		if !inAll {
+			continue
+		}
+		result[m] = struct{}{}
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+func unionSets(sets []setValue) setValue {
+	result := make(setValue)
+	for _, set := range sets {
+		for m := range set {
+			result[m] = struct{}{}
+		}
+	}
+	return result
+}
+
+// diffSets returns the members of the first set that are in none of the
+// others.
+func diffSets(sets []setValue) setValue {
+	result := make(setValue)
+	for m := range sets[0] {
+		found := false
+		for _, set := range sets[1:] {
+			if set.has(m) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result[m] = struct{}{}
+		}
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSetBasics(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(3, "SADD", "s", "a", "b", "c")
+	tc.wantInt(1, "SADD", "s", "a", "d")
+	tc.wantInt(4, "SCARD", "s")
+	tc.wantStrings(false, []string{"a", "b", "c", "d"}, "SMEMBERS", "s")
+	tc.wantInt(1, "SISMEMBER", "s", "a")
+	tc.wantInt(0, "SISMEMBER", "s", "z")
+	tc.wantInt(0, "SISMEMBER", "missing", "a")
+
+	got := tc.do("SMISMEMBER", "s", "a", "z", "d")
+	if len(got.Array) != 3 || got.Array[0].Int != 1 || got.Array[1].Int != 0 || got.Array[2].Int != 1 {
+		t.Fatalf("SMISMEMBER: got %#v", got)
+	}
+
+	tc.wantInt(2, "SREM", "s", "a", "b", "z")
+	tc.wantInt(2, "SREM", "s", "c", "d")
+	tc.wantInt(0, "SCARD", "s")
+}
+
+func TestSetAlgebra(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("SADD", "a", "1", "2", "3", "4")
+	tc.do("SADD", "b", "3", "4", "5")
+	tc.do("SADD", "c", "4", "5", "6")
+
+	tc.wantStrings(false, []string{"4"}, "SINTER", "a", "b", "c")
+	tc.wantStrings(false, nil, "SINTER", "a", "missing")
+	tc.wantStrings(false, []string{"1", "2", "3", "4", "5", "6"}, "SUNION", "a", "b", "c")
+	tc.wantStrings(false, []string{"1", "2"}, "SDIFF", "a", "b", "c")
+	tc.wantStrings(false, []string{"1", "2", "3", "4"}, "SDIFF", "a", "missing")
+
+	tc.wantInt(2, "SINTERSTORE", "dst", "a", "b")
+	tc.wantStrings(false, []string{"3", "4"}, "SMEMBERS", "dst")
+	tc.wantInt(5, "SUNIONSTORE", "dst", "a", "b")
+	tc.wantInt(2, "SDIFFSTORE", "dst", "a", "b")
+	tc.wantStrings(false, []string{"1", "2"}, "SMEMBERS", "dst")
+
+	// Storing an empty result removes the destination, whatever its type.
+	tc.do("SET", "str", "v")
+	tc.wantInt(0, "SINTERSTORE", "str", "a", "missing")
+	tc.wantNull("GET", "str")
+
+	tc.wantInt(1, "SINTERCARD", "2", "a", "c")
+	tc.wantInt(2, "SINTERCARD", "2", "a", "b")
+	tc.wantInt(1, "SINTERCARD", "2", "a", "b", "LIMIT", "1")
+	tc.wantInt(2, "SINTERCARD", "2", "a", "b", "limit", "0")
+	tc.wantError("ERR numkeys should be greater than 0", "SINTERCARD", "0", "a")
+	tc.wantError("ERR Number of keys can't be greater than number of args", "SINTERCARD", "3", "a", "b")
+	tc.wantError("ERR LIMIT can't be negative", "SINTERCARD", "1", "a", "LIMIT", "-1")
+	tc.wantError("ERR syntax error", "SINTERCARD", "1", "a", "LIMIT")
+
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "SINTER", "a", "str")
+	tc.wantError(errWrongType.Error(), "SUNIONSTORE", "dst", "a", "str")
+}
+
+func TestSetMove(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("SADD", "src", "a", "b")
+	tc.wantInt(1, "SMOVE", "src", "dst", "a")
+	tc.wantInt(0, "SMOVE", "src", "dst", "a")
+	tc.wantInt(1, "SMOVE", "src", "src", "b")
+	tc.wantInt(1, "SMOVE", "src", "dst", "b")
+	tc.wantInt(0, "SCARD", "src")
+	tc.wantStrings(false, []string{"a", "b"}, "SMEMBERS", "dst")
+
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "SMOVE", "dst", "str", "a")
+	tc.wantInt(2, "SCARD", "dst")
+}
+
+func TestSetRandomMembers(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantNull("SRANDMEMBER", "missing")
+	tc.wantStrings(true, nil, "SRANDMEMBER", "missing", "5")
+	tc.wantStrings(true, nil, "SRANDMEMBER", "missing", "-5")
+
+	members := []string{"a", "b", "c", "d", "e"}
+	tc.do(append([]string{"SADD", "s"}, members...)...)
+	inSet := map[string]bool{}
+	for _, m := range members {
+		inSet[m] = true
+	}
+
+	tc.wantStrings(true, nil, "SRANDMEMBER", "s", "0")
+	tc.wantStrings(false, members, "SRANDMEMBER", "s", "10")
+
+	got := tc.strings("SRANDMEMBER", "s", "3")
+	seen := map[string]bool{}
+	for _, m := range got {
+		if !inSet[m] || seen[m] {
+			t.Fatalf("SRANDMEMBER s 3: invalid or repeated member in %q", got)
+		}
+		seen[m] = true
+	}
+	if len(got) != 3 {
+		t.Fatalf("SRANDMEMBER s 3: got %d members", len(got))
+	}
+
+	// A negative count may repeat members and always returns -count items.
+	got = tc.strings("SRANDMEMBER", "s", "-20")
+	if len(got) != 20 {
+		t.Fatalf("SRANDMEMBER s -20: got %d members", len(got))
+	}
+	for _, m := range got {
+		if !inSet[m] {
+			t.Fatalf("SRANDMEMBER s -20: unexpected member %q", m)
+		}
+	}
+
+	tc.wantError("ERR value is not an integer or out of range", "SRANDMEMBER", "s", "x")
+	tc.wantError("ERR value is out of range", "SRANDMEMBER", "s", "-9223372036854775807")
+	tc.wantError("ERR value is out of range", "SRANDMEMBER", "s", "-4611686018427387903")
+	tc.wantError("ERR value is out of range", "SRANDMEMBER", "s", strconv.Itoa(-maxRandomRepeat-1))
+	if got := tc.strings("SRANDMEMBER", "s", strconv.Itoa(-maxRandomRepeat)); len(got) != maxRandomRepeat {
+		t.Fatalf("SRANDMEMBER s -%d: got %d members", maxRandomRepeat, len(got))
+	}
+	tc.wantInt(5, "SCARD", "s")
+}
+
+func TestSetPop(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantNull("SPOP", "missing")
+	tc.do("SADD", "s", "a", "b", "c", "d")
+
+	got := tc.do("SPOP", "s")
+	tc.wantInt(0, "SISMEMBER", "s", string(got.Bulk))
+	tc.wantInt(3, "SCARD", "s")
+
+	popped := tc.strings("SPOP", "s", "2")
+	if len(popped) != 2 {
+		t.Fatalf("SPOP s 2: got %q", popped)
+	}
+	tc.wantInt(1, "SCARD", "s")
+	if rest := tc.strings("SPOP", "s", "5"); len(rest) != 1 {
+		t.Fatalf("SPOP s 5: got %q", rest)
+	}
+	tc.wantInt(0, "SCARD", "s")
+
+	tc.wantError("ERR value is out of range, must be positive", "SPOP", "s", "-1")
+}
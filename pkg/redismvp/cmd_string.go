@@ -0,0 +1,205 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+// redisVersion is the Redis version whose behavior the server follows, as
+// reported by HELLO.
+const redisVersion = "7.2.0"
+
+// maxStringSize caps the length strings can be grown to by SETRANGE and
+// BITFIELD, matching Redis's default proto-max-bulk-len of 512MB.
+const maxStringSize = 512 << 20
+
+func init() {
+	registerCommands(
+		&command{name: "ping", arity: -1, flags: []string{flagFast}, group: "connection",
+			summary: "Returns the server's liveliness response.", handler: cmdPing},
+		&command{name: "echo", arity: 2, flags: []string{flagFast}, group: "connection",
+			summary: "Returns the given string.", handler: cmdEcho},
+		&command{name: "hello", arity: -1, flags: []string{flagFast}, group: "connection",
+			summary: "Handshakes with the Redis server.", handler: cmdHello},
+		&command{name: "select", arity: 2, flags: []string{flagFast}, group: "connection",
+			summary: "Changes the selected database.", handler: cmdSelect},
+		&command{name: "quit", arity: -1, flags: []string{flagFast}, group: "connection",
+			summary: "Closes the connection.", handler: cmdQuit},
+		&command{name: "set", arity: 3, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Sets the string value of a key.", handler: cmdSet},
+		&command{name: "get", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Returns the string value of a key.", handler: cmdGet},
+		&command{name: "getset", arity: 3, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Returns the previous string value of a key after setting it to a new value.", handler: cmdGetSet},
+		&command{name: "incr", arity: 2, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Increments the integer value of a key by one.", handler: cmdIncr},
+		&command{name: "setrange", arity: 4, flags: []string{flagWrite, flagDenyOOM}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.", handler: cmdSetRange},
+		&command{name: "getrange", arity: 4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "string", summary: "Returns a substring of the string stored at a key.", handler: cmdGetRange},
+	)
+}
+
+func cmdPing(_ *clientConn, dst []byte, args [][]byte) []byte {
+	switch len(args) {
+	case 0:
+		return appendSimple(dst, "PONG")
+	case 1:
+		return appendBulk(dst, args[0])
+	default:
+		return appendWrongArity(dst, "ping")
+	}
+}
+
+func cmdEcho(_ *clientConn, dst []byte, args [][]byte) []byte {
+	return appendBulk(dst, args[0])
+}
+
+// cmdHello negotiates the protocol version of the connection and describes
+// the server. AUTH and SETNAME are not supported.
+func cmdHello(c *clientConn, dst []byte, args [][]byte) []byte {
+	if len(args) > 1 {
+		return appendError(dst, "ERR Syntax error in HELLO option '"+string(args[1])+"'")
+	}
+	if len(args) == 1 && c.codec != nil {
+		if err := c.codec.Negotiate(args[0]); err != nil {
+			return appendError(dst, err.Error())
+		}
+	}
+	proto, role := 2, "master"
+	if c.codec != nil {
+		proto = c.codec.Version()
+	}
+	if c.server.repl.link != nil {
+		role = "replica"
+	}
+	dst = c.appendMapLen(dst, 7)
+	dst = appendBulkString(dst, "server")
+	dst = appendBulkString(dst, "redis")
+	dst = appendBulkString(dst, "version")
+	dst = appendBulkString(dst, redisVersion)
+	dst = appendBulkString(dst, "proto")
+	dst = appendInteger(dst, int64(proto))
+	dst = appendBulkString(dst, "id")
+	dst = appendInteger(dst, int64(c.id))
+	dst = appendBulkString(dst, "mode")
+	dst = appendBulkString(dst, "standalone")
+	dst = appendBulkString(dst, "role")
+	dst = appendBulkString(dst, role)
+	dst = appendBulkString(dst, "modules")
+	return appendArrayLen(dst, 0)
+}
+
+// cmdSelect accepts the index of a configured database. The server has a
+// single keyspace, so that is only ever database 0.
+func cmdSelect(c *clientConn, dst []byte, args [][]byte) []byte {
+	idx, ok := parseInt(args[0])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	if idx < 0 || idx >= int64(max(c.server.databases, 1)) {
+		return appendError(dst, "ERR DB index is out of range")
+	}
+	return appendSimple(dst, "OK")
+}
+
+// cmdQuit replies OK; the connection is closed once the reply is written
+// and later pipelined commands are dropped, as Redis does.
+func cmdQuit(c *clientConn, dst []byte, _ [][]byte) []byte {
+	c.quit = true
+	return appendSimple(dst, "OK")
+}
+
+func cmdSet(c *clientConn, dst []byte, args [][]byte) []byte {
+	c.server.store.kv[string(args[0])] = args[1]
+	return appendSimple(dst, "OK")
+}
+
+func cmdGet(c *clientConn, dst []byte, args [][]byte) []byte {
+	v, ok, err := c.server.store.lookupString(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if !ok {
+		return appendNull(dst)
+	}
+	return appendBulk(dst, v)
+}
+
+func cmdGetSet(c *clientConn, dst []byte, args [][]byte) []byte {
+	key := string(args[0])
+	old, ok, err := c.server.store.lookupString(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	c.server.store.kv[key] = args[1]
+	if !ok {
+		return appendNull(dst)
+	}
+	return appendBulk(dst, old)
+}
+
+func cmdIncr(c *clientConn, dst []byte, args [][]byte) []byte {
+	n, err := c.server.store.incr(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, n)
+}
+
+func cmdSetRange(c *clientConn, dst []byte, args [][]byte) []byte {
+	offset, ok := parseInt(args[1])
+	if !ok {
+		return appendNotInteger(dst)
+	}
+	if offset < 0 {
+		return appendError(dst, "ERR offset is out of range")
+	}
+	key, value := string(args[0]), args[2]
+	store := c.server.store
+	if len(value) == 0 {
+		// Nothing to write: report the length without creating the key.
+		cur, _, err := store.lookupString(key)
+		if err != nil {
+			return appendStoreError(dst, err)
+		}
+		return appendInteger(dst, int64(len(cur)))
+	}
+	// Compared this way round, a huge offset cannot overflow the sum.
+	if offset > maxStringSize-int64(len(value)) {
+		return appendError(dst, "ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+	buf, err := store.growString(key, int(offset)+len(value))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	copy(buf[offset:], value)
+	return appendInteger(dst, int64(len(buf)))
+}
+
+func cmdGetRange(c *clientConn, dst []byte, args [][]byte) []byte {
+	start, ok1 := parseInt(args[1])
+	end, ok2 := parseInt(args[2])
+	if !ok1 || !ok2 {
+		return appendNotInteger(dst)
+	}
+	v, _, err := c.server.store.lookupString(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	n := int64(len(v))
+	if start < 0 && end < 0 && start > end {
+		return appendBulk(dst, nil)
+	}
+	if start < 0 {
+		start = max(start+n, 0)
+	}
+	if end < 0 {
+		end = max(end+n, 0)
+	}
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return appendBulk(dst, nil)
+	}
+	return appendBulk(dst, v[start:end+1])
+}
@@ -0,0 +1,684 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"maps"
+	"math"
+	"time"
+)
+
+func init() {
+	registerCommands(
+		&command{name: "zadd", arity: -4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Adds one or more members to a sorted set, or updates their scores.", handler: cmdZAdd},
+		&command{name: "zincrby", arity: 4, flags: []string{flagWrite, flagDenyOOM, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Increments the score of a member in a sorted set.", handler: cmdZIncrBy},
+		&command{name: "zrem", arity: -3, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Removes one or more members from a sorted set.", handler: cmdZRem},
+		&command{name: "zcard", arity: 2, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the number of members in a sorted set.", handler: cmdZCard},
+		&command{name: "zscore", arity: 3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the score of a member in a sorted set.", handler: cmdZScore},
+		&command{name: "zmscore", arity: -3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the score of one or more members in a sorted set.", handler: cmdZMScore},
+		&command{name: "zrank", arity: -3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the index of a member in a sorted set ordered by ascending scores.", handler: cmdZRank},
+		&command{name: "zrevrank", arity: -3, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the index of a member in a sorted set ordered by descending scores.", handler: cmdZRevRank},
+		&command{name: "zcount", arity: 4, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the count of members in a sorted set that have scores within a range.", handler: cmdZCount},
+		&command{name: "zlexcount", arity: 4, flags: []string{flagReadonly, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the number of members in a sorted set within a lexicographical range.", handler: cmdZLexCount},
+		&command{name: "zrange", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a range of indexes, scores or names.", handler: cmdZRange},
+		&command{name: "zrevrange", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a range of indexes, in reverse order.", handler: cmdZRevRange},
+		&command{name: "zrangebyscore", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a range of scores.", handler: cmdZRangeByScore},
+		&command{name: "zrevrangebyscore", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a range of scores, in reverse order.", handler: cmdZRevRangeByScore},
+		&command{name: "zrangebylex", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a lexicographical range.", handler: cmdZRangeByLex},
+		&command{name: "zrevrangebylex", arity: -4, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns members in a sorted set within a lexicographical range, in reverse order.", handler: cmdZRevRangeByLex},
+		&command{name: "zpopmin", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the lowest-scoring members from a sorted set after removing them.", handler: cmdZPopMin},
+		&command{name: "zpopmax", arity: -2, flags: []string{flagWrite, flagFast}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Returns the highest-scoring members from a sorted set after removing them.", handler: cmdZPopMax},
+		&command{name: "bzpopmin", arity: -3, flags: []string{flagWrite, flagFast, flagBlocking}, firstKey: 1, lastKey: -2, step: 1,
+			group: "sorted-set", summary: "Removes and returns the member with the lowest score from one or more sorted sets, blocking until one is available.", handler: cmdBZPopMin},
+		&command{name: "bzpopmax", arity: -3, flags: []string{flagWrite, flagFast, flagBlocking}, firstKey: 1, lastKey: -2, step: 1,
+			group: "sorted-set", summary: "Removes and returns the member with the highest score from one or more sorted sets, blocking until one is available.", handler: cmdBZPopMax},
+		&command{name: "zmpop", arity: -4, flags: []string{flagWrite, flagMovableKeys}, numKeysAt: 1,
+			group:   "sorted-set",
+			summary: "Returns the highest- or lowest-scoring members from one or more sorted sets after removing them.", handler: cmdZMPop},
+		&command{name: "bzmpop", arity: -5, flags: []string{flagWrite, flagBlocking, flagMovableKeys}, numKeysAt: 2,
+			group:   "sorted-set",
+			summary: "Removes and returns a member by score from one or more sorted sets, blocking until one is available.", handler: cmdBZMPop},
+		&command{name: "zscan", arity: -3, flags: []string{flagReadonly}, firstKey: 1, lastKey: 1, step: 1,
+			group: "sorted-set", summary: "Iterates over members and scores of a sorted set.", handler: cmdZScan},
+	)
+}
+
+// zaddFlags are the ZADD options.
+type zaddFlags struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+func cmdZAdd(c *clientConn, dst []byte, args [][]byte) []byte {
+	key := string(args[0])
+	var f zaddFlags
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "NX"):
+			f.nx = true
+		case argIs(args[i], "XX"):
+			f.xx = true
+		case argIs(args[i], "GT"):
+			f.gt = true
+		case argIs(args[i], "LT"):
+			f.lt = true
+		case argIs(args[i], "CH"):
+			f.ch = true
+		case argIs(args[i], "INCR"):
+			f.incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return appendSyntaxError(dst)
+	}
+	if f.nx && f.xx {
+		return appendError(dst, "ERR XX and NX options at the same time are not compatible")
+	}
+	if (f.gt && f.nx) || (f.lt && f.nx) || (f.gt && f.lt) {
+		return appendError(dst, "ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if f.incr && len(pairs) > 2 {
+		return appendError(dst, "ERR INCR option supports a single increment-element pair")
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, ok := parseScore(pairs[2*j])
+		if !ok {
+			return appendError(dst, "ERR value is not a valid float")
+		}
+		scores[j] = score
+	}
+
+	store := c.server.store
+	zset, err := store.lookupZSet(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if zset == nil {
+		if f.xx {
+			// Nothing can be updated, and XX never creates the key.
+			if f.incr {
+				return appendNull(dst)
+			}
+			return appendInteger(dst, 0)
+		}
+		zset, _ = store.zsetForWrite(key)
+		defer store.dropIfEmpty(key)
+	}
+
+	added, changed := int64(0), int64(0)
+	for j, score := range scores {
+		member := string(pairs[2*j+1])
+		cur, exists := zset.score(member)
+		if (exists && f.nx) || (!exists && f.xx) {
+			if f.incr {
+				return appendNull(dst)
+			}
+			continue
+		}
+		if f.incr && exists {
+			score += cur
+			if math.IsNaN(score) {
+				return appendError(dst, "ERR resulting score is not a number (NaN)")
+			}
+		}
+		if exists && ((f.gt && score <= cur) || (f.lt && score >= cur)) {
+			if f.incr {
+				return appendNull(dst)
+			}
+			continue
+		}
+		if zset.add(member, score) {
+			added++
+		} else if exists && score != cur {
+			changed++
+		}
+		if f.incr {
+			return appendScore(dst, score)
+		}
+	}
+	if f.ch {
+		return appendInteger(dst, added+changed)
+	}
+	return appendInteger(dst, added)
+}
+
+func cmdZIncrBy(c *clientConn, dst []byte, args [][]byte) []byte {
+	incr, ok := parseScore(args[1])
+	if !ok {
+		return appendError(dst, "ERR value is not a valid float")
+	}
+	zset, err := c.server.store.zsetForWrite(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	member := string(args[2])
+	score, _ := zset.score(member)
+	score += incr
+	if math.IsNaN(score) {
+		c.server.store.dropIfEmpty(string(args[0]))
+		return appendError(dst, "ERR resulting score is not a number (NaN)")
+	}
+	zset.add(member, score)
+	return appendScore(dst, score)
+}
+
+func cmdZRem(c *clientConn, dst []byte, args [][]byte) []byte {
+	key := string(args[0])
+	zset, err := c.server.store.lookupZSet(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	removed := int64(0)
+	if zset != nil {
+		for _, m := range args[1:] {
+			if zset.remove(string(m)) {
+				removed++
+			}
+		}
+	}
+	c.server.store.dropIfEmpty(key)
+	return appendInteger(dst, removed)
+}
+
+func cmdZCard(c *clientConn, dst []byte, args [][]byte) []byte {
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	return appendInteger(dst, int64(zset.len()))
+}
+
+func cmdZScore(c *clientConn, dst []byte, args [][]byte) []byte {
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	score, ok := zset.score(string(args[1]))
+	if !ok {
+		return appendNull(dst)
+	}
+	return appendScore(dst, score)
+}
+
+func cmdZMScore(c *clientConn, dst []byte, args [][]byte) []byte {
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	dst = appendArrayLen(dst, len(args)-1)
+	for _, m := range args[1:] {
+		if score, ok := zset.score(string(m)); ok {
+			dst = appendScore(dst, score)
+		} else {
+			dst = appendNull(dst)
+		}
+	}
+	return dst
+}
+
+func cmdZRank(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.zrank(dst, args, false)
+}
+
+func cmdZRevRank(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.zrank(dst, args, true)
+}
+
+func (c *clientConn) zrank(dst []byte, args [][]byte, rev bool) []byte {
+	withScore := false
+	switch {
+	case len(args) == 3 && argIs(args[2], "WITHSCORE"):
+		withScore = true
+	case len(args) != 2:
+		return appendSyntaxError(dst)
+	}
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	member := string(args[1])
+	rank, ok := zset.rank(member)
+	if !ok {
+		if withScore {
+			return appendNullArray(dst)
+		}
+		return appendNull(dst)
+	}
+	if rev {
+		rank = zset.len() - 1 - rank
+	}
+	if !withScore {
+		return appendInteger(dst, int64(rank))
+	}
+	score, _ := zset.score(member)
+	dst = appendArrayLen(dst, 2)
+	dst = appendInteger(dst, int64(rank))
+	return appendScore(dst, score)
+}
+
+func cmdZCount(c *clientConn, dst []byte, args [][]byte) []byte {
+	r, ok := parseScoreRange(args[1], args[2])
+	if !ok {
+		return appendError(dst, "ERR min or max is not a float")
+	}
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if zset == nil {
+		return appendInteger(dst, 0)
+	}
+	start, end := zset.scoreIndexes(r)
+	return appendInteger(dst, int64(end-start))
+}
+
+func cmdZLexCount(c *clientConn, dst []byte, args [][]byte) []byte {
+	r, ok := parseLexRange(args[1], args[2])
+	if !ok {
+		return appendError(dst, "ERR min or max not valid string range item")
+	}
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if zset == nil {
+		return appendInteger(dst, 0)
+	}
+	start, end := zset.lexIndexes(r)
+	return appendInteger(dst, int64(end-start))
+}
+
+// zrangeKind selects how ZRANGE interprets its start and stop arguments.
+type zrangeKind int
+
+const (
+	zrangeByRank zrangeKind = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeSpec is a parsed ZRANGE request. The legacy ZREVRANGE,
+// ZRANGEBYSCORE and ZRANGEBYLEX commands are translated into one.
+type zrangeSpec struct {
+	kind          zrangeKind
+	rev           bool
+	withScores    bool
+	offset, limit int64 // limit < 0 means no limit
+}
+
+func cmdZRange(c *clientConn, dst []byte, args [][]byte) []byte {
+	spec := zrangeSpec{limit: -1}
+	hasLimit := false
+	for i := 3; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "BYSCORE"):
+			spec.kind = zrangeByScore
+		case argIs(args[i], "BYLEX"):
+			spec.kind = zrangeByLex
+		case argIs(args[i], "REV"):
+			spec.rev = true
+		case argIs(args[i], "WITHSCORES"):
+			spec.withScores = true
+		case argIs(args[i], "LIMIT") && i+2 < len(args):
+			off, ok1 := parseInt(args[i+1])
+			lim, ok2 := parseInt(args[i+2])
+			if !ok1 || !ok2 {
+				return appendNotInteger(dst)
+			}
+			spec.offset, spec.limit, hasLimit = off, lim, true
+			i += 2
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+	if hasLimit && spec.kind == zrangeByRank {
+		return appendError(dst, "ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if spec.withScores && spec.kind == zrangeByLex {
+		return appendError(dst, "ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	start, stop := args[1], args[2]
+	if spec.rev && spec.kind != zrangeByRank {
+		// Score and lex ranges are given as max then min when reversed.
+		start, stop = stop, start
+	}
+	return c.zrange(dst, string(args[0]), start, stop, spec)
+}
+
+func cmdZRevRange(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.legacyZRange(dst, args, zrangeByRank, true)
+}
+
+func cmdZRangeByScore(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.legacyZRange(dst, args, zrangeByScore, false)
+}
+
+func cmdZRevRangeByScore(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.legacyZRange(dst, args, zrangeByScore, true)
+}
+
+func cmdZRangeByLex(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.legacyZRange(dst, args, zrangeByLex, false)
+}
+
+func cmdZRevRangeByLex(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.legacyZRange(dst, args, zrangeByLex, true)
+}
+
+// legacyZRange handles the pre-6.2 range commands, which take only the
+// WITHSCORES and LIMIT options that apply to their kind, and for reversed
+// score and lex ranges take max before min.
+func (c *clientConn) legacyZRange(dst []byte, args [][]byte, kind zrangeKind, rev bool) []byte {
+	spec := zrangeSpec{kind: kind, rev: rev, limit: -1}
+	for i := 3; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "WITHSCORES") && kind != zrangeByLex:
+			spec.withScores = true
+		case argIs(args[i], "LIMIT") && kind != zrangeByRank && i+2 < len(args):
+			off, ok1 := parseInt(args[i+1])
+			lim, ok2 := parseInt(args[i+2])
+			if !ok1 || !ok2 {
+				return appendNotInteger(dst)
+			}
+			spec.offset, spec.limit = off, lim
+			i += 2
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+	start, stop := args[1], args[2]
+	if rev && kind != zrangeByRank {
+		start, stop = stop, start
+	}
+	return c.zrange(dst, string(args[0]), start, stop, spec)
+}
+
+// zrange replies with the entries selected by spec. For score and lex
+// ranges, start and stop are always min and max.
+func (c *clientConn) zrange(dst []byte, key string, start, stop []byte, spec zrangeSpec) []byte {
+	var lo, hi int // half-open index range in ascending order
+	var zset *zsetValue
+	switch spec.kind {
+	case zrangeByRank:
+		startIdx, ok1 := parseInt(start)
+		stopIdx, ok2 := parseInt(stop)
+		if !ok1 || !ok2 {
+			return appendNotInteger(dst)
+		}
+		var err error
+		if zset, err = c.server.store.lookupZSet(key); err != nil {
+			return appendStoreError(dst, err)
+		}
+		n := int64(zset.len())
+		lo64, hi64, ok := normalizeRange(startIdx, stopIdx, n)
+		if !ok {
+			return appendArrayLen(dst, 0)
+		}
+		if spec.rev {
+			lo64, hi64 = n-1-hi64, n-1-lo64
+		}
+		lo, hi = int(lo64), int(hi64)+1
+	case zrangeByScore:
+		r, ok := parseScoreRange(start, stop)
+		if !ok {
+			return appendError(dst, "ERR min or max is not a float")
+		}
+		var err error
+		if zset, err = c.server.store.lookupZSet(key); err != nil {
+			return appendStoreError(dst, err)
+		}
+		if zset != nil {
+			lo, hi = zset.scoreIndexes(r)
+		}
+	case zrangeByLex:
+		r, ok := parseLexRange(start, stop)
+		if !ok {
+			return appendError(dst, "ERR min or max not valid string range item")
+		}
+		var err error
+		if zset, err = c.server.store.lookupZSet(key); err != nil {
+			return appendStoreError(dst, err)
+		}
+		if zset != nil {
+			lo, hi = zset.lexIndexes(r)
+		}
+	}
+	if zset == nil || lo >= hi {
+		return appendArrayLen(dst, 0)
+	}
+
+	entries := zset.order[lo:hi]
+	if spec.offset < 0 {
+		return appendArrayLen(dst, 0)
+	}
+	if spec.offset >= int64(len(entries)) {
+		entries = nil
+	} else if spec.kind != zrangeByRank {
+		count := int64(len(entries)) - spec.offset
+		if spec.limit >= 0 && spec.limit < count {
+			count = spec.limit
+		}
+		if spec.rev {
+			entries = entries[int64(len(entries))-spec.offset-count : int64(len(entries))-spec.offset]
+		} else {
+			entries = entries[spec.offset : spec.offset+count]
+		}
+	}
+	return appendZEntries(dst, entries, spec.rev, spec.withScores)
+}
+
+// normalizeRange converts Redis start/stop indexes, which may be negative,
+// into an inclusive range within [0, n). It reports false for empty ranges.
+func normalizeRange(start, stop, n int64) (int64, int64, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return start, min(stop, n-1), true
+}
+
+// appendZEntries replies with entries, reversed if rev is set, as a flat
+// member[, score] array.
+func appendZEntries(dst []byte, entries []zsetEntry, rev, withScores bool) []byte {
+	n := len(entries)
+	if withScores {
+		dst = appendArrayLen(dst, 2*n)
+	} else {
+		dst = appendArrayLen(dst, n)
+	}
+	for i := range entries {
+		e := entries[i]
+		if rev {
+			e = entries[n-1-i]
+		}
+		dst = appendBulkString(dst, e.member)
+		if withScores {
+			dst = appendScore(dst, e.score)
+		}
+	}
+	return dst
+}
+
+func cmdZPopMin(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.zpop(dst, args, false)
+}
+
+func cmdZPopMax(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.zpop(dst, args, true)
+}
+
+func (c *clientConn) zpop(dst []byte, args [][]byte, fromMax bool) []byte {
+	if len(args) > 2 {
+		return appendSyntaxError(dst)
+	}
+	count := int64(1)
+	if len(args) == 2 {
+		n, ok := parseInt(args[1])
+		if !ok || n < 0 {
+			return appendError(dst, "ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	key := string(args[0])
+	zset, err := c.server.store.lookupZSet(key)
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if zset == nil {
+		return appendArrayLen(dst, 0)
+	}
+	popped := zset.pop(int(min(count, math.MaxInt32)), fromMax)
+	c.server.store.dropIfEmpty(key)
+	return appendZEntries(dst, popped, false, true)
+}
+
+func cmdBZPopMin(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.bzpop(dst, args, false)
+}
+
+func cmdBZPopMax(c *clientConn, dst []byte, args [][]byte) []byte {
+	return c.bzpop(dst, args, true)
+}
+
+// bzpop pops from the first non-empty sorted set among the keys, or
+// blocks until one of them is created.
+func (c *clientConn) bzpop(dst []byte, args [][]byte, fromMax bool) []byte {
+	deadline, errReply := parseBlockTimeout(args[len(args)-1], time.Now())
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	keys := keyStrings(args[:len(args)-1])
+	return c.serveOrBlock(dst, keys, deadline, func(dst []byte) ([]byte, bool) {
+		return c.server.store.popFirstZSet(dst, keys, fromMax)
+	})
+}
+
+// popFirstZSet pops one entry from the first non-empty sorted set among
+// keys and appends the [key, member, score] reply. A key of another type
+// is served with a WRONGTYPE error, as in Redis.
+func (s *Store) popFirstZSet(dst []byte, keys []string, fromMax bool) ([]byte, bool) {
+	for _, key := range keys {
+		zset, err := s.lookupZSet(key)
+		if err != nil {
+			return appendStoreError(dst, err), true
+		}
+		if zset.len() == 0 {
+			continue
+		}
+		e := zset.pop(1, fromMax)[0]
+		s.dropIfEmpty(key)
+		dst = appendArrayLen(dst, 3)
+		dst = appendBulkString(dst, key)
+		dst = appendBulkString(dst, e.member)
+		return appendScore(dst, e.score), true
+	}
+	return dst, false
+}
+
+func cmdZMPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	m, errReply := parseMPopArgs(args, "MIN", "MAX")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	if out, ok := c.server.store.zmpop(dst, m); ok {
+		return out
+	}
+	return appendNullArray(dst)
+}
+
+func cmdBZMPop(c *clientConn, dst []byte, args [][]byte) []byte {
+	deadline, errReply := parseBlockTimeout(args[0], time.Now())
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	m, errReply := parseMPopArgs(args[1:], "MIN", "MAX")
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	return c.serveOrBlock(dst, m.keys, deadline, func(dst []byte) ([]byte, bool) {
+		return c.server.store.zmpop(dst, m)
+	})
+}
+
+// zmpop pops up to m.count entries from the first non-empty sorted set
+// among m.keys and appends the [key, [[member, score], ...]] reply.
+func (s *Store) zmpop(dst []byte, m mpopArgs) ([]byte, bool) {
+	for _, key := range m.keys {
+		zset, err := s.lookupZSet(key)
+		if err != nil {
+			return appendStoreError(dst, err), true
+		}
+		if zset.len() == 0 {
+			continue
+		}
+		popped := zset.pop(m.count, m.fromBack)
+		s.dropIfEmpty(key)
+		dst = appendArrayLen(dst, 2)
+		dst = appendBulkString(dst, key)
+		dst = appendArrayLen(dst, len(popped))
+		for _, e := range popped {
+			dst = appendArrayLen(dst, 2)
+			dst = appendBulkString(dst, e.member)
+			dst = appendScore(dst, e.score)
+		}
+		return dst, true
+	}
+	return dst, false
+}
+
+func cmdZScan(c *clientConn, dst []byte, args [][]byte) []byte {
+	opts, errReply := parseScanArgs(args[1:])
+	if errReply != "" {
+		return appendError(dst, errReply)
+	}
+	zset, err := c.server.store.lookupZSet(string(args[0]))
+	if err != nil {
+		return appendStoreError(dst, err)
+	}
+	if zset == nil {
+		return appendScanReply(dst, 0, nil)
+	}
+	page, next := c.server.store.scanPage(scanKey{key: string(args[0])}, maps.Keys(zset.scores), func(m string) bool {
+		_, ok := zset.scores[m]
+		return ok
+	}, opts.cursor, opts.count)
+	items := make([]string, 0, 2*len(page))
+	for _, m := range page {
+		if opts.match(m) {
+			items = append(items, m, string(formatScore(zset.scores[m])))
+		}
+	}
+	return appendScanReply(dst, next, items)
+}
@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestZSetAddAndScore(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(3, "ZADD", "z", "1", "a", "2", "b", "3", "c")
+	tc.wantInt(0, "ZADD", "z", "10", "a")
+	tc.wantBulk("10", "ZSCORE", "z", "a")
+	tc.wantInt(2, "ZADD", "z", "CH", "1", "a", "4", "d")
+	tc.wantInt(0, "ZADD", "z", "NX", "100", "a")
+	tc.wantBulk("1", "ZSCORE", "z", "a")
+	tc.wantInt(0, "ZADD", "z", "XX", "1", "new")
+	tc.wantNull("ZSCORE", "z", "new")
+	tc.wantInt(1, "ZADD", "z", "GT", "CH", "5", "a", "0", "b")
+	tc.wantBulk("5", "ZSCORE", "z", "a")
+	tc.wantBulk("2", "ZSCORE", "z", "b")
+	tc.wantBulk("7.5", "ZADD", "z", "INCR", "2.5", "a")
+	tc.wantNull("ZADD", "z", "NX", "INCR", "1", "a")
+	tc.wantInt(4, "ZCARD", "z")
+
+	tc.wantError("ERR XX and NX options at the same time are not compatible", "ZADD", "z", "NX", "XX", "1", "a")
+	tc.wantError("ERR GT, LT, and/or NX options at the same time are not compatible", "ZADD", "z", "GT", "LT", "1", "a")
+	tc.wantError("ERR INCR option supports a single increment-element pair", "ZADD", "z", "INCR", "1", "a", "2", "b")
+	tc.wantError("ERR value is not a valid float", "ZADD", "z", "x", "a")
+	tc.wantError("ERR syntax error", "ZADD", "z", "1", "a", "2")
+
+	tc.wantBulk("3.5", "ZINCRBY", "z", "1.5", "b")
+	tc.wantBulk("-inf", "ZINCRBY", "z", "-inf", "neg")
+	tc.wantError("ERR resulting score is not a number (NaN)", "ZINCRBY", "z", "+inf", "neg")
+
+	got := tc.do("ZMSCORE", "z", "a", "missing")
+	if len(got.Array) != 2 || string(got.Array[0].Bulk) != "7.5" || got.Array[1].Kind != redisproto.KindNull {
+		t.Fatalf("ZMSCORE: got %#v", got)
+	}
+
+	tc.wantInt(2, "ZREM", "z", "a", "neg", "missing")
+	tc.wantInt(0, "ZADD", "fresh", "XX", "1", "a")
+	if reply := tc.do("TYPE", "fresh"); reply.Str != "none" {
+		t.Fatalf("ZADD XX created a key: TYPE = %q", reply.Str)
+	}
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "ZADD", "str", "1", "a")
+}
+
+func TestZSetRankAndCount(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("ZADD", "z", "1", "a", "2", "b", "2", "c", "3", "d")
+	tc.wantInt(0, "ZRANK", "z", "a")
+	tc.wantInt(2, "ZRANK", "z", "c")
+	tc.wantInt(0, "ZREVRANK", "z", "d")
+	tc.wantNull("ZRANK", "z", "missing")
+	got := tc.do("ZRANK", "z", "b", "WITHSCORE")
+	if len(got.Array) != 2 || got.Array[0].Int != 1 || string(got.Array[1].Bulk) != "2" {
+		t.Fatalf("ZRANK WITHSCORE: got %#v", got)
+	}
+
+	tc.wantInt(3, "ZCOUNT", "z", "2", "+inf")
+	tc.wantInt(1, "ZCOUNT", "z", "(2", "3")
+	tc.wantInt(0, "ZCOUNT", "z", "(1", "(2")
+	tc.wantInt(0, "ZCOUNT", "missing", "-inf", "+inf")
+	tc.wantError("ERR min or max is not a float", "ZCOUNT", "z", "x", "1")
+
+	tc.do("ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d")
+	tc.wantInt(4, "ZLEXCOUNT", "lex", "-", "+")
+	tc.wantInt(2, "ZLEXCOUNT", "lex", "[b", "[c")
+	tc.wantInt(1, "ZLEXCOUNT", "lex", "(b", "[c")
+	tc.wantError("ERR min or max not valid string range item", "ZLEXCOUNT", "lex", "b", "+")
+}
+
+func TestZSetRanges(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("ZADD", "z", "1", "a", "2", "b", "3", "c", "4", "d")
+	tc.wantStrings(true, []string{"a", "b", "c", "d"}, "ZRANGE", "z", "0", "-1")
+	tc.wantStrings(true, []string{"b", "2", "c", "3"}, "ZRANGE", "z", "1", "2", "WITHSCORES")
+	tc.wantStrings(true, []string{"d", "c"}, "ZRANGE", "z", "0", "1", "REV")
+	tc.wantStrings(true, []string{"d", "c"}, "ZREVRANGE", "z", "0", "1")
+	tc.wantStrings(true, nil, "ZRANGE", "z", "5", "10")
+
+	tc.wantStrings(true, []string{"b", "c"}, "ZRANGE", "z", "(1", "3", "BYSCORE")
+	tc.wantStrings(true, []string{"c", "b"}, "ZRANGE", "z", "3", "(1", "BYSCORE", "REV")
+	tc.wantStrings(true, []string{"c"}, "ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "2", "1")
+	tc.wantStrings(true, []string{"b", "c", "d"}, "ZRANGEBYSCORE", "z", "2", "+inf")
+	tc.wantStrings(true, []string{"c", "3", "b", "2"}, "ZREVRANGEBYSCORE", "z", "3", "2", "WITHSCORES")
+	tc.wantStrings(true, []string{"c"}, "ZREVRANGEBYSCORE", "z", "+inf", "-inf", "LIMIT", "1", "1")
+
+	tc.do("ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d")
+	tc.wantStrings(true, []string{"b", "c"}, "ZRANGEBYLEX", "lex", "[b", "(d")
+	tc.wantStrings(true, []string{"d", "c"}, "ZREVRANGEBYLEX", "lex", "+", "[c")
+	tc.wantStrings(true, []string{"b"}, "ZRANGE", "lex", "-", "+", "BYLEX", "LIMIT", "1", "1")
+
+	tc.wantError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX",
+		"ZRANGE", "z", "0", "1", "LIMIT", "0", "1")
+	tc.wantError("ERR syntax error, WITHSCORES not supported in combination with BYLEX",
+		"ZRANGE", "lex", "-", "+", "BYLEX", "WITHSCORES")
+	tc.wantError("ERR syntax error", "ZRANGEBYLEX", "lex", "-", "+", "WITHSCORES")
+}
+
+func TestZSetPop(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantStrings(true, nil, "ZPOPMIN", "missing")
+	tc.do("ZADD", "z", "1", "a", "2", "b", "3", "c")
+	tc.wantStrings(true, []string{"a", "1"}, "ZPOPMIN", "z")
+	tc.wantStrings(true, []string{"c", "3", "b", "2"}, "ZPOPMAX", "z", "5")
+	tc.wantInt(0, "ZCARD", "z")
+	if reply := tc.do("TYPE", "z"); reply.Str != "none" {
+		t.Fatalf("empty sorted set was not removed: TYPE = %q", reply.Str)
+	}
+	tc.wantError("ERR value is out of range, must be positive", "ZPOPMIN", "z", "-1")
+
+	// BZPOPMIN serves immediately when a key is non-empty.
+	tc.do("ZADD", "z2", "5", "x")
+	tc.wantStrings(true, []string{"z2", "x", "5"}, "BZPOPMIN", "z", "z2", "0")
+	tc.wantError("ERR timeout is negative", "BZPOPMIN", "z", "-1")
+	tc.wantError("ERR timeout is not a float or out of range", "BZPOPMIN", "z", "x")
+}
+
+func TestZSetScan(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.do("ZADD", "z", "1", "a", "2.5", "b")
+	items := scanAll(tc, "ZSCAN", "z")
+	if len(items) != 4 || !items["a"] || !items["1"] || !items["b"] || !items["2.5"] {
+		t.Fatalf("ZSCAN: got %v", items)
+	}
+}
@@ -0,0 +1,403 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// Command flags, named after their Redis counterparts.
+const (
+	flagWrite    = "write"
+	flagReadonly = "readonly"
+	flagFast     = "fast"
+	flagDenyOOM  = "denyoom"
+	flagBlocking = "blocking"
+	// flagMovableKeys marks commands whose key positions depend on their
+	// arguments, such as a numkeys count; firstKey is then zero.
+	flagMovableKeys = "movablekeys"
+)
+
+// command describes one entry of the command table. Arity follows the Redis
+// convention: it counts the command name, and a negative value -N means
+// "at least N arguments".
+type command struct {
+	name  string
+	arity int
+	flags []string
+	// firstKey, lastKey and step locate key arguments, as reported by
+	// COMMAND INFO. lastKey -1 means "up to the last argument".
+	firstKey int
+	lastKey  int
+	step     int
+	// numKeysAt is, for flagMovableKeys commands, the position of the
+	// numkeys argument the keys follow.
+	numKeysAt int
+	group     string
+	summary   string
+	// handler appends the reply for args (excluding the command name) to
+	// dst. It runs with the store lock held.
+	handler func(c *clientConn, dst []byte, args [][]byte) []byte
+}
+
+// commandTable maps lower-case command names to their entries.
+var commandTable = map[string]*command{}
+
+func registerCommands(cmds ...*command) {
+	for _, cmd := range cmds {
+		if _, dup := commandTable[cmd.name]; dup {
+			panic("redismvp: duplicate command " + cmd.name)
+		}
+		commandTable[cmd.name] = cmd
+	}
+}
+
+// extensionTable maps lower-case names of extension commands to their
+// entries. Extension commands are not part of Redis; they are namespaced
+// with an "x." prefix and only served when Config.ExtensionCommands is set.
+var extensionTable = map[string]*command{}
+
+func registerExtensions(cmds ...*command) {
+	for _, cmd := range cmds {
+		if !strings.HasPrefix(cmd.name, "x.") {
+			panic("redismvp: extension command " + cmd.name + " lacks the x. prefix")
+		}
+		if _, dup := extensionTable[cmd.name]; dup {
+			panic("redismvp: duplicate command " + cmd.name)
+		}
+		extensionTable[cmd.name] = cmd
+	}
+}
+
+// lookupCommand returns the command served under name, including extension
+// commands if they are enabled, after Config.RenameCommands.
+func (s *Server) lookupCommand(name []byte) *command {
+	lower := strings.ToLower(string(name))
+	if s.renames.from != nil {
+		if cmd, ok := s.renames.to[lower]; ok {
+			return cmd
+		}
+		if _, ok := s.renames.from[lower]; ok {
+			return nil
+		}
+	}
+	return s.lookupOriginal(lower)
+}
+
+// lookupOriginal returns the command whose own name is lower, renames
+// aside. Commands replayed from a master or the AOF are looked up this way,
+// since they are recorded under their own names.
+func (s *Server) lookupOriginal(lower string) *command {
+	if cmd, ok := commandTable[lower]; ok {
+		return cmd
+	}
+	if s.extensions {
+		return extensionTable[lower]
+	}
+	return nil
+}
+
+// commandRenames is the command table lookup layer for
+// Config.RenameCommands.
+type commandRenames struct {
+	// to maps lower-case new names to their commands, and from the names
+	// of the renamed and disabled commands to their new names, empty for
+	// disabled ones.
+	to       map[string]*command
+	from     map[string]string
+	disabled int
+}
+
+// newCommandRenames validates renames against the command table.
+func newCommandRenames(renames map[string]string, extensions bool) (commandRenames, error) {
+	if len(renames) == 0 {
+		return commandRenames{}, nil
+	}
+	lookup := (&Server{extensions: extensions}).lookupOriginal
+	r := commandRenames{to: make(map[string]*command), from: make(map[string]string)}
+	for name, newName := range renames {
+		name, newName = strings.ToLower(name), strings.ToLower(newName)
+		cmd := lookup(name)
+		if cmd == nil {
+			return commandRenames{}, fmt.Errorf("rename-command: unknown command %q", name)
+		}
+		if _, dup := r.from[name]; dup {
+			return commandRenames{}, fmt.Errorf("rename-command: %q renamed twice", name)
+		}
+		r.from[name] = newName
+		if newName == "" {
+			r.disabled++
+			continue
+		}
+		if _, dup := r.to[newName]; dup {
+			return commandRenames{}, fmt.Errorf("rename-command: %q used twice", newName)
+		}
+		r.to[newName] = cmd
+	}
+	// A new name may take that of another command only if that one is
+	// renamed too.
+	for newName := range r.to {
+		if _, moved := r.from[newName]; !moved && lookup(newName) != nil {
+			return commandRenames{}, fmt.Errorf("rename-command: %q is already a command", newName)
+		}
+	}
+	return r, nil
+}
+
+// disables reports whether the command named name is disabled.
+func (r commandRenames) disables(name string) bool {
+	newName, ok := r.from[name]
+	return ok && newName == ""
+}
+
+// keys returns the key arguments of args, which start with the command
+// name.
+func (cmd *command) keys(args [][]byte) []string {
+	first, last, step := cmd.firstKey, cmd.lastKey, cmd.step
+	if at := cmd.numKeysAt; at > 0 && at < len(args) {
+		n, ok := parseInt(args[at])
+		if !ok || n < 1 {
+			return nil
+		}
+		first, last, step = at+1, at+int(min(n, int64(len(args)))), 1
+	}
+	if first == 0 {
+		return nil
+	}
+	if last < 0 {
+		last += len(args)
+	}
+	last = min(last, len(args)-1)
+	keys := make([]string, 0, (last-first)/step+1)
+	for i := first; i <= last; i += step {
+		keys = append(keys, string(args[i]))
+	}
+	return keys
+}
+
+func (cmd *command) arityOK(argc int) bool {
+	if cmd.arity >= 0 {
+		return argc == cmd.arity
+	}
+	return argc >= -cmd.arity
+}
+
+func (c *clientConn) appendResponse(dst []byte, frame redisproto.Value) []byte {
+	if frame.Kind != redisproto.KindArray {
+		return appendError(dst, "ERR Protocol error: command must be array")
+	}
+	if len(frame.Array) == 0 {
+		return appendError(dst, "ERR Protocol error: empty command")
+	}
+
+	args := make([][]byte, len(frame.Array))
+	for i, item := range frame.Array {
+		arg, ok := tokenBytes(item)
+		if !ok {
+			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", item.Kind))
+		}
+		args[i] = arg
+	}
+
+	var cmd *command
+	if c.replayed {
+		cmd = c.server.lookupOriginal(strings.ToLower(string(args[0])))
+	} else {
+		cmd = c.server.lookupCommand(args[0])
+	}
+	if cmd == nil {
+		return appendError(dst, "ERR unknown command '"+strings.ToLower(string(args[0]))+"'")
+	}
+	if c.server.renames.from != nil {
+		// Replicas and the AOF know the command by its own name.
+		args[0] = []byte(cmd.name)
+	}
+	if !cmd.arityOK(len(args)) {
+		return appendWrongArity(dst, cmd.name)
+	}
+	if c.pauseWriteFor(cmd) {
+		c.pauseWrite(frame)
+		return dst
+	}
+	if c.rejectWrite(cmd) {
+		return appendError(dst, "READONLY You can't write against a read only replica.")
+	}
+
+	store := c.server.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if c.outOfMemory(cmd) {
+		return appendError(dst, errOOM)
+	}
+	write := slices.Contains(cmd.flags, flagWrite)
+	ev := c.server.evict
+	var keys []string
+	if ev != nil || (write && store.snap != nil) {
+		keys = cmd.keys(args)
+	}
+	if write && store.snap != nil {
+		store.preserve(keys)
+	}
+	start := len(dst)
+	dst = cmd.handler(c, dst, args[1:])
+	if ev != nil {
+		ev.track(store.kv, cmd, keys, write)
+	}
+	if write {
+		c.propagateWrite(args, dst[start:])
+	}
+	return dst
+}
+
+// pauseWriteFor reports whether cmd is a write that must wait for a
+// failover to end.
+func (c *clientConn) pauseWriteFor(cmd *command) bool {
+	s := c.server
+	if s.failover == nil || !slices.Contains(cmd.flags, flagWrite) {
+		return false
+	}
+	return s.repl.link == nil || c != s.repl.link.client
+}
+
+// rejectWrite reports whether cmd is a write that a read-only replica must
+// refuse. Commands from the master are always applied.
+func (c *clientConn) rejectWrite(cmd *command) bool {
+	s := c.server
+	l := s.repl.link
+	return l != nil && c != l.client && !s.replicaWritable && slices.Contains(cmd.flags, flagWrite)
+}
+
+// propagateWrite feeds a write command to the replication stream unless it
+// failed. A command that blocked is propagated once it is served.
+func (c *clientConn) propagateWrite(args [][]byte, reply []byte) {
+	if rewritten := c.propagateArgs; rewritten != nil {
+		args, c.propagateArgs = rewritten, nil
+	}
+	switch {
+	case c.blocked != nil:
+		c.blocked.args = args
+	case len(args) == 0:
+	case len(reply) > 0 && reply[0] == '-':
+	default:
+		c.server.propagate(args)
+		c.woff = c.server.repl.offset
+	}
+}
+
+// appendStoreError converts an error returned by a Store accessor into a
+// reply.
+func appendStoreError(dst []byte, err error) []byte {
+	switch {
+	case errors.Is(err, errWrongType):
+		return appendError(dst, err.Error())
+	case errors.Is(err, errValueNotInteger):
+		return appendError(dst, "ERR value is not an integer or out of range")
+	default:
+		return appendError(dst, "ERR "+err.Error())
+	}
+}
+
+func appendNotInteger(dst []byte) []byte {
+	return appendError(dst, "ERR value is not an integer or out of range")
+}
+
+func appendSyntaxError(dst []byte) []byte {
+	return appendError(dst, "ERR syntax error")
+}
+
+// parseInt parses a base-10 signed 64-bit integer argument.
+func parseInt(arg []byte) (int64, bool) {
+	n, err := strconv.ParseInt(string(arg), 10, 64)
+	return n, err == nil
+}
+
+// argIs reports whether arg equals the upper-case keyword name,
+// ignoring ASCII case.
+func argIs(arg []byte, name string) bool {
+	if len(arg) != len(name) {
+		return false
+	}
+	for i := 0; i < len(arg); i++ {
+		b := arg[i]
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		if b != name[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func keyStrings(args [][]byte) []string {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = string(arg)
+	}
+	return keys
+}
+
+// mpopArgs holds the parsed arguments of LMPOP and ZMPOP.
+type mpopArgs struct {
+	keys []string
+	// fromBack is set for RIGHT (LMPOP) and MAX (ZMPOP).
+	fromBack bool
+	count    int
+}
+
+// parseMPopArgs parses "numkeys key [key ...] where [COUNT count]", where
+// where is one of the keywords front and back.
+func parseMPopArgs(args [][]byte, front, back string) (mpopArgs, string) {
+	var m mpopArgs
+	numKeys, ok := parseInt(args[0])
+	if !ok || numKeys < 1 {
+		return m, "ERR numkeys should be greater than 0"
+	}
+	if numKeys >= int64(len(args)-1) {
+		return m, "ERR syntax error"
+	}
+	m.keys = keyStrings(args[1 : 1+numKeys])
+	rest := args[1+numKeys:]
+	switch {
+	case argIs(rest[0], front):
+	case argIs(rest[0], back):
+		m.fromBack = true
+	default:
+		return m, "ERR syntax error"
+	}
+	m.count = 1
+	switch {
+	case len(rest) == 1:
+	case len(rest) == 3 && argIs(rest[1], "COUNT"):
+		n, ok := parseInt(rest[2])
+		if !ok || n < 1 {
+			return m, "ERR count should be greater than 0"
+		}
+		m.count = int(min(n, math.MaxInt32))
+	default:
+		return m, "ERR syntax error"
+	}
+	return m, ""
+}
+
+// replay runs a command that already ran elsewhere, received from a master
+// or read back from the AOF. There is nobody to reply to, so failures are
+// only logged, and a blocking command that would wait gives up at once.
+func (c *clientConn) replay(frame redisproto.Value) {
+	if reply := c.appendResponse(nil, frame); len(reply) > 0 && reply[0] == '-' {
+		c.log.Warn("replayed command failed", "command", commandName(frame), "reply", string(reply[1:len(reply)-2]))
+	}
+	if c.blocked != nil {
+		c.unblock()
+	}
+}
@@ -0,0 +1,331 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// testClient runs commands through the dispatcher without a loop or socket,
+// so command semantics can be tested without the native library.
+type testClient struct {
+	t *testing.T
+	c *clientConn
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &Server{store: NewStore(), log: log, slowLog: -1}
+	s.store.keyCreated = s.keyCreated
+	return &testClient{t: t, c: &clientConn{server: s, log: log}}
+}
+
+func (tc *testClient) do(args ...string) redisproto.Value {
+	tc.t.Helper()
+	wire := tc.c.execute(nil, buildTestCommand(args))
+	frames, err := redisproto.NewParser().Feed(wire)
+	if err != nil {
+		tc.t.Fatalf("%v: parse reply %q: %v", args, wire, err)
+	}
+	if len(frames) != 1 {
+		tc.t.Fatalf("%v: expected one reply, got %d in %q", args, len(frames), wire)
+	}
+	return frames[0]
+}
+
+func (tc *testClient) wantInt(want int64, args ...string) {
+	tc.t.Helper()
+	got := tc.do(args...)
+	if got.Kind != redisproto.KindInteger || got.Int != want {
+		tc.t.Fatalf("%v: got %#v, want integer %d", args, got, want)
+	}
+}
+
+func (tc *testClient) wantError(want string, args ...string) {
+	tc.t.Helper()
+	got := tc.do(args...)
+	if got.Kind != redisproto.KindError || got.Str != want {
+		tc.t.Fatalf("%v: got %#v, want error %q", args, got, want)
+	}
+}
+
+func (tc *testClient) wantBulk(want string, args ...string) {
+	tc.t.Helper()
+	got := tc.do(args...)
+	if got.Kind != redisproto.KindBulkString || string(got.Bulk) != want {
+		tc.t.Fatalf("%v: got %#v, want bulk %q", args, got, want)
+	}
+}
+
+func (tc *testClient) wantNull(args ...string) {
+	tc.t.Helper()
+	got := tc.do(args...)
+	if got.Kind != redisproto.KindNull {
+		tc.t.Fatalf("%v: got %#v, want null", args, got)
+	}
+}
+
+// wantStrings checks an array reply. Unordered replies are compared after
+// sorting both sides.
+func (tc *testClient) wantStrings(ordered bool, want []string, args ...string) {
+	tc.t.Helper()
+	got := tc.strings(args...)
+	if !ordered {
+		sort.Strings(got)
+		want = append([]string(nil), want...)
+		sort.Strings(want)
+	}
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		tc.t.Fatalf("%v: got %q, want %q", args, got, want)
+	}
+}
+
+// strings returns the elements of an array reply of bulk strings.
+func (tc *testClient) strings(args ...string) []string {
+	tc.t.Helper()
+	got := tc.do(args...)
+	if got.Kind != redisproto.KindArray {
+		tc.t.Fatalf("%v: got %#v, want array", args, got)
+	}
+	out := make([]string, len(got.Array))
+	for i, v := range got.Array {
+		out[i] = string(v.Bulk)
+	}
+	return out
+}
+
+func buildTestCommand(args []string) redisproto.Value {
+	cmd := redisproto.Value{Kind: redisproto.KindArray, Array: make([]redisproto.Value, 0, len(args))}
+	for _, arg := range args {
+		cmd.Array = append(cmd.Array, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte(arg)})
+	}
+	return cmd
+}
+
+func TestCommandDispatch(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantBulk("hello", "ping", "hello")
+	tc.wantError("ERR wrong number of arguments for 'ping' command", "PING", "a", "b")
+	tc.wantError("ERR wrong number of arguments for 'get' command", "GET")
+	tc.wantError("ERR unknown command 'noexist'", "NOEXIST")
+
+	got := tc.c.execute(nil, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PING"})
+	if string(got) != "-ERR Protocol error: command must be array\r\n" {
+		t.Fatalf("non-array command: got %q", got)
+	}
+	got = tc.c.execute(nil, redisproto.Value{Kind: redisproto.KindArray})
+	if string(got) != "-ERR Protocol error: empty command\r\n" {
+		t.Fatalf("empty command: got %q", got)
+	}
+}
+
+func TestHello(t *testing.T) {
+	tc := newTestClient(t)
+	tc.c.codec = redisproto.NewCodec()
+	tc.c.id = 7
+
+	fields := func(proto string) string {
+		return "$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.2.0\r\n" +
+			"$5\r\nproto\r\n:" + proto + "\r\n$2\r\nid\r\n:7\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n" +
+			"$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n"
+	}
+	if got := tc.c.execute(nil, buildTestCommand([]string{"HELLO"})); string(got) != "*14\r\n"+fields("2") {
+		t.Fatalf("HELLO: %q", got)
+	}
+	tc.wantError("NOPROTO sorry, this protocol version is not supported", "HELLO", "4")
+	tc.wantError("ERR Protocol version is not an integer or out of range", "HELLO", "three")
+	tc.wantError("ERR Syntax error in HELLO option 'SETNAME'", "HELLO", "3", "SETNAME", "x")
+	if tc.c.codec.Version() != 2 {
+		t.Fatalf("failed HELLO switched to RESP%d", tc.c.codec.Version())
+	}
+	if got := tc.c.execute(nil, buildTestCommand([]string{"HELLO", "3"})); string(got) != "%7\r\n"+fields("3") {
+		t.Fatalf("HELLO 3: %q", got)
+	}
+	if tc.c.codec.Version() != 3 {
+		t.Fatal("HELLO 3 did not switch the connection to RESP3")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tc := newTestClient(t)
+	if got := tc.do("SELECT", "0"); got.Str != "OK" {
+		t.Fatalf("SELECT 0: %#v", got)
+	}
+	tc.wantError("ERR DB index is out of range", "SELECT", "1")
+	tc.wantError("ERR DB index is out of range", "SELECT", "-1")
+	tc.wantError("ERR value is not an integer or out of range", "SELECT", "db")
+}
+
+func TestCommandTableArity(t *testing.T) {
+	for name, cmd := range commandTable {
+		if cmd.name != name {
+			t.Errorf("%s registered as %q", cmd.name, name)
+		}
+		if cmd.arity == 0 {
+			t.Errorf("%s: arity must not be zero", name)
+		}
+		if cmd.handler == nil {
+			t.Errorf("%s: missing handler", name)
+		}
+	}
+}
+
+// randomBinary returns n random bytes, as a string, drawn mostly from the
+// bytes that break text handling: NUL, CR, LF, space and invalid UTF-8.
+func randomBinary(rng *rand.Rand, n int) string {
+	const tricky = "\x00\r\n \xff\xfe\xc3"
+	b := make([]byte, n)
+	for i := range b {
+		if rng.IntN(2) == 0 {
+			b[i] = tricky[rng.IntN(len(tricky))]
+		} else {
+			b[i] = byte(rng.Uint32())
+		}
+	}
+	return string(b)
+}
+
+func TestBinarySafeKeys(t *testing.T) {
+	cfg := aofConfig(t)
+	tc := startAOFServer(t, cfg)
+	s := tc.c.server
+	s.dbFilename = filepath.Join(t.TempDir(), DefaultDBFilename)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	keys := map[string]bool{"": true, "\x00": true, "a\x00b": true, "\r\n": true, "\xff\xfe": true}
+	for len(keys) < 200 {
+		keys[randomBinary(rng, 1+rng.IntN(24))] = true
+	}
+	values := map[string]string{}
+	i := 0
+	for key := range keys {
+		values[key] = randomBinary(rng, rng.IntN(24))
+		tc.do("SET", key, values[key])
+		tc.do("HSET", "hash", key, values[key])
+		tc.do("SADD", "set", key)
+		tc.do("ZADD", "zset", strconv.Itoa(i), key)
+		tc.do("RPUSH", "list", key)
+		i++
+	}
+	for key, v := range values {
+		tc.wantBulk(v, "GET", key)
+		tc.wantBulk(v, "HGET", "hash", key)
+		tc.wantInt(1, "SISMEMBER", "set", key)
+	}
+	want := map[string]bool{"hash": true, "set": true, "zset": true, "list": true}
+	for key := range keys {
+		want[key] = true
+	}
+	if got := scanAll(tc, "SCAN", "COUNT", "50"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SCAN returned %d keys, want %d", len(got), len(want))
+	}
+
+	// Keys survive a copy, a DUMP/RESTORE round trip, the RDB and the AOF.
+	tc.do("COPY", "\x00", "\x00copy")
+	tc.wantBulk(values["\x00"], "GET", "\x00copy")
+	dump := tc.do("DUMP", "a\x00b")
+	tc.do("RESTORE", "a\x00b\r\n", "0", string(dump.Bulk))
+	tc.wantBulk(values["a\x00b"], "GET", "a\x00b\r\n")
+	wantReloadRoundTrip(t, tc)
+	restarted := startAOFServer(t, cfg)
+	wantSameDataset(t, restarted.c.server, s)
+
+	// Arguments quoted in errors cannot break the reply framing.
+	tc.wantError("ERR unknown command 'x  y'", "x\r\ny")
+	tc.wantError("ERR unknown subcommand 'a b'. Try OBJECT HELP.", "OBJECT", "a\nb")
+}
+
+func TestWrongTypeError(t *testing.T) {
+	tc := newTestClient(t)
+
+	tc.wantInt(1, "SADD", "s", "a")
+	tc.wantError(errWrongType.Error(), "GET", "s")
+	tc.wantError(errWrongType.Error(), "INCR", "s")
+	tc.do("SET", "str", "v")
+	tc.wantError(errWrongType.Error(), "SADD", "str", "a")
+
+	if _, ok := tc.c.server.store.Get("s"); ok {
+		t.Fatal("Store.Get returned a set value")
+	}
+}
+
+func TestRenameCommands(t *testing.T) {
+	tc := newTestClient(t)
+	s := tc.c.server
+	renames, err := newCommandRenames(map[string]string{
+		"UNLINK": "",
+		"debug":  "",
+		"Set":    "put-3f9a",
+		"get":    "set", // free, since SET moved
+	}, false)
+	if err != nil {
+		t.Fatalf("newCommandRenames: %v", err)
+	}
+	s.renames = renames
+	s.repl.createBacklog()
+	start := s.repl.offset + 1
+
+	tc.wantError("ERR unknown command 'unlink'", "UNLINK", "k")
+	tc.wantError("ERR unknown command 'debug'", "DEBUG", "SLEEP", "0")
+	if got := tc.do("PUT-3F9A", "k", "v"); got.Str != "OK" {
+		t.Fatalf("renamed SET: got %#v", got)
+	}
+	tc.wantBulk("v", "set", "k")
+	tc.wantError("ERR unknown command 'get'", "GET", "k")
+	tc.wantError("ERR wrong number of arguments for 'set' command", "put-3f9a", "k")
+
+	// Replicas and the AOF get the command under its own name.
+	stream, _ := s.repl.partialResync(s.repl.id, start)
+	if want := string(appendBulkArray(nil, [][]byte{[]byte("set"), []byte("k"), []byte("v")})); string(stream) != want {
+		t.Fatalf("replication stream %q, want %q", stream, want)
+	}
+	// ... and replay it that way.
+	replica := &clientConn{server: s, log: tc.c.log, replayed: true}
+	replica.replay(buildTestCommand([]string{"SET", "k", "w"}))
+	tc.wantBulk("w", "SET", "k")
+
+	// Disabled commands are gone from COMMAND; renamed ones stay, under
+	// their own names.
+	tc.wantInt(int64(len(commandTable)-2), "COMMAND", "COUNT")
+	if got := tc.do("COMMAND"); len(got.Array) != len(commandTable)-2 {
+		t.Fatalf("COMMAND lists %d commands, want %d", len(got.Array), len(commandTable)-2)
+	}
+	if got := tc.do("COMMAND", "INFO", "unlink", "put-3f9a"); len(got.Array) != 2 ||
+		len(got.Array[0].Array) != 0 || string(got.Array[1].Array[0].Bulk) != "set" {
+		t.Fatalf("COMMAND INFO: got %#v", got)
+	}
+}
+
+func TestRenameCommandsInvalid(t *testing.T) {
+	for _, renames := range []map[string]string{
+		{"nosuchcommand": "x"},
+		{"x.setifeq": "swap"}, // extension commands are off
+		{"set": "get"},
+		{"set": "a", "get": "A"},
+		{"SET": "a", "set": "b"},
+	} {
+		if _, err := newCommandRenames(renames, false); err == nil {
+			t.Errorf("newCommandRenames(%v) accepted", renames)
+		}
+	}
+	if _, err := newCommandRenames(map[string]string{"x.setifeq": "swap"}, true); err != nil {
+		t.Errorf("renaming an enabled extension command: %v", err)
+	}
+}
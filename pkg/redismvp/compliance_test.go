@@ -0,0 +1,540 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// The compliance suite replays assertions ported from the upstream Redis
+// test suite (tests/unit/type/string.tcl, hash.tcl, list.tcl and
+// tests/unit/expire.tcl). Each case keeps the upstream test name and runs
+// on a fresh server. A case using a command the server does not register
+// is reported as unimplemented rather than failed, so the report shows how
+// much of the upstream behavior the server covers:
+//
+//	go test ./pkg/redismvp -run TestCompliance -v
+
+// complianceCase is one upstream test.
+type complianceCase struct {
+	group string
+	name  string
+	steps []complianceStep
+}
+
+// complianceStep runs args and compares the reply, rendered the way the
+// Tcl client sees it: bulk and simple strings as is, integers in decimal,
+// arrays as brace-delimited lists, and nulls as "(nil)". A want starting
+// with "!" is a glob matched against an error reply, like assert_error.
+type complianceStep struct {
+	args   []string
+	want   string
+	sorted bool // compare an array reply ignoring order, like lsort
+}
+
+func r(want string, args ...string) complianceStep {
+	return complianceStep{args: args, want: want}
+}
+
+func rSorted(want string, args ...string) complianceStep {
+	return complianceStep{args: args, want: want, sorted: true}
+}
+
+var complianceCases = []complianceCase{
+	// tests/unit/type/string.tcl
+	{"string", "SET and GET an item", []complianceStep{
+		r("OK", "set", "x", "foobar"),
+		r("foobar", "get", "x"),
+	}},
+	{"string", "SET and GET an empty item", []complianceStep{
+		r("OK", "set", "x", ""),
+		r("", "get", "x"),
+	}},
+	{"string", "SETNX target key missing", []complianceStep{
+		r("1", "setnx", "novar", "foobared"),
+		r("foobared", "get", "novar"),
+	}},
+	{"string", "SETNX target key exists", []complianceStep{
+		r("OK", "set", "novar", "foobared"),
+		r("0", "setnx", "novar", "blabla"),
+		r("foobared", "get", "novar"),
+	}},
+	{"string", "GETSET (set new value)", []complianceStep{
+		r("(nil)", "getset", "foo", "xyz"),
+		r("xyz", "get", "foo"),
+	}},
+	{"string", "GETSET (replace old value)", []complianceStep{
+		r("OK", "set", "foo", "bar"),
+		r("bar", "getset", "foo", "xyz"),
+		r("xyz", "get", "foo"),
+	}},
+	{"string", "MSET base case", []complianceStep{
+		r("OK", "mset", "x", "10", "y", "foo bar", "z", "x x x x x x x\n\n\r\n"),
+		r("{10 {foo bar} {x x x x x x x\n\n\r\n}}", "mget", "x", "y", "z"),
+	}},
+	{"string", "MSET wrong number of args", []complianceStep{
+		r("!*wrong number*", "mset", "x", "10", "y", "foo bar", "z"),
+	}},
+	{"string", "MSETNX with already existent key", []complianceStep{
+		r("OK", "set", "x2", "xxx"),
+		r("0", "msetnx", "x1", "xxx", "y2", "yyy", "x2", "zzz"),
+		r("(nil)", "get", "x1"),
+	}},
+	{"string", "STRLEN against non-existing key", []complianceStep{
+		r("0", "strlen", "notakey"),
+	}},
+	{"string", "STRLEN against plain string", []complianceStep{
+		r("OK", "set", "mystring", "foozzz0123456789 baz"),
+		r("20", "strlen", "mystring"),
+	}},
+	{"string", "SETRANGE against non-existing key", []complianceStep{
+		r("3", "setrange", "mykey", "0", "foo"),
+		r("foo", "get", "mykey"),
+	}},
+	{"string", "SETRANGE against non-existing key with an empty value", []complianceStep{
+		r("0", "setrange", "mykey", "0", ""),
+		r("none", "type", "mykey"),
+	}},
+	{"string", "SETRANGE against string-encoded key", []complianceStep{
+		r("OK", "set", "mykey", "foo"),
+		r("3", "setrange", "mykey", "0", "b"),
+		r("boo", "get", "mykey"),
+		r("3", "setrange", "mykey", "0", ""),
+		r("boo", "get", "mykey"),
+		r("3", "setrange", "mykey", "1", "b"),
+		r("bbo", "get", "mykey"),
+	}},
+	{"string", "SETRANGE against key with wrong type", []complianceStep{
+		r("1", "lpush", "mykey", "foo"),
+		r("!WRONGTYPE*", "setrange", "mykey", "0", "bar"),
+	}},
+	{"string", "SETRANGE with out of range offset", []complianceStep{
+		r("!*out of range*", "setrange", "mykey", "-1", "world"),
+		r("!*maximum allowed size*", "setrange", "mykey", "536870908", "world"),
+	}},
+	{"string", "GETRANGE against non-existing key", []complianceStep{
+		r("", "getrange", "mykey", "0", "-1"),
+	}},
+	{"string", "GETRANGE against string value", []complianceStep{
+		r("OK", "set", "mykey", "Hello World"),
+		r("Hell", "getrange", "mykey", "0", "3"),
+		r("Hello World", "getrange", "mykey", "0", "-1"),
+		r("orld", "getrange", "mykey", "-4", "-1"),
+		r("", "getrange", "mykey", "5", "3"),
+		r(" World", "getrange", "mykey", "5", "5000"),
+		r("Hello World", "getrange", "mykey", "-5000", "10000"),
+	}},
+	{"string", "APPEND basics", []complianceStep{
+		r("3", "append", "foo", "bar"),
+		r("bar", "get", "foo"),
+		r("6", "append", "foo", "100"),
+		r("bar100", "get", "foo"),
+	}},
+	{"string", "GETDEL propagate as DEL command to replica", []complianceStep{
+		r("OK", "set", "foo", "bar"),
+		r("bar", "getdel", "foo"),
+		r("(nil)", "get", "foo"),
+	}},
+
+	// tests/unit/type/incr.tcl
+	{"string", "INCR against non existing key", []complianceStep{
+		r("1", "incr", "novar"),
+		r("1", "get", "novar"),
+	}},
+	{"string", "INCR against key created by incr itself", []complianceStep{
+		r("1", "incr", "novar"),
+		r("2", "incr", "novar"),
+	}},
+	{"string", "INCR against key originally set with SET", []complianceStep{
+		r("OK", "set", "novar", "100"),
+		r("101", "incr", "novar"),
+	}},
+	{"string", "INCR over 32bit value", []complianceStep{
+		r("OK", "set", "novar", "17179869184"),
+		r("17179869185", "incr", "novar"),
+	}},
+	{"string", "INCR fails against key with spaces (left)", []complianceStep{
+		r("OK", "set", "novar", "    11"),
+		r("!ERR*", "incr", "novar"),
+	}},
+	{"string", "INCR fails against a key holding a list", []complianceStep{
+		r("1", "rpush", "mylist", "1"),
+		r("!WRONGTYPE*", "incr", "mylist"),
+	}},
+	{"string", "INCR fails against key with overflow", []complianceStep{
+		r("OK", "set", "foo", "9223372036854775807"),
+		r("!ERR*overflow*", "incr", "foo"),
+	}},
+	{"string", "DECRBY over 32bit value with over 32bit increment", []complianceStep{
+		r("OK", "set", "novar", "17179869184"),
+		r("-1", "decrby", "novar", "17179869185"),
+	}},
+	{"string", "INCRBYFLOAT against non existing key", []complianceStep{
+		r("1", "incrbyfloat", "novar", "1"),
+		r("1.25", "incrbyfloat", "novar", "0.25"),
+	}},
+
+	// tests/unit/type/hash.tcl
+	{"hash", "HSET/HLEN - Small hash creation", []complianceStep{
+		r("1", "hset", "smallhash", "a", "1"),
+		r("1", "hset", "smallhash", "b", "2"),
+		r("0", "hset", "smallhash", "a", "3"),
+		r("2", "hlen", "smallhash"),
+	}},
+	{"hash", "HSET in update and insert mode", []complianceStep{
+		r("1", "hset", "smallhash", "a", "1"),
+		r("0", "hset", "smallhash", "a", "newval"),
+		r("newval", "hget", "smallhash", "a"),
+		r("1", "hset", "smallhash", "__foobar123__", "newval"),
+	}},
+	{"hash", "HSETNX target key missing - small hash", []complianceStep{
+		r("1", "hsetnx", "smallhash", "__123123123__", "foo"),
+		r("foo", "hget", "smallhash", "__123123123__"),
+	}},
+	{"hash", "HSETNX target key exists - small hash", []complianceStep{
+		r("1", "hsetnx", "smallhash", "__123123123__", "foo"),
+		r("0", "hsetnx", "smallhash", "__123123123__", "bar"),
+		r("foo", "hget", "smallhash", "__123123123__"),
+	}},
+	{"hash", "HSET/HMSET wrong number of args", []complianceStep{
+		r("!*wrong number*", "hset", "smallhash", "key1", "val1", "key2"),
+		r("!*wrong number*", "hmset", "smallhash", "key1", "val1", "key2"),
+	}},
+	{"hash", "HMSET - small hash", []complianceStep{
+		r("OK", "hmset", "smallhash", "a", "1", "b", "2"),
+		r("{1 2 (nil)}", "hmget", "smallhash", "a", "b", "c"),
+	}},
+	{"hash", "HMGET against non existing key and fields", []complianceStep{
+		r("{(nil) (nil)}", "hmget", "doesntexist", "__123123123__", "__456456456__"),
+	}},
+	{"hash", "Hash commands against wrong type", []complianceStep{
+		r("OK", "set", "wrongtype", "somevalue"),
+		r("!WRONGTYPE*", "hmget", "wrongtype", "field1", "field2"),
+		r("!WRONGTYPE*", "hget", "wrongtype", "field1"),
+		r("!WRONGTYPE*", "hset", "wrongtype", "field1", "v"),
+	}},
+	{"hash", "HKEYS/HVALS/HGETALL - small hash", []complianceStep{
+		r("3", "hset", "smallhash", "a", "1", "b", "2", "c", "3"),
+		rSorted("{a b c}", "hkeys", "smallhash"),
+		rSorted("{1 2 3}", "hvals", "smallhash"),
+		rSorted("{1 2 3 a b c}", "hgetall", "smallhash"),
+	}},
+	{"hash", "HEXISTS", []complianceStep{
+		r("1", "hset", "smallhash", "a", "1"),
+		r("1", "hexists", "smallhash", "a"),
+		r("0", "hexists", "smallhash", "nokey"),
+	}},
+	{"hash", "HDEL and return value", []complianceStep{
+		r("1", "hset", "smallhash", "a", "1"),
+		r("0", "hdel", "smallhash", "nokey"),
+		r("1", "hdel", "smallhash", "a"),
+		r("(nil)", "hget", "smallhash", "a"),
+	}},
+	{"hash", "HDEL - hash becomes empty before deleting all specified fields", []complianceStep{
+		r("OK", "hmset", "myhash", "a", "1", "b", "2", "c", "3"),
+		r("3", "hdel", "myhash", "a", "b", "c", "d", "e"),
+		r("none", "type", "myhash"),
+	}},
+	{"hash", "HINCRBY against non existing database key", []complianceStep{
+		r("2", "hincrby", "htest", "foo", "2"),
+	}},
+	{"hash", "HINCRBY over 32bit value", []complianceStep{
+		r("1", "hset", "smallhash", "tmp", "17179869184"),
+		r("17179869185", "hincrby", "smallhash", "tmp", "1"),
+	}},
+	{"hash", "HINCRBY fails against hash value with spaces (left)", []complianceStep{
+		r("1", "hset", "smallhash", "str", " 11"),
+		r("!ERR*not an integer*", "hincrby", "smallhash", "str", "1"),
+	}},
+	{"hash", "HINCRBY can detect overflows", []complianceStep{
+		r("1", "hset", "hash", "n", "-9223372036854775484"),
+		r("-9223372036854775485", "hincrby", "hash", "n", "-1"),
+		r("!*overflow*", "hincrby", "hash", "n", "-10000"),
+	}},
+	{"hash", "HINCRBYFLOAT against non existing database key", []complianceStep{
+		r("2.5", "hincrbyfloat", "htest", "foo", "2.5"),
+	}},
+	{"hash", "HINCRBYFLOAT fails against hash value with spaces (left)", []complianceStep{
+		r("1", "hset", "smallhash", "str", " 11"),
+		r("!ERR*not*float*", "hincrbyfloat", "smallhash", "str", "1"),
+	}},
+	{"hash", "HSTRLEN against the small hash", []complianceStep{
+		r("1", "hset", "smallhash", "f", "hello"),
+		r("5", "hstrlen", "smallhash", "f"),
+		r("0", "hstrlen", "smallhash", "nokey"),
+	}},
+	{"hash", "HGETDEL", []complianceStep{
+		r("2", "hset", "myhash", "f1", "v1", "f2", "v2"),
+		r("{v1}", "hgetdel", "myhash", "fields", "1", "f1"),
+	}},
+
+	// tests/unit/type/list.tcl
+	{"list", "LPUSH, RPUSH, LLENGTH, LINDEX, LPOP", []complianceStep{
+		r("1", "lpush", "mylist", "a"),
+		r("2", "rpush", "mylist", "b"),
+		r("3", "rpush", "mylist", "c"),
+		r("3", "llen", "mylist"),
+		r("a", "lindex", "mylist", "0"),
+		r("b", "lindex", "mylist", "1"),
+		r("c", "lindex", "mylist", "2"),
+		r("(nil)", "lindex", "mylist", "3"),
+		r("c", "rpop", "mylist"),
+		r("a", "lpop", "mylist"),
+	}},
+	{"list", "LPOP/RPOP with wrong number of arguments", []complianceStep{
+		r("!*wrong number of arguments*", "lpop", "key", "1", "1"),
+		r("!*wrong number of arguments*", "rpop", "key", "2", "2"),
+	}},
+	{"list", "RPOP/LPOP with the optional count argument", []complianceStep{
+		r("7", "rpush", "listcount", "aa", "bb", "cc", "dd", "ee", "ff", "gg"),
+		r("{aa}", "lpop", "listcount", "1"),
+		r("{bb cc}", "lpop", "listcount", "2"),
+		r("{gg}", "rpop", "listcount", "1"),
+		r("{ff ee}", "rpop", "listcount", "2"),
+	}},
+	{"list", "LPOP/RPOP with the count 0 returns an empty array", []complianceStep{
+		r("1", "lpush", "listcount", "zero"),
+		r("{}", "lpop", "listcount", "0"),
+		r("{}", "rpop", "listcount", "0"),
+	}},
+	{"list", "LPOP/RPOP against non existing key", []complianceStep{
+		r("(nil)", "lpop", "non_existing_key"),
+		r("(nil)", "rpop", "non_existing_key"),
+	}},
+	{"list", "LPOP/RPOP with <count> against non existing key in RESP2", []complianceStep{
+		r("(nil)", "lpop", "non_existing_key", "0"),
+		r("(nil)", "lpop", "non_existing_key", "1"),
+		r("(nil)", "rpop", "non_existing_key", "0"),
+		r("(nil)", "rpop", "non_existing_key", "1"),
+	}},
+	{"list", "Variadic RPUSH/LPUSH", []complianceStep{
+		r("4", "lpush", "mylist", "a", "b", "c", "d"),
+		r("8", "rpush", "mylist", "0", "1", "2", "3"),
+		r("{d c b a 0 1 2 3}", "lrange", "mylist", "0", "-1"),
+	}},
+	{"list", "LRANGE basics", []complianceStep{
+		r("10", "rpush", "mylist", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		r("{1 2 3 4 5 6 7 8}", "lrange", "mylist", "1", "-2"),
+		r("{7 8 9}", "lrange", "mylist", "-3", "-1"),
+		r("{4}", "lrange", "mylist", "4", "4"),
+	}},
+	{"list", "LRANGE inverted indexes", []complianceStep{
+		r("10", "rpush", "mylist", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		r("{}", "lrange", "mylist", "6", "2"),
+	}},
+	{"list", "LRANGE out of range indexes including the full list", []complianceStep{
+		r("3", "rpush", "mylist", "1", "2", "3"),
+		r("{1 2 3}", "lrange", "mylist", "-1000", "1000"),
+	}},
+	{"list", "LRANGE against non existing key", []complianceStep{
+		r("{}", "lrange", "nosuchkey", "0", "1"),
+	}},
+	{"list", "LPUSH against non-list value error", []complianceStep{
+		r("OK", "set", "mylist", "foo"),
+		r("!WRONGTYPE*", "lpush", "mylist", "bar"),
+		r("!WRONGTYPE*", "rpush", "mylist", "bar"),
+	}},
+	{"list", "LMPOP single existing list", []complianceStep{
+		r("4", "rpush", "list1", "a", "b", "c", "d"),
+		r("{list1 {a}}", "lmpop", "2", "list1", "list2", "left", "count", "1"),
+		r("{list1 {d c}}", "lmpop", "2", "list1", "list2", "right", "count", "2"),
+		r("(nil)", "lmpop", "2", "list2", "list3", "left"),
+	}},
+	{"list", "LPUSHX, RPUSHX - generic", []complianceStep{
+		r("0", "lpushx", "xlist", "a"),
+		r("0", "llen", "xlist"),
+		r("0", "rpushx", "xlist", "a"),
+	}},
+	{"list", "LINSERT against non-list value error", []complianceStep{
+		r("OK", "set", "k1", "v1"),
+		r("!WRONGTYPE*", "linsert", "k1", "after", "0", "0"),
+	}},
+	{"list", "LSET out of range index", []complianceStep{
+		r("1", "rpush", "mylist", "a"),
+		r("!ERR*range*", "lset", "mylist", "10", "foo"),
+	}},
+	{"list", "LREM remove all the occurrences", []complianceStep{
+		r("6", "rpush", "mylist", "foo", "bar", "foobar", "foobared", "zap", "bar"),
+		r("2", "lrem", "mylist", "0", "bar"),
+		r("{foo foobar foobared zap}", "lrange", "mylist", "0", "-1"),
+	}},
+	{"list", "LTRIM basics", []complianceStep{
+		r("3", "rpush", "mylist", "a", "b", "c"),
+		r("OK", "ltrim", "mylist", "0", "1"),
+		r("{a b}", "lrange", "mylist", "0", "-1"),
+	}},
+
+	// tests/unit/expire.tcl
+	{"expire", "EXPIRE - set timeouts multiple times", []complianceStep{
+		r("OK", "set", "x", "foobar"),
+		r("1", "expire", "x", "5"),
+		r("5", "ttl", "x"),
+		r("1", "expire", "x", "10"),
+		r("10", "ttl", "x"),
+	}},
+	{"expire", "SETEX - Check value", []complianceStep{
+		r("OK", "setex", "y", "1", "foo"),
+		r("foo", "get", "y"),
+	}},
+	{"expire", "SETEX - Wrong time parameter", []complianceStep{
+		r("!*invalid expire*", "setex", "z", "-10", "foo"),
+	}},
+	{"expire", "PERSIST can undo an EXPIRE", []complianceStep{
+		r("OK", "set", "x", "foo"),
+		r("1", "expire", "x", "50"),
+		r("1", "persist", "x"),
+		r("-1", "ttl", "x"),
+	}},
+	{"expire", "PERSIST returns 0 against non existing or non volatile keys", []complianceStep{
+		r("OK", "set", "x", "foo"),
+		r("0", "persist", "foo"),
+		r("0", "persist", "nokeyatall"),
+	}},
+	{"expire", "TTL returns time to live in seconds", []complianceStep{
+		r("OK", "setex", "x", "10", "somevalue"),
+		r("10", "ttl", "x"),
+	}},
+	{"expire", "TTL / PTTL / EXPIRETIME return -1 if key has no expire", []complianceStep{
+		r("OK", "set", "x", "hello"),
+		r("-1", "ttl", "x"),
+		r("-1", "pttl", "x"),
+	}},
+	{"expire", "TTL / PTTL / EXPIRETIME return -2 if key does not exit", []complianceStep{
+		r("-2", "ttl", "x"),
+		r("-2", "pttl", "x"),
+	}},
+	{"expire", "EXPIRE with big integer overflow when basetime is added", []complianceStep{
+		r("OK", "set", "foo", "bar"),
+		r("!ERR invalid expire time in 'expire' command", "expire", "foo", "9223370399119966"),
+	}},
+	{"expire", "EXPIRE with negative expiry", []complianceStep{
+		r("OK", "set", "foo", "bar"),
+		r("1", "expire", "foo", "-1"),
+		r("(nil)", "get", "foo"),
+	}},
+}
+
+// renderTcl renders v the way the upstream Tcl client returns it.
+func renderTcl(v redisproto.Value, sorted bool) string {
+	switch v.Kind {
+	case redisproto.KindSimpleString:
+		return v.Str
+	case redisproto.KindError:
+		return "!" + v.Str
+	case redisproto.KindInteger:
+		return strconv.FormatInt(v.Int, 10)
+	case redisproto.KindBulkString:
+		return string(v.Bulk)
+	case redisproto.KindNull:
+		return "(nil)"
+	case redisproto.KindArray:
+		items := make([]string, len(v.Array))
+		for i, item := range v.Array {
+			s := renderTcl(item, false)
+			// Nested arrays come back braced already; other elements are
+			// braced when Tcl would need it to keep them one word.
+			if item.Kind != redisproto.KindArray && (s == "" || strings.ContainsAny(s, " \t\r\n")) {
+				s = "{" + s + "}"
+			}
+			items[i] = s
+		}
+		if sorted {
+			sort.Strings(items)
+		}
+		return "{" + strings.Join(items, " ") + "}"
+	default:
+		return fmt.Sprintf("(%s)", v.Kind)
+	}
+}
+
+// missingCommands returns the commands used by c that the server does not
+// register.
+func (c complianceCase) missingCommands() []string {
+	var missing []string
+	for _, step := range c.steps {
+		name := step.args[0]
+		if _, ok := commandTable[name]; !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func TestCompliance(t *testing.T) {
+	type tally struct {
+		passed, failed int
+		missing        []string
+	}
+	report := map[string]*tally{}
+	var groups []string
+
+	for _, tc := range complianceCases {
+		g := report[tc.group]
+		if g == nil {
+			g = &tally{}
+			report[tc.group] = g
+			groups = append(groups, tc.group)
+		}
+		if missing := tc.missingCommands(); len(missing) > 0 {
+			for _, name := range missing {
+				if !slices.Contains(g.missing, name) {
+					g.missing = append(g.missing, name)
+				}
+			}
+			t.Run(tc.group+"/"+tc.name, func(t *testing.T) {
+				t.Skipf("unimplemented: %s", strings.Join(missing, ", "))
+			})
+			continue
+		}
+		ok := t.Run(tc.group+"/"+tc.name, func(t *testing.T) {
+			client := newTestClient(t)
+			for _, step := range tc.steps {
+				got := renderTcl(client.do(step.args...), step.sorted)
+				if !stepMatches(step.want, got) {
+					t.Fatalf("%q: got %q, want %q", step.args, got, step.want)
+				}
+			}
+		})
+		if ok {
+			g.passed++
+		} else {
+			g.failed++
+		}
+	}
+
+	for _, group := range groups {
+		g := report[group]
+		total := 0
+		for _, tc := range complianceCases {
+			if tc.group == group {
+				total++
+			}
+		}
+		sort.Strings(g.missing)
+		t.Logf("%-7s %2d/%2d passed, %d failed, %d unimplemented (missing: %s)",
+			group, g.passed, total, g.failed, total-g.passed-g.failed, strings.Join(g.missing, " "))
+	}
+}
+
+// stepMatches compares a rendered reply with a step's expectation.
+func stepMatches(want, got string) bool {
+	if strings.HasPrefix(want, "!") {
+		if !strings.HasPrefix(got, "!") {
+			return false
+		}
+		ok, err := path.Match(want[1:], got[1:])
+		return err == nil && ok
+	}
+	return want == got
+}
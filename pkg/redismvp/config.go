@@ -0,0 +1,427 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redis log levels mapped onto slog levels. Redis has "verbose" between
+// debug and notice, and "nothing" to silence the log entirely.
+const (
+	LevelDebug   = slog.LevelDebug
+	LevelVerbose = slog.Level(-2)
+	LevelNotice  = slog.LevelInfo
+	LevelWarning = slog.LevelWarn
+	LevelNothing = slog.Level(math.MaxInt32)
+)
+
+// DefaultSlowLogThreshold is the command latency above which a command is
+// logged as slow when Config.SlowLogThreshold is zero.
+const DefaultSlowLogThreshold = 10 * time.Millisecond
+
+// DefaultMaxClients is the connection limit used when Config.MaxClients is
+// zero, the Redis default.
+const DefaultMaxClients = 10000
+
+// DefaultAppendFilename is the append only file used when
+// Config.AppendFilename is empty.
+const DefaultAppendFilename = "appendonly.aof"
+
+// AppendFsync is how often the append only file is fsynced, like the Redis
+// "appendfsync" setting.
+type AppendFsync int
+
+const (
+	// AppendFsyncEverySec fsyncs once per second, so a crash of the machine
+	// loses at most about a second of writes.
+	AppendFsyncEverySec AppendFsync = iota
+	// AppendFsyncAlways fsyncs every write before it is acknowledged.
+	AppendFsyncAlways
+	// AppendFsyncNo leaves flushing to the operating system.
+	AppendFsyncNo
+)
+
+// MaxMemoryPolicy is how keys are chosen for eviction once the dataset
+// outgrows Config.MaxMemory, like the Redis "maxmemory-policy" setting.
+// The server has no key expiry, so the volatile-* policies do not exist.
+type MaxMemoryPolicy int
+
+const (
+	// MaxMemoryNoEviction evicts nothing: commands that can grow the
+	// dataset are refused while it is over the limit.
+	MaxMemoryNoEviction MaxMemoryPolicy = iota
+	// MaxMemoryAllKeysLRU evicts the least recently used keys.
+	MaxMemoryAllKeysLRU
+	// MaxMemoryAllKeysLFU evicts the least frequently used keys, as
+	// tracked by approximate access counters.
+	MaxMemoryAllKeysLFU
+	// MaxMemoryAllKeysRandom evicts random keys.
+	MaxMemoryAllKeysRandom
+)
+
+// Defaults of the eviction settings, the Redis ones.
+const (
+	DefaultMaxMemorySamples = 5
+	DefaultLFULogFactor     = 10
+	DefaultLFUDecayTime     = time.Minute
+)
+
+// Config controls how a Server is started.
+type Config struct {
+	// Addr is the listen address, e.g. 127.0.0.1:6379.
+	// Use 127.0.0.1:0 to allocate an ephemeral port.
+	Addr string
+
+	// Pipe starts the server without a listener, Addr being ignored:
+	// clients connect with [Server.DialPipe] only. Tests use it to run
+	// many servers in parallel without taking ports.
+	Pipe bool
+
+	// LogLevel is the minimum level written to LogOutput. Use
+	// [ParseLogLevel] to convert a Redis-style loglevel name.
+	LogLevel slog.Level
+
+	// LogOutput receives text-formatted log records. Defaults to os.Stderr.
+	// Ignored when Logger is set.
+	LogOutput io.Writer
+
+	// Logger overrides LogLevel and LogOutput entirely.
+	Logger *slog.Logger
+
+	// Timeout closes client connections idle for longer than this, like
+	// the Redis "timeout" setting. Zero disables idle disconnection.
+	Timeout time.Duration
+
+	// TCPKeepAlive sends TCP keepalive probes to clients idle for this
+	// long, like the Redis "tcp-keepalive" setting, so connections to
+	// peers that vanished are eventually closed. Zero disables them.
+	TCPKeepAlive time.Duration
+
+	// TCPNoDelay turns off Nagle's algorithm on client connections, so
+	// replies are sent without waiting to coalesce them. Redis always
+	// does; redis-server turns it on by default.
+	TCPNoDelay bool
+
+	// SlowLogThreshold is the command execution time above which a command
+	// is logged at warning level. Defaults to DefaultSlowLogThreshold;
+	// a negative value disables slow command logging.
+	SlowLogThreshold time.Duration
+
+	// ReplicaOf makes the server a replica of the master at this host:port
+	// address, like the Redis "replicaof" setting. Empty starts a master.
+	ReplicaOf string
+
+	// ReplBacklogSize is the size in bytes of the replication backlog kept
+	// for partial resynchronization. Defaults to DefaultReplBacklogSize.
+	ReplBacklogSize int
+
+	// ReplicaWritable lets clients of a replica run write commands, like
+	// setting the Redis "replica-read-only" option to no. Such writes are
+	// local to the replica and never propagated.
+	ReplicaWritable bool
+
+	// Dir is the directory of the persistence files, like the Redis "dir"
+	// setting: relative DBFilename and AppendFilename paths are resolved
+	// against it, and the temporary files that replace them are written
+	// there. It is created if missing. Defaults to the working directory.
+	Dir string
+
+	// DBFilename is the path of the RDB file written by SAVE and BGSAVE,
+	// and loaded at startup unless AppendOnly is set. Defaults to
+	// DefaultDBFilename in Dir.
+	DBFilename string
+
+	// AppendOnly logs every write to an append only file, which is
+	// replayed at startup, like the Redis "appendonly" setting.
+	AppendOnly bool
+
+	// AppendFilename is the path of the append only file. Defaults to
+	// DefaultAppendFilename in Dir.
+	AppendFilename string
+
+	// AppendFsync is the fsync policy of the append only file. Use
+	// [ParseAppendFsync] to convert a Redis-style policy name.
+	AppendFsync AppendFsync
+
+	// AOFNoRDBPreamble makes BGREWRITEAOF write the dataset as commands
+	// instead of an RDB snapshot, like setting the Redis
+	// "aof-use-rdb-preamble" option to no.
+	AOFNoRDBPreamble bool
+
+	// MaxClients is the number of clients above which new connections are
+	// refused with an error, like the Redis "maxclients" setting. Defaults
+	// to DefaultMaxClients.
+	MaxClients int
+
+	// Databases is the number of databases SELECT accepts, like the Redis
+	// "databases" setting. The server has a single keyspace, so only one
+	// database is supported; zero means one.
+	Databases int
+
+	// ProtectedMode refuses connections from non-loopback addresses, like
+	// the Redis "protected-mode" setting. The server has no password, so
+	// it applies to every remote connection.
+	ProtectedMode bool
+
+	// MaxMemory is the memory limit in bytes for the dataset, like the
+	// Redis "maxmemory" setting. Past it, write commands that can grow the
+	// dataset first evict keys according to MaxMemoryPolicy, and are
+	// refused if that frees nothing. Zero means no limit. Use
+	// [ParseMemorySize] to convert a size such as "100mb".
+	MaxMemory int64
+
+	// MaxMemoryPolicy selects the keys evicted to stay under MaxMemory.
+	// Use [ParseMaxMemoryPolicy] to convert a Redis-style policy name.
+	MaxMemoryPolicy MaxMemoryPolicy
+
+	// MaxMemorySamples is the number of keys sampled to pick each key to
+	// evict, like the Redis "maxmemory-samples" setting. Defaults to
+	// DefaultMaxMemorySamples.
+	MaxMemorySamples int
+
+	// LFULogFactor controls how many hits it takes to saturate the access
+	// counters of the allkeys-lfu policy, like the Redis "lfu-log-factor"
+	// setting. Defaults to DefaultLFULogFactor.
+	LFULogFactor int
+
+	// LFUDecayTime is how long a key must go unused for its access counter
+	// to drop by one, like the Redis "lfu-decay-time" setting, which is in
+	// minutes. Defaults to DefaultLFUDecayTime; a negative value disables
+	// decay.
+	LFUDecayTime time.Duration
+
+	// ExtensionCommands serves the extension commands, which are not part
+	// of Redis and are namespaced with an "x." prefix, such as X.SETIFEQ.
+	// Off by default, so the server only answers to Redis commands.
+	ExtensionCommands bool
+
+	// DebugKeyspace serves DEBUG KEYSPACE, which walks every key and
+	// reveals their names, so only administrators should be able to run
+	// it. Off by default, when the subcommand is refused.
+	DebugKeyspace bool
+
+	// RenameCommands maps command names to the names they are served
+	// under instead, like the Redis "rename-command" setting, so that
+	// deployments can hide commands such as DEBUG behind an unguessable
+	// name. An empty new name disables the command. Names are
+	// case-insensitive. Commands are still written to replicas and the
+	// append only file under their own names.
+	RenameCommands map[string]string
+
+	// faults injects failures into client connections in tests.
+	faults faultInjector
+}
+
+// ParseLogLevel converts a Redis loglevel name (debug, verbose, notice,
+// warning, nothing) into a slog level.
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "verbose":
+		return LevelVerbose, nil
+	case "notice":
+		return LevelNotice, nil
+	case "warning":
+		return LevelWarning, nil
+	case "nothing":
+		return LevelNothing, nil
+	default:
+		return 0, fmt.Errorf("invalid loglevel %q", name)
+	}
+}
+
+// ParseAppendFsync converts a Redis appendfsync policy name (always,
+// everysec, no) into an AppendFsync.
+func ParseAppendFsync(name string) (AppendFsync, error) {
+	switch strings.ToLower(name) {
+	case "always":
+		return AppendFsyncAlways, nil
+	case "everysec":
+		return AppendFsyncEverySec, nil
+	case "no":
+		return AppendFsyncNo, nil
+	default:
+		return 0, fmt.Errorf("invalid appendfsync policy %q", name)
+	}
+}
+
+// String returns the Redis name of the policy.
+func (f AppendFsync) String() string {
+	switch f {
+	case AppendFsyncEverySec:
+		return "everysec"
+	case AppendFsyncAlways:
+		return "always"
+	case AppendFsyncNo:
+		return "no"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMaxMemoryPolicy converts a Redis maxmemory-policy name
+// (noeviction, allkeys-lru, allkeys-lfu, allkeys-random) into a
+// MaxMemoryPolicy.
+func ParseMaxMemoryPolicy(name string) (MaxMemoryPolicy, error) {
+	name = strings.ToLower(name)
+	for p, n := range maxMemoryPolicyNames {
+		if n == name {
+			return MaxMemoryPolicy(p), nil
+		}
+	}
+	if strings.HasPrefix(name, "volatile-") {
+		return 0, fmt.Errorf("maxmemory-policy %q is not supported: keys cannot expire", name)
+	}
+	return 0, fmt.Errorf("invalid maxmemory-policy %q", name)
+}
+
+var maxMemoryPolicyNames = []string{"noeviction", "allkeys-lru", "allkeys-lfu", "allkeys-random"}
+
+// String returns the Redis name of the policy.
+func (p MaxMemoryPolicy) String() string {
+	if int(p) < len(maxMemoryPolicyNames) {
+		return maxMemoryPolicyNames[p]
+	}
+	return "unknown"
+}
+
+// ParseMemorySize converts a Redis memory size, a number with an optional
+// unit (b, k, kb, m, mb, g, gb, case-insensitive), into bytes. As in
+// Redis, k, m and g are powers of 1000 and kb, mb and gb powers of 1024.
+func ParseMemorySize(s string) (int64, error) {
+	lower := strings.ToLower(s)
+	digits := strings.TrimRight(lower, "bkmg")
+	mult := int64(1)
+	switch lower[len(digits):] {
+	case "", "b":
+	case "k":
+		mult = 1000
+	case "kb":
+		mult = 1 << 10
+	case "m":
+		mult = 1000 * 1000
+	case "mb":
+		mult = 1 << 20
+	case "g":
+		mult = 1000 * 1000 * 1000
+	case "gb":
+		mult = 1 << 30
+	default:
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	return n * mult, nil
+}
+
+func (c Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	out := c.LogOutput
+	if out == nil {
+		out = os.Stderr
+	}
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: c.LogLevel}))
+}
+
+// parseReplicaOf splits a ReplicaOf address into host and port.
+func parseReplicaOf(addr string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid replicaof address %q: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid replicaof port %q", portStr)
+	}
+	return host, port, nil
+}
+
+func (c Config) slowLogThreshold() time.Duration {
+	if c.SlowLogThreshold == 0 {
+		return DefaultSlowLogThreshold
+	}
+	return c.SlowLogThreshold
+}
+
+func (c Config) maxClients() int {
+	if c.MaxClients == 0 {
+		return DefaultMaxClients
+	}
+	return c.MaxClients
+}
+
+// keepAlive returns the keepalive settings of client connections. As in
+// Redis, once TCPKeepAlive elapses probes go out every third of it, and
+// the connection is dropped after three go unanswered.
+func (c Config) keepAlive() net.KeepAliveConfig {
+	if c.TCPKeepAlive <= 0 {
+		return net.KeepAliveConfig{}
+	}
+	return net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     c.TCPKeepAlive,
+		Interval: max(c.TCPKeepAlive/3, time.Second),
+		Count:    3,
+	}
+}
+
+func (c Config) appendFilename() string {
+	if c.AppendFilename == "" {
+		return c.inDir(DefaultAppendFilename)
+	}
+	return c.inDir(c.AppendFilename)
+}
+
+func (c Config) dbFilename() string {
+	if c.DBFilename == "" {
+		return c.inDir(DefaultDBFilename)
+	}
+	return c.inDir(c.DBFilename)
+}
+
+// inDir resolves a persistence file path against Dir.
+func (c Config) inDir(name string) string {
+	if c.Dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.Dir, name)
+}
+
+func (c Config) maxMemorySamples() int {
+	if c.MaxMemorySamples == 0 {
+		return DefaultMaxMemorySamples
+	}
+	return c.MaxMemorySamples
+}
+
+func (c Config) lfuLogFactor() int {
+	if c.LFULogFactor == 0 {
+		return DefaultLFULogFactor
+	}
+	return c.LFULogFactor
+}
+
+func (c Config) lfuDecayTime() time.Duration {
+	if c.LFUDecayTime == 0 {
+		return DefaultLFUDecayTime
+	}
+	return c.LFUDecayTime
+}
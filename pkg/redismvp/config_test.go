@@ -0,0 +1,125 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"debug":   LevelDebug,
+		"VERBOSE": LevelVerbose,
+		"notice":  LevelNotice,
+		"warning": LevelWarning,
+		"nothing": LevelNothing,
+	}
+	for name, want := range cases {
+		got, err := ParseLogLevel(name)
+		if err != nil {
+			t.Fatalf("ParseLogLevel(%q) error: %v", name, err)
+		}
+		if got != want {
+			t.Fatalf("ParseLogLevel(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Fatal("expected error for unknown loglevel")
+	}
+}
+
+func TestParseAppendFsync(t *testing.T) {
+	cases := map[string]AppendFsync{
+		"always":   AppendFsyncAlways,
+		"EVERYSEC": AppendFsyncEverySec,
+		"no":       AppendFsyncNo,
+	}
+	for name, want := range cases {
+		if got, err := ParseAppendFsync(name); err != nil || got != want {
+			t.Fatalf("ParseAppendFsync(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseAppendFsync("sometimes"); err == nil {
+		t.Fatal("expected error for unknown appendfsync policy")
+	}
+}
+
+func TestParseMaxMemoryPolicy(t *testing.T) {
+	for _, want := range []MaxMemoryPolicy{MaxMemoryNoEviction, MaxMemoryAllKeysLRU, MaxMemoryAllKeysLFU, MaxMemoryAllKeysRandom} {
+		if got, err := ParseMaxMemoryPolicy(strings.ToUpper(want.String())); err != nil || got != want {
+			t.Fatalf("ParseMaxMemoryPolicy(%q) = %v, %v", want, got, err)
+		}
+	}
+	if _, err := ParseMaxMemoryPolicy("volatile-lru"); err == nil || !strings.Contains(err.Error(), "cannot expire") {
+		t.Fatalf("volatile-lru: %v", err)
+	}
+	if _, err := ParseMaxMemoryPolicy("allkeys-mru"); err == nil {
+		t.Fatal("expected error for unknown maxmemory-policy")
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	cases := map[string]int64{
+		"0":     0,
+		"512":   512,
+		"100b":  100,
+		"2k":    2000,
+		"2KB":   2048,
+		"3m":    3000000,
+		"3mb":   3 << 20,
+		"1g":    1000000000,
+		"1Gb":   1 << 30,
+		"12345": 12345,
+	}
+	for in, want := range cases {
+		if got, err := ParseMemorySize(in); err != nil || got != want {
+			t.Fatalf("ParseMemorySize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "mb", "-1", "1tb", "1.5gb", "99999999999gb"} {
+		if _, err := ParseMemorySize(in); err == nil {
+			t.Fatalf("ParseMemorySize(%q) succeeded", in)
+		}
+	}
+}
+
+func TestConfigLoggerHonorsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := Config{LogLevel: LevelNotice, LogOutput: &buf}.logger()
+
+	log.Log(context.Background(), LevelVerbose, "client connected")
+	log.Warn("protocol error")
+
+	out := buf.String()
+	if strings.Contains(out, "client connected") {
+		t.Fatalf("verbose record should be filtered at notice level: %q", out)
+	}
+	if !strings.Contains(out, "protocol error") {
+		t.Fatalf("warning record missing: %q", out)
+	}
+}
+
+func TestConfigKeepAlive(t *testing.T) {
+	cases := []struct {
+		keepAlive time.Duration
+		want      net.KeepAliveConfig
+	}{
+		{0, net.KeepAliveConfig{}},
+		{300 * time.Second, net.KeepAliveConfig{Enable: true, Idle: 300 * time.Second, Interval: 100 * time.Second, Count: 3}},
+		{2 * time.Second, net.KeepAliveConfig{Enable: true, Idle: 2 * time.Second, Interval: time.Second, Count: 3}},
+	}
+	for _, tc := range cases {
+		if got := (Config{TCPKeepAlive: tc.keepAlive}).keepAlive(); got != tc.want {
+			t.Errorf("TCPKeepAlive %s: got %+v, want %+v", tc.keepAlive, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements maxmemory: accounting of the memory used by the
+// dataset, access tracking, and eviction.
+//
+// # Accounting
+//
+// Memory is estimated rather than measured. Each key is charged a fixed
+// overhead plus the bytes of its name and value; aggregates are charged
+// from a sample of their elements, like MEMORY USAGE does in Redis. A
+// key's charge is recomputed after every write command that names it, and
+// the whole dataset is recounted after loading and after write commands
+// without key arguments.
+//
+// # Access tracking
+//
+// Like Redis, keys carry either an access time (LRU) or an 8-bit access
+// counter (LFU). The counter grows logarithmically: a hit increments it
+// with probability 1/((counter-lfuInitVal)*LFULogFactor+1), so with the
+// default factor it takes about a million hits to saturate it at 255. A
+// counter drops by one for every LFUDecayTime the key goes unused, which
+// is applied lazily the next time it is read. New keys start at
+// lfuInitVal so they are not evicted before they get a chance to be hit.
+//
+// # Eviction
+//
+// Before a command that can grow the dataset (flagged denyoom) runs while
+// the dataset is over the limit, keys are evicted until it is not. Each
+// pick samples MaxMemorySamples keys into a pool of the best candidates
+// seen so far, and evicts the best of the pool. Evictions are propagated
+// to replicas and the AOF as DEL. Replicas never evict: they apply what
+// their master evicted.
+
+package redismvp
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+const (
+	// lfuInitVal is the access counter of a new key.
+	lfuInitVal = 5
+	// evictionPoolSize is the number of candidates kept between picks.
+	evictionPoolSize = 16
+
+	// keyOverhead approximates the bookkeeping of a key: its map entry and
+	// the headers of its name and value.
+	keyOverhead = 64
+	// elemOverhead approximates the bookkeeping of an aggregate element.
+	elemOverhead = 16
+	// sizeSamples is the number of elements aggregates are sized from.
+	sizeSamples = 16
+)
+
+const errOOM = "OOM command not allowed when used memory > 'maxmemory'."
+
+// keyMeta is what the evictor knows about a key.
+type keyMeta struct {
+	size int64
+	// clock is the last access of the key, in nanoseconds since the epoch.
+	clock   int64
+	counter uint8
+}
+
+type evictionCandidate struct {
+	key string
+	// score grows with how good a victim the key is.
+	score int64
+}
+
+// evictor accounts for memory and picks keys to evict. It is only used
+// with the store lock held.
+type evictor struct {
+	policy    MaxMemoryPolicy
+	maxMemory int64
+	samples   int
+	logFactor int
+	decayTime time.Duration
+
+	used    int64
+	meta    map[string]keyMeta
+	pool    []evictionCandidate
+	evicted int64
+
+	now   func() time.Time
+	float func() float64
+}
+
+// newEvictor returns the evictor for cfg, or nil when there is neither a
+// memory limit nor a policy that tracks access.
+func newEvictor(cfg Config) *evictor {
+	if cfg.MaxMemory <= 0 && cfg.MaxMemoryPolicy == MaxMemoryNoEviction {
+		return nil
+	}
+	return &evictor{
+		policy:    cfg.MaxMemoryPolicy,
+		maxMemory: cfg.MaxMemory,
+		samples:   cfg.maxMemorySamples(),
+		logFactor: cfg.lfuLogFactor(),
+		decayTime: cfg.lfuDecayTime(),
+		meta:      make(map[string]keyMeta),
+		now:       time.Now,
+		float:     rand.Float64,
+	}
+}
+
+// account recomputes the size of keys after a write.
+func (e *evictor) account(kv map[string]any, keys []string) {
+	for _, key := range keys {
+		m, tracked := e.meta[key]
+		v, ok := kv[key]
+		switch {
+		case !ok && tracked:
+			e.used -= m.size
+			delete(e.meta, key)
+		case ok && tracked:
+			size := entrySize(key, v)
+			e.used += size - m.size
+			m.size = size
+			e.meta[key] = m
+		case ok:
+			m = keyMeta{size: entrySize(key, v), clock: e.now().UnixNano(), counter: lfuInitVal}
+			e.used += m.size
+			e.meta[key] = m
+		}
+	}
+}
+
+// recount recomputes the size of every key, keeping the access history of
+// the keys already tracked.
+func (e *evictor) recount(kv map[string]any) {
+	now := e.now().UnixNano()
+	meta := make(map[string]keyMeta, len(kv))
+	e.used = 0
+	for key, v := range kv {
+		m, ok := e.meta[key]
+		if !ok {
+			m = keyMeta{clock: now, counter: lfuInitVal}
+		}
+		m.size = entrySize(key, v)
+		e.used += m.size
+		meta[key] = m
+	}
+	e.meta = meta
+	e.pool = e.pool[:0]
+}
+
+// touch records an access to keys.
+func (e *evictor) touch(keys []string) {
+	now := e.now().UnixNano()
+	for _, key := range keys {
+		m, ok := e.meta[key]
+		if !ok {
+			continue
+		}
+		if e.policy == MaxMemoryAllKeysLFU {
+			m.counter = e.lfuIncr(e.lfuDecay(m, now))
+		}
+		m.clock = now
+		e.meta[key] = m
+	}
+}
+
+// lfuDecay returns the counter of m once decayed to now.
+func (e *evictor) lfuDecay(m keyMeta, now int64) uint8 {
+	if e.decayTime <= 0 {
+		return m.counter
+	}
+	periods := (now - m.clock) / int64(e.decayTime)
+	if periods >= int64(m.counter) {
+		return 0
+	}
+	return m.counter - uint8(max(periods, 0))
+}
+
+// lfuIncr returns counter after a hit.
+func (e *evictor) lfuIncr(counter uint8) uint8 {
+	if counter == 255 {
+		return counter
+	}
+	base := max(int(counter)-lfuInitVal, 0)
+	if e.float() < 1/float64(base*e.logFactor+1) {
+		counter++
+	}
+	return counter
+}
+
+// frequency returns the decayed access counter of key.
+func (e *evictor) frequency(key string) (uint8, bool) {
+	m, ok := e.meta[key]
+	if !ok {
+		return 0, false
+	}
+	return e.lfuDecay(m, e.now().UnixNano()), true
+}
+
+// score rates key as a victim: its idle time for LRU, or how far its
+// counter is below saturation for LFU.
+func (e *evictor) score(key string, now int64) int64 {
+	m := e.meta[key]
+	if e.policy == MaxMemoryAllKeysLFU {
+		return 255 - int64(e.lfuDecay(m, now))
+	}
+	return now - m.clock
+}
+
+// victim picks the key to evict next.
+func (e *evictor) victim(kv map[string]any) (string, bool) {
+	if e.policy == MaxMemoryAllKeysRandom {
+		for key := range kv {
+			return key, true
+		}
+		return "", false
+	}
+	now := e.now().UnixNano()
+	n := 0
+	for key := range kv {
+		if n == e.samples {
+			break
+		}
+		n++
+		e.offer(evictionCandidate{key: key, score: e.score(key, now)})
+	}
+	for len(e.pool) > 0 {
+		best := e.pool[len(e.pool)-1]
+		e.pool = e.pool[:len(e.pool)-1]
+		if _, ok := kv[best.key]; ok {
+			return best.key, true
+		}
+	}
+	return "", false
+}
+
+// offer adds c to the pool, kept in ascending score order, if it beats the
+// worst candidate of a full pool.
+func (e *evictor) offer(c evictionCandidate) {
+	if i := slices.IndexFunc(e.pool, func(p evictionCandidate) bool { return p.key == c.key }); i >= 0 {
+		e.pool = slices.Delete(e.pool, i, i+1)
+	}
+	if len(e.pool) == evictionPoolSize {
+		if c.score <= e.pool[0].score {
+			return
+		}
+		e.pool = slices.Delete(e.pool, 0, 1)
+	}
+	i, _ := slices.BinarySearchFunc(e.pool, c.score, func(p evictionCandidate, score int64) int {
+		return cmp.Compare(p.score, score)
+	})
+	e.pool = slices.Insert(e.pool, i, c)
+}
+
+// freeMemory evicts keys until the dataset fits in maxmemory, and reports
+// whether it does. The caller holds the store lock.
+func (s *Server) freeMemory() bool {
+	e := s.evict
+	for e.maxMemory > 0 && e.used > e.maxMemory {
+		if e.policy == MaxMemoryNoEviction {
+			return false
+		}
+		key, ok := e.victim(s.store.kv)
+		if !ok {
+			return false
+		}
+		s.store.preserve([]string{key})
+		s.store.del(key)
+		e.account(s.store.kv, []string{key})
+		e.evicted++
+		s.propagate([][]byte{[]byte("DEL"), []byte(key)})
+	}
+	return true
+}
+
+// outOfMemory frees memory before cmd if it can grow the dataset, and
+// reports whether cmd must be refused because that failed.
+func (c *clientConn) outOfMemory(cmd *command) bool {
+	s := c.server
+	if s.evict == nil || c.replayed || s.repl.link != nil || !slices.Contains(cmd.flags, flagDenyOOM) {
+		return false
+	}
+	return !s.freeMemory()
+}
+
+// track updates the evictor after cmd ran on keys. Keys the command
+// created are not counted as accessed.
+func (e *evictor) track(kv map[string]any, cmd *command, keys []string, write bool) {
+	if write && cmd.firstKey == 0 && cmd.numKeysAt == 0 {
+		e.recount(kv)
+		return
+	}
+	e.touch(keys)
+	if write {
+		e.account(kv, keys)
+	}
+}
+
+// entrySize estimates the memory used by key holding v.
+func entrySize(key string, v any) int64 {
+	size := int64(keyOverhead + len(key))
+	switch v := v.(type) {
+	case []byte:
+		size += int64(len(v))
+	case *listValue:
+		items := v.items[v.head:]
+		size += sampledSize(len(items), func(yield func(int) bool) {
+			for _, item := range items {
+				if !yield(len(item)) {
+					return
+				}
+			}
+		})
+	case setValue:
+		size += sampledSize(len(v), func(yield func(int) bool) {
+			for member := range v {
+				if !yield(len(member)) {
+					return
+				}
+			}
+		})
+	case hashValue:
+		size += sampledSize(len(v), func(yield func(int) bool) {
+			for field, value := range v {
+				if !yield(len(field) + len(value)) {
+					return
+				}
+			}
+		})
+	case *zsetValue:
+		// Members are held by both the score map and the order slice.
+		size += sampledSize(len(v.order), func(yield func(int) bool) {
+			for _, e := range v.order {
+				if !yield(2*len(e.member) + 16) {
+					return
+				}
+			}
+		})
+	}
+	return size
+}
+
+// sampledSize estimates the size of n elements from the sizes of the first
+// sizeSamples yielded by sizes.
+func sampledSize(n int, sizes func(yield func(int) bool)) int64 {
+	if n == 0 {
+		return 0
+	}
+	sampled, total := 0, 0
+	for size := range sizes {
+		total += size + elemOverhead
+		if sampled++; sampled == sizeSamples {
+			break
+		}
+	}
+	return int64(total) * int64(n) / int64(sampled)
+}
@@ -0,0 +1,235 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// newEvictingClient returns a test client of a server configured with
+// cfg's eviction settings, whose clock only moves when advanced.
+func newEvictingClient(t *testing.T, cfg Config) (*testClient, func(time.Duration)) {
+	t.Helper()
+	tc := newTestClient(t)
+	ev := newEvictor(cfg)
+	now := time.Unix(1000, 0)
+	ev.now = func() time.Time { return now }
+	tc.c.server.evict = ev
+	return tc, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestObjectFreq(t *testing.T) {
+	tc, advance := newEvictingClient(t, Config{MaxMemoryPolicy: MaxMemoryAllKeysLFU, LFUDecayTime: time.Minute})
+	ev := tc.c.server.evict
+	ev.float = func() float64 { return 0 } // every hit increments
+
+	tc.do("SET", "k", "v")
+	tc.wantInt(lfuInitVal, "OBJECT", "FREQ", "k")
+	for range 3 {
+		tc.do("GET", "k")
+	}
+	tc.wantInt(lfuInitVal+3, "OBJECT", "FREQ", "k")
+	tc.wantInt(lfuInitVal+3, "OBJECT", "FREQ", "k") // OBJECT is not an access
+	tc.wantNull("OBJECT", "FREQ", "missing")
+
+	advance(2*time.Minute + time.Second)
+	tc.wantInt(lfuInitVal+1, "OBJECT", "FREQ", "k")
+	tc.do("GET", "k")
+	tc.wantInt(lfuInitVal+2, "OBJECT", "FREQ", "k")
+	advance(time.Hour)
+	tc.wantInt(0, "OBJECT", "FREQ", "k")
+
+	plain := newTestClient(t)
+	plain.do("SET", "k", "v")
+	plain.wantError("ERR An LFU maxmemory policy is not selected, access frequency not tracked. "+
+		"Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.",
+		"OBJECT", "FREQ", "k")
+	plain.wantError("ERR wrong number of arguments for 'object|freq' command", "OBJECT", "FREQ")
+}
+
+func TestLFUCounterIsLogarithmic(t *testing.T) {
+	ev := newEvictor(Config{MaxMemoryPolicy: MaxMemoryAllKeysLFU})
+	rng := rand.New(rand.NewSource(1))
+	ev.float = rng.Float64
+	counter := uint8(lfuInitVal)
+	for range 1000 {
+		counter = ev.lfuIncr(counter)
+	}
+	// With the default factor, 1000 hits reach about 18 and a million 255.
+	if counter < 12 || counter > 25 {
+		t.Fatalf("counter after 1000 hits = %d", counter)
+	}
+	if got := ev.lfuIncr(255); got != 255 {
+		t.Fatalf("saturated counter incremented to %d", got)
+	}
+}
+
+func TestMemoryAccounting(t *testing.T) {
+	tc, _ := newEvictingClient(t, Config{MaxMemory: 1 << 20})
+	ev := tc.c.server.evict
+
+	tc.do("SET", "k", "12345")
+	if want := int64(keyOverhead + 1 + 5); ev.used != want {
+		t.Fatalf("used = %d, want %d", ev.used, want)
+	}
+	tc.do("SET", "k", "1234567890")
+	tc.do("RPUSH", "l", "a", "b", "c")
+	if want := int64(2*keyOverhead + 1 + 10 + 1 + 3*(1+elemOverhead)); ev.used != want {
+		t.Fatalf("used = %d, want %d", ev.used, want)
+	}
+	tc.do("DEL", "k")
+	if want := int64(keyOverhead + 1 + 3*(1+elemOverhead)); ev.used != want {
+		t.Fatalf("used after DEL = %d, want %d", ev.used, want)
+	}
+	tc.do("DEL", "l")
+	if ev.used != 0 || len(ev.meta) != 0 {
+		t.Fatalf("used after deleting every key = %d, %d keys", ev.used, len(ev.meta))
+	}
+}
+
+// fillKeys sets n keys named prefix and a three-digit number.
+func fillKeys(tc *testClient, prefix string, n int) {
+	for i := range n {
+		tc.do("SET", fmt.Sprintf("%s%03d", prefix, i), "0123456")
+	}
+}
+
+func TestEvictionAllKeysLRU(t *testing.T) {
+	const keySize = keyOverhead + 10
+	tc, advance := newEvictingClient(t, Config{
+		MaxMemory:        10 * keySize,
+		MaxMemoryPolicy:  MaxMemoryAllKeysLRU,
+		MaxMemorySamples: 64, // sample every key
+	})
+	for i := range 10 {
+		tc.do("SET", fmt.Sprintf("k%02d", i), "0123456")
+		advance(time.Second)
+	}
+	tc.do("GET", "k00")
+	tc.do("GET", "k01")
+	advance(time.Second)
+
+	// Over the limit by one key: the next write evicts k02, the least
+	// recently used, before it runs.
+	tc.do("SET", "k10", "0123456")
+	tc.do("SET", "k11", "0123456")
+	tc.wantNull("GET", "k02")
+	for _, key := range []string{"k00", "k01", "k11"} {
+		tc.wantBulk("0123456", "GET", key)
+	}
+	if got := tc.c.server.evict.evicted; got != 1 {
+		t.Fatalf("evicted = %d, want 1", got)
+	}
+}
+
+func TestEvictionAllKeysLFU(t *testing.T) {
+	tc, _ := newEvictingClient(t, Config{
+		MaxMemory:        20 * (keyOverhead + 14),
+		MaxMemoryPolicy:  MaxMemoryAllKeysLFU,
+		MaxMemorySamples: 64,
+	})
+	tc.c.server.evict.float = func() float64 { return 0 }
+	fillKeys(tc, "hot", 5)
+	for range 3 {
+		for i := range 5 {
+			tc.do("GET", fmt.Sprintf("hot%03d", i))
+		}
+	}
+	// A scan of once-used keys pushes out other once-used keys, never the
+	// hot ones, however recently those were used.
+	for i := range 100 {
+		key := fmt.Sprintf("scan%03d", i)
+		tc.do("SET", key, "0123456")
+		tc.do("GET", key)
+	}
+	for i := range 5 {
+		tc.wantBulk("0123456", "GET", fmt.Sprintf("hot%03d", i))
+	}
+	if got := tc.c.server.evict.evicted; got < 80 {
+		t.Fatalf("evicted = %d, want the scan to evict", got)
+	}
+}
+
+func TestEvictionPropagatesDel(t *testing.T) {
+	tc, _ := newEvictingClient(t, Config{MaxMemory: 1, MaxMemoryPolicy: MaxMemoryAllKeysRandom})
+	s := tc.c.server
+	s.repl.createBacklog()
+	tc.do("SET", "a", "1")
+	before := s.repl.offset
+	tc.do("SET", "b", "1")
+	del := int64(len(appendBulkArray(nil, [][]byte{[]byte("DEL"), []byte("a")})))
+	set := int64(len(appendBulkArray(nil, [][]byte{[]byte("SET"), []byte("b"), []byte("1")})))
+	if got := s.repl.offset - before; got != del+set {
+		t.Fatalf("propagated %d bytes, want DEL then SET (%d)", got, del+set)
+	}
+	tc.wantNull("GET", "a")
+
+	// Commands replayed from a master or the AOF never evict.
+	tc.c.replayed = true
+	tc.do("SET", "c", "1")
+	tc.wantBulk("1", "GET", "b")
+	tc.wantBulk("1", "GET", "c")
+}
+
+func TestNoEvictionRefusesWrites(t *testing.T) {
+	tc, _ := newEvictingClient(t, Config{MaxMemory: 2 * (keyOverhead + 11)})
+	fillKeys(tc, "k", 3)
+	tc.wantError(errOOM, "SET", "k003", "0123456")
+	tc.wantError(errOOM, "RPUSH", "l", "x")
+	tc.wantBulk("0123456", "GET", "k000")
+	tc.wantInt(1, "DEL", "k000")
+	tc.do("SET", "k003", "0123456")
+	tc.wantBulk("0123456", "GET", "k003")
+}
+
+// TestZipfianHitRate compares the hit rates of the LRU and LFU policies on a
+// cache-aside workload: GET a zipfian key and SET it on a miss, with every
+// fifth request part of a sequential scan over cold keys. LFU keeps the
+// popular keys through the scans; LRU lets each scan flush them.
+func TestZipfianHitRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow in short mode")
+	}
+	hitRate := func(policy MaxMemoryPolicy) float64 {
+		const keys, capacity, requests = 5000, 250, 60000
+		tc, advance := newEvictingClient(t, Config{
+			MaxMemory:       capacity * (keyOverhead + 12),
+			MaxMemoryPolicy: policy,
+		})
+		tc.c.server.evict.float = rand.New(rand.NewSource(2)).Float64
+		rng := rand.New(rand.NewSource(1))
+		zipf := rand.NewZipf(rng, 1.1, 1, keys-1)
+		hits, gets, scan := 0, 0, 0
+		for i := range requests {
+			advance(time.Millisecond)
+			var key string
+			if i%5 == 0 {
+				key = fmt.Sprintf("cold%05d", scan)
+				scan++
+			} else {
+				key = fmt.Sprintf("key%05d", zipf.Uint64())
+				gets++
+			}
+			switch {
+			case tc.do("GET", key).Kind == redisproto.KindNull:
+				tc.do("SET", key, "v")
+			case i%5 != 0:
+				hits++
+			}
+		}
+		return float64(hits) / float64(gets)
+	}
+	lru, lfu := hitRate(MaxMemoryAllKeysLRU), hitRate(MaxMemoryAllKeysLFU)
+	t.Logf("hit rate: allkeys-lru %.3f, allkeys-lfu %.3f", lru, lfu)
+	if lfu <= lru {
+		t.Fatalf("allkeys-lfu hit rate %.3f not above allkeys-lru %.3f", lfu, lru)
+	}
+}
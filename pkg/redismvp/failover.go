@@ -0,0 +1,238 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// FAILOVER hands the master role to a replica without losing writes. The
+// master pauses client writes, asks its replicas for their offset and
+// waits until the target has acknowledged everything. It then becomes a
+// replica of the target, sending PSYNC with the FAILOVER option, which
+// makes the target promote itself before accepting us as a replica.
+// Writes paused meanwhile run once the failover ends: they fail with
+// -READONLY if it succeeded, or go through if it was aborted.
+
+// failoverStage is the progress of a failover, named as INFO reports it.
+type failoverStage uint8
+
+const (
+	failoverWaitForSync failoverStage = iota
+	failoverInProgress
+)
+
+func (st failoverStage) String() string {
+	if st == failoverWaitForSync {
+		return "waiting-for-sync"
+	}
+	return "failover-in-progress"
+}
+
+type failoverState struct {
+	stage failoverStage
+	// host and port name the target; host is empty to pick any replica
+	// that catches up.
+	host string
+	port int
+	// deadline is zero without TIMEOUT. With force, the failover goes
+	// ahead when it passes even if the target has not caught up.
+	deadline time.Time
+	force    bool
+}
+
+func init() {
+	registerCommands(
+		&command{name: "failover", arity: -1, group: "server",
+			summary: "Starts a coordinated failover from a server to one of its replicas.", handler: cmdFailover},
+	)
+}
+
+func cmdFailover(c *clientConn, dst []byte, args [][]byte) []byte {
+	s := c.server
+	if len(args) == 1 && argIs(args[0], "ABORT") {
+		if s.failover == nil {
+			return appendError(dst, "ERR No failover in progress.")
+		}
+		s.abortFailover("failover manually aborted")
+		return appendSimple(dst, "OK")
+	}
+
+	var (
+		host       string
+		port       int64
+		timeout    int64
+		hasTimeout bool
+		force      bool
+	)
+	for i := 0; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "TO") && i+2 < len(args) && host == "":
+			host = string(args[i+1])
+			var ok bool
+			if port, ok = parseInt(args[i+2]); !ok {
+				return appendNotInteger(dst)
+			}
+			i += 2
+		case argIs(args[i], "TIMEOUT") && i+1 < len(args) && !hasTimeout:
+			var ok bool
+			if timeout, ok = parseInt(args[i+1]); !ok {
+				return appendNotInteger(dst)
+			}
+			if timeout <= 0 {
+				return appendError(dst, "ERR FAILOVER timeout must be greater than 0")
+			}
+			hasTimeout = true
+			i++
+		case argIs(args[i], "FORCE") && !force:
+			force = true
+		default:
+			return appendSyntaxError(dst)
+		}
+	}
+
+	if s.repl.link != nil {
+		return appendError(dst, "ERR FAILOVER is not valid when server is a replica.")
+	}
+	if force && (!hasTimeout || host == "") {
+		return appendError(dst, "ERR FAILOVER with force option requires both a timeout and target HOST and IP.")
+	}
+	if s.failover != nil {
+		return appendError(dst, "ERR FAILOVER already in progress.")
+	}
+	if len(s.repl.replicas) == 0 {
+		return appendError(dst, "ERR FAILOVER requires connected replicas.")
+	}
+	if host != "" && s.findReplica(host, int(port)) == nil {
+		return appendError(dst, "ERR FAILOVER target HOST and PORT is not a replica.")
+	}
+
+	f := &failoverState{host: host, port: int(port), force: force}
+	if hasTimeout {
+		f.deadline = time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	}
+	s.failover = f
+	// Ask for offsets right away instead of waiting for periodic ACKs.
+	s.feedReplication(appendBulkArray(nil, [][]byte{[]byte("REPLCONF"), []byte("GETACK"), []byte("*")}))
+	s.log.Info("failover requested", "target", failoverTarget(host, int(port)), "timeout_ms", timeout, "force", force)
+	return appendSimple(dst, "OK")
+}
+
+// failoverTarget formats a failover target for logging.
+func failoverTarget(host string, port int) string {
+	if host == "" {
+		return "any"
+	}
+	return host + ":" + strconv.Itoa(port)
+}
+
+// findReplica returns the online replica reachable at host:port.
+func (s *Server) findReplica(host string, port int) *clientConn {
+	for _, c := range sortedConns(s.repl.replicas) {
+		if h, p := c.replicaAddr(); h == host && p == port && c.replica.online {
+			return c
+		}
+	}
+	return nil
+}
+
+// pollFailover moves a failover waiting for its target to the role switch
+// once the target has acknowledged our offset, or the timeout passed.
+func (s *Server) pollFailover(now time.Time) {
+	f := s.failover
+	if f == nil && len(s.paused) > 0 {
+		s.unpauseWrites()
+	}
+	if f == nil || f.stage != failoverWaitForSync {
+		return
+	}
+	target := s.caughtUpReplica(f)
+	if target == nil {
+		if f.deadline.IsZero() || now.Before(f.deadline) {
+			return
+		}
+		if !f.force {
+			s.abortFailover("replica never caught up before timeout")
+			return
+		}
+		s.log.Warn("failover timeout reached, forcing failover")
+	} else {
+		f.host, f.port = target.replicaAddr()
+	}
+
+	f.stage = failoverInProgress
+	s.replicaOf(f.host, f.port)
+	s.repl.link.failover = true
+	s.log.Info("failover target caught up, switching roles", "target", failoverTarget(f.host, f.port))
+}
+
+// caughtUpReplica returns the failover target if it has acknowledged our
+// whole stream. Without an explicit target, any such replica qualifies.
+func (s *Server) caughtUpReplica(f *failoverState) *clientConn {
+	for _, c := range sortedConns(s.repl.replicas) {
+		if !c.replica.online || c.replica.ackOffset != s.repl.offset {
+			continue
+		}
+		if h, p := c.replicaAddr(); f.host == "" || (h == f.host && p == f.port) {
+			return c
+		}
+	}
+	return nil
+}
+
+// failoverDone ends a failover once we replicate from the new master.
+// Paused writes are resumed by the next poll.
+func (s *Server) failoverDone() {
+	s.failover = nil
+	s.log.Info("failover complete", "master", s.repl.link.addr())
+}
+
+// abortFailover cancels a failover and stays, or becomes again, a master.
+// Our history was not touched by the target, so the replication ID is
+// kept and our replicas can carry on.
+func (s *Server) abortFailover(reason string) {
+	f := s.failover
+	if f == nil {
+		return
+	}
+	if f.stage == failoverInProgress && s.repl.link != nil {
+		s.repl.link.close()
+		s.repl.link = nil
+	}
+	s.failover = nil
+	s.log.Warn("failover aborted", "reason", reason)
+}
+
+// pauseWrite parks c, whose write command frame must wait for the
+// failover to end. Like a blocked command, frames that follow are queued.
+func (c *clientConn) pauseWrite(frame redisproto.Value) {
+	s := c.server
+	c.block(nil, time.Time{}, func(dst []byte) ([]byte, bool) { return dst, false }, nil)
+	c.blocked.paused = true
+	c.pending = append([]redisproto.Value{frame}, c.pending...)
+	if s.paused == nil {
+		s.paused = make(map[*clientConn]struct{})
+	}
+	s.paused[c] = struct{}{}
+}
+
+// unpauseWrites runs the commands of paused clients again. Ending a
+// failover can happen inside a command, with the store locked, so this is
+// left to the next poll.
+func (s *Server) unpauseWrites() {
+	paused := s.paused
+	s.paused = nil
+	for _, c := range sortedConns(paused) {
+		if c.blocked == nil || !c.blocked.paused {
+			continue
+		}
+		c.unblock()
+		c.resume(nil)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"os"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// faultInjector lets tests disturb client connections the way a slow
+// server or a flaky network would. Its methods run on the loop goroutine.
+// Servers started outside tests never have one.
+type faultInjector interface {
+	// beforeCommand runs before c executes frame. It returns how long to
+	// stall the loop first, and whether to drop the connection instead of
+	// executing frame and the frames after it.
+	beforeCommand(c *clientConn, frame redisproto.Value) (delay time.Duration, disconnect bool)
+	// replyLimit returns how many bytes of the reply wire to write to c
+	// before dropping the connection, or -1 to write all of it.
+	replyLimit(c *clientConn, wire []byte) int
+}
+
+// diskFaultInjector lets tests crash the server while it persists, to
+// check that a restart never loads a partially written file. A crash stops
+// persisting for good and leaves the files as they are; the test then
+// abandons the server, as if its process had died.
+type diskFaultInjector interface {
+	// beforeWrite runs on the snapshot writer goroutine before chunk is
+	// written to f. It returns how many bytes of chunk reach f before the
+	// crash, or -1 to write all of it and go on.
+	beforeWrite(f *os.File, chunk []byte) int
+	// beforeRename runs on the loop before tmp, complete and fsynced,
+	// replaces path. It returns true to crash instead.
+	beforeRename(tmp, path string) bool
+}
+
+// injectWrite applies the write faults for chunk. It returns false if the
+// server crashed, after writing the part of chunk that made it to f.
+func (s *Server) injectWrite(f *os.File, chunk []byte) bool {
+	limit := s.diskFaults.beforeWrite(f, chunk)
+	if limit < 0 {
+		return true
+	}
+	_, _ = f.Write(chunk[:min(limit, len(chunk))])
+	return false
+}
+
+// injectRename applies the rename faults. It returns false if the server
+// crashed.
+func (s *Server) injectRename(tmp, path string) bool {
+	return !s.diskFaults.beforeRename(tmp, path)
+}
+
+// injectBeforeCommand applies the command faults for frame. It returns
+// false if c was dropped.
+func (c *clientConn) injectBeforeCommand(frame redisproto.Value) bool {
+	delay, disconnect := c.server.faults.beforeCommand(c, frame)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if disconnect {
+		c.close("fault injected: disconnect")
+		return false
+	}
+	return true
+}
+
+// injectReply writes wire to c, truncated and followed by a disconnect if
+// the injector says so. It returns false if c was dropped.
+func (c *clientConn) injectReply(wire []byte) bool {
+	limit := c.server.faults.replyLimit(c, wire)
+	if limit < 0 || limit >= len(wire) {
+		return true
+	}
+	_ = writeAll(c.fd, wire[:limit])
+	c.close("fault injected: truncated reply")
+	return false
+}
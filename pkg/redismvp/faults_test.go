@@ -0,0 +1,231 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/rediscli"
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// scriptedFaults drops the connection before the command named drop, and
+// cuts replies to limit bytes.
+type scriptedFaults struct {
+	drop  string
+	limit int
+}
+
+func (f *scriptedFaults) beforeCommand(_ *clientConn, frame redisproto.Value) (time.Duration, bool) {
+	return 0, strings.EqualFold(commandName(frame), f.drop)
+}
+
+func (f *scriptedFaults) replyLimit(*clientConn, []byte) int {
+	return f.limit
+}
+
+// chaosFaults disturbs only connections that touched a "chaos:" key: it
+// delays their commands, truncates their replies and drops them at random.
+type chaosFaults struct {
+	rng      *rand.Rand
+	victims  map[*clientConn]bool
+	enabled  atomic.Bool
+	injected atomic.Int64
+}
+
+func (f *chaosFaults) beforeCommand(c *clientConn, frame redisproto.Value) (time.Duration, bool) {
+	if len(frame.Array) > 1 && strings.HasPrefix(string(frame.Array[1].Bulk), "chaos:") {
+		f.victims[c] = true
+	}
+	if !f.enabled.Load() || !f.victims[c] {
+		return 0, false
+	}
+	switch f.rng.IntN(4) {
+	case 0:
+		f.injected.Add(1)
+		return time.Duration(f.rng.IntN(500)) * time.Microsecond, false
+	case 1:
+		f.injected.Add(1)
+		return 0, true
+	}
+	return 0, false
+}
+
+func (f *chaosFaults) replyLimit(c *clientConn, wire []byte) int {
+	if !f.enabled.Load() || !f.victims[c] || f.rng.IntN(3) != 0 {
+		return -1
+	}
+	f.injected.Add(1)
+	return f.rng.IntN(len(wire))
+}
+
+// newFaultClient returns a client whose replies go to the returned socket.
+func newFaultClient(t *testing.T, faults faultInjector) (*clientConn, int) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Close(fds[0])
+		_ = syscall.Close(fds[1])
+	})
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &Server{store: NewStore(), log: log, slowLog: -1, faults: faults}
+	s.store.keyCreated = s.keyCreated
+	return &clientConn{server: s, log: log, fd: int32(fds[0])}, fds[1]
+}
+
+func TestFaultTruncatedReply(t *testing.T) {
+	c, peer := newFaultClient(t, &scriptedFaults{limit: 7})
+
+	frames := []redisproto.Value{buildTestCommand([]string{"SET", "k", "v"}), buildTestCommand([]string{"GET", "k"})}
+	if c.process(nil, frames) {
+		t.Fatal("process kept a client whose reply was truncated")
+	}
+	if !c.closed || len(c.server.pendingFDs) != 1 {
+		t.Fatalf("client not closed: closed=%v pending fds=%v", c.closed, c.server.pendingFDs)
+	}
+	buf := make([]byte, 64)
+	n, err := syscall.Read(peer, buf)
+	if err != nil || string(buf[:n]) != "+OK\r\n$1" {
+		t.Fatalf("peer read %q, %v", buf[:n], err)
+	}
+	if v, ok, _ := c.server.store.lookupString("k"); !ok || string(v) != "v" {
+		t.Fatalf("SET did not run before the truncated reply: %q", v)
+	}
+}
+
+func TestFaultDisconnectBeforeCommand(t *testing.T) {
+	c, _ := newFaultClient(t, &scriptedFaults{drop: "INCR", limit: -1})
+
+	frames := []redisproto.Value{
+		buildTestCommand([]string{"SET", "k", "v"}),
+		buildTestCommand([]string{"INCR", "n"}),
+		buildTestCommand([]string{"SET", "after", "v"}),
+	}
+	if c.process(nil, frames) {
+		t.Fatal("process kept a dropped client")
+	}
+	store := c.server.store
+	if _, ok, _ := store.lookupString("k"); !ok {
+		t.Fatal("command before the disconnect did not run")
+	}
+	for _, key := range []string{"n", "after"} {
+		if _, ok, _ := store.lookupString(key); ok {
+			t.Fatalf("%s ran after the disconnect", key)
+		}
+	}
+}
+
+func TestChaosLeavesOtherClientsIntact(t *testing.T) {
+	t.Parallel()
+	faults := &chaosFaults{rng: rand.New(rand.NewPCG(1, 2)), victims: map[*clientConn]bool{}}
+	faults.enabled.Store(true)
+	srv := startTestServer(t, Config{LogLevel: LevelNothing, faults: faults})
+	dial := testDialer(srv)
+
+	const (
+		healthy = 4
+		victims = 4
+		rounds  = 100
+	)
+	stop := make(chan struct{})
+	var victimWG sync.WaitGroup
+	for i := 0; i < victims; i++ {
+		victimWG.Add(1)
+		go func() {
+			defer victimWG.Done()
+			cli := rediscli.NewClient(srv.Addr())
+			cli.Dial = dial
+			cli.Timeout = 200 * time.Millisecond
+			key := fmt.Sprintf("chaos:%d", i)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Errors are expected: the reply may be cut short or never
+				// come. Each Do dials again, which is how clients recover.
+				_, _ = cli.Do([]string{"RPUSH", key, strings.Repeat("x", 64)})
+				_, _ = cli.Do([]string{"LRANGE", key, "0", "-1"})
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < healthy; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dial("tcp", srv.Addr())
+			if err != nil {
+				t.Errorf("dial failed: %v", err)
+				return
+			}
+			defer conn.Close()
+			key := fmt.Sprintf("healthy:%d", i)
+			for n := int64(1); n <= rounds; n++ {
+				got := sendCommand(t, conn, []string{"INCR", key})
+				if got.Kind != redisproto.KindInteger || got.Int != n {
+					t.Errorf("%s: INCR %d returned %#v", key, n, got)
+					return
+				}
+			}
+			got := sendCommand(t, conn, []string{"GET", key})
+			if string(got.Bulk) != fmt.Sprint(rounds) {
+				t.Errorf("%s: GET returned %#v", key, got)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	victimWG.Wait()
+
+	if faults.injected.Load() == 0 {
+		t.Fatal("no faults were injected")
+	}
+
+	// With the faults off, a client that was disrupted works again and
+	// its list holds only whole elements.
+	faults.enabled.Store(false)
+	cli := rediscli.NewClient(srv.Addr())
+	cli.Dial = dial
+	got, err := cli.Do([]string{"LRANGE", "chaos:0", "0", "-1"})
+	if err != nil {
+		t.Fatalf("client did not recover: %v", err)
+	}
+	for _, item := range got.Array {
+		if len(item.Bulk) != 64 {
+			t.Fatalf("corrupted element %q", item.Bulk)
+		}
+	}
+
+	// Dropped connections were all released.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		srv.clientsMu.Lock()
+		open := len(srv.clients)
+		srv.clientsMu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still open", open)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
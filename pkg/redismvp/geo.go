@@ -0,0 +1,266 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geo members are stored in a sorted set whose score is a 52-bit geohash,
+// computed exactly as Redis does, so a GEO key is an ordinary zset and
+// scores match upstream bit for bit.
+
+const (
+	geoStep      = 26 // bits per coordinate in a stored geohash
+	geoLonMin    = -180.0
+	geoLonMax    = 180.0
+	geoLatMin    = -85.05112878
+	geoLatMax    = 85.05112878
+	earthRadiusM = 6372797.560856
+	mercatorMax  = 20037726.37
+)
+
+// geoPoint is a longitude/latitude pair in degrees.
+type geoPoint struct {
+	lon, lat float64
+}
+
+func validGeoPoint(p geoPoint) bool {
+	return p.lon >= geoLonMin && p.lon <= geoLonMax && p.lat >= geoLatMin && p.lat <= geoLatMax
+}
+
+// geohashCell returns the integer cell coordinates of p at step bits per
+// coordinate within the given latitude range.
+func geohashCell(p geoPoint, step uint, latMin, latMax float64) (lonCell, latCell uint32) {
+	cells := float64(uint64(1) << step)
+	limit := uint64(1)<<step - 1
+	lat := uint64((p.lat - latMin) / (latMax - latMin) * cells)
+	lon := uint64((p.lon - geoLonMin) / (geoLonMax - geoLonMin) * cells)
+	return uint32(min(lon, limit)), uint32(min(lat, limit))
+}
+
+// geohashEncode interleaves the cell coordinates of p into a 2*step bit
+// hash. Longitude takes the odd bits, so the top bit is a longitude bit
+// as in standard geohashes.
+func geohashEncode(p geoPoint, step uint, latMin, latMax float64) uint64 {
+	lon, lat := geohashCell(p, step, latMin, latMax)
+	return interleave(lat, lon)
+}
+
+// geohashDecode returns the center of the cell identified by a 52-bit
+// geohash, clamped to the valid coordinate range.
+func geohashDecode(hash uint64) geoPoint {
+	lat, lon := deinterleave(hash)
+	cells := float64(uint64(1) << geoStep)
+	latScale, lonScale := geoLatMax-geoLatMin, geoLonMax-geoLonMin
+	latLo := geoLatMin + float64(lat)/cells*latScale
+	latHi := geoLatMin + float64(lat+1)/cells*latScale
+	lonLo := geoLonMin + float64(lon)/cells*lonScale
+	lonHi := geoLonMin + float64(lon+1)/cells*lonScale
+	return geoPoint{
+		lon: min(max((lonLo+lonHi)/2, geoLonMin), geoLonMax),
+		lat: min(max((latLo+latHi)/2, geoLatMin), geoLatMax),
+	}
+}
+
+// interleave spreads x over the even bits and y over the odd bits.
+func interleave(x, y uint32) uint64 {
+	return spreadBits(x) | spreadBits(y)<<1
+}
+
+func deinterleave(v uint64) (x, y uint32) {
+	return squashBits(v), squashBits(v >> 1)
+}
+
+func spreadBits(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func squashBits(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
+
+// geohashString renders the 11-character base32 geohash reported by
+// GEOHASH. It re-encodes the stored position against the standard
+// latitude range of [-90, 90].
+func geohashString(hash uint64) string {
+	const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+	std := geohashEncode(geohashDecode(hash), geoStep, -90, 90)
+	var buf [11]byte
+	for i := range buf {
+		idx := uint64(0)
+		if i < 10 {
+			idx = std >> (52 - (i+1)*5) & 0x1f
+		}
+		buf[i] = alphabet[idx]
+	}
+	return string(buf[:])
+}
+
+func degToRad(d float64) float64 { return d * math.Pi / 180 }
+
+func radToDeg(r float64) float64 { return r * 180 / math.Pi }
+
+// geoDistance returns the haversine distance between a and b in meters.
+func geoDistance(a, b geoPoint) float64 {
+	v := math.Sin(degToRad(b.lon-a.lon) / 2)
+	if v == 0 {
+		return geoLatDistance(a.lat, b.lat)
+	}
+	u := math.Sin(degToRad(b.lat-a.lat) / 2)
+	h := u*u + math.Cos(degToRad(a.lat))*math.Cos(degToRad(b.lat))*v*v
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(h))
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadiusM * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+// geoShape is the area searched by GEOSEARCH: a circle of radius meters,
+// or a width by height box, around center.
+type geoShape struct {
+	center        geoPoint
+	radius        float64
+	width, height float64
+	box           bool
+	// unit is the number of meters in the unit used for replies.
+	unit float64
+}
+
+// contains reports whether p lies in the shape, and its distance to the
+// center in meters.
+func (s *geoShape) contains(p geoPoint) (float64, bool) {
+	if !s.box {
+		d := geoDistance(s.center, p)
+		return d, d <= s.radius
+	}
+	if geoLatDistance(p.lat, s.center.lat) > s.height/2 {
+		return 0, false
+	}
+	if geoDistance(geoPoint{lon: s.center.lon, lat: p.lat}, p) > s.width/2 {
+		return 0, false
+	}
+	return geoDistance(s.center, p), true
+}
+
+// searchRanges returns the geohash score ranges [lo, hi) of the cells
+// that together cover the shape: the center cell and its neighbors at the
+// finest step whose cells are at least as large as the shape.
+func (s *geoShape) searchRanges() [][2]uint64 {
+	halfW, halfH := s.radius, s.radius
+	if s.box {
+		halfW, halfH = s.width/2, s.height/2
+	}
+	latDelta := radToDeg(halfH / earthRadiusM)
+	lonDelta := max(
+		radToDeg(halfW/earthRadiusM/math.Cos(degToRad(s.center.lat+latDelta))),
+		radToDeg(halfW/earthRadiusM/math.Cos(degToRad(s.center.lat-latDelta))))
+
+	step := geoEstimateStep(max(halfW, halfH), s.center.lat)
+	for step > 1 {
+		cells := float64(uint64(1) << step)
+		if (geoLatMax-geoLatMin)/cells >= latDelta && (geoLonMax-geoLonMin)/cells >= lonDelta {
+			break
+		}
+		step--
+	}
+
+	lonCell, latCell := geohashCell(s.center, step, geoLatMin, geoLatMax)
+	limit := int64(1)<<step - 1
+	shift := 2 * (geoStep - step)
+	var ranges [][2]uint64
+	seen := make(map[uint64]bool, 9)
+	for dLat := int64(-1); dLat <= 1; dLat++ {
+		lat := int64(latCell) + dLat
+		if lat < 0 || lat > limit {
+			continue
+		}
+		for dLon := int64(-1); dLon <= 1; dLon++ {
+			// Longitude wraps around the antimeridian.
+			lon := (int64(lonCell) + dLon + limit + 1) & limit
+			cell := interleave(uint32(lat), uint32(lon))
+			if seen[cell] {
+				continue
+			}
+			seen[cell] = true
+			ranges = append(ranges, [2]uint64{cell << shift, (cell + 1) << shift})
+		}
+	}
+	return ranges
+}
+
+// geoEstimateStep returns the geohash step whose cells are roughly the
+// size of a search of the given radius, as Redis estimates it.
+func geoEstimateStep(radius, lat float64) uint {
+	if radius == 0 {
+		return geoStep
+	}
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	step -= 2
+	// Cells shrink horizontally towards the poles.
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	return uint(min(max(step, 1), geoStep))
+}
+
+// parseGeoUnit returns the number of meters in a GEO distance unit.
+func parseGeoUnit(arg []byte) (float64, bool) {
+	switch strings.ToLower(string(arg)) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "ft":
+		return 0.3048, true
+	case "mi":
+		return 1609.34, true
+	default:
+		return 0, false
+	}
+}
+
+// appendGeoDistance appends a distance in meters converted to unit, with
+// four decimals as Redis replies.
+func appendGeoDistance(dst []byte, meters, unit float64) []byte {
+	return appendBulk(dst, strconv.AppendFloat(nil, meters/unit, 'f', 4, 64))
+}
+
+// appendGeoCoord appends a coordinate the way Redis prints them: 17
+// decimals with trailing zeros removed.
+func appendGeoCoord(dst []byte, v float64) []byte {
+	s := strconv.FormatFloat(v, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return appendBulkString(dst, s)
+}
+
+func appendGeoPoint(dst []byte, p geoPoint) []byte {
+	dst = appendArrayLen(dst, 2)
+	dst = appendGeoCoord(dst, p.lon)
+	return appendGeoCoord(dst, p.lat)
+}
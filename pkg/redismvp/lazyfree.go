@@ -0,0 +1,105 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// lazyFreeThreshold is the number of elements above which UNLINK releases
+// a value in the background, matching Redis's LAZYFREE_THRESHOLD.
+const lazyFreeThreshold = 64
+
+// lazyFreeQueueSize bounds the values waiting for background release. When
+// the queue is full, values are released on the caller's goroutine.
+const lazyFreeQueueSize = 1024
+
+// lazyFreer releases large values removed by UNLINK off the event loop.
+//
+// Dropping the last reference to a value is O(1) in Go, but tearing down a
+// collection with millions of entries still costs the loop goroutine time
+// in clear() and in the write barriers of the following GC cycle. The
+// worker does that work instead, like Redis's lazyfree background thread.
+// libxev's thread pool cannot run Go functions, so the worker is a plain
+// goroutine.
+type lazyFreer struct {
+	queue   chan any
+	wg      sync.WaitGroup
+	pending atomic.Int64
+}
+
+func newLazyFreer() *lazyFreer {
+	f := &lazyFreer{queue: make(chan any, lazyFreeQueueSize)}
+	f.wg.Add(1)
+	go f.run()
+	return f
+}
+
+// free hands v to the worker if it is large enough to be worth it. Small
+// values, and every value when f is nil, are simply left to the GC.
+func (f *lazyFreer) free(v any) {
+	if f == nil || freeEffort(v) <= lazyFreeThreshold {
+		return
+	}
+	f.pending.Add(1)
+	select {
+	case f.queue <- v:
+	default:
+		f.pending.Add(-1)
+		releaseValue(v)
+	}
+}
+
+// close waits for queued values to be released and stops the worker.
+func (f *lazyFreer) close() {
+	if f == nil {
+		return
+	}
+	close(f.queue)
+	f.wg.Wait()
+}
+
+func (f *lazyFreer) run() {
+	defer f.wg.Done()
+	for v := range f.queue {
+		releaseValue(v)
+		f.pending.Add(-1)
+	}
+}
+
+// freeEffort estimates the cost of releasing v as its element count.
+func freeEffort(v any) int {
+	switch v := v.(type) {
+	case *listValue:
+		return v.len()
+	case setValue:
+		return len(v)
+	case hashValue:
+		return len(v)
+	case *zsetValue:
+		return v.len()
+	default:
+		return 1
+	}
+}
+
+// releaseValue drops the references held by a value that has already been
+// removed from the keyspace.
+func releaseValue(v any) {
+	switch v := v.(type) {
+	case *listValue:
+		clear(v.items)
+		v.items = nil
+	case setValue:
+		clear(v)
+	case hashValue:
+		clear(v)
+	case *zsetValue:
+		clear(v.scores)
+		v.order = nil
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLazyFreeReleasesLargeValues(t *testing.T) {
+	f := newLazyFreer()
+
+	large := setValue{}
+	for i := range lazyFreeThreshold + 1 {
+		large[strconv.Itoa(i)] = struct{}{}
+	}
+	small := setValue{"a": {}}
+	f.free(large)
+	f.free(small)
+	f.close()
+
+	if len(large) != 0 {
+		t.Fatalf("large set not released: %d members left", len(large))
+	}
+	if len(small) != 1 {
+		t.Fatal("small set was released by the worker")
+	}
+	if n := f.pending.Load(); n != 0 {
+		t.Fatalf("pending = %d after close", n)
+	}
+}
+
+func TestUnlinkHandsValuesToLazyFree(t *testing.T) {
+	tc := newTestClient(t)
+	tc.c.server.lazyFree = newLazyFreer()
+
+	args := []string{"SADD", "big"}
+	for i := range lazyFreeThreshold + 1 {
+		args = append(args, strconv.Itoa(i))
+	}
+	tc.do(args...)
+	big := tc.c.server.store.kv["big"].(setValue)
+
+	tc.wantInt(1, "UNLINK", "big")
+	tc.c.server.lazyFree.close()
+	if len(big) != 0 {
+		t.Fatalf("unlinked set not released: %d members left", len(big))
+	}
+	tc.wantInt(0, "TOUCH", "big")
+}
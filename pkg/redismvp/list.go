@@ -0,0 +1,82 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+// listValue is the in-memory representation of a Redis list. It is a
+// slice used as a deque: items[head:] are the elements, and the slots
+// before head are free room for pushes at the front.
+type listValue struct {
+	items [][]byte
+	head  int
+}
+
+func newList() *listValue {
+	return &listValue{}
+}
+
+// len returns the number of elements; it is safe on a nil list.
+func (l *listValue) len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.items) - l.head
+}
+
+// index returns the element at position i, which must be in range.
+func (l *listValue) index(i int) []byte {
+	return l.items[l.head+i]
+}
+
+// elements returns the elements in positions start through stop
+// inclusive. The result aliases the list.
+func (l *listValue) elements(start, stop int) [][]byte {
+	return l.items[l.head+start : l.head+stop+1]
+}
+
+func (l *listValue) pushFront(v []byte) {
+	if l.head == 0 {
+		n := len(l.items)
+		room := max(n, 4)
+		grown := make([][]byte, room+n, room+2*n)
+		copy(grown[room:], l.items)
+		l.items, l.head = grown, room
+	}
+	l.head--
+	l.items[l.head] = v
+}
+
+func (l *listValue) pushBack(v []byte) {
+	if l.head > 0 && len(l.items) == cap(l.items) {
+		// Reuse the room at the front instead of growing.
+		n := copy(l.items, l.items[l.head:])
+		clear(l.items[n:])
+		l.items, l.head = l.items[:n], 0
+	}
+	l.items = append(l.items, v)
+}
+
+// pop removes up to n elements from the front, or from the back if
+// fromBack is set, and returns them in the order they were removed.
+func (l *listValue) pop(n int, fromBack bool) [][]byte {
+	n = min(n, l.len())
+	out := make([][]byte, n)
+	for i := range out {
+		if fromBack {
+			last := len(l.items) - 1
+			out[i] = l.items[last]
+			l.items[last] = nil
+			l.items = l.items[:last]
+		} else {
+			out[i] = l.items[l.head]
+			l.items[l.head] = nil
+			l.head++
+		}
+	}
+	if l.len() == 0 {
+		l.items, l.head = l.items[:0], 0
+	}
+	return out
+}
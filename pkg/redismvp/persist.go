@@ -0,0 +1,48 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The persistence files, the RDB file and the append only file, are never
+// rewritten in place. A new version is written to a temporary file in the
+// same directory, fsynced, and renamed over the old one; then the
+// directory is fsynced, so the rename survives a crash as well. A crash at
+// any point leaves the old file or the new one, complete, next to at worst
+// a stray temporary file that nothing loads.
+
+// createTemp creates the temporary file that will replace path, named
+// after pattern as in [os.CreateTemp].
+func createTemp(path, pattern string) (*os.File, error) {
+	return os.CreateTemp(filepath.Dir(path), pattern)
+}
+
+// replaceFile renames tmp, complete and fsynced, over path. Once the
+// rename is done it cannot be undone, so failing to fsync the directory
+// afterwards is only logged.
+func (s *Server) replaceFile(tmp, path string) error {
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir fsyncs a directory so the files created or renamed in it survive
+// a crash.
+func (s *Server) syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err == nil {
+		err = d.Sync()
+		_ = d.Close()
+	}
+	if err != nil {
+		s.log.Warn("fsync of the directory failed", "dir", dir, "err", err)
+	}
+}
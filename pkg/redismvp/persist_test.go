@@ -0,0 +1,182 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// crashFaults crashes the server at one point of persisting: keep bytes
+// into the write of chunk, or before the rename when rename is set.
+type crashFaults struct {
+	chunk  int
+	keep   int
+	rename bool
+
+	written int
+	// file is the temporary file being written, closed by the test since
+	// the crashed server never does.
+	file    *os.File
+	crashed chan struct{}
+}
+
+func newCrashFaults(t *testing.T, chunk, keep int, rename bool) *crashFaults {
+	f := &crashFaults{chunk: chunk, keep: keep, rename: rename, crashed: make(chan struct{})}
+	t.Cleanup(func() {
+		if f.file != nil {
+			_ = f.file.Close()
+		}
+	})
+	return f
+}
+
+func (f *crashFaults) beforeWrite(file *os.File, _ []byte) int {
+	f.file = file
+	f.written++
+	if f.rename || f.written-1 != f.chunk {
+		return -1
+	}
+	close(f.crashed)
+	return f.keep
+}
+
+func (f *crashFaults) beforeRename(string, string) bool {
+	if f.rename {
+		close(f.crashed)
+	}
+	return f.rename
+}
+
+// runUntilCrash moves the running snapshot along until the server crashes.
+func runUntilCrash(t *testing.T, s *Server, f *crashFaults) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		select {
+		case <-f.crashed:
+			return
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the server did not crash")
+		}
+		s.pollSnapshot()
+	}
+}
+
+func wantTempFiles(t *testing.T, dir string, want bool) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, "temp-*"))
+	if (len(matches) > 0) != want {
+		t.Fatalf("temporary files in %s: %v", dir, matches)
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	cfg := Config{Dir: "/data"}
+	if got := cfg.dbFilename(); got != "/data/dump.rdb" {
+		t.Fatalf("dbFilename() = %q", got)
+	}
+	cfg.AppendFilename = "aof/appendonly.aof"
+	if got := cfg.appendFilename(); got != "/data/aof/appendonly.aof" {
+		t.Fatalf("appendFilename() = %q", got)
+	}
+	cfg.DBFilename = "/elsewhere/dump.rdb"
+	if got := cfg.dbFilename(); got != "/elsewhere/dump.rdb" {
+		t.Fatalf("absolute dbFilename() = %q", got)
+	}
+	if got := (Config{}).dbFilename(); got != DefaultDBFilename {
+		t.Fatalf("dbFilename() without dir = %q", got)
+	}
+}
+
+// TestSnapshotCrash crashes BGSAVE part way through and restarts from the
+// directory: the restart loads the last complete snapshot, never the
+// partial one.
+func TestSnapshotCrash(t *testing.T) {
+	cases := []struct {
+		name        string
+		chunk, keep int
+		rename      bool
+	}{
+		{name: "torn header", chunk: 0, keep: 7},
+		{name: "torn chunk", chunk: 1, keep: 1000},
+		{name: "after a whole chunk", chunk: 1, keep: snapshotChunkSize},
+		{name: "before rename", rename: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Dir: filepath.Join(t.TempDir(), "data")}
+			if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			tc := newTestClient(t)
+			s := tc.c.server
+			s.dbFilename = cfg.dbFilename()
+			fillStore(tc, 200, 200)
+			if got := tc.do("SAVE"); got.Str != "OK" {
+				t.Fatalf("SAVE: %#v", got)
+			}
+			saved := dumpStore(s.store)
+
+			for i := range 100 {
+				tc.do("SET", fmt.Sprint("after:", i), "x")
+			}
+			faults := newCrashFaults(t, tt.chunk, tt.keep, tt.rename)
+			s.diskFaults = faults
+			if got := tc.do("BGSAVE"); got.Str != "Background saving started" {
+				t.Fatalf("BGSAVE: %#v", got)
+			}
+			runUntilCrash(t, s, faults)
+			wantTempFiles(t, cfg.Dir, true)
+
+			restarted := newTestClient(t)
+			if err := restarted.c.server.loadRDBFile(cfg.dbFilename()); err != nil {
+				t.Fatalf("load after crash: %v", err)
+			}
+			if got := dumpStore(restarted.c.server.store); !reflect.DeepEqual(got, saved) {
+				t.Fatalf("dataset after crash is not the last complete snapshot")
+			}
+		})
+	}
+}
+
+// TestAOFRewriteCrash crashes BGREWRITEAOF part way through: the restart
+// replays the old file, which kept logging the writes made meanwhile.
+func TestAOFRewriteCrash(t *testing.T) {
+	for _, rename := range []bool{false, true} {
+		t.Run(fmt.Sprintf("rename=%v", rename), func(t *testing.T) {
+			cfg := Config{
+				Dir:         filepath.Join(t.TempDir(), "data"),
+				AppendOnly:  true,
+				AppendFsync: AppendFsyncAlways,
+			}
+			if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			tc := startAOFServer(t, cfg)
+			s := tc.c.server
+			fillStore(tc, 200, 200)
+
+			faults := newCrashFaults(t, 1, 100, rename)
+			s.diskFaults = faults
+			if got := tc.do("BGREWRITEAOF"); got.Str != "Background append only file rewriting started" {
+				t.Fatalf("BGREWRITEAOF: %#v", got)
+			}
+			tc.do("SET", "during", "rewrite")
+			runUntilCrash(t, s, faults)
+			wantTempFiles(t, cfg.Dir, true)
+
+			restarted := startAOFServer(t, cfg)
+			wantSameDataset(t, restarted.c.server, s)
+		})
+	}
+}
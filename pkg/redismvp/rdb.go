@@ -0,0 +1,470 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// RDB encoding of the dataset, used as the preamble of rewritten append
+// only files. The writer produces the plain encodings of every type, which
+// any Redis version loads. The reader also accepts the integer and LZF
+// string encodings Redis writes, so preambles written by Redis load too.
+
+const rdbVersion = 11
+
+// Value types.
+const (
+	rdbTypeString = 0
+	rdbTypeList   = 1
+	rdbTypeSet    = 2
+	rdbTypeHash   = 4
+	rdbTypeZSet2  = 5
+)
+
+// Opcodes found where a value type is expected.
+const (
+	rdbOpAux      = 0xfa
+	rdbOpResizeDB = 0xfb
+	rdbOpExpireMS = 0xfc
+	rdbOpExpire   = 0xfd
+	rdbOpSelectDB = 0xfe
+	rdbOpEOF      = 0xff
+)
+
+// Special string encodings, flagged by the top two bits of a length.
+const (
+	rdbEncInt8  = 0
+	rdbEncInt16 = 1
+	rdbEncInt32 = 2
+	rdbEncLZF   = 3
+)
+
+var errRDBTruncated = errors.New("unexpected end of RDB data")
+
+// rdbCRCTable is for the Jones polynomial Redis checksums RDB files with.
+var rdbCRCTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// rdbChecksum extends crc, the checksum of the data before p, over p. It
+// is the Redis CRC-64, which unlike the hash/crc64 checksums starts from
+// zero and does not invert its result.
+func rdbChecksum(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, rdbCRCTable, p)
+}
+
+// appendRDB appends an RDB file holding kv. aux lists the names and
+// values of auxiliary fields in pairs.
+func appendRDB(dst []byte, kv map[string]any, aux ...string) []byte {
+	start := len(dst)
+	dst = appendRDBHeader(dst, len(kv), aux...)
+	for key, v := range kv {
+		dst = appendRDBValue(dst, key, v)
+	}
+	return appendRDBFooter(dst, rdbChecksum(0, dst[start:]))
+}
+
+// appendRDBHeader appends what precedes the keys of an RDB file: the
+// version, the aux fields and the selection of database 0, sized for
+// nkeys keys.
+func appendRDBHeader(dst []byte, nkeys int, aux ...string) []byte {
+	dst = append(dst, "REDIS"...)
+	dst = fmt.Appendf(dst, "%04d", rdbVersion)
+	for i := 0; i+1 < len(aux); i += 2 {
+		dst = append(dst, rdbOpAux)
+		dst = appendRDBString(dst, aux[i])
+		dst = appendRDBString(dst, aux[i+1])
+	}
+	dst = append(dst, rdbOpSelectDB)
+	dst = appendRDBLen(dst, 0)
+	dst = append(dst, rdbOpResizeDB)
+	dst = appendRDBLen(dst, nkeys)
+	return appendRDBLen(dst, 0)
+}
+
+// appendRDBFooter ends an RDB file whose content so far has checksum crc.
+func appendRDBFooter(dst []byte, crc uint64) []byte {
+	dst = append(dst, rdbOpEOF)
+	crc = rdbChecksum(crc, dst[len(dst)-1:])
+	return binary.LittleEndian.AppendUint64(dst, crc)
+}
+
+func appendRDBValue(dst []byte, key string, v any) []byte {
+	dst = append(dst, rdbTypeOf(v))
+	dst = appendRDBString(dst, key)
+	return appendRDBObject(dst, v)
+}
+
+// rdbTypeOf returns the RDB value type v is encoded as.
+func rdbTypeOf(v any) byte {
+	switch v.(type) {
+	case []byte:
+		return rdbTypeString
+	case *listValue:
+		return rdbTypeList
+	case setValue:
+		return rdbTypeSet
+	case hashValue:
+		return rdbTypeHash
+	case *zsetValue:
+		return rdbTypeZSet2
+	default:
+		panic(fmt.Sprintf("redismvp: cannot encode %T", v))
+	}
+}
+
+// appendRDBObject appends the encoding of v that follows its type and key.
+func appendRDBObject(dst []byte, v any) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return appendRDBString(dst, v)
+	case *listValue:
+		dst = appendRDBLen(dst, v.len())
+		for _, item := range v.items[v.head:] {
+			dst = appendRDBString(dst, item)
+		}
+		return dst
+	case setValue:
+		dst = appendRDBLen(dst, len(v))
+		for m := range v {
+			dst = appendRDBString(dst, m)
+		}
+		return dst
+	case hashValue:
+		dst = appendRDBLen(dst, len(v))
+		for f, val := range v {
+			dst = appendRDBString(dst, f)
+			dst = appendRDBString(dst, val)
+		}
+		return dst
+	case *zsetValue:
+		dst = appendRDBLen(dst, v.len())
+		for _, e := range v.order {
+			dst = appendRDBString(dst, e.member)
+			dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(e.score))
+		}
+		return dst
+	default:
+		panic(fmt.Sprintf("redismvp: cannot encode %T", v))
+	}
+}
+
+// appendDumpPayload appends the DUMP serialization of v: its type and
+// encoding as in an RDB file, the RDB version as two little-endian bytes,
+// and the checksum of all that.
+func appendDumpPayload(dst []byte, v any) []byte {
+	start := len(dst)
+	dst = append(dst, rdbTypeOf(v))
+	dst = appendRDBObject(dst, v)
+	dst = binary.LittleEndian.AppendUint16(dst, rdbVersion)
+	return binary.LittleEndian.AppendUint64(dst, rdbChecksum(0, dst[start:]))
+}
+
+// loadDumpPayload decodes a value serialized by DUMP, checking its version
+// and checksum.
+func loadDumpPayload(payload []byte) (any, error) {
+	errBad := errors.New("DUMP payload version or checksum are wrong")
+	if len(payload) < 10 {
+		return nil, errBad
+	}
+	body, footer := payload[:len(payload)-10], payload[len(payload)-10:]
+	sum := binary.LittleEndian.Uint64(footer[2:])
+	if binary.LittleEndian.Uint16(footer) > rdbVersion || (sum != 0 && sum != rdbChecksum(0, payload[:len(payload)-8])) {
+		return nil, errBad
+	}
+	r := &rdbReader{data: body}
+	v := r.value(r.byte())
+	if r.err == nil && r.off != len(body) {
+		r.err = errors.New("trailing data")
+	}
+	if r.err != nil {
+		return nil, errors.New("Bad data format")
+	}
+	return v, nil
+}
+
+func appendRDBLen(dst []byte, n int) []byte {
+	switch {
+	case n < 1<<6:
+		return append(dst, byte(n))
+	case n < 1<<14:
+		return append(dst, 0x40|byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, 0x80), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, 0x81), uint64(n))
+	}
+}
+
+func appendRDBString[T string | []byte](dst []byte, s T) []byte {
+	dst = appendRDBLen(dst, len(s))
+	return append(dst, s...)
+}
+
+// loadRDB adds the keys of the RDB file at the start of data to kv. It
+// returns the length of the file, which may be followed by more data.
+func loadRDB(data []byte, kv map[string]any) (int, error) {
+	if len(data) < 9 || string(data[:5]) != "REDIS" {
+		return 0, errors.New("not an RDB file")
+	}
+	version, err := strconv.Atoi(string(data[5:9]))
+	if err != nil || version < 1 || version > rdbVersion {
+		return 0, fmt.Errorf("unsupported RDB version %q", data[5:9])
+	}
+	r := &rdbReader{data: data, off: 9}
+	for {
+		op := r.byte()
+		if r.err != nil {
+			return 0, r.err
+		}
+		switch op {
+		case rdbOpEOF:
+			end := r.off
+			if version >= 5 {
+				sum := binary.LittleEndian.Uint64(r.read(8))
+				if r.err != nil {
+					return 0, r.err
+				}
+				if sum != 0 && sum != rdbChecksum(0, data[:end]) {
+					return 0, errors.New("RDB checksum mismatch")
+				}
+			}
+			return r.off, nil
+		case rdbOpAux:
+			r.string()
+			r.string()
+		case rdbOpSelectDB:
+			if db := r.length(); db != 0 && r.err == nil {
+				return 0, fmt.Errorf("RDB selects database %d, only 0 is supported", db)
+			}
+		case rdbOpResizeDB:
+			r.length()
+			r.length()
+		case rdbOpExpireMS:
+			// Keys never expire here, so expire times are dropped.
+			r.read(8)
+		case rdbOpExpire:
+			r.read(4)
+		default:
+			key := string(r.string())
+			v := r.value(op)
+			if r.err != nil {
+				return 0, r.err
+			}
+			kv[key] = v
+		}
+	}
+}
+
+// rdbReader decodes RDB data. The first error sticks: later reads return
+// zero values, so callers check err once per record.
+type rdbReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+// read returns the next n bytes. After an error it returns zeros, enough
+// of them for the fixed-size fields decoded from the result.
+func (r *rdbReader) read(n int) []byte {
+	if r.err == nil && n > len(r.data)-r.off {
+		r.err = errRDBTruncated
+	}
+	if r.err != nil {
+		return make([]byte, min(n, 8))
+	}
+	p := r.data[r.off : r.off+n]
+	r.off += n
+	return p
+}
+
+func (r *rdbReader) byte() byte {
+	return r.read(1)[0]
+}
+
+// lengthOrEncoding reads a length. If its top bits flag a special string
+// encoding instead, it returns the encoding and true.
+func (r *rdbReader) lengthOrEncoding() (int, bool) {
+	b := r.byte()
+	switch b >> 6 {
+	case 0:
+		return int(b), false
+	case 1:
+		return int(b&0x3f)<<8 | int(r.byte()), false
+	case 3:
+		return int(b & 0x3f), true
+	}
+	var n uint64
+	switch b {
+	case 0x80:
+		n = uint64(binary.BigEndian.Uint32(r.read(4)))
+	case 0x81:
+		n = binary.BigEndian.Uint64(r.read(8))
+	default:
+		r.fail(fmt.Errorf("bad RDB length prefix 0x%02x", b))
+	}
+	if n > math.MaxInt32 {
+		r.fail(fmt.Errorf("RDB length %d out of range", n))
+		return 0, false
+	}
+	return int(n), false
+}
+
+// length reads the length of a string or the element count of a value.
+// Either takes at least as many bytes, so lengths beyond the end of the
+// data are reported as truncation before anything is allocated.
+func (r *rdbReader) length() int {
+	n, encoded := r.lengthOrEncoding()
+	if encoded {
+		r.fail(errors.New("RDB string encoding where a length was expected"))
+	}
+	if n > len(r.data)-r.off {
+		r.fail(errRDBTruncated)
+		return 0
+	}
+	return n
+}
+
+func (r *rdbReader) string() []byte {
+	n, encoded := r.lengthOrEncoding()
+	if !encoded {
+		return slices.Clone(r.read(n))
+	}
+	switch n {
+	case rdbEncInt8:
+		return strconv.AppendInt(nil, int64(int8(r.byte())), 10)
+	case rdbEncInt16:
+		return strconv.AppendInt(nil, int64(int16(binary.LittleEndian.Uint16(r.read(2)))), 10)
+	case rdbEncInt32:
+		return strconv.AppendInt(nil, int64(int32(binary.LittleEndian.Uint32(r.read(4)))), 10)
+	case rdbEncLZF:
+		// The uncompressed size can exceed the data left, up to the
+		// compression ratio LZF can reach.
+		compressed := r.length()
+		size, encoded := r.lengthOrEncoding()
+		if encoded || size > lzfMaxRatio*compressed {
+			r.fail(errors.New("bad LZF string length in RDB"))
+			return nil
+		}
+		out, err := lzfDecompress(r.read(compressed), size)
+		if err != nil {
+			r.fail(err)
+		}
+		return out
+	default:
+		r.fail(fmt.Errorf("unknown RDB string encoding %d", n))
+		return nil
+	}
+}
+
+func (r *rdbReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *rdbReader) value(typ byte) any {
+	switch typ {
+	case rdbTypeString:
+		return r.string()
+	case rdbTypeList:
+		l := newList()
+		for n := r.length(); n > 0 && r.err == nil; n-- {
+			l.pushBack(r.string())
+		}
+		return l
+	case rdbTypeSet:
+		set := make(setValue)
+		for n := r.length(); n > 0 && r.err == nil; n-- {
+			set[string(r.string())] = struct{}{}
+		}
+		return set
+	case rdbTypeHash:
+		h := make(hashValue)
+		for n := r.length(); n > 0 && r.err == nil; n-- {
+			f := string(r.string())
+			h[f] = r.string()
+		}
+		return h
+	case rdbTypeZSet2:
+		z := newZSet()
+		for n := r.length(); n > 0 && r.err == nil; n-- {
+			m := string(r.string())
+			score := math.Float64frombits(binary.LittleEndian.Uint64(r.read(8)))
+			if _, dup := z.scores[m]; !dup {
+				z.scores[m] = score
+				z.order = append(z.order, zsetEntry{member: m, score: score})
+			}
+		}
+		slices.SortFunc(z.order, func(a, b zsetEntry) int {
+			if a.less(b.score, b.member) {
+				return -1
+			}
+			return 1
+		})
+		return z
+	default:
+		r.fail(fmt.Errorf("unsupported RDB value type %d", typ))
+		return nil
+	}
+}
+
+// lzfMaxRatio bounds the expansion of LZF data: a 3 byte back reference
+// yields at most 264 bytes.
+const lzfMaxRatio = 88
+
+// lzfDecompress expands LZF data, which Redis uses for long strings, into
+// a buffer of the given size.
+func lzfDecompress(in []byte, size int) ([]byte, error) {
+	errCorrupt := errors.New("corrupt LZF string in RDB")
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 {
+			// A literal run of ctrl+1 bytes.
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > size {
+				return nil, errCorrupt
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+		// A back reference: length in the top 3 bits, extended by one byte
+		// when they are all set, and a 13 bit offset.
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errCorrupt
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errCorrupt
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		n += 2
+		if ref < 0 || len(out)+n > size {
+			return nil, errCorrupt
+		}
+		// Copy byte by byte: the reference may overlap what it produces.
+		for j := range n {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != size {
+		return nil, errCorrupt
+	}
+	return out, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRDBChecksum(t *testing.T) {
+	// The check value of the Redis CRC-64 implementation.
+	if got := rdbChecksum(0, []byte("123456789")); got != 0xe9c6d914c4b8d9ca {
+		t.Fatalf("checksum = %#x", got)
+	}
+	if got := rdbChecksum(rdbChecksum(0, []byte("1234")), []byte("56789")); got != 0xe9c6d914c4b8d9ca {
+		t.Fatalf("checksum in two parts = %#x", got)
+	}
+}
+
+func TestRDBRoundTrip(t *testing.T) {
+	tc := newTestClient(t)
+	tc.do("SET", "str", "value")
+	tc.do("SET", "empty", "")
+	tc.do("SET", "long", strings.Repeat("x", 20000))
+	tc.do("RPUSH", "list", "a", "b", "c")
+	tc.do("LPUSH", "list", "front")
+	tc.do("SADD", "set", "m1", "m2")
+	tc.do("HSET", "hash", "f1", "v1", "f2", "")
+	tc.do("ZADD", "zset", "1.5", "a", "-inf", "b", "1.5", "0")
+	kv := tc.c.server.store.kv
+
+	data := appendRDB([]byte("prefix"), kv, "aof-base", "1")
+	data = append(data, "*1\r\n$4\r\nPING\r\n"...)
+	loaded := make(map[string]any)
+	n, err := loadRDB(data[len("prefix"):], loaded)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if rest := string(data[len("prefix")+n:]); rest != "*1\r\n$4\r\nPING\r\n" {
+		t.Fatalf("data after the RDB file: %q", rest)
+	}
+	if got, want := dumpStore(&Store{kv: loaded}), dumpStore(&Store{kv: kv}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded %v, want %v", got, want)
+	}
+	if z := loaded["zset"].(*zsetValue); z.order[0].member != "b" || z.order[1].member != "0" {
+		t.Fatalf("zset order after load: %v", z.order)
+	}
+}
+
+func TestRDBLoadErrors(t *testing.T) {
+	data := appendRDB(nil, map[string]any{"k": []byte("v")})
+	for _, cut := range []int{5, 12, len(data) - 9, len(data) - 1} {
+		if _, err := loadRDB(data[:cut], map[string]any{}); err == nil {
+			t.Fatalf("loaded RDB data cut at %d of %d bytes", cut, len(data))
+		}
+	}
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)-12] ^= 1
+	if _, err := loadRDB(corrupt, map[string]any{}); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("corrupt data: %v", err)
+	}
+	if _, err := loadRDB([]byte("REDIS0099\xff"), map[string]any{}); err == nil {
+		t.Fatal("loaded a future RDB version")
+	}
+	if _, err := loadRDB(data[:len(data)-11], map[string]any{}); !errors.Is(err, errRDBTruncated) {
+		t.Fatalf("truncated data: %v", err)
+	}
+}
+
+// TestRDBLoadRedisEncodings loads strings the way Redis writes them:
+// integers in binary and long strings LZF compressed.
+func TestRDBLoadRedisEncodings(t *testing.T) {
+	data := []byte("REDIS0011")
+	data = append(data, rdbTypeString, 1, 'a', 0xc0, 0xfe)                   // int8 -2
+	data = append(data, rdbTypeString, 1, 'b', 0xc1, 0x39, 0x30)             // int16 12345
+	data = append(data, rdbTypeString, 1, 'c', 0xc2, 0x00, 0x00, 0x00, 0x80) // int32 min
+	// "abcabcabcabc": a literal "abc" then a 9 byte reference 3 back.
+	data = append(data, rdbTypeString, 1, 'd', 0xc3, 7, 12, 2, 'a', 'b', 'c', 7<<5, 0, 2)
+	data = append(data, rdbOpEOF, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	kv := make(map[string]any)
+	if _, err := loadRDB(data, kv); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := map[string]string{"a": "-2", "b": "12345", "c": "-2147483648", "d": "abcabcabcabc"}
+	for k, v := range want {
+		if got := string(kv[k].([]byte)); got != v {
+			t.Fatalf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// replLinkState is the progress of a replica's connection to its master,
+// named after the states ROLE reports.
+type replLinkState uint8
+
+const (
+	// linkConnect waits for the next connection attempt.
+	linkConnect replLinkState = iota
+	// linkHandshake has sent PING, REPLCONF and PSYNC and reads the
+	// replies to the first three.
+	linkHandshake
+	// linkPSync waits for the reply to PSYNC.
+	linkPSync
+	// linkTransfer receives the snapshot of a full resync.
+	linkTransfer
+	// linkConnected applies the replication stream.
+	linkConnected
+)
+
+func (st replLinkState) String() string {
+	switch st {
+	case linkConnect:
+		return "connect"
+	case linkHandshake, linkPSync:
+		return "handshake"
+	case linkTransfer:
+		return "sync"
+	default:
+		return "connected"
+	}
+}
+
+const (
+	replRetryDelay  = time.Second
+	replAckInterval = time.Second
+)
+
+// masterLink is a replica's connection to its master. Dialing and reading
+// happen on a goroutine per connection; the bytes read are handed to the
+// loop goroutine, which runs the handshake and applies the stream.
+type masterLink struct {
+	host string
+	port int
+
+	dial       func(network, addr string) (net.Conn, error)
+	retryDelay time.Duration
+	// failover is set when we connect to a replica that takes over as
+	// master; its PSYNC asks the replica to promote itself first.
+	failover bool
+
+	state   replLinkState
+	conn    *masterConn
+	retryAt time.Time
+	lastAck time.Time
+	// fsyncAcked is the AOF fsync offset sent with the last ACK.
+	fsyncAcked int64
+
+	// buf holds bytes received but not yet consumed by the handshake or
+	// the snapshot transfer.
+	buf []byte
+	// handshakeReplies counts the replies read in linkHandshake.
+	handshakeReplies int
+	// transferSize is the snapshot length, or -1 before its header.
+	transferSize int
+	snapshot     []byte
+	masterID     string
+	masterOffset int64
+
+	parser *redisproto.Parser
+	// client executes the commands of the stream.
+	client *clientConn
+}
+
+func newMasterLink(s *Server, host string, port int) *masterLink {
+	dial := s.replDial
+	if dial == nil {
+		dial = func(network, addr string) (net.Conn, error) {
+			d := net.Dialer{Timeout: 2 * time.Second}
+			return d.Dial(network, addr)
+		}
+	}
+	return &masterLink{
+		host:       host,
+		port:       port,
+		dial:       dial,
+		retryDelay: replRetryDelay,
+		client: &clientConn{
+			server:   s,
+			log:      s.log.With("client", "master"),
+			replayed: true,
+		},
+	}
+}
+
+func (l *masterLink) addr() string {
+	return net.JoinHostPort(l.host, strconv.Itoa(l.port))
+}
+
+// masterConn is one connection to the master, shared between its reader
+// goroutine and the loop, which the reader wakes when it has news.
+type masterConn struct {
+	wake   func()
+	mu     sync.Mutex
+	conn   net.Conn
+	inbox  []byte
+	err    error
+	closed bool
+}
+
+func (mc *masterConn) run(dial func(string, string) (net.Conn, error), addr string, handshake []byte) {
+	defer mc.wake()
+	conn, err := dial("tcp", addr)
+	if err != nil {
+		mc.fail(err)
+		return
+	}
+	mc.mu.Lock()
+	if mc.closed {
+		mc.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	mc.conn = conn
+	mc.mu.Unlock()
+
+	if _, err := conn.Write(handshake); err != nil {
+		mc.fail(err)
+		return
+	}
+	buf := make([]byte, 16<<10)
+	for {
+		n, err := conn.Read(buf)
+		mc.mu.Lock()
+		mc.inbox = append(mc.inbox, buf[:n]...)
+		if err != nil && mc.err == nil {
+			mc.err = err
+		}
+		mc.mu.Unlock()
+		if err != nil {
+			return
+		}
+		mc.wake()
+	}
+}
+
+func (mc *masterConn) fail(err error) {
+	mc.mu.Lock()
+	if mc.err == nil {
+		mc.err = err
+	}
+	mc.mu.Unlock()
+}
+
+// take returns the bytes read since the last call and the read error, if
+// the connection failed.
+func (mc *masterConn) take() ([]byte, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	data := mc.inbox
+	mc.inbox = nil
+	return data, mc.err
+}
+
+func (mc *masterConn) write(p []byte) error {
+	mc.mu.Lock()
+	conn := mc.conn
+	mc.mu.Unlock()
+	if conn == nil {
+		return errors.New("not connected")
+	}
+	_, err := conn.Write(p)
+	return err
+}
+
+func (mc *masterConn) close() {
+	mc.mu.Lock()
+	mc.closed = true
+	conn := mc.conn
+	mc.mu.Unlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
+
+// poll connects when a retry is due, consumes what the master sent and
+// acknowledges the processed offset.
+func (l *masterLink) poll(s *Server, now time.Time) {
+	if l.conn == nil {
+		if !now.Before(l.retryAt) {
+			l.connect(s)
+		}
+		return
+	}
+	data, err := l.conn.take()
+	if len(data) > 0 {
+		if herr := l.handle(s, data); herr != nil {
+			err = herr
+		}
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("connection closed by master")
+		}
+		s.log.Warn("lost connection to master", "master", l.addr(), "err", err)
+		l.drop(now)
+		if l.failover {
+			s.abortFailover("failover target did not accept the sync: " + err.Error())
+		}
+		return
+	}
+	if l.failover && l.state == linkConnected {
+		l.failover = false
+		s.failoverDone()
+	}
+	// A master waiting in WAITAOF learns about our fsyncs right away.
+	fsynced := s.aof != nil && s.aof.fsyncedOffset != l.fsyncAcked
+	if l.state == linkConnected && (fsynced || now.Sub(l.lastAck) >= replAckInterval) {
+		l.sendAck(s, now)
+	}
+}
+
+// connect starts a connection attempt. The handshake is pipelined: PSYNC
+// asks to continue from the offset after the last byte we processed, with
+// the ID of the history it belongs to.
+func (l *masterLink) connect(s *Server) {
+	id, offset := "?", "-1"
+	if s.repl.id != "" {
+		id, offset = s.repl.id, strconv.FormatInt(s.repl.offset+1, 10)
+	}
+	var hs []byte
+	hs = appendBulkArray(hs, [][]byte{[]byte("PING")})
+	hs = appendBulkArray(hs, [][]byte{[]byte("REPLCONF"), []byte("listening-port"), []byte(strconv.Itoa(s.port()))})
+	hs = appendBulkArray(hs, [][]byte{[]byte("REPLCONF"), []byte("capa"), []byte("psync2")})
+	psync := [][]byte{[]byte("PSYNC"), []byte(id), []byte(offset)}
+	if l.failover {
+		psync = append(psync, []byte("FAILOVER"))
+	}
+	hs = appendBulkArray(hs, psync)
+
+	l.conn = &masterConn{wake: s.wake}
+	l.state = linkHandshake
+	l.buf = nil
+	l.handshakeReplies = 0
+	l.transferSize = -1
+	l.snapshot = nil
+	s.log.Info("connecting to master", "master", l.addr(), "replid", id, "offset", offset)
+	go l.conn.run(l.dial, l.addr(), hs)
+}
+
+// drop closes the connection and schedules a reconnection. Stream bytes
+// of a partially received command are discarded; they are sent again
+// after the offset we report on the next PSYNC.
+func (l *masterLink) drop(now time.Time) {
+	if l.conn != nil {
+		l.conn.close()
+		l.conn = nil
+	}
+	l.state = linkConnect
+	l.retryAt = now.Add(l.retryDelay)
+	l.parser = nil
+	l.buf = nil
+	l.snapshot = nil
+}
+
+func (l *masterLink) close() {
+	l.drop(time.Time{})
+}
+
+func (l *masterLink) handle(s *Server, data []byte) error {
+	if l.state == linkConnected {
+		return l.apply(s, data)
+	}
+	l.buf = append(l.buf, data...)
+	for {
+		switch l.state {
+		case linkHandshake, linkPSync:
+			line, ok := l.nextLine()
+			if !ok {
+				return nil
+			}
+			// Masters send bare newlines as keepalives while preparing
+			// a snapshot.
+			if line == "" {
+				continue
+			}
+			if err := l.handshakeReply(s, line); err != nil {
+				return err
+			}
+		case linkTransfer:
+			if l.transferSize < 0 {
+				line, ok := l.nextLine()
+				if !ok {
+					return nil
+				}
+				if line == "" {
+					continue
+				}
+				n, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+				if !strings.HasPrefix(line, "$") || err != nil || n < 0 {
+					return fmt.Errorf("bad snapshot header %q", line)
+				}
+				l.transferSize = n
+				l.snapshot = make([]byte, 0, n)
+			}
+			take := min(len(l.buf), l.transferSize-len(l.snapshot))
+			l.snapshot = append(l.snapshot, l.buf[:take]...)
+			l.buf = l.buf[take:]
+			if len(l.snapshot) < l.transferSize {
+				return nil
+			}
+			if err := l.loadSnapshot(s); err != nil {
+				return err
+			}
+		case linkConnected:
+			rest := l.buf
+			l.buf = nil
+			if len(rest) == 0 {
+				return nil
+			}
+			return l.apply(s, rest)
+		default:
+			return nil
+		}
+	}
+}
+
+// nextLine pops a CRLF-terminated line from buf.
+func (l *masterLink) nextLine() (string, bool) {
+	i := bytes.IndexByte(l.buf, '\n')
+	if i < 0 {
+		return "", false
+	}
+	line := strings.TrimSuffix(string(l.buf[:i]), "\r")
+	l.buf = l.buf[i+1:]
+	return line, true
+}
+
+func (l *masterLink) handshakeReply(s *Server, line string) error {
+	if l.state == linkHandshake {
+		l.handshakeReplies++
+		switch {
+		case l.handshakeReplies == 1 && line[0] == '-':
+			return fmt.Errorf("master replied to PING: %s", line[1:])
+		case line[0] == '-':
+			// Old masters reject REPLCONF options they do not know.
+			s.log.Log(context.Background(), LevelVerbose, "master rejected REPLCONF", "reply", line[1:])
+		}
+		if l.handshakeReplies == 3 {
+			l.state = linkPSync
+		}
+		return nil
+	}
+
+	fields := strings.Fields(line)
+	switch {
+	case fields[0] == "+FULLRESYNC" && len(fields) == 3:
+		offset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad FULLRESYNC reply %q", line)
+		}
+		l.masterID, l.masterOffset = fields[1], offset
+		l.state = linkTransfer
+		s.log.Info("full resync from master", "replid", l.masterID, "offset", offset)
+	case fields[0] == "+CONTINUE":
+		r := &s.repl
+		if len(fields) > 1 && fields[1] != r.id {
+			r.shiftReplID()
+			r.id = fields[1]
+			// Our replicas follow the old ID; reconnecting lets them
+			// continue under the new one.
+			s.disconnectReplicas("master replication ID changed")
+		}
+		if r.backlog == nil {
+			r.createBacklog()
+		}
+		l.startStream(s)
+		s.log.Info("partial resync with master accepted", "replid", r.id, "offset", r.offset)
+	default:
+		return fmt.Errorf("unexpected reply to PSYNC: %s", line)
+	}
+	return nil
+}
+
+// loadSnapshot replaces the dataset with the snapshot of a full resync and
+// adopts the master's history.
+func (l *masterLink) loadSnapshot(s *Server) error {
+	frames, err := redisproto.NewParser().Feed(l.snapshot)
+	l.snapshot = nil
+	if err != nil {
+		return fmt.Errorf("bad snapshot: %w", err)
+	}
+
+	store := s.store
+	store.mu.Lock()
+	if err := s.waitSnapshot(); err != nil {
+		s.log.Warn("background snapshot before full resync failed", "err", err)
+	}
+	for key, v := range store.kv {
+		s.lazyFree.free(v)
+		delete(store.kv, key)
+	}
+	if s.evict != nil {
+		s.evict.recount(store.kv)
+	}
+	s.resetAOF()
+	store.mu.Unlock()
+	for _, frame := range frames {
+		l.execute(frame)
+	}
+	if s.aof != nil {
+		store.mu.Lock()
+		err := s.startAOFRewrite()
+		store.mu.Unlock()
+		if err != nil {
+			s.log.Warn("rewriting the append only file after a full resync failed", "err", err)
+		}
+	}
+
+	r := &s.repl
+	r.id, r.offset = l.masterID, l.masterOffset
+	r.clearSecondID()
+	r.backlog = nil
+	r.createBacklog()
+	s.disconnectReplicas("full resync with master")
+	l.startStream(s)
+	s.log.Info("snapshot loaded", "commands", len(frames))
+	return nil
+}
+
+func (l *masterLink) startStream(s *Server) {
+	l.state = linkConnected
+	l.parser = redisproto.NewParser()
+	l.lastAck = time.Time{}
+}
+
+// apply executes the commands of the stream and proxies them to our own
+// backlog and replicas. Only complete commands advance the offset.
+func (l *masterLink) apply(s *Server, data []byte) error {
+	frames, err := l.parser.Feed(data)
+	if err != nil {
+		return fmt.Errorf("bad replication stream: %w", err)
+	}
+	for _, frame := range frames {
+		raw, err := redisproto.Encode(frame)
+		if err != nil {
+			return fmt.Errorf("bad replication stream: %w", err)
+		}
+		if !isGetAck(frame) {
+			l.execute(frame)
+		}
+		s.feedReplication(raw)
+		if isGetAck(frame) {
+			// The acknowledged offset includes the GETACK, so a master
+			// waiting for us to catch up sees its own offset.
+			l.sendAck(s, time.Now())
+		}
+	}
+	// Writes from the master never serve local waiters: popping for them
+	// here would make our dataset diverge from the master's.
+	s.readyKeys = s.readyKeys[:0]
+	return nil
+}
+
+func (l *masterLink) execute(frame redisproto.Value) {
+	l.client.replay(frame)
+}
+
+func isGetAck(frame redisproto.Value) bool {
+	if len(frame.Array) != 3 {
+		return false
+	}
+	name, _ := tokenBytes(frame.Array[0])
+	sub, _ := tokenBytes(frame.Array[1])
+	return argIs(name, "REPLCONF") && argIs(sub, "GETACK")
+}
+
+func (l *masterLink) sendAck(s *Server, now time.Time) {
+	l.lastAck = now
+	ack := [][]byte{[]byte("REPLCONF"), []byte("ACK"), []byte(strconv.FormatInt(s.repl.offset, 10))}
+	if s.aof != nil {
+		l.fsyncAcked = s.aof.fsyncedOffset
+		ack = append(ack, []byte("FACK"), []byte(strconv.FormatInt(l.fsyncAcked, 10)))
+	}
+	if err := l.conn.write(appendBulkArray(nil, ack)); err != nil {
+		s.log.Warn("sending ACK to master failed", "err", err)
+	}
+}
@@ -0,0 +1,370 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Replication follows the Redis PSYNC2 design. A master feeds every write
+// command it executes, encoded as a RESP array, to its online replicas and
+// to a circular backlog. The stream is addressed by a replication ID and a
+// byte offset, so a replica that reconnects can ask for the bytes it
+// missed (PSYNC <id> <offset>) and only falls back to a full snapshot when
+// the master no longer holds them or the history has changed.
+//
+// A replica proxies the exact bytes it receives into its own backlog and
+// to its own replicas, so its offset always matches its master's and it
+// can serve partial resyncs after being promoted.
+
+// DefaultReplBacklogSize is the replication backlog size used when
+// Config.ReplBacklogSize is zero, matching Redis's repl-backlog-size.
+const DefaultReplBacklogSize = 1 << 20
+
+// replBacklog is a circular buffer holding the most recent part of the
+// replication stream.
+type replBacklog struct {
+	buf []byte
+	// idx is where the next byte is written.
+	idx int
+	// histLen is the number of valid bytes, at most len(buf).
+	histLen int
+	// end is the replication offset of the last byte written.
+	end int64
+}
+
+// newReplBacklog creates an empty backlog whose next byte will have
+// offset end+1.
+func newReplBacklog(size int, end int64) *replBacklog {
+	return &replBacklog{buf: make([]byte, size), end: end}
+}
+
+// start returns the offset of the oldest byte held.
+func (b *replBacklog) start() int64 {
+	return b.end - int64(b.histLen) + 1
+}
+
+func (b *replBacklog) write(p []byte) {
+	b.end += int64(len(p))
+	if len(p) > len(b.buf) {
+		p = p[len(p)-len(b.buf):]
+	}
+	for len(p) > 0 {
+		n := copy(b.buf[b.idx:], p)
+		p = p[n:]
+		b.idx = (b.idx + n) % len(b.buf)
+		b.histLen = min(b.histLen+n, len(b.buf))
+	}
+}
+
+// since returns a copy of the stream from offset through the last byte
+// written. It reports false if offset is not in the backlog; offset end+1
+// is valid and yields no bytes.
+func (b *replBacklog) since(offset int64) ([]byte, bool) {
+	if offset < b.start() || offset > b.end+1 {
+		return nil, false
+	}
+	n := int(b.end + 1 - offset)
+	out := make([]byte, 0, n)
+	from := (b.idx - n + len(b.buf)) % len(b.buf)
+	if from+n <= len(b.buf) {
+		return append(out, b.buf[from:from+n]...), true
+	}
+	out = append(out, b.buf[from:]...)
+	return append(out, b.buf[:n-(len(b.buf)-from)]...), true
+}
+
+// replState is the replication state of a server, only touched from the
+// loop goroutine. The zero value is a master without a backlog.
+type replState struct {
+	// id names the history the current offset belongs to. id2 is the
+	// previous history, which is shared with ours up to secondOffset; it
+	// lets replicas of our former master resync partially after a
+	// promotion.
+	id           string
+	id2          string
+	offset       int64
+	secondOffset int64
+
+	backlogSize int
+	backlog     *replBacklog
+	replicas    map[*clientConn]struct{}
+
+	// link is the connection to our master; it is nil on a master.
+	link *masterLink
+
+	fullSyncs    int64
+	partialSyncs int64
+}
+
+// replicaInfo is the master-side state of a connection that announced
+// itself as a replica.
+type replicaInfo struct {
+	listeningPort int
+	// online is set once the replica has been sent the stream it asked
+	// for and receives propagated writes.
+	online    bool
+	ackOffset int64
+	ackTime   time.Time
+	// aofAckOffset is the offset the replica's AOF has fsynced, or -1 for
+	// a replica without AOF.
+	aofAckOffset int64
+}
+
+func newReplID() string {
+	var b [20]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// shiftReplID starts a new history, keeping the current one as the
+// secondary ID so replicas that followed it can still resync partially.
+func (r *replState) shiftReplID() {
+	r.id2 = r.id
+	r.secondOffset = r.offset + 1
+	r.id = newReplID()
+}
+
+func (r *replState) clearSecondID() {
+	r.id2 = ""
+	r.secondOffset = -1
+}
+
+// createBacklog allocates the backlog. A master that had none has not
+// recorded its recent history, so it also starts a new one: a replica
+// presenting the old ID must not be offered a partial resync.
+func (r *replState) createBacklog() {
+	size := r.backlogSize
+	if size <= 0 {
+		size = DefaultReplBacklogSize
+	}
+	if r.link == nil {
+		r.id = newReplID()
+		r.clearSecondID()
+	}
+	r.backlog = newReplBacklog(size, r.offset)
+}
+
+// propagate feeds a write command executed on this server to the AOF and
+// the replication stream. Replicas only proxy the stream of their master,
+// but log the writes they apply to their own AOF.
+func (s *Server) propagate(args [][]byte) {
+	if s.repl.link != nil && s.aof == nil {
+		return
+	}
+	raw := appendBulkArray(nil, args)
+	if s.repl.link == nil {
+		s.feedReplication(raw)
+	}
+	s.feedAOF(raw)
+}
+
+// feedReplication appends raw stream bytes to the backlog and sends them to
+// every online replica.
+func (s *Server) feedReplication(raw []byte) {
+	r := &s.repl
+	if r.backlog == nil {
+		return
+	}
+	r.offset += int64(len(raw))
+	r.backlog.write(raw)
+	for c := range r.replicas {
+		if err := writeAll(c.fd, raw); err != nil {
+			c.close("replica write error: " + err.Error())
+		}
+	}
+}
+
+// propagateAs replaces the arguments of the running write command in the
+// replication stream, for commands whose effect is not determined by their
+// arguments alone.
+func (c *clientConn) propagateAs(args ...[]byte) {
+	c.propagateArgs = args
+}
+
+// skipPropagation keeps the running write command out of the replication
+// stream, for commands that turned out to change nothing.
+func (c *clientConn) skipPropagation() {
+	c.propagateArgs = [][]byte{}
+}
+
+// syncReplica answers PSYNC (or SYNC, when psync is false) and makes c an
+// online replica. A partial resync sends +CONTINUE and the missed part of
+// the stream; otherwise the reply is +FULLRESYNC followed by a snapshot of
+// the dataset as a bulk payload without trailing CRLF, as Redis sends its
+// RDB file.
+func (s *Server) syncReplica(c *clientConn, dst []byte, id string, offset int64, psync bool) []byte {
+	r := &s.repl
+	if c.replica == nil {
+		c.replica = &replicaInfo{aofAckOffset: -1}
+	}
+	if psync {
+		if missed, ok := r.partialResync(id, offset); ok {
+			r.partialSyncs++
+			dst = appendSimple(dst, "CONTINUE "+r.id)
+			dst = append(dst, missed...)
+			s.addReplica(c)
+			c.log.Info("partial resynchronization accepted", "offset", offset, "backlog_bytes", len(missed))
+			return dst
+		}
+	}
+
+	if r.backlog == nil {
+		r.createBacklog()
+	}
+	r.fullSyncs++
+	if psync {
+		dst = appendSimple(dst, "FULLRESYNC "+r.id+" "+strconv.FormatInt(r.offset, 10))
+	}
+	payload := appendDatasetCommands(nil, s.store)
+	dst = append(dst, '$')
+	dst = strconv.AppendInt(dst, int64(len(payload)), 10)
+	dst = append(dst, '\r', '\n')
+	dst = append(dst, payload...)
+	s.addReplica(c)
+	c.log.Info("full resynchronization", "offset", r.offset, "snapshot_bytes", len(payload))
+	return dst
+}
+
+// partialResync returns the part of the stream a replica at offset with
+// history id is missing, if the backlog still holds it.
+func (r *replState) partialResync(id string, offset int64) ([]byte, bool) {
+	if r.backlog == nil || id == "" {
+		return nil, false
+	}
+	if id != r.id && (id != r.id2 || offset > r.secondOffset) {
+		return nil, false
+	}
+	return r.backlog.since(offset)
+}
+
+func (s *Server) addReplica(c *clientConn) {
+	if s.repl.replicas == nil {
+		s.repl.replicas = make(map[*clientConn]struct{})
+	}
+	s.repl.replicas[c] = struct{}{}
+	c.replica.online = true
+	c.replica.ackOffset = s.repl.offset
+	if r := s.reaper; r != nil {
+		r.Remove(c)
+	}
+}
+
+func (s *Server) removeReplica(c *clientConn) {
+	if c.replica != nil {
+		delete(s.repl.replicas, c)
+	}
+}
+
+// sortedConns orders a set of connections by fd, so replies and actions
+// over them are stable.
+func sortedConns(conns map[*clientConn]struct{}) []*clientConn {
+	return slices.SortedFunc(maps.Keys(conns), func(a, b *clientConn) int {
+		return cmp.Compare(a.fd, b.fd)
+	})
+}
+
+// disconnectReplicas drops every replica, which makes them reconnect and
+// resync against our new history.
+func (s *Server) disconnectReplicas(reason string) {
+	for c := range s.repl.replicas {
+		c.close(reason)
+	}
+}
+
+// datasetItemsPerCommand caps the elements one command of a dataset dump
+// adds to a collection, as Redis does when rewriting its AOF, so large
+// collections stay within the protocol limits.
+const datasetItemsPerCommand = 64
+
+// appendDatasetCommands appends commands that rebuild every key of store.
+// It is the snapshot sent to replicas on a full resync; the caller holds
+// the store lock.
+func appendDatasetCommands(dst []byte, store *Store) []byte {
+	for key, v := range store.kv {
+		dst = appendKeyCommands(dst, key, v)
+	}
+	return dst
+}
+
+// appendKeyCommands appends the commands that recreate key holding v.
+func appendKeyCommands(dst []byte, key string, v any) []byte {
+	k := []byte(key)
+	switch v := v.(type) {
+	case []byte:
+		return appendBulkArray(dst, [][]byte{[]byte("SET"), k, v})
+	case *listValue:
+		return appendBatchedCommands(dst, "RPUSH", k, 1, v.elements(0, v.len()-1))
+	case setValue:
+		items := make([][]byte, 0, len(v))
+		for m := range v {
+			items = append(items, []byte(m))
+		}
+		return appendBatchedCommands(dst, "SADD", k, 1, items)
+	case hashValue:
+		items := make([][]byte, 0, 2*len(v))
+		for f, val := range v {
+			items = append(items, []byte(f), val)
+		}
+		return appendBatchedCommands(dst, "HSET", k, 2, items)
+	case *zsetValue:
+		items := make([][]byte, 0, 2*v.len())
+		for _, e := range v.order {
+			items = append(items, formatScore(e.score), []byte(e.member))
+		}
+		return appendBatchedCommands(dst, "ZADD", k, 2, items)
+	default:
+		panic(fmt.Sprintf("redismvp: cannot encode %T", v))
+	}
+}
+
+// appendBatchedCommands appends "name key items...", split into commands
+// of at most datasetItemsPerCommand elements of width arguments each.
+func appendBatchedCommands(dst []byte, name string, key []byte, width int, items [][]byte) []byte {
+	for len(items) > 0 {
+		n := min(datasetItemsPerCommand*width, len(items))
+		args := append([][]byte{[]byte(name), key}, items[:n]...)
+		dst = appendBulkArray(dst, args)
+		items = items[n:]
+	}
+	return dst
+}
+
+// pollReplication drives the link to our master, if any, and a pending
+// failover.
+func (s *Server) pollReplication(now time.Time) {
+	if l := s.repl.link; l != nil {
+		l.poll(s, now)
+	}
+	s.pollFailover(now)
+}
+
+// port returns the port the server listens on, or 0 if it has no listener.
+func (s *Server) port() int {
+	if s.listener == nil {
+		return 0
+	}
+	_, port := s.listener.Addr()
+	return int(port)
+}
+
+// replicaAddr returns the address a replica can be reached at: its peer IP
+// and the port it announced with REPLCONF listening-port.
+func (c *clientConn) replicaAddr() (string, int) {
+	host, _, err := net.SplitHostPort(peerAddr(c.fd))
+	if err != nil {
+		host = ""
+	}
+	return host, c.replica.listeningPort
+}
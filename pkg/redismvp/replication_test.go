@@ -0,0 +1,541 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+func TestReplBacklog(t *testing.T) {
+	b := newReplBacklog(8, 100)
+	if _, ok := b.since(101); !ok {
+		t.Fatal("empty backlog should accept the next offset")
+	}
+	if _, ok := b.since(100); ok {
+		t.Fatal("empty backlog accepted an offset it never held")
+	}
+
+	b.write([]byte("abcde"))
+	if got, _ := b.since(102); string(got) != "bcde" {
+		t.Fatalf("since(102) = %q", got)
+	}
+	// Wrap around: the oldest bytes are overwritten.
+	b.write([]byte("fghij"))
+	if b.start() != 103 || b.end != 110 {
+		t.Fatalf("backlog holds [%d, %d], want [103, 110]", b.start(), b.end)
+	}
+	if got, _ := b.since(103); string(got) != "cdefghij" {
+		t.Fatalf("since(103) = %q", got)
+	}
+	if got, ok := b.since(111); !ok || len(got) != 0 {
+		t.Fatalf("since(end+1) = %q, %v", got, ok)
+	}
+	for _, off := range []int64{102, 112} {
+		if _, ok := b.since(off); ok {
+			t.Fatalf("since(%d) should be out of range", off)
+		}
+	}
+
+	// A write larger than the buffer keeps its tail.
+	b.write([]byte("0123456789"))
+	if got, _ := b.since(b.start()); string(got) != "23456789" {
+		t.Fatalf("after oversized write got %q", got)
+	}
+}
+
+func TestPartialResyncDecision(t *testing.T) {
+	r := &replState{backlogSize: 64}
+	r.createBacklog()
+	r.backlog.write([]byte("0123456789"))
+	r.offset = 10
+	oldID := r.id
+
+	cases := []struct {
+		id     string
+		offset int64
+		ok     bool
+	}{
+		{oldID, 1, true},
+		{oldID, 11, true},
+		{oldID, 12, false},
+		{"unknown", 5, false},
+		{"?", -1, false},
+	}
+	for _, tc := range cases {
+		if _, ok := r.partialResync(tc.id, tc.offset); ok != tc.ok {
+			t.Fatalf("partialResync(%s, %d) = %v, want %v", tc.id, tc.offset, ok, tc.ok)
+		}
+	}
+
+	// After a promotion the old ID stays valid up to the switch point.
+	r.shiftReplID()
+	r.backlog.write([]byte("ab"))
+	r.offset = 12
+	if got, ok := r.partialResync(oldID, 11); !ok || string(got) != "ab" {
+		t.Fatalf("resync on the previous ID: %q, %v", got, ok)
+	}
+	if _, ok := r.partialResync(oldID, 12); ok {
+		t.Fatal("previous ID accepted past the switch point")
+	}
+	if _, ok := r.partialResync(r.id, 12); !ok {
+		t.Fatal("current ID rejected")
+	}
+}
+
+// replTestPair connects a replica test server to a master test server over
+// socket pairs; either server can dial the other. The test goroutine drives
+// both servers, standing in for their run loops.
+type replTestPair struct {
+	t        *testing.T
+	master   *Server
+	replica  *Server
+	accepted chan *clientConn
+	// links are the accepting side of every connection made.
+	links []*clientConn
+	// readLimit, when not negative, is the number of bytes the replica
+	// can read before its connection fails.
+	readLimit atomic.Int64
+}
+
+func newReplTestPair(t *testing.T, backlogSize int) *replTestPair {
+	t.Helper()
+	p := &replTestPair{
+		t:        t,
+		master:   newBlockingTestServer(),
+		replica:  newBlockingTestServer(),
+		accepted: make(chan *clientConn, 8),
+	}
+	p.master.repl.backlogSize = backlogSize
+	p.readLimit.Store(-1)
+	t.Cleanup(func() {
+		if l := p.replica.repl.link; l != nil {
+			l.close()
+		}
+		if l := p.master.repl.link; l != nil {
+			l.close()
+		}
+		for _, c := range p.links {
+			c.close("test done")
+		}
+		p.master.flushPendingFDs()
+		p.replica.flushPendingFDs()
+	})
+
+	p.master.replDial = p.dialer(p.replica)
+	p.replica.replDial = p.dialer(p.master)
+	p.onReplica().do("REPLICAOF", "127.0.0.1", "6379")
+	p.replica.repl.link.retryDelay = 0
+	return p
+}
+
+func (p *replTestPair) onMaster() *testClient {
+	return &testClient{t: p.t, c: &clientConn{server: p.master, log: p.master.log}}
+}
+
+func (p *replTestPair) onReplica() *testClient {
+	return &testClient{t: p.t, c: &clientConn{server: p.replica, log: p.replica.log}}
+}
+
+// dialer returns a dial function connecting to target. It runs on the
+// dialing server's connection goroutine.
+func (p *replTestPair) dialer(target *Server) func(string, string) (net.Conn, error) {
+	return func(_, _ string) (net.Conn, error) {
+		return p.dial(target)
+	}
+}
+
+func (p *replTestPair) dial(target *Server) (net.Conn, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.SetNonblock(fds[0], true); err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fds[1]), "dialer")
+	conn, err := net.FileConn(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	p.accepted <- &clientConn{
+		server: target,
+		fd:     int32(fds[0]),
+		codec:  redisproto.NewCodec(),
+		log:    target.log,
+	}
+	return &limitConn{Conn: conn, limit: &p.readLimit}, nil
+}
+
+// step runs one iteration of both servers' loops.
+func (p *replTestPair) step() {
+	for len(p.accepted) > 0 {
+		p.links = append(p.links, <-p.accepted)
+	}
+	buf := make([]byte, 64<<10)
+	for _, c := range p.links {
+		for !c.closed {
+			n, err := syscall.Read(int(c.fd), buf)
+			if errors.Is(err, syscall.EAGAIN) {
+				break
+			}
+			if n <= 0 {
+				c.close("peer closed")
+				break
+			}
+			c.onRead(nil, slices.Clone(buf[:n]), nil)
+		}
+	}
+	now := time.Now()
+	for _, s := range []*Server{p.master, p.replica} {
+		s.flushPendingFDs()
+		s.pollReplication(now)
+		s.pollAOF(now)
+		s.pollWaitAOF()
+	}
+}
+
+func (p *replTestPair) waitFor(what string, cond func() bool) {
+	p.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			p.t.Fatalf("timed out waiting for %s", what)
+		}
+		p.step()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitSynced waits until the replica has applied everything the master
+// propagated and checks that both hold the same data.
+func (p *replTestPair) waitSynced() {
+	p.t.Helper()
+	p.waitFor("replica to catch up", func() bool {
+		l := p.replica.repl.link
+		return l.state == linkConnected && p.replica.repl.offset == p.master.repl.offset &&
+			p.replica.repl.id == p.master.repl.id
+	})
+	if got, want := dumpStore(p.replica.store), dumpStore(p.master.store); !reflect.DeepEqual(got, want) {
+		p.t.Fatalf("replica dataset %v, master %v", got, want)
+	}
+}
+
+// kill closes the master side of the replica's current connection.
+func (p *replTestPair) kill() {
+	for _, c := range p.links {
+		c.close("killed by test")
+	}
+	p.master.flushPendingFDs()
+	p.waitFor("replica to notice the disconnect", func() bool {
+		return p.replica.repl.link.state != linkConnected
+	})
+}
+
+func (p *replTestPair) wantSyncs(full, partial int64) {
+	p.t.Helper()
+	if r := &p.master.repl; r.fullSyncs != full || r.partialSyncs != partial {
+		p.t.Fatalf("master served %d full and %d partial syncs, want %d and %d",
+			r.fullSyncs, r.partialSyncs, full, partial)
+	}
+}
+
+var errCut = errors.New("connection cut by test")
+
+// limitConn fails reads once its shared limit is used up, delivering only
+// the bytes within the limit.
+type limitConn struct {
+	net.Conn
+	limit *atomic.Int64
+}
+
+func (c *limitConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if left := c.limit.Load(); left >= 0 {
+		if int64(n) > left {
+			c.limit.Store(-1)
+			return int(left), errCut
+		}
+		c.limit.Add(-int64(n))
+	}
+	return n, err
+}
+
+// dumpStore renders every key canonically, so datasets can be compared
+// regardless of internal layout.
+func dumpStore(s *Store) map[string]string {
+	out := make(map[string]string, len(s.kv))
+	for k, v := range s.kv {
+		switch v := v.(type) {
+		case []byte:
+			out[k] = fmt.Sprintf("string:%q", v)
+		case *listValue:
+			out[k] = fmt.Sprintf("list:%q", v.elements(0, v.len()-1))
+		case setValue:
+			out[k] = fmt.Sprintf("set:%q", slices.Sorted(maps.Keys(v)))
+		case hashValue:
+			out[k] = fmt.Sprintf("hash:%q", v)
+		case *zsetValue:
+			out[k] = fmt.Sprintf("zset:%v", v.order)
+		}
+	}
+	return out
+}
+
+func TestReplicationFullSync(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	m := p.onMaster()
+	m.do("SET", "s", "v")
+	m.do("RPUSH", "l", "a", "b", "c")
+	m.do("SADD", "set", "x", "y")
+	m.do("HSET", "h", "f", "1")
+	m.do("ZADD", "z", "1.5", "m", "-2", "n")
+	p.replica.store.kv["stale"] = []byte("dropped by the full sync")
+
+	p.waitSynced()
+	p.wantSyncs(1, 0)
+
+	m.do("INCR", "counter")
+	m.do("LPOP", "l")
+	m.do("ZREM", "z", "n")
+	m.do("GET", "s")
+	m.wantError(errWrongType.Error(), "INCR", "l")
+	p.waitSynced()
+
+	got := m.do("ROLE")
+	if len(got.Array) != 3 || string(got.Array[0].Bulk) != "master" || got.Array[1].Int != p.master.repl.offset ||
+		len(got.Array[2].Array) != 1 {
+		t.Fatalf("master ROLE: %#v", got)
+	}
+	got = p.onReplica().do("ROLE")
+	if len(got.Array) != 5 || string(got.Array[0].Bulk) != "slave" || got.Array[2].Int != 6379 ||
+		string(got.Array[3].Bulk) != "connected" || got.Array[4].Int != p.master.repl.offset {
+		t.Fatalf("replica ROLE: %#v", got)
+	}
+}
+
+func TestReplicationPartialResync(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	m := p.onMaster()
+	m.do("SET", "k", "1")
+	p.waitSynced()
+
+	// Writes made while the replica is away are served from the backlog.
+	p.kill()
+	m.do("INCR", "k")
+	m.do("RPUSH", "l", "x", "y")
+	p.waitSynced()
+	p.wantSyncs(1, 1)
+
+	// Cut the connection in the middle of a command: the replica only
+	// counts complete commands and asks for the rest again.
+	p.readLimit.Store(10)
+	m.do("SET", "big", strings.Repeat("v", 100))
+	p.waitFor("the cut", func() bool { return p.replica.repl.link.state != linkConnected })
+	m.do("SADD", "after", "cut")
+	p.waitSynced()
+	p.wantSyncs(1, 2)
+}
+
+func TestReplicationBacklogOverflow(t *testing.T) {
+	p := newReplTestPair(t, 64)
+	m := p.onMaster()
+	m.do("SET", "k", "v")
+	p.waitSynced()
+
+	p.kill()
+	for i := range 10 {
+		m.do("SET", fmt.Sprint("key", i), "value")
+	}
+	p.waitSynced()
+	p.wantSyncs(2, 0)
+}
+
+func TestReplicationDeterministicWrites(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	m := p.onMaster()
+	m.do("SADD", "s", "a", "b", "c", "d", "e")
+	p.waitSynced()
+
+	// SPOP is propagated as SREM of the members it picked.
+	m.do("SPOP", "s")
+	m.do("SPOP", "s", "2")
+	p.waitSynced()
+
+	// A blocked pop reaches the replica when it is served.
+	waiter, waiterPeer := newSocketClient(t, p.master)
+	feed(t, waiter, "BLMPOP", "0", "1", "q", "LEFT")
+	expectNoReply(t, waiterPeer)
+	writer, writerPeer := newSocketClient(t, p.master)
+	feed(t, writer, "RPUSH", "q", "1", "2")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 2})
+	p.waitSynced()
+	p.onReplica().wantStrings(true, []string{"2"}, "LRANGE", "q", "0", "-1")
+}
+
+func TestReplicaPromotion(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	p.onMaster().do("SET", "k", "v")
+	p.waitSynced()
+
+	r := p.onReplica()
+	masterID := p.master.repl.id
+	r.do("REPLICAOF", "NO", "ONE")
+	if p.replica.repl.link != nil || p.replica.repl.id == masterID || p.replica.repl.id2 != masterID {
+		t.Fatalf("after promotion: id %s, id2 %s", p.replica.repl.id, p.replica.repl.id2)
+	}
+	// Replicas of the old master can continue against the promoted one.
+	if _, ok := p.replica.repl.partialResync(masterID, p.master.repl.offset+1); !ok {
+		t.Fatal("promoted replica refused the old master's history")
+	}
+	if got := r.do("ROLE"); string(got.Array[0].Bulk) != "master" || got.Array[1].Int != p.master.repl.offset {
+		t.Fatalf("ROLE after promotion: %#v", got)
+	}
+}
+
+func TestReadOnlyReplica(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	p.onMaster().do("SET", "k", "v")
+	p.waitSynced()
+
+	r := p.onReplica()
+	const readOnlyErr = "READONLY You can't write against a read only replica."
+	r.wantError(readOnlyErr, "SET", "k", "mine")
+	r.wantError(readOnlyErr, "BLMPOP", "0", "1", "q", "LEFT")
+	r.wantBulk("v", "GET", "k")
+
+	// READONLY and READWRITE only toggle the connection flag.
+	if got := r.do("READONLY"); got.Str != "OK" || !r.c.readonlyMode {
+		t.Fatalf("READONLY: %#v", got)
+	}
+	r.wantError(readOnlyErr, "DEL", "k")
+	if got := r.do("READWRITE"); got.Str != "OK" || r.c.readonlyMode {
+		t.Fatalf("READWRITE: %#v", got)
+	}
+
+	// Writes from the master still apply.
+	p.onMaster().do("SET", "k", "new")
+	p.waitSynced()
+
+	p.replica.replicaWritable = true
+	r.do("SET", "local", "1")
+	r.wantBulk("1", "GET", "local")
+	if p.replica.repl.offset != p.master.repl.offset {
+		t.Fatal("a local write on a writable replica changed its offset")
+	}
+
+	r.do("REPLICAOF", "NO", "ONE")
+	p.replica.replicaWritable = false
+	r.do("SET", "k", "promoted")
+	r.wantBulk("promoted", "GET", "k")
+}
+
+func TestFailover(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	m := p.onMaster()
+	for i := range 5 {
+		m.do("SET", fmt.Sprint("acked", i), "v")
+	}
+	p.waitSynced()
+
+	// This write is acknowledged before the replica has seen it.
+	writer, writerPeer := newSocketClient(t, p.master)
+	feed(t, writer, "INCR", "n")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 1})
+
+	if got := m.do("FAILOVER"); got.Str != "OK" {
+		t.Fatalf("FAILOVER: %#v", got)
+	}
+	m.wantError("ERR FAILOVER already in progress.", "FAILOVER")
+	m.wantError("ERR REPLICAOF not allowed while failing over.", "REPLICAOF", "NO", "ONE")
+
+	// Writes are paused; the read queued behind one waits with it.
+	feed(t, writer, "SET", "late", "x")
+	feed(t, writer, "GET", "n")
+	expectNoReply(t, writerPeer)
+
+	oldID := p.master.repl.id
+	p.waitFor("role switch", func() bool {
+		l := p.master.repl.link
+		return p.master.failover == nil && l != nil && l.state == linkConnected
+	})
+	p.step()
+	expectReplies(t, writerPeer,
+		redisproto.Value{Kind: redisproto.KindError, Str: "READONLY You can't write against a read only replica."},
+		redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte("1")})
+
+	if p.replica.repl.link != nil || p.replica.repl.id2 != oldID {
+		t.Fatalf("target not promoted: id2 %s, want %s", p.replica.repl.id2, oldID)
+	}
+	// The old master continued from the history it shares with the target.
+	if p.replica.repl.fullSyncs != 0 || p.replica.repl.partialSyncs != 1 {
+		t.Fatalf("new master served %d full and %d partial syncs",
+			p.replica.repl.fullSyncs, p.replica.repl.partialSyncs)
+	}
+
+	r := p.onReplica()
+	r.wantBulk("1", "GET", "n")
+	r.wantBulk("v", "GET", "acked4")
+	r.wantNull("GET", "late")
+	r.do("SET", "after", "failover")
+	p.waitFor("old master to follow", func() bool {
+		return p.master.repl.offset == p.replica.repl.offset
+	})
+	if got, want := dumpStore(p.master.store), dumpStore(p.replica.store); !reflect.DeepEqual(got, want) {
+		t.Fatalf("old master has %v, new master has %v", got, want)
+	}
+}
+
+func TestFailoverAbort(t *testing.T) {
+	p := newReplTestPair(t, 0)
+	p.onMaster().do("SET", "k", "v")
+	p.waitSynced()
+
+	m := p.onMaster()
+	m.wantError("ERR No failover in progress.", "FAILOVER", "ABORT")
+	m.wantError("ERR FAILOVER with force option requires both a timeout and target HOST and IP.", "FAILOVER", "FORCE")
+	m.wantError("ERR FAILOVER timeout must be greater than 0", "FAILOVER", "TIMEOUT", "0")
+	m.wantError("ERR FAILOVER target HOST and PORT is not a replica.", "FAILOVER", "TO", "10.0.0.1", "6379")
+	m.wantError("ERR syntax error", "FAILOVER", "NOW")
+	p.onReplica().wantError("ERR FAILOVER is not valid when server is a replica.", "FAILOVER")
+
+	// An aborted failover lets paused writes through.
+	writer, writerPeer := newSocketClient(t, p.master)
+	m.do("FAILOVER")
+	feed(t, writer, "SET", "k", "paused")
+	expectNoReply(t, writerPeer)
+	if got := m.do("FAILOVER", "ABORT"); got.Str != "OK" {
+		t.Fatalf("FAILOVER ABORT: %#v", got)
+	}
+	p.master.pollReplication(time.Now())
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"})
+	m.wantBulk("paused", "GET", "k")
+
+	// Without steps the replica never acknowledges, so the timeout aborts.
+	m.do("FAILOVER", "TIMEOUT", "10")
+	p.master.pollReplication(time.Now().Add(time.Second))
+	if p.master.failover != nil || p.master.repl.link != nil {
+		t.Fatal("failover did not abort after its timeout")
+	}
+	m.do("SET", "k", "again")
+	m.wantBulk("again", "GET", "k")
+
+	empty := newTestClient(t)
+	empty.wantError("ERR FAILOVER requires connected replicas.", "FAILOVER")
+}
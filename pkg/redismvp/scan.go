@@ -0,0 +1,270 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"cmp"
+	"hash/maphash"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultScanCount is the COUNT hint used when a SCAN-family command does
+// not specify one, as in Redis.
+const defaultScanCount = 10
+
+// SCAN cursors are positions in a fixed order of the element names: a
+// hash of the name, shifted so that no position is zero. Because the order
+// depends only on the names themselves, not on how the collection is
+// stored, the iteration gives the guarantees Redis documents however the
+// collection grows or shrinks between calls:
+//
+//   - An element present for the whole iteration is returned at least
+//     once: every page holds the elements from the cursor up to the next
+//     cursor, which is the position of the first element it left out.
+//   - No element is returned twice: the next cursor is beyond every
+//     position on the page, so cursors only grow and the iteration ends.
+//     A page never ends in the middle of a run of equal positions, or the
+//     rest of the run would be skipped.
+//   - Elements added or removed during the iteration may or may not be
+//     returned.
+//
+// A page is found in a scanIndex, the names of the collection sorted by
+// position, by seeking to the cursor. The index of an iteration is built
+// when it starts and kept by the store until it ends, so a full iteration
+// sorts the collection once. Names the collection lost since are skipped;
+// names it gained are missed, as the third guarantee allows, except by an
+// iteration that has to build the index again.
+
+// scanSeed seeds the hash of scan positions. It is fixed for the process,
+// so cursors hold across calls, though not across restarts.
+var scanSeed = maphash.MakeSeed()
+
+func scanPosition(name string) uint64 {
+	return maphash.String(scanSeed, name)>>1 + 1
+}
+
+type scanEntry struct {
+	pos  uint64
+	name string
+}
+
+func compareScanEntries(a, b scanEntry) int {
+	if c := cmp.Compare(a.pos, b.pos); c != 0 {
+		return c
+	}
+	return strings.Compare(a.name, b.name)
+}
+
+// scanIndex holds the names of a collection in scan order.
+type scanIndex struct {
+	entries []scanEntry
+}
+
+func newScanIndex(names iter.Seq[string]) *scanIndex {
+	var entries []scanEntry
+	for name := range names {
+		entries = append(entries, scanEntry{scanPosition(name), name})
+	}
+	slices.SortFunc(entries, compareScanEntries)
+	return &scanIndex{entries: entries}
+}
+
+// page returns up to count names at or after cursor in scan order for
+// which has reports true, and the cursor of the following page (0 once
+// the iteration is complete). The page may hold more than count names when
+// several share the position of the last one.
+func (ix *scanIndex) page(cursor uint64, count int, has func(string) bool) ([]string, uint64) {
+	i, _ := slices.BinarySearchFunc(ix.entries, cursor, func(e scanEntry, pos uint64) int {
+		return cmp.Compare(e.pos, pos)
+	})
+	var page []string
+	for ; i < len(ix.entries); i++ {
+		e := ix.entries[i]
+		if len(page) >= count && e.pos != ix.entries[i-1].pos {
+			return page, e.pos
+		}
+		if has(e.name) {
+			page = append(page, e.name)
+		}
+	}
+	return page, 0
+}
+
+// scanKey identifies the collection an iteration runs over: the elements
+// of the key, or the keyspace itself.
+type scanKey struct {
+	key      string
+	keyspace bool
+}
+
+// maxScanIndexes bounds the indexes the store keeps for iterations in
+// progress. An iteration whose index was dropped builds it again.
+const maxScanIndexes = 64
+
+// scanPage returns a page of the collection identified by key, whose names
+// are names and whose membership has reports, and keeps its index for the
+// next page. The store's lock must be held.
+func (s *Store) scanPage(key scanKey, names iter.Seq[string], has func(string) bool, cursor uint64, count int) ([]string, uint64) {
+	ix := s.scans[key]
+	if ix == nil || cursor == 0 {
+		ix = newScanIndex(names)
+		if s.scans == nil {
+			s.scans = make(map[scanKey]*scanIndex)
+		}
+		if _, ok := s.scans[key]; !ok && len(s.scans) >= maxScanIndexes {
+			for k := range s.scans {
+				delete(s.scans, k)
+				break
+			}
+		}
+		s.scans[key] = ix
+	}
+	page, next := ix.page(cursor, count, has)
+	if next == 0 {
+		delete(s.scans, key)
+	}
+	return page, next
+}
+
+// scanOptions holds the parsed arguments shared by SCAN, SSCAN and HSCAN.
+type scanOptions struct {
+	cursor   uint64
+	count    int
+	pattern  []byte
+	typeName string
+	noValues bool
+}
+
+// parseScanArgs parses "cursor [MATCH pattern] [COUNT count]" plus the
+// extra keywords listed in extra (TYPE or NOVALUES). On failure it returns
+// the error reply to send.
+func parseScanArgs(args [][]byte, extra ...string) (scanOptions, string) {
+	opts := scanOptions{count: defaultScanCount}
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		return opts, "ERR invalid cursor"
+	}
+	opts.cursor = cursor
+
+	for i := 1; i < len(args); i++ {
+		switch {
+		case argIs(args[i], "MATCH") && i+1 < len(args):
+			i++
+			opts.pattern = args[i]
+		case argIs(args[i], "COUNT") && i+1 < len(args):
+			i++
+			n, ok := parseInt(args[i])
+			if !ok {
+				return opts, "ERR value is not an integer or out of range"
+			}
+			if n < 1 {
+				return opts, "ERR syntax error"
+			}
+			opts.count = int(min(n, int64(1<<31-1)))
+		case argIs(args[i], "TYPE") && i+1 < len(args) && slices.Contains(extra, "TYPE"):
+			i++
+			opts.typeName = string(args[i])
+		case argIs(args[i], "NOVALUES") && slices.Contains(extra, "NOVALUES"):
+			opts.noValues = true
+		default:
+			return opts, "ERR syntax error"
+		}
+	}
+	return opts, ""
+}
+
+func (o scanOptions) match(name string) bool {
+	return o.pattern == nil || globMatch(o.pattern, []byte(name))
+}
+
+// appendScanReply appends the two-element [cursor, items] SCAN reply.
+func appendScanReply(dst []byte, next uint64, items []string) []byte {
+	dst = appendArrayLen(dst, 2)
+	dst = appendBulkString(dst, strconv.FormatUint(next, 10))
+	return appendStringArray(dst, items)
+}
+
+// globMatch reports whether s matches the Redis glob pattern, which
+// supports *, ?, [abc], [^abc], [a-z] and backslash escapes.
+func globMatch(pattern, s []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var ok bool
+			ok, pattern = matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			s = s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class that starts right after
+// '[' and returns the pattern remaining after the closing ']'.
+func matchClass(pattern []byte, c byte) (bool, []byte) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return matched != negate, pattern
+}
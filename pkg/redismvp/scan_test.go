@@ -0,0 +1,322 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "session:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+	for _, tc := range cases {
+		if got := globMatch([]byte(tc.pattern), []byte(tc.s)); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+// TestScanPageSurvivesMutation checks the SCAN guarantee: every element
+// present for the whole iteration is returned at least once, even when
+// other elements are added and removed between pages.
+func TestScanPageSurvivesMutation(t *testing.T) {
+	set := map[string]struct{}{}
+	for i := 0; i < 500; i++ {
+		set["stable:"+strconv.Itoa(i)] = struct{}{}
+	}
+	has := func(name string) bool {
+		_, ok := set[name]
+		return ok
+	}
+
+	var s Store
+	seen := map[string]bool{}
+	cursor, round := uint64(0), 0
+	for {
+		page, next := s.scanPage(scanKey{key: "set"}, maps.Keys(set), has, cursor, 7)
+		if round%10 == 9 {
+			// An iteration whose index was dropped builds it again.
+			clear(s.scans)
+		}
+		for _, name := range page {
+			seen[name] = true
+		}
+		set["churn:"+strconv.Itoa(round)] = struct{}{}
+		delete(set, "churn:"+strconv.Itoa(round-3))
+		round++
+		if next == 0 {
+			break
+		}
+		if next <= cursor {
+			t.Fatalf("cursor did not advance: %d -> %d", cursor, next)
+		}
+		cursor = next
+	}
+
+	for i := 0; i < 500; i++ {
+		if name := "stable:" + strconv.Itoa(i); !seen[name] {
+			t.Fatalf("%s was never returned", name)
+		}
+	}
+	if len(s.scans) != 0 {
+		t.Fatalf("%d indexes kept after the iteration ended", len(s.scans))
+	}
+}
+
+// sortedScanPage is scanIndex.page done the obvious way, by sorting every
+// candidate.
+func sortedScanPage(names []string, cursor uint64, count int) ([]string, uint64) {
+	var candidates []scanEntry
+	for _, name := range names {
+		if pos := scanPosition(name); pos >= cursor {
+			candidates = append(candidates, scanEntry{pos, name})
+		}
+	}
+	slices.SortFunc(candidates, compareScanEntries)
+	end := min(count, len(candidates))
+	for end > 0 && end < len(candidates) && candidates[end].pos == candidates[end-1].pos {
+		end++
+	}
+	var page []string
+	for _, e := range candidates[:end] {
+		page = append(page, e.name)
+	}
+	if end == len(candidates) {
+		return page, 0
+	}
+	return page, candidates[end].pos
+}
+
+func TestScanPageMatchesSortedOrder(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		set := map[string]struct{}{}
+		for range rng.IntN(300) {
+			set[strconv.Itoa(rng.IntN(1000))] = struct{}{}
+		}
+		names := slices.Collect(maps.Keys(set))
+		cursor := uint64(0)
+		if rng.IntN(2) == 0 {
+			cursor = rng.Uint64() >> 1
+		}
+		count := 1 + rng.IntN(40)
+		gotPage, gotNext := newScanIndex(maps.Keys(set)).page(cursor, count, func(string) bool { return true })
+		wantPage, wantNext := sortedScanPage(names, cursor, count)
+		if !slices.Equal(gotPage, wantPage) || gotNext != wantNext {
+			t.Fatalf("page(cursor %d, count %d) = %v, %d; want %v, %d",
+				cursor, count, gotPage, gotNext, wantPage, wantNext)
+		}
+	}
+}
+
+// TestScanUnderRandomMutation runs every SCAN-family command to completion
+// while the collection grows and shrinks at random between calls, and
+// checks the documented guarantees: every element present throughout is
+// returned, none twice, only elements that existed, and the cursor only
+// grows.
+func TestScanUnderRandomMutation(t *testing.T) {
+	type scanned struct {
+		cmd    string
+		add    func(tc *testClient, name string)
+		remove func(tc *testClient, name string)
+	}
+	commands := []scanned{
+		{"SCAN", func(tc *testClient, name string) { tc.do("SET", name, "v") },
+			func(tc *testClient, name string) { tc.do("DEL", name) }},
+		{"SSCAN", func(tc *testClient, name string) { tc.do("SADD", "coll", name) },
+			func(tc *testClient, name string) { tc.do("SREM", "coll", name) }},
+		{"HSCAN", func(tc *testClient, name string) { tc.do("HSET", "coll", name, "v") },
+			func(tc *testClient, name string) { tc.do("HDEL", "coll", name) }},
+		{"ZSCAN", func(tc *testClient, name string) { tc.do("ZADD", "coll", "1", name) },
+			func(tc *testClient, name string) { tc.do("ZREM", "coll", name) }},
+	}
+	for _, sc := range commands {
+		t.Run(sc.cmd, func(t *testing.T) {
+			for seed := range uint64(5) {
+				rng := rand.New(rand.NewPCG(seed, 7))
+				tc := newTestClient(t)
+				stable := map[string]bool{}
+				for i := range 300 {
+					name := fmt.Sprint("stable:", i)
+					stable[name] = true
+					sc.add(tc, name)
+				}
+				// ever holds every element that existed at some point.
+				ever := maps.Clone(stable)
+				var churn []string
+				nextChurn := 0
+
+				seen := map[string]bool{}
+				cursor := uint64(0)
+				for {
+					args := []string{sc.cmd}
+					if sc.cmd != "SCAN" {
+						args = append(args, "coll")
+					}
+					args = append(args, strconv.FormatUint(cursor, 10), "COUNT", strconv.Itoa(1+rng.IntN(30)))
+					reply := tc.do(args...)
+					step := 1
+					if sc.cmd == "HSCAN" || sc.cmd == "ZSCAN" {
+						step = 2
+					}
+					items := reply.Array[1].Array
+					for i := 0; i < len(items); i += step {
+						name := string(items[i].Bulk)
+						if seen[name] {
+							t.Fatalf("seed %d: %s returned twice", seed, name)
+						}
+						if !ever[name] {
+							t.Fatalf("seed %d: %s returned but never existed", seed, name)
+						}
+						seen[name] = true
+					}
+					next, err := strconv.ParseUint(string(reply.Array[0].Bulk), 10, 64)
+					if err != nil {
+						t.Fatalf("seed %d: cursor %q", seed, reply.Array[0].Bulk)
+					}
+					if next == 0 {
+						break
+					}
+					if next <= cursor {
+						t.Fatalf("seed %d: cursor went from %d to %d", seed, cursor, next)
+					}
+					cursor = next
+
+					// Grow or shrink the collection by up to a few hundred
+					// elements, so the map resizes along the way.
+					switch n := rng.IntN(200); rng.IntN(3) {
+					case 0:
+						for range n {
+							name := fmt.Sprint("churn:", nextChurn)
+							nextChurn++
+							churn = append(churn, name)
+							ever[name] = true
+							sc.add(tc, name)
+						}
+					case 1:
+						rng.Shuffle(len(churn), func(i, j int) { churn[i], churn[j] = churn[j], churn[i] })
+						n = min(n, len(churn))
+						for _, name := range churn[:n] {
+							sc.remove(tc, name)
+						}
+						churn = churn[n:]
+					}
+				}
+
+				for name := range stable {
+					if !seen[name] {
+						t.Fatalf("seed %d: %s was present throughout but never returned", seed, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScanCommands(t *testing.T) {
+	tc := newTestClient(t)
+
+	for i := 0; i < 30; i++ {
+		tc.do("SET", "str:"+strconv.Itoa(i), "v")
+	}
+	tc.do("SADD", "set:1", "a", "b")
+	tc.do("HSET", "hash:1", "f", "v")
+
+	keys := scanAll(tc, "SCAN", "MATCH", "str:*", "COUNT", "4")
+	if len(keys) != 30 {
+		t.Fatalf("SCAN MATCH str:* returned %d keys", len(keys))
+	}
+	keys = scanAll(tc, "SCAN", "TYPE", "hash")
+	if len(keys) != 1 || !keys["hash:1"] {
+		t.Fatalf("SCAN TYPE hash returned %v", keys)
+	}
+
+	members := scanAll(tc, "SSCAN", "set:1")
+	if len(members) != 2 || !members["a"] || !members["b"] {
+		t.Fatalf("SSCAN returned %v", members)
+	}
+
+	tc.wantError("ERR invalid cursor", "SCAN", "abc")
+	tc.wantError("ERR syntax error", "SCAN", "0", "COUNT", "0")
+	tc.wantError("ERR syntax error", "SSCAN", "set:1", "0", "TYPE", "set")
+	tc.wantError(errWrongType.Error(), "SSCAN", "hash:1", "0")
+}
+
+// scanAll runs a SCAN-family command to completion and returns the set of
+// items returned. The cursor is inserted after the command's key, if any.
+func scanAll(tc *testClient, args ...string) map[string]bool {
+	tc.t.Helper()
+	at := 1
+	if args[0] != "SCAN" {
+		at = 2
+	}
+	items := map[string]bool{}
+	cursor := "0"
+	for {
+		cmd := append(append(append([]string{}, args[:at]...), cursor), args[at:]...)
+		reply := tc.do(cmd...)
+		if len(reply.Array) != 2 {
+			tc.t.Fatalf("%v: unexpected reply %#v", cmd, reply)
+		}
+		for _, v := range reply.Array[1].Array {
+			items[string(v.Bulk)] = true
+		}
+		cursor = string(reply.Array[0].Bulk)
+		if cursor == "0" {
+			return items
+		}
+	}
+}
+
+// BenchmarkScanAll runs SSCAN over a large set to completion. With the
+// index kept between pages, a full iteration costs a sort of the set, not
+// a pass over it for every page.
+func BenchmarkScanAll(b *testing.B) {
+	set := map[string]struct{}{}
+	for i := range 100000 {
+		set["member:"+strconv.Itoa(i)] = struct{}{}
+	}
+	has := func(name string) bool {
+		_, ok := set[name]
+		return ok
+	}
+	var s Store
+	b.ReportAllocs()
+	for range b.N {
+		cursor := uint64(0)
+		for {
+			_, next := s.scanPage(scanKey{key: "set"}, maps.Keys(set), has, cursor, 10)
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+}
@@ -6,9 +6,12 @@
 package redismvp
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,6 +29,56 @@ type Server struct {
 	listener *xev.TCPListener
 	store    *Store
 	host     string
+	log      *slog.Logger
+	slowLog  time.Duration
+	clientID atomic.Uint64
+	reaper   *xev.IdleReaper[*clientConn]
+	lazyFree *lazyFreer
+	repl     replState
+	// replicaWritable disables rejecting client writes while a replica.
+	replicaWritable bool
+	// replDial, if set, replaces the dialer used to connect to a master.
+	replDial func(network, addr string) (net.Conn, error)
+	failover *failoverState
+	// paused holds clients whose write commands wait for a failover.
+	paused map[*clientConn]struct{}
+	// aof is set when append only file persistence is enabled.
+	aof *aofState
+	// dbFilename is the RDB file SAVE and BGSAVE write.
+	dbFilename string
+	// lastSave is when the dataset was last saved or loaded, or the server
+	// started.
+	lastSave        time.Time
+	bgsaveScheduled bool
+	maxClients      int
+	databases       int
+	protectedMode   bool
+	// keepAlive and noDelay are applied to clients accepted over TCP.
+	keepAlive net.KeepAliveConfig
+	noDelay   bool
+	// evict is set when maxmemory or an access-tracking policy is.
+	evict *evictor
+	// extensions enables the commands of extensionTable.
+	extensions bool
+	// debugKeyspace enables DEBUG KEYSPACE.
+	debugKeyspace bool
+	// renames applies Config.RenameCommands to command lookups.
+	renames commandRenames
+	// faults, set only by tests, injects delays and disconnects.
+	faults faultInjector
+	// diskFaults, set only by tests, crashes the server while it persists.
+	diskFaults diskFaultInjector
+	// pollFailing is set while running the loop fails, so a failure is
+	// logged once rather than on every turn.
+	pollFailing bool
+	// stopping is set on the loop goroutine once shutdown has begun.
+	stopping bool
+
+	// Blocking command state, only touched from the loop goroutine.
+	blockedOn      map[string][]*clientConn
+	blockedClients map[*clientConn]struct{}
+	readyKeys      []string
+	servingReady   bool
 
 	clientsMu sync.Mutex
 	clients   map[*clientConn]struct{}
@@ -37,20 +90,62 @@ type Server struct {
 	stopped    atomic.Bool
 }
 
-// Start creates and runs a server bound to addr.
+// Start creates and runs a server bound to addr with default settings.
 // Use 127.0.0.1:0 to allocate an ephemeral port.
 func Start(addr string) (*Server, error) {
-	loop, err := xev.NewLoop()
+	return StartConfig(Config{Addr: addr})
+}
+
+// StartConfig creates and runs a server using cfg.
+func StartConfig(cfg Config) (*Server, error) {
+	if cfg.MaxClients < 0 {
+		return nil, fmt.Errorf("invalid maxclients %d", cfg.MaxClients)
+	}
+	if cfg.TCPKeepAlive < 0 {
+		return nil, fmt.Errorf("invalid tcp-keepalive %s", cfg.TCPKeepAlive)
+	}
+	if cfg.MaxMemory < 0 {
+		return nil, fmt.Errorf("invalid maxmemory %d", cfg.MaxMemory)
+	}
+	if cfg.MaxMemorySamples < 0 || cfg.LFULogFactor < 0 {
+		return nil, fmt.Errorf("invalid maxmemory-samples %d or lfu-log-factor %d", cfg.MaxMemorySamples, cfg.LFULogFactor)
+	}
+	if cfg.Databases < 0 || cfg.Databases > 1 {
+		return nil, fmt.Errorf("databases %d is not supported: the server has a single keyspace", cfg.Databases)
+	}
+	renames, err := newCommandRenames(cfg.RenameCommands, cfg.ExtensionCommands)
 	if err != nil {
 		return nil, err
 	}
-
-	listener, err := xev.Listen("tcp", addr)
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create dir: %w", err)
+		}
+	}
+	// Other goroutines, such as DialPipe callers, the snapshot writer and
+	// the master link reader, hand work to the loop with Post, which also
+	// wakes it.
+	loop, err := xev.NewLoop(xev.WithPost())
 	if err != nil {
-		loop.Close()
 		return nil, err
 	}
 
+	// Running out of fds must not spin the loop on a failing accept: drop
+	// the pending connection via the reserve fd and back off. The first
+	// command of a client is read with its accept.
+	var listener *xev.TCPListener
+	if !cfg.Pipe {
+		listener, err = xev.Listen("tcp", cfg.Addr,
+			xev.WithReserveFD(),
+			xev.WithAcceptBackoff(10*time.Millisecond, time.Second),
+			xev.WithEarlyData(4096),
+		)
+		if err != nil {
+			loop.Close()
+			return nil, err
+		}
+	}
+
 	s := &Server{
 		loop:     loop,
 		listener: listener,
@@ -58,19 +153,95 @@ func Start(addr string) (*Server, error) {
 		clients:  make(map[*clientConn]struct{}),
 		stopCh:   make(chan struct{}),
 		doneCh:   make(chan struct{}),
-		host:     parseHost(addr),
+		host:     parseHost(cfg.Addr),
+		log:      cfg.logger(),
+		slowLog:  cfg.slowLogThreshold(),
+		repl:     replState{backlogSize: cfg.ReplBacklogSize, secondOffset: -1},
+
+		replicaWritable: cfg.ReplicaWritable,
+		dbFilename:      cfg.dbFilename(),
+		lastSave:        time.Now(),
+		maxClients:      cfg.maxClients(),
+		databases:       cfg.Databases,
+		protectedMode:   cfg.ProtectedMode,
+		keepAlive:       cfg.keepAlive(),
+		noDelay:         cfg.TCPNoDelay,
+		evict:           newEvictor(cfg),
+		extensions:      cfg.ExtensionCommands,
+		debugKeyspace:   cfg.DebugKeyspace,
+		renames:         renames,
+		faults:          cfg.faults,
+	}
+	s.store.keyCreated = s.keyCreated
+	s.lazyFree = newLazyFreer()
+	load := func() error { return s.loadRDBFile(s.dbFilename) }
+	if cfg.AppendOnly {
+		load = func() error { return s.openAOF(cfg) }
+	}
+	if err := load(); err != nil {
+		s.lazyFree.close()
+		s.closeListener()
+		loop.Close()
+		return nil, err
+	}
+	if s.evict != nil {
+		s.evict.recount(s.store.kv)
+	}
+	if cfg.ReplicaOf != "" {
+		host, port, err := parseReplicaOf(cfg.ReplicaOf)
+		if err != nil {
+			s.closeAOF()
+			s.lazyFree.close()
+			s.closeListener()
+			loop.Close()
+			return nil, err
+		}
+		s.replicaOf(host, port)
 	}
 
-	if err := s.listener.AcceptFunc(s.loop, s.onAccept); err != nil {
-		s.listener.Close()
+	if cfg.Timeout > 0 {
+		s.reaper = xev.NewIdleReaper(loop, cfg.Timeout, (*clientConn).expire)
+		if err := s.reaper.Start(); err != nil {
+			s.closeAOF()
+			s.lazyFree.close()
+			s.closeListener()
+			s.loop.Close()
+			return nil, err
+		}
+	}
+
+	if s.listener != nil {
+		if err := s.listener.AcceptFunc(s.loop, s.onAccept); err != nil {
+			s.stopReaper()
+			s.closeAOF()
+			s.lazyFree.close()
+			s.closeListener()
+			s.loop.Close()
+			return nil, err
+		}
+	}
+	if _, err := s.loop.Schedule(cronInterval, s.cron); err != nil {
+		s.stopReaper()
+		s.closeAOF()
+		s.lazyFree.close()
+		s.closeListener()
 		s.loop.Close()
 		return nil, err
 	}
 
+	s.log.Info("server started", "addr", s.Addr())
 	go s.run()
 	return s, nil
 }
 
+// cronInterval bounds how long the loop sleeps without an event, and so
+// the resolution of the work driven by time rather than by events:
+// blocking command timeouts, fsyncs under everysec, replication acks and
+// reconnects, and failover deadlines.
+const cronInterval = 10 * time.Millisecond
+
+// run blocks in the loop until an event, a posted function or the cron
+// timer wakes it, then does the housekeeping of a turn.
 func (s *Server) run() {
 	defer close(s.doneCh)
 
@@ -82,14 +253,63 @@ func (s *Server) run() {
 		default:
 		}
 
-		_ = s.loop.Poll()
+		s.turn(s.loop.RunOnce)
+		now := time.Now()
+		if len(s.blockedClients) > 0 {
+			s.expireBlocked(now)
+		}
+		s.pollReplication(now)
+		s.pollSnapshot()
+		s.pollAOF(now)
+		if len(s.blockedClients) > 0 {
+			s.pollWaitAOF()
+		}
 		s.flushPendingFDs()
-		time.Sleep(50 * time.Microsecond)
 	}
 }
 
+// cron runs on the loop every cronInterval. It does nothing itself: the
+// housekeeping follows every turn of the loop, and the timer only makes
+// sure there is one.
+func (s *Server) cron() xev.Action {
+	return xev.Continue
+}
+
+// wake makes the loop turn soon, from any goroutine. It fails quietly once
+// the loop is shutting down, and does nothing for a server built without
+// one, as in tests.
+func (s *Server) wake() {
+	if s.loop != nil {
+		_ = s.loop.Post(func() {})
+	}
+}
+
+// turn runs the loop once with run, logging when it starts and stops
+// failing.
+func (s *Server) turn(run func() error) {
+	err := run()
+	switch {
+	case err != nil && !s.pollFailing:
+		s.log.Error("event loop poll failed", "err", err)
+	case err == nil && s.pollFailing:
+		s.log.Warn("event loop poll recovered")
+	}
+	s.pollFailing = err != nil
+}
+
 func (s *Server) shutdownInLoop() {
-	s.listener.Close()
+	s.stopping = true
+	// Functions posted from now on are refused; those already posted run
+	// during the polls below, which also complete the close, releasing the
+	// port.
+	_ = s.loop.ClosePost()
+	if s.listener != nil {
+		_ = s.listener.Close(s.loop, nil)
+	}
+	s.stopReaper()
+	if l := s.repl.link; l != nil {
+		l.close()
+	}
 
 	s.clientsMu.Lock()
 	clients := make([]*clientConn, 0, len(s.clients))
@@ -103,60 +323,227 @@ func (s *Server) shutdownInLoop() {
 	}
 
 	for i := 0; i < 32; i++ {
-		_ = s.loop.Poll()
+		s.turn(s.loop.Poll)
 		s.flushPendingFDs()
 	}
 	for _, c := range clients {
-		_ = syscall.Close(int(c.conn.Fd()))
+		_ = syscall.Close(int(c.fd))
 	}
 	s.flushPendingFDs()
 	s.loop.Close()
+	s.store.mu.Lock()
+	if err := s.waitSnapshot(); err != nil {
+		s.log.Warn("background snapshot failed at shutdown", "err", err)
+	}
+	s.store.mu.Unlock()
+	s.closeAOF()
+	s.lazyFree.close()
+	s.log.Info("server stopped", "clients_closed", len(clients))
 }
 
-func (s *Server) onAccept(_ *xev.TCPListener, conn *xev.TCPConn, err error) xev.Action {
+func (s *Server) onAccept(l *xev.TCPListener, conn *xev.TCPConn, err error) xev.Action {
 	if err != nil {
+		s.log.Warn("accept failed", "err", err)
+		return xev.Continue
+	}
+	peer := peerAddr(conn.Fd())
+	// Connections from DialPipe are Unix sockets, without TCP options.
+	if l != nil {
+		s.setSocketOptions(conn, peer)
+	}
+	if reason := s.refuse(peer); reason != "" {
+		s.log.Warn("connection refused", "peer", peer, "reason", reason)
+		_ = writeAll(conn.Fd(), appendError(nil, reason))
+		s.enqueueFD(conn.Fd())
 		return xev.Continue
 	}
 
+	id := s.clientID.Add(1)
 	client := &clientConn{
 		server: s,
+		id:     id,
 		conn:   conn,
-		parser: redisproto.NewParser(),
+		fd:     conn.Fd(),
+		codec:  redisproto.NewCodec(),
 		read:   make([]byte, 4096),
+		log:    s.log.With("client_id", id, "peer", peer),
 	}
+	client.log.Log(context.Background(), LevelVerbose, "client connected")
+	client.touch()
 
 	s.clientsMu.Lock()
 	s.clients[client] = struct{}{}
 	s.clientsMu.Unlock()
 
+	if early := conn.EarlyData(); early != nil && client.onRead(conn, early, nil) != xev.Continue {
+		return xev.Continue
+	}
 	if readErr := conn.ReadFunc(s.loop, client.read, client.onRead); readErr != nil {
-		client.close()
+		client.log.Warn("start read failed", "err", readErr)
+		client.close("read setup failed")
 	}
 	return xev.Continue
 }
 
-// Addr returns listener address host:port.
+// setSocketOptions applies the configured TCP options to an accepted
+// connection. A failure is logged and the connection kept, as in Redis.
+func (s *Server) setSocketOptions(conn *xev.TCPConn, peer string) {
+	if s.noDelay {
+		if err := conn.SetNoDelay(true); err != nil {
+			s.log.Warn("failed to set TCP_NODELAY", "peer", peer, "err", err)
+		}
+	}
+	if s.keepAlive.Enable {
+		if err := conn.SetKeepAliveConfig(s.keepAlive); err != nil {
+			s.log.Warn("failed to set TCP keepalive", "peer", peer, "err", err)
+		}
+	}
+}
+
+// protectedModeError is sent to remote clients refused by protected mode.
+const protectedModeError = "DENIED Running in protected mode because protected mode is enabled and no " +
+	"password is set. In this mode connections are only accepted from the loopback interface. " +
+	"Disable protected mode to accept connections from other hosts."
+
+// refuse returns the error a new connection from peer is refused with, or
+// "" to accept it.
+func (s *Server) refuse(peer string) string {
+	if s.protectedMode && !isLoopback(peer) {
+		return protectedModeError
+	}
+	s.clientsMu.Lock()
+	n := len(s.clients)
+	s.clientsMu.Unlock()
+	if n >= s.maxClients {
+		return "ERR max number of clients reached"
+	}
+	return ""
+}
+
+// isLoopback reports whether peer, a host:port address, is on the loopback
+// interface. Peers without an IP address are local.
+func isLoopback(peer string) bool {
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || ip.IsLoopback()
+}
+
+// Addr returns listener address host:port, or "pipe" for a server started
+// with Config.Pipe.
 func (s *Server) Addr() string {
+	if s.listener == nil {
+		return "pipe"
+	}
 	_, port := s.listener.Addr()
 	return fmt.Sprintf("%s:%d", s.host, port)
 }
 
+// closeListener closes the listener of a server that failed to start.
+func (s *Server) closeListener() {
+	if s.listener != nil {
+		_ = s.listener.CloseNow()
+	}
+}
+
+// DialPipe connects a client to the server through an in-process socket
+// pair instead of the network. The server takes the connection like one
+// from its listener, so the client is served by the same code as a TCP
+// client; it is treated as coming from the loopback interface. DialPipe
+// works whether or not the server listens on a port.
+func (s *Server) DialPipe() (net.Conn, error) {
+	conn, peer, err := xev.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loop.Post(func() { s.acceptPipe(conn) }); err != nil {
+		_ = syscall.Close(int(conn.Fd()))
+		_ = peer.Close()
+		return nil, net.ErrClosed
+	}
+	return peer, nil
+}
+
+// acceptPipe takes a connection handed over by DialPipe, or closes it if
+// the server is shutting down.
+func (s *Server) acceptPipe(conn *xev.TCPConn) {
+	if s.stopping {
+		_ = syscall.Close(int(conn.Fd()))
+		return
+	}
+	s.onAccept(nil, conn, nil)
+}
+
 // Close shuts down the server.
 func (s *Server) Close() error {
 	if !s.stopped.CompareAndSwap(false, true) {
 		return nil
 	}
 	close(s.stopCh)
+	s.wake()
 	<-s.doneCh
 	return nil
 }
 
 type clientConn struct {
 	server *Server
+	id     uint64
 	conn   *xev.TCPConn
-	parser *redisproto.Parser
+	fd     int32
+	// codec parses the client's frames and holds the protocol version
+	// negotiated with HELLO.
+	codec  *redisproto.Codec
 	read   []byte
+	log    *slog.Logger
 	closed bool
+	// closeReason overrides the reason logged when the read loop closes the
+	// connection, for closes initiated by the server.
+	closeReason string
+	// quit is set by QUIT: the connection is closed after its reply.
+	quit bool
+	// replies batches the replies to pipelined commands. It is created
+	// by replyWriter on first use.
+	replies *redisproto.ReplyWriter
+
+	// blocked is set while a blocking command waits; frames received in
+	// the meantime are queued in pending.
+	blocked *blockedState
+	pending []redisproto.Value
+
+	// replica is set on connections of replicas of this server.
+	replica *replicaInfo
+	// propagateArgs, if set by a write command, is propagated to replicas
+	// instead of the command itself.
+	propagateArgs [][]byte
+	// woff is the replication offset right after the client's last write,
+	// which WAITAOF waits to be fsynced.
+	woff int64
+	// readonlyMode is set by READONLY, with which cluster clients declare
+	// they accept possibly stale reads from a replica.
+	readonlyMode bool
+	// replayed is set on the clients that replay commands from a master
+	// or the AOF, which never evict keys.
+	replayed bool
+}
+
+// touch records activity for the idle reaper. Replicas are never
+// disconnected for being idle.
+func (c *clientConn) touch() {
+	if r := c.server.reaper; r != nil && c.replica == nil {
+		r.Touch(c)
+	}
+}
+
+// expire disconnects an idle client. Shutting the socket down completes the
+// pending read with EOF, so the normal close path releases the fd.
+func (c *clientConn) expire() {
+	if c.closed {
+		return
+	}
+	c.closeReason = "idle timeout"
+	_ = syscall.Shutdown(int(c.fd), syscall.SHUT_RDWR)
 }
 
 func (c *clientConn) onRead(_ *xev.TCPConn, data []byte, err error) xev.Action {
@@ -164,153 +551,138 @@ func (c *clientConn) onRead(_ *xev.TCPConn, data []byte, err error) xev.Action {
 		return xev.Stop
 	}
 	if err != nil {
-		c.close()
+		c.close("read error: " + err.Error())
 		return xev.Stop
 	}
 	if len(data) == 0 {
-		c.close()
+		c.close("peer closed")
 		return xev.Stop
 	}
 
-	frames, parseErr := c.parser.Feed(data)
+	c.touch()
+	frames, parseErr := c.codec.Feed(data)
 	if parseErr != nil {
-		return c.writeSyncResponse(redisError("ERR Protocol error: " + parseErr.Error()))
+		return c.protocolError(parseErr)
 	}
 
 	if len(frames) == 0 {
 		return xev.Continue
 	}
-
-	wire := make([]byte, 0, 128)
-	for _, frame := range frames {
-		wire = c.appendResponse(wire, frame)
+	if c.blocked != nil {
+		c.pending = append(c.pending, frames...)
+		return xev.Continue
 	}
-	if writeErr := writeAll(c.conn.Fd(), wire); writeErr != nil {
-		c.close()
+	if !c.process(nil, frames) {
 		return xev.Stop
 	}
 	return xev.Continue
 }
 
-func (c *clientConn) appendResponse(dst []byte, frame redisproto.Value) []byte {
-	if frame.Kind != redisproto.KindArray {
-		return appendError(dst, "ERR Protocol error: command must be array")
-	}
-	if len(frame.Array) == 0 {
-		return appendError(dst, "ERR Protocol error: empty command")
-	}
-
-	command, ok := tokenBytes(frame.Array[0])
-	if !ok {
-		return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[0].Kind))
+// process sends reply, if any, then executes frames and sends their
+// replies, batched by the client's reply writer. If a command blocks, the
+// remaining frames are queued until it is served; after QUIT they are
+// dropped and the client is closed. It returns false if the client was
+// closed.
+func (c *clientConn) process(reply []byte, frames []redisproto.Value) bool {
+	w := c.replyWriter()
+	if w.Append(reply) != nil {
+		return false
 	}
-
-	switch {
-	case commandIs(command, "PING"):
-		if len(frame.Array) == 1 {
-			return appendSimple(dst, "PONG")
-		}
-		if len(frame.Array) == 2 {
-			arg, ok := tokenBytes(frame.Array[1])
-			if !ok {
-				return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[1].Kind))
-			}
-			return appendBulk(dst, arg)
-		}
-		return appendWrongArity(dst, "ping")
-	case commandIs(command, "ECHO"):
-		if len(frame.Array) != 2 {
-			return appendWrongArity(dst, "echo")
-		}
-		arg, ok := tokenBytes(frame.Array[1])
-		if !ok {
-			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[1].Kind))
-		}
-		return appendBulk(dst, arg)
-	case commandIs(command, "SET"):
-		if len(frame.Array) != 3 {
-			return appendWrongArity(dst, "set")
-		}
-		key, ok := tokenString(frame.Array[1])
-		if !ok {
-			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[1].Kind))
-		}
-		value, ok := tokenBytes(frame.Array[2])
-		if !ok {
-			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[2].Kind))
-		}
-		c.server.store.Set(key, value)
-		return appendSimple(dst, "OK")
-	case commandIs(command, "GET"):
-		if len(frame.Array) != 2 {
-			return appendWrongArity(dst, "get")
-		}
-		key, ok := tokenString(frame.Array[1])
-		if !ok {
-			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[1].Kind))
-		}
-		v, hit := c.server.store.Get(key)
-		if !hit {
-			return appendNull(dst)
+	for i, frame := range frames {
+		if c.server.faults != nil && !c.injectBeforeCommand(frame) {
+			return false
 		}
-		return appendBulk(dst, v)
-	case commandIs(command, "DEL"):
-		if len(frame.Array) < 2 {
-			return appendWrongArity(dst, "del")
+		if w.Commit(c.execute(w.Buf(), frame)) != nil {
+			return false
 		}
-		keys := make([]string, 0, len(frame.Array)-1)
-		for i := 1; i < len(frame.Array); i++ {
-			key, ok := tokenString(frame.Array[i])
-			if !ok {
-				return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[i].Kind))
+		if c.quit {
+			if w.Flush() == nil {
+				c.close("quit")
 			}
-			keys = append(keys, key)
-		}
-		return appendInteger(dst, c.server.store.Del(keys...))
-	case commandIs(command, "INCR"):
-		if len(frame.Array) != 2 {
-			return appendWrongArity(dst, "incr")
-		}
-		key, ok := tokenString(frame.Array[1])
-		if !ok {
-			return appendError(dst, fmt.Sprintf("ERR Protocol error: invalid command token kind %s", frame.Array[1].Kind))
+			return false
 		}
-		n, incrErr := c.server.store.Incr(key)
-		if incrErr != nil {
-			if errors.Is(incrErr, errValueNotInteger) {
-				return appendError(dst, "ERR value is not an integer or out of range")
-			}
-			return appendError(dst, "ERR "+incrErr.Error())
+		c.server.serveReadyKeys()
+		if c.blocked != nil {
+			c.pending = append(c.pending, frames[i+1:]...)
+			break
 		}
-		return appendInteger(dst, n)
-	default:
-		return appendError(dst, "ERR unknown command '"+strings.ToLower(string(command))+"'")
 	}
+	return w.Flush() == nil
 }
 
-func (c *clientConn) writeSyncResponse(v redisproto.Value) xev.Action {
-	wire, err := redisproto.Encode(v)
-	if err != nil {
-		wire, _ = redisproto.Encode(redisError("ERR internal encode error"))
+func (c *clientConn) replyWriter() *redisproto.ReplyWriter {
+	if c.replies == nil {
+		c.replies = redisproto.NewReplyWriter(c.writeReplies, redisproto.DefaultFlushPolicy)
+	}
+	return c.replies
+}
+
+// errClientClosed fails the reply writer of a client the fault injector
+// dropped.
+var errClientClosed = errors.New("client closed")
+
+// writeReplies writes a batch of replies to the client, closing it if the
+// write fails.
+func (c *clientConn) writeReplies(wire []byte) error {
+	if c.server.faults != nil && !c.injectReply(wire) {
+		return errClientClosed
+	}
+	if err := writeAll(c.fd, wire); err != nil {
+		c.close("write error: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// execute appends the response for frame and logs it if it ran longer than
+// the configured slow log threshold.
+func (c *clientConn) execute(dst []byte, frame redisproto.Value) []byte {
+	if c.server.slowLog < 0 {
+		return c.appendResponse(dst, frame)
+	}
+	start := time.Now()
+	dst = c.appendResponse(dst, frame)
+	if elapsed := time.Since(start); elapsed > c.server.slowLog {
+		c.log.Warn("slow command", "command", commandName(frame), "duration", elapsed)
 	}
-	if writeErr := writeAll(c.conn.Fd(), wire); writeErr != nil {
-		c.close()
+	return dst
+}
+
+// protocolError replies to input the parser rejected and closes the
+// connection, as Redis does: the parser has lost the frame boundaries, so
+// nothing after the error can be trusted. The fd is released by the run
+// loop once the read callback returns.
+func (c *clientConn) protocolError(err error) xev.Action {
+	c.log.Warn("protocol error", "err", err)
+	wire := appendError(nil, "ERR Protocol error: "+err.Error())
+	if writeErr := writeAll(c.fd, wire); writeErr != nil {
+		c.close("write error: " + writeErr.Error())
 		return xev.Stop
 	}
-	return xev.Continue
+	c.close("protocol error")
+	return xev.Stop
 }
 
-func (c *clientConn) close() {
+func (c *clientConn) close(reason string) {
 	if c.closed {
 		return
 	}
 	c.closed = true
+	if c.closeReason != "" {
+		reason = c.closeReason
+	}
+	c.log.Log(context.Background(), LevelVerbose, "client disconnected", "reason", reason)
+	if r := c.server.reaper; r != nil {
+		r.Remove(c)
+	}
+	c.unblock()
+	c.server.removeReplica(c)
 
 	c.server.clientsMu.Lock()
 	delete(c.server.clients, c)
 	c.server.clientsMu.Unlock()
 
-	c.server.enqueueFD(c.conn.Fd())
+	c.server.enqueueFD(c.fd)
 }
 
 func (c *clientConn) shutdown() {
@@ -318,12 +690,21 @@ func (c *clientConn) shutdown() {
 		return
 	}
 	c.closed = true
+	c.log.Log(context.Background(), LevelVerbose, "client disconnected", "reason", "server shutdown")
+	c.unblock()
+	c.server.removeReplica(c)
 
 	c.server.clientsMu.Lock()
 	delete(c.server.clients, c)
 	c.server.clientsMu.Unlock()
 
-	_ = syscall.Shutdown(int(c.conn.Fd()), syscall.SHUT_RDWR)
+	_ = syscall.Shutdown(int(c.fd), syscall.SHUT_RDWR)
+}
+
+func (s *Server) stopReaper() {
+	if s.reaper != nil {
+		s.reaper.Stop()
+	}
 }
 
 func (s *Server) enqueueFD(fd int32) {
@@ -341,7 +722,9 @@ func (s *Server) flushPendingFDs() {
 	s.closeMu.Unlock()
 
 	for _, fd := range pending {
-		_ = syscall.Close(int(fd))
+		if err := syscall.Close(int(fd)); err != nil {
+			s.log.Warn("close client socket failed", "fd", fd, "err", err)
+		}
 	}
 }
 
@@ -367,31 +750,25 @@ func tokenString(v redisproto.Value) (string, bool) {
 	}
 }
 
-func commandIs(token []byte, name string) bool {
-	if len(token) != len(name) {
-		return false
-	}
-	for i := 0; i < len(token); i++ {
-		b := token[i]
-		if b >= 'a' && b <= 'z' {
-			b -= 'a' - 'A'
-		}
-		if b != name[i] {
-			return false
-		}
-	}
-	return true
-}
-
 func appendSimple(dst []byte, s string) []byte {
 	dst = append(dst, '+')
 	dst = append(dst, s...)
 	return append(dst, '\r', '\n')
 }
 
+// appendError appends an error reply. Errors may quote client arguments,
+// which are binary, so CR and LF become spaces, as in Redis, instead of
+// ending the reply early.
 func appendError(dst []byte, s string) []byte {
 	dst = append(dst, '-')
-	dst = append(dst, s...)
+	for i := range len(s) {
+		switch b := s[i]; b {
+		case '\r', '\n':
+			dst = append(dst, ' ')
+		default:
+			dst = append(dst, b)
+		}
+	}
 	return append(dst, '\r', '\n')
 }
 
@@ -407,12 +784,64 @@ func appendNull(dst []byte) []byte {
 	return append(dst, '$', '-', '1', '\r', '\n')
 }
 
+func appendNullArray(dst []byte) []byte {
+	return append(dst, '*', '-', '1', '\r', '\n')
+}
+
 func appendInteger(dst []byte, n int64) []byte {
 	dst = append(dst, ':')
 	dst = strconv.AppendInt(dst, n, 10)
 	return append(dst, '\r', '\n')
 }
 
+func appendBool(dst []byte, b bool) []byte {
+	if b {
+		return appendInteger(dst, 1)
+	}
+	return appendInteger(dst, 0)
+}
+
+// appendMapLen starts a map of n entries: a RESP3 map, or for RESP2
+// clients an array of alternating keys and values.
+func (c *clientConn) appendMapLen(dst []byte, n int) []byte {
+	if c.codec != nil && c.codec.Version() == 3 {
+		dst = append(dst, '%')
+		dst = strconv.AppendInt(dst, int64(n), 10)
+		return append(dst, '\r', '\n')
+	}
+	return appendArrayLen(dst, 2*n)
+}
+
+func appendArrayLen(dst []byte, n int) []byte {
+	dst = append(dst, '*')
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return append(dst, '\r', '\n')
+}
+
+func appendBulkString(dst []byte, s string) []byte {
+	dst = append(dst, '$')
+	dst = strconv.AppendInt(dst, int64(len(s)), 10)
+	dst = append(dst, '\r', '\n')
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+func appendStringArray(dst []byte, items []string) []byte {
+	dst = appendArrayLen(dst, len(items))
+	for _, s := range items {
+		dst = appendBulkString(dst, s)
+	}
+	return dst
+}
+
+func appendBulkArray(dst []byte, items [][]byte) []byte {
+	dst = appendArrayLen(dst, len(items))
+	for _, v := range items {
+		dst = appendBulk(dst, v)
+	}
+	return dst
+}
+
 func appendWrongArity(dst []byte, cmd string) []byte {
 	return appendError(dst, "ERR wrong number of arguments for '"+cmd+"' command")
 }
@@ -441,8 +870,33 @@ func decodeCommand(frame redisproto.Value) (string, []string, error) {
 	return name, parts[1:], nil
 }
 
-func redisError(s string) redisproto.Value {
-	return redisproto.Value{Kind: redisproto.KindError, Str: s}
+// commandName returns the upper-cased command name of frame for logging.
+func commandName(frame redisproto.Value) string {
+	if frame.Kind != redisproto.KindArray || len(frame.Array) == 0 {
+		return ""
+	}
+	name, ok := tokenString(frame.Array[0])
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(name)
+}
+
+// peerAddr returns the remote address of a connected socket, or "" if it
+// cannot be determined.
+func peerAddr(fd int32) string {
+	sa, err := syscall.Getpeername(int(fd))
+	if err != nil {
+		return ""
+	}
+	switch a := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return net.JoinHostPort(net.IP(a.Addr[:]).String(), strconv.Itoa(a.Port))
+	case *syscall.SockaddrInet6:
+		return net.JoinHostPort(net.IP(a.Addr[:]).String(), strconv.Itoa(a.Port))
+	default:
+		return ""
+	}
 }
 
 func parseHost(addr string) string {
@@ -453,6 +907,9 @@ func parseHost(addr string) string {
 	return host
 }
 
+// writeAll writes a reply directly to the socket. The client's completion
+// carries its read, which re-arms after every callback, so an async write
+// from the read callback would break the xev re-entrancy rules.
 func writeAll(fd int32, payload []byte) error {
 	for len(payload) > 0 {
 		n, err := syscall.Write(int(fd), payload)
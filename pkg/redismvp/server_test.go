@@ -7,34 +7,31 @@ package redismvp
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/crrow/libxev-go/pkg/cxev"
 	"github.com/crrow/libxev-go/pkg/redisproto"
+	"github.com/crrow/libxev-go/pkg/xev"
 )
 
 func TestRedisServerCommandSemantics(t *testing.T) {
-	if !cxev.ExtLibLoaded() {
-		t.Skip("extended library not loaded")
-	}
+	t.Parallel()
+	srv := startTestServer(t, Config{})
 
-	srv, err := Start("127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("start failed: %v", err)
-	}
-	defer func() { _ = srv.Close() }()
-
-	conn, err := net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
-	if err != nil {
-		t.Fatalf("dial failed: %v", err)
-	}
-	defer conn.Close()
+	conn := dialTestServer(t, srv)
 
 	mustResponse(t, conn, []string{"PING"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
 	mustResponse(t, conn, []string{"PING", "hello"}, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte("hello")})
@@ -57,15 +54,8 @@ func TestRedisServerCommandSemantics(t *testing.T) {
 }
 
 func TestRedisServerConcurrentClients(t *testing.T) {
-	if !cxev.ExtLibLoaded() {
-		t.Skip("extended library not loaded")
-	}
-
-	srv, err := Start("127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("start failed: %v", err)
-	}
-	defer func() { _ = srv.Close() }()
+	t.Parallel()
+	srv := startTestServer(t, Config{})
 
 	const clients = 16
 	results := make([]int64, 0, clients)
@@ -77,7 +67,7 @@ func TestRedisServerConcurrentClients(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			conn, dialErr := net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
+			conn, dialErr := testDialer(srv)("tcp", srv.Addr())
 			if dialErr != nil {
 				t.Errorf("dial failed: %v", dialErr)
 				return
@@ -110,49 +100,45 @@ func TestRedisServerConcurrentClients(t *testing.T) {
 }
 
 func TestRedisServerCloseWithActiveClients(t *testing.T) {
-	if !cxev.ExtLibLoaded() {
-		t.Skip("extended library not loaded")
-	}
-
-	srv, err := Start("127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("start failed: %v", err)
-	}
+	t.Parallel()
+	srv := startTestServer(t, Config{})
 
-	conns := make([]net.Conn, 0, 24)
 	for i := 0; i < 24; i++ {
-		conn, dialErr := net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
-		if dialErr != nil {
-			t.Fatalf("dial failed: %v", dialErr)
-		}
-		conns = append(conns, conn)
+		dialTestServer(t, srv)
 	}
 
 	if closeErr := srv.Close(); closeErr != nil {
 		t.Fatalf("server close failed: %v", closeErr)
 	}
-
-	for _, conn := range conns {
-		_ = conn.Close()
-	}
 }
 
-func TestRedisServerProtocolErrorsDeterministic(t *testing.T) {
+func TestRedisServerCloseReleasesPort(t *testing.T) {
 	if !cxev.ExtLibLoaded() {
 		t.Skip("extended library not loaded")
 	}
+	t.Parallel()
 
-	srv, err := Start("127.0.0.1:0")
+	srv, err := StartConfig(Config{Addr: "127.0.0.1:0", LogLevel: LevelNothing})
 	if err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
-	defer func() { _ = srv.Close() }()
+	addr := srv.Addr()
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
 
-	conn, err := net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		t.Fatalf("dial failed: %v", err)
+		t.Fatalf("port not released after Close: %v", err)
 	}
-	defer conn.Close()
+	_ = ln.Close()
+}
+
+func TestRedisServerProtocolErrorsDeterministic(t *testing.T) {
+	t.Parallel()
+	srv := startTestServer(t, Config{})
+
+	conn := dialTestServer(t, srv)
 
 	_, _ = conn.Write([]byte("+HELLO\r\n"))
 	resp := readOneValue(t, conn)
@@ -180,6 +166,212 @@ func TestRedisServerProtocolErrorsDeterministic(t *testing.T) {
 	}
 }
 
+func TestRedisServerIdleTimeout(t *testing.T) {
+	t.Parallel()
+	srv := startTestServer(t, Config{Timeout: 100 * time.Millisecond})
+
+	conn := dialTestServer(t, srv)
+
+	mustResponse(t, conn, []string{"PING"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 16)
+	n, readErr := conn.Read(buf)
+	if readErr == nil {
+		t.Fatalf("expected idle connection to be closed, read %q", buf[:n])
+	}
+	var ne net.Error
+	if errors.As(readErr, &ne) && ne.Timeout() {
+		t.Fatal("idle connection was not closed by the server")
+	}
+}
+
+func TestRefuseConnection(t *testing.T) {
+	s := &Server{clients: map[*clientConn]struct{}{{}: {}}, maxClients: 2, protectedMode: true}
+
+	if got := s.refuse("127.0.0.1:5000"); got != "" {
+		t.Fatalf("loopback client refused: %q", got)
+	}
+	if got := s.refuse("[::1]:5000"); got != "" {
+		t.Fatalf("IPv6 loopback client refused: %q", got)
+	}
+	if got := s.refuse("10.0.0.2:5000"); !strings.HasPrefix(got, "DENIED") {
+		t.Fatalf("remote client in protected mode: %q", got)
+	}
+	s.protectedMode = false
+	if got := s.refuse("10.0.0.2:5000"); got != "" {
+		t.Fatalf("remote client refused without protected mode: %q", got)
+	}
+
+	s.clients[&clientConn{}] = struct{}{}
+	if got := s.refuse("127.0.0.1:5000"); got != "ERR max number of clients reached" {
+		t.Fatalf("client over maxclients: %q", got)
+	}
+}
+
+func TestRedisServerMaxClients(t *testing.T) {
+	t.Parallel()
+	srv := startTestServer(t, Config{MaxClients: 1})
+
+	first := dialTestServer(t, srv)
+	mustResponse(t, first, []string{"PING"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
+
+	second := dialTestServer(t, srv)
+	got := readOneValue(t, second)
+	if got.Kind != redisproto.KindError || got.Str != "ERR max number of clients reached" {
+		t.Fatalf("second client got %#v", got)
+	}
+	// The refused connection is closed right after the reply.
+	_ = second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if n, err := second.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("refused connection read = %d, %v; want EOF", n, err)
+	}
+
+	// Disconnecting frees the slot for the next client.
+	_ = first.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		srv.clientsMu.Lock()
+		n := len(srv.clients)
+		srv.clientsMu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients still connected after the disconnect", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	third := dialTestServer(t, srv)
+	mustResponse(t, third, []string{"PING"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
+}
+
+func TestRedisServerSocketOptions(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	t.Parallel()
+
+	// Socket options only apply over TCP, whatever the test transport.
+	srv, err := StartConfig(Config{Addr: "127.0.0.1:0", TCPKeepAlive: 60 * time.Second, TCPNoDelay: true})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer func() { _ = srv.Close() }()
+
+	conn, err := net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	mustResponse(t, conn, []string{"PING"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"})
+
+	srv.clientsMu.Lock()
+	var fd int
+	for c := range srv.clients {
+		fd = int(c.fd)
+	}
+	srv.clientsMu.Unlock()
+
+	opts := []struct {
+		name       string
+		level, opt int
+		want       int
+	}{
+		{"TCP_NODELAY", unix.IPPROTO_TCP, unix.TCP_NODELAY, 1},
+		{"SO_KEEPALIVE", unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1},
+		{"TCP_KEEPINTVL", unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, 20},
+		{"TCP_KEEPCNT", unix.IPPROTO_TCP, unix.TCP_KEEPCNT, 3},
+	}
+	for _, o := range opts {
+		got, err := unix.GetsockoptInt(fd, o.level, o.opt)
+		if err != nil {
+			t.Fatalf("getsockopt %s: %v", o.name, err)
+		}
+		if got != o.want {
+			t.Errorf("%s = %d, want %d", o.name, got, o.want)
+		}
+	}
+}
+
+func TestRedisServerPipe(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	t.Parallel()
+
+	srv, err := StartConfig(Config{Pipe: true, LogLevel: LevelNothing})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if got := srv.Addr(); got != "pipe" {
+		t.Errorf("Addr() = %q, want pipe", got)
+	}
+	conn, err := srv.DialPipe()
+	if err != nil {
+		t.Fatalf("DialPipe failed: %v", err)
+	}
+	defer conn.Close()
+	mustResponse(t, conn, []string{"SET", "k", "v"}, redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"})
+	mustResponse(t, conn, []string{"GET", "k"}, redisproto.Value{Kind: redisproto.KindBulkString, Bulk: []byte("v")})
+
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := srv.DialPipe(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("DialPipe after Close: %v, want net.ErrClosed", err)
+	}
+}
+
+// testTransport selects how integration tests reach their servers:
+// in-process socket pairs by default, which take no ports and let tests
+// run in parallel freely, or loopback TCP with REDISMVP_TEST_TRANSPORT=tcp.
+var testTransport = os.Getenv("REDISMVP_TEST_TRANSPORT")
+
+// startTestServer starts a server with cfg on the test transport, to be
+// closed when the test ends. It skips the test without the extended
+// library.
+func startTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	if testTransport == "tcp" {
+		cfg.Addr = "127.0.0.1:0"
+	} else {
+		cfg.Pipe = true
+	}
+	srv, err := StartConfig(cfg)
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv
+}
+
+// testDialer returns a dial function connecting to srv over the test
+// transport, in the shape of rediscli.Client.Dial. The arguments are
+// ignored.
+func testDialer(srv *Server) func(network, addr string) (net.Conn, error) {
+	if srv.listener == nil {
+		return func(string, string) (net.Conn, error) { return srv.DialPipe() }
+	}
+	return func(string, string) (net.Conn, error) {
+		return net.DialTimeout("tcp", srv.Addr(), 2*time.Second)
+	}
+}
+
+// dialTestServer connects a client to srv, to be closed when the test ends.
+func dialTestServer(t *testing.T, srv *Server) net.Conn {
+	t.Helper()
+	conn, err := testDialer(srv)("tcp", srv.Addr())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
 func mustResponse(t *testing.T, conn net.Conn, cmd []string, want redisproto.Value) {
 	t.Helper()
 	got := sendCommand(t, conn, cmd)
@@ -244,3 +436,109 @@ func TestDecodeCommand(t *testing.T) {
 		t.Fatalf("unexpected args: %v", args)
 	}
 }
+
+func TestPipelinedRepliesBatched(t *testing.T) {
+	s := newBlockingTestServer()
+	c, peer := newSocketClient(t, s)
+	writes := 0
+	c.replies = redisproto.NewReplyWriter(func(p []byte) error {
+		writes++
+		return c.writeReplies(p)
+	}, redisproto.FlushPolicy{MaxBytes: 4096, MaxReplies: 100})
+
+	// One read carries 250 commands; their replies are written in bounded
+	// batches, in order, while the peer drains the socket.
+	const n = 250
+	var wire []byte
+	for i := range n {
+		wire, _ = redisproto.AppendEncode(wire, buildTestCommand([]string{"ECHO", fmt.Sprint(i, strings.Repeat("x", 30))}))
+	}
+	got := make(chan error, 1)
+	go func() {
+		parser := redisproto.NewParser()
+		buf := make([]byte, 4096)
+		_ = peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for i := 0; i < n; {
+			m, err := peer.Read(buf)
+			if err != nil {
+				got <- err
+				return
+			}
+			frames, _ := parser.Feed(buf[:m])
+			for _, f := range frames {
+				if want := fmt.Sprint(i, strings.Repeat("x", 30)); string(f.Bulk) != want {
+					got <- fmt.Errorf("reply %d: %q, want %q", i, f.Bulk, want)
+					return
+				}
+				i++
+			}
+		}
+		got <- nil
+	}()
+	c.onRead(nil, wire, nil)
+	if err := <-got; err != nil {
+		t.Fatal(err)
+	}
+	// 100 replies of about 40 bytes stay under the byte bound, so the reply
+	// bound splits the batches, and the end of the read flushes the rest.
+	if writes != 3 {
+		t.Fatalf("%d writes, want 3", writes)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	s := newBlockingTestServer()
+	c, peer := newSocketClient(t, s)
+
+	// Commands pipelined after QUIT are dropped.
+	var wire []byte
+	for _, args := range [][]string{{"PING"}, {"QUIT"}, {"SET", "k", "v"}} {
+		wire, _ = redisproto.AppendEncode(wire, buildTestCommand(args))
+	}
+	if action := c.onRead(nil, wire, nil); action != xev.Stop {
+		t.Fatalf("onRead after QUIT = %v, want Stop", action)
+	}
+	expectReplies(t, peer,
+		redisproto.Value{Kind: redisproto.KindSimpleString, Str: "PONG"},
+		redisproto.Value{Kind: redisproto.KindSimpleString, Str: "OK"})
+	expectNoReply(t, peer)
+	if !c.closed || !slices.Contains(s.pendingFDs, c.fd) {
+		t.Fatalf("client not closed after QUIT: closed=%v pending=%v", c.closed, s.pendingFDs)
+	}
+	if _, ok := s.store.kv["k"]; ok {
+		t.Fatal("command after QUIT was executed")
+	}
+}
+
+func TestProtocolErrorCloses(t *testing.T) {
+	s := newBlockingTestServer()
+	c, peer := newSocketClient(t, s)
+
+	// The frame after the malformed one is never parsed.
+	if action := c.onRead(nil, []byte("*1\r\n$x\r\n*1\r\n$4\r\nPING\r\n"), nil); action != xev.Stop {
+		t.Fatalf("onRead = %v, want Stop", action)
+	}
+	got := readOneValue(t, peer)
+	if got.Kind != redisproto.KindError || !strings.HasPrefix(got.Str, "ERR Protocol error: ") {
+		t.Fatalf("got %#v, want a protocol error", got)
+	}
+	expectNoReply(t, peer)
+	if !c.closed || !slices.Contains(s.pendingFDs, c.fd) {
+		t.Fatalf("client not closed after protocol error: closed=%v pending=%v", c.closed, s.pendingFDs)
+	}
+}
+
+func TestRedisServerProtocolErrorCloses(t *testing.T) {
+	t.Parallel()
+	srv := startTestServer(t, Config{})
+
+	conn := dialTestServer(t, srv)
+	_, _ = conn.Write([]byte("$-5\r\n"))
+	if resp := readOneValue(t, conn); resp.Kind != redisproto.KindError {
+		t.Fatalf("expected protocol error, got %#v", resp)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if n, err := conn.Read(make([]byte, 16)); err != io.EOF {
+		t.Fatalf("read after protocol error: n=%d err=%v, want EOF", n, err)
+	}
+}
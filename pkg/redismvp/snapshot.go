@@ -0,0 +1,321 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Background snapshots (BGSAVE and BGREWRITEAOF). Redis forks and lets
+// copy-on-write pages keep the child's view of the dataset frozen; Go
+// cannot fork, so the loop serializes the dataset itself, a chunk per
+// iteration, while clients keep writing. Copy-on-write happens per key
+// instead: before a write command changes a key the snapshot has not
+// reached yet, the key is serialized as it still is, and marked so the
+// snapshot skips it later. Keys created after the start are marked the
+// same way. The remaining keys are walked by one map iteration, paused
+// between chunks; Go allows changing a map while iterating it, and
+// iteration produces every key that exists throughout exactly once.
+//
+// The result is the dataset as of the start. Memory overhead is the set
+// of keys written during the snapshot plus the serialized chunks waiting
+// for the writer goroutine, which the queue bounds, rather than a copy of
+// the dataset. Writes pay for serializing the keys they touch first.
+
+// DefaultDBFilename is the snapshot file used when Config.DBFilename is
+// empty.
+const DefaultDBFilename = "dump.rdb"
+
+const (
+	// snapshotChunkSize is how much the loop serializes per iteration.
+	snapshotChunkSize = 64 << 10
+	// snapshotQueue is how many chunks may wait for the disk; the loop
+	// stops serializing while the writer is behind.
+	snapshotQueue = 16
+)
+
+// snapshotFormat is the encoding of a snapshot: an RDB file, or commands
+// as in an AOF rewritten without RDB preamble.
+type snapshotFormat uint8
+
+const (
+	snapshotRDB snapshotFormat = iota
+	snapshotCommands
+)
+
+type snapshot struct {
+	format snapshotFormat
+	next   func() (string, any, bool)
+	stop   func()
+	// visited holds keys serialized ahead of the iteration, or created
+	// after the start, which the iteration skips.
+	visited map[string]struct{}
+	buf     []byte
+	crc     uint64
+	keys    int
+	// iterated is set once every key was serialized and the last chunk
+	// queued.
+	iterated bool
+	chunks   chan []byte
+	done     chan error
+	start    time.Time
+	// finish runs on the loop once the file was written and fsynced, or
+	// writing it failed.
+	finish func(err error)
+}
+
+func init() {
+	registerCommands(
+		&command{name: "save", arity: 1, group: "server",
+			summary: "Synchronously saves the database(s) to disk.", handler: cmdSave},
+		&command{name: "bgsave", arity: -1, group: "server",
+			summary: "Asynchronously saves the database(s) to disk.", handler: cmdBGSave},
+		&command{name: "lastsave", arity: 1, flags: []string{flagFast}, group: "server",
+			summary: "Returns the Unix timestamp of the last successful save to disk.", handler: cmdLastSave},
+	)
+}
+
+func cmdSave(c *clientConn, dst []byte, _ [][]byte) []byte {
+	s := c.server
+	if s.store.snap != nil {
+		return appendError(dst, "ERR Background save already in progress")
+	}
+	if err := s.startSave(); err != nil {
+		return appendError(dst, "ERR "+err.Error())
+	}
+	if err := s.waitSnapshot(); err != nil {
+		return appendError(dst, "ERR "+err.Error())
+	}
+	return appendSimple(dst, "OK")
+}
+
+func cmdBGSave(c *clientConn, dst []byte, args [][]byte) []byte {
+	s := c.server
+	schedule := len(args) == 1 && argIs(args[0], "SCHEDULE")
+	if len(args) > 0 && !schedule {
+		return appendSyntaxError(dst)
+	}
+	if sn := s.store.snap; sn != nil {
+		if s.aof != nil && s.aof.rewrite != nil {
+			if schedule {
+				s.bgsaveScheduled = true
+				return appendSimple(dst, "Background saving scheduled")
+			}
+			return appendError(dst, "ERR An AOF log rewriting in progress: can't BGSAVE right now. "+
+				"Use BGSAVE SCHEDULE in order to schedule a BGSAVE whenever possible.")
+		}
+		return appendError(dst, "ERR Background save already in progress")
+	}
+	if err := s.startSave(); err != nil {
+		return appendError(dst, "ERR "+err.Error())
+	}
+	return appendSimple(dst, "Background saving started")
+}
+
+func cmdLastSave(c *clientConn, dst []byte, _ [][]byte) []byte {
+	return appendInteger(dst, c.server.lastSave.Unix())
+}
+
+// startSave starts writing the dataset to the RDB file. The file is
+// written under a temporary name and renamed once complete.
+func (s *Server) startSave() error {
+	path := s.dbFilename
+	tmp, err := createTemp(path, "temp-*.rdb")
+	if err != nil {
+		return err
+	}
+	s.startSnapshot(tmp, snapshotRDB, func(err error) {
+		if err == nil {
+			if s.diskFaults != nil && !s.injectRename(tmp.Name(), path) {
+				return
+			}
+			err = s.replaceFile(tmp.Name(), path)
+		}
+		_ = tmp.Close()
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+			s.log.Warn("background saving failed", "err", err)
+			return
+		}
+		s.lastSave = time.Now()
+		s.log.Info("DB saved on disk", "path", path)
+	}, "ctime", strconv.FormatInt(time.Now().Unix(), 10))
+	return nil
+}
+
+// loadRDBFile loads the snapshot at path, if it exists, into the empty
+// store.
+func (s *Server) loadRDBFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read RDB file: %w", err)
+	}
+	start := time.Now()
+	if _, err := loadRDB(data, s.store.kv); err != nil {
+		return fmt.Errorf("load RDB file: %w", err)
+	}
+	s.lastSave = time.Now()
+	s.log.Info("DB loaded from disk", "keys", len(s.store.kv), "duration", time.Since(start))
+	return nil
+}
+
+// startSnapshot starts writing the dataset to f in format, and calls
+// finish once it is done. aux lists RDB aux fields in name/value pairs.
+// The caller holds the store lock, and no other snapshot may be running.
+func (s *Server) startSnapshot(f *os.File, format snapshotFormat, finish func(error), aux ...string) {
+	next, stop := iter.Pull2(maps.All(s.store.kv))
+	sn := &snapshot{
+		format:  format,
+		next:    next,
+		stop:    stop,
+		visited: make(map[string]struct{}),
+		chunks:  make(chan []byte, snapshotQueue),
+		done:    make(chan error, 1),
+		start:   time.Now(),
+		finish:  finish,
+	}
+	if format == snapshotRDB {
+		sn.buf = appendRDBHeader(sn.buf, len(s.store.kv), aux...)
+	}
+	s.store.snap = sn
+	// The writer wakes the loop after each chunk, so it serializes the
+	// next, and once the file is done.
+	go func() {
+		var err error
+		for chunk := range sn.chunks {
+			if err != nil {
+				continue
+			}
+			if s.diskFaults != nil && !s.injectWrite(f, chunk) {
+				return
+			}
+			_, err = f.Write(chunk)
+			s.wake()
+		}
+		if err == nil {
+			err = f.Sync()
+		}
+		sn.done <- err
+		s.wake()
+	}()
+	s.log.Info("background snapshot started", "keys", len(s.store.kv))
+}
+
+// preserve serializes keys as they are before a write changes them, unless
+// the snapshot already holds or must skip them. The caller holds the store
+// lock.
+func (st *Store) preserve(keys []string) {
+	sn := st.snap
+	if sn == nil || sn.iterated {
+		return
+	}
+	for _, key := range keys {
+		if _, ok := sn.visited[key]; ok {
+			continue
+		}
+		sn.visited[key] = struct{}{}
+		if v, ok := st.kv[key]; ok {
+			sn.add(key, v)
+		}
+	}
+}
+
+func (sn *snapshot) add(key string, v any) {
+	if sn.format == snapshotRDB {
+		sn.buf = appendRDBValue(sn.buf, key, v)
+	} else {
+		sn.buf = appendKeyCommands(sn.buf, key, v)
+	}
+	sn.keys++
+}
+
+// produce serializes keys until a chunk is full and queues it. At the end
+// of the iteration it queues the rest of the file and closes the queue.
+// The caller holds the store lock.
+func (sn *snapshot) produce() {
+	for len(sn.buf) < snapshotChunkSize {
+		key, v, ok := sn.next()
+		if !ok {
+			sn.iterated = true
+			if sn.format == snapshotRDB {
+				sn.buf = appendRDBFooter(sn.buf, rdbChecksum(sn.crc, sn.buf))
+			}
+			sn.chunks <- sn.buf
+			close(sn.chunks)
+			sn.buf = nil
+			sn.stop()
+			sn.visited = nil
+			return
+		}
+		if _, ok := sn.visited[key]; !ok {
+			sn.add(key, v)
+		}
+	}
+	if sn.format == snapshotRDB {
+		sn.crc = rdbChecksum(sn.crc, sn.buf)
+	}
+	sn.chunks <- sn.buf
+	sn.buf = make([]byte, 0, snapshotChunkSize)
+}
+
+// pollSnapshot moves the running snapshot along by a chunk, if the writer
+// keeps up, and completes it once the file is written.
+func (s *Server) pollSnapshot() {
+	sn := s.store.snap
+	if sn == nil {
+		if s.bgsaveScheduled {
+			s.bgsaveScheduled = false
+			s.store.mu.Lock()
+			err := s.startSave()
+			s.store.mu.Unlock()
+			if err != nil {
+				s.log.Warn("scheduled background saving failed", "err", err)
+			}
+		}
+		return
+	}
+	if !sn.iterated && len(sn.chunks) < cap(sn.chunks) {
+		s.store.mu.Lock()
+		sn.produce()
+		s.store.mu.Unlock()
+	}
+	select {
+	case err := <-sn.done:
+		s.endSnapshot(sn, err)
+	default:
+	}
+}
+
+// waitSnapshot completes the running snapshot right away. The caller holds
+// the store lock.
+func (s *Server) waitSnapshot() error {
+	sn := s.store.snap
+	if sn == nil {
+		return nil
+	}
+	for !sn.iterated {
+		sn.produce()
+	}
+	err := <-sn.done
+	s.endSnapshot(sn, err)
+	return err
+}
+
+func (s *Server) endSnapshot(sn *snapshot, err error) {
+	s.store.snap = nil
+	s.log.Info("background snapshot done", "keys", sn.keys, "duration", time.Since(sn.start), "err", err)
+	sn.finish(err)
+}
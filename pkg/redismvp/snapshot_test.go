@@ -0,0 +1,328 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/redisproto"
+)
+
+// newSnapshotTestClient returns a client of a test server saving to a
+// fresh RDB file.
+func newSnapshotTestClient(t *testing.T) *testClient {
+	tc := newTestClient(t)
+	tc.c.server.dbFilename = filepath.Join(t.TempDir(), DefaultDBFilename)
+	return tc
+}
+
+// fillStore adds n keys of every type holding values of about size bytes.
+func fillStore(tc *testClient, n, size int) {
+	pad := strings.Repeat("x", size)
+	for i := range n {
+		tc.do("SET", fmt.Sprint("s:", i), pad)
+		tc.do("RPUSH", fmt.Sprint("l:", i), pad, "b", "c")
+		tc.do("SADD", fmt.Sprint("set:", i), pad, "b")
+		tc.do("HSET", fmt.Sprint("h:", i), "f", pad)
+		tc.do("ZADD", fmt.Sprint("z:", i), "1", pad, "2", "b")
+	}
+}
+
+// stepSnapshot runs the loop's part of the running snapshot until it
+// completes, calling between before every step, and returns the number of
+// steps.
+func stepSnapshot(t *testing.T, s *Server, between func(step int)) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	step := 0
+	for s.store.snap != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("snapshot did not complete")
+		}
+		between(step)
+		s.pollSnapshot()
+		step++
+	}
+	return step
+}
+
+func loadDump(t *testing.T, path string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read dump: %v", err)
+	}
+	kv := make(map[string]any)
+	if n, err := loadRDB(data, kv); err != nil || n != len(data) {
+		t.Fatalf("load dump: %d of %d bytes: %v", n, len(data), err)
+	}
+	return dumpStore(&Store{kv: kv})
+}
+
+func TestBGSaveUnderWrites(t *testing.T) {
+	tc := newSnapshotTestClient(t)
+	s := tc.c.server
+	fillStore(tc, 500, 200)
+	want := dumpStore(s.store)
+
+	waiter, waiterPeer := newSocketClient(t, s)
+	feed(t, waiter, "BLMPOP", "0", "1", "l:new", "LEFT")
+	expectNoReply(t, waiterPeer)
+
+	if got := tc.do("BGSAVE"); got.Str != "Background saving started" {
+		t.Fatalf("BGSAVE: %#v", got)
+	}
+	tc.wantError("ERR Background save already in progress", "BGSAVE")
+	tc.wantError("ERR Background save already in progress", "SAVE")
+	tc.wantInt(s.lastSave.Unix(), "LASTSAVE")
+
+	steps := stepSnapshot(t, s, func(step int) {
+		// Change keys before and after the iteration reaches them, in
+		// every way a write can.
+		for i := step * 25; i < step*25+25 && i < 500; i++ {
+			tc.do("SET", fmt.Sprint("s:", i), "changed")
+			tc.do("RPUSH", fmt.Sprint("l:", i), "more")
+			tc.do("SREM", fmt.Sprint("set:", i), "b")
+			tc.do("DEL", fmt.Sprint("h:", i))
+			tc.do("ZINCRBY", fmt.Sprint("z:", i), "5", "b")
+			tc.do("SET", fmt.Sprint("new:", i), "v")
+			tc.do("UNLINK", fmt.Sprint("new:", i-1))
+		}
+		s.store.Set(fmt.Sprint("api:", step), []byte("v"))
+		s.store.Del(fmt.Sprint("s:", 499-step))
+	})
+	if steps < 3 {
+		t.Fatalf("snapshot completed in %d steps, want several chunks", steps)
+	}
+	if got := loadDump(t, s.dbFilename); !reflect.DeepEqual(got, want) {
+		t.Fatalf("dump differs from the dataset at BGSAVE: %d keys, want %d", len(got), len(want))
+	}
+	if got := tc.do("LASTSAVE"); got.Int < time.Now().Add(-time.Minute).Unix() {
+		t.Fatalf("LASTSAVE = %d", got.Int)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(s.dbFilename), "temp-*")); len(matches) > 0 {
+		t.Fatalf("temporary files left: %v", matches)
+	}
+
+	// A blocked pop served during a snapshot preserves the popped key.
+	tc.do("BGSAVE")
+	writer, writerPeer := newSocketClient(t, s)
+	feed(t, writer, "RPUSH", "l:new", "x")
+	expectReplies(t, writerPeer, redisproto.Value{Kind: redisproto.KindInteger, Int: 1})
+	expectReplies(t, waiterPeer, redisproto.Value{Kind: redisproto.KindArray, Array: []redisproto.Value{
+		{Kind: redisproto.KindBulkString, Bulk: []byte("l:new")}, bulkArray("x")}})
+	want = dumpStore(s.store)
+	tc.do("RPUSH", "l:0", "after")
+	stepSnapshot(t, s, func(int) {})
+	if got := loadDump(t, s.dbFilename); !reflect.DeepEqual(got, want) {
+		t.Fatal("dump differs from the dataset at the second BGSAVE")
+	}
+}
+
+func TestSaveRestart(t *testing.T) {
+	tc := newSnapshotTestClient(t)
+	s := tc.c.server
+	fillStore(tc, 50, 10)
+	tc.do("SET", "counter", "10")
+	if got := tc.do("SAVE"); got.Str != "OK" {
+		t.Fatalf("SAVE: %#v", got)
+	}
+	if s.store.snap != nil {
+		t.Fatal("SAVE returned before the snapshot completed")
+	}
+
+	restarted := newSnapshotTestClient(t)
+	if err := restarted.c.server.loadRDBFile(s.dbFilename); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	wantSameDataset(t, restarted.c.server, s)
+	restarted.wantInt(11, "INCR", "counter")
+
+	// No file to load starts an empty server.
+	empty := newSnapshotTestClient(t)
+	if err := empty.c.server.loadRDBFile(empty.c.server.dbFilename); err != nil || len(empty.c.server.store.kv) != 0 {
+		t.Fatalf("load missing file: %v", err)
+	}
+}
+
+// wantReloadRoundTrip runs DEBUG RELOAD and fails unless the dataset
+// survived the RDB round trip unchanged.
+func wantReloadRoundTrip(t *testing.T, tc *testClient) {
+	t.Helper()
+	want := dumpStore(tc.c.server.store)
+	if got := tc.do("DEBUG", "RELOAD"); got.Str != "OK" {
+		t.Fatalf("DEBUG RELOAD: %#v", got)
+	}
+	if got := dumpStore(tc.c.server.store); !reflect.DeepEqual(got, want) {
+		t.Fatalf("dataset after DEBUG RELOAD:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestDebugReload(t *testing.T) {
+	tc := newSnapshotTestClient(t)
+	wantReloadRoundTrip(t, tc)
+	fillStore(tc, 20, 100)
+	tc.do("SET", "counter", "41")
+	tc.do("GEOADD", "geo", "13.361389", "38.115556", "Palermo")
+	tc.do("SETBIT", "bits", "100", "1")
+	wantReloadRoundTrip(t, tc)
+	tc.wantInt(42, "INCR", "counter")
+
+	// NOSAVE loads the dump as it is, dropping writes since the save.
+	tc.do("SET", "unsaved", "v")
+	if got := tc.do("DEBUG", "RELOAD", "NOSAVE"); got.Str != "OK" {
+		t.Fatalf("DEBUG RELOAD NOSAVE: %#v", got)
+	}
+	tc.wantNull("GET", "unsaved")
+	tc.wantBulk("41", "GET", "counter")
+
+	// NOFLUSH keeps keys missing from the dump, and refuses to overwrite
+	// the others unless MERGE is given.
+	tc.do("SET", "unsaved", "v")
+	tc.wantError("ERR Error trying to load the RDB dump, check server logs.", "DEBUG", "RELOAD", "NOSAVE", "NOFLUSH")
+	tc.do("SET", "counter", "0")
+	if got := tc.do("DEBUG", "RELOAD", "NOSAVE", "NOFLUSH", "MERGE"); got.Str != "OK" {
+		t.Fatalf("DEBUG RELOAD NOSAVE NOFLUSH MERGE: %#v", got)
+	}
+	tc.wantBulk("v", "GET", "unsaved")
+	tc.wantBulk("41", "GET", "counter")
+
+	tc.wantError("ERR syntax error", "DEBUG", "RELOAD", "FAST")
+	tc.wantError("ERR unknown subcommand 'NOPE'. Try DEBUG HELP.", "DEBUG", "NOPE")
+
+	missing := newSnapshotTestClient(t)
+	missing.wantError("ERR Error trying to load the RDB dump, check server logs.", "DEBUG", "RELOAD", "NOSAVE")
+}
+
+func TestBGSaveAndAOFRewriteScheduling(t *testing.T) {
+	tc := startAOFServer(t, aofConfig(t))
+	s := tc.c.server
+	s.dbFilename = filepath.Join(t.TempDir(), DefaultDBFilename)
+	tc.do("SET", "k", "v")
+	tc.wantError("ERR syntax error", "BGSAVE", "NOW")
+
+	tc.do("BGREWRITEAOF")
+	tc.wantError("ERR An AOF log rewriting in progress: can't BGSAVE right now. "+
+		"Use BGSAVE SCHEDULE in order to schedule a BGSAVE whenever possible.", "BGSAVE")
+	if got := tc.do("BGSAVE", "SCHEDULE"); got.Str != "Background saving scheduled" {
+		t.Fatalf("BGSAVE SCHEDULE: %#v", got)
+	}
+	stepSnapshot(t, s, func(int) {})
+	if s.aof.rewrite != nil {
+		t.Fatal("rewrite still running after its snapshot completed")
+	}
+	// The next iteration starts the scheduled save.
+	s.pollSnapshot()
+	if s.store.snap == nil || s.aof.rewrite != nil {
+		t.Fatal("scheduled BGSAVE did not start")
+	}
+
+	if got := tc.do("BGREWRITEAOF"); got.Str != "Background append only file rewriting scheduled" {
+		t.Fatalf("BGREWRITEAOF: %#v", got)
+	}
+	stepSnapshot(t, s, func(int) {})
+	if got := loadDump(t, s.dbFilename); len(got) != 1 {
+		t.Fatalf("dump: %v", got)
+	}
+	s.pollAOF(time.Now())
+	if s.aof.rewrite == nil {
+		t.Fatal("scheduled BGREWRITEAOF did not start")
+	}
+	waitAOFRewrite(t, s)
+}
+
+func TestCommandKeys(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"SET", "k", "v"}, []string{"k"}},
+		{[]string{"DEL", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{[]string{"HSET", "h", "f", "v"}, []string{"h"}},
+		{[]string{"SMOVE", "src", "dst", "m"}, []string{"src", "dst"}},
+		{[]string{"BZPOPMIN", "a", "b", "0"}, []string{"a", "b"}},
+		{[]string{"LMPOP", "2", "a", "b", "LEFT"}, []string{"a", "b"}},
+		{[]string{"BLMPOP", "0", "1", "a", "LEFT"}, []string{"a"}},
+		{[]string{"PING"}, nil},
+	} {
+		cmd := commandTable[strings.ToLower(tt.args[0])]
+		args := make([][]byte, len(tt.args))
+		for i, a := range tt.args {
+			args[i] = []byte(a)
+		}
+		if got := cmd.keys(args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: keys %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkSnapshot compares a background snapshot of a dataset taking
+// writes to a tenth of its keys with deep copying the dataset first, the
+// simplest consistent alternative. The retained-B metric is the memory
+// held on top of the dataset: written keys serialized ahead of the
+// iteration and queued chunks, or the copy.
+func BenchmarkSnapshot(b *testing.B) {
+	const keys = 20000
+	kv := make(map[string]any, keys)
+	for i := range keys {
+		kv[fmt.Sprint("key:", i)] = []byte(strings.Repeat("v", 100))
+	}
+
+	b.Run("incremental", func(b *testing.B) {
+		s := &Server{store: &Store{kv: kv}, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+		f, err := os.Create(filepath.Join(b.TempDir(), DefaultDBFilename))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		b.ReportAllocs()
+		var retained int
+		for b.Loop() {
+			_, _ = f.Seek(0, 0)
+			s.startSnapshot(f, snapshotRDB, func(error) {})
+			sn := s.store.snap
+			written := 0
+			for !sn.iterated {
+				// Writes between chunks touch a tenth of the keys over the
+				// whole snapshot.
+				for range keys / 10 / 32 {
+					s.store.preserve([]string{fmt.Sprint("key:", written*7%keys)})
+					written++
+				}
+				queued := len(sn.chunks)*snapshotChunkSize + len(sn.buf)
+				retained = max(retained, len(sn.visited)*(len("key:00000")+16)+queued)
+				sn.produce()
+			}
+			if err := s.waitSnapshot(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(retained), "retained-B")
+	})
+
+	b.Run("copy", func(b *testing.B) {
+		b.ReportAllocs()
+		var retained int
+		for b.Loop() {
+			dup := make(map[string]any, len(kv))
+			size := 0
+			for k, v := range kv {
+				dup[k] = copyValue(v)
+				size += len(k) + len(v.([]byte)) + 48
+			}
+			retained = size
+		}
+		b.ReportMetric(float64(retained), "retained-B")
+	})
+}
@@ -7,34 +7,71 @@ package redismvp
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
 	"strconv"
 	"sync"
 )
 
-var errValueNotInteger = errors.New("value is not an integer or out of range")
+var (
+	errValueNotInteger = errors.New("value is not an integer or out of range")
+	errIncrOverflow    = errors.New("increment or decrement would overflow")
+	errWrongType       = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+// setValue is the in-memory representation of a Redis set.
+type setValue map[string]struct{}
+
+// hashValue is the in-memory representation of a Redis hash.
+type hashValue map[string][]byte
 
 // Store provides thread-safe in-memory key/value storage.
+//
+// Each key holds one typed value: []byte for strings, *listValue for
+// lists, setValue for sets, hashValue for hashes and *zsetValue for sorted
+// sets. Command handlers run with mu held and use the unexported
+// accessors; the exported methods lock on their own and only see string
+// values.
+//
+// Keys, like values and members, are arbitrary bytes: a Go string holds
+// them unchanged, so nothing assumes valid UTF-8 or the absence of NULs.
 type Store struct {
 	mu sync.RWMutex
-	kv map[string][]byte
+	kv map[string]any
+
+	// keyCreated, if set, is called whenever a command creates a key that
+	// blocking commands can wait on.
+	keyCreated func(key string)
+
+	// snap is the background snapshot in progress, which keys must be
+	// preserved for before they are written.
+	snap *snapshot
+
+	// scans holds the indexes of the SCAN-family iterations in progress.
+	scans map[scanKey]*scanIndex
 }
 
 // NewStore creates an empty store.
 func NewStore() *Store {
-	return &Store{kv: make(map[string][]byte)}
+	return &Store{kv: make(map[string]any)}
 }
 
-// Get returns value for key.
+// Get returns the string value for key. Keys holding other types are
+// reported as missing.
 func (s *Store) Get(key string) ([]byte, bool) {
 	s.mu.RLock()
-	v, ok := s.kv[key]
+	v, ok := s.kv[key].([]byte)
 	s.mu.RUnlock()
 	return v, ok
 }
 
-// Set stores value for key.
+// Set stores value for key, replacing any existing value of any type.
 func (s *Store) Set(key string, value []byte) {
 	s.mu.Lock()
+	s.preserve([]string{key})
 	s.kv[key] = value
 	s.mu.Unlock()
 }
@@ -43,7 +80,19 @@ func (s *Store) Set(key string, value []byte) {
 func (s *Store) Del(keys ...string) int64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.preserve(keys)
+	return s.del(keys...)
+}
+
+// Incr increments integer value at key and returns new value.
+func (s *Store) Incr(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preserve([]string{key})
+	return s.incr(key)
+}
 
+func (s *Store) del(keys ...string) int64 {
 	deleted := int64(0)
 	for _, key := range keys {
 		if _, ok := s.kv[key]; ok {
@@ -54,12 +103,24 @@ func (s *Store) Del(keys ...string) int64 {
 	return deleted
 }
 
-// Incr increments integer value at key and returns new value.
-func (s *Store) Incr(key string) (int64, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// unlink removes keys like del, passing each removed value to release.
+func (s *Store) unlink(keys []string, release func(any)) int64 {
+	removed := int64(0)
+	for _, key := range keys {
+		if v, ok := s.kv[key]; ok {
+			delete(s.kv, key)
+			release(v)
+			removed++
+		}
+	}
+	return removed
+}
 
-	raw, ok := s.kv[key]
+func (s *Store) incr(key string) (int64, error) {
+	raw, ok, err := s.lookupString(key)
+	if err != nil {
+		return 0, err
+	}
 	if !ok {
 		s.kv[key] = []byte("1")
 		return 1, nil
@@ -69,7 +130,235 @@ func (s *Store) Incr(key string) (int64, error) {
 	if err != nil {
 		return 0, errValueNotInteger
 	}
+	if n == math.MaxInt64 {
+		return 0, errIncrOverflow
+	}
 	n++
 	s.kv[key] = []byte(strconv.FormatInt(n, 10))
 	return n, nil
 }
+
+// lookupString returns the string stored at key.
+func (s *Store) lookupString(key string) ([]byte, bool, error) {
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, false, nil
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, false, errWrongType
+	}
+	return b, true, nil
+}
+
+// growString returns the string stored at key padded with zero bytes to
+// at least size bytes, creating the key if it does not exist.
+func (s *Store) growString(key string, size int) ([]byte, error) {
+	b, _, err := s.lookupString(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.kv[key]; !ok || len(b) < size {
+		b = append(b, make([]byte, size-len(b))...)
+		s.kv[key] = b
+	}
+	return b, nil
+}
+
+// lookupList returns the list stored at key, or nil if the key does not
+// exist.
+func (s *Store) lookupList(key string) (*listValue, error) {
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := v.(*listValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return list, nil
+}
+
+// listForWrite returns the list stored at key, creating an empty one if
+// the key does not exist.
+func (s *Store) listForWrite(key string) (*listValue, error) {
+	list, err := s.lookupList(key)
+	if err != nil || list != nil {
+		return list, err
+	}
+	list = newList()
+	s.kv[key] = list
+	s.created(key)
+	return list, nil
+}
+
+// lookupSet returns the set stored at key, or nil if the key does not exist.
+func (s *Store) lookupSet(key string) (setValue, error) {
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, nil
+	}
+	set, ok := v.(setValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return set, nil
+}
+
+// setForWrite returns the set stored at key, creating an empty one if the
+// key does not exist. Callers must call dropIfEmpty after removing members.
+func (s *Store) setForWrite(key string) (setValue, error) {
+	set, err := s.lookupSet(key)
+	if err != nil || set != nil {
+		return set, err
+	}
+	set = make(setValue)
+	s.kv[key] = set
+	return set, nil
+}
+
+// lookupHash returns the hash stored at key, or nil if the key does not
+// exist.
+func (s *Store) lookupHash(key string) (hashValue, error) {
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, nil
+	}
+	hash, ok := v.(hashValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return hash, nil
+}
+
+// hashForWrite returns the hash stored at key, creating an empty one if
+// the key does not exist.
+func (s *Store) hashForWrite(key string) (hashValue, error) {
+	hash, err := s.lookupHash(key)
+	if err != nil || hash != nil {
+		return hash, err
+	}
+	hash = make(hashValue)
+	s.kv[key] = hash
+	return hash, nil
+}
+
+// lookupZSet returns the sorted set stored at key, or nil if the key does
+// not exist.
+func (s *Store) lookupZSet(key string) (*zsetValue, error) {
+	v, ok := s.kv[key]
+	if !ok {
+		return nil, nil
+	}
+	zset, ok := v.(*zsetValue)
+	if !ok {
+		return nil, errWrongType
+	}
+	return zset, nil
+}
+
+// zsetForWrite returns the sorted set stored at key, creating an empty one
+// if the key does not exist.
+func (s *Store) zsetForWrite(key string) (*zsetValue, error) {
+	zset, err := s.lookupZSet(key)
+	if err != nil || zset != nil {
+		return zset, err
+	}
+	zset = newZSet()
+	s.kv[key] = zset
+	s.created(key)
+	return zset, nil
+}
+
+func (s *Store) created(key string) {
+	if s.keyCreated != nil {
+		s.keyCreated(key)
+	}
+}
+
+// dropIfEmpty deletes key if it holds an empty collection. Redis never
+// keeps empty aggregate values around.
+func (s *Store) dropIfEmpty(key string) {
+	switch v := s.kv[key].(type) {
+	case *listValue:
+		if v.len() == 0 {
+			delete(s.kv, key)
+		}
+	case setValue:
+		if len(v) == 0 {
+			delete(s.kv, key)
+		}
+	case hashValue:
+		if len(v) == 0 {
+			delete(s.kv, key)
+		}
+	case *zsetValue:
+		if v.len() == 0 {
+			delete(s.kv, key)
+		}
+	}
+}
+
+// copyValue returns a deep copy of a stored value.
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return append([]byte(nil), v...)
+	case *listValue:
+		return &listValue{items: slices.Clone(v.items[v.head:])}
+	case setValue:
+		return maps.Clone(v)
+	case hashValue:
+		return maps.Clone(v)
+	case *zsetValue:
+		return &zsetValue{scores: maps.Clone(v.scores), order: slices.Clone(v.order)}
+	default:
+		panic(fmt.Sprintf("redismvp: cannot copy %T", v))
+	}
+}
+
+// typeName returns the TYPE reply for a stored value.
+func typeName(v any) string {
+	switch v.(type) {
+	case []byte:
+		return "string"
+	case *listValue:
+		return "list"
+	case setValue:
+		return "set"
+	case hashValue:
+		return "hash"
+	case *zsetValue:
+		return "zset"
+	default:
+		return "none"
+	}
+}
+
+// maxRandomRepeat bounds the negative count of SRANDMEMBER and HRANDFIELD,
+// which may repeat members and so is not limited by the size of the
+// collection. Redis streams such replies; here they are built in memory,
+// so a client must not be able to ask for billions of entries.
+const maxRandomRepeat = 1 << 20
+
+// randomKeys returns n keys of m chosen uniformly at random. Without
+// repeat, n must not exceed len(m) and the keys are distinct.
+func randomKeys[V any](m map[string]V, n int, repeat bool) []string {
+	all := make([]string, 0, len(m))
+	for k := range m {
+		all = append(all, k)
+	}
+	out := make([]string, n)
+	if repeat {
+		for i := range out {
+			out[i] = all[rand.IntN(len(all))]
+		}
+		return out
+	}
+	for i := range out {
+		j := i + rand.IntN(len(all)-i)
+		all[i], all[j] = all[j], all[i]
+		out[i] = all[i]
+	}
+	return out
+}
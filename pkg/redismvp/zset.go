@@ -0,0 +1,270 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redismvp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// zsetEntry is one member of a sorted set.
+type zsetEntry struct {
+	member string
+	score  float64
+}
+
+// less orders entries by score, then by member, as Redis does.
+func (e zsetEntry) less(score float64, member string) bool {
+	if e.score != score {
+		return e.score < score
+	}
+	return e.member < member
+}
+
+// zsetValue is the in-memory representation of a Redis sorted set: a score
+// index plus a slice kept in (score, member) order. Inserts and removals
+// shift the slice, which is cheaper than a skiplist at the sizes this
+// server targets.
+type zsetValue struct {
+	scores map[string]float64
+	order  []zsetEntry
+}
+
+func newZSet() *zsetValue {
+	return &zsetValue{scores: make(map[string]float64)}
+}
+
+func (z *zsetValue) len() int {
+	if z == nil {
+		return 0
+	}
+	return len(z.order)
+}
+
+func (z *zsetValue) score(member string) (float64, bool) {
+	if z == nil {
+		return 0, false
+	}
+	s, ok := z.scores[member]
+	return s, ok
+}
+
+// search returns the index of the first entry not less than (score, member).
+func (z *zsetValue) search(score float64, member string) int {
+	return sort.Search(len(z.order), func(i int) bool {
+		return !z.order[i].less(score, member)
+	})
+}
+
+// add sets member's score and reports whether member is new.
+func (z *zsetValue) add(member string, score float64) bool {
+	old, exists := z.scores[member]
+	if exists {
+		if old == score {
+			return false
+		}
+		z.removeAt(z.search(old, member))
+	}
+	z.scores[member] = score
+	i := z.search(score, member)
+	z.order = append(z.order, zsetEntry{})
+	copy(z.order[i+1:], z.order[i:])
+	z.order[i] = zsetEntry{member: member, score: score}
+	return !exists
+}
+
+// remove deletes member and reports whether it was present.
+func (z *zsetValue) remove(member string) bool {
+	score, ok := z.scores[member]
+	if !ok {
+		return false
+	}
+	z.removeAt(z.search(score, member))
+	delete(z.scores, member)
+	return true
+}
+
+func (z *zsetValue) removeAt(i int) {
+	copy(z.order[i:], z.order[i+1:])
+	z.order = z.order[:len(z.order)-1]
+}
+
+// rank returns the 0-based position of member in ascending order.
+func (z *zsetValue) rank(member string) (int, bool) {
+	score, ok := z.score(member)
+	if !ok {
+		return 0, false
+	}
+	return z.search(score, member), true
+}
+
+// pop removes and returns up to n entries from the low end, or from the
+// high end when fromMax is set.
+func (z *zsetValue) pop(n int, fromMax bool) []zsetEntry {
+	n = min(n, len(z.order))
+	out := make([]zsetEntry, n)
+	if fromMax {
+		for i := range out {
+			out[i] = z.order[len(z.order)-1-i]
+		}
+		z.order = z.order[:len(z.order)-n]
+	} else {
+		copy(out, z.order[:n])
+		z.order = append(z.order[:0], z.order[n:]...)
+	}
+	for _, e := range out {
+		delete(z.scores, e.member)
+	}
+	return out
+}
+
+// scoreRange is a parsed ZRANGEBYSCORE-style interval.
+type scoreRange struct {
+	min, max     float64
+	minEx, maxEx bool
+}
+
+// parseScoreRange parses min and max bounds such as "1", "(1" or "-inf".
+func parseScoreRange(minArg, maxArg []byte) (scoreRange, bool) {
+	var r scoreRange
+	var ok bool
+	if r.min, r.minEx, ok = parseScoreBound(minArg); !ok {
+		return r, false
+	}
+	if r.max, r.maxEx, ok = parseScoreBound(maxArg); !ok {
+		return r, false
+	}
+	return r, true
+}
+
+func parseScoreBound(arg []byte) (float64, bool, bool) {
+	exclusive := len(arg) > 0 && arg[0] == '('
+	if exclusive {
+		arg = arg[1:]
+	}
+	f, ok := parseScore(arg)
+	return f, exclusive, ok
+}
+
+func (r scoreRange) aboveMin(score float64) bool {
+	if r.minEx {
+		return score > r.min
+	}
+	return score >= r.min
+}
+
+func (r scoreRange) belowMax(score float64) bool {
+	if r.maxEx {
+		return score < r.max
+	}
+	return score <= r.max
+}
+
+// scoreIndexes returns the half-open index range of entries inside r.
+func (z *zsetValue) scoreIndexes(r scoreRange) (int, int) {
+	start := sort.Search(len(z.order), func(i int) bool { return r.aboveMin(z.order[i].score) })
+	end := sort.Search(len(z.order), func(i int) bool { return !r.belowMax(z.order[i].score) })
+	return start, max(start, end)
+}
+
+// lexRange is a parsed ZRANGEBYLEX-style interval. minInf and maxInf
+// stand for the "-" and "+" bounds.
+type lexRange struct {
+	min, max       string
+	minEx, maxEx   bool
+	minInf, maxInf bool
+}
+
+func parseLexRange(minArg, maxArg []byte) (lexRange, bool) {
+	var r lexRange
+	var ok bool
+	if r.min, r.minEx, r.minInf, ok = parseLexBound(minArg, '-'); !ok {
+		return r, false
+	}
+	if r.max, r.maxEx, r.maxInf, ok = parseLexBound(maxArg, '+'); !ok {
+		return r, false
+	}
+	return r, true
+}
+
+func parseLexBound(arg []byte, inf byte) (string, bool, bool, bool) {
+	if len(arg) == 0 {
+		return "", false, false, false
+	}
+	switch arg[0] {
+	case inf:
+		return "", false, true, len(arg) == 1
+	case '(':
+		return string(arg[1:]), true, false, true
+	case '[':
+		return string(arg[1:]), false, false, true
+	default:
+		return "", false, false, false
+	}
+}
+
+func (r lexRange) aboveMin(member string) bool {
+	switch {
+	case r.minInf:
+		return true
+	case r.minEx:
+		return member > r.min
+	default:
+		return member >= r.min
+	}
+}
+
+func (r lexRange) belowMax(member string) bool {
+	switch {
+	case r.maxInf:
+		return true
+	case r.maxEx:
+		return member < r.max
+	default:
+		return member <= r.max
+	}
+}
+
+// lexIndexes returns the half-open index range of entries inside r. Like
+// Redis, the result is only meaningful when all members share one score.
+func (z *zsetValue) lexIndexes(r lexRange) (int, int) {
+	start := sort.Search(len(z.order), func(i int) bool { return r.aboveMin(z.order[i].member) })
+	end := sort.Search(len(z.order), func(i int) bool { return !r.belowMax(z.order[i].member) })
+	return start, max(start, end)
+}
+
+// parseScore parses a score argument, accepting "inf", "+inf" and "-inf"
+// in any case but rejecting NaN.
+func parseScore(arg []byte) (float64, bool) {
+	switch strings.ToLower(string(arg)) {
+	case "inf", "+inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	}
+	return parseFloat(arg)
+}
+
+// appendScore appends a score in Redis's format: integral scores without
+// a fractional part, "inf" and "-inf" for infinities.
+func appendScore(dst []byte, score float64) []byte {
+	return appendBulk(dst, formatScore(score))
+}
+
+func formatScore(score float64) []byte {
+	switch {
+	case math.IsInf(score, 1):
+		return []byte("inf")
+	case math.IsInf(score, -1):
+		return []byte("-inf")
+	case score == math.Trunc(score) && math.Abs(score) < 1e17:
+		return strconv.AppendInt(nil, int64(score), 10)
+	default:
+		return strconv.AppendFloat(nil, score, 'g', -1, 64)
+	}
+}
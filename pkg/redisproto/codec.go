@@ -0,0 +1,102 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redisproto
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrNoProto is returned by Codec.Negotiate for a protocol version other
+// than 2 or 3. Its text is the Redis HELLO error reply.
+var ErrNoProto = errors.New("NOPROTO sorry, this protocol version is not supported")
+
+// Codec holds the protocol state of one connection: the parser for the
+// frames it receives and the protocol version (2 or 3) of the values it
+// sends. A connection starts in RESP2 and switches once HELLO negotiates
+// RESP3.
+//
+// In RESP2 the RESP3 kinds are sent as their RESP2 counterparts (maps,
+// sets and pushes as flat arrays, booleans as integers, the rest as bulk
+// strings) and attributes are dropped, so callers can build one reply for
+// both versions. In RESP3 a
+// null is sent as "_".
+type Codec struct {
+	parser  *Parser
+	version int
+}
+
+// NewCodec returns a RESP2 codec.
+func NewCodec() *Codec {
+	return &Codec{parser: NewParser(), version: 2}
+}
+
+// Version returns the protocol version values are encoded in.
+func (c *Codec) Version() int {
+	return c.version
+}
+
+// SwitchToRESP3 encodes values in RESP3 from now on.
+func (c *Codec) SwitchToRESP3() {
+	c.version = 3
+}
+
+// Negotiate switches to the protocol version requested by a HELLO
+// protover argument. Otherwise it keeps the current version and returns
+// the error HELLO replies with: ErrNoProto for an unsupported version.
+func (c *Codec) Negotiate(protover []byte) error {
+	switch v, err := strconv.Atoi(string(protover)); {
+	case err != nil:
+		return errors.New("ERR Protocol version is not an integer or out of range")
+	case v == 2:
+		c.version = 2
+	case v == 3:
+		c.SwitchToRESP3()
+	default:
+		return ErrNoProto
+	}
+	return nil
+}
+
+// Feed parses incoming bytes; see Parser.Feed.
+func (c *Codec) Feed(in []byte) ([]Value, error) {
+	return c.parser.Feed(in)
+}
+
+// Buffered returns the number of bytes of an incomplete frame received.
+func (c *Codec) Buffered() int {
+	return c.parser.Buffered()
+}
+
+// AppendEncode appends v encoded in the negotiated version to dst.
+func (c *Codec) AppendEncode(dst []byte, v Value) ([]byte, error) {
+	if c.version == 3 {
+		return appendEncode(dst, v, true)
+	}
+	return appendEncode(dst, downgrade(v), false)
+}
+
+// downgrade returns v with the RESP3 kinds replaced by their RESP2
+// counterparts and attributes removed.
+func downgrade(v Value) Value {
+	switch v.Kind {
+	case KindBigNumber, KindDouble:
+		return Value{Kind: KindBulkString, Bulk: []byte(v.Str)}
+	case KindVerbatimString:
+		return Value{Kind: KindBulkString, Bulk: v.Bulk}
+	case KindBoolean:
+		return Value{Kind: KindInteger, Int: v.Int}
+	case KindArray, KindMap, KindSet, KindPush:
+		arr := make([]Value, len(v.Array))
+		for i, item := range v.Array {
+			arr[i] = downgrade(item)
+		}
+		return Value{Kind: KindArray, Array: arr}
+	default:
+		v.Attrs = nil
+		return v
+	}
+}
@@ -10,16 +10,30 @@ import (
 	"strconv"
 )
 
-// Encode serializes a single RESP2 value.
+// Encode serializes a single RESP value.
 func Encode(v Value) ([]byte, error) {
 	return AppendEncode(nil, v)
 }
 
-// AppendEncode appends serialized bytes for v into dst.
+// AppendEncode appends serialized bytes for v into dst. Values are
+// encoded as given, with RESP2 nulls; a Codec adapts them to the
+// negotiated protocol version.
 func AppendEncode(dst []byte, v Value) ([]byte, error) {
+	return appendEncode(dst, v, false)
+}
+
+// appendEncode encodes v, with nulls in the RESP3 form if resp3Null is set.
+func appendEncode(dst []byte, v Value, resp3Null bool) ([]byte, error) {
 	if err := v.validateForEncode(); err != nil {
 		return nil, err
 	}
+	if len(v.Attrs) > 0 {
+		var err error
+		dst, err = appendAggregate(dst, '|', len(v.Attrs)/2, v.Attrs, resp3Null)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	switch v.Kind {
 	case KindSimpleString:
@@ -45,20 +59,59 @@ func AppendEncode(dst []byte, v Value) ([]byte, error) {
 		dst = append(dst, '\r', '\n')
 		return dst, nil
 	case KindArray:
-		dst = append(dst, '*')
-		dst = strconv.AppendInt(dst, int64(len(v.Array)), 10)
+		return appendAggregate(dst, '*', len(v.Array), v.Array, resp3Null)
+	case KindMap:
+		return appendAggregate(dst, '%', len(v.Array)/2, v.Array, resp3Null)
+	case KindSet:
+		return appendAggregate(dst, '~', len(v.Array), v.Array, resp3Null)
+	case KindPush:
+		return appendAggregate(dst, '>', len(v.Array), v.Array, resp3Null)
+	case KindDouble:
+		dst = append(dst, ',')
+		dst = append(dst, v.Str...)
 		dst = append(dst, '\r', '\n')
-		for _, item := range v.Array {
-			var err error
-			dst, err = AppendEncode(dst, item)
-			if err != nil {
-				return nil, err
-			}
-		}
 		return dst, nil
+	case KindBoolean:
+		if v.Int == 1 {
+			return append(dst, '#', 't', '\r', '\n'), nil
+		}
+		return append(dst, '#', 'f', '\r', '\n'), nil
 	case KindNull:
+		if resp3Null {
+			return append(dst, '_', '\r', '\n'), nil
+		}
 		return append(dst, '$', '-', '1', '\r', '\n'), nil
+	case KindBigNumber:
+		dst = append(dst, '(')
+		dst = append(dst, v.Str...)
+		dst = append(dst, '\r', '\n')
+		return dst, nil
+	case KindVerbatimString:
+		dst = append(dst, '=')
+		dst = strconv.AppendInt(dst, int64(len(v.Str)+1+len(v.Bulk)), 10)
+		dst = append(dst, '\r', '\n')
+		dst = append(dst, v.Str...)
+		dst = append(dst, ':')
+		dst = append(dst, v.Bulk...)
+		dst = append(dst, '\r', '\n')
+		return dst, nil
 	default:
 		return nil, fmt.Errorf("unsupported kind: %s", v.Kind)
 	}
 }
+
+// appendAggregate appends the header of an aggregate of n entries and its
+// items.
+func appendAggregate(dst []byte, prefix byte, n int, items []Value, resp3Null bool) ([]byte, error) {
+	dst = append(dst, prefix)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	dst = append(dst, '\r', '\n')
+	for _, item := range items {
+		var err error
+		dst, err = appendEncode(dst, item, resp3Null)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
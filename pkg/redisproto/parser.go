@@ -15,7 +15,7 @@ const defaultMaxBulkLen = 512 << 20 // 512 MiB
 const defaultMaxArrayLen = 1 << 20  // 1M elements
 const defaultMaxDepth = 64
 
-// Parser incrementally parses RESP2 frames from streaming input.
+// Parser incrementally parses RESP2 and RESP3 frames from streaming input.
 type Parser struct {
 	buf         []byte
 	maxBulkLen  int
@@ -68,6 +68,12 @@ func (p *Parser) Feed(in []byte) ([]Value, error) {
 	return out, nil
 }
 
+// Buffered returns the number of bytes of an incomplete frame kept from
+// previous calls to Feed.
+func (p *Parser) Buffered() int {
+	return len(p.buf)
+}
+
 func (p *Parser) parseAt(data []byte, offset, depth int) (Value, int, bool, error) {
 	if depth > p.maxDepth {
 		return Value{}, 0, false, fmt.Errorf("array nesting exceeds max depth %d", p.maxDepth)
@@ -112,23 +118,109 @@ func (p *Parser) parseAt(data []byte, offset, depth int) (Value, int, bool, erro
 		if n < -1 {
 			return Value{}, 0, false, fmt.Errorf("negative bulk string length: %d", n)
 		}
-		if n > int64(p.maxBulkLen) {
-			return Value{}, 0, false, fmt.Errorf("bulk string length %d exceeds limit %d", n, p.maxBulkLen)
+		bulk, need, complete, err := p.readBlob(data, next, n, "bulk string")
+		if !complete || err != nil {
+			return Value{}, 0, false, err
 		}
-
-		need := next + int(n) + 2
-		if need > len(data) {
+		return Value{Kind: KindBulkString, Bulk: bulk}, need, true, nil
+	case '_':
+		line, next, ok := readLine(data, offset)
+		if !ok {
 			return Value{}, 0, false, nil
 		}
-		if data[next+int(n)] != '\r' || data[next+int(n)+1] != '\n' {
-			return Value{}, 0, false, fmt.Errorf("bulk string missing CRLF terminator")
+		if len(line) != 0 {
+			return Value{}, 0, false, fmt.Errorf("invalid null %q", string(line))
 		}
-
-		bulk := append([]byte(nil), data[next:next+int(n)]...)
-		if n == 0 {
-			bulk = []byte{}
+		return Value{Kind: KindNull}, next, true, nil
+	case '(':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		if !isBigNumber(string(line)) {
+			return Value{}, 0, false, fmt.Errorf("invalid big number %q", string(line))
+		}
+		return Value{Kind: KindBigNumber, Str: string(line)}, next, true, nil
+	case '=':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		n, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil || n < 0 {
+			return Value{}, 0, false, fmt.Errorf("invalid verbatim string length %q", string(line))
+		}
+		blob, need, complete, err := p.readBlob(data, next, n, "verbatim string")
+		if !complete || err != nil {
+			return Value{}, 0, false, err
+		}
+		if len(blob) < 4 || blob[3] != ':' {
+			return Value{}, 0, false, fmt.Errorf("verbatim string missing format prefix")
+		}
+		return Value{Kind: KindVerbatimString, Str: string(blob[:3]), Bulk: blob[4:]}, need, true, nil
+	case '%', '~', '>':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		kind, what, width := KindMap, "map", int64(2)
+		switch prefix {
+		case '~':
+			kind, what, width = KindSet, "set", 1
+		case '>':
+			kind, what, width = KindPush, "push", 1
+		}
+		n, err := p.aggregateLen(line, what, width)
+		if err != nil {
+			return Value{}, 0, false, err
+		}
+		items, end, complete, err := p.parseItems(data, next, n, depth)
+		if err != nil || !complete {
+			return Value{}, 0, false, err
+		}
+		return Value{Kind: kind, Array: items}, end, true, nil
+	case '|':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		n, err := p.aggregateLen(line, "attribute", 2)
+		if err != nil {
+			return Value{}, 0, false, err
+		}
+		attrs, cursor, complete, err := p.parseItems(data, next, n, depth)
+		if err != nil || !complete {
+			return Value{}, 0, false, err
+		}
+		// The attribute describes the value that follows it.
+		v, end, complete, err := p.parseAt(data, cursor, depth)
+		if err != nil || !complete {
+			return Value{}, 0, false, err
+		}
+		v.Attrs = append(attrs, v.Attrs...)
+		return v, end, true, nil
+	case ',':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		if _, err := strconv.ParseFloat(string(line), 64); err != nil {
+			return Value{}, 0, false, fmt.Errorf("invalid double %q", string(line))
+		}
+		return Value{Kind: KindDouble, Str: string(line)}, next, true, nil
+	case '#':
+		line, next, ok := readLine(data, offset)
+		if !ok {
+			return Value{}, 0, false, nil
+		}
+		switch string(line) {
+		case "t":
+			return Value{Kind: KindBoolean, Int: 1}, next, true, nil
+		case "f":
+			return Value{Kind: KindBoolean}, next, true, nil
+		default:
+			return Value{}, 0, false, fmt.Errorf("invalid boolean %q", string(line))
 		}
-		return Value{Kind: KindBulkString, Bulk: bulk}, need, true, nil
 	case '*':
 		line, next, ok := readLine(data, offset)
 		if !ok {
@@ -139,6 +231,11 @@ func (p *Parser) parseAt(data []byte, offset, depth int) (Value, int, bool, erro
 		if err != nil {
 			return Value{}, 0, false, fmt.Errorf("invalid array length %q: %w", string(line), err)
 		}
+		// RESP2 has a null array ("*-1") besides the null bulk string; both
+		// decode to KindNull.
+		if n == -1 {
+			return Value{Kind: KindNull}, next, true, nil
+		}
 		if n < 0 {
 			return Value{}, 0, false, fmt.Errorf("negative array length: %d", n)
 		}
@@ -146,18 +243,9 @@ func (p *Parser) parseAt(data []byte, offset, depth int) (Value, int, bool, erro
 			return Value{}, 0, false, fmt.Errorf("array length %d exceeds limit %d", n, p.maxArrayLen)
 		}
 
-		arr := make([]Value, 0, int(n))
-		cursor := next
-		for i := int64(0); i < n; i++ {
-			item, itemNext, complete, parseErr := p.parseAt(data, cursor, depth+1)
-			if parseErr != nil {
-				return Value{}, 0, false, parseErr
-			}
-			if !complete {
-				return Value{}, 0, false, nil
-			}
-			arr = append(arr, item)
-			cursor = itemNext
+		arr, cursor, complete, err := p.parseItems(data, next, n, depth)
+		if err != nil || !complete {
+			return Value{}, 0, false, err
 		}
 		return Value{Kind: KindArray, Array: arr}, cursor, true, nil
 	default:
@@ -165,6 +253,54 @@ func (p *Parser) parseAt(data []byte, offset, depth int) (Value, int, bool, erro
 	}
 }
 
+// aggregateLen parses the header of a RESP3 aggregate whose entries are
+// width values each, and returns the number of values that follow.
+func (p *Parser) aggregateLen(line []byte, what string, width int64) (int64, error) {
+	n, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s length %q", what, string(line))
+	}
+	if n > int64(p.maxArrayLen)/width {
+		return 0, fmt.Errorf("%s length %d exceeds limit %d", what, n, int64(p.maxArrayLen)/width)
+	}
+	return n * width, nil
+}
+
+// parseItems parses the n values of an aggregate at depth, starting at
+// offset.
+func (p *Parser) parseItems(data []byte, offset int, n int64, depth int) ([]Value, int, bool, error) {
+	items := make([]Value, 0, int(n))
+	for i := int64(0); i < n; i++ {
+		item, next, complete, err := p.parseAt(data, offset, depth+1)
+		if err != nil || !complete {
+			return nil, 0, false, err
+		}
+		items = append(items, item)
+		offset = next
+	}
+	return items, offset, true, nil
+}
+
+// readBlob reads the n byte payload of a length-prefixed string starting
+// at offset, and returns a copy and the offset past its CRLF.
+func (p *Parser) readBlob(data []byte, offset int, n int64, what string) ([]byte, int, bool, error) {
+	if n > int64(p.maxBulkLen) {
+		return nil, 0, false, fmt.Errorf("%s length %d exceeds limit %d", what, n, p.maxBulkLen)
+	}
+	need := offset + int(n) + 2
+	if need > len(data) {
+		return nil, 0, false, nil
+	}
+	if data[offset+int(n)] != '\r' || data[offset+int(n)+1] != '\n' {
+		return nil, 0, false, fmt.Errorf("%s missing CRLF terminator", what)
+	}
+	blob := append([]byte(nil), data[offset:offset+int(n)]...)
+	if n == 0 {
+		blob = []byte{}
+	}
+	return blob, need, true, nil
+}
+
 func readLine(data []byte, offset int) ([]byte, int, bool) {
 	if offset >= len(data) {
 		return nil, 0, false
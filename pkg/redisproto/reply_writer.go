@@ -0,0 +1,96 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redisproto
+
+// FlushPolicy bounds a batch of replies held by a ReplyWriter. A batch is
+// flushed once it holds MaxBytes bytes or MaxReplies replies; a zero field
+// sets no bound. A single reply larger than MaxBytes is flushed whole.
+type FlushPolicy struct {
+	MaxBytes   int
+	MaxReplies int
+}
+
+// DefaultFlushPolicy flushes every 64 KiB, the amount Redis writes to a
+// client per event, or every 1024 replies.
+var DefaultFlushPolicy = FlushPolicy{MaxBytes: 64 << 10, MaxReplies: 1024}
+
+// ReplyWriter batches encoded replies to a pipelining client, so that a
+// read carrying many commands is answered with few writes, without
+// buffering an unbounded amount of output when the commands are many or
+// their replies large.
+//
+// Replies are appended whole: the caller appends to Buf and hands the
+// result to Commit, which flushes the batch once it crosses the policy's
+// bounds. The caller calls Flush at the end of each batch of input, such
+// as one read or loop tick, so replies are never held back waiting for
+// more commands.
+type ReplyWriter struct {
+	write   func([]byte) error
+	policy  FlushPolicy
+	buf     []byte
+	replies int
+	err     error
+}
+
+// NewReplyWriter returns a writer sending batches through write, which
+// must write all of p or return an error.
+func NewReplyWriter(write func(p []byte) error, policy FlushPolicy) *ReplyWriter {
+	return &ReplyWriter{write: write, policy: policy}
+}
+
+// Buf returns the pending batch, for the caller to append a reply to and
+// pass to Commit.
+func (w *ReplyWriter) Buf() []byte {
+	return w.buf
+}
+
+// Commit makes b, which is Buf with zero or more replies appended, the
+// pending batch, and flushes it if it crossed the policy's bounds. It
+// counts one reply if b grew. It returns the error of the flush, or of an
+// earlier one; after an error, batches are discarded instead of written.
+func (w *ReplyWriter) Commit(b []byte) error {
+	if len(b) > len(w.buf) {
+		w.replies++
+	}
+	w.buf = b
+	p := w.policy
+	if (p.MaxBytes > 0 && len(w.buf) >= p.MaxBytes) || (p.MaxReplies > 0 && w.replies >= p.MaxReplies) {
+		return w.Flush()
+	}
+	return w.err
+}
+
+// Append commits reply as one encoded reply.
+func (w *ReplyWriter) Append(reply []byte) error {
+	if len(reply) == 0 {
+		return w.err
+	}
+	return w.Commit(append(w.buf, reply...))
+}
+
+// Flush writes the pending batch, if any.
+func (w *ReplyWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return w.err
+	}
+	if w.err == nil {
+		w.err = w.write(w.buf)
+	}
+	// Keep the buffer for the next batch unless one huge reply grew it
+	// well past what batches normally need.
+	if w.policy.MaxBytes > 0 && cap(w.buf) > 4*w.policy.MaxBytes {
+		w.buf = nil
+	} else {
+		w.buf = w.buf[:0]
+	}
+	w.replies = 0
+	return w.err
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (w *ReplyWriter) Buffered() int {
+	return len(w.buf)
+}
@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redisproto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReplyWriterFlushPolicy(t *testing.T) {
+	var writes []string
+	w := NewReplyWriter(func(p []byte) error {
+		writes = append(writes, string(p))
+		return nil
+	}, FlushPolicy{MaxBytes: 16, MaxReplies: 3})
+
+	// The reply bound flushes after the third reply.
+	for range 3 {
+		if err := w.Commit(append(w.Buf(), ":1\r\n"...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(writes) != 1 || writes[0] != ":1\r\n:1\r\n:1\r\n" || w.Buffered() != 0 {
+		t.Fatalf("writes %q, %d bytes buffered", writes, w.Buffered())
+	}
+
+	// The byte bound flushes once a reply crosses it, and replies are
+	// never split.
+	_ = w.Append([]byte("+0123456789\r\n"))
+	if len(writes) != 1 {
+		t.Fatalf("flushed below the byte bound: %q", writes)
+	}
+	_ = w.Append([]byte("+abcdef\r\n"))
+	if len(writes) != 2 || writes[1] != "+0123456789\r\n+abcdef\r\n" {
+		t.Fatalf("writes %q", writes)
+	}
+
+	// A commit appending nothing, like a command that blocked, counts no
+	// reply, and an explicit flush sends what is pending.
+	_ = w.Commit(w.Buf())
+	_ = w.Commit(append(w.Buf(), ":2\r\n"...))
+	_ = w.Commit(w.Buf())
+	if len(writes) != 2 {
+		t.Fatalf("empty commits counted as replies: %q", writes)
+	}
+	if err := w.Flush(); err != nil || len(writes) != 3 || writes[2] != ":2\r\n" {
+		t.Fatalf("flush: %v, writes %q", err, writes)
+	}
+	if err := w.Flush(); err != nil || len(writes) != 3 {
+		t.Fatalf("flushing nothing wrote: %v, %q", err, writes)
+	}
+
+	// A reply far larger than the bound does not pin its buffer.
+	_ = w.Append([]byte("$100\r\n" + strings.Repeat("x", 100) + "\r\n"))
+	if len(writes) != 4 || cap(w.Buf()) != 0 {
+		t.Fatalf("%d writes, buffer cap %d after a large reply", len(writes), cap(w.Buf()))
+	}
+}
+
+func TestReplyWriterError(t *testing.T) {
+	errClosed := errors.New("closed")
+	writes := 0
+	w := NewReplyWriter(func([]byte) error {
+		writes++
+		return errClosed
+	}, FlushPolicy{MaxReplies: 1})
+
+	if err := w.Append([]byte("+OK\r\n")); !errors.Is(err, errClosed) {
+		t.Fatalf("Append: %v", err)
+	}
+	// Later batches are dropped with the same error.
+	if err := w.Append([]byte("+OK\r\n")); !errors.Is(err, errClosed) || writes != 1 || w.Buffered() != 0 {
+		t.Fatalf("Append after error: %v, %d writes, %d buffered", err, writes, w.Buffered())
+	}
+}
@@ -27,6 +27,12 @@ func TestParserPartialAndMultiFrame(t *testing.T) {
 			t.Fatalf("feed %d failed: %v", i, err)
 		}
 		all = append(all, out...)
+		if i == 0 && parser.Buffered() != len(":12") {
+			t.Fatalf("buffered %d bytes of the partial frame, want %d", parser.Buffered(), len(":12"))
+		}
+	}
+	if parser.Buffered() != 0 {
+		t.Fatalf("buffered %d bytes after complete frames", parser.Buffered())
 	}
 
 	want := []Value{
@@ -41,7 +47,7 @@ func TestParserPartialAndMultiFrame(t *testing.T) {
 
 func TestParserArrayAndNull(t *testing.T) {
 	parser := NewParser()
-	resp := "*4\r\n$3\r\nGET\r\n$3\r\nkey\r\n$-1\r\n:1\r\n"
+	resp := "*5\r\n$3\r\nGET\r\n$3\r\nkey\r\n$-1\r\n*-1\r\n:1\r\n"
 	out, err := parser.Feed([]byte(resp))
 	if err != nil {
 		t.Fatalf("feed failed: %v", err)
@@ -54,6 +60,7 @@ func TestParserArrayAndNull(t *testing.T) {
 		{Kind: KindBulkString, Bulk: []byte("GET")},
 		{Kind: KindBulkString, Bulk: []byte("key")},
 		{Kind: KindNull},
+		{Kind: KindNull},
 		{Kind: KindInteger, Int: 1},
 	}}
 	if !reflect.DeepEqual(out[0], want) {
@@ -0,0 +1,183 @@
+/*
+ * MIT License
+ * Copyright (c) 2026 Crrow
+ */
+
+package redisproto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseRESP3Kinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{name: "big number", input: "(3492890328409238509324850943850943825024385\r\n",
+			want: Value{Kind: KindBigNumber, Str: "3492890328409238509324850943850943825024385"}},
+		{name: "negative big number", input: "(-12\r\n", want: Value{Kind: KindBigNumber, Str: "-12"}},
+		{name: "verbatim", input: "=15\r\ntxt:Some string\r\n",
+			want: Value{Kind: KindVerbatimString, Str: "txt", Bulk: []byte("Some string")}},
+		{name: "empty verbatim", input: "=4\r\nmkd:\r\n",
+			want: Value{Kind: KindVerbatimString, Str: "mkd", Bulk: []byte{}}},
+		{name: "attribute", input: "|1\r\n+key-popularity\r\n*2\r\n$1\r\na\r\n:100\r\n*1\r\n:2039123\r\n",
+			want: Value{
+				Kind:  KindArray,
+				Array: []Value{{Kind: KindInteger, Int: 2039123}},
+				Attrs: []Value{
+					{Kind: KindSimpleString, Str: "key-popularity"},
+					{Kind: KindArray, Array: []Value{{Kind: KindBulkString, Bulk: []byte("a")}, {Kind: KindInteger, Int: 100}}},
+				},
+			}},
+		{name: "attribute inside array", input: "*2\r\n|1\r\n+ttl\r\n:3600\r\n$1\r\nv\r\n:1\r\n",
+			want: Value{Kind: KindArray, Array: []Value{
+				{Kind: KindBulkString, Bulk: []byte("v"), Attrs: []Value{{Kind: KindSimpleString, Str: "ttl"}, {Kind: KindInteger, Int: 3600}}},
+				{Kind: KindInteger, Int: 1},
+			}}},
+		{name: "map", input: "%2\r\n+first\r\n:1\r\n+second\r\n~1\r\n#t\r\n",
+			want: Value{Kind: KindMap, Array: []Value{
+				{Kind: KindSimpleString, Str: "first"}, {Kind: KindInteger, Int: 1},
+				{Kind: KindSimpleString, Str: "second"}, {Kind: KindSet, Array: []Value{{Kind: KindBoolean, Int: 1}}},
+			}}},
+		{name: "push", input: ">2\r\n$7\r\nmessage\r\n#f\r\n",
+			want: Value{Kind: KindPush, Array: []Value{{Kind: KindBulkString, Bulk: []byte("message")}, {Kind: KindBoolean}}}},
+		{name: "double", input: ",-1.5e3\r\n", want: Value{Kind: KindDouble, Str: "-1.5e3"}},
+		{name: "infinite double", input: ",inf\r\n", want: Value{Kind: KindDouble, Str: "inf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser().Feed([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("feed failed: %v", err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Fatalf("unexpected frames: got=%#v want=%#v", got, tt.want)
+			}
+			enc, err := Encode(tt.want)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			if string(enc) != tt.input {
+				t.Fatalf("unexpected encoding: got=%q want=%q", enc, tt.input)
+			}
+		})
+	}
+}
+
+// TestParseAttributePartial feeds an attribute and its value a byte at a
+// time: nothing is returned until the value is complete.
+func TestParseAttributePartial(t *testing.T) {
+	input := "|1\r\n+hint\r\n(7\r\n:1\r\n"
+	parser := NewParser()
+	for i := range len(input) - 1 {
+		out, err := parser.Feed([]byte{input[i]})
+		if err != nil || len(out) != 0 {
+			t.Fatalf("feed byte %d: %#v, %v", i, out, err)
+		}
+	}
+	got, err := parser.Feed([]byte{input[len(input)-1]})
+	if err != nil {
+		t.Fatalf("feed failed: %v", err)
+	}
+	want := []Value{{Kind: KindInteger, Int: 1, Attrs: []Value{
+		{Kind: KindSimpleString, Str: "hint"},
+		{Kind: KindBigNumber, Str: "7"},
+	}}}
+	if !reflect.DeepEqual(got, want) || parser.Buffered() != 0 {
+		t.Fatalf("unexpected frames: got=%#v want=%#v", got, want)
+	}
+}
+
+func TestParseRESP3Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		errLike string
+	}{
+		{name: "null with data", input: "_x\r\n", errLike: "invalid null"},
+		{name: "big number with dot", input: "(1.5\r\n", errLike: "invalid big number"},
+		{name: "empty big number", input: "(\r\n", errLike: "invalid big number"},
+		{name: "verbatim without format", input: "=3\r\ntxt\r\n", errLike: "verbatim string missing format prefix"},
+		{name: "verbatim bad length", input: "=-1\r\n", errLike: "invalid verbatim string length"},
+		{name: "verbatim broken tail", input: "=5\r\ntxt:abc", errLike: "verbatim string missing CRLF terminator"},
+		{name: "attribute bad length", input: "|x\r\n", errLike: "invalid attribute length"},
+		{name: "attribute bad value", input: "|1\r\n+k\r\n:v\r\n", errLike: "invalid integer"},
+		{name: "map bad length", input: "%-1\r\n", errLike: "invalid map length"},
+		{name: "set bad length", input: "~x\r\n", errLike: "invalid set length"},
+		{name: "double not a number", input: ",1.2.3\r\n", errLike: "invalid double"},
+		{name: "boolean not t or f", input: "#x\r\n", errLike: "invalid boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().Feed([]byte(tt.input))
+			if !contains(err, tt.errLike) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeRESP3Rejects(t *testing.T) {
+	for _, v := range []Value{
+		{Kind: KindBigNumber, Str: "12a"},
+		{Kind: KindVerbatimString, Str: "text", Bulk: []byte("x")},
+		{Kind: KindVerbatimString, Str: "t:t", Bulk: []byte("x")},
+		{Kind: KindInteger, Int: 1, Attrs: []Value{{Kind: KindSimpleString, Str: "lonely key"}}},
+		{Kind: KindMap, Array: []Value{{Kind: KindSimpleString, Str: "lonely key"}}},
+		{Kind: KindDouble, Str: "one"},
+		{Kind: KindBoolean, Int: 2},
+	} {
+		if _, err := Encode(v); err == nil {
+			t.Fatalf("encoded invalid value %#v", v)
+		}
+	}
+}
+
+func TestCodecVersions(t *testing.T) {
+	reply := Value{Kind: KindArray, Array: []Value{
+		{Kind: KindNull},
+		{Kind: KindBigNumber, Str: "12345678901234567890"},
+		{Kind: KindVerbatimString, Str: "txt", Bulk: []byte("hi"), Attrs: []Value{
+			{Kind: KindSimpleString, Str: "k"}, {Kind: KindInteger, Int: 1},
+		}},
+		{Kind: KindMap, Array: []Value{{Kind: KindDouble, Str: "1.5"}, {Kind: KindBoolean, Int: 1}}},
+	}}
+
+	c := NewCodec()
+	if c.Version() != 2 {
+		t.Fatalf("new codec speaks RESP%d", c.Version())
+	}
+	got, err := c.AppendEncode(nil, reply)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if want := "*4\r\n$-1\r\n$20\r\n12345678901234567890\r\n$2\r\nhi\r\n*2\r\n$3\r\n1.5\r\n:1\r\n"; string(got) != want {
+		t.Fatalf("RESP2 encoding: got=%q want=%q", got, want)
+	}
+
+	if err := c.Negotiate([]byte("4")); !errors.Is(err, ErrNoProto) || c.Version() != 2 {
+		t.Fatalf("negotiate 4: %v, version %d", err, c.Version())
+	}
+	if err := c.Negotiate([]byte("x")); err == nil {
+		t.Fatal("negotiated a non-integer version")
+	}
+	if err := c.Negotiate([]byte("3")); err != nil || c.Version() != 3 {
+		t.Fatalf("negotiate 3: %v, version %d", err, c.Version())
+	}
+	got, err = c.AppendEncode(nil, reply)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	want := "*4\r\n_\r\n(12345678901234567890\r\n|1\r\n+k\r\n:1\r\n=6\r\ntxt:hi\r\n%1\r\n,1.5\r\n#t\r\n"
+	if string(got) != want {
+		t.Fatalf("RESP3 encoding: got=%q want=%q", got, want)
+	}
+	frames, err := c.Feed(got)
+	if err != nil || len(frames) != 1 || !reflect.DeepEqual(frames[0], reply) {
+		t.Fatalf("parse RESP3 encoding: %#v, %v", frames, err)
+	}
+}
@@ -5,9 +5,14 @@
 
 package redisproto
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
-// Kind identifies RESP2 value types supported by MVP.
+// Kind identifies RESP value types supported by MVP: the RESP2 types and
+// the RESP3 ones except blob errors.
 type Kind int
 
 const (
@@ -17,15 +22,32 @@ const (
 	KindBulkString
 	KindArray
 	KindNull
+	KindBigNumber
+	KindVerbatimString
+	KindMap
+	KindSet
+	KindPush
+	KindDouble
+	KindBoolean
 )
 
-// Value is a typed RESP2 value.
+// Value is a typed RESP value. Str holds simple strings, errors, the
+// decimal digits of big numbers, doubles as sent ("1.5", "inf") and the
+// three letter format of verbatim strings ("txt", "mkd"); Bulk holds the
+// payload of bulk and verbatim strings. Int holds integers and booleans
+// (0 or 1). Array holds the elements of arrays, sets and pushes, and maps
+// as alternating keys and values.
+//
+// Attrs holds the RESP3 attribute frame ("|") that preceded the value, as
+// alternating keys and values. Attributes carry out-of-band hints a
+// reader may ignore; they are encoded again in front of the value.
 type Value struct {
 	Kind  Kind
 	Str   string
 	Int   int64
 	Bulk  []byte
 	Array []Value
+	Attrs []Value
 }
 
 func (k Kind) String() string {
@@ -42,25 +64,84 @@ func (k Kind) String() string {
 		return "array"
 	case KindNull:
 		return "null"
+	case KindBigNumber:
+		return "big_number"
+	case KindVerbatimString:
+		return "verbatim_string"
+	case KindMap:
+		return "map"
+	case KindSet:
+		return "set"
+	case KindPush:
+		return "push"
+	case KindDouble:
+		return "double"
+	case KindBoolean:
+		return "boolean"
 	default:
 		return "unknown"
 	}
 }
 
 func (v Value) validateForEncode() error {
+	if len(v.Attrs)%2 != 0 {
+		return fmt.Errorf("%s has an attribute key without value", v.Kind)
+	}
 	switch v.Kind {
 	case KindSimpleString, KindError:
 		if hasRESPNewline(v.Str) {
 			return fmt.Errorf("%s contains CR or LF", v.Kind)
 		}
 		return nil
-	case KindInteger, KindBulkString, KindArray, KindNull:
+	case KindBigNumber:
+		if !isBigNumber(v.Str) {
+			return fmt.Errorf("invalid big number %q", v.Str)
+		}
+		return nil
+	case KindVerbatimString:
+		if len(v.Str) != 3 || hasRESPNewline(v.Str) || strings.IndexByte(v.Str, ':') >= 0 {
+			return fmt.Errorf("invalid verbatim string format %q", v.Str)
+		}
+		return nil
+	case KindMap:
+		if len(v.Array)%2 != 0 {
+			return fmt.Errorf("map has a key without value")
+		}
+		return nil
+	case KindDouble:
+		if _, err := strconv.ParseFloat(v.Str, 64); err != nil || hasRESPNewline(v.Str) {
+			return fmt.Errorf("invalid double %q", v.Str)
+		}
+		return nil
+	case KindBoolean:
+		if v.Int != 0 && v.Int != 1 {
+			return fmt.Errorf("invalid boolean %d", v.Int)
+		}
+		return nil
+	case KindInteger, KindBulkString, KindArray, KindNull, KindSet, KindPush:
 		return nil
 	default:
 		return fmt.Errorf("unsupported kind: %d", v.Kind)
 	}
 }
 
+// isBigNumber reports whether s is an optionally signed run of decimal
+// digits.
+func isBigNumber(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func hasRESPNewline(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\r' || s[i] == '\n' {
@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// When accept fails with EMFILE/ENFILE the pending connection stays in the
+// kernel backlog, so re-arming immediately fails again and the loop spins.
+// Two remedies are available as listener options:
+//
+//   - Backoff: stop accepting for a while, doubling the pause while the
+//     condition persists, so other connections get a chance to close.
+//   - Reserve fd: keep one spare descriptor open; on exhaustion, release it,
+//     accept the pending connection and close it right away, then take the
+//     spare back. The client sees a clean close instead of a hang, and the
+//     backlog drains.
+
+// ListenOption configures a [TCPListener] created by [Listen].
+type ListenOption func(*TCPListener)
+
+// WithAcceptBackoff pauses accepting for initial after an EMFILE/ENFILE
+// failure that the handler answered with [Continue], doubling the pause on
+// each consecutive failure up to max. A successful accept resets the pause.
+func WithAcceptBackoff(initial, max time.Duration) ListenOption {
+	return func(l *TCPListener) {
+		if max < initial {
+			max = initial
+		}
+		l.backoffInitial = initial
+		l.backoffMax = max
+	}
+}
+
+// WithReserveFD keeps a spare file descriptor that is given up to accept and
+// immediately close one pending connection whenever accept fails with
+// EMFILE/ENFILE.
+func WithReserveFD() ListenOption {
+	return func(l *TCPListener) {
+		l.reserveFD = openReserveFD()
+	}
+}
+
+func openReserveFD() int {
+	fd, err := syscall.Open("/dev/null", syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1
+	}
+	return fd
+}
+
+// shedPendingConnection uses the reserve fd to drop one queued connection.
+func (l *TCPListener) shedPendingConnection() {
+	if l.reserveFD < 0 {
+		return
+	}
+	closeFD(l.reserveFD)
+	l.reserveFD = -1
+
+	acceptAndClose(int(cxev.TCPFd(&l.tcp)))
+
+	l.reserveFD = openReserveFD()
+}
+
+// acceptAndClose accepts one connection without blocking and closes it.
+// The listener's blocking mode is restored afterwards because the backend
+// may rely on it.
+func acceptAndClose(fd int) {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	if errno != 0 {
+		return
+	}
+	if flags&syscall.O_NONBLOCK == 0 {
+		if err := syscall.SetNonblock(fd, true); err != nil {
+			return
+		}
+		defer func() { _ = syscall.SetNonblock(fd, false) }()
+	}
+	if nfd, _, err := syscall.Accept(fd); err == nil {
+		closeFD(nfd)
+	}
+}
+
+// pauseAccept schedules the accept to be re-armed after the current backoff
+// delay. It returns false if backoff is not configured.
+func (l *TCPListener) pauseAccept() bool {
+	if l.backoffInitial <= 0 {
+		return false
+	}
+	if l.backoffTimer == nil {
+		timer, err := NewTimer()
+		if err != nil {
+			return false
+		}
+		l.backoffTimer = timer
+	}
+
+	delay := l.backoffCurrent
+	if delay == 0 {
+		delay = l.backoffInitial
+	}
+	l.backoffCurrent = min(delay*2, l.backoffMax)
+
+	err := l.backoffTimer.RunFunc(l.loop, delay, func(*Timer, error) Action {
+		if l.handler != nil {
+			l.arm()
+		}
+		return Stop
+	})
+	return err == nil
+}
+
+func (l *TCPListener) resetBackoff() {
+	l.backoffCurrent = 0
+}
+
+func (l *TCPListener) closeBackoff() {
+	if l.backoffTimer != nil {
+		l.backoffTimer.Close()
+		l.backoffTimer = nil
+	}
+	if l.reserveFD >= 0 {
+		closeFD(l.reserveFD)
+		l.reserveFD = -1
+	}
+}
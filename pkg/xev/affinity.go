@@ -0,0 +1,93 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"runtime"
+)
+
+// The Go scheduler moves goroutines between threads and the kernel moves
+// threads between cores, so a loop's caches and its socket's softirq
+// processing can end up on different cores from one event to the next.
+// Locking the goroutine that runs a loop to its thread keeps the loop, and
+// the FFI calls into libxev, on one thread; pinning that thread to one CPU
+// also removes the migration between cores. Only Linux supports pinning;
+// elsewhere the helpers do nothing.
+
+// ErrInvalidCPU is returned when pinning to a CPU the process may not run
+// on.
+var ErrInvalidCPU = errors.New("cpu not available to the process")
+
+// WithCPU makes [Loop.Run] pin its goroutine's thread to cpu while it runs,
+// and restore the thread's previous affinity when it returns. Run fails
+// with [ErrInvalidCPU] if the process may not run on cpu. On systems other
+// than Linux the option has no effect.
+func WithCPU(cpu int) LoopOption {
+	return func(c *loopConfig) {
+		c.cpu = cpu
+		c.pin = true
+	}
+}
+
+// WithLockOSThread makes [Loop.Run] and [Loop.RunContext] lock their
+// goroutine to its thread with [runtime.LockOSThread] while they run, so
+// every callback of the run happens on the same thread. [WithCPU] implies
+// it. A loop driven with [Loop.RunOnce] or [Loop.Poll] is not locked; lock
+// the goroutine that drives it, or call [Loop.PinToCPU].
+func WithLockOSThread() LoopOption {
+	return func(c *loopConfig) {
+		c.lockThread = true
+	}
+}
+
+// PinToCPU locks the calling goroutine to its thread and pins the thread
+// to cpu, for loops driven with [Loop.RunOnce] or [Loop.Poll] from a
+// dedicated goroutine. Call it from the goroutine that runs the loop; the
+// goroutine stays locked, and its thread is discarded when it exits. On
+// systems other than Linux PinToCPU does nothing and returns nil.
+func (l *Loop) PinToCPU(cpu int) error {
+	runtime.LockOSThread()
+	if err := setThreadCPU(cpu); err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	return nil
+}
+
+// pinThread locks the calling goroutine to its thread and pins the thread
+// to cpu. The returned function restores the thread's affinity and unlocks
+// it.
+func pinThread(cpu int) (func(), error) {
+	runtime.LockOSThread()
+	restore, err := saveThreadAffinity()
+	if err == nil {
+		err = setThreadCPU(cpu)
+	}
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	return func() {
+		restore()
+		runtime.UnlockOSThread()
+	}, nil
+}
+
+// lockThread locks the calling goroutine to its thread for a run, pinned
+// to the loop's CPU if it has one. The returned function undoes it.
+func (l *Loop) lockThread() (func(), error) {
+	switch {
+	case l.cpu >= 0:
+		return pinThread(l.cpu)
+	case l.lockOSThread:
+		runtime.LockOSThread()
+		return runtime.UnlockOSThread, nil
+	default:
+		return func() {}, nil
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxCPUs is the number of CPUs a CPU set can hold.
+const maxCPUs = 8 * int(unsafe.Sizeof(unix.CPUSet{}))
+
+// setThreadCPU pins the calling thread to cpu. The kernel rejects a mask
+// with no CPU the process may use with EINVAL.
+func setThreadCPU(cpu int) error {
+	if cpu < 0 || cpu >= maxCPUs {
+		return fmt.Errorf("pin to cpu %d: %w", cpu, ErrInvalidCPU)
+	}
+	var set unix.CPUSet
+	set.Set(cpu)
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("pin to cpu %d: %w", cpu, ErrInvalidCPU)
+		}
+		return fmt.Errorf("sched_setaffinity: %w", err)
+	}
+	return nil
+}
+
+// saveThreadAffinity returns a function restoring the calling thread's
+// current affinity.
+func saveThreadAffinity() (func(), error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return nil, fmt.Errorf("sched_getaffinity: %w", err)
+	}
+	return func() { _ = unix.SchedSetaffinity(0, &set) }, nil
+}
+
+// processCPUs lists the CPUs the process may run on.
+func processCPUs() []int {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(unix.Getpid(), &set); err != nil {
+		return nil
+	}
+	cpus := make([]int, 0, set.Count())
+	for cpu := 0; len(cpus) < set.Count(); cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
@@ -0,0 +1,119 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestPinThread(t *testing.T) {
+	cpus := processCPUs()
+	if len(cpus) == 0 {
+		t.Fatal("no CPUs available to the process")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var before unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &before); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := cpus[len(cpus)-1]
+	unpin, err := pinThread(cpu)
+	if err != nil {
+		t.Fatalf("pinThread(%d): %v", cpu, err)
+	}
+	var pinned unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &pinned); err != nil {
+		t.Fatal(err)
+	}
+	if pinned.Count() != 1 || !pinned.IsSet(cpu) {
+		t.Fatalf("affinity after pinning to %d holds %d CPUs", cpu, pinned.Count())
+	}
+	unpin()
+	var after unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Fatal("affinity not restored after unpinning")
+	}
+
+	for _, bad := range []int{-1, maxCPUs} {
+		if _, err := pinThread(bad); !errors.Is(err, ErrInvalidCPU) {
+			t.Fatalf("pinThread(%d) = %v, want ErrInvalidCPU", bad, err)
+		}
+	}
+}
+
+func TestLoopGroupPinned(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	g, err := NewLoopGroup(LoopGroupOptions{Loops: 3, Pin: true})
+	if err != nil {
+		t.Fatalf("NewLoopGroup failed: %v", err)
+	}
+	defer g.Close()
+
+	// Each loop records the affinity of the thread running its callbacks.
+	sets := make([]unix.CPUSet, len(g.Loops()))
+	for i, loop := range g.Loops() {
+		if _, err := loop.Schedule(time.Millisecond, func() Action {
+			_ = unix.SchedGetaffinity(0, &sets[i])
+			return Stop
+		}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	if err := g.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	cpus := processCPUs()
+	for i, set := range sets {
+		if want := cpus[i%len(cpus)]; set.Count() != 1 || !set.IsSet(want) {
+			t.Fatalf("loop %d ran on %d CPUs, want only cpu %d", i, set.Count(), want)
+		}
+	}
+}
+
+func TestLoopLockOSThread(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop(WithLockOSThread())
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	// Timers a few milliseconds apart give the scheduler every chance to
+	// move an unlocked goroutine between threads.
+	tids := map[int]bool{}
+	for i := range 20 {
+		if _, err := loop.Schedule(time.Duration(i)*time.Millisecond, func() Action {
+			tids[unix.Gettid()] = true
+			runtime.Gosched()
+			return Stop
+		}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(tids) != 1 {
+		t.Fatalf("callbacks ran on %d threads, want 1", len(tids))
+	}
+}
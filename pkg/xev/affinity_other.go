@@ -0,0 +1,21 @@
+//go:build !linux
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+func setThreadCPU(int) error {
+	return nil
+}
+
+func saveThreadAffinity() (func(), error) {
+	return func() {}, nil
+}
+
+func processCPUs() []int {
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// countingReader is a read handler that allocates nothing itself.
+type countingReader struct {
+	reads, bytes int
+	action       Action
+}
+
+func (r *countingReader) OnRead(_ *TCPConn, data []byte, _ error) Action {
+	r.reads++
+	r.bytes += len(data)
+	return r.action
+}
+
+func TestTCPReadDispatchDoesNotAllocate(t *testing.T) {
+	r := &countingReader{action: Continue}
+	c := &TCPConn{loop: &Loop{}, readHandler: r}
+	data := make([]byte, 32)
+	c.ops.submit(tcpConnOwner, "read")
+
+	dispatch := func() {
+		if c.readCallback(nil, nil, data, int32(len(data)), 0, 0) != cxev.Rearm {
+			t.Fatal("read not re-armed")
+		}
+	}
+	if allocs := testing.AllocsPerRun(1000, dispatch); allocs != 0 {
+		t.Fatalf("read dispatch allocates %.1f times", allocs)
+	}
+	if c.stats.Reads != uint64(r.reads) || c.stats.BytesIn != uint64(r.bytes) {
+		t.Fatalf("stats %+v after %d reads", c.stats, r.reads)
+	}
+}
+
+func TestUDPReadDispatchReusesPeerAddr(t *testing.T) {
+	var got []*net.UDPAddr
+	c := &UDPConn{loop: &Loop{}}
+	c.readHandler = UDPReadFunc(func(_ *UDPConn, _ []byte, addr *net.UDPAddr, _ error) Action {
+		got = append(got, addr)
+		return Continue
+	})
+	c.ops.submit(udpConnOwner, "read")
+
+	// Laid out as sockaddrToUDPAddr reads it: family, port, IPv4 address.
+	var a, b cxev.Sockaddr
+	copy(a[1:], []byte{2, 0x1f, 0x90, 127, 0, 0, 1})
+	copy(b[1:], []byte{2, 0x1f, 0x91, 127, 0, 0, 1})
+	data := []byte("datagram")
+	for _, from := range []*cxev.Sockaddr{&a, &a, &b, &a} {
+		c.readCallback(nil, nil, from, data, int32(len(data)), 0, 0)
+	}
+	if got[0].String() != "127.0.0.1:8080" || got[2].String() != "127.0.0.1:8081" {
+		t.Fatalf("decoded %v", got)
+	}
+	if got[1] != got[0] || got[2] == got[1] || got[3] == got[0] {
+		t.Fatalf("peer address not reused only for the same sender: %p", got)
+	}
+
+	count := 0
+	c.readHandler = UDPReadFunc(func(*UDPConn, []byte, *net.UDPAddr, error) Action {
+		count++
+		return Continue
+	})
+	dispatch := func() { c.readCallback(nil, nil, &a, data, int32(len(data)), 0, 0) }
+	if allocs := testing.AllocsPerRun(1000, dispatch); allocs != 0 {
+		t.Fatalf("datagram dispatch allocates %.1f times", allocs)
+	}
+}
+
+// restartingReader stops after every read and starts the next one, the
+// pattern that used to register a fresh callback per read.
+type restartingReader struct {
+	loop  *Loop
+	buf   []byte
+	bytes int
+	err   error
+}
+
+func (r *restartingReader) OnRead(c *TCPConn, data []byte, err error) Action {
+	r.bytes += len(data)
+	if r.err = err; err == nil {
+		r.err = c.Read(r.loop, r.buf, r)
+	}
+	return Stop
+}
+
+func TestTCPRestartedReadsDoNotAllocate(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	var server *TCPConn
+	_ = listener.AcceptFunc(loop, func(_ *TCPListener, conn *TCPConn, err error) Action {
+		server = conn
+		return Stop
+	})
+	peer, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", itoa(int(port))))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer peer.Close()
+	for server == nil {
+		if err := loop.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &restartingReader{loop: loop, buf: make([]byte, 64)}
+	if err := server.Read(loop, r.buf, r); err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("x")
+	roundTrip := func() {
+		want := r.bytes + len(msg)
+		if _, err := peer.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		for r.bytes < want && r.err == nil {
+			if err := loop.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	roundTrip() // warm the pools
+	if allocs := testing.AllocsPerRun(200, roundTrip); allocs != 0 {
+		t.Fatalf("restarted read allocates %.1f times", allocs)
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	_ = peer.Close()
+	for r.err == nil {
+		if err := loop.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = server.CloseFunc(loop, nil)
+	_ = loop.Run()
+}
+
+func TestFilePReadsDoNotAllocate(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoopWithThreadPool()
+	if err != nil {
+		t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+	}
+	defer loop.Close()
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+
+	buf := make([]byte, 16)
+	done := false
+	handler := FileReadFunc(func(_ *File, data []byte, err error) Action {
+		done = true
+		return Stop
+	})
+	read := func() {
+		done = false
+		if err := file.PRead(loop, buf, 0, handler); err != nil {
+			t.Fatal(err)
+		}
+		for !done {
+			if err := loop.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	read() // warm the pools
+	if allocs := testing.AllocsPerRun(200, read); allocs != 0 {
+		t.Fatalf("file read allocates %.1f times", allocs)
+	}
+	_ = file.CloseFunc(loop, nil)
+	_ = loop.Run()
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"strings"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// BackendKind is the kernel interface a loop is built on. libxev picks it
+// when the library is compiled: io_uring on Linux, or epoll when built for
+// it, and kqueue on BSD and macOS.
+type BackendKind int
+
+const (
+	// BackendUnknown is any backend other than those below.
+	BackendUnknown BackendKind = iota
+	BackendIOUring
+	BackendEpoll
+	BackendKqueue
+)
+
+func (k BackendKind) String() string {
+	switch k {
+	case BackendIOUring:
+		return "io_uring"
+	case BackendEpoll:
+		return "epoll"
+	case BackendKqueue:
+		return "kqueue"
+	default:
+		return "unknown"
+	}
+}
+
+// BackendFeatures are what a loop's backend supports, as flags.
+type BackendFeatures uint32
+
+const (
+	// FeatureNativeFileIO means file I/O is done by the kernel. Without
+	// it, [File] operations need a loop created with
+	// [NewLoopWithThreadPool].
+	FeatureNativeFileIO = BackendFeatures(cxev.FeatureNativeFileIO)
+	// FeatureThreadPool means the loop has a thread pool for blocking
+	// operations.
+	FeatureThreadPool = BackendFeatures(cxev.FeatureThreadPool)
+	// FeaturePoll means the backend can wait for readiness, as
+	// [TCPConn.WaitWritable] does.
+	FeaturePoll = BackendFeatures(cxev.FeaturePoll)
+	// FeatureMsg means the backend can receive control messages, as
+	// [UDPConn.ReadMsgFrom] does.
+	FeatureMsg = BackendFeatures(cxev.FeatureMsg)
+	// FeatureFd means the backend has the descriptor [Loop.BackendFd]
+	// returns.
+	FeatureFd = BackendFeatures(cxev.FeatureFd)
+	// FeatureSQPoll means the io_uring ring is polled by a kernel thread,
+	// as [WithSQPoll] asks for.
+	FeatureSQPoll = BackendFeatures(cxev.FeatureSQPoll)
+)
+
+var featureNames = []struct {
+	f    BackendFeatures
+	name string
+}{
+	{FeatureNativeFileIO, "native-file-io"},
+	{FeatureThreadPool, "thread-pool"},
+	{FeaturePoll, "poll"},
+	{FeatureMsg, "msg"},
+	{FeatureFd, "fd"},
+	{FeatureSQPoll, "sqpoll"},
+}
+
+// String lists the features by name, separated by commas.
+func (f BackendFeatures) String() string {
+	var names []string
+	for _, fn := range featureNames {
+		if f&fn.f != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// BackendInfo describes the backend of a loop.
+type BackendInfo struct {
+	Kind     BackendKind
+	Features BackendFeatures
+}
+
+// String formats the backend for logs and bug reports, such as
+// "io_uring [native-file-io,poll,msg,fd]".
+func (b BackendInfo) String() string {
+	return b.Kind.String() + " [" + b.Features.String() + "]"
+}
+
+// Backend reports which backend the loop is running and what it supports.
+//
+// Returns [ErrExtLibNotLoaded] without the extended library.
+func (l *Loop) Backend() (BackendInfo, error) {
+	if !cxev.ExtLibLoaded() {
+		return BackendInfo{}, ErrExtLibNotLoaded
+	}
+	return BackendInfo{
+		Kind:     BackendKind(cxev.LoopBackend(&l.inner)),
+		Features: BackendFeatures(cxev.LoopFeatures(&l.inner)),
+	}, nil
+}
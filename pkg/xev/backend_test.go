@@ -0,0 +1,61 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestBackendInfoString(t *testing.T) {
+	b := BackendInfo{Kind: BackendIOUring, Features: FeatureNativeFileIO | FeaturePoll | FeatureFd}
+	if got, want := b.String(), "io_uring [native-file-io,poll,fd]"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if got := BackendKind(42).String(); got != "unknown" {
+		t.Fatalf("unknown kind String() = %q", got)
+	}
+}
+
+func TestLoopBackend(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		var l Loop
+		if _, err := l.Backend(); !errors.Is(err, ErrExtLibNotLoaded) {
+			t.Fatalf("Backend: %v, want ErrExtLibNotLoaded", err)
+		}
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoopWithThreadPool()
+	if err != nil {
+		t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+	}
+	defer loop.Close()
+	b, err := loop.Backend()
+	if err != nil {
+		t.Fatalf("Backend failed: %v", err)
+	}
+	switch runtime.GOOS {
+	case "linux":
+		if b.Kind != BackendIOUring && b.Kind != BackendEpoll {
+			t.Fatalf("backend on linux = %v", b)
+		}
+	case "darwin":
+		if b.Kind != BackendKqueue {
+			t.Fatalf("backend on darwin = %v", b)
+		}
+	}
+	if b.Features&(FeatureNativeFileIO|FeatureThreadPool) == 0 {
+		t.Fatalf("backend %v can do file I/O neither natively nor on the thread pool", b)
+	}
+	if _, err := loop.BackendFd(); (err == nil) != (b.Features&FeatureFd != 0) {
+		t.Fatalf("BackendFd error %v disagrees with features %v", err, b.Features)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// libxev runs the callbacks of every completion that is ready when it polls
+// before it looks at its timers again, so a burst of thousands of incoming
+// connections is accepted, and handed to the accept handler, all in one
+// iteration while timers wait. A callback budget bounds that: once an
+// iteration has run its budget of I/O callbacks, connections accepted after
+// that are carried over to the next iteration, which runs them before it
+// polls again. Meanwhile the listener is not accepting, so the connections
+// behind them wait in the kernel's backlog. TCP reads past the budget are
+// carried over the same way, their data waiting in the buffer, so that busy
+// connections cannot hold up the timers and the other connections either.
+
+// WithCallbackBudget caps the I/O callbacks (accepts and TCP reads) the
+// loop runs per iteration at n. Past the cap, accepted connections and
+// completed reads are carried over to the next iteration instead of being
+// handed to their handlers, which run them before the loop polls again.
+// Zero, the default, sets no cap.
+//
+// The budget requires the extended library; without it, it has no effect.
+// [Loop.BudgetStats] reports how often it is reached.
+func WithCallbackBudget(n int) LoopOption {
+	return func(c *loopConfig) {
+		c.budget = n
+	}
+}
+
+// WithCallbackTimeBudget caps the time the loop spends running I/O
+// callbacks per iteration at d, like [WithCallbackBudget] caps their
+// number. The time is measured from the first callback run after the loop
+// polls, and separately for the work carried over, which runs before it.
+// It can be combined with WithCallbackBudget; whichever is reached first
+// applies.
+//
+// The budget requires the extended library; without it, it has no effect.
+func WithCallbackTimeBudget(d time.Duration) LoopOption {
+	return func(c *loopConfig) {
+		c.budgetTime = d
+	}
+}
+
+// BudgetStats counts how the callback budget of a loop held work back.
+type BudgetStats struct {
+	// Exhausted is the number of iterations that reached the budget.
+	Exhausted uint64
+	// Deferred is the number of callbacks carried over to a later
+	// iteration, counted once each however long they wait.
+	Deferred uint64
+	// MaxCarried is the most callbacks carried over into one iteration.
+	MaxCarried int
+}
+
+// BudgetStats returns how the callback budget has held work back so far.
+// A loop without a budget reports zeros.
+func (l *Loop) BudgetStats() BudgetStats {
+	return l.budgetStats
+}
+
+// spend records an I/O callback against the iteration's budget. A nil loop
+// is ignored.
+func (l *Loop) spend() {
+	if l != nil {
+		l.dispatched++
+	}
+}
+
+// overBudget reports whether the iteration has run its budget of I/O
+// callbacks. The first check after the clock is reset starts it.
+func (l *Loop) overBudget() bool {
+	if l == nil {
+		return false
+	}
+	over := l.budget > 0 && l.dispatched >= l.budget
+	if !over && l.budgetTime > 0 {
+		if l.budgetStart.IsZero() {
+			l.budgetStart = time.Now()
+		} else {
+			over = time.Since(l.budgetStart) >= l.budgetTime
+		}
+	}
+	if over && !l.exhausted {
+		l.exhausted = true
+		l.budgetStats.Exhausted++
+	}
+	return over
+}
+
+// carry queues fn to run at the start of the next iteration.
+func (l *Loop) carry(fn func()) {
+	l.carried = append(l.carried, fn)
+	l.budgetStats.Deferred++
+}
+
+// runCarried starts a new iteration's budget and runs the work carried over
+// from the last, until the budget is spent again; what is left carries on.
+// The time budget starts afresh for the callbacks of the poll that follows.
+func (l *Loop) runCarried() {
+	l.dispatched = 0
+	l.exhausted = false
+	l.budgetStart = time.Time{}
+	defer func() { l.budgetStart = time.Time{} }()
+	carried := l.carried
+	l.carried = nil
+	l.budgetStats.MaxCarried = max(l.budgetStats.MaxCarried, len(carried))
+	for i, fn := range carried {
+		if l.overBudget() {
+			l.carried = append(carried[i:], l.carried...)
+			return
+		}
+		fn()
+	}
+}
+
+// stepped reports whether the loop has to be run one iteration at a time
+// from Go, for hooks or a callback budget.
+func (l *Loop) stepped() bool {
+	return l.budget > 0 || l.budgetTime > 0 || l.hooks.any()
+}
+
+// alive reports whether the loop has operations in flight or work carried
+// over.
+func (l *Loop) alive() bool {
+	return len(l.carried) > 0 || cxev.LoopAlive(&l.inner)
+}
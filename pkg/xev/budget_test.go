@@ -0,0 +1,252 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestCallbackBudgetCarriesAccepts(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop(WithCallbackBudget(1))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+
+	const clients = 5
+	for i := 0; i < clients; i++ {
+		client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer client.Close()
+	}
+
+	// Every iteration may hand over at most one connection.
+	if _, err := loop.AddHook(HookCheck, func() {
+		if loop.dispatched > 1 {
+			t.Errorf("iteration ran %d accept callbacks, budget is 1", loop.dispatched)
+		}
+	}); err != nil {
+		t.Fatalf("AddHook failed: %v", err)
+	}
+
+	accepted := 0
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("accept error: %v", err)
+			return Stop
+		}
+		accepted++
+		_ = conn.CloseFunc(loop, nil)
+		if accepted == clients {
+			_ = l.Close(loop, nil)
+		}
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if accepted != clients {
+		t.Fatalf("accepted %d connections, want %d", accepted, clients)
+	}
+}
+
+func TestCallbackTimeBudget(t *testing.T) {
+	l := &Loop{budgetTime: time.Millisecond}
+	if l.overBudget() {
+		t.Fatal("over budget before the first callback")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !l.overBudget() || !l.overBudget() {
+		t.Fatal("not over budget once the time is spent")
+	}
+	if got := l.BudgetStats().Exhausted; got != 1 {
+		t.Fatalf("Exhausted = %d, want 1 for one iteration", got)
+	}
+
+	l.carry(func() {})
+	l.carry(func() {})
+	l.runCarried()
+	if len(l.carried) != 0 {
+		t.Fatalf("%d callbacks still carried with the budget reset", len(l.carried))
+	}
+	if got := l.BudgetStats(); got != (BudgetStats{Exhausted: 1, Deferred: 2, MaxCarried: 2}) {
+		t.Fatalf("BudgetStats() = %+v", got)
+	}
+}
+
+func TestCallbackBudgetCarriesReads(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop(WithCallbackBudget(1))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+
+	const clients = 4
+	for i := 0; i < clients; i++ {
+		client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer client.Close()
+		if _, err := client.Write([]byte("ping")); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	if _, err := loop.AddHook(HookCheck, func() {
+		if loop.dispatched > 1 {
+			t.Errorf("iteration ran %d callbacks, budget is 1", loop.dispatched)
+		}
+	}); err != nil {
+		t.Fatalf("AddHook failed: %v", err)
+	}
+
+	accepted, read := 0, 0
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("accept error: %v", err)
+			return Stop
+		}
+		accepted++
+		_ = conn.ReadFunc(loop, make([]byte, 16), func(c *TCPConn, data []byte, err error) Action {
+			if string(data) == "ping" {
+				read++
+			}
+			_ = c.CloseFunc(loop, nil)
+			return Stop
+		})
+		if accepted == clients {
+			_ = l.Close(loop, nil)
+		}
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if read != clients {
+		t.Fatalf("read from %d connections, want %d", read, clients)
+	}
+	if stats := loop.BudgetStats(); stats.Exhausted == 0 || stats.Deferred == 0 {
+		t.Fatalf("BudgetStats() = %+v, want work held back", stats)
+	}
+}
+
+func TestCallbackBudgetDropsCarriedReadOnClose(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop(WithCallbackBudget(1))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+
+	const clients = 4
+	for i := 0; i < clients; i++ {
+		client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer client.Close()
+		if _, err := client.Write([]byte("ping")); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	var conns []*TCPConn
+	dropped := make(map[*TCPConn]bool)
+	// Close the connections whose completed read was carried over, before
+	// the next iteration resumes it.
+	if _, err := loop.AddHook(HookCheck, func() {
+		for _, c := range conns {
+			if !dropped[c] && !c.closed.Load() && c.ops.op == "read" && c.callbackID == 0 {
+				dropped[c] = true
+				_ = c.CloseFunc(loop, nil)
+			}
+		}
+	}); err != nil {
+		t.Fatalf("AddHook failed: %v", err)
+	}
+
+	read := 0
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("accept error: %v", err)
+			return Stop
+		}
+		conns = append(conns, conn)
+		_ = conn.ReadFunc(loop, make([]byte, 16), func(c *TCPConn, data []byte, err error) Action {
+			if dropped[c] {
+				t.Error("read delivered after the connection was closed")
+			}
+			read++
+			_ = c.CloseFunc(loop, nil)
+			return Stop
+		})
+		if len(conns) == clients {
+			_ = l.Close(loop, nil)
+		}
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(dropped) == 0 {
+		t.Fatal("no read was carried over")
+	}
+	if read+len(dropped) != clients {
+		t.Fatalf("read %d and dropped %d of %d connections", read, len(dropped), clients)
+	}
+	if n := cxev.DebugTCPCallbackCount(); n != 0 {
+		t.Fatalf("expected no TCP callback leaks, found %d active registrations", n)
+	}
+}
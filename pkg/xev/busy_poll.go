@@ -0,0 +1,57 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// A loop blocked in the kernel waiting for events pays a wakeup when one
+// arrives: the thread is rescheduled, which takes tens of microseconds and
+// far more on a busy or power-saving machine. Busy polling trades CPU for
+// that latency: after handling events the loop keeps polling without
+// blocking for a bounded time, so an event arriving within it is picked up
+// by a thread that is already running.
+
+// WithBusyPoll makes [Loop.Run] poll for events without blocking for up to
+// d before each blocking wait. Every wakeup is followed by d of spinning on
+// the loop's thread, so a loop that sees traffic more often than every d
+// keeps a core busy. Values of tens of microseconds suit latency-critical
+// servers; zero, the default, always blocks.
+//
+// Busy polling requires the extended library; without it Run blocks as
+// usual. [Loop.RunOnce] and [Loop.Poll] are not affected.
+func WithBusyPoll(d time.Duration) LoopOption {
+	return func(c *loopConfig) {
+		c.busyPoll = d
+	}
+}
+
+// runBusyPoll runs the loop until it has nothing left to do, spinning for
+// l.busyPoll before every blocking wait. Liveness is only checked after a
+// run, which submits operations queued since the last one.
+func (l *Loop) runBusyPoll() error {
+	for {
+		deadline := time.Now().Add(l.busyPoll)
+		for time.Now().Before(deadline) {
+			if err := l.iterate(cxev.RunNoWait); err != nil {
+				return err
+			}
+			if !l.alive() {
+				return nil
+			}
+		}
+		if err := l.iterate(cxev.RunOnce); err != nil {
+			return err
+		}
+		if !l.alive() {
+			return nil
+		}
+	}
+}
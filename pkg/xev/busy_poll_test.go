@@ -0,0 +1,157 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestBusyPollRunsUntilDone(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop(WithBusyPoll(50 * time.Microsecond))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	fired := 0
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond} {
+		if _, err := loop.Schedule(d, func() Action {
+			fired++
+			return Stop
+		}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() { done <- loop.Run() }()
+	select {
+	case err := <-done:
+		if err != nil || fired != 2 {
+			t.Fatalf("Run returned %v after %d timers, want 2", err, fired)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once the timers fired")
+	}
+}
+
+// BenchmarkBusyPollLatency measures the round trip of one byte through an
+// echo server on a loop that blocks for events, and on loops that spin
+// first. It reports the p50 and p99 round trip and the CPU time the
+// process used per wall-clock second, the price of the spinning:
+//
+//	go test ./pkg/xev -run '^$' -bench BusyPollLatency
+func BenchmarkBusyPollLatency(b *testing.B) {
+	if !cxev.ExtLibLoaded() {
+		b.Skip("extended library not loaded")
+	}
+	for _, tc := range []struct {
+		name string
+		spin time.Duration
+	}{
+		{"block", 0},
+		{"spin=20us", 20 * time.Microsecond},
+		{"spin=200us", 200 * time.Microsecond},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			benchmarkEchoLatency(b, tc.spin)
+		})
+	}
+}
+
+func benchmarkEchoLatency(b *testing.B, spin time.Duration) {
+	loop, err := NewLoop(WithBusyPoll(spin))
+	if err != nil {
+		b.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	buf := make([]byte, 64)
+	err = listener.AcceptFunc(loop, func(_ *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			return Stop
+		}
+		_ = conn.ReadFunc(loop, buf, func(c *TCPConn, data []byte, err error) Action {
+			if err != nil || len(data) == 0 {
+				c.CloseFunc(loop, nil)
+				return Stop
+			}
+			// Echo straight from the callback, like a server answering a
+			// request, so only the loop's wakeup is measured.
+			_, _ = syscall.Write(int(c.Fd()), data)
+			return Continue
+		})
+		return Stop
+	})
+	if err != nil {
+		b.Fatalf("Accept failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- loop.Run() }()
+
+	client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+	if err != nil {
+		b.Fatalf("dial failed: %v", err)
+	}
+	// Gaps between requests let a blocking loop fall asleep, as it would
+	// between the requests of a real client.
+	const gap = 50 * time.Microsecond
+	rtts := make([]time.Duration, 0, b.N)
+	one := []byte{'x'}
+	var ru0, ru1 syscall.Rusage
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &ru0)
+	start := time.Now()
+	b.ResetTimer()
+	for range b.N {
+		spinUntil(time.Now().Add(gap))
+		t0 := time.Now()
+		if _, err := client.Write(one); err != nil {
+			b.Fatalf("write failed: %v", err)
+		}
+		if _, err := client.Read(one); err != nil {
+			b.Fatalf("read failed: %v", err)
+		}
+		rtts = append(rtts, time.Since(t0))
+	}
+	b.StopTimer()
+	wall := time.Since(start)
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &ru1)
+	_ = client.Close()
+	if err := <-done; err != nil {
+		b.Fatalf("Run failed: %v", err)
+	}
+
+	slices.Sort(rtts)
+	b.ReportMetric(float64(rtts[len(rtts)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(rtts[len(rtts)*99/100].Nanoseconds()), "p99-ns")
+	cpu := time.Duration(syscall.TimevalToNsec(ru1.Utime) - syscall.TimevalToNsec(ru0.Utime) +
+		syscall.TimevalToNsec(ru1.Stime) - syscall.TimevalToNsec(ru0.Stime))
+	b.ReportMetric(cpu.Seconds()/wall.Seconds(), "cpu-s/s")
+}
+
+// spinUntil waits for t without sleeping, so the client's own wakeup does
+// not blur the server's.
+func spinUntil(t time.Time) {
+	for time.Now().Before(t) {
+	}
+}
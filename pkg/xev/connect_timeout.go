@@ -0,0 +1,80 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// A connect to an unreachable address can stay pending until the kernel
+// gives up, which takes minutes, and holds the connection's completion the
+// whole time. A connect timeout runs a loop timer next to the connect; if
+// it fires first, the connect is cancelled through libxev and its handler
+// sees ErrConnectTimeout instead of the cancellation error.
+
+// ErrConnectTimeout is passed to the [TCPConn.Connect] handler of a
+// connection dialed with [WithConnectTimeout] when the connect did not
+// complete in time.
+var ErrConnectTimeout = errors.New("connect timed out")
+
+// WithConnectTimeout makes [TCPConn.Connect] cancel the connect if it has
+// not completed after d, and pass [ErrConnectTimeout] to its handler. With
+// a proxy option, d bounds the connect to the proxy, not the handshake.
+func WithConnectTimeout(d time.Duration) DialOption {
+	return func(c *TCPConn) {
+		if d > 0 {
+			c.connectTimeout = &connectTimeout{d: d}
+		}
+	}
+}
+
+// connectTimeout is the timer of a connect.
+type connectTimeout struct {
+	d       time.Duration
+	stop    func()
+	expired bool
+}
+
+// arm starts the timer for a connect about to be submitted on c.
+func (t *connectTimeout) arm(loop *Loop, c *TCPConn) error {
+	t.expired = false
+	stop, err := loop.Schedule(t.d, func() Action {
+		t.expired = true
+		// libxev holds the cancel completion until the cancellation is
+		// done; the registered callback keeps it reachable until then,
+		// even if the connection is dropped or dials again meanwhile.
+		cancel := new(cxev.TCPCompletion)
+		cxev.TCPCancelWithCallback(&loop.inner, &c.completion, cancel, func(_ *cxev.Loop, _ *cxev.TCPCompletion, _ int32, userdata uintptr) cxev.CbAction {
+			// A connect that completed before the cancellation reached
+			// it makes the cancellation fail, which is fine.
+			unregisterTCPCallback(userdata, nil)
+			runtime.KeepAlive(cancel)
+			return cxev.Disarm
+		})
+		return Stop
+	})
+	t.stop = stop
+	return err
+}
+
+// settle stops the timer of a completed connect and returns the error to
+// pass to the handler. A timer still armed is cancelled through libxev,
+// which releases it once the loop has run the cancellation.
+func (t *connectTimeout) settle(err error) error {
+	if t.stop != nil {
+		t.stop()
+		t.stop = nil
+	}
+	if t.expired && err != nil {
+		return ErrConnectTimeout
+	}
+	return err
+}
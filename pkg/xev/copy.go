@@ -0,0 +1,281 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"hash"
+)
+
+// DefaultCopyChunkSize is the size of the reads and writes of a chunked
+// [CopyFile] when [CopyOptions.ChunkSize] is zero.
+const DefaultCopyChunkSize = 64 * 1024
+
+// errKernelCopyUnsupported is returned by kernelCopy, before it has copied
+// anything, when the kernel cannot copy between the files.
+var errKernelCopyUnsupported = errors.New("kernel copy not supported")
+
+// CopyOptions configures [CopyFile].
+type CopyOptions struct {
+	// ChunkSize is the size of each read and write of a chunked copy.
+	// Zero means [DefaultCopyChunkSize].
+	ChunkSize int
+	// NoKernelCopy always copies in chunks through the loop, even where
+	// the kernel could copy the files itself.
+	NoKernelCopy bool
+	// Transforms are applied, in order, to every chunk on its way from
+	// src to dst. A copy with transforms is always chunked.
+	Transforms []ChunkTransform
+	// Background makes the reads and writes of a chunked copy background
+	// operations, which a loop with [WithFileConcurrency] starts after
+	// the foreground ones; otherwise they have the priority of each file.
+	Background bool
+}
+
+// ChunkTransform processes the chunks of a [CopyFile], for instance to hash
+// or compress them. Its methods run one at a time, in the order of the
+// chunks, on goroutines other than the loop's, so a slow transform does not
+// hold up the loop.
+type ChunkTransform interface {
+	// Transform returns the bytes to write in place of chunk: chunk
+	// itself, new bytes, or none. It must not keep chunk, which is reused
+	// for the next one.
+	Transform(chunk []byte) ([]byte, error)
+	// Final returns the bytes to write after the last chunk, such as the
+	// trailer of a compressed stream.
+	Final() ([]byte, error)
+}
+
+// HashChunks returns a [ChunkTransform] that writes every chunk to h and
+// passes it on unchanged, so h holds the hash of the copied data once the
+// copy is done.
+func HashChunks(h hash.Hash) ChunkTransform {
+	return hashTransform{h}
+}
+
+type hashTransform struct {
+	h hash.Hash
+}
+
+func (t hashTransform) Transform(chunk []byte) ([]byte, error) {
+	_, _ = t.h.Write(chunk)
+	return chunk, nil
+}
+
+func (t hashTransform) Final() ([]byte, error) {
+	return nil, nil
+}
+
+// CopyFile copies the contents of src to dst, from the start of each, and
+// calls done on the loop's goroutine with the number of bytes written to
+// dst once it is finished. done is never called before CopyFile returns.
+//
+// On Linux the kernel copies the files with copy_file_range on a goroutine
+// of CopyFile's own, so the data never passes through user space and
+// filesystems that support it can share extents instead. Where the kernel
+// cannot copy them, such as on other systems or, with older kernels,
+// between filesystems, and whenever [CopyOptions.Transforms] are set, the
+// copy is made of chunks read with [File.PRead] and written with
+// [File.PWrite], which needs a loop created with [NewLoopWithThreadPool].
+//
+// The length copied is the size of src when the copy starts. dst is not
+// truncated, and both files must stay open until done is called.
+//
+// Returns an error if done is nil or ChunkSize is negative, and
+// [ErrClosed] if either file is closed.
+func CopyFile(loop *Loop, src, dst *File, opts CopyOptions, done func(written int64, err error)) error {
+	if done == nil {
+		return errors.New("done cannot be nil")
+	}
+	if opts.ChunkSize < 0 {
+		return errors.New("chunk size cannot be negative")
+	}
+	if src.closed.Load() || dst.closed.Load() {
+		return ErrClosed
+	}
+	chunk := opts.ChunkSize
+	if chunk == 0 {
+		chunk = DefaultCopyChunkSize
+	}
+
+	// Taking the size, the kernel copy and the transforms block, so they
+	// run on goroutines that hand their results back to the loop.
+	n, err := NewNotifier(loop)
+	if err != nil {
+		return err
+	}
+	c := &fileCopy{
+		loop:       loop,
+		src:        src,
+		dst:        dst,
+		chunk:      chunk,
+		transforms: opts.Transforms,
+		background: opts.Background,
+		notifier:   n,
+		done:       done,
+	}
+	srcFd, dstFd := int(src.Fd()), int(dst.Fd())
+	chunked := opts.NoKernelCopy || len(opts.Transforms) > 0
+	go func() {
+		size, err := fileSize(srcFd)
+		var written int64
+		switch {
+		case err != nil:
+		case chunked:
+			err = errKernelCopyUnsupported
+		default:
+			written, err = kernelCopy(srcFd, dstFd, size)
+		}
+		_ = n.Post(func() { c.start(size, written, err) })
+	}()
+	return nil
+}
+
+// fileCopy is the state of a CopyFile. A chunked copy reads each chunk into
+// buf at roff, passes it through the transforms and writes the result at
+// woff before it reads the next.
+type fileCopy struct {
+	loop       *Loop
+	src, dst   *File
+	chunk      int
+	transforms []ChunkTransform
+	background bool
+	notifier   *Notifier
+	done       func(written int64, err error)
+
+	buf        []byte
+	size       int64
+	roff, woff int64
+	// ending is set once the transforms' final bytes are being written.
+	ending bool
+}
+
+// start continues the copy on the loop once the size of src is known and
+// the kernel has copied it, or declined to.
+func (c *fileCopy) start(size, written int64, err error) {
+	if !errors.Is(err, errKernelCopyUnsupported) {
+		c.finish(written, err)
+		return
+	}
+	c.size = size
+	c.buf = make([]byte, c.chunk)
+	if err := c.next(); err != nil {
+		c.finish(c.woff, err)
+	}
+}
+
+func (c *fileCopy) finish(written int64, err error) {
+	_ = c.notifier.Close()
+	c.done(written, err)
+}
+
+// next starts reading the next chunk. Past the end of src, it writes the
+// transforms' final bytes, then finishes the copy.
+func (c *fileCopy) next() error {
+	if c.roff < c.size {
+		n := min(int64(len(c.buf)), c.size-c.roff)
+		return c.src.pread(c.loop, c.buf[:n], uint64(c.roff), c.priority(c.src), FileReadFunc(c.onRead))
+	}
+	if c.ending || len(c.transforms) == 0 {
+		c.finish(c.woff, nil)
+		return nil
+	}
+	c.ending = true
+	c.offLoop(func() ([]byte, error) { return finalChunk(c.transforms) })
+	return nil
+}
+
+func (c *fileCopy) onRead(_ *File, data []byte, err error) Action {
+	if err == nil && len(data) == 0 {
+		// src shrank since the copy started.
+		c.size = c.roff
+		err = c.next()
+	} else if err == nil {
+		c.roff += int64(len(data))
+		if len(c.transforms) == 0 {
+			err = c.write(data)
+		} else {
+			c.offLoop(func() ([]byte, error) { return transformChunk(c.transforms, data) })
+		}
+	}
+	if err != nil {
+		c.finish(c.woff, err)
+	}
+	return Stop
+}
+
+// offLoop runs fn on a goroutine and writes the bytes it returns.
+func (c *fileCopy) offLoop(fn func() ([]byte, error)) {
+	go func() {
+		out, err := fn()
+		_ = c.notifier.Post(func() {
+			if err == nil {
+				err = c.write(out)
+			}
+			if err != nil {
+				c.finish(c.woff, err)
+			}
+		})
+	}()
+}
+
+// write starts writing data, the rest of the chunk, at woff, and reads the
+// next chunk once it is written.
+func (c *fileCopy) write(data []byte) error {
+	if len(data) == 0 {
+		return c.next()
+	}
+	return c.dst.pwrite(c.loop, data, uint64(c.woff), c.priority(c.dst), FileWriteFunc(func(_ *File, n int, err error) Action {
+		if err == nil {
+			c.woff += int64(n)
+			err = c.write(data[n:])
+		}
+		if err != nil {
+			c.finish(c.woff, err)
+		}
+		return Stop
+	}))
+}
+
+// priority returns the class of the copy's operations on f.
+func (c *fileCopy) priority(f *File) FilePriority {
+	if c.background {
+		return PriorityBackground
+	}
+	return f.priority
+}
+
+// transformChunk passes chunk through every transform in turn.
+func transformChunk(transforms []ChunkTransform, chunk []byte) ([]byte, error) {
+	for _, t := range transforms {
+		if len(chunk) == 0 {
+			return nil, nil
+		}
+		var err error
+		if chunk, err = t.Transform(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return chunk, nil
+}
+
+// finalChunk collects the final bytes of every transform, passing those of
+// each through the transforms after it.
+func finalChunk(transforms []ChunkTransform) ([]byte, error) {
+	var out []byte
+	for i, t := range transforms {
+		tail, err := t.Final()
+		if err != nil {
+			return nil, err
+		}
+		if tail, err = transformChunk(transforms[i+1:], tail); err != nil {
+			return nil, err
+		}
+		out = append(out, tail...)
+	}
+	return out, nil
+}
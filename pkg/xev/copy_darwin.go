@@ -0,0 +1,13 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+// kernelCopy always falls back to a chunked copy: fcopyfile, the Darwin
+// equivalent of copy_file_range, is not available without cgo.
+func kernelCopy(src, dst int, size int64) (int64, error) {
+	return 0, errKernelCopyUnsupported
+}
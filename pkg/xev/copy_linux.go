@@ -0,0 +1,50 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelCopyChunk caps a single copy_file_range call, so a large copy is
+// made of several calls rather than one the kernel may cut short anyway.
+const kernelCopyChunk = 1 << 30
+
+// kernelCopy copies size bytes from the start of src to the start of dst
+// with copy_file_range. It returns errKernelCopyUnsupported if the first
+// call fails because the kernel or the filesystems cannot copy the files.
+func kernelCopy(src, dst int, size int64) (int64, error) {
+	var roff, woff, written int64
+	for written < size {
+		n, err := unix.CopyFileRange(src, &roff, dst, &woff, int(min(size-written, kernelCopyChunk)), 0)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			if written == 0 && kernelCopyUnsupported(err) {
+				return 0, errKernelCopyUnsupported
+			}
+			return written, os.NewSyscallError("copy_file_range", err)
+		}
+		if n == 0 {
+			// src shrank since its size was taken.
+			break
+		}
+		written += int64(n)
+	}
+	return written, nil
+}
+
+// kernelCopyUnsupported reports whether a copy_file_range error means the
+// copy has to be made through user space instead.
+func kernelCopyUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL)
+}
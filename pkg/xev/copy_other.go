@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "errors"
+
+func fileSize(fd int) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func kernelCopy(src, dst int, size int64) (int64, error) {
+	return 0, errKernelCopyUnsupported
+}
@@ -0,0 +1,199 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestCopyFile(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	// The chunked copy uses a chunk size that does not divide the file.
+	for _, opts := range []CopyOptions{{}, {NoKernelCopy: true, ChunkSize: 4000}} {
+		loop, err := NewLoopWithThreadPool()
+		if err != nil {
+			t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+		}
+
+		dir := t.TempDir()
+		want := make([]byte, 100_000)
+		rand.New(rand.NewSource(1)).Read(want)
+		srcPath := filepath.Join(dir, "src")
+		dstPath := filepath.Join(dir, "dst")
+		if err := os.WriteFile(srcPath, want, 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		src, err := OpenFile(srcPath, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile src failed: %v", err)
+		}
+		dst, err := OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			t.Fatalf("OpenFile dst failed: %v", err)
+		}
+
+		finished := false
+		var written int64
+		err = CopyFile(loop, src, dst, opts, func(n int64, err error) {
+			if err != nil {
+				t.Errorf("copy with %+v failed: %v", opts, err)
+			}
+			finished, written = true, n
+			_ = src.CloseFunc(loop, nil)
+			_ = dst.CloseFunc(loop, nil)
+		})
+		if err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		if err := loop.Run(); err != nil {
+			t.Fatalf("Loop.Run failed: %v", err)
+		}
+		loop.Close()
+
+		if !finished {
+			t.Fatalf("copy with %+v did not finish", opts)
+		}
+		if written != int64(len(want)) {
+			t.Fatalf("copy with %+v wrote %d bytes, want %d", opts, written, len(want))
+		}
+		got, err := os.ReadFile(dstPath)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("copy with %+v produced different contents", opts)
+		}
+	}
+}
+
+// gzipChunks compresses the chunks of a copy.
+type gzipChunks struct {
+	out bytes.Buffer
+	zw  *gzip.Writer
+}
+
+func newGzipChunks() *gzipChunks {
+	g := &gzipChunks{}
+	g.zw = gzip.NewWriter(&g.out)
+	return g
+}
+
+func (g *gzipChunks) Transform(chunk []byte) ([]byte, error) {
+	g.out.Reset()
+	if _, err := g.zw.Write(chunk); err != nil {
+		return nil, err
+	}
+	return g.out.Bytes(), nil
+}
+
+func (g *gzipChunks) Final() ([]byte, error) {
+	g.out.Reset()
+	if err := g.zw.Close(); err != nil {
+		return nil, err
+	}
+	return g.out.Bytes(), nil
+}
+
+func TestCopyFileTransforms(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoopWithThreadPool()
+	if err != nil {
+		t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+	}
+	defer loop.Close()
+
+	dir := t.TempDir()
+	want := bytes.Repeat([]byte("libxev "), 20_000)
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst.gz")
+	if err := os.WriteFile(srcPath, want, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	src, err := OpenFile(srcPath, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile src failed: %v", err)
+	}
+	dst, err := OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile dst failed: %v", err)
+	}
+
+	h := sha256.New()
+	opts := CopyOptions{ChunkSize: 4000, Transforms: []ChunkTransform{HashChunks(h), newGzipChunks()}}
+	finished := false
+	var written int64
+	err = CopyFile(loop, src, dst, opts, func(n int64, err error) {
+		if err != nil {
+			t.Errorf("copy failed: %v", err)
+		}
+		finished, written = true, n
+		_ = src.CloseFunc(loop, nil)
+		_ = dst.CloseFunc(loop, nil)
+	})
+	if err != nil {
+		t.Fatalf("CopyFile failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+
+	if !finished {
+		t.Fatal("copy did not finish")
+	}
+	compressed, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if written != int64(len(compressed)) {
+		t.Fatalf("copy wrote %d bytes, dst holds %d", written, len(compressed))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("decompressed copy differs from src")
+	}
+	if sum := sha256.Sum256(want); !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Fatal("hash of the copied chunks differs from the hash of src")
+	}
+}
+
+func TestFinalChunkPassesThroughLaterTransforms(t *testing.T) {
+	h := sha256.New()
+	g := newGzipChunks()
+	// The gzip trailer is hashed by the transform after it.
+	out, err := finalChunk([]ChunkTransform{g, HashChunks(h)})
+	if err != nil {
+		t.Fatalf("finalChunk failed: %v", err)
+	}
+	if len(out) == 0 {
+		t.Fatal("finalChunk returned no gzip trailer")
+	}
+	if sum := sha256.Sum256(out); !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Fatal("final bytes were not passed through the later transform")
+	}
+}
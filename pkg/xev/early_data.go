@@ -0,0 +1,78 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "errors"
+
+// A listener with early data reads the first bytes of each accepted
+// connection before handing it over. In request/response protocols the
+// client sends its request right after connecting, so it has usually
+// arrived by the time the accept completes: the listener reads it there
+// without blocking, and the handler answers it without first arming a read
+// and waiting a loop iteration for it.
+
+// errNotReady is returned by recvNow when nothing has arrived yet.
+var errNotReady = errors.New("xev: no data ready")
+
+// WithEarlyData makes the listener read the first bytes of every accepted
+// connection, up to size, before passing it to the Accept handler, which
+// finds them in [TCPConn.EarlyData].
+//
+// The bytes already received when the accept completes are read right
+// away and the handler runs in the accept callback, as without the option.
+// Otherwise the listener reads on the loop and calls the handler from the
+// read callback, once they arrive; the [Action] it returns then is ignored,
+// as with [WithProtocols], and the listener keeps accepting until a handler
+// called from the accept returns [Stop] or the listener is closed. A
+// connection closed or failing before sending anything is closed without
+// reaching the handler; accept errors reach it as usual.
+//
+// WithEarlyData has no effect together with WithProtocols, which reads the
+// first bytes itself.
+func WithEarlyData(size int) ListenOption {
+	return func(l *TCPListener) {
+		l.earlySize = max(size, 1)
+	}
+}
+
+// EarlyData returns the first bytes of a connection accepted by a listener
+// with [WithEarlyData], or nil. They are a slice of a buffer of the
+// connection's own, which the handler may reuse for its reads once it is
+// done with them.
+func (c *TCPConn) EarlyData() []byte {
+	return c.earlyData
+}
+
+// readEarly reads the first bytes of conn. It returns true if they were
+// there already, so the handler can run now; otherwise conn is either
+// closed or waiting for them on the loop, and is handed over from there.
+func (l *TCPListener) readEarly(conn *TCPConn) bool {
+	buf := make([]byte, l.earlySize)
+	n, err := recvNow(int(conn.fd), buf)
+	if n > 0 {
+		conn.earlyData = buf[:n]
+		countIn(&conn.stats, l.loop, int32(n), 0)
+		return true
+	}
+	if err != errNotReady {
+		_ = conn.CloseFunc(l.loop, nil)
+		return false
+	}
+	err = conn.ReadFunc(l.loop, buf, func(c *TCPConn, data []byte, err error) Action {
+		if err != nil || len(data) == 0 || l.closing {
+			_ = c.CloseFunc(l.loop, nil)
+			return Stop
+		}
+		c.earlyData = data
+		l.onAccept(c, nil)
+		return Stop
+	})
+	if err != nil {
+		_ = conn.CloseFunc(l.loop, nil)
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestEarlyDataListener(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0", WithEarlyData(64))
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	var got []string
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("accept error: %v", err)
+			return Stop
+		}
+		got = append(got, string(conn.EarlyData()))
+		_ = conn.CloseFunc(loop, nil)
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	// One client sends right away, one after the accept has completed, and
+	// one hangs up without sending anything.
+	addr := "127.0.0.1:" + itoa(int(port))
+	for _, c := range []struct {
+		payload string
+		delay   time.Duration
+	}{{"*1\r\n$4\r\nPING\r\n", 0}, {"late", 50 * time.Millisecond}, {"", 0}} {
+		go func() {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			defer conn.Close()
+			time.Sleep(c.delay)
+			if c.payload != "" {
+				_, _ = conn.Write([]byte(c.payload))
+				time.Sleep(100 * time.Millisecond)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(got) < 2 && time.Now().Before(deadline) {
+		_ = loop.Poll()
+	}
+	// Give the silent client's connection time to be dropped, had it been
+	// handed over.
+	for end := time.Now().Add(100 * time.Millisecond); time.Now().Before(end); {
+		_ = loop.Poll()
+	}
+	slices.Sort(got)
+	if want := []string{"*1\r\n$4\r\nPING\r\n", "late"}; !slices.Equal(got, want) {
+		t.Fatalf("early data: got %q want %q", got, want)
+	}
+}
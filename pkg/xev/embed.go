@@ -0,0 +1,65 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// A loop normally owns a goroutine that blocks in [Loop.Run]. An
+// application that already has an event system, such as a GUI toolkit's
+// main loop or another runtime's poller, can instead embed the loop in it:
+// the host waits on the loop's backend descriptor along with its own
+// sources and runs the loop without blocking when that descriptor is
+// readable. One iteration of the host is then
+//
+//	loop.Poll()   // process ready completions
+//	loop.Submit() // submit what their callbacks started
+//	// wait until fd is readable or the next timer is due
+//	timeout := -1
+//	if at, ok := loop.NextTimerDeadline(); ok {
+//	    timeout = int(max(at-loop.Now(), 0) / time.Millisecond)
+//	}
+//	unix.Poll([]unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}, timeout)
+//
+// The timeout matters: on the epoll backend timers are kept by the loop,
+// not the kernel, and never make the descriptor readable.
+
+// BackendFd returns the descriptor the loop's backend waits on: the
+// io_uring ring on Linux, its epoll descriptor with the epoll backend, or
+// the kqueue on BSD and macOS. It becomes readable when the loop has
+// completions to process; then call [Loop.Poll], followed by
+// [Loop.Submit].
+//
+// The descriptor belongs to the loop: wait on it for readability only, and
+// not after [Loop.Close].
+//
+// Returns [ErrExtLibNotLoaded] without the extended library, and
+// [errors.ErrUnsupported] if the backend has no descriptor.
+func (l *Loop) BackendFd() (int, error) {
+	if !cxev.ExtLibLoaded() {
+		return -1, ErrExtLibNotLoaded
+	}
+	fd := cxev.LoopFd(&l.inner)
+	if fd < 0 {
+		return -1, errors.ErrUnsupported
+	}
+	return int(fd), nil
+}
+
+// Submit hands the operations started since the last run of the loop to
+// the kernel without waiting for any to complete. Operations started by
+// callbacks are only queued by the run that calls them; a loop embedded
+// in another event system must submit them before the host waits on
+// [Loop.BackendFd], or their completions would never wake it.
+//
+// Returns [ErrExtLibNotLoaded] without the extended library.
+func (l *Loop) Submit() error {
+	return cxev.LoopSubmit(&l.inner)
+}
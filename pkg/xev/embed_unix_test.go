@@ -0,0 +1,100 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestBackendFdWithoutLibrary(t *testing.T) {
+	if cxev.ExtLibLoaded() {
+		t.Skip("extended library loaded")
+	}
+	var l Loop
+	if _, err := l.BackendFd(); !errors.Is(err, ErrExtLibNotLoaded) {
+		t.Fatalf("BackendFd: %v, want ErrExtLibNotLoaded", err)
+	}
+	if err := l.Submit(); !errors.Is(err, ErrExtLibNotLoaded) {
+		t.Fatalf("Submit: %v, want ErrExtLibNotLoaded", err)
+	}
+}
+
+// TestEmbeddedLoop drives a loop from a poll(2) on its backend descriptor,
+// the way a host event system would.
+func TestEmbeddedLoop(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	fd, err := loop.BackendFd()
+	if err != nil {
+		t.Fatalf("BackendFd failed: %v", err)
+	}
+
+	conn, peer, err := Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	defer peer.Close()
+
+	var got []byte
+	err = conn.ReadFunc(loop, make([]byte, 16), func(c *TCPConn, data []byte, err error) Action {
+		if err != nil {
+			t.Errorf("read: %v", err)
+			return Stop
+		}
+		got = append(got, data...)
+		if len(got) < 4 {
+			return Continue
+		}
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("ReadFunc failed: %v", err)
+	}
+	if err := loop.Submit(); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = peer.Write([]byte("ping"))
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(got) < 4 && time.Now().Before(deadline) {
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		if _, err := unix.Poll(fds, 1000); err != nil && err != unix.EINTR {
+			t.Fatalf("poll: %v", err)
+		}
+		if err := loop.Poll(); err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+		if err := loop.Submit(); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	if string(got) != "ping" {
+		t.Fatalf("read %q, want ping", got)
+	}
+	_ = conn.CloseFunc(loop, nil)
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
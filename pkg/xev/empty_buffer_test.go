@@ -65,6 +65,40 @@ func TestEmptyBufferReturnsError(t *testing.T) {
 	}))
 }
 
+func TestTransportEmptyBufferReturnsError(t *testing.T) {
+	// The buffer is checked before any I/O is started, so connections
+	// without a socket and a nil loop are enough.
+	var loop *Loop
+	tcpConn := NewTransportConn(nil)
+	udpConn := NewPacketTransportConn(nil)
+
+	udpAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12345}
+
+	checkEmptyErr := func(name string, err error) {
+		t.Helper()
+		if !errors.Is(err, ErrEmptyBuffer) {
+			t.Fatalf("%s: expected ErrEmptyBuffer, got %v", name, err)
+		}
+	}
+
+	checkEmptyErr("tcp read", tcpConn.ReadFunc(loop, []byte{}, func(conn *TCPConn, data []byte, err error) Action {
+		return Stop
+	}))
+	checkEmptyErr("tcp write", tcpConn.WriteFunc(loop, []byte{}, func(conn *TCPConn, bytesWritten int, err error) Action {
+		return Stop
+	}))
+
+	checkEmptyErr("udp read", udpConn.ReadFromFunc(loop, []byte{}, func(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, err error) Action {
+		return Stop
+	}))
+	checkEmptyErr("udp write to", udpConn.WriteToFunc(loop, []byte{}, "127.0.0.1:12345", func(conn *UDPConn, bytesWritten int, err error) Action {
+		return Stop
+	}))
+	checkEmptyErr("udp write to addr", udpConn.WriteToAddrFunc(loop, []byte{}, udpAddr, func(conn *UDPConn, bytesWritten int, err error) Action {
+		return Stop
+	}))
+}
+
 func TestFileEmptyBufferReturnsError(t *testing.T) {
 	if !cxev.ExtLibLoaded() {
 		t.Skip("extended library not loaded")
@@ -0,0 +1,64 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// ErrClosed is returned by operations started on a connection, listener or
+// file after its Close, instead of submitting them on a descriptor that
+// may already belong to something else. It is [net.ErrClosed], so code
+// written against the net package recognizes it.
+var ErrClosed = net.ErrClosed
+
+// OpError is the error delivered to handlers when an async operation fails.
+//
+// When the failure has an errno equivalent, OpError unwraps to it, so callers
+// can test for specific conditions:
+//
+//	if errors.Is(err, syscall.EMFILE) {
+//	    // out of file descriptors
+//	}
+type OpError struct {
+	// Op names the failed operation, e.g. "accept" or "read".
+	Op string
+	// Code is the raw error code reported by the extended library.
+	Code int32
+	// Errno is the errno equivalent of Code, or 0 if there is none.
+	Errno syscall.Errno
+}
+
+func newOpError(op string, code int32) *OpError {
+	return &OpError{Op: op, Code: code, Errno: cxev.ErrnoFromCode(code)}
+}
+
+func (e *OpError) Error() string {
+	if e.Errno != 0 {
+		return e.Op + " error: " + e.Errno.Error()
+	}
+	return e.Op + " error: " + cxev.ErrorName(e.Code)
+}
+
+// Unwrap returns the errno equivalent of the failure, if any.
+func (e *OpError) Unwrap() error {
+	if e.Errno == 0 {
+		return nil
+	}
+	return e.Errno
+}
+
+// isFdExhaustion reports whether err means the process or system ran out of
+// file descriptors.
+func isFdExhaustion(err error) bool {
+	var op *OpError
+	return errors.As(err, &op) && (op.Errno == syscall.EMFILE || op.Errno == syscall.ENFILE)
+}
@@ -0,0 +1,41 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+func TestOpErrorUnwrapsErrno(t *testing.T) {
+	err := error(&OpError{Op: "accept", Code: 42, Errno: syscall.EMFILE})
+
+	if !errors.Is(err, syscall.EMFILE) {
+		t.Fatal("expected OpError to match syscall.EMFILE")
+	}
+	if !isFdExhaustion(fmt.Errorf("wrapped: %w", err)) {
+		t.Fatal("expected wrapped EMFILE to count as fd exhaustion")
+	}
+	if isFdExhaustion(&OpError{Op: "accept", Code: 7, Errno: syscall.ECONNABORTED}) {
+		t.Fatal("ECONNABORTED is not fd exhaustion")
+	}
+	if isFdExhaustion(nil) {
+		t.Fatal("nil is not fd exhaustion")
+	}
+}
+
+func TestOpErrorWithoutErrno(t *testing.T) {
+	err := &OpError{Op: "read", Code: 9}
+	if err.Unwrap() != nil {
+		t.Fatal("expected no wrapped errno")
+	}
+	if got := err.Error(); got != "read error: 9" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/crrow/libxev-go/pkg/cxev"
@@ -74,6 +75,11 @@ import (
 type File struct {
 	file cxev.File
 	fd   int32
+
+	// closed is set by Close; see ErrClosed.
+	closed atomic.Bool
+	// priority is the class of the file's operations; see SetPriority.
+	priority FilePriority
 }
 
 // FileReadHandler handles file read completions.
@@ -136,17 +142,57 @@ func (f FileCloseFunc) OnClose(file *File, err error) {
 // fileOp holds per-operation state including completion and callback ID.
 // Each async operation gets its own fileOp, allowing concurrent operations.
 // The completion and buffer must be pinned to prevent GC from moving them while C code holds pointers.
+//
+// Reads take their fileOp from a free list of the loop and return it when
+// done, so a steady stream of reads does not allocate. A read in flight is
+// reachable through its callback registration.
 type fileOp struct {
 	completion cxev.FileCompletion
 	file       *File
 	loop       *Loop
 	callbackID uintptr
+	span       *opSpan
 	buf        []byte         // for read operations, to pass to callback
 	pinner     runtime.Pinner // pins completion and buffer
+	// kind is the operation, offset its position for PRead and PWrite, and
+	// prio the queue it waits in when the loop caps the operations in
+	// flight.
+	kind   fileOpKind
+	offset uint64
+	prio   FilePriority
 
 	readHandler  FileReadHandler
 	writeHandler FileWriteHandler
 	closeHandler FileCloseHandler
+	// readDone is readCallback, bound when the op is created.
+	readDone cxev.FileReadCallback
+}
+
+// readOp returns an op for a read of buf into f, with its memory pinned.
+func (l *Loop) readOp(f *File, buf []byte, handler FileReadHandler) *fileOp {
+	var op *fileOp
+	if n := len(l.fileOps); n > 0 {
+		op = l.fileOps[n-1]
+		l.fileOps[n-1] = nil
+		l.fileOps = l.fileOps[:n-1]
+	} else {
+		op = &fileOp{}
+		op.readDone = op.readCallback
+	}
+	op.file, op.loop, op.buf, op.readHandler = f, l, buf, handler
+	op.pinner.Pin(&op.completion)
+	op.pinner.Pin(&buf[0])
+	op.pinner.Pin(&f.file)
+	return op
+}
+
+// releaseReadOp unpins a finished read op and returns it to the free list.
+// Its completion is left alone: libxev may look at it once the callback
+// returns, and initializes it again when the op is next submitted.
+func (l *Loop) releaseReadOp(op *fileOp) {
+	op.pinner.Unpin()
+	op.file, op.loop, op.span, op.buf, op.readHandler, op.callbackID = nil, nil, nil, nil, nil, 0
+	l.fileOps = append(l.fileOps, op)
 }
 
 var activeFileOps sync.Map
@@ -237,19 +283,14 @@ func (f *File) Read(loop *Loop, buf []byte, handler FileReadHandler) error {
 	if len(buf) == 0 {
 		return ErrEmptyBuffer
 	}
-
-	op := &fileOp{
-		file:        f,
-		loop:        loop,
-		buf:         buf,
-		readHandler: handler,
+	if f.closed.Load() {
+		return ErrClosed
 	}
-	op.pinner.Pin(&op.completion)
-	op.pinner.Pin(&buf[0])
-	op.pinner.Pin(&f.file)
 
-	op.callbackID = cxev.FileReadWithCallback(&f.file, &loop.inner, &op.completion, buf, op.readCallback)
-	activeFileOps.Store(op.callbackID, op)
+	op := loop.readOp(f, buf, handler)
+	op.kind, op.prio = fileRead, f.priority
+	op.span = loop.startOp("xev.file.read")
+	loop.submitFileOp(op)
 	return nil
 }
 
@@ -266,14 +307,16 @@ func (op *fileOp) readCallback(loop *cxev.Loop, c *cxev.FileCompletion, data []b
 		err = fmt.Errorf("read error: code=%d, bytesRead=%d", errCode, bytesRead)
 	}
 
-	action := op.readHandler.OnRead(op.file, data, err)
+	op.span.complete()
+	action := op.onRead(data, err)
+	op.span = op.span.finish(int(bytesRead), errCode, action)
+	op.loop.poolCompleted(action == Continue)
 	if action == Continue {
 		return cxev.Rearm
 	}
 
-	activeFileOps.Delete(op.callbackID)
-	op.pinner.Unpin()
 	cxev.UnregisterFileCallback(op.callbackID)
+	op.loop.releaseReadOp(op)
 	return cxev.Disarm
 }
 
@@ -284,19 +327,24 @@ func (f *File) Write(loop *Loop, data []byte, handler FileWriteHandler) error {
 	if len(data) == 0 {
 		return ErrEmptyBuffer
 	}
+	if f.closed.Load() {
+		return ErrClosed
+	}
 
 	op := &fileOp{
 		file:         f,
 		loop:         loop,
 		buf:          data,
 		writeHandler: handler,
+		kind:         fileWrite,
+		prio:         f.priority,
 	}
 	op.pinner.Pin(&op.completion)
 	op.pinner.Pin(&data[0])
 	op.pinner.Pin(&f.file)
 
-	op.callbackID = cxev.FileWriteWithCallback(&f.file, &loop.inner, &op.completion, data, op.writeCallback)
-	activeFileOps.Store(op.callbackID, op)
+	op.span = loop.startOp("xev.file.write")
+	loop.submitFileOp(op)
 	return nil
 }
 
@@ -313,7 +361,10 @@ func (op *fileOp) writeCallback(loop *cxev.Loop, c *cxev.FileCompletion, bytesWr
 		err = fmt.Errorf("write error: code=%d, bytesWritten=%d", errCode, bytesWritten)
 	}
 
-	action := op.writeHandler.OnWrite(op.file, int(bytesWritten), err)
+	op.span.complete()
+	action := op.onWrite(int(bytesWritten), err)
+	op.span = op.span.finish(int(bytesWritten), errCode, action)
+	op.loop.poolCompleted(action == Continue)
 	if action == Continue {
 		return cxev.Rearm
 	}
@@ -332,22 +383,22 @@ func (op *fileOp) writeCallback(loop *cxev.Loop, c *cxev.FileCompletion, bytesWr
 //
 // The offset is in bytes from the start of the file.
 func (f *File) PRead(loop *Loop, buf []byte, offset uint64, handler FileReadHandler) error {
+	return f.pread(loop, buf, offset, f.priority, handler)
+}
+
+// pread is PRead in the priority class prio.
+func (f *File) pread(loop *Loop, buf []byte, offset uint64, prio FilePriority, handler FileReadHandler) error {
 	if len(buf) == 0 {
 		return ErrEmptyBuffer
 	}
-
-	op := &fileOp{
-		file:        f,
-		loop:        loop,
-		buf:         buf,
-		readHandler: handler,
+	if f.closed.Load() {
+		return ErrClosed
 	}
-	op.pinner.Pin(&op.completion)
-	op.pinner.Pin(&buf[0])
-	op.pinner.Pin(&f.file)
 
-	op.callbackID = cxev.FilePReadWithCallback(&f.file, &loop.inner, &op.completion, buf, offset, op.readCallback)
-	activeFileOps.Store(op.callbackID, op)
+	op := loop.readOp(f, buf, handler)
+	op.kind, op.offset, op.prio = filePRead, offset, prio
+	op.span = loop.startOp("xev.file.pread")
+	loop.submitFileOp(op)
 	return nil
 }
 
@@ -366,22 +417,33 @@ func (f *File) PReadFunc(loop *Loop, buf []byte, offset uint64, fn func(file *Fi
 //
 // The offset is in bytes from the start of the file.
 func (f *File) PWrite(loop *Loop, data []byte, offset uint64, handler FileWriteHandler) error {
+	return f.pwrite(loop, data, offset, f.priority, handler)
+}
+
+// pwrite is PWrite in the priority class prio.
+func (f *File) pwrite(loop *Loop, data []byte, offset uint64, prio FilePriority, handler FileWriteHandler) error {
 	if len(data) == 0 {
 		return ErrEmptyBuffer
 	}
+	if f.closed.Load() {
+		return ErrClosed
+	}
 
 	op := &fileOp{
 		file:         f,
 		loop:         loop,
 		buf:          data,
 		writeHandler: handler,
+		kind:         filePWrite,
+		offset:       offset,
+		prio:         prio,
 	}
 	op.pinner.Pin(&op.completion)
 	op.pinner.Pin(&data[0])
 	op.pinner.Pin(&f.file)
 
-	op.callbackID = cxev.FilePWriteWithCallback(&f.file, &loop.inner, &op.completion, data, offset, op.writeCallback)
-	activeFileOps.Store(op.callbackID, op)
+	op.span = loop.startOp("xev.file.pwrite")
+	loop.submitFileOp(op)
 	return nil
 }
 
@@ -394,33 +456,43 @@ func (f *File) PWriteFunc(loop *Loop, data []byte, offset uint64, fn func(file *
 
 // Close starts an async close operation.
 //
-// The handler (if non-nil) is called when the close completes.
+// The handler (if non-nil) is called when the close completes. Operations
+// started after Close return [ErrClosed], as for [TCPConn.Close].
 func (f *File) Close(loop *Loop, handler FileCloseHandler) error {
+	if !f.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
 	op := &fileOp{
 		file:         f,
 		loop:         loop,
 		closeHandler: handler,
+		kind:         fileClose,
+		prio:         f.priority,
 	}
 	op.pinner.Pin(&op.completion)
 	op.pinner.Pin(&f.file)
 
-	op.callbackID = cxev.FileCloseWithCallback(&f.file, &loop.inner, &op.completion, func(loop *cxev.Loop, c *cxev.FileCompletion, result int32, userdata uintptr) cxev.CbAction {
-		var err error
-		if result != 0 {
-			err = errors.New("close error")
-		}
-		if op.closeHandler != nil {
-			op.closeHandler.OnClose(op.file, err)
-		}
-		activeFileOps.Delete(op.callbackID)
-		op.pinner.Unpin()
-		cxev.UnregisterFileCallback(op.callbackID)
-		return cxev.Disarm
-	})
-	activeFileOps.Store(op.callbackID, op)
+	op.span = loop.startOp("xev.file.close")
+	loop.submitFileOp(op)
 	return nil
 }
 
+func (op *fileOp) closeCallback(loop *cxev.Loop, c *cxev.FileCompletion, result int32, userdata uintptr) cxev.CbAction {
+	var err error
+	if result != 0 {
+		err = errors.New("close error")
+	}
+	op.span = op.span.finish(0, result, Stop)
+	if op.closeHandler != nil {
+		op.onClosed(err)
+	}
+	activeFileOps.Delete(op.callbackID)
+	op.pinner.Unpin()
+	cxev.UnregisterFileCallback(op.callbackID)
+	op.loop.poolCompleted(false)
+	return cxev.Disarm
+}
+
 // CloseFunc starts an async close using a callback function.
 //
 // This is a convenience wrapper around [File.Close] for functional-style callbacks.
@@ -0,0 +1,150 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "github.com/crrow/libxev-go/pkg/cxev"
+
+// The thread pool runs the file operations it is given in the order they
+// arrive, so a large background job, such as a copy, queues ahead of the
+// reads a client is waiting for. A loop with WithFileConcurrency hands the
+// pool no more than a few operations at a time and keeps the rest itself,
+// in a queue for each priority class, starting foreground operations
+// before background ones as slots free up.
+
+// FilePriority is the scheduling class of a file operation.
+type FilePriority int
+
+const (
+	// PriorityForeground is for operations something is waiting on, such
+	// as a client request. It is the default.
+	PriorityForeground FilePriority = iota
+	// PriorityBackground is for bulk work that can wait, such as copies,
+	// compaction or prefetching.
+	PriorityBackground
+)
+
+// String returns "foreground" or "background".
+func (p FilePriority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "foreground"
+}
+
+// fileOpKind is the operation of a fileOp.
+type fileOpKind uint8
+
+const (
+	fileRead fileOpKind = iota
+	filePRead
+	fileWrite
+	filePWrite
+	fileClose
+)
+
+// WithFileConcurrency caps the file operations the loop has in flight on
+// its thread pool at n. Operations started past the cap wait on the loop
+// until one in flight completes, foreground ones first; within a class,
+// and so for the operations of a file, they start in order. A read
+// returning [Continue] keeps its place in flight.
+//
+// Without the cap, which is the default, operations go to the pool as they
+// are started and priorities have no effect. A cap around the pool's
+// worker count keeps the pool's own queue short, so that foreground work
+// waits behind little else. Backends that do file I/O in the kernel, such
+// as io_uring, are not capped.
+func WithFileConcurrency(n int) LoopOption {
+	return func(c *loopConfig) {
+		c.fileConcurrency = n
+	}
+}
+
+// SetPriority sets the class of the operations started on the file from
+// now on; see [WithFileConcurrency]. Operations already started keep
+// theirs.
+func (f *File) SetPriority(p FilePriority) {
+	f.priority = p
+}
+
+// Priority returns the class of the file's operations.
+func (f *File) Priority() FilePriority {
+	return f.priority
+}
+
+// submitFileOp starts op, or queues it if the loop has as many operations
+// in flight as it allows.
+func (l *Loop) submitFileOp(op *fileOp) {
+	if l.fileConcurrency > 0 && l.poolFileIO && l.poolTasks.pending >= l.fileConcurrency {
+		l.fileQueues[op.prio] = append(l.fileQueues[op.prio], op)
+		return
+	}
+	op.start()
+}
+
+// startQueuedFileOps starts queued operations while there is room in
+// flight, foreground first.
+func (l *Loop) startQueuedFileOps() {
+	for l.poolTasks.pending < l.fileConcurrency {
+		op := l.nextQueuedFileOp()
+		if op == nil {
+			return
+		}
+		op.start()
+	}
+}
+
+// nextQueuedFileOp takes the next operation to start off its queue, or
+// returns nil if none is waiting.
+func (l *Loop) nextQueuedFileOp() *fileOp {
+	for i := range l.fileQueues {
+		q := &l.fileQueues[i]
+		if len(*q) > 0 {
+			op := (*q)[0]
+			(*q)[0] = nil
+			*q = (*q)[1:]
+			return op
+		}
+	}
+	return nil
+}
+
+// queuedFileOps returns the number of operations waiting to start.
+func (l *Loop) queuedFileOps() int {
+	return len(l.fileQueues[PriorityForeground]) + len(l.fileQueues[PriorityBackground])
+}
+
+// dropQueuedFileOps forgets the operations that never started, when the
+// loop is closed.
+func (l *Loop) dropQueuedFileOps() {
+	for i := range l.fileQueues {
+		for _, op := range l.fileQueues[i] {
+			op.pinner.Unpin()
+		}
+		l.fileQueues[i] = nil
+	}
+}
+
+// start submits op to libxev.
+func (op *fileOp) start() {
+	f, loop := op.file, op.loop
+	switch op.kind {
+	case fileRead:
+		op.callbackID = cxev.FileReadWithCallback(&f.file, &loop.inner, &op.completion, op.buf, op.readDone)
+	case filePRead:
+		op.callbackID = cxev.FilePReadWithCallback(&f.file, &loop.inner, &op.completion, op.buf, op.offset, op.readDone)
+	case fileWrite:
+		op.callbackID = cxev.FileWriteWithCallback(&f.file, &loop.inner, &op.completion, op.buf, op.writeCallback)
+		activeFileOps.Store(op.callbackID, op)
+	case filePWrite:
+		op.callbackID = cxev.FilePWriteWithCallback(&f.file, &loop.inner, &op.completion, op.buf, op.offset, op.writeCallback)
+		activeFileOps.Store(op.callbackID, op)
+	case fileClose:
+		op.callbackID = cxev.FileCloseWithCallback(&f.file, &loop.inner, &op.completion, op.closeCallback)
+		activeFileOps.Store(op.callbackID, op)
+	}
+	loop.poolSubmitted()
+}
@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestFileQueuesForegroundFirst(t *testing.T) {
+	loop := &Loop{poolFileIO: true, fileConcurrency: 1}
+	loop.poolTasks.pending = 1
+
+	var ops []*fileOp
+	for _, prio := range []FilePriority{PriorityBackground, PriorityForeground, PriorityBackground, PriorityForeground} {
+		op := &fileOp{prio: prio, offset: uint64(len(ops))}
+		ops = append(ops, op)
+		loop.submitFileOp(op)
+	}
+	if n := loop.queuedFileOps(); n != 4 {
+		t.Fatalf("%d operations queued with the pool full, want 4", n)
+	}
+
+	var order []uint64
+	for op := loop.nextQueuedFileOp(); op != nil; op = loop.nextQueuedFileOp() {
+		order = append(order, op.offset)
+	}
+	if want := []uint64{1, 3, 0, 2}; !slices.Equal(order, want) {
+		t.Fatalf("start order %v, want %v", order, want)
+	}
+}
+
+func TestFileConcurrencyMixedLoad(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoopWithThreadPool(WithFileConcurrency(1))
+	if err != nil {
+		t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+	}
+	defer loop.Close()
+	if !loop.poolFileIO {
+		t.Skip("file I/O does not use the thread pool on this backend")
+	}
+
+	dir := t.TempDir()
+	bulk, err := OpenFile(filepath.Join(dir, "bulk"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	bulk.SetPriority(PriorityBackground)
+	interactive, err := OpenFile(filepath.Join(dir, "interactive"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+
+	// A backlog of background writes, then foreground ones: only the
+	// first background write goes ahead of them.
+	const n = 8
+	var order []string
+	chunk := make([]byte, 64<<10)
+	for i := range n {
+		err := bulk.PWriteFunc(loop, chunk, uint64(i*len(chunk)), func(*File, int, error) Action {
+			order = append(order, "bg")
+			return Stop
+		})
+		if err != nil {
+			t.Fatalf("PWrite failed: %v", err)
+		}
+	}
+	for i := range n {
+		err := interactive.PWriteFunc(loop, []byte("x"), uint64(i), func(*File, int, error) Action {
+			order = append(order, "fg")
+			return Stop
+		})
+		if err != nil {
+			t.Fatalf("PWrite failed: %v", err)
+		}
+	}
+	if p := loop.Stats().Pool; p.Waiting != 2*n-1 {
+		t.Fatalf("%d operations waiting, want %d", p.Waiting, 2*n-1)
+	}
+
+	for i := 0; i < 1000 && len(order) < 2*n; i++ {
+		loop.RunOnce()
+	}
+	want := []string{"bg"}
+	for range n {
+		want = append(want, "fg")
+	}
+	for range n - 1 {
+		want = append(want, "bg")
+	}
+	if !slices.Equal(order, want) {
+		t.Fatalf("completion order %v, want %v", order, want)
+	}
+	if p := loop.Stats().Pool; p.Waiting != 0 || p.Completed != 2*n {
+		t.Fatalf("pool stats after the load: %+v", p)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"cmp"
+	"errors"
+	"runtime"
+	"slices"
+	"unsafe"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// On io_uring, the kernel looks up the descriptor and maps the buffer of
+// every read and write it is handed, taking and dropping references as it
+// goes. A ring can have files and buffers registered with it beforehand,
+// and operations that name them by index skip that work. A loop with
+// WithFixedFiles gives each connection it reads or writes a slot in a file
+// table registered with its ring, and one with registered buffers
+// (Loop.RegisterBuffers) submits the reads and writes that fall within
+// them as READ_FIXED and WRITE_FIXED. Both are transparent: connections
+// read and write the same way, and loops on other backends, or on kernels
+// that refuse the registration, do so without them.
+
+// fixedBuffer is a registered buffer, by address range.
+type fixedBuffer struct {
+	start, end uintptr
+	index      int32
+}
+
+// WithFixedFiles registers a table of n file slots with the loop's
+// io_uring ring. Each TCP connection takes a slot the first time it reads
+// or writes on the loop, while slots are free, and gives it back when
+// closed; its operations then name the slot rather than the descriptor.
+// Servers with many busy connections save a descriptor lookup per
+// operation.
+//
+// The default, zero, registers no table. Sparse file tables need Linux
+// 5.19; on older kernels, on other backends and without the extended
+// library the loop runs without one.
+func WithFixedFiles(n int) LoopOption {
+	return func(c *loopConfig) {
+		c.fixedFiles = n
+	}
+}
+
+// initFixedFiles registers the file table cfg asks for, if the ring takes
+// it.
+func (l *Loop) initFixedFiles(cfg loopConfig) {
+	if cfg.fixedFiles <= 0 || !cxev.ExtLibLoaded() {
+		return
+	}
+	if cxev.LoopRegisterFiles(&l.inner, uint32(cfg.fixedFiles)) != nil {
+		return
+	}
+	l.freeFileSlots = make([]int32, cfg.fixedFiles)
+	for i := range l.freeFileSlots {
+		// Hand out the lowest slots first.
+		l.freeFileSlots[i] = int32(cfg.fixedFiles - 1 - i)
+	}
+}
+
+// RegisterBuffers registers bufs with the loop's io_uring ring, replacing
+// the buffers registered before; calling it with none unregisters them.
+// Reads into and writes from a slice of a registered buffer are then
+// submitted against it, so the kernel does not map the memory for each
+// one. Register a few large buffers, such as a slab that connection
+// buffers are carved from, rather than many small ones.
+//
+// The loop keeps the buffers pinned until they are replaced or the loop
+// is closed. Replacing them while reads or writes into them are in flight
+// is safe; those operations keep the mapping they were submitted with.
+//
+// On other backends it does nothing and returns nil. Without the extended
+// library it returns [ErrExtLibNotLoaded]. It returns an error if the
+// kernel refuses the buffers, such as for exceeding RLIMIT_MEMLOCK.
+func (l *Loop) RegisterBuffers(bufs ...[]byte) error {
+	if !cxev.ExtLibLoaded() {
+		return ErrExtLibNotLoaded
+	}
+	var pin runtime.Pinner
+	fixed := make([]fixedBuffer, 0, len(bufs))
+	for i, b := range bufs {
+		if len(b) == 0 {
+			pin.Unpin()
+			return ErrEmptyBuffer
+		}
+		pin.Pin(&b[0])
+		start := uintptr(unsafe.Pointer(&b[0]))
+		fixed = append(fixed, fixedBuffer{start: start, end: start + uintptr(len(b)), index: int32(i)})
+	}
+	err := cxev.LoopRegisterBuffers(&l.inner, bufs)
+	if errors.Is(err, errors.ErrUnsupported) {
+		pin.Unpin()
+		return nil
+	}
+	// The ring dropped the buffers registered before either way.
+	l.fixedPin.Unpin()
+	l.fixedPin, l.fixedBufs = runtime.Pinner{}, nil
+	if err != nil {
+		pin.Unpin()
+		return err
+	}
+	slices.SortFunc(fixed, func(a, b fixedBuffer) int {
+		return cmp.Compare(a.start, b.start)
+	})
+	l.fixedPin, l.fixedBufs = pin, fixed
+	return nil
+}
+
+// fixedBuffer returns the index of the registered buffer buf lies within,
+// or -1.
+func (l *Loop) fixedBuffer(buf []byte) int32 {
+	if len(l.fixedBufs) == 0 || len(buf) == 0 {
+		return -1
+	}
+	p := uintptr(unsafe.Pointer(&buf[0]))
+	i, found := slices.BinarySearchFunc(l.fixedBufs, p, func(b fixedBuffer, p uintptr) int {
+		return cmp.Compare(b.start, p)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || p+uintptr(len(buf)) > l.fixedBufs[i].end {
+		return -1
+	}
+	return l.fixedBufs[i].index
+}
+
+// fixedFile returns the slot of the loop's file table c's socket is in,
+// putting it in a free one if it has none, or -1.
+func (c *TCPConn) fixedFile(loop *Loop) int32 {
+	if c.fileLoop != nil {
+		if c.fileLoop == loop {
+			return c.fileSlot
+		}
+		return -1
+	}
+	n := len(loop.freeFileSlots)
+	if n == 0 {
+		return -1
+	}
+	slot := loop.freeFileSlots[n-1]
+	if cxev.LoopUpdateFile(&loop.inner, uint32(slot), cxev.TCPFd(&c.tcp)) != nil {
+		return -1
+	}
+	loop.freeFileSlots = loop.freeFileSlots[:n-1]
+	c.fileLoop, c.fileSlot = loop, slot
+	return slot
+}
+
+// releaseFixedFile empties c's slot, which keeps the socket open while it
+// holds it, before the socket is closed.
+func (c *TCPConn) releaseFixedFile() {
+	loop := c.fileLoop
+	if loop == nil {
+		return
+	}
+	c.fileLoop = nil
+	if loop.closed {
+		return
+	}
+	if cxev.LoopUpdateFile(&loop.inner, uint32(c.fileSlot), -1) == nil {
+		loop.freeFileSlots = append(loop.freeFileSlots, c.fileSlot)
+	}
+}
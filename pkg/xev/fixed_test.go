@@ -0,0 +1,203 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"unsafe"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestFixedBufferLookup(t *testing.T) {
+	slab := make([]byte, 4096)
+	other := make([]byte, 512)
+	var l Loop
+	for i, b := range [][]byte{slab, other} {
+		start := uintptr(unsafe.Pointer(&b[0]))
+		l.fixedBufs = append(l.fixedBufs, fixedBuffer{start: start, end: start + uintptr(len(b)), index: int32(i)})
+	}
+	if l.fixedBufs[0].start > l.fixedBufs[1].start {
+		l.fixedBufs[0], l.fixedBufs[1] = l.fixedBufs[1], l.fixedBufs[0]
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+		want int32
+	}{
+		{"whole slab", slab, 0},
+		{"slab slice", slab[1024:2048], 0},
+		{"slab tail", slab[4000:], 0},
+		{"other", other[10:20], 1},
+		{"unregistered", make([]byte, 64), -1},
+		{"empty", slab[:0], -1},
+	}
+	for _, tt := range tests {
+		if got := l.fixedBuffer(tt.buf); got != tt.want {
+			t.Errorf("%s: fixedBuffer = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// A slice that starts in a registered buffer and runs past its end is
+	// not within it.
+	wide := make([]byte, 8192)
+	start := uintptr(unsafe.Pointer(&wide[0]))
+	l.fixedBufs = []fixedBuffer{{start: start, end: start + 4096, index: 0}}
+	if got := l.fixedBuffer(wide[2048:6144]); got != -1 {
+		t.Errorf("straddling slice: fixedBuffer = %d, want -1", got)
+	}
+}
+
+func TestFixedEcho(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop(WithFixedFiles(4))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	slab := make([]byte, 64<<10)
+	if err := loop.RegisterBuffers(slab); err != nil {
+		t.Fatalf("RegisterBuffers failed: %v", err)
+	}
+	slots := len(loop.freeFileSlots)
+
+	client, done := startFixedEcho(t, loop, slab[:4096])
+	msg := bytes.Repeat([]byte("fixed"), 200)
+	got := make([]byte, len(msg))
+	for range 10 {
+		if _, err := client.Write(msg); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if _, err := io.ReadFull(client, got); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatal("echo does not match")
+		}
+	}
+	_ = client.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if got := len(loop.freeFileSlots); got != slots {
+		t.Fatalf("free file slots = %d after close, want %d", got, slots)
+	}
+	if n := cxev.DebugTCPCallbackCount(); n != 0 {
+		t.Fatalf("expected no TCP callback leaks, found %d active registrations", n)
+	}
+}
+
+// BenchmarkTCPEchoFixed echoes 16KiB messages through a loop with and
+// without a registered file table and buffer. On io_uring the fixed
+// variants save the descriptor lookup and the mapping of the buffer for
+// every read and write; on other backends all four run the same path.
+func BenchmarkTCPEchoFixed(b *testing.B) {
+	if !cxev.ExtLibLoaded() {
+		b.Skip("extended library not loaded")
+	}
+	for _, tc := range []struct {
+		name          string
+		files, buffer bool
+	}{
+		{"plain", false, false},
+		{"files", true, false},
+		{"buffers", false, true},
+		{"files+buffers", true, true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			var opts []LoopOption
+			if tc.files {
+				opts = append(opts, WithFixedFiles(16))
+			}
+			loop, err := NewLoop(opts...)
+			if err != nil {
+				b.Fatalf("NewLoop failed: %v", err)
+			}
+			defer loop.Close()
+			slab := make([]byte, 1<<20)
+			if tc.buffer {
+				if err := loop.RegisterBuffers(slab); err != nil {
+					b.Fatalf("RegisterBuffers failed: %v", err)
+				}
+			}
+
+			client, done := startFixedEcho(b, loop, slab[:64<<10])
+			msg := make([]byte, 16<<10)
+			got := make([]byte, len(msg))
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.Write(msg); err != nil {
+					b.Fatalf("write failed: %v", err)
+				}
+				if _, err := io.ReadFull(client, got); err != nil {
+					b.Fatalf("read failed: %v", err)
+				}
+			}
+			b.StopTimer()
+			_ = client.Close()
+			if err := <-done; err != nil {
+				b.Fatalf("Run failed: %v", err)
+			}
+		})
+	}
+}
+
+// startFixedEcho runs an echo server on loop that reads into and writes
+// from buf, and returns a client connected to it and the result of the
+// loop's Run, which returns once the client closes.
+func startFixedEcho(tb testing.TB, loop *Loop, buf []byte) (net.Conn, <-chan error) {
+	tb.Helper()
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("Listen failed: %v", err)
+	}
+	tb.Cleanup(func() { _ = listener.CloseNow() })
+	_, port := listener.Addr()
+
+	var echo func(c *TCPConn, data []byte, err error) Action
+	echo = func(c *TCPConn, data []byte, err error) Action {
+		if err != nil || len(data) == 0 {
+			c.CloseFunc(loop, nil)
+			return Stop
+		}
+		c.WriteFunc(loop, data, func(c *TCPConn, _ int, err error) Action {
+			if err != nil {
+				c.CloseFunc(loop, nil)
+				return Stop
+			}
+			c.ReadFunc(loop, buf, echo)
+			return Stop
+		})
+		return Stop
+	}
+	err = listener.AcceptFunc(loop, func(_ *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			return Stop
+		}
+		conn.ReadFunc(loop, buf, echo)
+		return Stop
+	})
+	if err != nil {
+		tb.Fatalf("Accept failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- loop.Run() }()
+
+	client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+	if err != nil {
+		tb.Fatalf("dial failed: %v", err)
+	}
+	return client, done
+}
@@ -0,0 +1,155 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// HookKind is the point in a loop iteration at which a hook runs, after
+// libuv's idle, prepare and check handles. An iteration runs the idle
+// hooks, then the prepare hooks, then polls for events and runs their
+// callbacks, then runs the check hooks.
+type HookKind uint8
+
+const (
+	// HookIdle runs at the start of every iteration. While an idle hook is
+	// registered the loop polls without blocking, so it keeps spinning and
+	// the hook runs as often as the loop can iterate.
+	HookIdle HookKind = iota + 1
+	// HookPrepare runs right before the loop polls for events.
+	HookPrepare
+	// HookCheck runs right after the loop has polled for events and run
+	// their callbacks.
+	HookCheck
+)
+
+func (k HookKind) String() string {
+	switch k {
+	case HookIdle:
+		return "idle"
+	case HookPrepare:
+		return "prepare"
+	case HookCheck:
+		return "check"
+	default:
+		return "unknown"
+	}
+}
+
+// hook is a registered hook; removed is set once its remove function has
+// been called, so an iteration already running the hooks skips it.
+type hook struct {
+	fn      func()
+	removed bool
+}
+
+// loopHooks holds a loop's hooks by kind. Removing a hook replaces its
+// kind's slice rather than changing it, so the slice an iteration is
+// ranging over stays intact.
+type loopHooks struct {
+	idle, prepare, check []*hook
+}
+
+func (h *loopHooks) list(kind HookKind) *[]*hook {
+	switch kind {
+	case HookIdle:
+		return &h.idle
+	case HookPrepare:
+		return &h.prepare
+	default:
+		return &h.check
+	}
+}
+
+func (h *loopHooks) any() bool {
+	return len(h.idle)+len(h.prepare)+len(h.check) > 0
+}
+
+func (h *loopHooks) run(hooks []*hook) {
+	for _, hk := range hooks {
+		if !hk.removed {
+			hk.fn()
+		}
+	}
+}
+
+// AddHook registers fn to run on the loop's goroutine at the point of every
+// loop iteration given by kind, for integrating work such as a custom
+// scheduler that has to run around the poll. Hooks run in the order they
+// were added, and may add and remove hooks. remove unregisters fn; it is
+// safe to call more than once.
+//
+// Hooks do not keep the loop alive: [Loop.Run] still returns once no
+// operations are left. Run only calls hooks if one was registered when it
+// started; [Loop.RunOnce], [Loop.Poll] and [Loop.RunContext] call the
+// hooks registered at each iteration.
+//
+// Returns [ErrExtLibNotLoaded] if the extended library is not available,
+// and an error if fn is nil or kind is unknown.
+func (l *Loop) AddHook(kind HookKind, fn func()) (remove func(), err error) {
+	if fn == nil {
+		return nil, errors.New("hook cannot be nil")
+	}
+	if kind < HookIdle || kind > HookCheck {
+		return nil, errors.New("unknown hook kind")
+	}
+	if !cxev.ExtLibLoaded() {
+		return nil, ErrExtLibNotLoaded
+	}
+	hk := &hook{fn: fn}
+	list := l.hooks.list(kind)
+	*list = append(*list, hk)
+	return func() {
+		if hk.removed {
+			return
+		}
+		hk.removed = true
+		kept := make([]*hook, 0, len(*list)-1)
+		for _, other := range *list {
+			if other != hk {
+				kept = append(kept, other)
+			}
+		}
+		*list = kept
+	}, nil
+}
+
+// iterate runs one iteration of the loop in mode, calling the hooks around
+// it and first running the work carried over from the last iteration. With
+// idle hooks registered or work still carried over, a mode that would block
+// polls instead.
+func (l *Loop) iterate(mode cxev.RunMode) error {
+	if !l.stepped() {
+		return l.panicked(cxev.LoopRun(&l.inner, mode))
+	}
+	if len(l.hooks.idle) > 0 {
+		l.hooks.run(l.hooks.idle)
+		mode = cxev.RunNoWait
+	}
+	l.runCarried()
+	if len(l.carried) > 0 {
+		mode = cxev.RunNoWait
+	}
+	l.hooks.run(l.hooks.prepare)
+	err := cxev.LoopRun(&l.inner, mode)
+	l.hooks.run(l.hooks.check)
+	return l.panicked(err)
+}
+
+// runStepped runs the loop until it has nothing left to do, one iteration
+// at a time so the hooks and the callback budget apply to each.
+func (l *Loop) runStepped() error {
+	for l.alive() {
+		if err := l.iterate(cxev.RunOnce); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestLoopHooksOrder(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	var events []string
+	for _, kind := range []HookKind{HookCheck, HookPrepare} {
+		kind := kind
+		if _, err := loop.AddHook(kind, func() { events = append(events, kind.String()) }); err != nil {
+			t.Fatalf("AddHook(%s) failed: %v", kind, err)
+		}
+	}
+	if _, err := loop.Schedule(time.Millisecond, func() Action {
+		events = append(events, "timer")
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	// Iterations before the timer fires run prepare, then check; the last
+	// one runs the timer between them.
+	n := len(events)
+	if n < 3 || !reflect.DeepEqual(events[n-3:], []string{"prepare", "timer", "check"}) {
+		t.Fatalf("events = %v, want them to end with prepare, timer, check", events)
+	}
+	for i := 0; i < n-3; i += 2 {
+		if events[i] != "prepare" || events[i+1] != "check" {
+			t.Fatalf("iteration %d ran %v", i/2, events[i:i+2])
+		}
+	}
+}
+
+func TestLoopIdleHookRemove(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	// An idle hook keeps RunOnce from blocking on the long timer.
+	idle := 0
+	var remove func()
+	remove, err = loop.AddHook(HookIdle, func() {
+		idle++
+		if idle == 3 {
+			remove()
+			remove()
+		}
+	})
+	if err != nil {
+		t.Fatalf("AddHook failed: %v", err)
+	}
+	cancel, err := loop.Schedule(time.Hour, func() Action { return Stop })
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		if err := loop.RunOnce(); err != nil {
+			t.Fatalf("Loop.RunOnce failed: %v", err)
+		}
+	}
+	if idle != 3 {
+		t.Fatalf("idle hook ran %d times, want 3", idle)
+	}
+	if loop.hooks.any() {
+		t.Fatal("removed hook is still registered")
+	}
+}
+
+func TestLoopAddHookInvalid(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	if _, err := loop.AddHook(HookPrepare, nil); err == nil {
+		t.Fatal("AddHook with a nil hook should fail")
+	}
+	if _, err := loop.AddHook(HookKind(0), func() {}); err == nil {
+		t.Fatal("AddHook with an unknown kind should fail")
+	}
+}
@@ -0,0 +1,31 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"syscall"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Logger receives reports of internal conditions that have no caller to
+// return an error to. It is [cxev.Logger]; [*slog.Logger] implements it.
+type Logger = cxev.Logger
+
+// SetLogger sets the logger for internal conditions of xev and cxev, such
+// as a descriptor that fails to close or a completion for a callback that
+// is no longer registered. A nil l restores the default, [slog.Default].
+func SetLogger(l Logger) {
+	cxev.SetLogger(l)
+}
+
+// closeFD closes fd, reporting a failure.
+func closeFD(fd int) {
+	if err := syscall.Close(fd); err != nil {
+		cxev.GetLogger().Warn("xev: close failed", "fd", fd, "err", err)
+	}
+}
@@ -45,8 +45,11 @@
 package xev
 
 import (
+	"runtime"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/crrow/libxev-go/pkg/cxev"
 )
 
@@ -61,7 +64,7 @@ import (
 //
 // A Loop is NOT thread-safe. All operations on a Loop and its associated
 // watchers must be performed from the same goroutine. For cross-goroutine
-// communication, use channels or the Async watcher (not yet implemented).
+// communication, use [Loop.Post] (see [WithPost]) or a [Notifier].
 //
 // # Lifecycle
 //
@@ -80,6 +83,57 @@ type Loop struct {
 	inner      cxev.Loop
 	threadPool cxev.ThreadPool
 	hasPool    bool
+	// poolFileIO is set when file operations run on the thread pool, and
+	// poolTasks counts them.
+	poolFileIO bool
+	poolTasks  poolTasks
+	tracer     trace.Tracer
+	stats      Stats
+	// busyPoll is the spin before blocking set by WithBusyPoll.
+	busyPoll time.Duration
+	// cpu is the CPU Run pins to, set by WithCPU, or -1.
+	cpu int
+	// lockOSThread locks Run to its thread, set by WithLockOSThread.
+	lockOSThread bool
+	// fileOps holds finished file read ops for reuse.
+	fileOps []*fileOp
+	// fileConcurrency is the cap on file operations in flight set by
+	// WithFileConcurrency, and fileQueues holds those waiting, by priority.
+	fileConcurrency int
+	fileQueues      [2][]*fileOp
+	// timers holds the armed timers by deadline; see NextTimerDeadline.
+	timers timerQueue
+	// post runs the functions given to Post, set by WithPost.
+	post *Notifier
+	// hooks holds the hooks registered with AddHook.
+	hooks loopHooks
+	// budget is the cap on I/O callbacks per iteration set by
+	// WithCallbackBudget, dispatched counts them, and carried holds the
+	// work put off to the next iteration. budgetTime is the cap set by
+	// WithCallbackTimeBudget, timed from budgetStart; exhausted is set once
+	// the iteration reaches either.
+	budget      int
+	dispatched  int
+	carried     []func()
+	budgetTime  time.Duration
+	budgetStart time.Time
+	exhausted   bool
+	budgetStats BudgetStats
+	// panicHandler is the policy for handler panics set by
+	// SetPanicHandler, and panicErr the panic it propagated.
+	panicHandler func(*PanicError) PanicPolicy
+	panicErr     *PanicError
+	// fixedBufs are the buffers registered with RegisterBuffers, by
+	// address, pinned by fixedPin, and freeFileSlots the free slots of the
+	// file table registered by WithFixedFiles.
+	fixedBufs     []fixedBuffer
+	fixedPin      runtime.Pinner
+	freeFileSlots []int32
+	// submitThreshold is the queue length at which starting an operation
+	// submits those queued, set by WithSubmitThreshold.
+	submitThreshold uint32
+	// closed is set by Close.
+	closed bool
 }
 
 // NewLoop creates a new event loop.
@@ -89,10 +143,41 @@ type Loop struct {
 //
 // For file I/O operations (which may block), use [NewLoopWithThreadPool] instead.
 //
-// Returns an error if the underlying OS event mechanism cannot be initialized.
-func NewLoop() (*Loop, error) {
+// Options such as [WithTracerProvider] customize the loop.
+//
+// Returns an error if the underlying OS event mechanism cannot be initialized,
+// [ErrInvalidRingEntries] for a bad [WithRingEntries] size,
+// [ErrInvalidSQPoll] for a bad [WithSQPoll] idle time, or
+// [ErrInvalidSubmitThreshold] for a bad [WithSubmitThreshold] threshold.
+func NewLoop(opts ...LoopOption) (*Loop, error) {
 	l := &Loop{}
-	if err := cxev.LoopInit(&l.inner); err != nil {
+	cfg := l.applyOptions(opts)
+	entries, err := cfg.entries()
+	if err != nil {
+		return nil, err
+	}
+	tuning, err := cfg.tuning()
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := cfg.threshold(entries)
+	if err != nil {
+		return nil, err
+	}
+	if (cfg.ringEntries != 0 || tuning != cxev.LoopTuning{}) && cxev.ExtLibLoaded() {
+		err = cxev.LoopInitTuned(&l.inner, &cxev.LoopOptions{Entries: entries}, &tuning)
+	} else {
+		err = cxev.LoopInit(&l.inner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cxev.ExtLibLoaded() {
+		l.submitThreshold = threshold
+	}
+	l.initFixedFiles(cfg)
+	if err := l.initPost(cfg); err != nil {
+		cxev.LoopDeinit(&l.inner)
 		return nil, err
 	}
 	return l, nil
@@ -121,18 +206,40 @@ func NewLoop() (*Loop, error) {
 //	    return err
 //	}
 //	// Use file with async operations...
-func NewLoopWithThreadPool() (*Loop, error) {
+func NewLoopWithThreadPool(opts ...LoopOption) (*Loop, error) {
 	l := &Loop{hasPool: true}
+	cfg := l.applyOptions(opts)
+	entries, err := cfg.entries()
+	if err != nil {
+		return nil, err
+	}
+	tuning, err := cfg.tuning()
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := cfg.threshold(entries)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize thread pool first
 	cxev.ThreadPoolInit(&l.threadPool, nil)
 
 	// Initialize loop with thread pool via options
-	opts := &cxev.LoopOptions{
-		Entries:    256,
+	loopOpts := &cxev.LoopOptions{
+		Entries:    entries,
 		ThreadPool: &l.threadPool,
 	}
-	if err := cxev.LoopInitWithOptions(&l.inner, opts); err != nil {
+	if err := cxev.LoopInitTuned(&l.inner, loopOpts, &tuning); err != nil {
+		return nil, err
+	}
+	l.poolFileIO = cxev.LoopFeatures(&l.inner)&cxev.FeatureThreadPool != 0
+	if cxev.ExtLibLoaded() {
+		l.submitThreshold = threshold
+	}
+	l.initFixedFiles(cfg)
+	if err := l.initPost(cfg); err != nil {
+		l.Close()
 		return nil, err
 	}
 
@@ -147,7 +254,11 @@ func NewLoopWithThreadPool() (*Loop, error) {
 //
 // After Close is called, the Loop must not be used.
 func (l *Loop) Close() {
+	l.closed = true
+	l.closePost()
+	l.dropQueuedFileOps()
 	cxev.LoopDeinit(&l.inner)
+	l.fixedPin.Unpin()
 	if l.hasPool {
 		cxev.ThreadPoolShutdown(&l.threadPool)
 		cxev.ThreadPoolDeinit(&l.threadPool)
@@ -156,20 +267,38 @@ func (l *Loop) Close() {
 
 // Run processes events until all watchers are removed.
 // This is the main entry point for running the event loop.
+//
+// With [WithBusyPoll], Run polls without blocking for a while before each
+// wait for events. With [WithLockOSThread], it runs locked to its thread,
+// and with [WithCPU] pinned to a CPU as well. Hooks added with
+// [Loop.AddHook] run around every iteration. A handler panic propagated
+// by the panic handler ([Loop.SetPanicHandler]) stops the loop and is
+// returned.
 func (l *Loop) Run() error {
-	return cxev.LoopRun(&l.inner, cxev.RunUntilDone)
+	unlock, err := l.lockThread()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if l.busyPoll > 0 && cxev.ExtLibLoaded() {
+		return l.runBusyPoll()
+	}
+	if l.stepped() {
+		return l.runStepped()
+	}
+	return l.panicked(cxev.LoopRun(&l.inner, cxev.RunUntilDone))
 }
 
 // RunOnce blocks until at least one event is ready, processes it, then returns.
 // Useful for integrating with other event sources or custom loop logic.
 func (l *Loop) RunOnce() error {
-	return cxev.LoopRun(&l.inner, cxev.RunOnce)
+	return l.iterate(cxev.RunOnce)
 }
 
 // Poll checks for ready events without blocking.
 // Processes any events that are immediately ready and returns.
 func (l *Loop) Poll() error {
-	return cxev.LoopRun(&l.inner, cxev.RunNoWait)
+	return l.iterate(cxev.RunNoWait)
 }
 
 // Now returns the loop's cached timestamp.
@@ -0,0 +1,256 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// LoopGroupOptions configures a [LoopGroup].
+type LoopGroupOptions struct {
+	// Loops is the number of loops. Zero means one per CPU the process
+	// may run on.
+	Loops int
+	// Pin pins loop i to the i-th CPU the process may run on, wrapping
+	// around when there are more loops than CPUs. It has no effect on
+	// systems other than Linux.
+	Pin bool
+	// LoopOptions are applied to every loop. With Pin set, a [WithCPU]
+	// among them is overridden.
+	LoopOptions []LoopOption
+	// Distribution is how [LoopGroup.Listen] spreads connections over the
+	// loops.
+	Distribution AcceptDistribution
+}
+
+// AcceptDistribution is how a [LoopGroup] spreads the connections it
+// accepts over its loops.
+type AcceptDistribution uint8
+
+const (
+	// DistributeAuto uses DistributeReusePort on Linux, and
+	// DistributeRoundRobin elsewhere.
+	DistributeAuto AcceptDistribution = iota
+	// DistributeReusePort gives every loop a listener of its own, sharing
+	// the address through SO_REUSEPORT, and lets the kernel pick the
+	// listener of each connection. Accepting scales with the loops, but
+	// the kernel picks by hashing addresses, so a handful of connections
+	// may be spread unevenly. Only Linux balances such listeners.
+	DistributeReusePort
+	// DistributeRoundRobin accepts on the first loop and hands the
+	// connections to the loops in turn, which spreads them evenly but
+	// leaves every accept to one loop.
+	DistributeRoundRobin
+)
+
+// LoopGroup runs several loops, each on its own goroutine, for servers
+// that spread connections across cores. [LoopGroup.Listen] accepts
+// connections and gives each to one of the loops.
+type LoopGroup struct {
+	loops        []*Loop
+	distribution AcceptDistribution
+}
+
+// NewLoopGroup creates the loops of a group. Register watchers on them
+// with [LoopGroup.Loops] before calling [LoopGroup.Run].
+func NewLoopGroup(opts LoopGroupOptions) (*LoopGroup, error) {
+	cpus := processCPUs()
+	n := opts.Loops
+	if n <= 0 {
+		n = len(cpus)
+		if n == 0 {
+			n = runtime.NumCPU()
+		}
+	}
+	g := &LoopGroup{loops: make([]*Loop, 0, n), distribution: opts.Distribution}
+	if g.distribution == DistributeAuto {
+		g.distribution = DistributeRoundRobin
+		if runtime.GOOS == "linux" {
+			g.distribution = DistributeReusePort
+		}
+	}
+	for i := range n {
+		loopOpts := opts.LoopOptions
+		if opts.Pin && len(cpus) > 0 {
+			loopOpts = append(loopOpts[:len(loopOpts):len(loopOpts)], WithCPU(cpus[i%len(cpus)]))
+		}
+		loop, err := NewLoop(loopOpts...)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		g.loops = append(g.loops, loop)
+	}
+	return g, nil
+}
+
+// Loops returns the loops of the group.
+func (g *LoopGroup) Loops() []*Loop {
+	return g.loops
+}
+
+// Run runs every loop on its own goroutine until all of them are done, and
+// returns their errors joined.
+func (g *LoopGroup) Run() error {
+	errs := make([]error, len(g.loops))
+	var wg sync.WaitGroup
+	for i, loop := range g.loops {
+		wg.Go(func() {
+			errs[i] = loop.Run()
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close closes every loop. The group must not be running.
+func (g *LoopGroup) Close() {
+	for _, loop := range g.loops {
+		loop.Close()
+	}
+}
+
+// GroupListener accepts connections for a [LoopGroup]; see
+// [LoopGroup.Listen].
+type GroupListener struct {
+	group *LoopGroup
+	// listeners holds the listener of each loop, nil for loops that do not
+	// accept.
+	listeners []*TCPListener
+	// notifiers run work on each loop: connections handed to it and the
+	// closing of its listener.
+	notifiers []*Notifier
+	fn        func(loop *Loop, conn *TCPConn, err error)
+	port      uint16
+	// next is the loop the next connection goes to, with round-robin
+	// distribution. Only the first loop touches it.
+	next   int
+	closed atomic.Bool
+}
+
+// Listen listens on address, a "host:port" as for [Listen], and spreads
+// the connections it accepts over the group's loops as the group's
+// Distribution says. fn is called on the loop each connection was given
+// to, which is the loop the connection's operations must use; a failed
+// accept is passed to fn on the loop that accepted, and accepting goes on.
+// opts apply to every listening socket.
+//
+// Call Listen before [LoopGroup.Run]. The listener keeps the loops
+// running until [GroupListener.Close].
+//
+// Returns [ErrExtLibNotLoaded] if the extended library is not available.
+func (g *LoopGroup) Listen(network, address string, fn func(loop *Loop, conn *TCPConn, err error), opts ...ListenOption) (*GroupListener, error) {
+	if fn == nil {
+		return nil, errors.New("fn cannot be nil")
+	}
+	gl := &GroupListener{
+		group:     g,
+		listeners: make([]*TCPListener, len(g.loops)),
+		notifiers: make([]*Notifier, len(g.loops)),
+		fn:        fn,
+	}
+	accepting := 1
+	if g.distribution == DistributeReusePort {
+		accepting = len(g.loops)
+		opts = append(opts[:len(opts):len(opts)], WithReusePort())
+	}
+	for i := range accepting {
+		l, err := Listen(network, address, opts...)
+		if err != nil {
+			gl.closeNow()
+			return nil, err
+		}
+		gl.listeners[i] = l
+		if i == 0 {
+			// The listeners after the first bind the port it was given.
+			_, gl.port = l.Addr()
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				gl.closeNow()
+				return nil, err
+			}
+			address = net.JoinHostPort(host, strconv.Itoa(int(gl.port)))
+		}
+	}
+	for i, loop := range g.loops {
+		n, err := NewNotifier(loop)
+		if err != nil {
+			gl.closeNow()
+			return nil, err
+		}
+		gl.notifiers[i] = n
+	}
+	for i, l := range gl.listeners {
+		if l != nil {
+			_ = l.AcceptFunc(g.loops[i], gl.onAccept(i))
+		}
+	}
+	return gl, nil
+}
+
+func (gl *GroupListener) onAccept(i int) func(*TCPListener, *TCPConn, error) Action {
+	loop := gl.group.loops[i]
+	return func(_ *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil || gl.group.distribution == DistributeReusePort {
+			gl.fn(loop, conn, err)
+			return Continue
+		}
+		k := gl.next
+		gl.next = (gl.next + 1) % len(gl.group.loops)
+		if k == i {
+			gl.fn(loop, conn, nil)
+			return Continue
+		}
+		to := gl.group.loops[k]
+		if gl.notifiers[k].Post(func() { gl.fn(to, conn, nil) }) != nil {
+			// Closing: the connection has nowhere to go.
+			_ = conn.Close(loop, nil)
+		}
+		return Continue
+	}
+}
+
+// Port returns the port the listener is bound to.
+func (gl *GroupListener) Port() uint16 {
+	return gl.port
+}
+
+// Close stops accepting and closes the listening sockets. It may be called
+// from any goroutine; each loop closes its own listener, and connections
+// already handed to a loop are still passed to fn. Closing a closed
+// listener returns [ErrClosed].
+func (gl *GroupListener) Close() error {
+	if !gl.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	for i, n := range gl.notifiers {
+		if l := gl.listeners[i]; l != nil {
+			loop := gl.group.loops[i]
+			_ = n.Post(func() { _ = l.Close(loop, nil) })
+		}
+		_ = n.Close()
+	}
+	return nil
+}
+
+// closeNow releases what Listen set up before it failed, when no loop has
+// run yet.
+func (gl *GroupListener) closeNow() {
+	for i, l := range gl.listeners {
+		if l != nil {
+			_ = l.CloseNow()
+		}
+		if n := gl.notifiers[i]; n != nil {
+			n.release(gl.group.loops[i])
+		}
+	}
+}
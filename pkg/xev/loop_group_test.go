@@ -0,0 +1,81 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestLoopGroupListen(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	for _, d := range []AcceptDistribution{DistributeRoundRobin, DistributeReusePort} {
+		if d == DistributeReusePort && runtime.GOOS != "linux" {
+			continue
+		}
+		g, err := NewLoopGroup(LoopGroupOptions{Loops: 3, Distribution: d})
+		if err != nil {
+			t.Fatalf("NewLoopGroup failed: %v", err)
+		}
+
+		const conns = 12
+		var mu sync.Mutex
+		perLoop := make(map[*Loop]int)
+		accepted := 0
+		var gl *GroupListener
+		gl, err = g.Listen("tcp", "127.0.0.1:0", func(loop *Loop, conn *TCPConn, err error) {
+			if err != nil {
+				t.Errorf("accept failed: %v", err)
+				return
+			}
+			_ = conn.Close(loop, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			perLoop[loop]++
+			if accepted++; accepted == conns {
+				_ = gl.Close()
+			}
+		})
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- g.Run() }()
+		for range conns {
+			c, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(gl.Port())))
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer c.Close()
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		g.Close()
+
+		if accepted != conns {
+			t.Fatalf("distribution %d accepted %d connections, want %d", d, accepted, conns)
+		}
+		if d == DistributeRoundRobin {
+			for _, loop := range g.Loops() {
+				if n := perLoop[loop]; n != conns/len(g.Loops()) {
+					t.Fatalf("round robin gave a loop %d connections: %v", n, perLoop)
+				}
+			}
+		}
+		if err := gl.Close(); err != ErrClosed {
+			t.Fatalf("second Close = %v, want ErrClosed", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"sync"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Notifier wakes a loop from other goroutines and runs work on it. It
+// wraps libxev's async watcher: [Notifier.Notify] and [Notifier.Post] may
+// be called from any goroutine, and the functions posted run on the
+// loop's goroutine, in order, during [Loop.Run], [Loop.RunOnce] or
+// [Loop.Poll]. A loop blocked in RunOnce returns once they have run.
+//
+// Notifications that arrive before the loop gets to them are coalesced:
+// the loop wakes once and runs every function posted in the meantime.
+//
+// A Notifier keeps its loop alive, so [Loop.Run] does not return until it
+// is closed.
+//
+// Example:
+//
+//	n, err := xev.NewNotifier(loop)
+//	if err != nil {
+//	    return err
+//	}
+//	go func() {
+//	    result := compute()
+//	    n.Post(func() { deliver(result) })
+//	}()
+type Notifier struct {
+	watcher    cxev.Watcher
+	completion cxev.Completion
+	callbackID uintptr
+	loop       *Loop
+
+	// mu guards posted and closed, and is held across AsyncNotify so the
+	// loop cannot release the watcher while another goroutine signals it.
+	mu     sync.Mutex
+	posted []func()
+	closed bool
+}
+
+// NewNotifier creates a notifier for loop and starts waiting on it.
+//
+// Returns an error if the async watcher cannot be initialized.
+func NewNotifier(loop *Loop) (*Notifier, error) {
+	n := &Notifier{loop: loop}
+	if err := cxev.AsyncInit(&n.watcher); err != nil {
+		return nil, err
+	}
+	n.callbackID = cxev.AsyncWaitWithCallback(&n.watcher, &loop.inner, &n.completion, n.callback)
+	return n, nil
+}
+
+// Notify wakes the loop without posting work. It is safe to call from any
+// goroutine. Returns [ErrClosed] after [Notifier.Close].
+func (n *Notifier) Notify() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	return cxev.AsyncNotify(&n.watcher)
+}
+
+// Post queues fn to run on the loop's goroutine and wakes the loop. It is
+// safe to call from any goroutine. Returns [ErrClosed] after
+// [Notifier.Close]; fn is then not run.
+func (n *Notifier) Post(fn func()) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	n.posted = append(n.posted, fn)
+	return cxev.AsyncNotify(&n.watcher)
+}
+
+// Close stops the notifier. It may be called from any goroutine: the
+// loop runs the functions posted before it, then releases the watcher and
+// no longer counts it as alive. Closing a closed notifier returns
+// [ErrClosed].
+func (n *Notifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	n.closed = true
+	return cxev.AsyncNotify(&n.watcher)
+}
+
+func (n *Notifier) callback(loop *cxev.Loop, c *cxev.Completion, result int32, userdata uintptr) cxev.CbAction {
+	n.mu.Lock()
+	posted, closed := n.posted, n.closed
+	n.posted = nil
+	n.mu.Unlock()
+	for _, fn := range posted {
+		n.onPosted(fn)
+	}
+
+	if !closed {
+		// A Close racing with the functions above notified again, so the
+		// rearmed wait picks it up along with anything posted before it.
+		return cxev.Rearm
+	}
+	cxev.UnregisterCallback(userdata)
+	n.callbackID = 0
+	n.mu.Lock()
+	cxev.AsyncDeinit(&n.watcher)
+	n.mu.Unlock()
+	return cxev.Disarm
+}
+
+// release closes n and runs loop until the watcher is released. Unlike
+// [Notifier.Close], it must be called on the loop's goroutine, and it
+// returns once the closing notification has been handled, so the caller
+// can rely on n no longer keeping the loop alive.
+func (n *Notifier) release(loop *Loop) {
+	_ = n.Close()
+	for n.callbackID != 0 {
+		if err := cxev.LoopRun(&loop.inner, cxev.RunOnce); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotifierPost(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	n, err := NewNotifier(loop)
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	const posts = 100
+	var got []int
+	go func() {
+		for i := 0; i < posts; i++ {
+			i := i
+			if err := n.Post(func() { got = append(got, i) }); err != nil {
+				t.Errorf("Post failed: %v", err)
+			}
+		}
+		if err := n.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+
+	// Run returns only once the notifier is closed and no longer keeps the
+	// loop alive.
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+
+	if len(got) != posts {
+		t.Fatalf("ran %d posted functions, want %d", len(got), posts)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("posted function %d ran as %d", i, v)
+		}
+	}
+}
+
+func TestNotifierWakesRunOnce(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	n, err := NewNotifier(loop)
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	go func() {
+		if err := n.Notify(); err != nil {
+			t.Errorf("Notify failed: %v", err)
+		}
+	}()
+	if err := loop.RunOnce(); err != nil {
+		t.Fatalf("Loop.RunOnce failed: %v", err)
+	}
+
+	if err := n.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+
+	if err := n.Post(func() {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Post after Close: %v, want ErrClosed", err)
+	}
+	if err := n.Notify(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Notify after Close: %v, want ErrClosed", err)
+	}
+	if err := n.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close: %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// LoopOption configures a [Loop] at construction time.
+type LoopOption func(*loopConfig)
+
+// loopConfig collects the settings made by LoopOptions.
+type loopConfig struct {
+	tracerProvider trace.TracerProvider
+	busyPoll       time.Duration
+	cpu            int
+	pin            bool
+	lockThread     bool
+	post           bool
+	ringEntries    int
+	sqpollIdle     time.Duration
+	fixedFiles     int
+	budget         int
+	budgetTime     time.Duration
+	// fileConcurrency is the cap set by WithFileConcurrency.
+	fileConcurrency int
+	// submitThreshold is the threshold set by WithSubmitThreshold.
+	submitThreshold int
+}
+
+// applyOptions sets the fields of l that opts configure and returns the
+// whole configuration, for the settings applied once the loop is
+// initialized.
+func (l *Loop) applyOptions(opts []LoopOption) loopConfig {
+	var cfg loopConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider != nil {
+		l.tracer = cfg.tracerProvider.Tracer(tracerName)
+	}
+	l.busyPoll = cfg.busyPoll
+	l.cpu = -1
+	if cfg.pin {
+		l.cpu = cfg.cpu
+	}
+	l.lockOSThread = cfg.lockThread
+	l.fileConcurrency = max(cfg.fileConcurrency, 0)
+	if cxev.ExtLibLoaded() {
+		l.budget = max(cfg.budget, 0)
+		l.budgetTime = max(cfg.budgetTime, 0)
+	}
+	return cfg
+}
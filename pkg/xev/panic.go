@@ -0,0 +1,222 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Handlers are called from libxev through libffi. A panic that escapes one
+// unwinds through the C frames of the loop, leaving it in whatever state
+// it was in mid-iteration, so the timer, TCP, UDP, file, process and
+// signal handlers and posted functions all run behind a recover and the
+// loop's panic handler decides what follows.
+
+// PanicPolicy is what a loop does with a panic recovered from a handler.
+type PanicPolicy uint8
+
+const (
+	// PanicCrash prints the panic and its stack and exits the program with
+	// status 2, as an unrecovered panic would, without unwinding through
+	// the loop. It is the default.
+	PanicCrash PanicPolicy = iota
+	// PanicDisarm treats the handler as having returned [Stop]: its
+	// operation is disarmed and the loop carries on.
+	PanicDisarm
+	// PanicPropagate disarms the operation like PanicDisarm and stops the
+	// loop, whose Run returns the [*PanicError]. Without the extended
+	// library the loop cannot be stopped early, so Run returns it once the
+	// loop is done.
+	PanicPropagate
+)
+
+func (p PanicPolicy) String() string {
+	switch p {
+	case PanicCrash:
+		return "crash"
+	case PanicDisarm:
+		return "disarm"
+	case PanicPropagate:
+		return "propagate"
+	default:
+		return "unknown"
+	}
+}
+
+// PanicError is a panic recovered from a handler.
+type PanicError struct {
+	// Op is the operation whose handler panicked, such as "tcp read".
+	Op string
+	// Value is the value the handler panicked with.
+	Value any
+	// Stack is the stack of the goroutine at the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("xev: panic in %s handler: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// SetPanicHandler sets the function that chooses what happens when a
+// handler on the loop panics. fn runs on the loop goroutine with the
+// recovered panic and returns the policy to apply. A nil fn restores the
+// default, [PanicCrash].
+//
+// [LogPanics] logs each panic and disarms its operation.
+func (l *Loop) SetPanicHandler(fn func(p *PanicError) PanicPolicy) {
+	l.panicHandler = fn
+}
+
+// LogPanics is a panic handler that logs the panic with the logger set by
+// [SetLogger] and returns [PanicDisarm].
+func LogPanics(p *PanicError) PanicPolicy {
+	cxev.GetLogger().Error("xev: recovered panic in handler", "op", p.Op, "panic", p.Value, "stack", string(p.Stack))
+	return PanicDisarm
+}
+
+// recoverHandler recovers a panic in the handler of op, which must be the
+// function deferring it. The panic is handled as the loop's panic handler
+// says and the handler's action, if any, becomes Stop.
+func (l *Loop) recoverHandler(op string, action *Action) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	p := &PanicError{Op: op, Value: v, Stack: debug.Stack()}
+	policy := PanicCrash
+	if l != nil && l.panicHandler != nil {
+		policy = l.panicHandler(p)
+	}
+	switch policy {
+	case PanicDisarm:
+	case PanicPropagate:
+		if l.panicErr == nil {
+			l.panicErr = p
+		}
+		if cxev.ExtLibLoaded() {
+			cxev.LoopSetStopped(&l.inner, true)
+		}
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", v, p.Stack)
+		os.Exit(2)
+	}
+	if action != nil {
+		*action = Stop
+	}
+}
+
+// panicked returns the panic a handler propagated during the last run in
+// place of err, and lets the loop run again.
+func (l *Loop) panicked(err error) error {
+	p := l.panicErr
+	if p == nil {
+		return err
+	}
+	l.panicErr = nil
+	if cxev.ExtLibLoaded() {
+		cxev.LoopSetStopped(&l.inner, false)
+	}
+	return p
+}
+
+// The on* methods below call handlers, each behind recoverHandler.
+
+func (t *Timer) onTimer(err error) (action Action) {
+	defer t.loop.recoverHandler("timer", &action)
+	return t.handler.OnTimer(t, err)
+}
+
+func (l *TCPListener) onAccept(conn *TCPConn, err error) (action Action) {
+	defer l.loop.recoverHandler("tcp accept", &action)
+	return l.handler.OnAccept(l, conn, err)
+}
+
+func (l *TCPListener) onClosed(err error) {
+	defer l.closeLoop.recoverHandler("tcp listener close", nil)
+	l.onClose(l, err)
+}
+
+func (c *TCPConn) onConnect(fn func(*TCPConn, error) Action, err error) (action Action) {
+	defer c.loop.recoverHandler("tcp connect", &action)
+	return fn(c, err)
+}
+
+func (c *TCPConn) onWritable(fn func(*TCPConn, error) Action, err error) (action Action) {
+	defer c.loop.recoverHandler("tcp wait writable", &action)
+	return fn(c, err)
+}
+
+func (c *TCPConn) onRead(data []byte, err error) (action Action) {
+	defer c.loop.recoverHandler("tcp read", &action)
+	return c.readHandler.OnRead(c, data, err)
+}
+
+func (c *TCPConn) onWrite(n int, err error) (action Action) {
+	defer c.loop.recoverHandler("tcp write", &action)
+	return c.writeHandler.OnWrite(c, n, err)
+}
+
+func (c *TCPConn) onClosed(err error) {
+	defer c.loop.recoverHandler("tcp close", nil)
+	c.closeHandler.OnClose(c, err)
+}
+
+func (c *UDPConn) onRead(data []byte, addr *net.UDPAddr, err error) (action Action) {
+	defer c.loop.recoverHandler("udp read", &action)
+	return c.readHandler.OnRead(c, data, addr, err)
+}
+
+func (c *UDPConn) onWrite(n int, err error) (action Action) {
+	defer c.loop.recoverHandler("udp write", &action)
+	return c.writeHandler.OnWrite(c, n, err)
+}
+
+func (c *UDPConn) onClosed(err error) {
+	defer c.loop.recoverHandler("udp close", nil)
+	c.closeHandler.OnClose(c, err)
+}
+
+func (op *fileOp) onRead(data []byte, err error) (action Action) {
+	defer op.loop.recoverHandler("file read", &action)
+	return op.readHandler.OnRead(op.file, data, err)
+}
+
+func (op *fileOp) onWrite(n int, err error) (action Action) {
+	defer op.loop.recoverHandler("file write", &action)
+	return op.writeHandler.OnWrite(op.file, n, err)
+}
+
+func (op *fileOp) onClosed(err error) {
+	defer op.loop.recoverHandler("file close", nil)
+	op.closeHandler.OnClose(op.file, err)
+}
+
+func (n *Notifier) onPosted(fn func()) {
+	defer n.loop.recoverHandler("post", nil)
+	fn()
+}
+
+func (p *Process) onExit(status int, err error) {
+	defer p.loop.recoverHandler("process exit", nil)
+	p.handler.OnExit(p, status, err)
+}
+
+func (s *Signal) onSignal(loop *Loop, sig os.Signal) (action Action) {
+	defer loop.recoverHandler("signal", &action)
+	return s.handler.OnSignal(s, sig)
+}
@@ -0,0 +1,177 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestPanicError(t *testing.T) {
+	boom := errors.New("boom")
+	p := &PanicError{Op: "tcp read", Value: boom}
+	if got := p.Error(); got != "xev: panic in tcp read handler: boom" {
+		t.Fatalf("Error() = %q", got)
+	}
+	if !errors.Is(p, boom) {
+		t.Fatal("PanicError does not unwrap to the error it panicked with")
+	}
+	if (&PanicError{Value: "boom"}).Unwrap() != nil {
+		t.Fatal("a non-error panic value unwraps")
+	}
+}
+
+func TestPanicDisarm(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	var recovered []*PanicError
+	loop.SetPanicHandler(func(p *PanicError) PanicPolicy {
+		recovered = append(recovered, p)
+		return PanicDisarm
+	})
+
+	bad, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer bad.Close()
+	good, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer good.Close()
+
+	fired := 0
+	if err := bad.RunFunc(loop, time.Millisecond, func(*Timer, error) Action {
+		fired++
+		panic("boom")
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	goodFired := false
+	if err := good.RunFunc(loop, 20*time.Millisecond, func(*Timer, error) Action {
+		goodFired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if fired != 1 {
+		t.Fatalf("panicking timer fired %d times, want it disarmed after 1", fired)
+	}
+	if !goodFired {
+		t.Fatal("the loop did not carry on after the panic")
+	}
+	if len(recovered) != 1 || recovered[0].Op != "timer" || recovered[0].Value != "boom" {
+		t.Fatalf("recovered %+v", recovered)
+	}
+	if !strings.Contains(string(recovered[0].Stack), "TestPanicDisarm") {
+		t.Fatalf("stack does not show the handler:\n%s", recovered[0].Stack)
+	}
+}
+
+func TestPanicPropagate(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	loop.SetPanicHandler(func(*PanicError) PanicPolicy { return PanicPropagate })
+
+	bad, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer bad.Close()
+	later, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer later.Close()
+
+	boom := errors.New("boom")
+	if err := bad.RunFunc(loop, time.Millisecond, func(*Timer, error) Action {
+		panic(boom)
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	laterFired := false
+	if err := later.RunFunc(loop, 100*time.Millisecond, func(*Timer, error) Action {
+		laterFired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+
+	err = loop.Run()
+	var p *PanicError
+	if !errors.As(err, &p) || !errors.Is(err, boom) {
+		t.Fatalf("Run returned %v, want the panic", err)
+	}
+	if laterFired {
+		t.Fatal("the loop kept running after the panic")
+	}
+
+	// The loop runs again, to the end.
+	if err := loop.Run(); err != nil {
+		t.Fatalf("second Run failed: %v", err)
+	}
+	if !laterFired {
+		t.Fatal("the remaining timer did not fire")
+	}
+}
+
+func TestPanicInPostedFunction(t *testing.T) {
+	loop, err := NewLoop(WithPost())
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	var recovered []*PanicError
+	loop.SetPanicHandler(func(p *PanicError) PanicPolicy {
+		recovered = append(recovered, p)
+		return PanicDisarm
+	})
+
+	ran := false
+	for _, fn := range []func(){
+		func() { panic("boom") },
+		// The functions posted after the panicking one still run.
+		func() { ran = true },
+		func() { _ = loop.ClosePost() },
+	} {
+		if err := loop.Post(fn); err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !ran {
+		t.Fatal("the function posted after the panic did not run")
+	}
+	if len(recovered) != 1 || recovered[0].Op != "post" || recovered[0].Value != "boom" {
+		t.Fatalf("recovered %v, want the post panic", recovered)
+	}
+}
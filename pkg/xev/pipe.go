@@ -0,0 +1,54 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Pipe returns the two ends of an in-process stream connection backed by a
+// Unix socket pair. conn is a socket like one a [TCPListener] accepts, so
+// a server can hand it to the handler it accepts with and run the same code
+// as for a TCP client; peer is the client side, for blocking I/O from
+// another goroutine.
+//
+// Servers under test can use it to take clients without binding a port.
+// The caller owns both ends: close conn like an accepted connection.
+//
+// Returns [ErrExtLibNotLoaded] if the extended library is not available.
+func Pipe() (conn *TCPConn, peer net.Conn, err error) {
+	if !cxev.ExtLibLoaded() {
+		return nil, nil, ErrExtLibNotLoaded
+	}
+
+	syscall.ForkLock.RLock()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err == nil {
+		syscall.CloseOnExec(fds[0])
+		syscall.CloseOnExec(fds[1])
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, nil, os.NewSyscallError("socketpair", err)
+	}
+
+	f := os.NewFile(uintptr(fds[1]), "pipe")
+	peer, err = net.FileConn(f)
+	_ = f.Close()
+	if err != nil {
+		closeFD(fds[0])
+		return nil, nil, err
+	}
+
+	conn = &TCPConn{fd: int32(fds[0])}
+	cxev.TCPInitFd(&conn.tcp, conn.fd)
+	return conn, peer, nil
+}
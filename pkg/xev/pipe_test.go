@@ -0,0 +1,77 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestPipeEcho(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	conn, peer, err := Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	defer peer.Close()
+
+	// The loop end echoes what it reads until the peer hangs up.
+	var closed bool
+	buf := make([]byte, 64)
+	err = conn.ReadFunc(loop, buf, func(c *TCPConn, data []byte, err error) Action {
+		if err != nil || len(data) == 0 {
+			_ = c.CloseFunc(loop, func(*TCPConn, error) { closed = true })
+			return Stop
+		}
+		reply := append([]byte(nil), data...)
+		_ = c.WriteFunc(loop, reply, func(*TCPConn, int, error) Action { return Stop })
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("ReadFunc failed: %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_ = peer.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := peer.Write([]byte("ping")); err != nil {
+			errc <- err
+			return
+		}
+		got := make([]byte, 4)
+		if _, err := io.ReadFull(peer, got); err != nil {
+			errc <- err
+			return
+		}
+		if string(got) != "ping" {
+			t.Errorf("echo = %q, want ping", got)
+		}
+		errc <- peer.Close()
+	}()
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("peer: %v", err)
+	}
+	if !closed {
+		t.Fatal("loop end was not closed")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "errors"
+
+// errPostDisabled is returned by Loop.Post on a loop created without
+// WithPost.
+var errPostDisabled = errors.New("loop created without WithPost")
+
+// WithPost lets other goroutines run functions on the loop with
+// [Loop.Post]. The loop owns a [Notifier] for it, created with the loop.
+//
+// Like any notifier, it keeps the loop alive: [Loop.Run] does not return
+// until [Loop.ClosePost] is called, which may be done from any goroutine
+// or from a posted function.
+func WithPost() LoopOption {
+	return func(c *loopConfig) {
+		c.post = true
+	}
+}
+
+// Post queues fn to run on the loop's goroutine and wakes the loop. It is
+// safe to call from any goroutine; functions run in the order they were
+// posted, during [Loop.Run], [Loop.RunOnce] or [Loop.Poll].
+//
+// The name sets it apart from [Loop.Submit], which submits queued
+// operations. Returns an error if the loop was created without
+// [WithPost], and [ErrClosed] after [Loop.ClosePost]; fn is then not run.
+func (l *Loop) Post(fn func()) error {
+	if l.post == nil {
+		return errPostDisabled
+	}
+	return l.post.Post(fn)
+}
+
+// ClosePost stops accepting functions for [Loop.Post]. The loop runs
+// those posted before it, then no longer counts posting as keeping it
+// alive. It may be called from any goroutine; calling it again returns
+// [ErrClosed].
+func (l *Loop) ClosePost() error {
+	if l.post == nil {
+		return errPostDisabled
+	}
+	return l.post.Close()
+}
+
+// initPost creates the post notifier if cfg asks for one.
+func (l *Loop) initPost(cfg loopConfig) error {
+	if !cfg.post {
+		return nil
+	}
+	n, err := NewNotifier(l)
+	if err != nil {
+		return err
+	}
+	l.post = n
+	return nil
+}
+
+// closePost releases the post notifier of a loop that is being closed.
+func (l *Loop) closePost() {
+	if l.post != nil {
+		l.post.release(l)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoopPost(t *testing.T) {
+	loop, err := NewLoop(WithPost())
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	const posts = 100
+	var got []int
+	go func() {
+		for i := 0; i < posts; i++ {
+			i := i
+			if err := loop.Post(func() { got = append(got, i) }); err != nil {
+				t.Errorf("Post failed: %v", err)
+			}
+		}
+		// Closing from a posted function runs after everything above.
+		if err := loop.Post(func() {
+			if err := loop.ClosePost(); err != nil {
+				t.Errorf("ClosePost failed: %v", err)
+			}
+		}); err != nil {
+			t.Errorf("Post failed: %v", err)
+		}
+	}()
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if len(got) != posts {
+		t.Fatalf("ran %d posted functions, want %d", len(got), posts)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("posted function %d ran as %d", i, v)
+		}
+	}
+
+	if err := loop.Post(func() {}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Post after ClosePost = %v, want ErrClosed", err)
+	}
+	if err := loop.ClosePost(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("second ClosePost = %v, want ErrClosed", err)
+	}
+}
+
+func TestLoopPostDisabled(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	if err := loop.Post(func() {}); err == nil {
+		t.Fatal("Post on a loop without WithPost should fail")
+	}
+}
+
+func TestLoopCloseReleasesPost(t *testing.T) {
+	loop, err := NewLoop(WithPost())
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	ran := false
+	if err := loop.Post(func() { ran = true }); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	loop.Close()
+	if !ran {
+		t.Fatal("function posted before Close did not run")
+	}
+}
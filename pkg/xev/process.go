@@ -0,0 +1,168 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"os"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Process watches a child process and reports its exit on the loop,
+// instead of a goroutine blocked in [os.Process.Wait].
+//
+// Start a child with [StartProcess], or watch one started otherwise with
+// [NewProcess], then call [Process.WaitFunc]. The wait reaps the child, so
+// nothing else may wait for it: in particular, do not call Wait on the
+// [os.Process] or [exec.Cmd] that started it.
+//
+// Example:
+//
+//	p, err := xev.StartProcess("/bin/sleep", []string{"sleep", "1"}, &os.ProcAttr{})
+//	if err != nil {
+//	    return err
+//	}
+//	defer p.Close()
+//	p.WaitFunc(loop, func(p *xev.Process, status int, err error) {
+//	    log.Printf("pid %d exited with status %d", p.Pid(), status)
+//	})
+//
+// # Thread Safety
+//
+// Process operations are not thread-safe. All operations on a Process must
+// be performed from the same goroutine that runs the [Loop].
+type Process struct {
+	process    cxev.Process
+	completion cxev.TCPCompletion
+	pid        int
+	// os is the handle of a child started by StartProcess, released once
+	// the child is reaped.
+	os         *os.Process
+	handler    ProcessHandler
+	callbackID uintptr
+	waiting    bool
+	loop       *Loop
+}
+
+// ProcessHandler handles the exit of a watched process.
+//
+// For simple use cases, [ProcessFunc] provides a more convenient functional
+// approach.
+type ProcessHandler interface {
+	// OnExit is called when the process has exited and been reaped.
+	// status is its exit status; err is non-nil if the wait failed.
+	OnExit(p *Process, status int, err error)
+}
+
+// ProcessFunc is a function adapter for [ProcessHandler].
+type ProcessFunc func(p *Process, status int, err error)
+
+// OnExit implements [ProcessHandler].
+func (f ProcessFunc) OnExit(p *Process, status int, err error) {
+	f(p, status, err)
+}
+
+// StartProcess starts a child process like [os.StartProcess] and returns a
+// watcher for it.
+//
+// Returns [ErrExtLibNotLoaded] if the extended library is not available;
+// the child is not started then.
+func StartProcess(name string, argv []string, attr *os.ProcAttr) (*Process, error) {
+	if !cxev.ExtLibLoaded() {
+		return nil, ErrExtLibNotLoaded
+	}
+	child, err := os.StartProcess(name, argv, attr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewProcess(child.Pid)
+	if err != nil {
+		_ = child.Kill()
+		_, _ = child.Wait()
+		return nil, err
+	}
+	p.os = child
+	return p, nil
+}
+
+// NewProcess returns a watcher for the child process pid, which must not
+// have been reaped yet.
+//
+// Returns [ErrExtLibNotLoaded] if the extended library is not available.
+func NewProcess(pid int) (*Process, error) {
+	if !cxev.ExtLibLoaded() {
+		return nil, ErrExtLibNotLoaded
+	}
+	p := &Process{pid: pid}
+	if err := cxev.ProcessInit(&p.process, int32(pid)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Pid returns the process ID of the watched process.
+func (p *Process) Pid() int {
+	return p.pid
+}
+
+// Wait starts waiting for the process to exit, calling handler on loop
+// when it does.
+//
+// Returns an error if handler is nil or a wait is already in flight.
+func (p *Process) Wait(loop *Loop, handler ProcessHandler) error {
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	if p.waiting {
+		return errors.New("process wait already in flight")
+	}
+	p.handler = handler
+	p.loop = loop
+	p.waiting = true
+	p.callbackID = cxev.ProcessWaitWithCallback(&p.process, &loop.inner, &p.completion, p.callback)
+	return nil
+}
+
+// WaitFunc starts waiting for the process to exit with a callback function.
+//
+// This is a convenience wrapper around [Process.Wait] for functional-style
+// callbacks.
+func (p *Process) WaitFunc(loop *Loop, fn func(p *Process, status int, err error)) error {
+	return p.Wait(loop, ProcessFunc(fn))
+}
+
+func (p *Process) callback(loop *cxev.Loop, c *cxev.TCPCompletion, status int32, errCode int32, userdata uintptr) cxev.CbAction {
+	var err error
+	if errCode != 0 {
+		err = newOpError("wait", errCode)
+	}
+	p.waiting = false
+	if p.os != nil {
+		// The child is reaped; only the handle is left to release.
+		_ = p.os.Release()
+		p.os = nil
+	}
+	cxev.UnregisterCallback(userdata)
+	p.callbackID = 0
+	p.onExit(int(status), err)
+	return cxev.Disarm
+}
+
+// Close releases the watcher. It must not be called while a wait is in
+// flight.
+func (p *Process) Close() {
+	if p.callbackID != 0 {
+		cxev.UnregisterCallback(p.callbackID)
+		p.callbackID = 0
+	}
+	if p.os != nil {
+		_ = p.os.Release()
+		p.os = nil
+	}
+	cxev.ProcessDeinit(&p.process)
+}
@@ -0,0 +1,99 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"os"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestProcessExitStatus(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	p, err := StartProcess("/bin/sh", []string{"sh", "-c", "exit 3"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatalf("StartProcess failed: %v", err)
+	}
+	defer p.Close()
+
+	exited := false
+	status := -1
+	err = p.WaitFunc(loop, func(p *Process, s int, err error) {
+		if err != nil {
+			t.Errorf("wait error: %v", err)
+		}
+		exited = true
+		status = s
+	})
+	if err != nil {
+		t.Fatalf("WaitFunc failed: %v", err)
+	}
+	if err := p.WaitFunc(loop, func(*Process, int, error) {}); err == nil {
+		t.Fatal("second WaitFunc should fail while waiting")
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !exited {
+		t.Fatal("exit was not reported")
+	}
+	if status != 3 {
+		t.Fatalf("exit status = %d, want 3", status)
+	}
+	if n := cxev.DebugProcessCallbackCount(); n != 0 {
+		t.Fatalf("expected no process callback leaks, found %d active registrations", n)
+	}
+}
+
+func TestProcessExitPanic(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	var recovered []*PanicError
+	loop.SetPanicHandler(func(p *PanicError) PanicPolicy {
+		recovered = append(recovered, p)
+		return PanicDisarm
+	})
+
+	p, err := StartProcess("/bin/sh", []string{"sh", "-c", "exit 0"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatalf("StartProcess failed: %v", err)
+	}
+	defer p.Close()
+	if err := p.WaitFunc(loop, func(*Process, int, error) { panic("boom") }); err != nil {
+		t.Fatalf("WaitFunc failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].Op != "process exit" || recovered[0].Value != "boom" {
+		t.Fatalf("recovered %v, want the exit handler panic", recovered)
+	}
+	if n := cxev.DebugProcessCallbackCount(); n != 0 {
+		t.Fatalf("expected no process callback leaks, found %d active registrations", n)
+	}
+}
@@ -0,0 +1,300 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// A connection dialed through a proxy connects to the proxy instead of the
+// target, then runs the proxy handshake on the loop with ordinary writes
+// and reads. The connect handler only runs once the proxy has opened the
+// tunnel to the target, or the handshake has failed. Bytes the proxy sends
+// after its reply are returned by the first reads on the connection.
+
+// DialOption configures a [TCPConn] created by [Dial].
+type DialOption func(*TCPConn)
+
+type proxyConfig struct {
+	proto    string // "socks5" or "http"
+	addr     string
+	user     string
+	password string
+}
+
+// WithSOCKS5Proxy connects through the SOCKS5 proxy at addr. A non-empty
+// user selects username/password authentication (RFC 1929); otherwise no
+// authentication is offered. The target of [TCPConn.Connect] may be a host
+// name, which the proxy resolves.
+func WithSOCKS5Proxy(addr, user, password string) DialOption {
+	return func(c *TCPConn) {
+		c.proxy = &proxyConfig{proto: "socks5", addr: addr, user: user, password: password}
+	}
+}
+
+// WithHTTPProxy connects through the HTTP proxy at addr with a CONNECT
+// request. A non-empty user is sent as basic Proxy-Authorization.
+func WithHTTPProxy(addr, user, password string) DialOption {
+	return func(c *TCPConn) {
+		c.proxy = &proxyConfig{proto: "http", addr: addr, user: user, password: password}
+	}
+}
+
+// ProxyError reports a failed proxy handshake. Err is the reply of the
+// proxy or the [*OpError] of the failed read or write.
+type ProxyError struct {
+	// Proto is "socks5" or "http".
+	Proto string
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return e.Proto + " proxy: " + e.Err.Error()
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// socks5Replies are the failure messages of RFC 1928 reply codes.
+var socks5Replies = []string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// handshake opens the tunnel to target and then calls done.
+func (c *TCPConn) handshake(target string, done func(error)) {
+	fail := func(err error) {
+		var pe *ProxyError
+		if !errors.As(err, &pe) {
+			err = &ProxyError{Proto: c.proxy.proto, Err: err}
+		}
+		done(err)
+	}
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		fail(err)
+		return
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		fail(fmt.Errorf("invalid port %q", portStr))
+		return
+	}
+	if c.proxy.proto == "http" {
+		c.httpConnect(target, fail, done)
+		return
+	}
+	c.socks5Greet(host, uint16(port), fail, done)
+}
+
+func (c *TCPConn) httpConnect(target string, fail, done func(error)) {
+	var req bytes.Buffer
+	fmt.Fprintf(&req, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", target, target)
+	if c.proxy.user != "" {
+		cred := base64.StdEncoding.EncodeToString([]byte(c.proxy.user + ":" + c.proxy.password))
+		fmt.Fprintf(&req, "Proxy-Authorization: Basic %s\r\n", cred)
+	}
+	req.WriteString("\r\n")
+
+	c.proxyRoundTrip(req.Bytes(), func(reply []byte) (int, error) {
+		end := bytes.Index(reply, []byte("\r\n\r\n"))
+		if end < 0 {
+			return 0, nil
+		}
+		status, _, _ := bytes.Cut(reply, []byte("\r\n"))
+		fields := bytes.Fields(status)
+		if len(fields) < 2 || !bytes.HasPrefix(fields[0], []byte("HTTP/1.")) {
+			return 0, fmt.Errorf("malformed reply %q", status)
+		}
+		if !bytes.Equal(fields[1], []byte("200")) {
+			return 0, fmt.Errorf("CONNECT failed: %s", status)
+		}
+		return end + 4, nil
+	}, func(err error) {
+		if err != nil {
+			fail(err)
+			return
+		}
+		done(nil)
+	})
+}
+
+func (c *TCPConn) socks5Greet(host string, port uint16, fail, done func(error)) {
+	method := byte(0x00)
+	if c.proxy.user != "" {
+		method = 0x02
+	}
+	c.proxyRoundTrip([]byte{5, 1, method}, fixedReply(2), func(err error) {
+		switch {
+		case err != nil:
+			fail(err)
+		case c.proxyReply[0] != 5 || c.proxyReply[1] != method:
+			fail(errors.New("no acceptable authentication method"))
+		case method == 0x02:
+			c.socks5Auth(host, port, fail, done)
+		default:
+			c.socks5Connect(host, port, fail, done)
+		}
+	})
+}
+
+func (c *TCPConn) socks5Auth(host string, port uint16, fail, done func(error)) {
+	user, password := c.proxy.user, c.proxy.password
+	if len(user) > 255 || len(password) > 255 {
+		fail(errors.New("username or password too long"))
+		return
+	}
+	req := []byte{1, byte(len(user))}
+	req = append(req, user...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	c.proxyRoundTrip(req, fixedReply(2), func(err error) {
+		switch {
+		case err != nil:
+			fail(err)
+		case c.proxyReply[1] != 0:
+			fail(errors.New("authentication failed"))
+		default:
+			c.socks5Connect(host, port, fail, done)
+		}
+	})
+}
+
+func (c *TCPConn) socks5Connect(host string, port uint16, fail, done func(error)) {
+	req := []byte{5, 1, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			fail(errors.New("host name too long"))
+			return
+		}
+		req = append(req, 3, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(append(req, 1), ip4...)
+	} else {
+		req = append(append(req, 4), ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, port)
+
+	c.proxyRoundTrip(req, func(reply []byte) (int, error) {
+		if len(reply) < 5 {
+			return 0, nil
+		}
+		if reply[0] != 5 {
+			return 0, fmt.Errorf("unexpected version %d", reply[0])
+		}
+		if code := int(reply[1]); code != 0 {
+			if code < len(socks5Replies) {
+				return 0, errors.New(socks5Replies[code])
+			}
+			return 0, fmt.Errorf("unknown reply code %d", code)
+		}
+		// The reply carries the address the proxy bound, which has the
+		// same encoding as the request.
+		var n int
+		switch reply[3] {
+		case 1:
+			n = 4 + 4 + 2
+		case 4:
+			n = 4 + 16 + 2
+		case 3:
+			n = 4 + 1 + int(reply[4]) + 2
+		default:
+			return 0, fmt.Errorf("unknown address type %d", reply[3])
+		}
+		if len(reply) < n {
+			return 0, nil
+		}
+		return n, nil
+	}, func(err error) {
+		if err != nil {
+			fail(err)
+			return
+		}
+		done(nil)
+	})
+}
+
+// fixedReply completes a reply of n bytes.
+func fixedReply(n int) func([]byte) (int, error) {
+	return func(reply []byte) (int, error) {
+		if len(reply) < n {
+			return 0, nil
+		}
+		return n, nil
+	}
+}
+
+// proxyRoundTrip writes req and reads until complete returns the length of
+// the reply, which is left in c.proxyReply. Bytes past the reply are kept
+// for the next reads on the connection.
+func (c *TCPConn) proxyRoundTrip(req []byte, complete func(reply []byte) (int, error), then func(error)) {
+	loop := c.loop
+	c.proxyReply = c.proxyReply[:0]
+	read := func() {
+		buf := make([]byte, 512)
+		err := c.ReadFunc(loop, buf, func(c *TCPConn, data []byte, err error) Action {
+			if err == nil && len(data) == 0 {
+				err = errors.New("connection closed during handshake")
+			}
+			if err != nil {
+				then(err)
+				return Stop
+			}
+			c.proxyReply = append(c.proxyReply, data...)
+			n, err := complete(c.proxyReply)
+			if err != nil {
+				then(err)
+				return Stop
+			}
+			if n == 0 {
+				return Continue
+			}
+			if rest := c.proxyReply[n:]; len(rest) > 0 {
+				c.peeked = append([]byte(nil), rest...)
+			}
+			c.proxyReply = c.proxyReply[:n]
+			then(nil)
+			return Stop
+		})
+		if err != nil {
+			then(err)
+		}
+	}
+
+	var write func(data []byte)
+	write = func(data []byte) {
+		err := c.WriteFunc(loop, data, func(c *TCPConn, n int, err error) Action {
+			switch {
+			case err != nil:
+				then(err)
+			case n < len(data):
+				write(data[n:])
+			default:
+				read()
+			}
+			return Stop
+		})
+		if err != nil {
+			then(err)
+		}
+	}
+	write(req)
+}
@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// serveFakeProxy accepts one connection on ln, runs handshake on it and
+// then echoes, prefixed with "tunnel:" to show the data went through it.
+func serveFakeProxy(t *testing.T, ln net.Listener, handshake func(net.Conn, *bufio.Reader) bool) {
+	t.Helper()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		if !handshake(conn, br) {
+			return
+		}
+		_, _ = conn.Write([]byte("tunnel:"))
+		buf := make([]byte, 64)
+		n, _ := br.Read(buf)
+		_, _ = conn.Write(buf[:n])
+	}()
+}
+
+func socks5Handshake(t *testing.T, wantHost string) func(net.Conn, *bufio.Reader) bool {
+	return func(conn net.Conn, br *bufio.Reader) bool {
+		greet := make([]byte, 3)
+		if _, err := io.ReadFull(br, greet); err != nil || greet[2] != 2 {
+			t.Errorf("unexpected greeting %v: %v", greet, err)
+			return false
+		}
+		_, _ = conn.Write([]byte{5, 2})
+		auth := make([]byte, 2+4+1+6)
+		if _, err := io.ReadFull(br, auth); err != nil || string(auth[2:6]) != "user" || string(auth[7:]) != "secret" {
+			t.Errorf("unexpected auth %q: %v", auth, err)
+			return false
+		}
+		_, _ = conn.Write([]byte{1, 0})
+		head := make([]byte, 5)
+		if _, err := io.ReadFull(br, head); err != nil || head[3] != 3 {
+			t.Errorf("unexpected connect request %v: %v", head, err)
+			return false
+		}
+		rest := make([]byte, int(head[4])+2)
+		if _, err := io.ReadFull(br, rest); err != nil || string(rest[:head[4]]) != wantHost {
+			t.Errorf("unexpected target %q: %v", rest, err)
+			return false
+		}
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0x1f, 0x90})
+		return true
+	}
+}
+
+func httpHandshake(status string) func(net.Conn, *bufio.Reader) bool {
+	return func(conn net.Conn, br *bufio.Reader) bool {
+		req, err := http.ReadRequest(br)
+		if err != nil || req.Method != http.MethodConnect || req.Host != "example.com:80" {
+			return false
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 " + status + "\r\n\r\n"))
+		return status == "200 Connection established"
+	}
+}
+
+func TestDialThroughProxy(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	tests := []struct {
+		name      string
+		handshake func(net.Conn, *bufio.Reader) bool
+		option    func(addr string) DialOption
+		wantErr   bool
+	}{
+		{name: "socks5", handshake: socks5Handshake(t, "example.com"),
+			option: func(addr string) DialOption { return WithSOCKS5Proxy(addr, "user", "secret") }},
+		{name: "http", handshake: httpHandshake("200 Connection established"),
+			option: func(addr string) DialOption { return WithHTTPProxy(addr, "", "") }},
+		{name: "http refused", handshake: httpHandshake("403 Forbidden"),
+			option: func(addr string) DialOption { return WithHTTPProxy(addr, "", "") }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loop, err := NewLoop()
+			if err != nil {
+				t.Fatalf("NewLoop failed: %v", err)
+			}
+			defer loop.Close()
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen failed: %v", err)
+			}
+			defer ln.Close()
+			serveFakeProxy(t, ln, tt.handshake)
+
+			conn, err := Dial("tcp", "127.0.0.1:0", tt.option(ln.Addr().String()))
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			var (
+				done     bool
+				connErr  error
+				received []byte
+			)
+			_ = conn.Connect(loop, "example.com:80", func(c *TCPConn, err error) Action {
+				if err != nil {
+					connErr, done = err, true
+					return Stop
+				}
+				_ = c.WriteFunc(loop, []byte("hi"), func(c *TCPConn, _ int, _ error) Action {
+					buf := make([]byte, 64)
+					_ = c.ReadFunc(loop, buf, func(c *TCPConn, data []byte, err error) Action {
+						received = append(received, data...)
+						if err != nil || len(data) == 0 || string(received) == "tunnel:hi" {
+							done = true
+							return Stop
+						}
+						return Continue
+					})
+					return Stop
+				})
+				return Stop
+			})
+			for i := 0; i < 2000 && !done; i++ {
+				_ = loop.RunOnce()
+			}
+
+			var pe *ProxyError
+			if tt.wantErr {
+				if !errors.As(connErr, &pe) {
+					t.Fatalf("expected a ProxyError, got %v", connErr)
+				}
+				return
+			}
+			if connErr != nil || string(received) != "tunnel:hi" {
+				t.Fatalf("got %q, err %v", received, connErr)
+			}
+		})
+	}
+}
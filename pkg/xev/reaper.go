@@ -0,0 +1,154 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"container/list"
+	"errors"
+	"time"
+)
+
+// minReapInterval bounds how often the reaper timer fires for very short
+// timeouts.
+const minReapInterval = 10 * time.Millisecond
+
+// IdleReaper closes connections that have been idle longer than a timeout.
+//
+// Connections are identified by a caller-chosen key K (typically a pointer
+// to the connection state). Call [IdleReaper.Touch] whenever a connection
+// does I/O and [IdleReaper.Remove] when it closes for other reasons. A
+// single timer per reaper scans for expired entries, so the cost of idle
+// tracking does not grow with the number of timers on the loop.
+//
+// Entries are kept in least-recently-active order, so each scan only
+// visits connections that have actually expired.
+//
+// Like [Loop], an IdleReaper is not thread-safe: all methods must be called
+// from the goroutine that runs the loop.
+//
+// # Example
+//
+//	reaper := xev.NewIdleReaper(loop, 5*time.Minute, func(c *client) {
+//	    c.close()
+//	})
+//	reaper.Start()
+//	defer reaper.Stop()
+//
+//	// On accept and on every read/write:
+//	reaper.Touch(c)
+type IdleReaper[K comparable] struct {
+	loop     Scheduler
+	timeout  time.Duration
+	interval time.Duration
+	onIdle   func(K)
+	now      func() time.Duration
+
+	order   *list.List // of *idleEntry[K], oldest first
+	entries map[K]*list.Element
+	cancel  func()
+}
+
+type idleEntry[K comparable] struct {
+	key      K
+	lastSeen time.Duration
+}
+
+// NewIdleReaper creates a reaper that calls onIdle for every key that has
+// not been touched for at least timeout. onIdle runs on the loop goroutine
+// after the key has been removed from the reaper. loop is usually a
+// [*Loop]; tests may pass a simulated scheduler.
+//
+// The scan interval is a quarter of timeout (at least 10ms), so a
+// connection is closed no later than 1.25x timeout after its last activity.
+func NewIdleReaper[K comparable](loop Scheduler, timeout time.Duration, onIdle func(K)) *IdleReaper[K] {
+	interval := timeout / 4
+	if interval < minReapInterval {
+		interval = minReapInterval
+	}
+	r := &IdleReaper[K]{
+		loop:     loop,
+		timeout:  timeout,
+		interval: interval,
+		onIdle:   onIdle,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+	if loop != nil {
+		r.now = loop.Now
+	}
+	return r
+}
+
+// Start arms the reaper timer on the loop.
+func (r *IdleReaper[K]) Start() error {
+	if r.timeout <= 0 {
+		return errors.New("idle timeout must be positive")
+	}
+	if r.cancel != nil {
+		return nil
+	}
+	cancel, err := r.loop.Schedule(r.interval, func() Action {
+		r.reap(r.now())
+		return Continue
+	})
+	if err != nil {
+		return err
+	}
+	r.cancel = cancel
+	return nil
+}
+
+// Stop disarms the reaper timer. Tracked keys are kept, so Start may be
+// called again later.
+func (r *IdleReaper[K]) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+}
+
+// Touch records activity on key, starting to track it if necessary.
+func (r *IdleReaper[K]) Touch(key K) {
+	now := r.now()
+	if el, ok := r.entries[key]; ok {
+		el.Value.(*idleEntry[K]).lastSeen = now
+		r.order.MoveToBack(el)
+		return
+	}
+	r.entries[key] = r.order.PushBack(&idleEntry[K]{key: key, lastSeen: now})
+}
+
+// Remove stops tracking key. It is a no-op for unknown keys.
+func (r *IdleReaper[K]) Remove(key K) {
+	if el, ok := r.entries[key]; ok {
+		r.order.Remove(el)
+		delete(r.entries, key)
+	}
+}
+
+// Len returns the number of tracked keys.
+func (r *IdleReaper[K]) Len() int {
+	return len(r.entries)
+}
+
+// reap expires every entry idle for at least timeout as of now.
+func (r *IdleReaper[K]) reap(now time.Duration) {
+	for {
+		front := r.order.Front()
+		if front == nil {
+			return
+		}
+		entry := front.Value.(*idleEntry[K])
+		if now-entry.lastSeen < r.timeout {
+			return
+		}
+		r.order.Remove(front)
+		delete(r.entries, entry.key)
+		r.onIdle(entry.key)
+	}
+}
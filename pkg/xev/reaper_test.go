@@ -0,0 +1,74 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIdleReaperExpiresLeastRecentlyActive(t *testing.T) {
+	var now time.Duration
+	var expired []string
+
+	r := NewIdleReaper[string](nil, time.Second, func(k string) {
+		expired = append(expired, k)
+	})
+	r.now = func() time.Duration { return now }
+
+	r.Touch("a")
+	now = 300 * time.Millisecond
+	r.Touch("b")
+	now = 600 * time.Millisecond
+	r.Touch("c")
+	now = 900 * time.Millisecond
+	r.Touch("a") // a is active again
+
+	now = 1300 * time.Millisecond
+	r.reap(now)
+	if want := []string{"b"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+
+	now = 1800 * time.Millisecond
+	r.reap(now)
+	if want := []string{"b", "c"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", r.Len())
+	}
+}
+
+func TestIdleReaperRemove(t *testing.T) {
+	var now time.Duration
+	calls := 0
+
+	r := NewIdleReaper[int](nil, time.Second, func(int) { calls++ })
+	r.now = func() time.Duration { return now }
+
+	r.Touch(1)
+	r.Touch(2)
+	r.Remove(1)
+	r.Remove(42) // unknown keys are ignored
+
+	r.reap(10 * time.Second)
+	if calls != 1 {
+		t.Fatalf("onIdle calls = %d, want 1", calls)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("Len = %d, want 0", r.Len())
+	}
+}
+
+func TestIdleReaperStartRejectsNonPositiveTimeout(t *testing.T) {
+	r := NewIdleReaper[int](nil, 0, func(int) {})
+	if err := r.Start(); err == nil {
+		t.Fatal("expected error for zero timeout")
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// This file implements the rules for starting operations from callbacks.
+//
+// # The rules
+//
+// A [TCPConn] or [UDPConn] owns a single completion, so it carries at most
+// one operation (read, write, connect or close) at a time:
+//
+//  1. Starting an operation on a connection that has one in flight is an
+//     error. libxev would reuse a completion it still holds.
+//  2. A callback may start one new operation on its own connection, but
+//     then it must return [Stop]. Returning Stop hands the completion back
+//     to libxev, which submits the new operation. Returning [Continue]
+//     would re-arm the old one on the same completion.
+//  3. Everything else waits for the completion to be free. Use Defer, which
+//     runs a function as soon as the connection has nothing in flight: right
+//     away if it is idle, or when the current callback returns Stop.
+//
+// [File] operations each get their own completion and are not restricted.
+//
+// # Enforcement
+//
+// Rule 2 is always enforced: a callback that returns Continue after
+// starting an operation is treated as returning Stop, so the new operation
+// wins. With debug assertions enabled ([SetDebugAssertions], or XEV_DEBUG
+// set in the environment), breaking either rule panics with a description
+// of the operations involved.
+
+package xev
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var debugAssertions atomic.Bool
+
+func init() {
+	debugAssertions.Store(os.Getenv("XEV_DEBUG") != "")
+}
+
+// SetDebugAssertions enables or disables the checks of the rules for
+// starting operations from callbacks. They are off unless XEV_DEBUG is set.
+func SetDebugAssertions(on bool) {
+	debugAssertions.Store(on)
+}
+
+type opState uint8
+
+const (
+	opIdle opState = iota
+	opInFlight
+	opDispatching // the callback of op is running
+)
+
+// opGuard tracks the operation on a connection's completion.
+type opGuard struct {
+	state opState
+	op    string
+	// next is the operation started by the running callback, if any.
+	next     string
+	deferred []func()
+}
+
+// violate reports a broken rule when debug assertions are enabled.
+func violate(format string, args ...any) {
+	if debugAssertions.Load() {
+		panic(fmt.Sprintf("xev: "+format, args...))
+	}
+}
+
+// submit records that op is being started on the completion.
+func (g *opGuard) submit(owner, op string) {
+	switch g.state {
+	case opInFlight:
+		violate("%s %s started while %s is in flight; use Defer", owner, op, g.op)
+	case opDispatching:
+		if g.next != "" {
+			violate("%s %s started after %s in the same %s callback", owner, op, g.next, g.op)
+		}
+		g.next = op
+		return
+	}
+	g.state = opInFlight
+	g.op = op
+}
+
+// dispatch records that the callback of the operation in flight is about
+// to run.
+func (g *opGuard) dispatch() {
+	g.state = opDispatching
+	g.next = ""
+}
+
+// finish records the action returned by the callback and returns the one to
+// give libxev. Callers run deferred functions once they are done with the
+// completed operation.
+func (g *opGuard) finish(owner string, action Action) Action {
+	if g.next != "" {
+		if action == Continue {
+			violate("%s %s callback returned Continue after starting %s", owner, g.op, g.next)
+			action = Stop
+		}
+		g.state = opInFlight
+		g.op, g.next = g.next, ""
+		return action
+	}
+	if action == Continue {
+		g.state = opInFlight
+		return action
+	}
+	g.state = opIdle
+	return action
+}
+
+// closed records that the completion carried the close of the connection;
+// deferred functions are dropped.
+func (g *opGuard) closed() {
+	g.state = opIdle
+	g.next = ""
+	g.deferred = nil
+}
+
+// whenIdle runs fn once the completion is free. While deferred functions
+// run, new ones join the end of the queue.
+func (g *opGuard) whenIdle(fn func()) {
+	if g.state == opIdle && len(g.deferred) == 0 {
+		fn()
+		return
+	}
+	g.deferred = append(g.deferred, fn)
+}
+
+// runDeferred runs deferred functions in order until one of them starts
+// an operation.
+func (g *opGuard) runDeferred() {
+	for g.state == opIdle && len(g.deferred) > 0 {
+		fn := g.deferred[0]
+		g.deferred[0] = nil
+		g.deferred = g.deferred[1:]
+		fn()
+	}
+}
+
+// Defer runs fn on the loop goroutine once the connection has no operation
+// in flight: immediately if it is idle, otherwise when the callback of the
+// current operation returns [Stop]. Functions run in the order they were
+// deferred, each after the operation started by the previous one finishes.
+// Pending functions are dropped when the connection is closed.
+//
+// Use Defer to start an operation when the connection may be busy, such as
+// from a timer or from the callback of another connection.
+func (c *TCPConn) Defer(fn func()) {
+	c.ops.whenIdle(fn)
+}
+
+// Defer runs fn once the socket has no operation in flight, like
+// [TCPConn.Defer].
+func (c *UDPConn) Defer(fn func()) {
+	c.ops.whenIdle(fn)
+}
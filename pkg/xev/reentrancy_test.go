@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func withDebugAssertions(t *testing.T) {
+	t.Helper()
+	prev := debugAssertions.Load()
+	SetDebugAssertions(true)
+	t.Cleanup(func() { SetDebugAssertions(prev) })
+}
+
+func wantViolation(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("no violation, want %q", want)
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, want) {
+			t.Fatalf("violation %q, want %q", r, want)
+		}
+	}()
+	fn()
+}
+
+func TestOpGuardResubmitFromCallback(t *testing.T) {
+	withDebugAssertions(t)
+	var g opGuard
+
+	g.submit("TCPConn", "read")
+	g.dispatch()
+	g.submit("TCPConn", "write") // allowed: the callback returns Stop
+	if got := g.finish("TCPConn", Stop); got != Stop {
+		t.Fatalf("finish = %v, want Stop", got)
+	}
+	if g.state != opInFlight || g.op != "write" {
+		t.Fatalf("state = %v %q, want write in flight", g.state, g.op)
+	}
+
+	g.dispatch()
+	g.submit("TCPConn", "read")
+	wantViolation(t, "write callback returned Continue after starting read", func() {
+		g.finish("TCPConn", Continue)
+	})
+}
+
+func TestOpGuardContinueAfterResubmitBecomesStop(t *testing.T) {
+	var g opGuard // assertions off: the violation is corrected, not reported
+	g.submit("TCPConn", "read")
+	g.dispatch()
+	g.submit("TCPConn", "write")
+	if got := g.finish("TCPConn", Continue); got != Stop {
+		t.Fatalf("finish = %v, want Stop", got)
+	}
+	if g.op != "write" {
+		t.Fatalf("op in flight = %q, want write", g.op)
+	}
+}
+
+func TestOpGuardSubmitWhileInFlight(t *testing.T) {
+	withDebugAssertions(t)
+	var g opGuard
+	g.submit("UDPConn", "read")
+	wantViolation(t, "UDPConn write started while read is in flight; use Defer", func() {
+		g.submit("UDPConn", "write")
+	})
+
+	g = opGuard{}
+	g.submit("TCPConn", "read")
+	g.dispatch()
+	g.submit("TCPConn", "write")
+	wantViolation(t, "TCPConn close started after write in the same read callback", func() {
+		g.submit("TCPConn", "close")
+	})
+}
+
+func TestOpGuardDeferred(t *testing.T) {
+	withDebugAssertions(t)
+	var g opGuard
+	var ran []string
+
+	g.whenIdle(func() { ran = append(ran, "idle") })
+	if !reflect.DeepEqual(ran, []string{"idle"}) {
+		t.Fatalf("ran = %v: an idle connection must run deferred work at once", ran)
+	}
+
+	g.submit("TCPConn", "read")
+	g.whenIdle(func() {
+		ran = append(ran, "write")
+		g.submit("TCPConn", "write")
+	})
+	g.whenIdle(func() { ran = append(ran, "after write") })
+
+	g.dispatch()
+	g.finish("TCPConn", Continue) // the read re-arms: nothing runs
+	g.runDeferred()
+	if len(ran) != 1 {
+		t.Fatalf("ran = %v while the read was re-armed", ran)
+	}
+
+	g.dispatch()
+	g.finish("TCPConn", Stop)
+	g.runDeferred()
+	if !reflect.DeepEqual(ran, []string{"idle", "write"}) {
+		t.Fatalf("ran = %v, want the write to start and the rest to wait", ran)
+	}
+
+	g.dispatch()
+	g.finish("TCPConn", Stop)
+	g.runDeferred()
+	if !reflect.DeepEqual(ran, []string{"idle", "write", "after write"}) {
+		t.Fatalf("ran = %v after the write completed", ran)
+	}
+}
+
+func TestOpGuardClosedDropsDeferred(t *testing.T) {
+	var g opGuard
+	g.submit("TCPConn", "close")
+	g.whenIdle(func() { t.Fatal("deferred work ran after close") })
+	g.dispatch()
+	g.closed()
+	g.runDeferred()
+}
@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// On the io_uring backend a loop submits through a ring with a fixed number
+// of entries, set when the loop is created: operations queued beyond it wait
+// for the next submission, so a loop with many sockets in flight per
+// iteration enters the kernel more often than it needs to, while one that
+// starts many in a callback only submits them at the end of the iteration.
+// libxev takes the ring size; the extended library can also create the
+// ring with an SQPOLL thread, which takes submissions off the ring without
+// the loop entering the kernel at all, and submit early once enough
+// operations are queued. The epoll and kqueue backends ignore all three.
+//
+// There is no timer resolution to tune on kqueue: libxev keeps its timers
+// in a heap of its own and waits in kevent with the nearest deadline as the
+// timeout, which has nanosecond resolution, rather than registering kqueue
+// timers whose resolution could be set.
+//
+// Files and buffers registered with the ring are set up by WithFixedFiles
+// and Loop.RegisterBuffers.
+
+const (
+	// defaultRingEntries is libxev's own default ring size.
+	defaultRingEntries = 256
+	// maxRingEntries is the kernel's limit, IORING_MAX_ENTRIES.
+	maxRingEntries = 32768
+)
+
+// ErrInvalidSQPoll is returned by [NewLoop] and [NewLoopWithThreadPool] for a
+// negative or out of range [WithSQPoll] idle time.
+var ErrInvalidSQPoll = errors.New("sqpoll idle time must be between 0 and 2^32-1 milliseconds")
+
+// ErrInvalidSubmitThreshold is returned by [NewLoop] and
+// [NewLoopWithThreadPool] for a [WithSubmitThreshold] threshold that is
+// negative or larger than the ring.
+var ErrInvalidSubmitThreshold = errors.New("submit threshold must be between 0 and the ring entries")
+
+// ErrInvalidRingEntries is returned by [NewLoop] and
+// [NewLoopWithThreadPool] for a [WithRingEntries] size io_uring cannot use.
+var ErrInvalidRingEntries = errors.New("ring entries must be a power of two between 1 and 32768")
+
+// WithRingEntries sets the number of entries of the loop's io_uring ring,
+// which must be a power of two no larger than 32768. The default is 256;
+// servers that keep thousands of operations in flight can raise it to
+// submit them in fewer system calls, at the cost of the ring's locked
+// memory. Other backends ignore it.
+//
+// Sizing the ring requires the extended library; without it [NewLoop]
+// uses the default.
+func WithRingEntries(n int) LoopOption {
+	return func(c *loopConfig) {
+		c.ringEntries = n
+	}
+}
+
+// entries returns the ring size cfg asks for, or the default.
+func (cfg loopConfig) entries() (uint32, error) {
+	n := cfg.ringEntries
+	if n == 0 {
+		return defaultRingEntries, nil
+	}
+	if n < 0 || n > maxRingEntries || bits.OnesCount(uint(n)) != 1 {
+		return 0, ErrInvalidRingEntries
+	}
+	return uint32(n), nil
+}
+
+// WithSQPoll creates the loop's io_uring ring with an SQPOLL kernel thread
+// that polls it for submissions, so the loop hands operations to the kernel
+// without a system call while the thread is awake. The thread sleeps once
+// the ring has been idle for idle, rounded up to a millisecond, and the
+// next submission enters the kernel to wake it: a longer idle time saves
+// those entries under bursty load at the cost of a core spinning for it.
+//
+// The default, zero, leaves SQPOLL off. Kernels before 5.11 only allow it
+// for privileged processes, and [NewLoop] returns
+// [cxev.ErrSQPollUnavailable] if the kernel refuses it. It requires the
+// extended library; without it, and on other backends, it is ignored.
+// [Loop.Backend] reports [FeatureSQPoll] on a loop it applied to.
+func WithSQPoll(idle time.Duration) LoopOption {
+	return func(c *loopConfig) {
+		c.sqpollIdle = idle
+	}
+}
+
+// tuning returns the backend tuning cfg asks for.
+func (cfg loopConfig) tuning() (cxev.LoopTuning, error) {
+	idle := cfg.sqpollIdle
+	if idle < 0 {
+		return cxev.LoopTuning{}, ErrInvalidSQPoll
+	}
+	ms := (idle + time.Millisecond - 1) / time.Millisecond
+	if ms > math.MaxUint32 {
+		return cxev.LoopTuning{}, ErrInvalidSQPoll
+	}
+	return cxev.LoopTuning{SQPollIdleMs: uint32(ms)}, nil
+}
+
+// WithSubmitThreshold makes the loop enter the kernel as soon as n
+// operations wait in its io_uring submission queue, instead of once per
+// iteration. It is checked as operations are started, so a callback that
+// starts many, such as a broadcast to thousands of connections, has the
+// kernel working on the first batches while it queues the rest. A lower
+// threshold lowers the latency of the first operations at the cost of
+// more system calls.
+//
+// The default, zero, submits once per iteration, as libxev does. n may not
+// exceed the ring size ([WithRingEntries]). It requires the extended
+// library; without it, and on other backends, it is ignored.
+func WithSubmitThreshold(n int) LoopOption {
+	return func(c *loopConfig) {
+		c.submitThreshold = n
+	}
+}
+
+// threshold returns the submit threshold cfg asks for, for a ring of
+// entries entries.
+func (cfg loopConfig) threshold(entries uint32) (uint32, error) {
+	n := cfg.submitThreshold
+	if n < 0 || n > int(entries) {
+		return 0, ErrInvalidSubmitThreshold
+	}
+	return uint32(n), nil
+}
+
+// submitQueued submits the queued operations once the submit threshold is
+// reached.
+func (l *Loop) submitQueued() {
+	_ = cxev.LoopSubmitThreshold(&l.inner, l.submitThreshold)
+}
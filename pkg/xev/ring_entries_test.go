@@ -0,0 +1,215 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestRingEntries(t *testing.T) {
+	tests := []struct {
+		n       int
+		want    uint32
+		wantErr bool
+	}{
+		{n: 0, want: defaultRingEntries},
+		{n: 1, want: 1},
+		{n: 4096, want: 4096},
+		{n: maxRingEntries, want: maxRingEntries},
+		{n: -8, wantErr: true},
+		{n: 100, wantErr: true},
+		{n: 2 * maxRingEntries, wantErr: true},
+	}
+	for _, tt := range tests {
+		var cfg loopConfig
+		WithRingEntries(tt.n)(&cfg)
+		got, err := cfg.entries()
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRingEntries) {
+				t.Errorf("entries(%d) error = %v, want ErrInvalidRingEntries", tt.n, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("entries(%d) = %d, %v, want %d", tt.n, got, err, tt.want)
+		}
+	}
+}
+
+func TestNewLoopRejectsInvalidRingEntries(t *testing.T) {
+	if _, err := NewLoop(WithRingEntries(100)); !errors.Is(err, ErrInvalidRingEntries) {
+		t.Fatalf("NewLoop error = %v, want ErrInvalidRingEntries", err)
+	}
+	if _, err := NewLoopWithThreadPool(WithRingEntries(100)); !errors.Is(err, ErrInvalidRingEntries) {
+		t.Fatalf("NewLoopWithThreadPool error = %v, want ErrInvalidRingEntries", err)
+	}
+}
+
+func TestNewLoopWithRingEntries(t *testing.T) {
+	loop, err := NewLoop(WithRingEntries(1024))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	fired := false
+	if _, err := loop.Schedule(0, func() Action {
+		fired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !fired {
+		t.Fatal("timer did not fire")
+	}
+}
+
+func TestSQPollTuning(t *testing.T) {
+	tests := []struct {
+		idle    time.Duration
+		want    uint32
+		wantErr bool
+	}{
+		{idle: 0, want: 0},
+		{idle: time.Microsecond, want: 1},
+		{idle: 2 * time.Second, want: 2000},
+		{idle: -time.Millisecond, wantErr: true},
+		{idle: 1 << 32 * time.Millisecond, wantErr: true},
+	}
+	for _, tt := range tests {
+		var cfg loopConfig
+		WithSQPoll(tt.idle)(&cfg)
+		got, err := cfg.tuning()
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSQPoll) {
+				t.Errorf("tuning(%v) error = %v, want ErrInvalidSQPoll", tt.idle, err)
+			}
+			continue
+		}
+		if err != nil || got.SQPollIdleMs != tt.want {
+			t.Errorf("tuning(%v) = %d, %v, want %d", tt.idle, got.SQPollIdleMs, err, tt.want)
+		}
+	}
+}
+
+func TestNewLoopRejectsInvalidSQPoll(t *testing.T) {
+	if _, err := NewLoop(WithSQPoll(-time.Second)); !errors.Is(err, ErrInvalidSQPoll) {
+		t.Fatalf("NewLoop error = %v, want ErrInvalidSQPoll", err)
+	}
+	if _, err := NewLoopWithThreadPool(WithSQPoll(-time.Second)); !errors.Is(err, ErrInvalidSQPoll) {
+		t.Fatalf("NewLoopWithThreadPool error = %v, want ErrInvalidSQPoll", err)
+	}
+}
+
+func TestNewLoopWithSQPoll(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop(WithSQPoll(10 * time.Millisecond))
+	if errors.Is(err, cxev.ErrSQPollUnavailable) {
+		t.Skip("kernel refuses SQPOLL")
+	}
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	info, err := loop.Backend()
+	if err != nil {
+		t.Fatalf("Backend failed: %v", err)
+	}
+	if got := info.Features&FeatureSQPoll != 0; got != (info.Kind == BackendIOUring) {
+		t.Fatalf("backend %v: sqpoll feature = %v", info, got)
+	}
+
+	fired := false
+	if _, err := loop.Schedule(0, func() Action {
+		fired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !fired {
+		t.Fatal("timer did not fire")
+	}
+}
+
+func TestSubmitThreshold(t *testing.T) {
+	tests := []struct {
+		n       int
+		entries uint32
+		want    uint32
+		wantErr bool
+	}{
+		{n: 0, entries: defaultRingEntries, want: 0},
+		{n: 32, entries: defaultRingEntries, want: 32},
+		{n: 1024, entries: 1024, want: 1024},
+		{n: -1, entries: defaultRingEntries, wantErr: true},
+		{n: 512, entries: defaultRingEntries, wantErr: true},
+	}
+	for _, tt := range tests {
+		var cfg loopConfig
+		WithSubmitThreshold(tt.n)(&cfg)
+		got, err := cfg.threshold(tt.entries)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSubmitThreshold) {
+				t.Errorf("threshold(%d, %d) error = %v, want ErrInvalidSubmitThreshold", tt.n, tt.entries, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("threshold(%d, %d) = %d, %v, want %d", tt.n, tt.entries, got, err, tt.want)
+		}
+	}
+	if _, err := NewLoop(WithSubmitThreshold(512)); !errors.Is(err, ErrInvalidSubmitThreshold) {
+		t.Fatalf("NewLoop error = %v, want ErrInvalidSubmitThreshold", err)
+	}
+}
+
+func TestNewLoopWithSubmitThreshold(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop(WithSubmitThreshold(2))
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	// Timers started from a callback are submitted in pairs as they are
+	// started, and all of them fire.
+	fired := 0
+	if _, err := loop.Schedule(0, func() Action {
+		for range 5 {
+			if _, err := loop.Schedule(0, func() Action {
+				fired++
+				return Stop
+			}); err != nil {
+				t.Errorf("Schedule failed: %v", err)
+			}
+		}
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if fired != 5 {
+		t.Fatalf("fired = %d, want 5", fired)
+	}
+}
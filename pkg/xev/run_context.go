@@ -0,0 +1,76 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"context"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// RunContext runs the loop like [Loop.Run], but also returns once ctx is
+// done, with ctx.Err(). Cancellation wakes a loop blocked waiting for
+// events; operations still in flight stay armed, so the loop can be run
+// again to finish them, or closed.
+//
+// The loop is woken by a watcher of its own, which does not keep the loop
+// alive. It is released before RunContext returns, which may run callbacks
+// that are ready by then. [WithLockOSThread]
+// and [WithCPU] apply as for Run; [WithBusyPoll] does not.
+//
+// Returns [ErrExtLibNotLoaded] if ctx can be cancelled and the extended
+// library is not available.
+func (l *Loop) RunContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return l.Run()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !cxev.ExtLibLoaded() {
+		return ErrExtLibNotLoaded
+	}
+	unlock, err := l.lockThread()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	wake, err := NewNotifier(l)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		wake.release(l)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = wake.Notify()
+		case <-stop:
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// With everything queued submitted, the wakeup watcher's wait is
+		// the one completion left once the loop is otherwise done.
+		if err := cxev.LoopSubmit(&l.inner); err != nil {
+			return err
+		}
+		if len(l.carried) == 0 && cxev.LoopActive(&l.inner) <= 1 {
+			return nil
+		}
+		if err := l.iterate(cxev.RunOnce); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestRunContextCancel(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	fired := false
+	if _, err := loop.Schedule(300*time.Millisecond, func() Action {
+		fired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if err := loop.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunContext = %v, want context.Canceled", err)
+	}
+	if fired {
+		t.Fatal("timer fired before RunContext returned")
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("RunContext took %v to notice the cancellation", elapsed)
+	}
+
+	// The timer is still armed; running again finishes it.
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !fired {
+		t.Fatal("timer did not fire after RunContext was cancelled")
+	}
+}
+
+func TestRunContextReturnsWhenDone(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	fired := 0
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond} {
+		if _, err := loop.Schedule(d, func() Action {
+			fired++
+			return Stop
+		}); err != nil {
+			t.Fatalf("Schedule failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- loop.RunContext(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil || fired != 2 {
+			t.Fatalf("RunContext returned %v after %d timers, want 2", err, fired)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return once the timers fired")
+	}
+	if cxev.LoopAlive(&loop.inner) {
+		t.Fatal("RunContext left its wakeup watcher armed")
+	}
+}
+
+func TestRunContextAlreadyDone(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := loop.RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunContext = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Signal delivers process signals on the loop goroutine, so a program can
+// react to them, for example by shutting down, alongside its other
+// callbacks instead of in a goroutine of its own.
+//
+// Signals are received with [signal.Notify] and handed to the loop through
+// a [Notifier], so the loop wakes for them even while blocked. While a
+// Signal is watching, it keeps the loop alive.
+//
+// Example:
+//
+//	sig := xev.NewSignal(syscall.SIGINT, syscall.SIGTERM)
+//	sig.RunFunc(loop, func(s *xev.Signal, received os.Signal) xev.Action {
+//	    log.Printf("received %v, shutting down", received)
+//	    server.Close(loop)
+//	    return xev.Stop
+//	})
+//
+// # Thread Safety
+//
+// Like [Timer], a Signal must only be used from the goroutine that runs
+// the [Loop].
+type Signal struct {
+	signals  []os.Signal
+	handler  SignalHandler
+	notifier *Notifier
+	ch       chan os.Signal
+	// done stops the goroutine forwarding ch to the loop.
+	done chan struct{}
+}
+
+// SignalHandler handles signals delivered by a [Signal].
+//
+// For simple use cases, [SignalFunc] provides a more convenient functional
+// approach.
+type SignalHandler interface {
+	// OnSignal is called on the loop goroutine for each signal received.
+	// Return [Continue] to keep watching, or [Stop] to stop.
+	OnSignal(s *Signal, sig os.Signal) Action
+}
+
+// SignalFunc is a function adapter for [SignalHandler].
+type SignalFunc func(s *Signal, sig os.Signal) Action
+
+// OnSignal implements [SignalHandler].
+func (f SignalFunc) OnSignal(s *Signal, sig os.Signal) Action {
+	return f(s, sig)
+}
+
+// NewSignal creates a watcher for sigs, or for SIGINT, SIGTERM and SIGHUP
+// if none are given. It receives nothing until one of the Run methods is
+// called.
+func NewSignal(sigs ...os.Signal) *Signal {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+	}
+	return &Signal{signals: sigs}
+}
+
+// RunWithHandler starts watching and calls handler on loop for each signal
+// received, until it returns [Stop] or the Signal is closed. Signals
+// received while the loop is busy are delivered in order once it gets to
+// them.
+//
+// Returns an error if handler is nil or the Signal is already running.
+func (s *Signal) RunWithHandler(loop *Loop, handler SignalHandler) error {
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	if s.notifier != nil {
+		return errors.New("signal watcher already running")
+	}
+	n, err := NewNotifier(loop)
+	if err != nil {
+		return err
+	}
+	s.handler = handler
+	s.notifier = n
+	s.ch = make(chan os.Signal, len(s.signals))
+	s.done = make(chan struct{})
+	signal.Notify(s.ch, s.signals...)
+	go s.forward(n, s.ch, s.done)
+	return nil
+}
+
+// RunFunc starts watching with a callback function. It is a convenience
+// wrapper around [Signal.RunWithHandler].
+func (s *Signal) RunFunc(loop *Loop, fn func(s *Signal, sig os.Signal) Action) error {
+	return s.RunWithHandler(loop, SignalFunc(fn))
+}
+
+// Close stops watching. Signals received but not yet delivered are
+// dropped, and the loop no longer counts the Signal as alive. It is safe
+// to call Close on a Signal that is not running.
+func (s *Signal) Close() {
+	if s.notifier == nil {
+		return
+	}
+	signal.Stop(s.ch)
+	close(s.done)
+	_ = s.notifier.Close()
+	s.notifier = nil
+	s.handler = nil
+}
+
+// forward posts the signals received on ch to the loop until done is
+// closed.
+func (s *Signal) forward(n *Notifier, ch <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case sig := <-ch:
+			if n.Post(func() { s.deliver(n, sig) }) != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// deliver runs on the loop goroutine. n identifies the run that received
+// sig, so that signals posted before a Close are not delivered to a later
+// run.
+func (s *Signal) deliver(n *Notifier, sig os.Signal) {
+	if s.notifier != n {
+		return
+	}
+	if s.onSignal(n.loop, sig) == Stop && s.notifier == n {
+		s.Close()
+	}
+}
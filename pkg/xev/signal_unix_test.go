@@ -0,0 +1,105 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestSignalDeliveredOnLoop(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	sig := NewSignal(syscall.SIGUSR1)
+	defer sig.Close()
+
+	var got []os.Signal
+	err = sig.RunFunc(loop, func(s *Signal, received os.Signal) Action {
+		got = append(got, received)
+		if len(got) == 1 {
+			// Signal again from the loop; the watcher keeps receiving.
+			if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+				t.Errorf("kill failed: %v", err)
+			}
+			return Continue
+		}
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+
+	// Run returns once the handler stops the watcher.
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != syscall.SIGUSR1 || got[1] != syscall.SIGUSR1 {
+		t.Fatalf("signals delivered = %v, want two SIGUSR1", got)
+	}
+}
+
+func TestSignalRunTwice(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	sig := NewSignal(syscall.SIGUSR2)
+	noop := func(*Signal, os.Signal) Action { return Continue }
+	if err := sig.RunFunc(loop, noop); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	if err := sig.RunFunc(loop, noop); err == nil {
+		t.Fatal("second RunFunc should fail while running")
+	}
+	sig.Close()
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	sig.Close()
+}
+
+func TestSignalHandlerPanic(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+	var recovered []*PanicError
+	loop.SetPanicHandler(func(p *PanicError) PanicPolicy {
+		recovered = append(recovered, p)
+		return PanicDisarm
+	})
+
+	sig := NewSignal(syscall.SIGUSR1)
+	defer sig.Close()
+	if err := sig.RunFunc(loop, func(*Signal, os.Signal) Action { panic("boom") }); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+
+	// The panic disarms the watcher, as Stop would, so Run returns.
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].Op != "signal" || recovered[0].Value != "boom" {
+		t.Fatalf("recovered %v, want the signal handler panic", recovered)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "bytes"
+
+// A sniffing listener serves several protocols on one port. Each accepted
+// connection is read on the loop until its first bytes match one of the
+// configured protocols, and is then handed to that protocol's handler. The
+// bytes read while sniffing are not lost: the first reads on the routed
+// connection return them before reading from the socket again.
+
+// Protocol routes connections whose first bytes satisfy Match to Handler.
+type Protocol struct {
+	// Name describes the protocol, e.g. "resp" or "http".
+	Name string
+	// Match reports whether prefix, the bytes received so far, belongs to
+	// the protocol. It is called again as more bytes arrive, so it should
+	// return false until it has seen enough to decide.
+	Match func(prefix []byte) bool
+	// Handler receives matching connections.
+	Handler AcceptHandler
+}
+
+// WithProtocols makes the listener sniff up to maxPrefix bytes of every
+// accepted connection and route it to the first protocol that matches. A
+// connection that matches none once maxPrefix bytes have arrived goes to
+// the handler passed to [TCPListener.Accept]; so do accept errors.
+//
+// A connection closed or failing before it is routed is closed without
+// reaching any handler. The [Action] returned by a handler receiving a
+// sniffed connection is ignored: the listener keeps accepting until the
+// Accept handler returns [Stop] or the listener is closed. Protocols where
+// the server speaks first cannot be sniffed.
+func WithProtocols(maxPrefix int, protocols ...Protocol) ListenOption {
+	return func(l *TCPListener) {
+		l.sniffLen = max(maxPrefix, 1)
+		l.protocols = protocols
+	}
+}
+
+// MatchPrefix matches connections starting with any of prefixes.
+func MatchPrefix(prefixes ...string) func([]byte) bool {
+	return func(data []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(data, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchTLS matches a TLS handshake record (content type 22, major version 3).
+func MatchTLS(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x16 && data[1] == 0x03
+}
+
+// MatchHTTP matches an HTTP/1.x request line by its method.
+var MatchHTTP = MatchPrefix("GET ", "HEAD ", "POST ", "PUT ", "DELETE ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE ")
+
+// route returns the handler for a connection whose first bytes are prefix.
+// It returns nil while no protocol matches and fewer than sniffLen bytes
+// have been seen.
+func (l *TCPListener) route(prefix []byte) AcceptHandler {
+	for _, p := range l.protocols {
+		if p.Match(prefix) {
+			return p.Handler
+		}
+	}
+	if len(prefix) >= l.sniffLen {
+		return l.handler
+	}
+	return nil
+}
+
+// sniff reads from conn until it can be routed.
+func (l *TCPListener) sniff(conn *TCPConn) {
+	var prefix []byte
+	buf := make([]byte, l.sniffLen)
+	err := conn.ReadFunc(l.loop, buf, func(c *TCPConn, data []byte, err error) Action {
+		if err != nil || len(data) == 0 {
+			_ = c.CloseFunc(l.loop, nil)
+			return Stop
+		}
+		prefix = append(prefix, data...)
+		handler := l.route(prefix)
+		if handler == nil {
+			return Continue
+		}
+		c.peeked = prefix
+		handler.OnAccept(l, c, nil)
+		return Stop
+	})
+	if err != nil {
+		_ = conn.CloseFunc(l.loop, nil)
+	}
+}
+
+// replayPeeked delivers the bytes read while sniffing to the pending read
+// handler from a zero delay timer, so the handler runs on the loop as it
+// would for a socket read. The socket read is armed once they are used up.
+// A handler that starts a new read instead of returning [Continue] gets the
+// same treatment; the timer cannot be re-run from its own callback.
+func (c *TCPConn) replayPeeked() error {
+	if c.replayTimer == nil {
+		timer, err := NewTimer()
+		if err != nil {
+			return err
+		}
+		c.replayTimer = timer
+	}
+	return c.replayTimer.RunFunc(c.loop, 0, func(*Timer, error) Action {
+		n := copy(c.readBuf, c.peeked)
+		c.peeked = c.peeked[n:]
+		if len(c.peeked) == 0 {
+			c.peeked = nil
+		}
+		c.replaying = true
+		action := c.onRead(c.readBuf[:n], nil)
+		c.replaying = false
+		if action != Continue && !c.readRequested {
+			return Stop
+		}
+		c.readRequested = false
+		if c.peeked != nil {
+			return Continue
+		}
+		// The handler may have started a write.
+		c.Defer(c.armRead)
+		return Stop
+	})
+}
@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+type namedAcceptHandler struct{ name string }
+
+func (h *namedAcceptHandler) OnAccept(*TCPListener, *TCPConn, error) Action { return Continue }
+
+func TestSniffRoute(t *testing.T) {
+	resp := &namedAcceptHandler{name: "resp"}
+	http := &namedAcceptHandler{name: "http"}
+	fallback := &namedAcceptHandler{name: "fallback"}
+
+	l := &TCPListener{handler: fallback}
+	WithProtocols(8,
+		Protocol{Name: "resp", Match: MatchPrefix("*"), Handler: resp},
+		Protocol{Name: "http", Match: MatchHTTP, Handler: http},
+	)(l)
+
+	tests := []struct {
+		prefix string
+		want   AcceptHandler
+	}{
+		{prefix: "*1\r\n", want: resp},
+		{prefix: "GET / HTTP/1.1\r\n", want: http},
+		{prefix: "GE", want: nil},
+		{prefix: "\x16\x03\x01\x02\x00\x01\x00\x01", want: fallback},
+	}
+	for _, tt := range tests {
+		if got := l.route([]byte(tt.prefix)); got != tt.want {
+			t.Fatalf("route(%q): got %v want %v", tt.prefix, got, tt.want)
+		}
+	}
+
+	if !MatchTLS([]byte("\x16\x03\x01")) || MatchTLS([]byte("\x16")) || MatchTLS([]byte("GET ")) {
+		t.Fatal("MatchTLS misclassified a prefix")
+	}
+}
+
+func TestSniffingListener(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	got := map[string]string{}
+	// echo reads the connection to EOF one byte at a time, so the replay
+	// of the sniffed bytes has to span several reads.
+	echo := func(name string) AcceptHandler {
+		return AcceptFunc(func(l *TCPListener, conn *TCPConn, err error) Action {
+			buf := make([]byte, 1)
+			_ = conn.ReadFunc(loop, buf, func(c *TCPConn, data []byte, err error) Action {
+				if err != nil || len(data) == 0 {
+					_ = c.CloseFunc(loop, nil)
+					return Stop
+				}
+				got[name] += string(data)
+				return Continue
+			})
+			return Continue
+		})
+	}
+
+	listener, err := Listen("tcp", "127.0.0.1:0", WithProtocols(4,
+		Protocol{Name: "resp", Match: MatchPrefix("*"), Handler: echo("resp")},
+		Protocol{Name: "http", Match: MatchHTTP, Handler: echo("http")},
+	))
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	if err := listener.Accept(loop, echo("other")); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	sent := map[string]string{
+		"resp":  "*1\r\n$4\r\nPING\r\n",
+		"http":  "GET / HTTP/1.1\r\n\r\n",
+		"other": "hello world",
+	}
+	for _, payload := range sent {
+		client, err := Dial("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		payload := []byte(payload)
+		_ = client.Connect(loop, "127.0.0.1:"+itoa(int(port)), func(c *TCPConn, err error) Action {
+			if err != nil {
+				t.Errorf("connect error: %v", err)
+				return Stop
+			}
+			_ = c.WriteFunc(loop, payload, func(c *TCPConn, _ int, _ error) Action {
+				_ = c.CloseFunc(loop, nil)
+				return Stop
+			})
+			return Stop
+		})
+	}
+
+	for i := 0; i < 2000 && !maps.Equal(got, sent); i++ {
+		_ = loop.RunOnce()
+	}
+	if !maps.Equal(got, sent) {
+		t.Fatalf("routed data: got %q want %q", got, sent)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"os"
+)
+
+// SetNoDelay controls whether the connection delays small writes to send
+// them together (Nagle's algorithm), like [net.TCPConn.SetNoDelay]. Unlike
+// the net package, sockets keep the system default, which is to delay,
+// until it is called.
+//
+// It does nothing on a connection backed by a [Transport].
+func (c *TCPConn) SetNoDelay(noDelay bool) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.transport != nil {
+		return nil
+	}
+	return os.NewSyscallError("setsockopt", setNoDelay(int(c.fd), noDelay))
+}
+
+// SetKeepAliveConfig configures the TCP keepalive probes of the
+// connection, like [net.TCPConn.SetKeepAliveConfig]. Idle, Interval and
+// Count are rounded up to whole seconds and probes; those that are zero or
+// negative keep the system defaults. When Enable is false, the other
+// fields are ignored.
+//
+// It does nothing on a connection backed by a [Transport].
+func (c *TCPConn) SetKeepAliveConfig(cfg net.KeepAliveConfig) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.transport != nil {
+		return nil
+	}
+	return os.NewSyscallError("setsockopt", setKeepAlive(int(c.fd), cfg))
+}
+
+// WithReusePort sets SO_REUSEPORT on the listening socket before it is
+// bound, so that several listeners can share the address. On Linux the
+// kernel spreads incoming connections over them. Listen fails where the
+// option is not supported.
+func WithReusePort() ListenOption {
+	return func(l *TCPListener) {
+		l.reusePort = true
+	}
+}
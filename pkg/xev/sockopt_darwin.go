@@ -0,0 +1,13 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "golang.org/x/sys/unix"
+
+// tcpKeepIdle is the option setting the idle time before keepalive probes,
+// which Darwin names TCP_KEEPALIVE.
+const tcpKeepIdle = unix.TCP_KEEPALIVE
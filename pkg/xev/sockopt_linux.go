@@ -0,0 +1,12 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "golang.org/x/sys/unix"
+
+// tcpKeepIdle is the option setting the idle time before keepalive probes.
+const tcpKeepIdle = unix.TCP_KEEPIDLE
@@ -0,0 +1,71 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestTCPConnSocketOptions(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	raw, err := client.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getsockopt := func(fd, level, opt int) int {
+		t.Helper()
+		v, err := unix.GetsockoptInt(fd, level, opt)
+		if err != nil {
+			t.Fatalf("getsockopt: %v", err)
+		}
+		return v
+	}
+	_ = raw.Control(func(fd uintptr) {
+		conn := &TCPConn{fd: int32(fd)}
+		if err := conn.SetNoDelay(false); err != nil {
+			t.Fatalf("SetNoDelay: %v", err)
+		}
+		if getsockopt(int(fd), unix.IPPROTO_TCP, unix.TCP_NODELAY) != 0 {
+			t.Error("TCP_NODELAY set after SetNoDelay(false)")
+		}
+
+		cfg := net.KeepAliveConfig{Enable: true, Idle: 90 * time.Second, Interval: 1500 * time.Millisecond, Count: 4}
+		if err := conn.SetKeepAliveConfig(cfg); err != nil {
+			t.Fatalf("SetKeepAliveConfig: %v", err)
+		}
+		got := [4]int{
+			getsockopt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE),
+			getsockopt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE),
+			getsockopt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL),
+			getsockopt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT),
+		}
+		if want := [4]int{1, 90, 2, 4}; got != want {
+			t.Errorf("keepalive, idle, interval, count = %v, want %v", got, want)
+		}
+
+		if err := conn.SetKeepAliveConfig(net.KeepAliveConfig{}); err != nil {
+			t.Fatalf("SetKeepAliveConfig: %v", err)
+		}
+		if getsockopt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE) != 0 {
+			t.Error("SO_KEEPALIVE still set after disabling keepalive")
+		}
+	})
+}
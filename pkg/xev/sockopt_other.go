@@ -0,0 +1,30 @@
+//go:build !linux && !darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"net"
+)
+
+func setNoDelay(fd int, noDelay bool) error {
+	return errors.ErrUnsupported
+}
+
+func setKeepAlive(fd int, cfg net.KeepAliveConfig) error {
+	return errors.ErrUnsupported
+}
+
+func setReusePort(fd int) error {
+	return errors.ErrUnsupported
+}
+
+func recvNow(fd int, buf []byte) (int, error) {
+	return 0, errNotReady
+}
@@ -0,0 +1,68 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func setNoDelay(fd int, noDelay bool) error {
+	v := 0
+	if noDelay {
+		v = 1
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_NODELAY, v)
+}
+
+func setKeepAlive(fd int, cfg net.KeepAliveConfig) error {
+	if !cfg.Enable {
+		return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_KEEPALIVE, 0)
+	}
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_KEEPALIVE, 1); err != nil {
+		return err
+	}
+	if cfg.Idle > 0 {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_TCP, tcpKeepIdle, roundSeconds(cfg.Idle)); err != nil {
+			return err
+		}
+	}
+	if cfg.Interval > 0 {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, roundSeconds(cfg.Interval)); err != nil {
+			return err
+		}
+	}
+	if cfg.Count > 0 {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_KEEPCNT, cfg.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setReusePort(fd int) error {
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+}
+
+// recvNow reads what has already arrived on fd without blocking, or
+// returns errNotReady if nothing has.
+func recvNow(fd int, buf []byte) (int, error) {
+	n, _, err := unix.Recvfrom(fd, buf, unix.MSG_DONTWAIT)
+	if err == unix.EAGAIN {
+		return 0, errNotReady
+	}
+	return n, err
+}
+
+// roundSeconds converts d to whole seconds, rounding up.
+func roundSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "github.com/crrow/libxev-go/pkg/cxev"
+
+// Stats counts the network traffic of a connection or of all connections on
+// a loop. Bytes are counted when a read or write completes successfully,
+// before its handler runs, so a handler already sees its own transfer.
+//
+// Like the rest of the package, counters are updated and read on the loop
+// goroutine without synchronization.
+type Stats struct {
+	// BytesIn and BytesOut are the bytes received and sent.
+	BytesIn  uint64
+	BytesOut uint64
+	// Reads and Writes are the successful read and write completions; for
+	// UDP, the datagrams received and sent.
+	Reads  uint64
+	Writes uint64
+	// Pool is the thread pool of a loop made with [NewLoopWithThreadPool].
+	// Only [Loop.Stats] sets it.
+	Pool PoolStats
+}
+
+// PoolStats describes the thread pool of a loop and the file operations
+// it runs. A pool that is the bottleneck shows tasks queued while no
+// worker is idle, and a utilization near 1.
+//
+// The task counts cover the file operations of the loop, and stay zero
+// on backends that do file I/O in the kernel, such as io_uring, where the
+// pool runs none. The worker counts need the extended library and are a
+// snapshot taken by [Loop.Stats]: the workers update them concurrently.
+type PoolStats struct {
+	// Queued is the operations submitted and waiting for a worker, and
+	// Running those a worker is running. Waiting is the operations the
+	// loop holds back under [WithFileConcurrency], not yet submitted.
+	Queued  int
+	Running int
+	Waiting int
+	// Completed is the operations that have completed.
+	Completed uint64
+	// Workers is the workers spawned so far, IdleWorkers those of them
+	// waiting for a task, and MaxWorkers the most the pool may spawn.
+	Workers     int
+	IdleWorkers int
+	MaxWorkers  int
+}
+
+// Utilization returns the fraction of the pool's workers busy with a
+// task, between 0 and 1.
+func (p PoolStats) Utilization() float64 {
+	if p.MaxWorkers == 0 {
+		return 0
+	}
+	return float64(p.Workers-p.IdleWorkers) / float64(p.MaxWorkers)
+}
+
+// poolTasks counts the operations a loop has handed to its thread pool.
+type poolTasks struct {
+	// pending is the operations submitted and not yet completed.
+	pending   int
+	completed uint64
+}
+
+// poolSubmitted records an operation handed to the loop's thread pool.
+func (l *Loop) poolSubmitted() {
+	if l.poolFileIO {
+		l.poolTasks.pending++
+	}
+}
+
+// poolCompleted records the completion of an operation of the thread pool;
+// rearmed is true if it was submitted again.
+func (l *Loop) poolCompleted(rearmed bool) {
+	if !l.poolFileIO {
+		return
+	}
+	l.poolTasks.completed++
+	if !rearmed {
+		l.poolTasks.pending--
+		l.startQueuedFileOps()
+	}
+}
+
+// poolStats returns the stats of the loop's thread pool.
+func (l *Loop) poolStats() PoolStats {
+	p := PoolStats{Completed: l.poolTasks.completed, Waiting: l.queuedFileOps()}
+	pending := l.poolTasks.pending
+	if cxev.ExtLibLoaded() {
+		w := cxev.ThreadPoolGetStats(&l.threadPool)
+		p.Workers, p.IdleWorkers, p.MaxWorkers = int(w.Spawned), int(w.Idle), int(w.MaxThreads)
+	}
+	// The busy workers are running the loop's operations; the rest of
+	// those pending wait in the pool's queue.
+	p.Running = min(max(p.Workers-p.IdleWorkers, 0), pending)
+	p.Queued = pending - p.Running
+	return p
+}
+
+func (s *Stats) addIn(n int) {
+	s.BytesIn += uint64(n)
+	s.Reads++
+}
+
+func (s *Stats) addOut(n int) {
+	s.BytesOut += uint64(n)
+	s.Writes++
+}
+
+// countIn records n received bytes on a connection's stats and its loop's.
+func countIn(conn *Stats, loop *Loop, n int32, errCode int32) {
+	if errCode != 0 || n <= 0 {
+		return
+	}
+	conn.addIn(int(n))
+	if loop != nil {
+		loop.stats.addIn(int(n))
+	}
+}
+
+// countOut records n sent bytes on a connection's stats and its loop's.
+func countOut(conn *Stats, loop *Loop, n int32, errCode int32) {
+	if errCode != 0 || n <= 0 {
+		return
+	}
+	conn.addOut(int(n))
+	if loop != nil {
+		loop.stats.addOut(int(n))
+	}
+}
+
+// Stats returns the traffic of the connection so far.
+func (c *TCPConn) Stats() Stats {
+	return c.stats
+}
+
+// Stats returns the traffic of the socket so far.
+func (c *UDPConn) Stats() Stats {
+	return c.stats
+}
+
+// Stats returns the traffic of every TCP and UDP connection that did I/O
+// on the loop, including connections already closed, and the state of its
+// thread pool if it has one.
+func (l *Loop) Stats() Stats {
+	s := l.stats
+	if l.hasPool {
+		s.Pool = l.poolStats()
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+func TestStatsAggregateOnLoop(t *testing.T) {
+	loop := &Loop{}
+	a := &TCPConn{loop: loop}
+	b := &UDPConn{loop: loop}
+
+	countIn(&a.stats, a.loop, 10, 0)
+	countOut(&a.stats, a.loop, 4, 0)
+	countIn(&b.stats, b.loop, 7, 0)
+	// Failed and empty completions transfer nothing.
+	countIn(&a.stats, a.loop, 0, 0)
+	countOut(&b.stats, b.loop, 3, 1)
+
+	if got, want := a.Stats(), (Stats{BytesIn: 10, BytesOut: 4, Reads: 1, Writes: 1}); got != want {
+		t.Fatalf("tcp stats: got %+v want %+v", got, want)
+	}
+	if got, want := b.Stats(), (Stats{BytesIn: 7, Reads: 1}); got != want {
+		t.Fatalf("udp stats: got %+v want %+v", got, want)
+	}
+	if got, want := loop.Stats(), (Stats{BytesIn: 17, BytesOut: 4, Reads: 2, Writes: 1}); got != want {
+		t.Fatalf("loop stats: got %+v want %+v", got, want)
+	}
+}
+
+func TestPoolTaskCounts(t *testing.T) {
+	loop := &Loop{poolFileIO: true}
+	for range 3 {
+		loop.poolSubmitted()
+	}
+	loop.poolCompleted(false)
+	loop.poolCompleted(true) // rearmed: still pending
+	if got := loop.poolTasks; got != (poolTasks{pending: 2, completed: 2}) {
+		t.Fatalf("pool tasks: %+v", got)
+	}
+
+	// A loop whose file I/O does not use the pool counts nothing.
+	native := &Loop{}
+	native.poolSubmitted()
+	native.poolCompleted(false)
+	if native.poolTasks != (poolTasks{}) {
+		t.Fatalf("native file I/O counted %+v", native.poolTasks)
+	}
+
+	if u := (PoolStats{Workers: 3, IdleWorkers: 1, MaxWorkers: 4}).Utilization(); u != 0.5 {
+		t.Fatalf("Utilization() = %v, want 0.5", u)
+	}
+	if u := (PoolStats{}).Utilization(); u != 0 {
+		t.Fatalf("Utilization() of no pool = %v", u)
+	}
+}
+
+func TestLoopStatsPool(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoopWithThreadPool()
+	if err != nil {
+		t.Fatalf("NewLoopWithThreadPool failed: %v", err)
+	}
+	defer loop.Close()
+
+	file, err := OpenFile(filepath.Join(t.TempDir(), "pool.txt"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+
+	const writes = 4
+	done := 0
+	for i := range writes {
+		err := file.PWriteFunc(loop, []byte("data"), uint64(i*4), func(*File, int, error) Action {
+			done++
+			return Stop
+		})
+		if err != nil {
+			t.Fatalf("PWrite failed: %v", err)
+		}
+	}
+	if p := loop.Stats().Pool; loop.poolFileIO && p.Queued+p.Running != writes {
+		t.Fatalf("pool stats before running: %+v, want %d pending", p, writes)
+	}
+	for i := 0; i < 100 && done < writes; i++ {
+		loop.RunOnce()
+	}
+	if done != writes {
+		t.Fatalf("%d of %d writes completed", done, writes)
+	}
+
+	p := loop.Stats().Pool
+	if p.Queued != 0 || p.Running != 0 {
+		t.Fatalf("pool stats after running: %+v, want nothing pending", p)
+	}
+	if loop.poolFileIO && p.Completed != writes {
+		t.Fatalf("pool completed %d tasks, want %d", p.Completed, writes)
+	}
+	if p.MaxWorkers == 0 || p.Workers > p.MaxWorkers || p.IdleWorkers > p.Workers {
+		t.Fatalf("pool workers: %+v", p)
+	}
+}
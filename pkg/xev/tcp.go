@@ -9,18 +9,28 @@ package xev
 import (
 	"errors"
 	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/crrow/libxev-go/pkg/cxev"
 )
 
 // ErrExtLibNotLoaded is returned when TCP/UDP/File operations are attempted
 // but the extended library is not available. Set LIBXEV_EXT_PATH environment
-// variable to the path of libxev_extended.dylib/.so/.dll.
-var ErrExtLibNotLoaded = errors.New("extended library (TCP support) not loaded; set LIBXEV_EXT_PATH")
+// variable to the path of libxev_extended.dylib/.so/.dll. It is
+// [cxev.ErrExtLibNotLoaded].
+var ErrExtLibNotLoaded = cxev.ErrExtLibNotLoaded
 
 // ErrEmptyBuffer is returned when an async read/write API is called with an empty buffer.
 var ErrEmptyBuffer = errors.New("buffer cannot be empty")
 
+// tcpConnOwner names TCPConn in re-entrancy assertions.
+const tcpConnOwner = "TCPConn"
+
 func unregisterTCPCallback(id uintptr, callbackID *uintptr) {
 	if id == 0 {
 		return
@@ -43,7 +53,7 @@ func unregisterTCPCallback(id uintptr, callbackID *uintptr) {
 //	if err != nil {
 //	    return err
 //	}
-//	defer listener.Close()
+//	defer listener.CloseNow()
 //
 //	listener.AcceptFunc(loop, func(l *xev.TCPListener, conn *xev.TCPConn, err error) xev.Action {
 //	    if err != nil {
@@ -60,6 +70,27 @@ type TCPListener struct {
 	callbackID uintptr
 	loop       *Loop
 	handler    AcceptHandler
+	span       *opSpan
+
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	backoffCurrent time.Duration
+	backoffTimer   *Timer
+	reserveFD      int
+	reusePort      bool
+
+	sniffLen  int
+	protocols []Protocol
+	// earlySize is the first read size set by WithEarlyData.
+	earlySize int
+
+	// Close state; see TCPListener.Close.
+	dispatching bool
+	closing     bool
+	cancelC     cxev.TCPCompletion
+	closeC      cxev.TCPCompletion
+	closeLoop   *Loop
+	onClose     func(l *TCPListener, err error)
 }
 
 // TCPConn represents an established TCP connection.
@@ -103,9 +134,49 @@ type TCPConn struct {
 	readBuf      []byte
 	callbackID   uintptr
 	loop         *Loop
+	span         *opSpan
 	readHandler  ReadHandler
 	writeHandler WriteHandler
 	closeHandler CloseHandler
+	// readDone is readCallback bound once, so restarting reads does not
+	// allocate a method value each time.
+	readDone cxev.TCPReadCallback
+
+	// peeked holds bytes read by a sniffing listener that the next reads
+	// return first.
+	peeked        []byte
+	replayTimer   *Timer
+	replaying     bool
+	readRequested bool
+	// earlyData holds the first bytes read by a listener with
+	// WithEarlyData.
+	earlyData []byte
+
+	// ops tracks the operation on completion; see reentrancy.go.
+	ops opGuard
+
+	proxy      *proxyConfig
+	proxyReply []byte
+
+	connectTimeout *connectTimeout
+
+	stats Stats
+
+	// transport replaces the socket of a connection made by
+	// NewTransportConn.
+	transport Transport
+
+	// fileSlot is the slot of fileLoop's file table the socket is in; see
+	// WithFixedFiles. fixedRead is set while a read goes through a
+	// registered file or buffer, and writeBuf holds the data of such a
+	// write, both to submit them again that way on Continue.
+	fileLoop  *Loop
+	fileSlot  int32
+	fixedRead bool
+	writeBuf  []byte
+
+	// closed is set by Close; see ErrClosed.
+	closed atomic.Bool
 }
 
 // AcceptHandler handles accepted TCP connections.
@@ -114,7 +185,8 @@ type TCPConn struct {
 // [AcceptFunc] provides a more convenient functional approach.
 type AcceptHandler interface {
 	// OnAccept is called when a new connection is accepted.
-	// conn is nil if err is non-nil.
+	// conn is nil if err is non-nil; err is an [*OpError] that unwraps to
+	// the errno (e.g. syscall.EMFILE) when one is known.
 	// Return [Continue] to keep accepting connections, or [Stop] to stop.
 	OnAccept(listener *TCPListener, conn *TCPConn, err error) Action
 }
@@ -190,6 +262,10 @@ func (f CloseFunc) OnClose(c *TCPConn, err error) {
 // The address should be in "host:port" format, e.g., "127.0.0.1:8080" or
 // "0.0.0.0:8080" for all interfaces.
 //
+// Options such as [WithAcceptBackoff] and [WithReserveFD] control how the
+// listener reacts to file descriptor exhaustion; [WithReusePort] lets
+// several listeners share the address.
+//
 // Returns [ErrExtLibNotLoaded] if the extended library is not available.
 //
 // Example:
@@ -198,8 +274,8 @@ func (f CloseFunc) OnClose(c *TCPConn, err error) {
 //	if err != nil {
 //	    return err
 //	}
-//	defer listener.Close()
-func Listen(network, address string) (*TCPListener, error) {
+//	defer listener.CloseNow()
+func Listen(network, address string, opts ...ListenOption) (*TCPListener, error) {
 	if !cxev.ExtLibLoaded() {
 		return nil, ErrExtLibNotLoaded
 	}
@@ -209,19 +285,34 @@ func Listen(network, address string) (*TCPListener, error) {
 		return nil, err
 	}
 
-	listener := &TCPListener{}
+	listener := &TCPListener{reserveFD: -1}
 
 	if err := cxev.TCPInit(&listener.tcp, cxev.AF_INET()); err != nil {
 		return nil, err
 	}
 
+	for _, opt := range opts {
+		opt(listener)
+	}
+
+	if listener.reusePort {
+		fd := int(cxev.TCPFd(&listener.tcp))
+		if err := setReusePort(fd); err != nil {
+			closeFD(fd)
+			listener.closeBackoff()
+			return nil, os.NewSyscallError("setsockopt", err)
+		}
+	}
+
 	cxev.SockaddrIPv4(&listener.addr, host[0], host[1], host[2], host[3], port)
 
 	if err := cxev.TCPBind(&listener.tcp, &listener.addr); err != nil {
+		listener.closeBackoff()
 		return nil, err
 	}
 
 	if err := cxev.TCPListen(&listener.tcp, 128); err != nil {
+		listener.closeBackoff()
 		return nil, err
 	}
 
@@ -235,11 +326,15 @@ func Listen(network, address string) (*TCPListener, error) {
 func (l *TCPListener) Accept(loop *Loop, handler AcceptHandler) error {
 	l.loop = loop
 	l.handler = handler
-
-	l.callbackID = cxev.TCPAcceptWithCallback(&l.tcp, &loop.inner, &l.completion, l.acceptCallback)
+	l.arm()
 	return nil
 }
 
+func (l *TCPListener) arm() {
+	l.span = l.loop.startOp("xev.tcp.accept")
+	l.callbackID = cxev.TCPAcceptWithCallback(&l.tcp, &l.loop.inner, &l.completion, l.acceptCallback)
+}
+
 // AcceptFunc starts accepting connections using a callback function.
 //
 // This is a convenience wrapper around [TCPListener.Accept] for functional-style
@@ -249,17 +344,49 @@ func (l *TCPListener) AcceptFunc(loop *Loop, fn func(listener *TCPListener, conn
 }
 
 func (l *TCPListener) acceptCallback(loop *cxev.Loop, c *cxev.TCPCompletion, fd int32, errCode int32, userdata uintptr) cxev.CbAction {
+	if l.closing {
+		// The accept Close cancelled, or one that completed first.
+		if errCode == 0 {
+			closeFD(int(fd))
+		}
+		return l.finishAccept(userdata)
+	}
+
 	var err error
 	var conn *TCPConn
 
 	if errCode != 0 {
-		err = errors.New("accept error")
+		err = newOpError("accept", errCode)
 	} else {
 		conn = &TCPConn{fd: fd}
 		cxev.TCPInitFd(&conn.tcp, fd)
+		l.resetBackoff()
+	}
+
+	l.span.complete()
+	if l.loop.overBudget() {
+		// The connection waits for the next iteration, and the ones behind
+		// it in the backlog until it has been handed over.
+		l.span = l.span.finish(0, errCode, Stop)
+		unregisterTCPCallback(userdata, &l.callbackID)
+		l.loop.carry(func() { l.resumeAccept(conn, err) })
+		return cxev.Disarm
 	}
 
-	action := l.handler.OnAccept(l, conn, err)
+	action := l.deliver(conn, err)
+	if l.closing {
+		l.span = l.span.finish(0, errCode, Stop)
+		return l.finishAccept(userdata)
+	}
+	if action == Continue && isFdExhaustion(err) {
+		l.shedPendingConnection()
+		if l.pauseAccept() {
+			l.span = l.span.finish(0, errCode, Stop)
+			unregisterTCPCallback(userdata, &l.callbackID)
+			return cxev.Disarm
+		}
+	}
+	l.span = l.span.finish(0, errCode, action)
 	if action == Continue {
 		return cxev.Rearm
 	}
@@ -267,6 +394,49 @@ func (l *TCPListener) acceptCallback(loop *cxev.Loop, c *cxev.TCPCompletion, fd
 	return cxev.Disarm
 }
 
+// deliver hands an accepted connection, or the accept error, to the
+// handler, or to protocol sniffing.
+func (l *TCPListener) deliver(conn *TCPConn, err error) Action {
+	l.loop.spend()
+	if conn != nil && l.protocols != nil {
+		l.sniff(conn)
+		return Continue
+	}
+	if conn != nil && l.earlySize > 0 && !l.readEarly(conn) {
+		return Continue
+	}
+	l.dispatching = true
+	action := l.onAccept(conn, err)
+	l.dispatching = false
+	return action
+}
+
+// resumeAccept delivers an accept carried over from an earlier iteration,
+// then accepts again if the handler asks for it.
+func (l *TCPListener) resumeAccept(conn *TCPConn, err error) {
+	if l.closing {
+		// Close found nothing in flight and closed the socket itself.
+		if conn != nil {
+			closeFD(int(conn.fd))
+		}
+		return
+	}
+	action := l.deliver(conn, err)
+	if l.closing {
+		l.closeSocket()
+		return
+	}
+	if action == Continue && isFdExhaustion(err) {
+		l.shedPendingConnection()
+		if l.pauseAccept() {
+			return
+		}
+	}
+	if action == Continue {
+		l.arm()
+	}
+}
+
 // Addr returns the local address the listener is bound to.
 // Returns the host (always "0.0.0.0" currently) and port number.
 func (l *TCPListener) Addr() (string, uint16) {
@@ -276,14 +446,83 @@ func (l *TCPListener) Addr() (string, uint16) {
 	return "0.0.0.0", port
 }
 
-// Close stops accepting connections and releases listener resources.
+// Close stops accepting connections and closes the listening socket on
+// loop. An accept in flight is cancelled first; handler, which may be nil,
+// is called once the socket is closed, after which its address can be
+// bound again. Close may be called from the accept handler.
 //
-// This should be called when the listener is no longer needed.
-func (l *TCPListener) Close() {
+// Closing a closed listener returns [ErrClosed].
+func (l *TCPListener) Close(loop *Loop, handler func(l *TCPListener, err error)) error {
+	if l.closing {
+		return ErrClosed
+	}
+	l.closing = true
+	l.handler = nil
+	l.closeBackoff()
+	l.closeLoop = loop
+	l.onClose = handler
+
+	switch {
+	case l.dispatching:
+		// acceptCallback finishes the close when the handler returns.
+	case l.callbackID != 0:
+		cxev.TCPCancelWithCallback(&loop.inner, &l.completion, &l.cancelC, func(_ *cxev.Loop, _ *cxev.TCPCompletion, _ int32, userdata uintptr) cxev.CbAction {
+			// The accept callback sees the cancellation and closes the
+			// socket; an accept that completed first fails it, which is
+			// fine too.
+			unregisterTCPCallback(userdata, nil)
+			return cxev.Disarm
+		})
+	default:
+		l.closeSocket()
+	}
+	return nil
+}
+
+// finishAccept disarms the accept of a closing listener and closes the
+// socket.
+func (l *TCPListener) finishAccept(userdata uintptr) cxev.CbAction {
+	unregisterTCPCallback(userdata, &l.callbackID)
+	l.closeSocket()
+	return cxev.Disarm
+}
+
+func (l *TCPListener) closeSocket() {
+	cxev.TCPCloseWithCallback(&l.tcp, &l.closeLoop.inner, &l.closeC, func(_ *cxev.Loop, _ *cxev.TCPCompletion, result int32, userdata uintptr) cxev.CbAction {
+		unregisterTCPCallback(userdata, nil)
+		var err error
+		if result != 0 {
+			err = newOpError("close", result)
+		}
+		if l.onClose != nil {
+			l.onClosed(err)
+		}
+		return cxev.Disarm
+	})
+}
+
+// CloseNow stops accepting connections and closes the listening socket
+// immediately, for teardown paths that do not run the loop again. An
+// accept still in flight on a completion-based backend such as io_uring
+// holds the socket open, bound, until the loop is closed; use
+// [TCPListener.Close] where the loop keeps running.
+//
+// Closing a closed listener returns [ErrClosed].
+func (l *TCPListener) CloseNow() error {
+	if l.closing {
+		return ErrClosed
+	}
+	l.closing = true
 	if l.callbackID != 0 {
 		cxev.UnregisterTCPCallback(l.callbackID)
 		l.callbackID = 0
 	}
+	l.handler = nil
+	l.closeBackoff()
+	if err := syscall.Close(int(cxev.TCPFd(&l.tcp))); err != nil {
+		return os.NewSyscallError("close", err)
+	}
+	return nil
 }
 
 // Dial creates a TCP connection ready to connect to an address.
@@ -291,8 +530,11 @@ func (l *TCPListener) Close() {
 // This creates the socket but does not connect yet. Call [TCPConn.Connect]
 // to initiate the async connection.
 //
+// Options such as [WithSOCKS5Proxy] and [WithHTTPProxy] make Connect go
+// through a proxy; [WithConnectTimeout] bounds how long it may take.
+//
 // Returns [ErrExtLibNotLoaded] if the extended library is not available.
-func Dial(network, address string) (*TCPConn, error) {
+func Dial(network, address string, opts ...DialOption) (*TCPConn, error) {
 	if !cxev.ExtLibLoaded() {
 		return nil, ErrExtLibNotLoaded
 	}
@@ -311,6 +553,10 @@ func Dial(network, address string) (*TCPConn, error) {
 	var addr cxev.Sockaddr
 	cxev.SockaddrIPv4(&addr, host[0], host[1], host[2], host[3], port)
 
+	for _, opt := range opts {
+		opt(conn)
+	}
+
 	return conn, nil
 }
 
@@ -319,6 +565,14 @@ func Dial(network, address string) (*TCPConn, error) {
 // The handler is called when the connection completes (success or failure).
 // On success, err is nil and the connection is ready for read/write operations.
 //
+// A connection dialed with a proxy option connects to the proxy and calls
+// handler once the proxy has connected it to address, or with a
+// [*ProxyError] if the handshake fails. Its handler is called once; the
+// returned [Action] is ignored.
+//
+// A connection dialed with [WithConnectTimeout] passes [ErrConnectTimeout]
+// to handler if the connect takes too long.
+//
 // Example:
 //
 //	conn, _ := xev.Dial("tcp", "")
@@ -331,8 +585,28 @@ func Dial(network, address string) (*TCPConn, error) {
 //	    return xev.Stop
 //	})
 func (c *TCPConn) Connect(loop *Loop, address string, handler func(conn *TCPConn, err error) Action) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
 	c.loop = loop
 
+	if c.transport != nil {
+		return errTransportConnect
+	}
+	if c.proxy != nil {
+		target := address
+		address = c.proxy.addr
+		direct := handler
+		handler = func(c *TCPConn, err error) Action {
+			if err != nil {
+				direct(c, err)
+				return Stop
+			}
+			c.handshake(target, func(err error) { direct(c, err) })
+			return Stop
+		}
+	}
+
 	host, port, err := parseAddress(address)
 	if err != nil {
 		return err
@@ -341,16 +615,31 @@ func (c *TCPConn) Connect(loop *Loop, address string, handler func(conn *TCPConn
 	var addr cxev.Sockaddr
 	cxev.SockaddrIPv4(&addr, host[0], host[1], host[2], host[3], port)
 
+	if c.connectTimeout != nil {
+		if err := c.connectTimeout.arm(loop, c); err != nil {
+			return err
+		}
+	}
+	c.ops.submit(tcpConnOwner, "connect")
+	c.span = loop.startOp("xev.tcp.connect", attribute.String("net.peer.address", address))
 	c.callbackID = cxev.TCPConnectWithCallback(&c.tcp, &loop.inner, &c.completion, &addr, func(loop *cxev.Loop, comp *cxev.TCPCompletion, result int32, userdata uintptr) cxev.CbAction {
 		var err error
 		if result != 0 {
-			err = errors.New("connect error")
+			err = newOpError("connect", result)
+		}
+		if c.connectTimeout != nil {
+			err = c.connectTimeout.settle(err)
 		}
-		action := handler(c, err)
+		span := c.span
+		span.complete()
+		c.ops.dispatch()
+		action := c.ops.finish(tcpConnOwner, c.onConnect(handler, err))
+		c.span = span.settle(c.span, 0, result, action)
 		if action == Continue {
 			return cxev.Rearm
 		}
 		unregisterTCPCallback(userdata, &c.callbackID)
+		c.ops.runDeferred()
 		return cxev.Disarm
 	})
 
@@ -364,19 +653,60 @@ func (c *TCPConn) Connect(loop *Loop, address string, handler func(conn *TCPConn
 //
 // The provided buffer is used for the read operation. The data slice passed
 // to the handler is a slice of this buffer containing the bytes read.
+//
+// Without tracing, reads do not allocate once a connection is running:
+// neither a handler that returns Continue nor one that returns Stop and
+// calls Read again. Keeping it that way also takes a handler that does not
+// allocate, such as a method value bound once rather than a new closure per
+// call.
 func (c *TCPConn) Read(loop *Loop, buf []byte, handler ReadHandler) error {
 	if len(buf) == 0 {
 		return ErrEmptyBuffer
 	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
 
 	c.loop = loop
 	c.readHandler = handler
 	c.readBuf = buf
 
-	c.callbackID = cxev.TCPReadWithCallback(&c.tcp, &loop.inner, &c.completion, buf, c.readCallback)
+	switch {
+	case c.replaying:
+		c.readRequested = true
+	case c.peeked != nil:
+		return c.replayPeeked()
+	default:
+		c.armRead()
+	}
 	return nil
 }
 
+func (c *TCPConn) armRead() {
+	if c.transport != nil {
+		c.transportRead()
+		return
+	}
+	if c.readDone == nil {
+		c.readDone = c.readCallback
+	}
+	c.ops.submit(tcpConnOwner, "read")
+	c.span = c.loop.startOp("xev.tcp.read")
+	c.submitRead()
+}
+
+// submitRead hands the read to libxev, through the loop's registered file
+// and buffer when it has them.
+func (c *TCPConn) submitRead() {
+	file, buf := c.fixedFile(c.loop), c.loop.fixedBuffer(c.readBuf)
+	if file >= 0 || buf >= 0 {
+		c.callbackID, c.fixedRead = cxev.TCPReadFixedWithCallback(&c.tcp, &c.loop.inner, &c.completion, c.readBuf, file, buf, c.readDone)
+		return
+	}
+	c.fixedRead = false
+	c.callbackID = cxev.TCPReadWithCallback(&c.tcp, &c.loop.inner, &c.completion, c.readBuf, c.readDone)
+}
+
 // ReadFunc starts an async read operation using a callback function.
 //
 // This is a convenience wrapper around [TCPConn.Read] for functional-style callbacks.
@@ -387,17 +717,58 @@ func (c *TCPConn) ReadFunc(loop *Loop, buf []byte, fn func(conn *TCPConn, data [
 func (c *TCPConn) readCallback(loop *cxev.Loop, comp *cxev.TCPCompletion, data []byte, bytesRead int32, errCode int32, userdata uintptr) cxev.CbAction {
 	var err error
 	if errCode != 0 {
-		err = errors.New("read error")
+		err = newOpError("read", errCode)
 	}
+	countIn(&c.stats, c.loop, bytesRead, errCode)
+	c.span.complete()
+	if c.loop.overBudget() {
+		// The data waits in the buffer for the next iteration. The read
+		// stays in flight meanwhile, as far as the connection is concerned.
+		unregisterTCPCallback(userdata, &c.callbackID)
+		c.loop.carry(func() { c.resumeRead(data, err, bytesRead, errCode) })
+		return cxev.Disarm
+	}
+	c.loop.spend()
 
-	action := c.readHandler.OnRead(c, data, err)
+	span := c.span
+	c.ops.dispatch()
+	action := c.ops.finish(tcpConnOwner, c.onRead(data, err))
+	c.span = span.settle(c.span, int(bytesRead), errCode, action)
 	if action == Continue {
-		return cxev.Rearm
+		if !c.fixedRead {
+			return cxev.Rearm
+		}
+		// libxev would rearm the read without the registered file and
+		// buffer.
+		unregisterTCPCallback(userdata, &c.callbackID)
+		c.submitRead()
+		return cxev.Disarm
 	}
 	unregisterTCPCallback(userdata, &c.callbackID)
+	c.ops.runDeferred()
 	return cxev.Disarm
 }
 
+// resumeRead hands a read carried over from an earlier iteration to the
+// handler, then submits the read again if the handler asks for it.
+func (c *TCPConn) resumeRead(data []byte, err error, bytesRead, errCode int32) {
+	if c.closed.Load() {
+		// Close was called while the read waited; its data goes with the
+		// connection.
+		return
+	}
+	c.loop.spend()
+	span := c.span
+	c.ops.dispatch()
+	action := c.ops.finish(tcpConnOwner, c.onRead(data, err))
+	c.span = span.settle(c.span, int(bytesRead), errCode, action)
+	if action == Continue {
+		c.submitRead()
+		return
+	}
+	c.ops.runDeferred()
+}
+
 // Write starts an async write operation using a handler interface.
 //
 // The handler's OnWrite method is called when the write completes. The
@@ -406,14 +777,40 @@ func (c *TCPConn) Write(loop *Loop, data []byte, handler WriteHandler) error {
 	if len(data) == 0 {
 		return ErrEmptyBuffer
 	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
 
 	c.loop = loop
 	c.writeHandler = handler
 
-	c.callbackID = cxev.TCPWriteWithCallback(&c.tcp, &loop.inner, &c.completion, data, c.writeCallback)
+	if c.transport != nil {
+		c.transportWrite(data)
+		return nil
+	}
+	c.ops.submit(tcpConnOwner, "write")
+	c.span = loop.startOp("xev.tcp.write")
+	c.submitWrite(data)
 	return nil
 }
 
+// submitWrite hands the write of data to libxev, through the loop's
+// registered file and buffer when it has them.
+func (c *TCPConn) submitWrite(data []byte) {
+	file, buf := c.fixedFile(c.loop), c.loop.fixedBuffer(data)
+	if file >= 0 || buf >= 0 {
+		var fixed bool
+		c.callbackID, fixed = cxev.TCPWriteFixedWithCallback(&c.tcp, &c.loop.inner, &c.completion, data, file, buf, c.writeCallback)
+		c.writeBuf = nil
+		if fixed {
+			c.writeBuf = data
+		}
+		return
+	}
+	c.writeBuf = nil
+	c.callbackID = cxev.TCPWriteWithCallback(&c.tcp, &c.loop.inner, &c.completion, data, c.writeCallback)
+}
+
 // WriteFunc starts an async write operation using a callback function.
 //
 // This is a convenience wrapper around [TCPConn.Write] for functional-style callbacks.
@@ -421,35 +818,110 @@ func (c *TCPConn) WriteFunc(loop *Loop, data []byte, fn func(conn *TCPConn, byte
 	return c.Write(loop, data, WriteFunc(fn))
 }
 
+// WaitWritable calls fn once the connection is writable, without writing
+// anything. Use it to manage the output buffer yourself: after a write
+// came up short, wait for writability and then write what is left, rather
+// than keeping a write in flight for the whole buffer.
+//
+// Return [Continue] from fn to wait again, or [Stop] when done. Like the
+// other operations, the wait occupies the connection; see [TCPConn.Defer].
+//
+// Returns an error if the loop's backend cannot wait for readiness
+// (kqueue), or on a connection made by [NewTransportConn].
+func (c *TCPConn) WaitWritable(loop *Loop, fn func(conn *TCPConn, err error) Action) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.transport != nil {
+		return errTransportWaitWritable
+	}
+	id, err := cxev.TCPPollWritableWithCallback(&c.tcp, &loop.inner, &c.completion, func(_ *cxev.Loop, _ *cxev.TCPCompletion, result int32, userdata uintptr) cxev.CbAction {
+		var err error
+		if result != 0 {
+			err = newOpError("wait writable", result)
+		}
+		span := c.span
+		span.complete()
+		c.ops.dispatch()
+		action := c.ops.finish(tcpConnOwner, c.onWritable(fn, err))
+		c.span = span.settle(c.span, 0, result, action)
+		if action == Continue {
+			return cxev.Rearm
+		}
+		unregisterTCPCallback(userdata, &c.callbackID)
+		c.ops.runDeferred()
+		return cxev.Disarm
+	})
+	if err != nil {
+		return err
+	}
+	c.loop = loop
+	c.callbackID = id
+	c.ops.submit(tcpConnOwner, "wait writable")
+	c.span = loop.startOp("xev.tcp.wait_writable")
+	return nil
+}
+
 func (c *TCPConn) writeCallback(loop *cxev.Loop, comp *cxev.TCPCompletion, bytesWritten int32, errCode int32, userdata uintptr) cxev.CbAction {
 	var err error
 	if errCode != 0 {
-		err = errors.New("write error")
+		err = newOpError("write", errCode)
 	}
+	countOut(&c.stats, c.loop, bytesWritten, errCode)
 
-	action := c.writeHandler.OnWrite(c, int(bytesWritten), err)
+	span := c.span
+	span.complete()
+	c.ops.dispatch()
+	action := c.ops.finish(tcpConnOwner, c.onWrite(int(bytesWritten), err))
+	c.span = span.settle(c.span, int(bytesWritten), errCode, action)
 	if action == Continue {
-		return cxev.Rearm
+		if c.writeBuf == nil {
+			return cxev.Rearm
+		}
+		unregisterTCPCallback(userdata, &c.callbackID)
+		c.submitWrite(c.writeBuf)
+		return cxev.Disarm
 	}
+	c.writeBuf = nil
 	unregisterTCPCallback(userdata, &c.callbackID)
+	c.ops.runDeferred()
 	return cxev.Disarm
 }
 
 // Close starts an async close operation.
 //
-// The handler (if non-nil) is called when the close completes. After close
-// completes, the connection must not be used.
+// The handler (if non-nil) is called when the close completes. Operations
+// started after Close, including another Close, return [ErrClosed]. Like
+// every other method, Close must be called on the loop's goroutine; to
+// close a connection from another goroutine, hand the call to the loop
+// with [Loop.Post].
 func (c *TCPConn) Close(loop *Loop, handler CloseHandler) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
 	c.loop = loop
 	c.closeHandler = handler
 
+	if c.transport != nil {
+		c.transportClose()
+		return nil
+	}
+	c.ops.submit(tcpConnOwner, "close")
+	c.span = loop.startOp("xev.tcp.close")
+	c.releaseFixedFile()
 	c.callbackID = cxev.TCPCloseWithCallback(&c.tcp, &loop.inner, &c.completion, func(loop *cxev.Loop, comp *cxev.TCPCompletion, result int32, userdata uintptr) cxev.CbAction {
 		var err error
 		if result != 0 {
-			err = errors.New("close error")
+			err = newOpError("close", result)
+		}
+		c.ops.closed()
+		c.span = c.span.finish(0, result, Stop)
+		if c.replayTimer != nil {
+			c.replayTimer.Close()
+			c.replayTimer = nil
 		}
 		if c.closeHandler != nil {
-			c.closeHandler.OnClose(c, err)
+			c.onClosed(err)
 		}
 		unregisterTCPCallback(userdata, &c.callbackID)
 		return cxev.Disarm
@@ -7,7 +7,11 @@
 package xev
 
 import (
+	"errors"
+	"net"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/crrow/libxev-go/pkg/cxev"
 )
@@ -21,7 +25,7 @@ func TestTCPListenerBind(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Listen failed: %v", err)
 	}
-	defer listener.Close()
+	defer listener.CloseNow()
 
 	_, port := listener.Addr()
 	if port == 0 {
@@ -45,7 +49,7 @@ func TestTCPEchoServer(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Listen failed: %v", err)
 	}
-	defer listener.Close()
+	defer listener.CloseNow()
 
 	_, port := listener.Addr()
 
@@ -133,6 +137,70 @@ func TestTCPEchoServer(t *testing.T) {
 	}
 }
 
+func TestTCPConnectTimeout(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	connect := func(address string) (calls int, err error) {
+		conn, dialErr := Dial("tcp", address, WithConnectTimeout(50*time.Millisecond))
+		if dialErr != nil {
+			t.Fatalf("Dial failed: %v", dialErr)
+		}
+		if err := conn.Connect(loop, address, func(_ *TCPConn, connErr error) Action {
+			calls++
+			err = connErr
+			return Stop
+		}); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		// Run past the timeout, so a timer left armed would fire.
+		start := time.Now()
+		for calls == 0 || time.Since(start) < 200*time.Millisecond {
+			if time.Since(start) > 5*time.Second {
+				t.Fatal("connect did not complete")
+			}
+			loop.Poll()
+			// The timer, cancelled on connect, must stay valid until
+			// libxev is done with it.
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		_ = conn.CloseFunc(loop, nil)
+		_ = loop.Run()
+		return calls, err
+	}
+
+	if calls, err := connect("127.0.0.1:" + itoa(int(port))); calls != 1 || err != nil {
+		t.Fatalf("local connect: %d calls, err %v", calls, err)
+	}
+
+	// Nothing answers at this address, so the connect stays pending.
+	calls, err := connect("10.255.255.1:9")
+	if calls != 1 {
+		t.Fatalf("handler called %d times", calls)
+	}
+	if err != nil && !errors.Is(err, ErrConnectTimeout) {
+		t.Skipf("connect failed before the timeout: %v", err)
+	}
+	if err == nil {
+		t.Fatal("connect to a blackholed address succeeded")
+	}
+}
+
 func itoa(n int) string {
 	if n == 0 {
 		return "0"
@@ -146,3 +214,207 @@ func itoa(n int) string {
 	}
 	return string(buf[i:])
 }
+
+// rebind fails the test unless 127.0.0.1:port can be listened on again.
+func rebind(t *testing.T, port uint16) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:"+itoa(int(port)))
+	if err != nil {
+		t.Fatalf("port %d not released: %v", port, err)
+	}
+	_ = ln.Close()
+}
+
+func TestTCPListenerCloseReleasesPort(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		t.Errorf("unexpected accept: %v", err)
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	closed := false
+	err = listener.Close(loop, func(l *TCPListener, err error) {
+		if err != nil {
+			t.Errorf("close error: %v", err)
+		}
+		closed = true
+	})
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !closed {
+		t.Fatal("close handler not called")
+	}
+	if err := listener.Close(loop, nil); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("second Close: %v, want net.ErrClosed", err)
+	}
+	rebind(t, port)
+	if n := cxev.DebugTCPCallbackCount(); n != 0 {
+		t.Fatalf("expected no TCP callback leaks, found %d active registrations", n)
+	}
+}
+
+func TestTCPListenerCloseFromAccept(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+
+	closed := false
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("accept error: %v", err)
+			return Stop
+		}
+		_ = conn.CloseFunc(loop, nil)
+		if err := l.Close(loop, func(*TCPListener, error) { closed = true }); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	client, err := net.Dial("tcp", "127.0.0.1:"+itoa(int(port)))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !closed {
+		t.Fatal("close handler not called")
+	}
+	rebind(t, port)
+}
+
+func TestTCPListenerCloseNow(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	_, port := listener.Addr()
+	if err := listener.CloseNow(); err != nil {
+		t.Fatalf("CloseNow failed: %v", err)
+	}
+	rebind(t, port)
+	if err := listener.CloseNow(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("second CloseNow: %v, want net.ErrClosed", err)
+	}
+}
+
+func TestTCPWaitWritable(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	listener, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.CloseNow()
+	_, port := listener.Addr()
+
+	err = listener.AcceptFunc(loop, func(l *TCPListener, conn *TCPConn, err error) Action {
+		if err == nil {
+			conn.CloseFunc(loop, nil)
+		}
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	client, err := Dial("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	waits := 0
+	done := false
+	err = client.Connect(loop, "127.0.0.1:"+itoa(int(port)), func(c *TCPConn, err error) Action {
+		if err != nil {
+			t.Errorf("connect error: %v", err)
+			return Stop
+		}
+		err = c.WaitWritable(loop, func(c *TCPConn, err error) Action {
+			if err != nil {
+				t.Errorf("wait writable error: %v", err)
+				return Stop
+			}
+			// A fresh connection has room, so the wait completes at once
+			// and again when re-armed.
+			waits++
+			if waits < 2 {
+				return Continue
+			}
+			done = true
+			c.CloseFunc(loop, nil)
+			return Stop
+		})
+		if err != nil {
+			t.Skipf("WaitWritable not supported: %v", err)
+		}
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	for i := 0; i < 1000 && !done; i++ {
+		loop.RunOnce()
+	}
+	for i := 0; i < 50; i++ {
+		loop.Poll()
+	}
+
+	if waits != 2 {
+		t.Fatalf("writability reported %d times, want 2", waits)
+	}
+	if n := cxev.DebugTCPCallbackCount(); n != 0 {
+		t.Fatalf("expected no TCP callback leaks, found %d active registrations", n)
+	}
+}
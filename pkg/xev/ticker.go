@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Ticker fires at a fixed period, on the schedule set when it started.
+//
+// A [Timer] that returns [Continue] is re-armed with its delay when its
+// callback returns, so the time the callback and the loop take adds up
+// tick after tick. A Ticker instead computes each tick from the start:
+// the n-th tick is due n periods after [Ticker.Run], however late the ones
+// before it ran. When the loop falls behind by more than a period, the
+// ticks already past are skipped rather than fired back to back, like
+// [time.Ticker]; [Ticker.Skipped] counts them.
+//
+// Ticks have the millisecond resolution of the loop's clock.
+//
+// Example:
+//
+//	ticker, err := xev.NewTicker()
+//	if err != nil {
+//	    return err
+//	}
+//	defer ticker.Close()
+//
+//	ticker.RunFunc(loop, time.Second, func(tk *xev.Ticker, err error) xev.Action {
+//	    flushMetrics()
+//	    return xev.Continue
+//	})
+//
+// # Thread Safety
+//
+// Ticker operations are not thread-safe. All operations on a Ticker must be
+// performed from the same goroutine that runs the [Loop].
+type Ticker struct {
+	// timers alternate: a timer cannot be run again from its own
+	// callback, so each tick arms the other one with the next delay.
+	timers  [2]*Timer
+	cur     int
+	loop    *Loop
+	handler TickerHandler
+	period  time.Duration
+	// next is the loop time the next tick is due.
+	next    time.Duration
+	skipped uint64
+	closed  bool
+}
+
+// TickerHandler handles the ticks of a [Ticker].
+type TickerHandler interface {
+	// OnTick is called for each tick. Return [Continue] for the next one,
+	// or [Stop] to stop the ticker.
+	OnTick(tk *Ticker, err error) Action
+}
+
+// TickerFunc is a function adapter for [TickerHandler].
+type TickerFunc func(tk *Ticker, err error) Action
+
+// OnTick implements [TickerHandler].
+func (f TickerFunc) OnTick(tk *Ticker, err error) Action {
+	return f(tk, err)
+}
+
+// NewTicker creates a ticker. It does not tick until [Ticker.Run] is
+// called; call [Ticker.Close] when it is no longer needed.
+func NewTicker() (*Ticker, error) {
+	a, err := NewTimer()
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewTimer()
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	return &Ticker{timers: [2]*Timer{a, b}}, nil
+}
+
+// Run starts the ticker, calling handler every period from now.
+//
+// Returns an error if handler is nil or period is under a millisecond.
+func (tk *Ticker) Run(loop *Loop, period time.Duration, handler TickerHandler) error {
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	if period < time.Millisecond {
+		return errors.New("ticker period must be at least a millisecond")
+	}
+	tk.loop = loop
+	tk.handler = handler
+	tk.period = period
+	tk.next = loop.Now() + period
+	return tk.timers[tk.cur].RunFunc(loop, period, tk.fire)
+}
+
+// RunFunc starts the ticker with a callback function.
+//
+// This is a convenience wrapper around [Ticker.Run] for functional-style
+// callbacks.
+func (tk *Ticker) RunFunc(loop *Loop, period time.Duration, fn func(tk *Ticker, err error) Action) error {
+	return tk.Run(loop, period, TickerFunc(fn))
+}
+
+// Period returns the period the ticker was started with.
+func (tk *Ticker) Period() time.Duration {
+	return tk.period
+}
+
+// Skipped returns the number of ticks skipped because the loop was more
+// than a period late for them.
+func (tk *Ticker) Skipped() uint64 {
+	return tk.skipped
+}
+
+// Close stops the ticker and releases its resources. It may be called from
+// the ticker's own handler.
+func (tk *Ticker) Close() {
+	if tk.closed {
+		return
+	}
+	tk.closed = true
+	for _, t := range tk.timers {
+		t.Close()
+	}
+}
+
+func (tk *Ticker) fire(_ *Timer, err error) Action {
+	if tk.handler.OnTick(tk, err) != Continue || err != nil || tk.closed {
+		return Stop
+	}
+	cxev.LoopUpdateNow(&tk.loop.inner)
+	delay := tk.advance(tk.loop.Now())
+	tk.cur ^= 1
+	_ = tk.timers[tk.cur].RunFunc(tk.loop, delay, tk.fire)
+	return Stop
+}
+
+// advance moves the schedule on to the next tick not yet past at now and
+// returns the delay until it, counting the ticks skipped.
+func (tk *Ticker) advance(now time.Duration) time.Duration {
+	tk.next += tk.period
+	if tk.next < now {
+		missed := (now - tk.next + tk.period - 1) / tk.period
+		tk.skipped += uint64(missed)
+		tk.next += missed * tk.period
+	}
+	return tk.next - now
+}
@@ -0,0 +1,87 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTickerAdvance(t *testing.T) {
+	ms := time.Millisecond
+	tk := &Ticker{period: 10 * ms, next: 10 * ms}
+
+	// On time, and a little late: the next tick keeps the schedule.
+	if d := tk.advance(10 * ms); d != 10*ms {
+		t.Fatalf("delay after an on-time tick = %v, want 10ms", d)
+	}
+	if d := tk.advance(23 * ms); d != 7*ms || tk.next != 30*ms {
+		t.Fatalf("delay after a late tick = %v (next %v), want 7ms to 30ms", d, tk.next)
+	}
+	if tk.Skipped() != 0 {
+		t.Fatalf("skipped %d ticks while keeping up", tk.Skipped())
+	}
+
+	// More than a period late: the ticks at 40ms and 50ms are skipped.
+	if d := tk.advance(55 * ms); d != 5*ms || tk.next != 60*ms {
+		t.Fatalf("delay after falling behind = %v (next %v), want 5ms to 60ms", d, tk.next)
+	}
+	if tk.Skipped() != 2 {
+		t.Fatalf("skipped %d ticks, want 2", tk.Skipped())
+	}
+
+	// A tick due exactly now fires right away.
+	if d := tk.advance(70 * ms); d != 0 || tk.Skipped() != 2 {
+		t.Fatalf("delay for a tick due now = %v, skipped %d", d, tk.Skipped())
+	}
+}
+
+func TestTickerKeepsSchedule(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	tk, err := NewTicker()
+	if err != nil {
+		t.Fatalf("NewTicker failed: %v", err)
+	}
+	defer tk.Close()
+
+	const period, ticks = 20 * time.Millisecond, 5
+	start := time.Now()
+	n := 0
+	err = tk.RunFunc(loop, period, func(tk *Ticker, err error) Action {
+		if err != nil {
+			t.Errorf("tick error: %v", err)
+			return Stop
+		}
+		n++
+		// Each tick takes a good part of the period; a re-armed timer
+		// would drift by that much every time.
+		time.Sleep(period / 2)
+		if n == ticks {
+			return Stop
+		}
+		return Continue
+	})
+	if err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if n != ticks {
+		t.Fatalf("ticked %d times, want %d", n, ticks)
+	}
+	// The last tick is due at ticks*period and its handler takes half a
+	// period; drift would add another half period per tick.
+	if elapsed := time.Since(start); elapsed > ticks*period+period {
+		t.Fatalf("%d ticks of %v took %v", ticks, period, elapsed)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"errors"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/crrow/libxev-go/pkg/cxev"
 )
 
@@ -61,6 +63,9 @@ type TimerEvent struct {
 //   - [Timer.RunWithHandler]: Interface for stateful handlers
 //   - [Timer.RunChan]: Channel for select-based patterns
 //
+// [Timer.RunAt] and [Timer.RunAtFunc] schedule a one-shot timer for a
+// [time.Time] instead of after a delay.
+//
 // # Thread Safety
 //
 // Timer operations are not thread-safe. All operations on a Timer must be
@@ -71,6 +76,19 @@ type Timer struct {
 	handler    TimerHandler
 	callbackID uintptr
 	loop       *Loop
+	span       *opSpan
+	// delay is the period the timer rearms with; deadline is when it
+	// fires next and queued its position in the loop's timer queue, or -1.
+	delay    time.Duration
+	deadline time.Duration
+	queued   int
+	// cancel is the completion that cancels the timer when it is closed
+	// while armed, and cancelID the callback of the cancellation. firing
+	// is set while the handler runs, closing once Close was called.
+	cancel   cxev.Completion
+	cancelID uintptr
+	firing   bool
+	closing  bool
 }
 
 // NewTimer creates a new timer.
@@ -80,7 +98,7 @@ type Timer struct {
 //
 // Returns an error if the timer cannot be initialized.
 func NewTimer() (*Timer, error) {
-	t := &Timer{}
+	t := &Timer{queued: -1}
 	if err := cxev.TimerInit(&t.watcher); err != nil {
 		return nil, err
 	}
@@ -93,9 +111,29 @@ func NewTimer() (*Timer, error) {
 // already fired. Close unregisters any pending callbacks and releases
 // the underlying watcher resources.
 //
+// A timer still armed is cancelled: its handler is not called again, and
+// the timer is released once the loop has run the cancellation, as libxev
+// holds on to the timer until then. Close may be called from the timer's
+// own handler.
+//
 // It is safe to call Close on a timer that has already fired or was never
-// scheduled.
+// scheduled, and to call it more than once.
 func (t *Timer) Close() {
+	if t.closing {
+		return
+	}
+	t.closing = true
+	if t.loop != nil {
+		t.loop.disarmTimer(t)
+	}
+	switch {
+	case t.firing:
+		// The callback releases the timer when the handler returns.
+		return
+	case t.callbackID != 0 && t.loop != nil && !t.loop.closed:
+		t.cancelID = cxev.TimerCancelWithCallback(&t.watcher, &t.loop.inner, &t.completion, &t.cancel, t.canceled)
+		return
+	}
 	if t.callbackID != 0 {
 		cxev.UnregisterCallback(t.callbackID)
 		t.callbackID = 0
@@ -118,7 +156,10 @@ func (t *Timer) RunWithHandler(loop *Loop, delay time.Duration, handler TimerHan
 	}
 	t.handler = handler
 	t.loop = loop
+	t.delay = delay
+	loop.armTimer(t, loop.Now()+delay)
 
+	t.span = loop.startOp("xev.timer", attribute.Int64("xev.timer.delay_ms", delay.Milliseconds()))
 	t.callbackID = cxev.TimerRunWithCallback(&t.watcher, &loop.inner, &t.completion, uint64(delay.Milliseconds()), t.callback)
 	return nil
 }
@@ -152,6 +193,58 @@ func (t *Timer) RunFunc(loop *Loop, delay time.Duration, fn func(t *Timer, resul
 	return t.RunWithHandler(loop, delay, TimerFunc(fn))
 }
 
+// RunAt schedules the timer to fire once at the time at, rather than after
+// a delay. The deadline is converted to the loop's monotonic clock when
+// RunAt is called, rounding up to the loop's millisecond resolution so the
+// timer never fires early; a deadline already past fires on the next
+// iteration.
+//
+// The timer fires once whatever the handler returns. A deadline carrying a
+// monotonic reading, such as one derived from [time.Now], is immune to
+// changes of the wall clock; one made with [time.Date] or parsed is not,
+// and is converted at the wall time of the call, so a later clock step
+// does not move it.
+//
+// Returns an error if handler is nil.
+func (t *Timer) RunAt(loop *Loop, at time.Time, handler TimerHandler) error {
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	// The loop measures delays from its cached time, which may be stale.
+	cxev.LoopUpdateNow(&loop.inner)
+	return t.RunWithHandler(loop, deadlineDelay(time.Until(at)), onceHandler{handler})
+}
+
+// RunAtFunc schedules the timer to fire once at the time at, with a
+// callback function.
+//
+// This is a convenience wrapper around [Timer.RunAt] for functional-style
+// callbacks.
+func (t *Timer) RunAtFunc(loop *Loop, at time.Time, fn func(t *Timer, result error) Action) error {
+	return t.RunAt(loop, at, TimerFunc(fn))
+}
+
+// deadlineDelay returns the timer delay for a deadline d from now, or zero
+// if it is past. The loop's clock counts whole milliseconds, truncating, so
+// it may be up to a millisecond behind: d is rounded up to a millisecond
+// and one more is added.
+func deadlineDelay(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return (d + 2*time.Millisecond - 1).Truncate(time.Millisecond)
+}
+
+// onceHandler fires a timer handler once, whatever it returns.
+type onceHandler struct {
+	TimerHandler
+}
+
+func (h onceHandler) OnTimer(t *Timer, result error) Action {
+	h.TimerHandler.OnTimer(t, result)
+	return Stop
+}
+
 // RunChan schedules the timer and returns a channel that receives the event.
 //
 // This method is useful for select-based patterns or integrating timers with
@@ -196,15 +289,87 @@ func (t *Timer) RunChan(loop *Loop, delay time.Duration) (<-chan TimerEvent, err
 }
 
 func (t *Timer) callback(loop *cxev.Loop, c *cxev.Completion, result int32, userdata uintptr) cxev.CbAction {
+	t.loop.disarmTimer(t)
+	if t.closing {
+		// The timer was closed while armed: this is the cancellation, or
+		// a fire that beat it, and the handler must not see either.
+		t.span = t.span.finish(0, result, Stop)
+		t.disarmed(userdata)
+		return cxev.Disarm
+	}
+
 	var err error
 	if result != 0 {
 		err = errors.New("timer error")
 	}
 
-	action := t.handler.OnTimer(t, err)
+	t.span.complete()
+	t.firing = true
+	action := t.onTimer(err)
+	t.firing = false
+	t.span = t.span.finish(0, result, action)
 
-	if action == Continue {
+	if action == Continue && !t.closing {
+		t.loop.armTimer(t, t.loop.Now()+t.delay)
 		return cxev.Rearm
 	}
+	t.disarmed(userdata)
+	return cxev.Disarm
+}
+
+// disarmed forgets the callback of a timer that fires no more. A closed
+// timer is released once its cancellation, if any, is done as well.
+func (t *Timer) disarmed(userdata uintptr) {
+	cxev.UnregisterCallback(userdata)
+	if t.callbackID == userdata {
+		t.callbackID = 0
+	}
+	if t.closing && t.cancelID == 0 {
+		cxev.TimerDeinit(&t.watcher)
+	}
+}
+
+// canceled runs when libxev is done with the cancellation started by
+// Close. Until then the registered callback keeps the timer, and so its
+// completions, reachable.
+func (t *Timer) canceled(_ *cxev.Loop, _ *cxev.Completion, _ int32, userdata uintptr) cxev.CbAction {
+	cxev.UnregisterCallback(userdata)
+	t.cancelID = 0
+	if t.callbackID == 0 {
+		cxev.TimerDeinit(&t.watcher)
+	}
 	return cxev.Disarm
 }
+
+// Scheduler runs callbacks on a clock. [*Loop] implements it with libxev
+// timers; xevtest.Loop implements it with simulated time, so timer-driven
+// helpers such as [IdleReaper] can be tested without the library.
+type Scheduler interface {
+	// Now returns the current time of the clock.
+	Now() time.Duration
+	// Schedule calls fn after delay, and again after each further delay
+	// while fn returns [Continue]. cancel stops it; it is safe to call
+	// after fn returned [Stop] and from fn itself.
+	Schedule(delay time.Duration, fn func() Action) (cancel func(), err error)
+}
+
+// Schedule implements [Scheduler] with a timer that is closed by cancel.
+// A cancel while the timer is armed cancels it through libxev, so the
+// loop still runs an iteration for it; see [Timer.Close].
+func (l *Loop) Schedule(delay time.Duration, fn func() Action) (func(), error) {
+	timer, err := NewTimer()
+	if err != nil {
+		return nil, err
+	}
+	err = timer.RunFunc(l, delay, func(_ *Timer, err error) Action {
+		if err != nil {
+			return Stop
+		}
+		return fn()
+	})
+	if err != nil {
+		timer.Close()
+		return nil, err
+	}
+	return timer.Close, nil
+}
@@ -0,0 +1,80 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"container/heap"
+	"time"
+)
+
+// NextTimerDeadline returns when the earliest armed [Timer] of the loop
+// fires, on the clock of [Loop.Now], and false if no timer is armed.
+//
+// It serves embedders that drive the loop with [Loop.Poll] from another
+// scheduler, such as a game or GUI main loop: until the deadline, timers
+// need no call into the loop, so the host may sleep for
+//
+//	if at, ok := loop.NextTimerDeadline(); ok {
+//	    sleep = min(sleep, max(at-loop.Now(), 0))
+//	}
+//
+// I/O completions are not timers and may become ready at any time; a host
+// waiting on sockets must also poll for them, or watch the loop's backend
+// descriptor. A repeating timer is rescheduled after its callback, so the
+// deadline reported during the callback is the next one of another timer.
+func (l *Loop) NextTimerDeadline() (time.Duration, bool) {
+	if len(l.timers) == 0 {
+		return 0, false
+	}
+	return l.timers[0].deadline, true
+}
+
+// armTimer records that t fires at deadline, replacing any deadline it
+// had.
+func (l *Loop) armTimer(t *Timer, deadline time.Duration) {
+	t.deadline = deadline
+	if t.queued >= 0 {
+		heap.Fix(&l.timers, t.queued)
+		return
+	}
+	heap.Push(&l.timers, t)
+}
+
+// disarmTimer forgets the deadline of t, if it is armed.
+func (l *Loop) disarmTimer(t *Timer) {
+	if t.queued >= 0 {
+		heap.Remove(&l.timers, t.queued)
+	}
+}
+
+// timerQueue orders the armed timers of a loop by deadline. Each timer
+// keeps its position in queued, or -1 when it is not in the queue.
+type timerQueue []*Timer
+
+func (q timerQueue) Len() int           { return len(q) }
+func (q timerQueue) Less(i, j int) bool { return q[i].deadline < q[j].deadline }
+
+func (q timerQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].queued = i
+	q[j].queued = j
+}
+
+func (q *timerQueue) Push(x any) {
+	t := x.(*Timer)
+	t.queued = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *timerQueue) Pop() any {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.queued = -1
+	*q = old[:len(old)-1]
+	return t
+}
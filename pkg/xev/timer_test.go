@@ -7,8 +7,11 @@
 package xev
 
 import (
+	"runtime"
 	"testing"
 	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
 )
 
 func TestTimerWithHandler(t *testing.T) {
@@ -104,3 +107,217 @@ func TestTimerChannel(t *testing.T) {
 		t.Error("timer event has wrong timer")
 	}
 }
+
+func TestTimerQueueOrder(t *testing.T) {
+	l := &Loop{}
+	if _, ok := l.NextTimerDeadline(); ok {
+		t.Fatal("empty loop reports a deadline")
+	}
+	timers := make([]*Timer, 4)
+	for i, at := range []time.Duration{30, 10, 20, 40} {
+		timers[i] = &Timer{queued: -1}
+		l.armTimer(timers[i], at*time.Millisecond)
+	}
+	next := func() time.Duration {
+		t.Helper()
+		at, ok := l.NextTimerDeadline()
+		if !ok {
+			t.Fatal("no deadline with armed timers")
+		}
+		return at
+	}
+	if got := next(); got != 10*time.Millisecond {
+		t.Fatalf("next deadline %v, want 10ms", got)
+	}
+	l.disarmTimer(timers[1])
+	l.disarmTimer(timers[1])
+	if got := next(); got != 20*time.Millisecond {
+		t.Fatalf("after disarming: next deadline %v, want 20ms", got)
+	}
+	// Rearming moves a timer rather than adding it twice.
+	l.armTimer(timers[3], 5*time.Millisecond)
+	if got := next(); got != 5*time.Millisecond || len(l.timers) != 3 {
+		t.Fatalf("after rearming: next deadline %v with %d timers", got, len(l.timers))
+	}
+	for _, tm := range timers {
+		l.disarmTimer(tm)
+	}
+	if _, ok := l.NextTimerDeadline(); ok {
+		t.Fatal("deadline left after disarming every timer")
+	}
+}
+
+func TestNextTimerDeadline(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	short, _ := NewTimer()
+	defer short.Close()
+	long, _ := NewTimer()
+	defer long.Close()
+	start := loop.Now()
+	ticks := 0
+	_ = long.RunFunc(loop, time.Second, func(*Timer, error) Action { return Stop })
+	_ = short.RunFunc(loop, 10*time.Millisecond, func(*Timer, error) Action {
+		ticks++
+		if ticks < 2 {
+			return Continue
+		}
+		return Stop
+	})
+
+	if at, ok := loop.NextTimerDeadline(); !ok || at != start+10*time.Millisecond {
+		t.Fatalf("NextTimerDeadline = %v, %v; want %v", at, ok, start+10*time.Millisecond)
+	}
+	if err := loop.RunOnce(); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if at, ok := loop.NextTimerDeadline(); !ok || at != loop.Now()+10*time.Millisecond {
+		t.Fatalf("after the first tick: NextTimerDeadline = %v, %v; want %v", at, ok, loop.Now()+10*time.Millisecond)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if at, ok := loop.NextTimerDeadline(); ok {
+		t.Fatalf("NextTimerDeadline = %v after every timer stopped", at)
+	}
+}
+
+func TestDeadlineDelay(t *testing.T) {
+	for _, c := range []struct {
+		in, want time.Duration
+	}{
+		{-time.Second, 0},
+		{0, 0},
+		{time.Microsecond, 2 * time.Millisecond},
+		{time.Millisecond, 2 * time.Millisecond},
+		{1500 * time.Microsecond, 3 * time.Millisecond},
+		{time.Second, time.Second + time.Millisecond},
+	} {
+		if got := deadlineDelay(c.in); got != c.want {
+			t.Errorf("deadlineDelay(%v) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTimerRunAt(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	timer, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer timer.Close()
+	past, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	defer past.Close()
+
+	at := time.Now().Add(30 * time.Millisecond)
+	var firedAt time.Time
+	fired := 0
+	// Continue does not re-arm a deadline timer.
+	if err := timer.RunAtFunc(loop, at, func(*Timer, error) Action {
+		firedAt = time.Now()
+		fired++
+		return Continue
+	}); err != nil {
+		t.Fatalf("RunAtFunc failed: %v", err)
+	}
+	pastFired := false
+	if err := past.RunAtFunc(loop, time.Now().Add(-time.Hour), func(*Timer, error) Action {
+		pastFired = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("RunAtFunc failed: %v", err)
+	}
+
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if fired != 1 || !pastFired {
+		t.Fatalf("deadline timer fired %d times, past one fired %v", fired, pastFired)
+	}
+	if firedAt.Before(at) {
+		t.Fatalf("timer fired %v before its deadline", at.Sub(firedAt))
+	}
+	if err := timer.RunAt(loop, at, nil); err == nil {
+		t.Fatal("RunAt accepted a nil handler")
+	}
+}
+
+func TestTimerCloseWhileArmed(t *testing.T) {
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	cancel, err := loop.Schedule(10*time.Millisecond, func() Action {
+		t.Error("cancelled schedule fired")
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	armed, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	if err := armed.RunFunc(loop, 10*time.Millisecond, func(*Timer, error) Action {
+		t.Error("closed timer fired")
+		return Continue
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+	ticks := 0
+	self, err := NewTimer()
+	if err != nil {
+		t.Fatalf("NewTimer failed: %v", err)
+	}
+	if err := self.RunFunc(loop, 5*time.Millisecond, func(tm *Timer, _ error) Action {
+		ticks++
+		if ticks == 2 {
+			tm.Close()
+		}
+		return Continue
+	}); err != nil {
+		t.Fatalf("RunFunc failed: %v", err)
+	}
+
+	cancel()
+	armed.Close()
+	armed.Close()
+	// Nothing but the pending cancellations holds the closed timers now;
+	// the loop must not touch collected memory while it runs them.
+	runtime.GC()
+
+	later := false
+	if _, err := loop.Schedule(30*time.Millisecond, func() Action {
+		runtime.GC()
+		later = true
+		return Stop
+	}); err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Loop.Run failed: %v", err)
+	}
+	if !later || ticks != 2 {
+		t.Fatalf("later timer fired %v, self-closing timer ticked %d times; want true, 2", later, ticks)
+	}
+	if _, ok := loop.NextTimerDeadline(); ok {
+		t.Fatal("closed timers left in the timer queue")
+	}
+}
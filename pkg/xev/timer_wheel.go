@@ -0,0 +1,317 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"time"
+)
+
+// Wheel geometry: wheelLevels levels of wheelSlots slots, each slot of a
+// level spanning a whole turn of the level below. Four levels of 64 slots
+// cover 2^24 ticks, over four hours at a millisecond tick; timeouts
+// further out wait in the last slot and are placed again as it comes up.
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+	wheelRange  = 1 << (wheelBits * wheelLevels)
+)
+
+// TimerWheel runs many timeouts, such as per-connection deadlines, on a
+// single loop timer.
+//
+// A [Timer] takes a libxev completion and calls into the library to arm,
+// fire and close it; with tens of thousands of connections each holding a
+// deadline, most of them reset long before they fire, that work dominates.
+// A TimerWheel keeps the timeouts in a hierarchical timing wheel in Go:
+// arming, resetting and stopping one is a few pointer updates, and the
+// wheel's own timer, armed only while a timeout is pending, fires once per
+// tick to expire those that are due.
+//
+// Timeouts have the resolution of the tick: one fires on the first tick at
+// or after its deadline, never before it. A coarser tick wakes the loop
+// less often; per-connection deadlines rarely need better than 10ms or
+// 100ms.
+//
+// Like [Loop], a TimerWheel is not thread-safe: all methods, and those of
+// its timers, must be called from the goroutine that runs the loop.
+//
+// # Example
+//
+//	wheel := xev.NewTimerWheel(loop, 10*time.Millisecond)
+//	defer wheel.Close()
+//
+//	// On accept:
+//	c.deadline, err = wheel.AfterFunc(30*time.Second, c.expire)
+//
+//	// On every read:
+//	c.deadline.Reset(30 * time.Second)
+type TimerWheel struct {
+	loop Scheduler
+	tick time.Duration
+	// start is the clock time of tick zero, and now the last tick
+	// processed.
+	start time.Duration
+	now   uint64
+	slots [wheelLevels][wheelSlots]wheelSlot
+	// pending is the number of timeouts armed.
+	pending int
+	// cancel stops the wheel's timer, which runs while running is set.
+	cancel  func()
+	running bool
+	// expired is reused to collect the timeouts of a tick.
+	expired []*WheelTimer
+	closed  bool
+}
+
+// wheelSlot holds the timeouts of a slot in a doubly linked list.
+type wheelSlot struct {
+	head *WheelTimer
+}
+
+// WheelTimer is a timeout of a [TimerWheel].
+type WheelTimer struct {
+	wheel *TimerWheel
+	fn    func()
+	// deadline is the tick the timeout is due.
+	deadline uint64
+	// pending is set while the timeout is armed. slot is the slot it is
+	// linked in, nil while it is being expired.
+	pending    bool
+	slot       *wheelSlot
+	prev, next *WheelTimer
+}
+
+// NewTimerWheel creates a timer wheel ticking every tick, at least a
+// millisecond, on loop. loop is usually a [*Loop]; tests may pass a
+// simulated scheduler.
+func NewTimerWheel(loop Scheduler, tick time.Duration) *TimerWheel {
+	return &TimerWheel{
+		loop:  loop,
+		tick:  max(tick, time.Millisecond),
+		start: loop.Now(),
+	}
+}
+
+// AfterFunc arms a timeout that calls fn on the loop goroutine once d has
+// elapsed, and returns it so it can be reset or stopped.
+//
+// Returns an error if fn is nil, the wheel is closed, or its timer cannot
+// be armed.
+func (w *TimerWheel) AfterFunc(d time.Duration, fn func()) (*WheelTimer, error) {
+	if fn == nil {
+		return nil, errors.New("fn cannot be nil")
+	}
+	t := &WheelTimer{wheel: w, fn: fn}
+	if err := w.arm(t, d); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Len returns the number of timeouts armed.
+func (w *TimerWheel) Len() int {
+	return w.pending
+}
+
+// Tick returns the resolution of the wheel.
+func (w *TimerWheel) Tick() time.Duration {
+	return w.tick
+}
+
+// Close stops the wheel's timer and drops its timeouts without calling
+// them, including those due in the tick being expired when a timeout's
+// function closes the wheel. Timeouts can no longer be armed on it. The
+// wheel's timer is cancelled through libxev, which the loop completes on
+// its next iteration.
+func (w *TimerWheel) Close() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.running = false
+	for lvl := range w.slots {
+		for i := range w.slots[lvl] {
+			for t := w.slots[lvl][i].head; t != nil; {
+				next := t.next
+				t.pending, t.slot, t.prev, t.next = false, nil, nil, nil
+				t = next
+			}
+			w.slots[lvl][i].head = nil
+		}
+	}
+	w.pending = 0
+}
+
+// Stop disarms the timeout. It returns true if the timeout was armed, and
+// false if it had already fired or been stopped.
+func (t *WheelTimer) Stop() bool {
+	if !t.pending {
+		return false
+	}
+	t.pending = false
+	if t.slot != nil {
+		t.unlink()
+	}
+	t.wheel.pending--
+	return true
+}
+
+// Reset arms the timeout again to fire once d has elapsed from now,
+// whether it is armed, fired or stopped. It may be called from the
+// timeout's own function.
+//
+// Returns an error if the wheel is closed or its timer cannot be armed.
+func (t *WheelTimer) Reset(d time.Duration) error {
+	t.Stop()
+	return t.wheel.arm(t, d)
+}
+
+// arm schedules t to fire d from now, starting the wheel's timer if it is
+// not running.
+func (w *TimerWheel) arm(t *WheelTimer, d time.Duration) error {
+	if w.closed {
+		return ErrClosed
+	}
+	now := w.elapsed()
+	if !w.running {
+		// The wheel is empty, so it can skip the ticks it slept through
+		// instead of turning through them.
+		if w.cancel != nil {
+			w.cancel()
+		}
+		cancel, err := w.loop.Schedule(w.tick, w.onTick)
+		if err != nil {
+			w.cancel = nil
+			return err
+		}
+		w.cancel, w.running = cancel, true
+		w.now = max(w.now, now)
+	}
+	// Round up, so the timeout never fires early, and count from the
+	// clock rather than from the last tick processed, which may lag.
+	ticks := uint64((max(d, 0) + w.tick - 1) / w.tick)
+	t.deadline = max(now+max(ticks, 1), w.now+1)
+	t.pending = true
+	w.pending++
+	w.place(t)
+	return nil
+}
+
+// elapsed returns the tick the clock is at.
+func (w *TimerWheel) elapsed() uint64 {
+	return uint64(max(w.loop.Now()-w.start, 0) / w.tick)
+}
+
+// place links t into the slot for its deadline: the finest level whose
+// turn, from the current tick, reaches it.
+func (w *TimerWheel) place(t *WheelTimer) {
+	deadline := t.deadline
+	delta := deadline - min(w.now, deadline)
+	if delta >= wheelRange {
+		// Beyond the wheel: wait in the last slot of the top level and
+		// be placed again when it comes up.
+		deadline = w.now + wheelRange - 1
+		delta = wheelRange - 1
+	}
+	lvl := 0
+	for delta >= 1<<(wheelBits*(lvl+1)) {
+		lvl++
+	}
+	s := &w.slots[lvl][(deadline>>(wheelBits*lvl))&wheelMask]
+	t.slot, t.prev, t.next = s, nil, s.head
+	if s.head != nil {
+		s.head.prev = t
+	}
+	s.head = t
+}
+
+func (t *WheelTimer) unlink() {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		t.slot.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.slot, t.prev, t.next = nil, nil, nil
+}
+
+// onTick runs on the wheel's timer. It keeps the timer running while
+// timeouts are armed.
+func (w *TimerWheel) onTick() Action {
+	w.advance(w.elapsed())
+	if w.pending == 0 || w.closed {
+		w.running = false
+		return Stop
+	}
+	return Continue
+}
+
+// advance turns the wheel to tick to, firing the timeouts due on the way.
+func (w *TimerWheel) advance(to uint64) {
+	for w.now < to && !w.closed {
+		w.now++
+		// When a level completes a turn, the next slot of the level above
+		// is spread over it.
+		for lvl := 1; lvl < wheelLevels; lvl++ {
+			if w.now&(1<<(wheelBits*lvl)-1) != 0 {
+				break
+			}
+			w.cascade(&w.slots[lvl][(w.now>>(wheelBits*lvl))&wheelMask])
+		}
+		w.expire(&w.slots[0][w.now&wheelMask])
+	}
+}
+
+// cascade places the timeouts of s again, in finer slots.
+func (w *TimerWheel) cascade(s *wheelSlot) {
+	t := s.head
+	s.head = nil
+	for t != nil {
+		next := t.next
+		w.place(t)
+		t = next
+	}
+}
+
+// expire fires the timeouts of s. They are taken off the slot first, so
+// that their functions may stop, reset or arm any timeout, including the
+// others due, or close the wheel, which drops those not yet fired.
+func (w *TimerWheel) expire(s *wheelSlot) {
+	expired := w.expired[:0]
+	for t := s.head; t != nil; {
+		next := t.next
+		t.slot, t.prev, t.next = nil, nil, nil
+		expired = append(expired, t)
+		t = next
+	}
+	s.head = nil
+	for i, t := range expired {
+		expired[i] = nil
+		if w.closed {
+			// Close counted t out of pending along with the rest.
+			t.pending = false
+			continue
+		}
+		// A function that ran before may have stopped or reset t.
+		if !t.pending || t.slot != nil {
+			continue
+		}
+		t.pending = false
+		w.pending--
+		t.fn()
+	}
+	w.expired = expired[:0]
+}
@@ -0,0 +1,241 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+	"github.com/crrow/libxev-go/pkg/xev"
+	"github.com/crrow/libxev-go/pkg/xevtest"
+)
+
+// The wheel's geometry: four levels of 64 slots.
+const (
+	wheelSlots = 64
+	wheelRange = 1 << 24
+)
+
+func TestTimerWheelFiresOnTimeAtEveryLevel(t *testing.T) {
+	clock := xevtest.NewLoop()
+	w := xev.NewTimerWheel(clock, time.Millisecond)
+
+	// Deadlines on every level of the wheel, and beyond its range.
+	rng := rand.New(rand.NewPCG(1, 2))
+	type due struct {
+		at    time.Duration
+		fired time.Duration
+	}
+	var timeouts []*due
+	for _, limit := range []int64{wheelSlots, wheelSlots * wheelSlots, 1 << 20, wheelRange + 5000} {
+		for range 50 {
+			d := &due{at: time.Duration(rng.Int64N(limit)+1) * time.Millisecond, fired: -1}
+			timeouts = append(timeouts, d)
+			if _, err := w.AfterFunc(d.at, func() { d.fired = clock.Now() }); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if w.Len() != len(timeouts) || clock.Pending() != 1 {
+		t.Fatalf("Len = %d, timers scheduled = %d", w.Len(), clock.Pending())
+	}
+
+	clock.Advance(time.Duration(wheelRange+6000) * time.Millisecond)
+	for _, d := range timeouts {
+		if d.fired != d.at {
+			t.Errorf("timeout for %v fired at %v", d.at, d.fired)
+		}
+	}
+	if w.Len() != 0 || clock.Pending() != 0 {
+		t.Fatalf("Len = %d after every timeout fired; wheel timer running: %v", w.Len(), clock.Pending() != 0)
+	}
+}
+
+func TestTimerWheelStopAndReset(t *testing.T) {
+	clock := xevtest.NewLoop()
+	w := xev.NewTimerWheel(clock, 10*time.Millisecond)
+
+	var fired []string
+	a, _ := w.AfterFunc(50*time.Millisecond, func() { fired = append(fired, "a") })
+	b, _ := w.AfterFunc(100*time.Millisecond, func() { fired = append(fired, "b") })
+	var c *xev.WheelTimer
+	c, _ = w.AfterFunc(100*time.Millisecond, func() {
+		fired = append(fired, "c")
+		// Due in the same tick, but stopped first.
+		b.Stop()
+		// Rearm itself, as a repeating timeout.
+		if len(fired) < 4 {
+			_ = c.Reset(100 * time.Millisecond)
+		}
+	})
+	_, _ = w.AfterFunc(5*time.Millisecond, func() { fired = append(fired, "short") })
+
+	if !a.Stop() || a.Stop() {
+		t.Fatal("Stop of an armed timeout must report it once")
+	}
+	clock.Advance(60 * time.Millisecond)
+	if err := a.Reset(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// A deadline is rounded up to the tick, never down: short, armed for
+	// 5ms, fires on the first tick, at 10ms.
+	clock.Advance(30 * time.Millisecond)
+	if want := []string{"short", "a"}; !slices.Equal(fired, want) {
+		t.Fatalf("fired %v, want %v", fired, want)
+	}
+	clock.Advance(200 * time.Millisecond)
+	if want := []string{"short", "a", "c", "c"}; !slices.Equal(fired, want) {
+		t.Fatalf("fired %v, want %v", fired, want)
+	}
+	if b.Stop() {
+		t.Fatal("b was stopped by c, yet Stop reports it armed")
+	}
+}
+
+func TestTimerWheelIdlesAndCloses(t *testing.T) {
+	clock := xevtest.NewLoop()
+	w := xev.NewTimerWheel(clock, time.Millisecond)
+
+	fired := 0
+	_, _ = w.AfterFunc(time.Millisecond, func() { fired++ })
+	clock.Advance(5 * time.Millisecond)
+	if fired != 1 || clock.Pending() != 0 {
+		t.Fatalf("fired %d; wheel timer running when idle: %v", fired, clock.Pending() != 0)
+	}
+
+	// After a long idle spell the wheel picks up at the current time.
+	clock.Advance(time.Hour)
+	_, _ = w.AfterFunc(2*time.Millisecond, func() { fired++ })
+	if clock.Pending() != 1 {
+		t.Fatalf("timers scheduled = %d, want 1", clock.Pending())
+	}
+	clock.Advance(2 * time.Millisecond)
+	if fired != 2 {
+		t.Fatalf("fired %d, want 2", fired)
+	}
+
+	_, _ = w.AfterFunc(time.Millisecond, func() { fired++ })
+	w.Close()
+	clock.Advance(time.Second)
+	if fired != 2 || w.Len() != 0 {
+		t.Fatalf("fired %d, Len %d after Close", fired, w.Len())
+	}
+	if _, err := w.AfterFunc(time.Millisecond, func() {}); err != xev.ErrClosed {
+		t.Fatalf("AfterFunc on a closed wheel: %v", err)
+	}
+}
+
+func TestTimerWheelClosedByATimeout(t *testing.T) {
+	clock := xevtest.NewLoop()
+	w := xev.NewTimerWheel(clock, 10*time.Millisecond)
+
+	// The three are due in the same tick; whichever fires first closes
+	// the wheel, and the others must not fire after it.
+	fired := 0
+	for range 3 {
+		if _, err := w.AfterFunc(10*time.Millisecond, func() {
+			fired++
+			w.Close()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	later, _ := w.AfterFunc(time.Second, func() { fired++ })
+	clock.Advance(2 * time.Second)
+	if fired != 1 || w.Len() != 0 || clock.Pending() != 0 {
+		t.Fatalf("fired %d, Len %d, wheel timer running %v after Close", fired, w.Len(), clock.Pending() != 0)
+	}
+	if later.Stop() {
+		t.Fatal("Stop reports a dropped timeout armed")
+	}
+}
+
+// BenchmarkTimeouts arms a deadline per connection on a real loop and
+// resets it, as on every read, with a Timer each and with a TimerWheel,
+// running the loop after each round. It reports the timer operations
+// submitted to libxev per reset: a Timer cancels its armed timer and arms
+// a new one, while the wheel's single timer keeps ticking untouched.
+func BenchmarkTimeouts(b *testing.B) {
+	const conns = 10000
+	run := func(b *testing.B, reset func(loop *xev.Loop) func(i int)) {
+		loop, err := xev.NewLoop()
+		if err != nil {
+			b.Skip(err)
+		}
+		defer loop.Close()
+		resetConn := reset(loop)
+		for i := range conns {
+			resetConn(i)
+		}
+		if err := loop.Poll(); err != nil {
+			b.Fatal(err)
+		}
+		ops := cxev.TotalCallbacks(cxev.KindTimer)
+		b.ResetTimer()
+		for range b.N {
+			for i := range conns {
+				resetConn(i)
+			}
+			if err := loop.Poll(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(float64(cxev.TotalCallbacks(cxev.KindTimer)-ops)/float64(b.N*conns), "xev-ops/reset")
+	}
+	b.Run("timers", func(b *testing.B) {
+		timers := make([]*xev.Timer, conns)
+		defer func() {
+			for _, t := range timers {
+				if t != nil {
+					t.Close()
+				}
+			}
+		}()
+		expire := func(*xev.Timer, error) xev.Action { return xev.Stop }
+		run(b, func(loop *xev.Loop) func(int) {
+			return func(i int) {
+				// An armed timer is reset by replacing it.
+				if timers[i] != nil {
+					timers[i].Close()
+				}
+				t, err := xev.NewTimer()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := t.RunFunc(loop, time.Minute, expire); err != nil {
+					b.Fatal(err)
+				}
+				timers[i] = t
+			}
+		})
+	})
+	b.Run("wheel", func(b *testing.B) {
+		timeouts := make([]*xev.WheelTimer, conns)
+		var w *xev.TimerWheel
+		defer func() {
+			if w != nil {
+				w.Close()
+			}
+		}()
+		run(b, func(loop *xev.Loop) func(int) {
+			w = xev.NewTimerWheel(loop, 10*time.Millisecond)
+			return func(i int) {
+				if timeouts[i] == nil {
+					timeouts[i], _ = w.AfterFunc(time.Minute, func() {})
+					return
+				}
+				if err := timeouts[i].Reset(time.Minute); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope reported on every span.
+const tracerName = "github.com/crrow/libxev-go/pkg/xev"
+
+// Span attribute keys recorded on every completed operation.
+const (
+	AttrBytes        = attribute.Key("xev.bytes")
+	AttrErrno        = attribute.Key("xev.errno")
+	AttrQueueLatency = attribute.Key("xev.queue_latency_us")
+)
+
+// WithTracerProvider enables OpenTelemetry tracing of async operations.
+//
+// When set, every operation submitted to the loop (timer, accept, connect,
+// read, write, close) starts a span at submission time and ends it when the
+// operation completes, before its handler runs. Completed spans carry the number of bytes
+// transferred ([AttrBytes]), the raw error code reported by libxev
+// ([AttrErrno], zero on success) and the time the operation spent between
+// submission and completion ([AttrQueueLatency]).
+//
+// Operations that are re-armed by returning [Continue] end their span on each
+// completion and start a fresh one for the next submission.
+//
+// A nil provider leaves tracing disabled, which is also the default.
+func WithTracerProvider(tp trace.TracerProvider) LoopOption {
+	return func(c *loopConfig) {
+		c.tracerProvider = tp
+	}
+}
+
+// opSpan tracks the span of a single in-flight operation.
+// A nil *opSpan is valid and means tracing is disabled.
+type opSpan struct {
+	loop      *Loop
+	name      string
+	attrs     []attribute.KeyValue
+	span      trace.Span
+	submitted time.Time
+	// completed is when the completion was delivered, recorded by complete
+	// before the handler runs.
+	completed time.Time
+}
+
+// startOp starts a span for an operation that is about to be submitted.
+// It returns nil when the loop has no tracer configured. Since timers and
+// I/O operations start here, it also submits the queued ones once the submit
+// threshold is reached.
+func (l *Loop) startOp(name string, attrs ...attribute.KeyValue) *opSpan {
+	if l == nil {
+		return nil
+	}
+	if l.submitThreshold > 0 {
+		l.submitQueued()
+	}
+	if l.tracer == nil {
+		return nil
+	}
+	// Completions run on the loop goroutine with no caller context, so
+	// every operation span is a root span.
+	_, span := l.tracer.Start(context.Background(), name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	return &opSpan{loop: l, name: name, attrs: attrs, span: span, submitted: time.Now()}
+}
+
+// complete records that the operation completed. Callbacks call it before
+// running the handler, so that neither the queue latency nor the span
+// takes in the time the handler spends. The first call counts: a
+// completion carried over to a later iteration keeps its time.
+func (s *opSpan) complete() {
+	if s != nil && s.completed.IsZero() {
+		s.completed = time.Now()
+	}
+}
+
+// end records the operation result and ends the span at the completion
+// time, or now if complete was not called.
+func (s *opSpan) end(bytes int, errCode int32) {
+	if s == nil {
+		return
+	}
+	at := s.completed
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s.span.SetAttributes(
+		AttrBytes.Int(bytes),
+		AttrErrno.Int(int(errCode)),
+		AttrQueueLatency.Int64(at.Sub(s.submitted).Microseconds()),
+	)
+	if errCode != 0 {
+		s.span.SetStatus(codes.Error, "operation failed")
+	}
+	s.span.End(trace.WithTimestamp(at))
+}
+
+// finish ends the span and, if the operation was re-armed, returns a new
+// span for its next completion.
+func (s *opSpan) finish(bytes int, errCode int32, action Action) *opSpan {
+	if s == nil {
+		return nil
+	}
+	s.end(bytes, errCode)
+	if action != Continue {
+		return nil
+	}
+	return s.loop.startOp(s.name, s.attrs...)
+}
+
+// settle is finish for a connection whose callback may have started
+// another operation, whose span cur then is: it returns the span the
+// connection tracks next.
+func (s *opSpan) settle(cur *opSpan, bytes int, errCode int32, action Action) *opSpan {
+	next := s.finish(bytes, errCode, action)
+	if action != Continue && cur != s {
+		return cur
+	}
+	return next
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttr(t *testing.T, attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	t.Helper()
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	t.Fatalf("attribute %s not recorded", key)
+	return attribute.Value{}
+}
+
+func TestOpSpanLifecycle(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	l := &Loop{}
+	l.applyOptions([]LoopOption{WithTracerProvider(tp)})
+
+	span := l.startOp("xev.tcp.read")
+	if span == nil {
+		t.Fatal("expected span when tracer provider is configured")
+	}
+
+	// A re-armed operation ends its span and starts the next one.
+	span = span.finish(5, 0, Continue)
+	if span == nil {
+		t.Fatal("expected new span after Continue")
+	}
+	if span = span.finish(0, 3, Stop); span != nil {
+		t.Fatal("expected no span after Stop")
+	}
+
+	ended := rec.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(ended))
+	}
+	for _, s := range ended {
+		if s.Name() != "xev.tcp.read" {
+			t.Errorf("span name = %q, want xev.tcp.read", s.Name())
+		}
+		spanAttr(t, s.Attributes(), AttrQueueLatency)
+	}
+
+	if got := spanAttr(t, ended[0].Attributes(), AttrBytes).AsInt64(); got != 5 {
+		t.Errorf("bytes = %d, want 5", got)
+	}
+	if ended[0].Status().Code == codes.Error {
+		t.Error("successful op should not have error status")
+	}
+	if got := spanAttr(t, ended[1].Attributes(), AttrErrno).AsInt64(); got != 3 {
+		t.Errorf("errno = %d, want 3", got)
+	}
+	if ended[1].Status().Code != codes.Error {
+		t.Error("failed op should have error status")
+	}
+}
+
+func TestOpSpanExcludesHandler(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	l := &Loop{}
+	l.applyOptions([]LoopOption{WithTracerProvider(tp)})
+
+	span := l.startOp("xev.tcp.read")
+	span.complete()
+	// The handler runs between the completion and the end of the span.
+	const handler = 50 * time.Millisecond
+	time.Sleep(handler)
+	span.complete()
+	span.finish(1, 0, Stop)
+
+	s := rec.Ended()[0]
+	if got := time.Duration(spanAttr(t, s.Attributes(), AttrQueueLatency).AsInt64()) * time.Microsecond; got >= handler {
+		t.Errorf("queue latency %v includes the handler", got)
+	}
+	if got := s.EndTime().Sub(s.StartTime()); got >= handler {
+		t.Errorf("span lasted %v, including the handler", got)
+	}
+}
+
+func TestOpSpanDisabled(t *testing.T) {
+	l := &Loop{}
+	l.applyOptions(nil)
+
+	span := l.startOp("xev.timer")
+	if span != nil {
+		t.Fatal("expected nil span without tracer provider")
+	}
+	// finish must be safe on a nil span.
+	if span.finish(0, 0, Continue) != nil {
+		t.Fatal("expected nil span after finish on disabled tracing")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"net"
+)
+
+// A connection can be backed by a Go transport instead of a socket. Its
+// handlers are called exactly as for a socket, so application code written
+// against [TCPConn] and [UDPConn] runs unchanged over in-memory transports
+// such as those of package xevtest, without the extended library. The loop
+// passed to the methods of such a connection is only used for [Stats] and
+// may be nil; tracing is not available.
+
+var (
+	errTransportConnect      = errors.New("connect is not supported on a transport connection")
+	errTransportWaitWritable = errors.New("waiting for writability is not supported on a transport connection")
+)
+
+// Transport carries the I/O of a [TCPConn] created by [NewTransportConn].
+// Each method starts an operation and calls done when it completes. done
+// must not be called before the method has returned, and must be called on
+// the goroutine that drives the connection.
+type Transport interface {
+	Read(buf []byte, done func(n int, err error))
+	Write(data []byte, done func(n int, err error))
+	Close(done func(err error))
+}
+
+// PacketTransport carries the I/O of a [UDPConn] created by
+// [NewPacketTransportConn], with the same rules as [Transport].
+type PacketTransport interface {
+	ReadFrom(buf []byte, done func(n int, from *net.UDPAddr, err error))
+	WriteTo(data []byte, to *net.UDPAddr, done func(n int, err error))
+	Close(done func(err error))
+	// LocalAddr is the address datagrams sent through the transport come
+	// from.
+	LocalAddr() *net.UDPAddr
+}
+
+// NewTransportConn returns a connection whose reads, writes and close go
+// to t. It is already connected, so [TCPConn.Connect] returns an error.
+func NewTransportConn(t Transport) *TCPConn {
+	return &TCPConn{transport: t, fd: -1}
+}
+
+// NewPacketTransportConn returns a bound UDP socket whose datagrams go
+// through t.
+func NewPacketTransportConn(t PacketTransport) *UDPConn {
+	return &UDPConn{transport: t}
+}
+
+// errCode maps a transport error onto the error code stats expect.
+func errCode(err error) int32 {
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+func (c *TCPConn) transportRead() {
+	buf := c.readBuf
+	c.transport.Read(buf, func(n int, err error) {
+		countIn(&c.stats, c.loop, int32(n), errCode(err))
+		if c.onRead(buf[:n], err) == Continue {
+			c.transportRead()
+		}
+	})
+}
+
+func (c *TCPConn) transportWrite(data []byte) {
+	c.transport.Write(data, func(n int, err error) {
+		countOut(&c.stats, c.loop, int32(n), errCode(err))
+		if c.onWrite(n, err) == Continue {
+			c.transportWrite(data)
+		}
+	})
+}
+
+func (c *TCPConn) transportClose() {
+	c.transport.Close(func(err error) {
+		if c.closeHandler != nil {
+			c.onClosed(err)
+		}
+	})
+}
+
+func (c *UDPConn) transportRead() {
+	buf := c.readBuf
+	c.transport.ReadFrom(buf, func(n int, from *net.UDPAddr, err error) {
+		countIn(&c.stats, c.loop, int32(n), errCode(err))
+		if c.onRead(buf[:n], from, err) == Continue {
+			c.rotated(n)
+			c.transportRead()
+		}
+	})
+}
+
+func (c *UDPConn) transportWrite(data []byte, to *net.UDPAddr) {
+	c.transport.WriteTo(data, to, func(n int, err error) {
+		countOut(&c.stats, c.loop, int32(n), errCode(err))
+		if c.onWrite(n, err) == Continue {
+			c.transportWrite(data, to)
+		}
+	})
+}
+
+func (c *UDPConn) transportClose() {
+	c.transport.Close(func(err error) {
+		if c.closeHandler != nil {
+			c.onClosed(err)
+		}
+	})
+}
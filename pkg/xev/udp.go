@@ -9,10 +9,16 @@ package xev
 import (
 	"errors"
 	"net"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/crrow/libxev-go/pkg/cxev"
 )
 
+// udpConnOwner names UDPConn in re-entrancy assertions.
+const udpConnOwner = "UDPConn"
+
 func unregisterUDPCallback(id uintptr, callbackID *uintptr) {
 	if id == 0 {
 		return
@@ -74,18 +80,52 @@ func unregisterUDPCallback(id uintptr, callbackID *uintptr) {
 //	conn.WriteToAddrFunc(loop, []byte("Reply"), remoteAddr, func(c *xev.UDPConn, n int, err error) xev.Action {
 //	    return xev.Stop
 //	})
+//
+// # Ancillary Data
+//
+// [UDPConn.ReadMsgFrom] also delivers the control message of each
+// datagram, such as its kernel receive time or the address it was sent
+// to, once enabled with [UDPConn.SetControlMessage]. [UDPConn.WriteMsgTo]
+// sends with a control message, so a server bound to a wildcard address
+// can reply from the address a request arrived on.
 type UDPConn struct {
 	udp        cxev.UDP
 	completion cxev.UDPCompletion
 	state      cxev.UDPState
 	addr       cxev.Sockaddr
 	readBuf    []byte
+	// readBufs is the pool of a rotating read, nil for other reads.
+	readBufs   *DatagramBuffers
 	callbackID uintptr
 	loop       *Loop
+	span       *opSpan
 
 	readHandler  UDPReadHandler
 	writeHandler UDPWriteHandler
 	closeHandler UDPCloseHandler
+	// readDone is readCallback bound once; see TCPConn.
+	readDone cxev.UDPReadCallback
+	// peer is the address last passed to the read handler, and peerRaw
+	// the sockaddr it was decoded from.
+	peer    *net.UDPAddr
+	peerRaw cxev.Sockaddr
+
+	// control receives the ancillary data of message reads, and cm
+	// carries it to the handler. writeControl holds the encoded
+	// ancillary data of a message write until it completes.
+	control      []byte
+	cm           *ControlMessage
+	writeControl []byte
+
+	stats Stats
+
+	transport PacketTransport
+
+	// ops tracks the operation on completion; see reentrancy.go.
+	ops opGuard
+
+	// closed is set by Close; see ErrClosed.
+	closed atomic.Bool
 }
 
 // UDPReadHandler handles received UDP datagrams.
@@ -95,7 +135,9 @@ type UDPConn struct {
 type UDPReadHandler interface {
 	// OnRead is called when a datagram is received.
 	// data contains the datagram payload.
-	// remoteAddr is the sender's address (may be nil on error).
+	// remoteAddr is the sender's address (may be nil on error). Datagrams
+	// from the sender of the previous one share its address, which must
+	// not be modified.
 	// Return [Continue] to keep receiving, or [Stop] to stop.
 	OnRead(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, err error) Action
 }
@@ -213,6 +255,9 @@ func NewUDPConn() (*UDPConn, error) {
 // This is typically used after [NewUDPConn] to bind the socket before
 // receiving datagrams. Use "0.0.0.0:0" to let the OS assign an address.
 func (c *UDPConn) Bind(address string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
 	host, port, err := parseAddress(address)
 	if err != nil {
 		return err
@@ -225,6 +270,10 @@ func (c *UDPConn) Bind(address string) error {
 // LocalAddr returns the local address the socket is bound to.
 // Returns the host (always "0.0.0.0" currently) and port number.
 func (c *UDPConn) LocalAddr() (string, uint16) {
+	if c.transport != nil {
+		addr := c.transport.LocalAddr()
+		return addr.IP.String(), uint16(addr.Port)
+	}
 	var addr cxev.Sockaddr
 	cxev.UDPGetsockname(&c.udp, &addr)
 	port := cxev.SockaddrPort(&addr)
@@ -238,16 +287,34 @@ func (c *UDPConn) LocalAddr() (string, uint16) {
 // to send a reply.
 //
 // Return [Continue] from the handler to keep receiving, or [Stop] to stop.
+// With Continue, the next datagram overwrites buf; use
+// [UDPConn.ReadFromRotating] if the handler keeps data beyond its call.
 func (c *UDPConn) ReadFrom(loop *Loop, buf []byte, handler UDPReadHandler) error {
 	if len(buf) == 0 {
 		return ErrEmptyBuffer
 	}
+	return c.startRead(loop, buf, nil, handler)
+}
 
+func (c *UDPConn) startRead(loop *Loop, buf []byte, bufs *DatagramBuffers, handler UDPReadHandler) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
 	c.loop = loop
 	c.readHandler = handler
 	c.readBuf = buf
+	c.readBufs = bufs
 
-	c.callbackID = cxev.UDPReadWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, buf, c.readCallback)
+	if c.transport != nil {
+		c.transportRead()
+		return nil
+	}
+	if c.readDone == nil {
+		c.readDone = c.readCallback
+	}
+	c.ops.submit(udpConnOwner, "read")
+	c.span = loop.startOp("xev.udp.read")
+	c.callbackID = cxev.UDPReadWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, buf, c.readDone)
 	return nil
 }
 
@@ -267,14 +334,27 @@ func (c *UDPConn) readCallback(loop *cxev.Loop, comp *cxev.UDPCompletion, remote
 
 	var addr *net.UDPAddr
 	if remoteAddr != nil {
-		addr = sockaddrToUDPAddr(remoteAddr)
+		addr = c.peerAddr(remoteAddr)
 	}
 
-	action := c.readHandler.OnRead(c, data, addr, err)
+	countIn(&c.stats, c.loop, bytesRead, errCode)
+	c.loop.spend()
+	span := c.span
+	span.complete()
+	c.ops.dispatch()
+	action := c.ops.finish(udpConnOwner, c.onRead(data, addr, err))
+	c.span = span.settle(c.span, int(bytesRead), errCode, action)
 	if action == Continue {
-		return cxev.Rearm
+		if !c.rotated(int(bytesRead)) {
+			return cxev.Rearm
+		}
+		// The completion is re-armed with the fresh buffer by a new read.
+		c.callbackID = cxev.UDPReadWithCallback(&c.udp, &c.loop.inner, &c.completion, &c.state, c.readBuf, c.readDone)
+		unregisterUDPCallback(userdata, &c.callbackID)
+		return cxev.Disarm
 	}
 	unregisterUDPCallback(userdata, &c.callbackID)
+	c.ops.runDeferred()
 	return cxev.Disarm
 }
 
@@ -285,10 +365,21 @@ func (c *UDPConn) WriteTo(loop *Loop, data []byte, address string, handler UDPWr
 	if len(data) == 0 {
 		return ErrEmptyBuffer
 	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
 
 	c.loop = loop
 	c.writeHandler = handler
 
+	if c.transport != nil {
+		to, err := net.ResolveUDPAddr("udp", address)
+		if err != nil {
+			return err
+		}
+		c.transportWrite(data, to)
+		return nil
+	}
 	host, port, err := parseAddress(address)
 	if err != nil {
 		return err
@@ -297,6 +388,8 @@ func (c *UDPConn) WriteTo(loop *Loop, data []byte, address string, handler UDPWr
 	var addr cxev.Sockaddr
 	cxev.SockaddrIPv4(&addr, host[0], host[1], host[2], host[3], port)
 
+	c.ops.submit(udpConnOwner, "write")
+	c.span = loop.startOp("xev.udp.write", attribute.String("net.peer.address", address))
 	c.callbackID = cxev.UDPWriteWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, &addr, data, c.writeCallback)
 	return nil
 }
@@ -320,10 +413,17 @@ func (c *UDPConn) WriteToAddr(loop *Loop, data []byte, addr *net.UDPAddr, handle
 	if len(data) == 0 {
 		return ErrEmptyBuffer
 	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
 
 	c.loop = loop
 	c.writeHandler = handler
 
+	if c.transport != nil {
+		c.transportWrite(data, addr)
+		return nil
+	}
 	ip4 := addr.IP.To4()
 	if ip4 == nil {
 		return errors.New("IPv6 not yet supported")
@@ -332,6 +432,8 @@ func (c *UDPConn) WriteToAddr(loop *Loop, data []byte, addr *net.UDPAddr, handle
 	var sockaddr cxev.Sockaddr
 	cxev.SockaddrIPv4(&sockaddr, ip4[0], ip4[1], ip4[2], ip4[3], uint16(addr.Port))
 
+	c.ops.submit(udpConnOwner, "write")
+	c.span = loop.startOp("xev.udp.write", attribute.String("net.peer.address", addr.String()))
 	c.callbackID = cxev.UDPWriteWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, &sockaddr, data, c.writeCallback)
 	return nil
 }
@@ -350,11 +452,17 @@ func (c *UDPConn) writeCallback(loop *cxev.Loop, comp *cxev.UDPCompletion, bytes
 		err = errors.New("write error")
 	}
 
-	action := c.writeHandler.OnWrite(c, int(bytesWritten), err)
+	countOut(&c.stats, c.loop, bytesWritten, errCode)
+	span := c.span
+	span.complete()
+	c.ops.dispatch()
+	action := c.ops.finish(udpConnOwner, c.onWrite(int(bytesWritten), err))
+	c.span = span.settle(c.span, int(bytesWritten), errCode, action)
 	if action == Continue {
 		return cxev.Rearm
 	}
 	unregisterUDPCallback(userdata, &c.callbackID)
+	c.ops.runDeferred()
 	return cxev.Disarm
 }
 
@@ -362,17 +470,29 @@ func (c *UDPConn) writeCallback(loop *cxev.Loop, comp *cxev.UDPCompletion, bytes
 //
 // The handler (if non-nil) is called when the close completes. After close
 // completes, call [UDPConn.Cleanup] to release callback resources.
+// Operations started after Close return [ErrClosed], as for [TCPConn.Close].
 func (c *UDPConn) Close(loop *Loop, handler UDPCloseHandler) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
 	c.loop = loop
 	c.closeHandler = handler
 
+	if c.transport != nil {
+		c.transportClose()
+		return nil
+	}
+	c.ops.submit(udpConnOwner, "close")
+	c.span = loop.startOp("xev.udp.close")
 	c.callbackID = cxev.UDPCloseWithCallback(&c.udp, &loop.inner, &c.completion, func(loop *cxev.Loop, comp *cxev.UDPCompletion, result int32, userdata uintptr) cxev.CbAction {
 		var err error
 		if result != 0 {
 			err = errors.New("close error")
 		}
+		c.ops.closed()
+		c.span = c.span.finish(0, result, Stop)
 		if c.closeHandler != nil {
-			c.closeHandler.OnClose(c, err)
+			c.onClosed(err)
 		}
 		unregisterUDPCallback(userdata, &c.callbackID)
 		return cxev.Disarm
@@ -390,6 +510,9 @@ func (c *UDPConn) CloseFunc(loop *Loop, fn func(conn *UDPConn, err error)) error
 
 // Fd returns the underlying file descriptor.
 func (c *UDPConn) Fd() int32 {
+	if c.transport != nil {
+		return -1
+	}
 	return cxev.UDPFd(&c.udp)
 }
 
@@ -404,6 +527,17 @@ func (c *UDPConn) Cleanup() {
 	}
 }
 
+// peerAddr returns the address of raw, reusing the last one decoded if
+// the sender is the same, so a steady stream of datagrams does not
+// allocate.
+func (c *UDPConn) peerAddr(raw *cxev.Sockaddr) *net.UDPAddr {
+	if c.peer == nil || *raw != c.peerRaw {
+		c.peer = sockaddrToUDPAddr(raw)
+		c.peerRaw = *raw
+	}
+	return c.peer
+}
+
 // sockaddrToUDPAddr converts a cxev.Sockaddr to [net.UDPAddr].
 //
 // The sockaddr is expected to be in BSD format:
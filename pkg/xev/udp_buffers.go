@@ -0,0 +1,96 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"sync"
+)
+
+// A read that returns [Continue] receives the next datagram into the same
+// buffer, so a handler that keeps the data slice (queues it, hands it to
+// another goroutine) sees it overwritten by the next datagram. Rotating
+// reads give each datagram its own buffer from a [DatagramBuffers] pool
+// instead; the handler owns it until it calls [DatagramBuffers.Release].
+
+// DatagramBuffers is a pool of equally sized receive buffers for
+// [UDPConn.ReadFromRotating]. Its methods may be called from any goroutine.
+type DatagramBuffers struct {
+	size int
+
+	mu   sync.Mutex
+	free [][]byte
+}
+
+// NewDatagramBuffers returns a pool of buffers of size bytes. Datagrams
+// longer than size are truncated.
+func NewDatagramBuffers(size int) *DatagramBuffers {
+	return &DatagramBuffers{size: size}
+}
+
+// Size returns the size of the buffers.
+func (p *DatagramBuffers) Size() int {
+	return p.size
+}
+
+// Release returns the buffer of a datagram delivered by a rotating read to
+// the pool. data must be the slice passed to the handler, or a reslice of
+// it starting at its first byte; data must not be used afterwards. Slices
+// that did not come from the pool are ignored.
+func (p *DatagramBuffers) Release(data []byte) {
+	if cap(data) != p.size {
+		return
+	}
+	p.mu.Lock()
+	p.free = append(p.free, data[:p.size])
+	p.mu.Unlock()
+}
+
+func (p *DatagramBuffers) get() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.free); n > 0 {
+		buf := p.free[n-1]
+		p.free[n-1] = nil
+		p.free = p.free[:n-1]
+		return buf
+	}
+	return make([]byte, p.size)
+}
+
+// ReadFromRotating starts receiving datagrams like [UDPConn.ReadFrom], but
+// each non-empty datagram is delivered in a buffer taken from bufs, which
+// then belongs to the handler. When the handler returns [Continue], the
+// next datagram is received into a fresh buffer, so data passed to earlier
+// calls is never overwritten. Release buffers to bufs once done with them;
+// buffers that are not released are garbage collected.
+//
+// Empty datagrams and errors keep the buffer, and the next read reuses it.
+func (c *UDPConn) ReadFromRotating(loop *Loop, bufs *DatagramBuffers, handler UDPReadHandler) error {
+	if bufs.size == 0 {
+		return ErrEmptyBuffer
+	}
+	return c.startRead(loop, bufs.get(), bufs, handler)
+}
+
+// ReadFromRotatingFunc starts rotating reads using a callback function.
+//
+// This is a convenience wrapper around [UDPConn.ReadFromRotating] for
+// functional-style callbacks.
+func (c *UDPConn) ReadFromRotatingFunc(loop *Loop, bufs *DatagramBuffers, fn func(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, err error) Action) error {
+	return c.ReadFromRotating(loop, bufs, UDPReadFunc(fn))
+}
+
+// rotated reports whether a handler that returned Continue was given the
+// read buffer, and if so replaces it with a fresh one.
+func (c *UDPConn) rotated(n int) bool {
+	if c.readBufs == nil || n <= 0 {
+		return false
+	}
+	c.readBuf = c.readBufs.get()
+	return true
+}
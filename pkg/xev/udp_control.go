@@ -0,0 +1,209 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// ControlFlags selects the ancillary data the kernel attaches to received
+// datagrams.
+type ControlFlags uint
+
+const (
+	// ControlTimestamp requests the time the kernel received each
+	// datagram, for latency measurements that exclude the wait for the
+	// loop to run the read callback.
+	ControlTimestamp ControlFlags = 1 << iota
+	// ControlPacketInfo requests the destination address of each
+	// datagram and the interface it arrived on, so a server bound to a
+	// wildcard address on a multihomed host can reply from the address
+	// the client sent to.
+	ControlPacketInfo
+)
+
+// ControlMessage is the ancillary data of a datagram.
+//
+// On reads, the fields enabled with [UDPConn.SetControlMessage] are set;
+// the others are zero. On writes, Src and IfIndex, when set, choose the
+// source address and outgoing interface of the datagram.
+//
+// Control messages are supported on Linux only. Elsewhere reads deliver
+// empty messages and writes with a non-empty message fail.
+type ControlMessage struct {
+	// Received is when the kernel received the datagram.
+	Received time.Time
+	// Dst is the destination address of the datagram.
+	Dst net.IP
+	// Src is the source address of a datagram being sent.
+	Src net.IP
+	// IfIndex is the index of the interface the datagram arrived on, or
+	// is to be sent from.
+	IfIndex int
+	// Truncated reports that the datagram was larger than the read
+	// buffer and its end was discarded.
+	Truncated bool
+}
+
+// UDPReadMsgHandler handles datagrams received with their ancillary data.
+type UDPReadMsgHandler interface {
+	// OnReadMsg is called like [UDPReadHandler.OnRead], with the control
+	// message of the datagram. cm is reused by the next datagram and must
+	// not be kept beyond the call.
+	OnReadMsg(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, cm *ControlMessage, err error) Action
+}
+
+// UDPReadMsgFunc is a function adapter for [UDPReadMsgHandler].
+type UDPReadMsgFunc func(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, cm *ControlMessage, err error) Action
+
+// OnReadMsg implements [UDPReadMsgHandler].
+func (f UDPReadMsgFunc) OnReadMsg(c *UDPConn, data []byte, addr *net.UDPAddr, cm *ControlMessage, err error) Action {
+	return f(c, data, addr, cm, err)
+}
+
+// SetControlMessage enables or disables the ancillary data in cf on
+// datagrams received by the socket. It does nothing on a connection backed
+// by a [PacketTransport].
+func (c *UDPConn) SetControlMessage(cf ControlFlags, on bool) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.transport != nil {
+		return nil
+	}
+	return setControlMessage(int(c.Fd()), cf, on)
+}
+
+// ReadMsgFrom starts receiving datagrams like [UDPConn.ReadFrom], passing
+// the handler the control message of each one. Enable the ancillary data
+// to receive with [UDPConn.SetControlMessage] first.
+//
+// It returns an error if the loop's backend cannot receive control
+// messages.
+func (c *UDPConn) ReadMsgFrom(loop *Loop, buf []byte, handler UDPReadMsgHandler) error {
+	if len(buf) == 0 {
+		return ErrEmptyBuffer
+	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	reader := msgReader{handler}
+	if c.transport != nil {
+		return c.startRead(loop, buf, nil, reader)
+	}
+	if c.control == nil {
+		c.control = make([]byte, controlBufLen)
+	}
+	if c.readDone == nil {
+		c.readDone = c.readCallback
+	}
+	id, err := cxev.UDPReadMsgWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, buf, c.control, c.readDone)
+	if err != nil {
+		return err
+	}
+	c.loop = loop
+	c.readHandler = reader
+	c.readBuf = buf
+	c.readBufs = nil
+	c.callbackID = id
+	c.ops.submit(udpConnOwner, "read")
+	c.span = loop.startOp("xev.udp.read")
+	return nil
+}
+
+// ReadMsgFromFunc starts receiving datagrams with their control messages
+// using a callback function.
+//
+// This is a convenience wrapper around [UDPConn.ReadMsgFrom] for
+// functional-style callbacks.
+func (c *UDPConn) ReadMsgFromFunc(loop *Loop, buf []byte, fn func(conn *UDPConn, data []byte, remoteAddr *net.UDPAddr, cm *ControlMessage, err error) Action) error {
+	return c.ReadMsgFrom(loop, buf, UDPReadMsgFunc(fn))
+}
+
+// msgReader runs a UDPReadMsgHandler on the read path, decoding the
+// control messages the kernel left in the connection's control buffer.
+type msgReader struct {
+	handler UDPReadMsgHandler
+}
+
+func (r msgReader) OnRead(c *UDPConn, data []byte, addr *net.UDPAddr, err error) Action {
+	if c.cm == nil {
+		c.cm = new(ControlMessage)
+	}
+	cm := c.cm
+	dst := cm.Dst[:0]
+	*cm = ControlMessage{Dst: dst}
+	if c.transport == nil && err == nil {
+		n := min(cxev.UDPStateControlLen(&c.state), len(c.control))
+		parseControlMessage(cm, c.control[:n], cxev.UDPStateMsgFlags(&c.state))
+	}
+	if len(cm.Dst) == 0 {
+		cm.Dst = nil
+	}
+	return r.handler.OnReadMsg(c, data, addr, cm, err)
+}
+
+// WriteMsgTo starts an async send to addr like [UDPConn.WriteToAddr], with
+// the ancillary data in cm, which may be nil. Use it to reply from the
+// address a request was sent to, passing the request's Dst as Src.
+//
+// cm is encoded before WriteMsgTo returns; it is ignored on a connection
+// backed by a [PacketTransport].
+func (c *UDPConn) WriteMsgTo(loop *Loop, data []byte, addr *net.UDPAddr, cm *ControlMessage, handler UDPWriteHandler) error {
+	if addr == nil {
+		return errors.New("address is nil")
+	}
+	if len(data) == 0 {
+		return ErrEmptyBuffer
+	}
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.transport != nil {
+		c.loop = loop
+		c.writeHandler = handler
+		c.transportWrite(data, addr)
+		return nil
+	}
+	ip4 := addr.IP.To4()
+	if ip4 == nil {
+		return errors.New("IPv6 not yet supported")
+	}
+	control, err := marshalControlMessage(c.writeControl[:0], cm)
+	if err != nil {
+		return err
+	}
+	c.writeControl = control
+
+	var sockaddr cxev.Sockaddr
+	cxev.SockaddrIPv4(&sockaddr, ip4[0], ip4[1], ip4[2], ip4[3], uint16(addr.Port))
+	id, err := cxev.UDPWriteMsgWithCallback(&c.udp, &loop.inner, &c.completion, &c.state, &sockaddr, data, control, c.writeCallback)
+	if err != nil {
+		return err
+	}
+	c.loop = loop
+	c.writeHandler = handler
+	c.callbackID = id
+	c.ops.submit(udpConnOwner, "write")
+	c.span = loop.startOp("xev.udp.write", attribute.String("net.peer.address", addr.String()))
+	return nil
+}
+
+// WriteMsgToFunc starts an async send with ancillary data using a callback
+// function.
+//
+// This is a convenience wrapper around [UDPConn.WriteMsgTo] for
+// functional-style callbacks.
+func (c *UDPConn) WriteMsgToFunc(loop *Loop, data []byte, addr *net.UDPAddr, cm *ControlMessage, fn func(conn *UDPConn, bytesWritten int, err error) Action) error {
+	return c.WriteMsgTo(loop, data, addr, cm, UDPWriteFunc(fn))
+}
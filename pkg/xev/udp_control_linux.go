@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const sizeofTimespec = int(unsafe.Sizeof(unix.Timespec{}))
+
+// controlBufLen fits every control message a read can enable: the three
+// timestamps of SO_TIMESTAMPING and an IP_PKTINFO.
+var controlBufLen = unix.CmsgSpace(3*sizeofTimespec) + unix.CmsgSpace(unix.SizeofInet4Pktinfo)
+
+func setControlMessage(fd int, cf ControlFlags, on bool) error {
+	if cf&ControlTimestamp != 0 {
+		// Software receive timestamps only; hardware ones need the NIC
+		// to be configured first.
+		flags := 0
+		if on {
+			flags = unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE
+		}
+		if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	if cf&ControlPacketInfo != 0 {
+		v := 0
+		if on {
+			v = 1
+		}
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_PKTINFO, v); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	return nil
+}
+
+// parseControlMessage decodes the control messages in b, received with the
+// message flags flags, into cm. Messages it does not know are skipped.
+func parseControlMessage(cm *ControlMessage, b []byte, flags int32) {
+	cm.Truncated = flags&unix.MSG_TRUNC != 0
+	for len(b) >= unix.SizeofCmsghdr {
+		h, data, rest, err := unix.ParseOneSocketControlMessage(b)
+		if err != nil {
+			return
+		}
+		switch {
+		case h.Level == unix.SOL_SOCKET && (h.Type == unix.SCM_TIMESTAMPING || h.Type == unix.SCM_TIMESTAMPNS):
+			// SCM_TIMESTAMPING carries the software timestamp first.
+			if len(data) >= sizeofTimespec {
+				ts := (*unix.Timespec)(unsafe.Pointer(&data[0]))
+				if ts.Sec != 0 || ts.Nsec != 0 {
+					cm.Received = time.Unix(ts.Unix())
+				}
+			}
+		case h.Level == unix.IPPROTO_IP && h.Type == unix.IP_PKTINFO:
+			if len(data) >= unix.SizeofInet4Pktinfo {
+				info := (*unix.Inet4Pktinfo)(unsafe.Pointer(&data[0]))
+				cm.IfIndex = int(info.Ifindex)
+				cm.Dst = append(cm.Dst[:0], info.Addr[:]...)
+			}
+		}
+		b = rest
+	}
+}
+
+// marshalControlMessage encodes the write fields of cm into b, reusing
+// its storage, and returns the control messages to send. A nil or empty
+// cm encodes to none.
+func marshalControlMessage(b []byte, cm *ControlMessage) ([]byte, error) {
+	if cm == nil || (cm.Src == nil && cm.IfIndex == 0) {
+		return b[:0], nil
+	}
+	var src [4]byte
+	if cm.Src != nil {
+		ip4 := cm.Src.To4()
+		if ip4 == nil {
+			return nil, errors.New("IPv6 not yet supported")
+		}
+		copy(src[:], ip4)
+	}
+	n := unix.CmsgSpace(unix.SizeofInet4Pktinfo)
+	b = slices.Grow(b[:0], n)[:n]
+	clear(b)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = unix.IPPROTO_IP
+	h.Type = unix.IP_PKTINFO
+	h.SetLen(unix.CmsgLen(unix.SizeofInet4Pktinfo))
+	info := (*unix.Inet4Pktinfo)(unsafe.Pointer(&b[unix.CmsgLen(0)]))
+	info.Ifindex = int32(cm.IfIndex)
+	info.Spec_dst = src
+	return b, nil
+}
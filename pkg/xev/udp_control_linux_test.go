@@ -0,0 +1,153 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// TestControlMessageKernel checks the encoding of control messages against
+// the kernel, through the standard library's sockets.
+func TestControlMessageKernel(t *testing.T) {
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	raw, err := server.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var setErr error
+	_ = raw.Control(func(fd uintptr) {
+		setErr = setControlMessage(int(fd), ControlTimestamp|ControlPacketInfo, true)
+	})
+	if setErr != nil {
+		t.Fatalf("setControlMessage: %v", setErr)
+	}
+	port := server.LocalAddr().(*net.UDPAddr).Port
+
+	client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Sent to a loopback address other than the one the client would
+	// pick, with the source chosen by an IP_PKTINFO message.
+	src := net.IPv4(127, 0, 0, 2)
+	oob, err := marshalControlMessage(nil, &ControlMessage{Src: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if _, _, err := client.WriteMsgUDP([]byte("ping"), oob, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 3), Port: port}); err != nil {
+		t.Fatalf("WriteMsgUDP: %v", err)
+	}
+
+	buf := make([]byte, 2)
+	control := make([]byte, controlBufLen)
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, flags, from, err := server.ReadMsgUDP(buf, control)
+	if err != nil {
+		t.Fatalf("ReadMsgUDP: %v", err)
+	}
+	var cm ControlMessage
+	parseControlMessage(&cm, control[:oobn], int32(flags))
+
+	if n != 2 || !cm.Truncated {
+		t.Errorf("read %d bytes, truncated %t; want 2, true", n, cm.Truncated)
+	}
+	if !from.IP.Equal(src) {
+		t.Errorf("datagram from %s, want %s", from.IP, src)
+	}
+	if !cm.Dst.Equal(net.IPv4(127, 0, 0, 3)) || cm.IfIndex <= 0 {
+		t.Errorf("destination %s on interface %d", cm.Dst, cm.IfIndex)
+	}
+	if cm.Received.Before(before.Add(-time.Second)) || cm.Received.After(time.Now()) {
+		t.Errorf("received at %s, sent at %s", cm.Received, before)
+	}
+}
+
+func TestUDPReadMsgFrom(t *testing.T) {
+	if !cxev.ExtLibLoaded() {
+		t.Skip("extended library not loaded")
+	}
+	loop, err := NewLoop()
+	if err != nil {
+		t.Fatalf("NewLoop failed: %v", err)
+	}
+	defer loop.Close()
+
+	server, err := ListenUDP("udp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer server.Cleanup()
+	if err := server.SetControlMessage(ControlTimestamp|ControlPacketInfo, true); err != nil {
+		t.Fatalf("SetControlMessage failed: %v", err)
+	}
+	_, port := server.LocalAddr()
+
+	// The server replies from the address each request was sent to.
+	var got []ControlMessage
+	err = server.ReadMsgFromFunc(loop, make([]byte, 64), func(c *UDPConn, data []byte, from *net.UDPAddr, cm *ControlMessage, err error) Action {
+		if err != nil {
+			t.Errorf("read error: %v", err)
+			return Stop
+		}
+		m := *cm
+		m.Dst = slices.Clone(cm.Dst)
+		got = append(got, m)
+		if len(got) < 2 {
+			return Continue
+		}
+		reply := &ControlMessage{Src: cm.Dst}
+		if err := c.WriteMsgToFunc(loop, []byte("pong"), from, reply, func(*UDPConn, int, error) Action { return Stop }); err != nil {
+			t.Errorf("WriteMsgTo failed: %v", err)
+		}
+		return Stop
+	})
+	if err != nil {
+		t.Fatalf("ReadMsgFrom failed: %v", err)
+	}
+
+	client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	dsts := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 4)}
+	for _, dst := range dsts {
+		if _, err := client.WriteToUDP([]byte("ping"), &net.UDPAddr{IP: dst, Port: int(port)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := loop.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("received %d datagrams, want 2", len(got))
+	}
+	for i, cm := range got {
+		if !cm.Dst.Equal(dsts[i]) || cm.Received.IsZero() || cm.Truncated {
+			t.Errorf("datagram %d: %+v, want destination %s and a timestamp", i, cm, dsts[i])
+		}
+	}
+	buf := make([]byte, 64)
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, from, err := client.ReadFromUDP(buf)
+	if err != nil || string(buf[:n]) != "pong" || !from.IP.Equal(dsts[1]) {
+		t.Fatalf("reply %q from %v (%v), want pong from %s", buf[:n], from, err, dsts[1])
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "errors"
+
+// controlBufLen is zero: no control messages are decoded on this platform.
+const controlBufLen = 0
+
+func setControlMessage(fd int, cf ControlFlags, on bool) error {
+	return errors.ErrUnsupported
+}
+
+func parseControlMessage(cm *ControlMessage, b []byte, flags int32) {}
+
+func marshalControlMessage(b []byte, cm *ControlMessage) ([]byte, error) {
+	if cm == nil || (cm.Src == nil && cm.IfIndex == 0) {
+		return b[:0], nil
+	}
+	return nil, errors.ErrUnsupported
+}
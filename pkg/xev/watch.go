@@ -0,0 +1,194 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often a [FileWatcher] collects events when
+// NewFileWatcher is given no interval.
+const DefaultWatchInterval = 100 * time.Millisecond
+
+// ErrWatchOverflow is delivered when the kernel dropped file events because
+// they were not collected fast enough.
+var ErrWatchOverflow = errors.New("file watch event queue overflowed")
+
+// FileOp is the kind of change a [FileEvent] reports.
+type FileOp uint8
+
+const (
+	// FileCreate reports a new entry in a watched directory, including one
+	// renamed into it.
+	FileCreate FileOp = 1 << iota
+	// FileModify reports that the contents of a file changed.
+	FileModify
+	// FileDelete reports that a watched path, or an entry of a watched
+	// directory, was removed or renamed away.
+	FileDelete
+)
+
+func (op FileOp) String() string {
+	var names []string
+	for _, n := range []struct {
+		op   FileOp
+		name string
+	}{{FileCreate, "create"}, {FileModify, "modify"}, {FileDelete, "delete"}} {
+		if op&n.op != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// FileEvent is a change to a watched path.
+type FileEvent struct {
+	// Path is the path that changed: a watched path, or for changes inside
+	// a watched directory, the directory joined with the entry name.
+	Path string
+	Op   FileOp
+}
+
+// FileWatchHandler receives the events of a [FileWatcher].
+type FileWatchHandler interface {
+	// OnFileEvent is called for each event, in order. A non-nil err means
+	// watching failed and no further events follow. Return [Stop] to stop
+	// watching.
+	OnFileEvent(w *FileWatcher, ev FileEvent, err error) Action
+}
+
+// FileWatchFunc is a function adapter for [FileWatchHandler].
+type FileWatchFunc func(w *FileWatcher, ev FileEvent, err error) Action
+
+// OnFileEvent implements [FileWatchHandler].
+func (f FileWatchFunc) OnFileEvent(w *FileWatcher, ev FileEvent, err error) Action {
+	return f(w, ev, err)
+}
+
+// FileWatcher reports creation, modification and deletion of files using
+// inotify on Linux and kqueue EVFILT_VNODE on Darwin.
+//
+// The kernel queues events on a descriptor that the watcher drains without
+// blocking from a repeating timer on the loop, so handlers run on the loop
+// goroutine like every other callback, at most interval after the change.
+// Watching a directory reports entries created in and removed from it. On
+// Linux it also reports modified entries; on Darwin only files watched
+// directly report modification.
+//
+// Like [Loop], a FileWatcher is not thread-safe: all methods must be called
+// from the goroutine that runs the loop.
+//
+// # Example
+//
+//	w, err := xev.NewFileWatcher(loop, 0, xev.FileWatchFunc(
+//	    func(w *xev.FileWatcher, ev xev.FileEvent, err error) xev.Action {
+//	        if err != nil {
+//	            return xev.Stop
+//	        }
+//	        if ev.Path == configPath && ev.Op&xev.FileModify != 0 {
+//	            reloadConfig()
+//	        }
+//	        return xev.Continue
+//	    }))
+//	if err != nil {
+//	    return err
+//	}
+//	defer w.Close()
+//	_ = w.Add(filepath.Dir(configPath))
+//	_ = w.Start()
+type FileWatcher struct {
+	loop     Scheduler
+	interval time.Duration
+	handler  FileWatchHandler
+	backend  watchBackend
+	events   []FileEvent
+	cancel   func()
+}
+
+// watchBackend is the kernel interface of a FileWatcher.
+type watchBackend interface {
+	add(path string) error
+	remove(path string) error
+	// poll appends the queued events to evs without blocking.
+	poll(evs []FileEvent) ([]FileEvent, error)
+	close() error
+}
+
+// NewFileWatcher creates a watcher that collects events every interval, or
+// every [DefaultWatchInterval] if interval is not positive, once started.
+// It returns [errors.ErrUnsupported] on platforms without a backend.
+func NewFileWatcher(loop Scheduler, interval time.Duration, handler FileWatchHandler) (*FileWatcher, error) {
+	if loop == nil {
+		return nil, errors.New("xev: NewFileWatcher requires a loop")
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	backend, err := newWatchBackend()
+	if err != nil {
+		return nil, err
+	}
+	return &FileWatcher{loop: loop, interval: interval, handler: handler, backend: backend}, nil
+}
+
+// Add starts watching path, which must exist.
+func (w *FileWatcher) Add(path string) error {
+	return w.backend.add(filepath.Clean(path))
+}
+
+// Remove stops watching path.
+func (w *FileWatcher) Remove(path string) error {
+	return w.backend.remove(filepath.Clean(path))
+}
+
+// Start begins delivering events to the handler.
+func (w *FileWatcher) Start() error {
+	w.Stop()
+	cancel, err := w.loop.Schedule(w.interval, w.deliver)
+	if err != nil {
+		return err
+	}
+	w.cancel = cancel
+	return nil
+}
+
+// Stop stops delivering events. Events that occur while stopped are queued
+// by the kernel and delivered after the next Start.
+func (w *FileWatcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+// Close stops the watcher and releases its kernel resources.
+func (w *FileWatcher) Close() error {
+	w.Stop()
+	return w.backend.close()
+}
+
+// deliver passes the queued events to the handler.
+func (w *FileWatcher) deliver() Action {
+	evs, err := w.backend.poll(w.events[:0])
+	w.events = evs
+	for _, ev := range evs {
+		if w.handler.OnFileEvent(w, ev, nil) == Stop {
+			return Stop
+		}
+	}
+	if err != nil {
+		w.handler.OnFileEvent(w, FileEvent{}, err)
+		return Stop
+	}
+	return Continue
+}
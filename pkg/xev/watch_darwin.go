@@ -0,0 +1,169 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const vnodeFlags = syscall.NOTE_WRITE | syscall.NOTE_EXTEND | syscall.NOTE_DELETE | syscall.NOTE_RENAME
+
+// kqueueBackend watches each path through its own descriptor. kqueue only
+// reports that a directory changed, so the entries of watched directories
+// are kept and compared after each change to find what was created or
+// removed.
+type kqueueBackend struct {
+	kq      int
+	watches map[int]*vnodeWatch // by descriptor
+	fds     map[string]int
+	events  []syscall.Kevent_t
+	closed  bool
+}
+
+type vnodeWatch struct {
+	path    string
+	entries map[string]struct{} // nil for files
+}
+
+func newWatchBackend() (watchBackend, error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, os.NewSyscallError("kqueue", err)
+	}
+	syscall.CloseOnExec(kq)
+	return &kqueueBackend{
+		kq:      kq,
+		watches: make(map[int]*vnodeWatch),
+		fds:     make(map[string]int),
+		events:  make([]syscall.Kevent_t, 64),
+	}, nil
+}
+
+func (b *kqueueBackend) add(path string) error {
+	if _, ok := b.fds[path]; ok {
+		return nil
+	}
+	fd, err := syscall.Open(path, syscall.O_EVTONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	w := &vnodeWatch{path: path}
+	if st, err := os.Stat(path); err == nil && st.IsDir() {
+		if w.entries, err = readEntries(path); err != nil {
+			closeFD(fd)
+			return err
+		}
+	}
+	var change syscall.Kevent_t
+	syscall.SetKevent(&change, fd, syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_CLEAR)
+	change.Fflags = vnodeFlags
+	if _, err := syscall.Kevent(b.kq, []syscall.Kevent_t{change}, nil, nil); err != nil {
+		closeFD(fd)
+		return &os.PathError{Op: "kevent", Path: path, Err: err}
+	}
+	b.watches[fd] = w
+	b.fds[path] = fd
+	return nil
+}
+
+func (b *kqueueBackend) remove(path string) error {
+	fd, ok := b.fds[path]
+	if !ok {
+		return fmt.Errorf("xev: %s is not watched", path)
+	}
+	b.drop(fd)
+	return nil
+}
+
+// drop forgets a watch. Closing the descriptor removes its kevent.
+func (b *kqueueBackend) drop(fd int) {
+	if w, ok := b.watches[fd]; ok {
+		delete(b.fds, w.path)
+		delete(b.watches, fd)
+		closeFD(fd)
+	}
+}
+
+func (b *kqueueBackend) poll(evs []FileEvent) ([]FileEvent, error) {
+	var zero syscall.Timespec
+	for {
+		n, err := syscall.Kevent(b.kq, nil, b.events, &zero)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return evs, os.NewSyscallError("kevent", err)
+		}
+		for _, ev := range b.events[:n] {
+			w, ok := b.watches[int(ev.Ident)]
+			if !ok {
+				continue
+			}
+			switch {
+			case ev.Fflags&(syscall.NOTE_DELETE|syscall.NOTE_RENAME) != 0:
+				evs = append(evs, FileEvent{Path: w.path, Op: FileDelete})
+				b.drop(int(ev.Ident))
+			case w.entries != nil:
+				evs = b.diffEntries(evs, w)
+			default:
+				evs = append(evs, FileEvent{Path: w.path, Op: FileModify})
+			}
+		}
+		if n < len(b.events) {
+			return evs, nil
+		}
+	}
+}
+
+// diffEntries appends the entries created in and removed from the
+// directory of w since it was last read.
+func (b *kqueueBackend) diffEntries(evs []FileEvent, w *vnodeWatch) []FileEvent {
+	entries, err := readEntries(w.path)
+	if err != nil {
+		// The directory is going away; its NOTE_DELETE follows.
+		return evs
+	}
+	for name := range entries {
+		if _, ok := w.entries[name]; !ok {
+			evs = append(evs, FileEvent{Path: filepath.Join(w.path, name), Op: FileCreate})
+		}
+	}
+	for name := range w.entries {
+		if _, ok := entries[name]; !ok {
+			evs = append(evs, FileEvent{Path: filepath.Join(w.path, name), Op: FileDelete})
+		}
+	}
+	w.entries = entries
+	return evs
+}
+
+func readEntries(dir string) (map[string]struct{}, error) {
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]struct{}, len(list))
+	for _, e := range list {
+		entries[e.Name()] = struct{}{}
+	}
+	return entries, nil
+}
+
+func (b *kqueueBackend) close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for fd := range b.watches {
+		b.drop(fd)
+	}
+	return syscall.Close(b.kq)
+}
@@ -0,0 +1,131 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+type inotifyBackend struct {
+	fd     int
+	paths  map[int32]string // by watch descriptor
+	wds    map[string]int32
+	buf    []byte
+	closed bool
+}
+
+func newWatchBackend() (watchBackend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	return &inotifyBackend{
+		fd:    fd,
+		paths: make(map[int32]string),
+		wds:   make(map[string]int32),
+		buf:   make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)),
+	}, nil
+}
+
+func (b *inotifyBackend) add(path string) error {
+	wd, err := syscall.InotifyAddWatch(b.fd, path, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	b.paths[int32(wd)] = path
+	b.wds[path] = int32(wd)
+	return nil
+}
+
+func (b *inotifyBackend) remove(path string) error {
+	wd, ok := b.wds[path]
+	if !ok {
+		return fmt.Errorf("xev: %s is not watched", path)
+	}
+	delete(b.wds, path)
+	delete(b.paths, wd)
+	if _, err := syscall.InotifyRmWatch(b.fd, uint32(wd)); err != nil {
+		return &os.PathError{Op: "inotify_rm_watch", Path: path, Err: err}
+	}
+	return nil
+}
+
+func (b *inotifyBackend) poll(evs []FileEvent) ([]FileEvent, error) {
+	for {
+		n, err := syscall.Read(b.fd, b.buf)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if errors.Is(err, syscall.EAGAIN) {
+			return evs, nil
+		}
+		if err != nil {
+			return evs, os.NewSyscallError("read", err)
+		}
+		if evs, err = b.parse(evs, b.buf[:n]); err != nil {
+			return evs, err
+		}
+	}
+}
+
+// parse appends the events in buf, a sequence of inotify_event records.
+func (b *inotifyBackend) parse(evs []FileEvent, buf []byte) ([]FileEvent, error) {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := syscall.SizeofInotifyEvent + int(raw.Len)
+		name := buf[syscall.SizeofInotifyEvent:end]
+		buf = buf[end:]
+
+		if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			return evs, ErrWatchOverflow
+		}
+		path, ok := b.paths[raw.Wd]
+		if !ok {
+			continue
+		}
+		if raw.Mask&syscall.IN_IGNORED != 0 {
+			// The watch is gone, because its path was removed or through
+			// remove.
+			delete(b.paths, raw.Wd)
+			delete(b.wds, path)
+			continue
+		}
+		if name = bytes.TrimRight(name, "\x00"); len(name) > 0 {
+			path = filepath.Join(path, string(name))
+		}
+		var op FileOp
+		switch {
+		case raw.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			op = FileCreate
+		case raw.Mask&syscall.IN_MODIFY != 0:
+			op = FileModify
+		case raw.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM|syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+			op = FileDelete
+		default:
+			continue
+		}
+		evs = append(evs, FileEvent{Path: path, Op: op})
+	}
+	return evs, nil
+}
+
+func (b *inotifyBackend) close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return syscall.Close(b.fd)
+}
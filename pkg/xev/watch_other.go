@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import "errors"
+
+func newWatchBackend() (watchBackend, error) {
+	return nil, errors.ErrUnsupported
+}
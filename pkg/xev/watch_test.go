@@ -0,0 +1,179 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// manualScheduler runs the scheduled callback only when fire is called.
+type manualScheduler struct {
+	fn func() Action
+}
+
+func (s *manualScheduler) Now() time.Duration { return 0 }
+
+func (s *manualScheduler) Schedule(_ time.Duration, fn func() Action) (func(), error) {
+	s.fn = fn
+	return func() { s.fn = nil }, nil
+}
+
+func (s *manualScheduler) fire() Action {
+	if s.fn == nil {
+		return Stop
+	}
+	return s.fn()
+}
+
+func newTestWatcher(t *testing.T, h FileWatchHandler) (*FileWatcher, *manualScheduler) {
+	t.Helper()
+	sched := &manualScheduler{}
+	w, err := NewFileWatcher(sched, time.Millisecond, h)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("file watching is not supported on this platform")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return w, sched
+}
+
+func TestFileWatcherDirectory(t *testing.T) {
+	dir := t.TempDir()
+	var got []FileEvent
+	w, sched := newTestWatcher(t, FileWatchFunc(func(_ *FileWatcher, ev FileEvent, err error) Action {
+		if err != nil {
+			t.Errorf("watch error: %v", err)
+			return Stop
+		}
+		got = append(got, ev)
+		return Continue
+	}))
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "conf")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sched.fire()
+	if !slices.Contains(got, FileEvent{Path: path, Op: FileCreate}) {
+		t.Fatalf("events after create = %v", got)
+	}
+
+	got = got[:0]
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if sched.fire() != Continue {
+		t.Fatal("watcher stopped after delete")
+	}
+	if !slices.Contains(got, FileEvent{Path: path, Op: FileDelete}) {
+		t.Fatalf("events after remove = %v", got)
+	}
+}
+
+func TestFileWatcherFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf")
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got []FileEvent
+	w, sched := newTestWatcher(t, FileWatchFunc(func(_ *FileWatcher, ev FileEvent, err error) Action {
+		if err != nil {
+			t.Errorf("watch error: %v", err)
+			return Stop
+		}
+		got = append(got, ev)
+		return Continue
+	}))
+	if err := w.Add(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if sched.fire(); len(got) != 0 {
+		t.Fatalf("events before any change = %v", got)
+	}
+	if err := os.WriteFile(path, []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sched.fire()
+	if !slices.Contains(got, FileEvent{Path: path, Op: FileModify}) {
+		t.Fatalf("events after write = %v", got)
+	}
+
+	got = got[:0]
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	sched.fire()
+	if !slices.Contains(got, FileEvent{Path: path, Op: FileDelete}) {
+		t.Fatalf("events after remove = %v", got)
+	}
+}
+
+func TestFileWatcherHandlerStop(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	w, sched := newTestWatcher(t, FileWatchFunc(func(*FileWatcher, FileEvent, error) Action {
+		calls++
+		return Stop
+	}))
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sched.fire() != Stop {
+		t.Fatal("deliver continued after the handler returned Stop")
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
+
+func TestFileWatcherRemove(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := newTestWatcher(t, FileWatchFunc(func(*FileWatcher, FileEvent, error) Action { return Continue }))
+	if err := w.Remove(dir); err == nil {
+		t.Fatal("removing an unwatched path succeeded")
+	}
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove(dir + "/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("watching a missing path succeeded")
+	}
+}
+
+func TestFileOpString(t *testing.T) {
+	for op, want := range map[FileOp]string{
+		0:                       "none",
+		FileCreate:              "create",
+		FileModify | FileDelete: "modify|delete",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", op, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/cxev"
+)
+
+// Watchdog defaults, used for zero fields of [WatchdogOptions].
+const (
+	DefaultWatchdogInterval = 100 * time.Millisecond
+	DefaultWatchdogBudget   = 50 * time.Millisecond
+	DefaultWatchdogDeadline = time.Second
+)
+
+// WatchdogKind is the kind of problem a [WatchdogReport] describes.
+type WatchdogKind uint8
+
+const (
+	// WatchdogSlowCallback reports that the loop came back later than its
+	// budget allows: a callback, or several in one iteration, held it.
+	WatchdogSlowCallback WatchdogKind = iota + 1
+	// WatchdogStalled reports that the loop has not iterated within the
+	// deadline and is still blocked.
+	WatchdogStalled
+)
+
+func (k WatchdogKind) String() string {
+	switch k {
+	case WatchdogSlowCallback:
+		return "slow callback"
+	case WatchdogStalled:
+		return "loop stalled"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchdogReport describes a late or stalled loop.
+type WatchdogReport struct {
+	Kind WatchdogKind
+	// Blocked is how long the loop went without iterating beyond the
+	// heartbeat interval. For a stall it is the time so far.
+	Blocked time.Duration
+	// Stacks holds the stacks of all goroutines, taken while the loop was
+	// stalled, if [WatchdogOptions.CaptureStacks] is set.
+	Stacks []byte
+}
+
+// WatchdogOptions configures a [Watchdog].
+type WatchdogOptions struct {
+	// Interval is the heartbeat period.
+	Interval time.Duration
+	// Budget is how late a heartbeat may run before it is reported as a
+	// slow callback.
+	Budget time.Duration
+	// Deadline is how long the loop may go without a heartbeat before it is
+	// reported as stalled.
+	Deadline time.Duration
+	// CaptureStacks records all goroutine stacks in stall reports, which
+	// shows the call the loop is blocked in.
+	CaptureStacks bool
+	// OnReport receives the reports. Slow callbacks are reported on the
+	// loop goroutine; stalls are reported on the watchdog goroutine, since
+	// the loop is blocked, so OnReport must be safe to call from there. If
+	// nil, reports are logged with the logger set by [SetLogger].
+	OnReport func(WatchdogReport)
+}
+
+// Watchdog detects callbacks that block the loop.
+//
+// It runs a heartbeat timer on the loop and watches it from its own
+// goroutine. A heartbeat that runs more than the budget after it was due
+// means the loop was held up in between, which is reported once the loop
+// recovers. A loop that misses heartbeats for longer than the deadline is
+// reported while it is still blocked, once per stall, so a handler stuck
+// in a blocking call is visible without waiting for it to return.
+//
+// Start the watchdog right before running the loop: time spent outside
+// Run counts as a stall.
+//
+// # Example
+//
+//	wd := xev.NewWatchdog(loop, xev.WatchdogOptions{
+//	    Budget:        20 * time.Millisecond,
+//	    CaptureStacks: true,
+//	})
+//	_ = wd.Start()
+//	defer wd.Stop()
+//	loop.Run()
+type Watchdog struct {
+	loop     Scheduler
+	opts     WatchdogOptions
+	now      func() time.Time
+	cancel   func()
+	done     chan struct{}
+	lastBeat atomic.Int64 // UnixNano of the last heartbeat
+	stalled  atomic.Int64 // lastBeat of the stall already reported
+}
+
+// NewWatchdog creates a watchdog for loop. Zero options take the defaults.
+func NewWatchdog(loop Scheduler, opts WatchdogOptions) *Watchdog {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchdogInterval
+	}
+	if opts.Budget <= 0 {
+		opts.Budget = DefaultWatchdogBudget
+	}
+	if opts.Deadline <= 0 {
+		opts.Deadline = DefaultWatchdogDeadline
+	}
+	return &Watchdog{loop: loop, opts: opts, now: time.Now}
+}
+
+// Start arms the heartbeat and starts the watchdog goroutine.
+func (w *Watchdog) Start() error {
+	if w.cancel != nil {
+		return nil
+	}
+	if w.loop == nil {
+		return errors.New("xev: watchdog has no loop")
+	}
+	w.lastBeat.Store(w.now().UnixNano())
+	w.stalled.Store(0)
+	cancel, err := w.loop.Schedule(w.opts.Interval, w.beat)
+	if err != nil {
+		return err
+	}
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go w.monitor(w.done)
+	return nil
+}
+
+// Stop disarms the heartbeat and stops the watchdog goroutine.
+func (w *Watchdog) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.cancel = nil
+	close(w.done)
+}
+
+// beat runs on the loop and reports how late it ran.
+func (w *Watchdog) beat() Action {
+	now := w.now().UnixNano()
+	late := time.Duration(now-w.lastBeat.Swap(now)) - w.opts.Interval
+	if late > w.opts.Budget {
+		w.report(WatchdogReport{Kind: WatchdogSlowCallback, Blocked: late})
+	}
+	return Continue
+}
+
+func (w *Watchdog) monitor(done <-chan struct{}) {
+	ticker := time.NewTicker(max(w.opts.Deadline/4, minReapInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reports a stall if the last heartbeat is older than the deadline
+// and this stall has not been reported yet.
+func (w *Watchdog) check() {
+	last := w.lastBeat.Load()
+	blocked := time.Duration(w.now().UnixNano()-last) - w.opts.Interval
+	if blocked <= w.opts.Deadline || w.stalled.Swap(last) == last {
+		return
+	}
+	r := WatchdogReport{Kind: WatchdogStalled, Blocked: blocked}
+	if w.opts.CaptureStacks {
+		r.Stacks = allStacks()
+	}
+	w.report(r)
+}
+
+func (w *Watchdog) report(r WatchdogReport) {
+	if w.opts.OnReport != nil {
+		w.opts.OnReport(r)
+		return
+	}
+	attrs := []any{"blocked", r.Blocked}
+	if r.Stacks != nil {
+		attrs = append(attrs, "stacks", string(r.Stacks))
+	}
+	cxev.GetLogger().Warn("xev watchdog: "+r.Kind.String(), attrs...)
+}
+
+// allStacks returns the stacks of all goroutines, growing the buffer until
+// they fit.
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xev
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWatchdogSlowCallback(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	var reports []WatchdogReport
+	sched := &manualScheduler{}
+	wd := NewWatchdog(sched, WatchdogOptions{
+		Interval: 100 * time.Millisecond,
+		Budget:   50 * time.Millisecond,
+		Deadline: time.Hour,
+		OnReport: func(r WatchdogReport) { reports = append(reports, r) },
+	})
+	wd.now = func() time.Time { return clock }
+	if err := wd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer wd.Stop()
+
+	clock = clock.Add(140 * time.Millisecond) // 40ms late: within budget
+	sched.fire()
+	if len(reports) != 0 {
+		t.Fatalf("reports within budget = %v", reports)
+	}
+
+	clock = clock.Add(400 * time.Millisecond)
+	if sched.fire() != Continue {
+		t.Fatal("heartbeat stopped")
+	}
+	if len(reports) != 1 || reports[0].Kind != WatchdogSlowCallback || reports[0].Blocked != 300*time.Millisecond {
+		t.Fatalf("reports = %+v, want one slow callback blocked 300ms", reports)
+	}
+}
+
+func TestWatchdogStallReportedOnce(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	var reports []WatchdogReport
+	sched := &manualScheduler{}
+	wd := NewWatchdog(sched, WatchdogOptions{
+		Interval:      100 * time.Millisecond,
+		Deadline:      time.Hour, // keep the watchdog goroutine idle
+		CaptureStacks: true,
+		OnReport:      func(r WatchdogReport) { reports = append(reports, r) },
+	})
+	wd.now = func() time.Time { return clock }
+	if err := wd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer wd.Stop()
+
+	clock = clock.Add(30 * time.Minute)
+	wd.check()
+	if len(reports) != 0 {
+		t.Fatalf("reports before the deadline = %v", reports)
+	}
+
+	clock = clock.Add(time.Hour)
+	wd.check()
+	wd.check()
+	if len(reports) != 1 || reports[0].Kind != WatchdogStalled {
+		t.Fatalf("reports = %+v, want one stall", reports)
+	}
+	if !bytes.Contains(reports[0].Stacks, []byte("TestWatchdogStallReportedOnce")) {
+		t.Fatal("stall report does not carry the goroutine stacks")
+	}
+
+	// The loop recovers, which is also a slow callback, then stalls again.
+	sched.fire()
+	clock = clock.Add(2 * time.Hour)
+	wd.check()
+	if len(reports) != 3 || reports[2].Kind != WatchdogStalled {
+		t.Fatalf("reports = %+v, want slow callback then a second stall", reports)
+	}
+}
+
+func TestWatchdogGoroutineReportsStall(t *testing.T) {
+	got := make(chan WatchdogReport, 1)
+	wd := NewWatchdog(&manualScheduler{}, WatchdogOptions{
+		Deadline: 20 * time.Millisecond,
+		OnReport: func(r WatchdogReport) {
+			select {
+			case got <- r:
+			default:
+			}
+		},
+	})
+	if err := wd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer wd.Stop()
+
+	// The manual scheduler never runs the heartbeat, like a blocked loop.
+	select {
+	case r := <-got:
+		if r.Kind != WatchdogStalled || r.Blocked <= 20*time.Millisecond {
+			t.Fatalf("report = %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no stall reported")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+// Package xevtest provides test doubles for code built on package xev that
+// run entirely in Go, without the libxev shared library.
+//
+// [Loop] is a simulated event loop: time only moves when the test calls
+// [Loop.Advance], and every scheduled callback and scripted completion runs
+// on the test goroutine in a fixed order. Timer-heavy logic such as idle
+// reaping, backoff and expiration cycles can therefore be tested instantly
+// and deterministically.
+//
+// [Loop.Pipe], [Loop.Listen] and [Loop.ListenUDP] create [xev.TCPConn] and
+// [xev.UDPConn] values backed by in-memory transports on the loop, so code
+// written against the xev handler interfaces can be tested as is.
+package xevtest
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/xev"
+)
+
+// Loop is a deterministic simulated event loop. It implements
+// [xev.Scheduler].
+//
+// Events due at the same time run in the order they were scheduled. Like
+// [xev.Loop], a Loop is not thread-safe.
+type Loop struct {
+	now    time.Duration
+	seq    uint64
+	events eventQueue
+	udp    map[string]*memPacketConn
+}
+
+var _ xev.Scheduler = (*Loop)(nil)
+
+// event is a callback due at a simulated time. A repeating event is pushed
+// again with the same interval while its callback returns xev.Continue.
+type event struct {
+	at       time.Duration
+	seq      uint64
+	interval time.Duration
+	fn       func() xev.Action
+	canceled bool
+}
+
+// NewLoop returns a loop whose clock starts at zero.
+func NewLoop() *Loop {
+	return &Loop{}
+}
+
+// Now returns the simulated time.
+func (l *Loop) Now() time.Duration {
+	return l.now
+}
+
+// Schedule implements [xev.Scheduler]. A zero delay runs fn once, at the
+// current time, whatever it returns.
+func (l *Loop) Schedule(delay time.Duration, fn func() xev.Action) (func(), error) {
+	ev := l.push(delay, fn)
+	ev.interval = delay
+	return func() { ev.canceled = true }, nil
+}
+
+// After delivers fn once after delay, the way a completion would be
+// delivered by a real loop. Scripting completions this way lets a test
+// decide exactly when each reply or error reaches the code under test.
+func (l *Loop) After(delay time.Duration, fn func()) {
+	l.push(delay, func() xev.Action {
+		fn()
+		return xev.Stop
+	})
+}
+
+func (l *Loop) push(delay time.Duration, fn func() xev.Action) *event {
+	l.seq++
+	ev := &event{at: l.now + max(delay, 0), seq: l.seq, fn: fn}
+	heap.Push(&l.events, ev)
+	return ev
+}
+
+// Advance moves the clock forward by d, running every event that falls due
+// on the way at its own time. Events scheduled by those callbacks run too
+// if they fall due within d. It returns the number of callbacks run.
+func (l *Loop) Advance(d time.Duration) int {
+	return l.runUntil(l.now + d)
+}
+
+// RunPending runs the events that are due now without moving the clock,
+// like a non-blocking poll of a real loop.
+func (l *Loop) RunPending() int {
+	return l.runUntil(l.now)
+}
+
+func (l *Loop) runUntil(end time.Duration) int {
+	ran := 0
+	for len(l.events) > 0 && l.events[0].at <= end {
+		ev := heap.Pop(&l.events).(*event)
+		if ev.canceled {
+			continue
+		}
+		l.now = ev.at
+		ran++
+		if ev.fn() == xev.Continue && ev.interval > 0 && !ev.canceled {
+			l.seq++
+			ev.at, ev.seq = l.now+ev.interval, l.seq
+			heap.Push(&l.events, ev)
+		}
+	}
+	l.now = max(l.now, end)
+	return ran
+}
+
+// Pending returns the number of scheduled events that have not run or been
+// canceled.
+func (l *Loop) Pending() int {
+	n := 0
+	for _, ev := range l.events {
+		if !ev.canceled {
+			n++
+		}
+	}
+	return n
+}
+
+// eventQueue is a min-heap of events ordered by due time, then by the
+// order they were scheduled in.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x any) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xevtest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crrow/libxev-go/pkg/xev"
+)
+
+func TestLoopRunsEventsInTimeOrder(t *testing.T) {
+	l := NewLoop()
+	var got []string
+	record := func(name string) func() {
+		return func() { got = append(got, name+"@"+l.Now().String()) }
+	}
+
+	l.After(30*time.Millisecond, record("c"))
+	l.After(10*time.Millisecond, record("a"))
+	l.After(10*time.Millisecond, record("b"))
+	ticks := 0
+	cancel, _ := l.Schedule(20*time.Millisecond, func() xev.Action {
+		ticks++
+		record("tick")()
+		if ticks == 1 {
+			// Scheduled from a callback and due within the same Advance.
+			l.After(5*time.Millisecond, record("nested"))
+		}
+		return xev.Continue
+	})
+
+	if n := l.Advance(45 * time.Millisecond); n != 6 {
+		t.Fatalf("ran %d events, want 6", n)
+	}
+	want := []string{"a@10ms", "b@10ms", "tick@20ms", "nested@25ms", "c@30ms", "tick@40ms"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if l.Now() != 45*time.Millisecond {
+		t.Fatalf("Now = %v, want 45ms", l.Now())
+	}
+
+	cancel()
+	if l.Pending() != 0 || l.Advance(time.Second) != 0 {
+		t.Fatal("canceled timer still fires")
+	}
+}
+
+func TestIdleReaperOnSimulatedLoop(t *testing.T) {
+	l := NewLoop()
+	var expired []string
+	r := xev.NewIdleReaper(l, time.Second, func(k string) {
+		expired = append(expired, k)
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	r.Touch("a")
+	r.Touch("b")
+	l.Advance(900 * time.Millisecond)
+	r.Touch("a")
+
+	// The reaper scans every 250ms, so b goes at the scan at 1s and a at
+	// the first scan after 1.9s.
+	l.Advance(99 * time.Millisecond)
+	if len(expired) != 0 {
+		t.Fatalf("expired early: %v", expired)
+	}
+	l.Advance(time.Millisecond)
+	if want := []string{"b"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+	l.Advance(750 * time.Millisecond)
+	if len(expired) != 1 {
+		t.Fatalf("expired early: %v", expired)
+	}
+	r.Touch("c")
+	l.Advance(250 * time.Millisecond)
+	if want := []string{"b", "a"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+
+	r.Stop()
+	l.Advance(time.Hour)
+	if r.Len() != 1 {
+		t.Fatalf("stopped reaper still expired keys: %v", expired)
+	}
+}
+
+func TestTimerWheelOnSimulatedLoop(t *testing.T) {
+	l := NewLoop()
+	w := xev.NewTimerWheel(l, 10*time.Millisecond)
+	defer w.Close()
+
+	var expired []string
+	deadline := func(name string, d time.Duration) *xev.WheelTimer {
+		t.Helper()
+		tm, err := w.AfterFunc(d, func() { expired = append(expired, name) })
+		if err != nil {
+			t.Fatalf("AfterFunc failed: %v", err)
+		}
+		return tm
+	}
+	a := deadline("a", time.Second)
+	deadline("b", time.Second)
+	if l.Pending() != 1 {
+		t.Fatalf("%d events scheduled for 2 timeouts, want 1", l.Pending())
+	}
+
+	// Activity on a pushes its deadline back.
+	l.Advance(900 * time.Millisecond)
+	if err := a.Reset(time.Second); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	l.Advance(100 * time.Millisecond)
+	if want := []string{"b"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+	l.Advance(time.Second)
+	if want := []string{"b", "a"}; !reflect.DeepEqual(expired, want) {
+		t.Fatalf("expired = %v, want %v", expired, want)
+	}
+	// With nothing armed, the wheel stops ticking.
+	if w.Len() != 0 || l.Pending() != 0 {
+		t.Fatalf("Len = %d, %d events pending once idle", w.Len(), l.Pending())
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xevtest
+
+import (
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/crrow/libxev-go/pkg/xev"
+)
+
+// The in-memory transports deliver every completion through the loop with
+// a zero delay, so nothing happens until the test runs [Loop.RunPending]
+// or [Loop.Advance], and a handler never runs inside the call that started
+// its operation.
+
+// memConn is one end of an in-memory stream connection.
+type memConn struct {
+	loop       *Loop
+	peer       *memConn
+	incoming   []byte
+	read       *memRead
+	closed     bool
+	peerClosed bool
+}
+
+type memRead struct {
+	buf  []byte
+	done func(int, error)
+}
+
+// Pipe returns the two ends of an in-memory TCP connection. Bytes written
+// to one end are read from the other; closing one end makes reads on the
+// other return [io.EOF] once the written bytes are consumed.
+func (l *Loop) Pipe() (*xev.TCPConn, *xev.TCPConn) {
+	a, b := &memConn{loop: l}, &memConn{loop: l}
+	a.peer, b.peer = b, a
+	return xev.NewTransportConn(a), xev.NewTransportConn(b)
+}
+
+func (c *memConn) Read(buf []byte, done func(int, error)) {
+	c.read = &memRead{buf: buf, done: done}
+	c.wakeRead()
+}
+
+// wakeRead schedules the pending read if it can complete.
+func (c *memConn) wakeRead() {
+	if c.read == nil || (len(c.incoming) == 0 && !c.closed && !c.peerClosed) {
+		return
+	}
+	r := c.read
+	c.read = nil
+	c.loop.After(0, func() {
+		switch {
+		case c.closed:
+			r.done(0, net.ErrClosed)
+		case len(c.incoming) > 0:
+			n := copy(r.buf, c.incoming)
+			c.incoming = c.incoming[n:]
+			r.done(n, nil)
+		default:
+			r.done(0, io.EOF)
+		}
+	})
+}
+
+func (c *memConn) Write(data []byte, done func(int, error)) {
+	var err error
+	switch {
+	case c.closed:
+		err = net.ErrClosed
+	case c.peerClosed:
+		err = syscall.EPIPE
+	default:
+		c.peer.incoming = append(c.peer.incoming, data...)
+		c.peer.wakeRead()
+	}
+	n := len(data)
+	if err != nil {
+		n = 0
+	}
+	c.loop.After(0, func() { done(n, err) })
+}
+
+func (c *memConn) Close(done func(error)) {
+	if c.closed {
+		c.loop.After(0, func() { done(net.ErrClosed) })
+		return
+	}
+	c.closed = true
+	c.peer.peerClosed = true
+	c.wakeRead()
+	c.peer.wakeRead()
+	c.loop.After(0, func() { done(nil) })
+}
+
+// Listener accepts in-memory connections made with [Listener.Dial].
+type Listener struct {
+	loop    *Loop
+	handler xev.AcceptHandler
+}
+
+// Listen returns a listener whose connections are driven by l.
+func (l *Loop) Listen() *Listener {
+	return &Listener{loop: l}
+}
+
+// Accept starts handing connections to handler, whose OnAccept receives a
+// nil listener. Accepting stops when the handler returns [xev.Stop].
+func (ln *Listener) Accept(handler xev.AcceptHandler) {
+	ln.handler = handler
+}
+
+// Dial connects to the listener and returns the client end. The server end
+// reaches the accept handler through the loop. Dial fails with
+// ECONNREFUSED while the listener is not accepting.
+func (ln *Listener) Dial() (*xev.TCPConn, error) {
+	if ln.handler == nil {
+		return nil, syscall.ECONNREFUSED
+	}
+	client, server := ln.loop.Pipe()
+	ln.loop.After(0, func() {
+		if ln.handler == nil {
+			_ = server.Close(nil, nil)
+			return
+		}
+		if ln.handler.OnAccept(nil, server, nil) != xev.Continue {
+			ln.handler = nil
+		}
+	})
+	return client, nil
+}
+
+// memPacketConn is an in-memory UDP socket bound to addr on its loop.
+type memPacketConn struct {
+	loop   *Loop
+	addr   *net.UDPAddr
+	queue  []datagram
+	read   *memPacketRead
+	closed bool
+}
+
+type datagram struct {
+	from *net.UDPAddr
+	data []byte
+}
+
+type memPacketRead struct {
+	buf  []byte
+	done func(int, *net.UDPAddr, error)
+}
+
+// ListenUDP returns an in-memory UDP socket bound to address. Datagrams
+// sent to an address nobody is bound to are dropped, as on a network.
+func (l *Loop) ListenUDP(address string) (*xev.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if l.udp == nil {
+		l.udp = make(map[string]*memPacketConn)
+	}
+	if _, ok := l.udp[addr.String()]; ok {
+		return nil, syscall.EADDRINUSE
+	}
+	c := &memPacketConn{loop: l, addr: addr}
+	l.udp[addr.String()] = c
+	return xev.NewPacketTransportConn(c), nil
+}
+
+func (c *memPacketConn) LocalAddr() *net.UDPAddr {
+	return c.addr
+}
+
+func (c *memPacketConn) ReadFrom(buf []byte, done func(int, *net.UDPAddr, error)) {
+	c.read = &memPacketRead{buf: buf, done: done}
+	c.wakeRead()
+}
+
+func (c *memPacketConn) wakeRead() {
+	if c.read == nil || (len(c.queue) == 0 && !c.closed) {
+		return
+	}
+	r := c.read
+	c.read = nil
+	c.loop.After(0, func() {
+		if c.closed {
+			r.done(0, nil, net.ErrClosed)
+			return
+		}
+		d := c.queue[0]
+		c.queue = c.queue[1:]
+		// Like a socket, a datagram longer than the buffer is truncated.
+		r.done(copy(r.buf, d.data), d.from, nil)
+	})
+}
+
+func (c *memPacketConn) WriteTo(data []byte, to *net.UDPAddr, done func(int, error)) {
+	if c.closed {
+		c.loop.After(0, func() { done(0, net.ErrClosed) })
+		return
+	}
+	if dst := c.loop.udp[to.String()]; dst != nil && !dst.closed {
+		dst.queue = append(dst.queue, datagram{from: c.addr, data: append([]byte(nil), data...)})
+		dst.wakeRead()
+	}
+	c.loop.After(0, func() { done(len(data), nil) })
+}
+
+func (c *memPacketConn) Close(done func(error)) {
+	if c.closed {
+		c.loop.After(0, func() { done(net.ErrClosed) })
+		return
+	}
+	c.closed = true
+	delete(c.loop.udp, c.addr.String())
+	c.wakeRead()
+	c.loop.After(0, func() { done(nil) })
+}
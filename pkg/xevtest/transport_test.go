@@ -0,0 +1,305 @@
+/*
+ * MIT License
+ * Copyright (c) 2023 Mitchell Hashimoto
+ * Copyright (c) 2026 Crrow
+ */
+
+package xevtest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/crrow/libxev-go/pkg/xev"
+)
+
+// echoServer is application code written against the xev handler
+// interfaces: it echoes what it reads until the peer closes.
+func echoServer(l *xev.TCPListener, conn *xev.TCPConn, err error) xev.Action {
+	if err != nil {
+		return xev.Stop
+	}
+	buf := make([]byte, 4)
+	_ = conn.ReadFunc(nil, buf, func(c *xev.TCPConn, data []byte, err error) xev.Action {
+		if err != nil || len(data) == 0 {
+			_ = c.CloseFunc(nil, nil)
+			return xev.Stop
+		}
+		_ = c.WriteFunc(nil, append([]byte(nil), data...), func(*xev.TCPConn, int, error) xev.Action {
+			return xev.Stop
+		})
+		return xev.Continue
+	})
+	return xev.Continue
+}
+
+func TestListenerEcho(t *testing.T) {
+	loop := NewLoop()
+	ln := loop.Listen()
+	ln.Accept(xev.AcceptFunc(echoServer))
+
+	client, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	var (
+		echoed  []byte
+		readErr error
+		closed  bool
+	)
+	_ = client.WriteFunc(nil, []byte("hello world"), func(c *xev.TCPConn, n int, err error) xev.Action {
+		if err != nil || n != len("hello world") {
+			t.Errorf("write: n=%d err=%v", n, err)
+		}
+		return xev.Stop
+	})
+	_ = client.ReadFunc(nil, make([]byte, 64), func(c *xev.TCPConn, data []byte, err error) xev.Action {
+		if err != nil {
+			readErr = err
+			return xev.Stop
+		}
+		echoed = append(echoed, data...)
+		return xev.Continue
+	})
+
+	loop.RunPending()
+	if string(echoed) != "hello world" {
+		t.Fatalf("echoed %q", echoed)
+	}
+	if got := client.Stats(); got.BytesOut != 11 || got.BytesIn != 11 {
+		t.Fatalf("client stats: %+v", got)
+	}
+
+	_ = client.CloseFunc(nil, func(*xev.TCPConn, error) { closed = true })
+	loop.RunPending()
+	if !closed || !errors.Is(readErr, net.ErrClosed) {
+		t.Fatalf("close: closed=%v readErr=%v", closed, readErr)
+	}
+	if loop.Pending() != 0 {
+		t.Fatalf("%d events left after close", loop.Pending())
+	}
+}
+
+func TestPipeEOFAndStop(t *testing.T) {
+	loop := NewLoop()
+	a, b := loop.Pipe()
+
+	var got []error
+	_ = b.ReadFunc(nil, make([]byte, 8), func(c *xev.TCPConn, data []byte, err error) xev.Action {
+		got = append(got, err)
+		if err != nil {
+			return xev.Stop
+		}
+		return xev.Continue
+	})
+	_ = a.WriteFunc(nil, []byte("x"), func(*xev.TCPConn, int, error) xev.Action { return xev.Stop })
+	_ = a.CloseFunc(nil, nil)
+	loop.RunPending()
+	if len(got) != 2 || got[0] != nil || !errors.Is(got[1], io.EOF) {
+		t.Fatalf("read results: %v", got)
+	}
+
+	var writeErr error
+	_ = b.WriteFunc(nil, []byte("y"), func(_ *xev.TCPConn, _ int, err error) xev.Action {
+		writeErr = err
+		return xev.Stop
+	})
+	loop.RunPending()
+	if writeErr == nil {
+		t.Fatal("write to a closed peer succeeded")
+	}
+
+	ln := loop.Listen()
+	ln.Accept(xev.AcceptFunc(func(*xev.TCPListener, *xev.TCPConn, error) xev.Action { return xev.Stop }))
+	if _, err := ln.Dial(); err != nil {
+		t.Fatalf("first Dial failed: %v", err)
+	}
+	loop.RunPending()
+	if _, err := ln.Dial(); err == nil {
+		t.Fatal("Dial succeeded after the handler stopped accepting")
+	}
+}
+
+func TestUDPRoundTrip(t *testing.T) {
+	loop := NewLoop()
+	server, err := loop.ListenUDP("127.0.0.1:5353")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	client, err := loop.ListenUDP("127.0.0.1:40000")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	if _, err := loop.ListenUDP("127.0.0.1:5353"); err == nil {
+		t.Fatal("bound the same address twice")
+	}
+
+	_ = server.ReadFromFunc(nil, make([]byte, 3), func(c *xev.UDPConn, data []byte, from *net.UDPAddr, err error) xev.Action {
+		_ = c.WriteToAddrFunc(nil, append([]byte("re:"), data...), from, func(*xev.UDPConn, int, error) xev.Action {
+			return xev.Stop
+		})
+		return xev.Continue
+	})
+	var replies []string
+	_ = client.ReadFromFunc(nil, make([]byte, 16), func(c *xev.UDPConn, data []byte, from *net.UDPAddr, err error) xev.Action {
+		if from.String() != "127.0.0.1:5353" {
+			t.Errorf("reply from %v", from)
+		}
+		replies = append(replies, string(data))
+		return xev.Continue
+	})
+	for _, msg := range []string{"ping", "hi"} {
+		_ = client.WriteToFunc(nil, []byte(msg), "127.0.0.1:5353", func(*xev.UDPConn, int, error) xev.Action {
+			return xev.Stop
+		})
+	}
+	// Nobody listens here; the datagram is dropped.
+	_ = client.WriteToFunc(nil, []byte("lost"), "127.0.0.1:1", func(*xev.UDPConn, int, error) xev.Action {
+		return xev.Stop
+	})
+	loop.RunPending()
+
+	// The server buffer holds 3 bytes, so "ping" arrives truncated.
+	if len(replies) != 2 || replies[0] != "re:pin" || replies[1] != "re:hi" {
+		t.Fatalf("replies = %q", replies)
+	}
+}
+
+func TestUDPRotatingReadKeepsData(t *testing.T) {
+	loop := NewLoop()
+	server, err := loop.ListenUDP("127.0.0.1:5353")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	client, err := loop.ListenUDP("127.0.0.1:40000")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+
+	bufs := xev.NewDatagramBuffers(8)
+	var kept [][]byte
+	_ = server.ReadFromRotatingFunc(nil, bufs, func(c *xev.UDPConn, data []byte, from *net.UDPAddr, err error) xev.Action {
+		kept = append(kept, data)
+		return xev.Continue
+	})
+	send := func(msgs ...string) {
+		for _, msg := range msgs {
+			_ = client.WriteToFunc(nil, []byte(msg), "127.0.0.1:5353", func(*xev.UDPConn, int, error) xev.Action {
+				return xev.Stop
+			})
+		}
+		loop.RunPending()
+	}
+
+	send("one", "two", "three")
+	if len(kept) != 3 || string(kept[0]) != "one" || string(kept[1]) != "two" || string(kept[2]) != "three" {
+		t.Fatalf("kept %q", kept)
+	}
+
+	// Released buffers are received into again.
+	first := &kept[0][0]
+	bufs.Release(kept[0])
+	kept = kept[:0]
+	send("four", "five")
+	if string(kept[0]) != "four" || string(kept[1]) != "five" {
+		t.Fatalf("kept %q", kept)
+	}
+	if &kept[1][0] != first {
+		t.Fatal("released buffer not reused")
+	}
+}
+
+func TestOperationsAfterClose(t *testing.T) {
+	loop := NewLoop()
+	a, b := loop.Pipe()
+	defer b.CloseFunc(nil, nil)
+	if err := a.CloseFunc(nil, nil); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	loop.RunPending()
+
+	buf := make([]byte, 4)
+	read := func(*xev.TCPConn, []byte, error) xev.Action { return xev.Stop }
+	if err := a.ReadFunc(nil, buf, read); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("Read after Close = %v, want ErrClosed", err)
+	}
+	write := func(*xev.TCPConn, int, error) xev.Action { return xev.Stop }
+	if err := a.WriteFunc(nil, []byte("x"), write); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+	wait := func(*xev.TCPConn, error) xev.Action { return xev.Stop }
+	if err := a.WaitWritable(nil, wait); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("WaitWritable after Close = %v, want ErrClosed", err)
+	}
+	if err := b.WaitWritable(nil, wait); err == nil {
+		t.Error("WaitWritable on a pipe end succeeded")
+	}
+	if err := a.CloseFunc(nil, nil); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+
+	udp, err := loop.ListenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	if err := udp.CloseFunc(nil, nil); err != nil {
+		t.Fatalf("UDP Close failed: %v", err)
+	}
+	loop.RunPending()
+	udpRead := func(*xev.UDPConn, []byte, *net.UDPAddr, error) xev.Action { return xev.Stop }
+	if err := udp.ReadFromFunc(nil, buf, udpRead); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("UDP ReadFrom after Close = %v, want ErrClosed", err)
+	}
+	udpWrite := func(*xev.UDPConn, int, error) xev.Action { return xev.Stop }
+	if err := udp.WriteToFunc(nil, []byte("x"), "127.0.0.1:1", udpWrite); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("UDP WriteTo after Close = %v, want ErrClosed", err)
+	}
+	if err := udp.CloseFunc(nil, nil); !errors.Is(err, xev.ErrClosed) {
+		t.Errorf("second UDP Close = %v, want ErrClosed", err)
+	}
+}
+
+// TestCloseFromOtherGoroutines closes a connection on behalf of several
+// goroutines. Close must run on the loop's goroutine, so they hand it
+// over, as they would with xev.Loop.Post: exactly one close is submitted,
+// the rest see ErrClosed.
+func TestCloseFromOtherGoroutines(t *testing.T) {
+	loop := NewLoop()
+	a, b := loop.Pipe()
+	defer b.CloseFunc(nil, nil)
+
+	const n = 8
+	posted := make(chan func(), n)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			posted <- func() { errs <- a.CloseFunc(nil, nil) }
+		}()
+	}
+	wg.Wait()
+	close(posted)
+	for fn := range posted {
+		fn()
+	}
+	close(errs)
+
+	var ok int
+	for err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case !errors.Is(err, xev.ErrClosed):
+			t.Errorf("Close = %v", err)
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d of %d Close calls succeeded, want 1", ok, n)
+	}
+	loop.RunPending()
+}
@@ -0,0 +1,120 @@
+// MIT License
+// Copyright (c) 2023 Mitchell Hashimoto
+// Copyright (c) 2026 Crrow
+
+// Callback ABI test shim.
+//
+// Each function here calls a callback of one of the signatures the extended
+// API (and libxev's timers) invoke, exactly as native code would. The Go
+// tests in pkg/cxev point them at the real trampolines to check that every
+// argument arrives in its position with its full width, which a libffi CIF
+// that disagrees with the C signature would break on some architectures
+// but not others.
+//
+// Pointers are passed through from the caller. Integers are fixed sentinels
+// whose bit patterns expose truncation and sign-extension errors; the Go
+// tests hold the same values.
+
+const std = @import("std");
+const builtin = @import("builtin");
+const xev = @import("xev");
+
+const tcp_api = @import("tcp_api.zig");
+const udp_api = @import("udp_api.zig");
+
+const func_callconv: std.builtin.CallingConvention = if (blk: {
+    const order = builtin.zig_version.order(.{ .major = 0, .minor = 14, .patch = 1 });
+    break :blk order == .lt or order == .eq;
+}) .C else .c;
+
+/// Result passed to (loop, completion, result, userdata) callbacks.
+pub const XEV_ABI_RESULT: c_int = -0x12345678;
+
+/// Count passed to write and accept callbacks.
+pub const XEV_ABI_COUNT: c_int = 0x7f3c5a21;
+
+/// Byte count passed to read callbacks. Callers slice their buffer by it,
+/// so it is small; the error code that follows it is the wide value.
+pub const XEV_ABI_READ_COUNT: c_int = 3;
+
+/// Error code passed to read, write and accept callbacks.
+pub const XEV_ABI_ERR: c_int = -0x0f1e2d3c;
+
+/// Call a (loop, completion, result, userdata) callback, the signature of
+/// timer, close and connect callbacks.
+export fn xev_abi_call_result(
+    cb: tcp_api.xev_tcp_cb,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    userdata: ?*anyopaque,
+) c_int {
+    return @intFromEnum(cb(loop, c, XEV_ABI_RESULT, userdata));
+}
+
+/// Call an accept callback with the count as the accepted fd.
+export fn xev_abi_call_accept(
+    cb: tcp_api.xev_tcp_accept_cb,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    userdata: ?*anyopaque,
+) c_int {
+    return @intFromEnum(cb(loop, c, XEV_ABI_COUNT, XEV_ABI_ERR, userdata));
+}
+
+/// Call a TCP or file read callback.
+export fn xev_abi_call_read(
+    cb: tcp_api.xev_tcp_read_cb,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    buf: [*]u8,
+    userdata: ?*anyopaque,
+) c_int {
+    return @intFromEnum(cb(loop, c, buf, XEV_ABI_READ_COUNT, XEV_ABI_ERR, userdata));
+}
+
+/// Call a TCP, UDP or file write callback, or a process wait callback.
+export fn xev_abi_call_write(
+    cb: tcp_api.xev_tcp_write_cb,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    userdata: ?*anyopaque,
+) c_int {
+    return @intFromEnum(cb(loop, c, XEV_ABI_COUNT, XEV_ABI_ERR, userdata));
+}
+
+/// Call a UDP read callback. It has seven arguments, so userdata is passed
+/// on the stack on x86-64.
+export fn xev_abi_call_udp_read(
+    cb: udp_api.xev_udp_read_cb,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    addr: *tcp_api.xev_sockaddr,
+    buf: [*]u8,
+    userdata: ?*anyopaque,
+) c_int {
+    return @intFromEnum(cb(loop, c, addr, buf, XEV_ABI_READ_COUNT, XEV_ABI_ERR, userdata));
+}
+
+//-------------------------------------------------------------------
+// Tests
+
+fn echoRead(
+    _: *xev.Loop,
+    _: *xev.Completion,
+    buf: [*]u8,
+    n: c_int,
+    err: c_int,
+    userdata: ?*anyopaque,
+) callconv(func_callconv) xev.CallbackAction {
+    const ok = n == XEV_ABI_READ_COUNT and err == XEV_ABI_ERR and buf[0] == 'x' and userdata != null;
+    return if (ok) .rearm else .disarm;
+}
+
+test "abi read shim" {
+    var loop: xev.Loop = undefined;
+    var c: xev.Completion = undefined;
+    var buf = [_]u8{'x'};
+    var userdata: u8 = 0;
+    const action = xev_abi_call_read(&echoRead, &loop, &c, &buf, &userdata);
+    try std.testing.expectEqual(@intFromEnum(xev.CallbackAction.rearm), action);
+}
@@ -0,0 +1,127 @@
+// MIT License
+// Copyright (c) 2023 Mitchell Hashimoto
+// Copyright (c) 2026 Crrow
+
+// Extended C API for libxev process watchers.
+//
+// This file exports xev.Process, which waits for a child process to exit
+// without blocking the loop. On Linux it waits on a pidfd; on kqueue it
+// uses EVFILT_PROC. It follows the same patterns as tcp_api.zig.
+
+const std = @import("std");
+const builtin = @import("builtin");
+const xev = @import("xev");
+
+const func_callconv: std.builtin.CallingConvention = if (blk: {
+    const order = builtin.zig_version.order(.{ .major = 0, .minor = 14, .patch = 1 });
+    break :blk order == .lt or order == .eq;
+}) .C else .c;
+
+//-------------------------------------------------------------------
+// Types and Constants
+
+/// Size for process watcher storage - must be >= sizeof(xev.Process)
+pub const XEV_SIZEOF_PROCESS = 16;
+
+/// Extended Completion struct with space for C callback pointer.
+const Completion = extern struct {
+    const Data = [@sizeOf(xev.Completion)]u8;
+    data: Data,
+    c_callback: *const anyopaque,
+};
+
+/// Opaque process watcher type for C API
+pub const xev_process = extern struct {
+    data: [XEV_SIZEOF_PROCESS]u8 align(@alignOf(usize)),
+};
+
+/// Callback type for process waits
+pub const xev_process_wait_cb = *const fn (
+    *xev.Loop,
+    *xev.Completion,
+    c_int, // exit status, valid when the error code is 0
+    c_int, // error code (0 on success)
+    ?*anyopaque, // userdata
+) callconv(func_callconv) xev.CallbackAction;
+
+//-------------------------------------------------------------------
+// Process Functions
+
+/// Initialize a watcher for the child process pid.
+/// Returns 0 on success, error code on failure.
+export fn xev_process_init(process: *xev_process, pid: std.posix.pid_t) c_int {
+    const p = xev.Process.init(pid) catch |err| return errorCode(err);
+    getProcess(process).* = p;
+    return 0;
+}
+
+/// Release the resources of a process watcher. No wait may be in flight
+/// on it.
+export fn xev_process_deinit(process: *xev_process) void {
+    getProcess(process).deinit();
+}
+
+/// Wait for the process to exit and reap it.
+/// This is an async operation - the callback will be invoked when complete.
+/// Note: The completion must be XEV_SIZEOF_TCP_COMPLETION bytes.
+export fn xev_process_wait(
+    process: *xev_process,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    userdata: ?*anyopaque,
+    cb: xev_process_wait_cb,
+) void {
+    const Callback = @typeInfo(@TypeOf(cb)).pointer.child;
+
+    // Store callback in the extended completion struct
+    const extern_c: *Completion = @ptrCast(@alignCast(c));
+    extern_c.c_callback = @ptrCast(cb);
+
+    getProcess(process).wait(loop, c, anyopaque, userdata, (struct {
+        fn callback(
+            ud: ?*anyopaque,
+            cb_loop: *xev.Loop,
+            cb_c: *xev.Completion,
+            r: xev.Process.WaitError!u32,
+        ) xev.CallbackAction {
+            const cb_extern_c: *Completion = @ptrCast(@alignCast(cb_c));
+            const cb_c_callback: *const Callback = @ptrCast(@alignCast(cb_extern_c.c_callback));
+
+            if (r) |status| {
+                return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, @as(c_int, @intCast(status)), @as(c_int, 0), ud });
+            } else |err| {
+                return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, @as(c_int, -1), errorCode(err), ud });
+            }
+        }
+    }).callback);
+}
+
+//-------------------------------------------------------------------
+// Size Constants for Go FFI
+
+export fn xev_sizeof_process() usize {
+    return XEV_SIZEOF_PROCESS;
+}
+
+//-------------------------------------------------------------------
+// Internal Helpers
+
+fn getProcess(process: *xev_process) *xev.Process {
+    return @ptrCast(@alignCast(&process.data));
+}
+
+/// Returns the unique error code for an error.
+fn errorCode(err: anyerror) c_int {
+    return @intFromError(err);
+}
+
+//-------------------------------------------------------------------
+// Tests
+
+test "process sizes" {
+    const testing = std.testing;
+
+    // Ensure our opaque type is large enough for the watcher
+    try testing.expect(@sizeOf(xev.Process) <= XEV_SIZEOF_PROCESS);
+    try testing.expect(@alignOf(xev.Process) <= @alignOf(xev_process));
+}
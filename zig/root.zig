@@ -2,11 +2,14 @@
 // Copyright (c) 2023 Mitchell Hashimoto
 // Copyright (c) 2026 Crrow
 
+const std = @import("std");
 const xev = @import("xev");
 
 pub const tcp = @import("tcp_api.zig");
 pub const file = @import("file_api.zig");
 pub const udp = @import("udp_api.zig");
+pub const abi = @import("abi_api.zig");
+pub const process = @import("process_api.zig");
 
 // Initialize a loop with options including thread pool support.
 // This replaces the old xev_loop_set_thread_pool pattern which is no longer
@@ -19,14 +22,263 @@ export fn xev_loop_init_with_options(loop: *xev.Loop, options: *const xev.Option
     return 0;
 }
 
+// Backend tuning passed to xev_loop_init_tuned. A zero value leaves every
+// knob at libxev's default.
+pub const LoopTuning = extern struct {
+    // Milliseconds the io_uring SQPOLL kernel thread polls the submission
+    // queue before it sleeps, after which the loop enters the kernel to
+    // wake it; 0 leaves SQPOLL off.
+    sqpoll_idle_ms: u32,
+    // Reserved for further knobs; zero.
+    reserved: u32 = 0,
+};
+
+// Initialize a loop like xev_loop_init_with_options, then apply tuning.
+// libxev creates the io_uring ring without setup flags, so a loop asking
+// for SQPOLL has its ring replaced by one of the same size created with
+// them. Backends without a ring ignore the tuning. Returns 0, -2 if the
+// kernel refuses SQPOLL to the process, or the error code of the failure
+// otherwise; the loop is left uninitialized on error.
+export fn xev_loop_init_tuned(loop: *xev.Loop, options: *const xev.Options, tuning: *const LoopTuning) c_int {
+    var result = xev.Loop.init(options.*) catch |err| return @intFromError(err);
+    if (@hasField(xev.Loop, "ring")) {
+        if (tuning.sqpoll_idle_ms > 0) {
+            const linux = std.os.linux;
+            var params = std.mem.zeroInit(linux.io_uring_params, .{
+                .flags = linux.IORING_SETUP_SQPOLL,
+                .sq_thread_idle = tuning.sqpoll_idle_ms,
+            });
+            const ring = linux.IoUring.init_params(@intCast(options.entries), &params) catch |err| {
+                result.deinit();
+                // libxev has just created a ring of the same size, so
+                // EPERM is the kernel refusing SQPOLL itself, as kernels
+                // before 5.11 do to unprivileged processes. Anything else,
+                // such as running out of locked memory, is reported as is.
+                if (err == error.PermissionDenied) return -2;
+                return @intFromError(err);
+            };
+            result.ring.deinit();
+            result.ring = ring;
+        }
+    }
+    loop.* = result;
+    return 0;
+}
+
+// Submit the loop's queued operations if at least threshold of them are
+// waiting in its io_uring submission queue, so that a callback starting
+// many operations hands them to the kernel in batches of that size rather
+// than all at the end of the loop iteration. Backends without a ring have
+// nothing queued. Returns 0 on success, error code on failure.
+export fn xev_loop_submit_threshold(loop: *xev.Loop, threshold: u32) c_int {
+    if (@hasField(xev.Loop, "ring")) {
+        if (loop.ring.sq_ready() >= threshold) {
+            loop.submit() catch |err| return @intFromError(err);
+        }
+    }
+    return 0;
+}
+
+// Register buffers with the loop's io_uring ring, replacing those
+// registered before, so that reads and writes into them can be submitted
+// as READ_FIXED and WRITE_FIXED, with the buffers mapped once rather than
+// on every operation. An empty list only unregisters. Returns 0, -1 if the
+// backend has no ring, or the error code of the registration.
+export fn xev_loop_register_buffers(loop: *xev.Loop, iovecs: [*]const std.posix.iovec, n: u32) c_int {
+    if (@hasField(xev.Loop, "ring")) {
+        loop.ring.unregister_buffers() catch {};
+        if (n == 0) return 0;
+        loop.ring.register_buffers(iovecs[0..n]) catch |err| return @intFromError(err);
+        return 0;
+    }
+    return -1;
+}
+
+// Register a table of n empty file slots with the loop's io_uring ring,
+// which xev_loop_update_file fills. An operation through a registered file
+// skips the kernel's descriptor lookup and reference counting. Returns 0,
+// -1 if the backend has no ring, or the error code of the registration;
+// sparse tables need Linux 5.19.
+export fn xev_loop_register_files(loop: *xev.Loop, n: u32) c_int {
+    if (@hasField(xev.Loop, "ring")) {
+        loop.ring.register_files_sparse(n) catch |err| return @intFromError(err);
+        return 0;
+    }
+    return -1;
+}
+
+// Set slot index of the loop's file table to fd, or empty it with -1. The
+// ring holds a reference to the file while it is in the table, so a slot
+// must be emptied before its descriptor is closed for the close to take
+// effect. Returns 0, -1 if the backend has no ring, or the error code.
+export fn xev_loop_update_file(loop: *xev.Loop, index: u32, fd: c_int) c_int {
+    if (@hasField(xev.Loop, "ring")) {
+        const fds = [1]std.posix.fd_t{fd};
+        loop.ring.register_files_update(index, &fds) catch |err| return @intFromError(err);
+        return 0;
+    }
+    return -1;
+}
+
+// Report whether the loop has completions in flight or waiting to be
+// submitted, the condition xev_loop_run(.until_done) keeps running for. It
+// lets callers that drive the loop with .no_wait tell when it is finished.
+export fn xev_loop_alive(loop: *xev.Loop) c_int {
+    if (loop.active > 0) return 1;
+    if (@hasField(xev.Loop, "submissions")) {
+        if (!loop.submissions.empty()) return 1;
+    }
+    return 0;
+}
+
+// Return the number of completions the loop has in flight, not counting
+// those queued but not yet submitted. A caller that keeps a watcher of its
+// own armed can compare against it to tell whether anything else is left,
+// which xev_loop_alive cannot.
+export fn xev_loop_active(loop: *xev.Loop) c_int {
+    return @intCast(loop.active);
+}
+
+// Return the descriptor the loop's backend waits on: the io_uring ring,
+// the epoll or the kqueue fd, or -1 if the backend has none. It becomes
+// readable when the loop has completions to process, so another event
+// system can wait on it and run the loop with .no_wait when it does.
+export fn xev_loop_fd(loop: *xev.Loop) c_int {
+    if (@hasField(xev.Loop, "ring")) return loop.ring.fd;
+    if (@hasField(xev.Loop, "kqueue_fd")) return loop.kqueue_fd;
+    if (@hasField(xev.Loop, "fd")) return loop.fd;
+    return -1;
+}
+
+// Identify the loop's backend, for callers tuning for it or reporting it:
+// 1 io_uring, 2 epoll, 3 kqueue, or 0 for any other. It tells them apart
+// the way xev_loop_fd does, by the descriptor field of the loop.
+export fn xev_loop_backend(_: *xev.Loop) c_int {
+    if (@hasField(xev.Loop, "ring")) return 1;
+    if (@hasField(xev.Loop, "kqueue_fd")) return 3;
+    if (@hasField(xev.Loop, "fd")) return 2;
+    return 0;
+}
+
+/// The loop does file I/O in the kernel rather than on a thread pool.
+pub const XEV_FEATURE_NATIVE_FILE_IO: c_uint = 1 << 0;
+/// The loop was given a thread pool for blocking operations.
+pub const XEV_FEATURE_THREAD_POOL: c_uint = 1 << 1;
+/// The backend can wait for readiness without reading or writing.
+pub const XEV_FEATURE_POLL: c_uint = 1 << 2;
+/// The backend can receive and send control messages (recvmsg, sendmsg).
+pub const XEV_FEATURE_MSG: c_uint = 1 << 3;
+/// The backend has a descriptor, returned by xev_loop_fd.
+pub const XEV_FEATURE_FD: c_uint = 1 << 4;
+/// The loop's io_uring ring is polled by an SQPOLL kernel thread.
+pub const XEV_FEATURE_SQPOLL: c_uint = 1 << 5;
+
+// Return the XEV_FEATURE_* flags of the loop.
+export fn xev_loop_features(loop: *xev.Loop) c_uint {
+    var flags: c_uint = 0;
+    if (@hasField(xev.Loop, "thread_pool")) {
+        if (loop.thread_pool != null) flags |= XEV_FEATURE_THREAD_POOL;
+    } else {
+        flags |= XEV_FEATURE_NATIVE_FILE_IO;
+    }
+    if (@hasField(@FieldType(xev.Completion, "op"), "poll")) flags |= XEV_FEATURE_POLL;
+    if (udp.hasMsgOps()) flags |= XEV_FEATURE_MSG;
+    if (xev_loop_fd(loop) >= 0) flags |= XEV_FEATURE_FD;
+    if (@hasField(xev.Loop, "ring")) {
+        if (loop.ring.flags & std.os.linux.IORING_SETUP_SQPOLL != 0) flags |= XEV_FEATURE_SQPOLL;
+    }
+    return flags;
+}
+
+// Set or clear the loop's stopped flag. A stopped loop returns from
+// xev_loop_run at the end of the current tick instead of waiting for more
+// completions; clearing the flag lets it run again. Callers use it to stop
+// a loop from one of its callbacks.
+export fn xev_loop_set_stopped(loop: *xev.Loop, stopped: c_int) void {
+    if (@hasField(@TypeOf(loop.flags), "stopped")) {
+        loop.flags.stopped = stopped != 0;
+    } else if (stopped != 0) {
+        loop.stop();
+    }
+}
+
+// Report the workers of a thread pool: out[0] is the most it may spawn,
+// out[1] how many it has spawned and out[2] how many of those are idle,
+// waiting for a task. The counts are read from the pool's sync word, which
+// its workers update atomically, so they are a snapshot that may already
+// be stale. libxev keeps no count of tasks; callers count their own.
+export fn xev_threadpool_stats(pool: *xev.ThreadPool, out: *[3]u32) void {
+    out.* = .{ 0, 0, 0 };
+    if (@hasField(xev.ThreadPool, "max_threads")) out[0] = pool.max_threads;
+    if (@hasField(xev.ThreadPool, "sync")) {
+        // Sync is packed as idle: u14, spawned: u14, then flags.
+        const sync: u32 = @bitCast(pool.sync.load(.monotonic));
+        out[1] = (sync >> 14) & 0x3fff;
+        out[2] = sync & 0x3fff;
+    }
+}
+
+// Hand the operations queued since the last run to the kernel without
+// waiting for any, so that their completions make the descriptor returned
+// by xev_loop_fd readable. A run queues, but does not submit, the
+// operations its callbacks start. Returns 0 on success, error code on
+// failure.
+export fn xev_loop_submit(loop: *xev.Loop) c_int {
+    if (@hasDecl(xev.Loop, "submit")) {
+        loop.submit() catch |err| return @intFromError(err);
+    }
+    return 0;
+}
+
+// Map an error code returned by the extended API back to an errno value.
+// Error codes are Zig error values (@intFromError), which are not stable
+// across builds and mean nothing to C callers; this gives callers a portable
+// way to recognize conditions such as EMFILE. Returns 0 when the error has
+// no errno equivalent.
+export fn xev_error_errno(code: c_int) c_int {
+    if (code <= 0) return 0;
+    const err = @errorFromInt(@as(std.meta.Int(.unsigned, @bitSizeOf(anyerror)), @intCast(code)));
+    const E = std.posix.E;
+    const e: E = switch (err) {
+        error.ProcessFdQuotaExceeded => .MFILE,
+        error.SystemFdQuotaExceeded => .NFILE,
+        error.SystemResources => .NOBUFS,
+        error.ConnectionAborted => .CONNABORTED,
+        error.ConnectionResetByPeer => .CONNRESET,
+        error.ConnectionRefused => .CONNREFUSED,
+        error.ConnectionTimedOut => .TIMEDOUT,
+        error.BrokenPipe => .PIPE,
+        error.WouldBlock => .AGAIN,
+        error.AccessDenied => .ACCES,
+        error.AddressInUse => .ADDRINUSE,
+        error.AddressNotAvailable => .ADDRNOTAVAIL,
+        error.NetworkUnreachable => .NETUNREACH,
+        error.Canceled => .CANCELED,
+        else => return 0,
+    };
+    return @intFromEnum(e);
+}
+
+// Return the Zig error name for an error code returned by the extended API.
+// The returned string is static and must not be freed.
+export fn xev_error_name(code: c_int) [*:0]const u8 {
+    if (code <= 0) return "Unknown";
+    const err = @errorFromInt(@as(std.meta.Int(.unsigned, @bitSizeOf(anyerror)), @intCast(code)));
+    return @errorName(err);
+}
+
 comptime {
     _ = tcp;
     _ = file;
     _ = udp;
+    _ = abi;
+    _ = process;
 }
 
 test {
     _ = tcp;
     _ = file;
     _ = udp;
+    _ = abi;
+    _ = process;
 }
@@ -422,6 +422,95 @@ export fn xev_tcp_write(
     }).callback);
 }
 
+/// Read from a TCP socket like xev_tcp_read, through the loop's registered
+/// file file_index and registered buffer buf_index, either -1 for none. buf
+/// must lie within the registered buffer.
+/// Returns 1 if the read was submitted through them, or 0 if it reads the
+/// usual way: on backends without a ring, when neither index is given, or
+/// when the completion waits for room in the ring. A read the callback
+/// rearms reads the usual way too.
+/// Note: The completion must be XEV_SIZEOF_TCP_COMPLETION bytes.
+export fn xev_tcp_read_fixed(
+    tcp: *xev_tcp,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    buf: [*]u8,
+    buf_len: usize,
+    file_index: c_int,
+    buf_index: c_int,
+    userdata: ?*anyopaque,
+    cb: xev_tcp_read_cb,
+) c_int {
+    xev_tcp_read(tcp, loop, c, buf, buf_len, userdata, cb);
+    return useFixed(loop, c, file_index, buf_index, false);
+}
+
+/// Write to a TCP socket like xev_tcp_write, through the loop's registered
+/// file file_index and registered buffer buf_index, either -1 for none. buf
+/// must lie within the registered buffer.
+/// Returns 1 if the write was submitted through them, or 0 if it writes the
+/// usual way, as xev_tcp_read_fixed does.
+/// Note: The completion must be XEV_SIZEOF_TCP_COMPLETION bytes.
+export fn xev_tcp_write_fixed(
+    tcp: *xev_tcp,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    buf: [*]const u8,
+    buf_len: usize,
+    file_index: c_int,
+    buf_index: c_int,
+    userdata: ?*anyopaque,
+    cb: xev_tcp_write_cb,
+) c_int {
+    xev_tcp_write(tcp, loop, c, buf, buf_len, userdata, cb);
+    return useFixed(loop, c, file_index, buf_index, true);
+}
+
+// useFixed relies on how libxev's io_uring backend adds a completion: it
+// takes an entry from the std IoUring submission queue, prepares it with
+// the completion as user data and marks the completion active, or queues
+// the completion if the ring is full. Check what can be checked at
+// compile time; useFixed panics if the rest no longer holds.
+comptime {
+    if (@hasField(xev.Loop, "ring")) {
+        std.debug.assert(@FieldType(xev.Loop, "ring") == std.os.linux.IoUring);
+        std.debug.assert(@hasField(@FieldType(xev.Completion, "flags"), "state"));
+    }
+}
+
+/// Rewrite the submission queue entry libxev has just prepared for c to go
+/// through a registered file and buffer, and return 1, or 0 if there is
+/// none to rewrite. libxev prepares the entry when the completion is added,
+/// from its descriptor and buffer, with no way to ask for either; the entry
+/// is still at the tail of the ring until the loop submits.
+fn useFixed(loop: *xev.Loop, c: *xev.Completion, file_index: c_int, buf_index: c_int, write: bool) c_int {
+    if (@hasField(xev.Loop, "ring")) {
+        if (file_index < 0 and buf_index < 0) return 0;
+        // A completion that found the ring full waits in libxev's queue.
+        if (c.flags.state != .active) return 0;
+        const linux = std.os.linux;
+        const sq = &loop.ring.sq;
+        const sqe = &sq.sqes[(sq.sqe_tail -% 1) & sq.mask];
+        if (sq.sqe_tail == sq.sqe_head or sqe.user_data != @intFromPtr(c)) {
+            @panic("libxev no longer prepares io_uring entries as completions are added; fixed operations need updating");
+        }
+        if (buf_index >= 0) {
+            // The buffer address and length stay; a socket has no
+            // position, which -1 stands for.
+            sqe.opcode = if (write) linux.IORING_OP.WRITE_FIXED else linux.IORING_OP.READ_FIXED;
+            sqe.off = std.math.maxInt(u64);
+            sqe.rw_flags = 0;
+            sqe.buf_index = @intCast(buf_index);
+        }
+        if (file_index >= 0) {
+            sqe.fd = file_index;
+            sqe.flags |= linux.IOSQE_FIXED_FILE;
+        }
+        return 1;
+    }
+    return 0;
+}
+
 /// Close a TCP socket.
 /// This is an async operation - the callback will be invoked when complete.
 /// Note: The completion must be XEV_SIZEOF_TCP_COMPLETION bytes.
@@ -496,6 +585,100 @@ export fn xev_tcp_shutdown(
     }).callback);
 }
 
+/// Wait until a TCP socket is writable, without writing anything, so the
+/// caller can flush its own buffer after a write came up short. The
+/// callback receives 0 once the socket is writable, or an error code.
+/// Returns 0 if the wait was queued, or an error code if the backend
+/// cannot poll for readiness (kqueue).
+/// Note: The completion must be XEV_SIZEOF_TCP_COMPLETION bytes.
+export fn xev_tcp_poll_writable(
+    tcp: *xev_tcp,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    userdata: ?*anyopaque,
+    cb: xev_tcp_cb,
+) c_int {
+    if (comptime !@hasField(@FieldType(xev.Completion, "op"), "poll")) {
+        return errorCode(error.Unsupported);
+    } else {
+        const Callback = @typeInfo(@TypeOf(cb)).pointer.child;
+
+        c.* = .{
+            .op = .{ .poll = .{ .fd = getFd(tcp), .events = std.posix.POLL.OUT } },
+            .userdata = userdata,
+            .callback = (struct {
+                fn callback(
+                    ud: ?*anyopaque,
+                    cb_loop: *xev.Loop,
+                    cb_c: *xev.Completion,
+                    r: xev.Result,
+                ) xev.CallbackAction {
+                    const cb_extern_c: *Completion = @ptrCast(@alignCast(cb_c));
+                    const cb_c_callback: *const Callback = @ptrCast(@alignCast(cb_extern_c.c_callback));
+
+                    if (r.poll) |_| {
+                        return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, @as(c_int, 0), ud });
+                    } else |err| {
+                        return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, errorCode(err), ud });
+                    }
+                }
+            }).callback,
+        };
+
+        // Store callback in the extended completion struct, past the part
+        // initialized above.
+        const extern_c: *Completion = @ptrCast(@alignCast(c));
+        extern_c.c_callback = @ptrCast(cb);
+
+        loop.add(c);
+        return 0;
+    }
+}
+
+/// Cancel the operation in flight on completion c, such as a connect that
+/// is taking too long. The cancelled operation's callback is invoked with
+/// error.Canceled; cb is invoked on c_cancel once the cancellation is done,
+/// with an error code if c had nothing to cancel.
+/// Note: Both completions must be XEV_SIZEOF_TCP_COMPLETION bytes.
+export fn xev_tcp_cancel(
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    c_cancel: *xev.Completion,
+    userdata: ?*anyopaque,
+    cb: xev_tcp_cb,
+) void {
+    const Callback = @typeInfo(@TypeOf(cb)).pointer.child;
+
+    c_cancel.* = .{
+        .op = .{ .cancel = .{ .c = c } },
+        .userdata = userdata,
+        .callback = (struct {
+            fn callback(
+                ud: ?*anyopaque,
+                cb_loop: *xev.Loop,
+                cb_c: *xev.Completion,
+                r: xev.Result,
+            ) xev.CallbackAction {
+                const cb_extern_c: *Completion = @ptrCast(@alignCast(cb_c));
+                const cb_c_callback: *const Callback = @ptrCast(@alignCast(cb_extern_c.c_callback));
+
+                if (r.cancel) |_| {
+                    return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, @as(c_int, 0), ud });
+                } else |err| {
+                    return @call(.auto, cb_c_callback, .{ cb_loop, cb_c, errorCode(err), ud });
+                }
+            }
+        }).callback,
+    };
+
+    // Store callback in the extended completion struct, past the part
+    // initialized above.
+    const extern_c: *Completion = @ptrCast(@alignCast(c_cancel));
+    extern_c.c_callback = @ptrCast(cb);
+
+    loop.add(c_cancel);
+}
+
 //-------------------------------------------------------------------
 // Size Constants for Go FFI
 
@@ -582,3 +765,45 @@ test "tcp init and bind" {
     // Clean up - close the socket directly since we're not using the event loop
     std.posix.close(xev_tcp_fd(&tcp));
 }
+
+test "fixed read rewrites the prepared entry" {
+    if (!@hasField(xev.Loop, "ring")) return error.SkipZigTest;
+    const testing = std.testing;
+    const linux = std.os.linux;
+
+    var loop = try xev.Loop.init(.{});
+    defer loop.deinit();
+
+    var fds: [2]std.posix.fd_t = undefined;
+    if (linux.E.init(linux.socketpair(linux.AF.UNIX, linux.SOCK.STREAM, 0, &fds)) != .SUCCESS) {
+        return error.SkipZigTest;
+    }
+    defer std.posix.close(fds[0]);
+    defer std.posix.close(fds[1]);
+
+    var buf: [64]u8 = undefined;
+    const iovecs = [1]std.posix.iovec{.{ .base = &buf, .len = buf.len }};
+    loop.ring.register_buffers(&iovecs) catch return error.SkipZigTest;
+    loop.ring.register_files_sparse(1) catch return error.SkipZigTest;
+    try loop.ring.register_files_update(0, fds[0..1]);
+
+    var tcp: xev_tcp = undefined;
+    xev_tcp_init_fd(&tcp, fds[0]);
+    var c: Completion = undefined;
+    const result = xev_tcp_read_fixed(&tcp, &loop, @ptrCast(&c), &buf, buf.len, 0, 0, null, (struct {
+        fn callback(_: *xev.Loop, _: *xev.Completion, _: [*]u8, _: c_int, _: c_int, _: ?*anyopaque) callconv(func_callconv) xev.CallbackAction {
+            return .disarm;
+        }
+    }).callback);
+    try testing.expectEqual(@as(c_int, 1), result);
+
+    const sq = &loop.ring.sq;
+    const sqe = sq.sqes[(sq.sqe_tail -% 1) & sq.mask];
+    try testing.expectEqual(linux.IORING_OP.READ_FIXED, sqe.opcode);
+    try testing.expectEqual(@as(i32, 0), sqe.fd);
+    try testing.expect(sqe.flags & linux.IOSQE_FIXED_FILE != 0);
+
+    // Complete the read so the loop is not left with it in flight.
+    _ = try std.posix.write(fds[1], "x");
+    try loop.run(.until_done);
+}
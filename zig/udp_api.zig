@@ -285,6 +285,213 @@ export fn xev_udp_write(
     }).callback);
 }
 
+/// State of a message read or write, kept in xev_udp_state. The first two
+/// fields are read by callers after a read completes, so their offsets are
+/// part of the API.
+const MsgState = extern struct {
+    /// Bytes of ancillary data received, msg_controllen after recvmsg.
+    control_len: u32,
+    /// msg_flags of the received message, such as MSG_TRUNC and MSG_CTRUNC.
+    flags: i32,
+    /// Capacity of the control buffer, restored when a read is rearmed.
+    control_cap: u32,
+    msghdr: std.posix.msghdr,
+    iov: std.posix.iovec,
+    addr: std.posix.sockaddr.storage,
+};
+
+pub fn hasMsgOps() bool {
+    const Op = @FieldType(xev.Completion, "op");
+    return @hasField(Op, "recvmsg") and @hasField(Op, "sendmsg");
+}
+
+/// Read a datagram with its ancillary data (recvmsg).
+///
+/// Like xev_udp_read, but the kernel also fills control, up to control_len
+/// bytes, with the control messages enabled on the socket. When the
+/// callback runs, the first 32-bit word of state holds the number of
+/// control bytes received and the second the message flags.
+/// Returns 0 once the read is submitted, or an error code if the backend
+/// has no recvmsg operation.
+export fn xev_udp_read_msg(
+    udp: *xev_udp,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    state: *xev_udp_state,
+    buf: [*]u8,
+    buf_len: usize,
+    control: ?[*]u8,
+    control_len: usize,
+    userdata: ?*anyopaque,
+    cb: xev_udp_read_cb,
+) c_int {
+    if (comptime !hasMsgOps()) return errorCode(error.Unsupported);
+    const Callback = @typeInfo(@TypeOf(cb)).pointer.child;
+
+    const extern_c: *Completion = @ptrCast(@alignCast(c));
+    extern_c.c_callback = @ptrCast(cb);
+
+    const ms: *MsgState = @ptrCast(@alignCast(&state.data));
+    ms.* = .{
+        .control_len = 0,
+        .flags = 0,
+        .control_cap = @intCast(control_len),
+        .msghdr = undefined,
+        .iov = .{ .base = buf, .len = buf_len },
+        .addr = undefined,
+    };
+    ms.msghdr = .{
+        .name = @ptrCast(&ms.addr),
+        .namelen = @sizeOf(std.posix.sockaddr.storage),
+        .iov = @ptrCast(&ms.iov),
+        .iovlen = 1,
+        .control = control,
+        .controllen = @intCast(control_len),
+        .flags = 0,
+    };
+
+    c.* = .{
+        .op = .{ .recvmsg = .{ .fd = getFd(udp), .msghdr = &ms.msghdr } },
+        .userdata = userdata,
+        .callback = (struct {
+            fn callback(
+                ud: ?*anyopaque,
+                cb_loop: *xev.Loop,
+                cb_c: *xev.Completion,
+                r: xev.Result,
+            ) xev.CallbackAction {
+                const cb_extern_c: *Completion = @ptrCast(@alignCast(cb_c));
+                const cb_c_callback: *const Callback = @ptrCast(@alignCast(cb_extern_c.c_callback));
+                const cb_ms: *MsgState = @fieldParentPtr("msghdr", cb_c.op.recvmsg.msghdr);
+
+                var remote_addr: xev_sockaddr = undefined;
+                @memset(&remote_addr.data, 0);
+                const name_len = @min(@as(usize, @intCast(cb_ms.msghdr.namelen)), remote_addr.data.len);
+                const src: [*]const u8 = @ptrCast(&cb_ms.addr);
+                @memcpy(remote_addr.data[0..name_len], src[0..name_len]);
+
+                cb_ms.control_len = @intCast(cb_ms.msghdr.controllen);
+                cb_ms.flags = @intCast(cb_ms.msghdr.flags);
+
+                const buf_ptr: [*]u8 = @ptrCast(cb_ms.iov.base);
+                const action = if (r.recvmsg) |bytes_read|
+                    @call(.auto, cb_c_callback, .{
+                        cb_loop,
+                        cb_c,
+                        &remote_addr,
+                        buf_ptr,
+                        @as(c_int, @intCast(bytes_read)),
+                        @as(c_int, 0),
+                        ud,
+                    })
+                else |err|
+                    @call(.auto, cb_c_callback, .{
+                        cb_loop,
+                        cb_c,
+                        &remote_addr,
+                        buf_ptr,
+                        @as(c_int, -1),
+                        errorCode(err),
+                        ud,
+                    });
+
+                // The kernel overwrote the lengths with what it received;
+                // a rearmed read needs the capacities back.
+                if (action == .rearm) {
+                    cb_ms.msghdr.namelen = @sizeOf(std.posix.sockaddr.storage);
+                    cb_ms.msghdr.controllen = @intCast(cb_ms.control_cap);
+                    cb_ms.msghdr.flags = 0;
+                }
+                return action;
+            }
+        }).callback,
+    };
+    loop.add(c);
+    return 0;
+}
+
+/// Write a datagram with ancillary data (sendmsg), such as an IP_PKTINFO
+/// message choosing the source address. control may be null.
+/// Returns 0 once the write is submitted, or an error code if the backend
+/// has no sendmsg operation.
+export fn xev_udp_write_msg(
+    udp: *xev_udp,
+    loop: *xev.Loop,
+    c: *xev.Completion,
+    state: *xev_udp_state,
+    addr: *const xev_sockaddr,
+    buf: [*]const u8,
+    buf_len: usize,
+    control: ?[*]const u8,
+    control_len: usize,
+    userdata: ?*anyopaque,
+    cb: xev_udp_write_cb,
+) c_int {
+    if (comptime !hasMsgOps()) return errorCode(error.Unsupported);
+    const Callback = @typeInfo(@TypeOf(cb)).pointer.child;
+
+    const extern_c: *Completion = @ptrCast(@alignCast(c));
+    extern_c.c_callback = @ptrCast(cb);
+
+    const address = sockaddrToAddress(addr);
+    const WriteState = extern struct {
+        msghdr: std.posix.msghdr_const,
+        iov: std.posix.iovec_const,
+        addr: std.posix.sockaddr.storage,
+    };
+    comptime std.debug.assert(@sizeOf(WriteState) <= XEV_SIZEOF_UDP_STATE);
+    const ws: *WriteState = @ptrCast(@alignCast(&state.data));
+    ws.iov = .{ .base = buf, .len = buf_len };
+    const addr_bytes: [*]const u8 = @ptrCast(&address.any);
+    const dst: [*]u8 = @ptrCast(&ws.addr);
+    @memcpy(dst[0..address.getOsSockLen()], addr_bytes[0..address.getOsSockLen()]);
+    ws.msghdr = .{
+        .name = @ptrCast(&ws.addr),
+        .namelen = address.getOsSockLen(),
+        .iov = @ptrCast(&ws.iov),
+        .iovlen = 1,
+        .control = control,
+        .controllen = @intCast(control_len),
+        .flags = 0,
+    };
+
+    c.* = .{
+        .op = .{ .sendmsg = .{ .fd = getFd(udp), .msghdr = &ws.msghdr } },
+        .userdata = userdata,
+        .callback = (struct {
+            fn callback(
+                ud: ?*anyopaque,
+                cb_loop: *xev.Loop,
+                cb_c: *xev.Completion,
+                r: xev.Result,
+            ) xev.CallbackAction {
+                const cb_extern_c: *Completion = @ptrCast(@alignCast(cb_c));
+                const cb_c_callback: *const Callback = @ptrCast(@alignCast(cb_extern_c.c_callback));
+
+                if (r.sendmsg) |bytes_written| {
+                    return @call(.auto, cb_c_callback, .{
+                        cb_loop,
+                        cb_c,
+                        @as(c_int, @intCast(bytes_written)),
+                        @as(c_int, 0),
+                        ud,
+                    });
+                } else |err| {
+                    return @call(.auto, cb_c_callback, .{
+                        cb_loop,
+                        cb_c,
+                        @as(c_int, -1),
+                        errorCode(err),
+                        ud,
+                    });
+                }
+            }
+        }).callback,
+    };
+    loop.add(c);
+    return 0;
+}
+
 /// Close a UDP socket.
 /// This is an async operation - the callback will be invoked when complete.
 export fn xev_udp_close(
@@ -379,6 +586,13 @@ test "udp sizes" {
     try testing.expect(@sizeOf(Completion) > @sizeOf(xev.Completion));
 }
 
+test "udp msg state fits" {
+    try std.testing.expect(@sizeOf(MsgState) <= XEV_SIZEOF_UDP_STATE);
+    // Callers read the control length and flags at fixed offsets.
+    try std.testing.expectEqual(0, @offsetOf(MsgState, "control_len"));
+    try std.testing.expectEqual(4, @offsetOf(MsgState, "flags"));
+}
+
 test "udp init and bind" {
     const testing = std.testing;
 